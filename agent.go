@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbpeer"
+	"golang.org/x/sys/windows/svc"
+)
+
+// defaultAgentInterval is the interval used by the agent when its
+// configuration doesn't specify one.
+const defaultAgentInterval = time.Hour
+
+// agentConfig describes a set of deployments that are periodically
+// re-evaluated and enforced by the LeafBridge agent.
+type agentConfig struct {
+	// Interval is the amount of time between enforcement runs.
+	Interval datatype.Duration `json:"interval,omitempty"`
+
+	// Deployments are the deployments to enforce, in order.
+	Deployments []agentDeployment `json:"deployments"`
+
+	// PeerSharing, if present, causes the agent to share its verified
+	// package files with peers on the local network, and to look for
+	// package files on those peers before downloading them.
+	PeerSharing *agentPeerSharing `json:"peer-sharing,omitempty"`
+}
+
+// agentPeerSharing describes how the agent shares package files with peers
+// on the local network.
+type agentPeerSharing struct {
+	// Port is the TCP port that package files are served on. If it is
+	// zero, the default port is used.
+	Port int `json:"port,omitempty"`
+}
+
+// agentDeployment describes a deployment that is enforced by the agent.
+type agentDeployment struct {
+	ConfigFile       string                 `json:"config-file"`
+	Flows            []lbdeploy.FlowID      `json:"flows"`
+	Environment      lbdeploy.EnvironmentID `json:"environment,omitempty"`
+	ResultFile       string                 `json:"result-file,omitempty"`
+	RequireSignature bool                   `json:"require-signature,omitempty"`
+	TrustedKeys      []string               `json:"trusted-keys,omitempty"`
+	LockWait         datatype.Duration      `json:"lock-wait,omitempty"`
+	Heartbeat        datatype.Duration      `json:"heartbeat,omitempty"`
+	EventJournal     string                 `json:"event-journal,omitempty"`
+}
+
+// loadAgentConfig reads the agent configuration file at path. Relative
+// paths within the configuration are resolved against the directory
+// containing the file.
+func loadAgentConfig(path string) (config agentConfig, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(config.Deployments) == 0 {
+		return config, fmt.Errorf("%s: no deployments are specified", path)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i := range config.Deployments {
+		entry := &config.Deployments[i]
+		if entry.ConfigFile == "" {
+			return config, fmt.Errorf("%s: deployment %d: a config file is missing", path, i+1)
+		}
+		if len(entry.Flows) == 0 {
+			return config, fmt.Errorf("%s: deployment %d: no flows are specified", path, i+1)
+		}
+		entry.ConfigFile = resolve(entry.ConfigFile)
+		entry.ResultFile = resolve(entry.ResultFile)
+		entry.EventJournal = resolve(entry.EventJournal)
+		for k := range entry.TrustedKeys {
+			entry.TrustedKeys[k] = resolve(entry.TrustedKeys[k])
+		}
+	}
+
+	return config, nil
+}
+
+// RunCmd runs the LeafBridge agent, which enforces a set of deployments
+// described by an agent configuration file.
+type RunCmd struct {
+	AgentConfig string `kong:"required,name='agent-config',help='Path to an agent configuration file listing the deployments to enforce.'"`
+	Schedule    bool   `kong:"optional,name='schedule',help='Keep running and re-enforce the deployments at the interval specified by the agent configuration.'"`
+	Verbose     bool   `kong:"optional,name='verbose',short='v',help='Show debug messages on the command line.'"`
+}
+
+// Run executes the LeafBridge run command.
+func (cmd RunCmd) Run(ctx context.Context) error {
+	// When started by the service control manager, run as a service.
+	if isService, err := svc.IsWindowsService(); err == nil && isService {
+		return runAgentService(ctx, cmd.schedule)
+	}
+
+	if cmd.Schedule {
+		return cmd.schedule(ctx)
+	}
+
+	config, err := loadAgentConfig(cmd.AgentConfig)
+	if err != nil {
+		return err
+	}
+	return cmd.enforce(ctx, config)
+}
+
+// schedule enforces the deployments repeatedly until ctx is cancelled. The
+// agent configuration is reloaded before each run, so that changes take
+// effect without restarting the agent.
+//
+// If peer sharing is enabled, package files are shared with peers for as
+// long as the agent runs. Changes to the peer sharing configuration take
+// effect when the agent is restarted.
+func (cmd RunCmd) schedule(ctx context.Context) error {
+	sharing := false
+	for {
+		interval := defaultAgentInterval
+
+		config, err := loadAgentConfig(cmd.AgentConfig)
+		if err != nil {
+			fmt.Printf("Failed to load the agent configuration: %s\n", err)
+		} else {
+			if config.PeerSharing != nil && !sharing {
+				sharing = true
+				go cmd.sharePackages(ctx, *config.PeerSharing)
+			}
+			if config.Interval > 0 {
+				interval = time.Duration(config.Interval)
+			}
+			if err := cmd.enforce(ctx, config); err != nil && ctx.Err() == nil {
+				fmt.Printf("One or more deployments failed: %s\n", err)
+			}
+		}
+
+		fmt.Printf("The next run will start in %s.\n", interval)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// sharePackages shares verified package files with peers on the local
+// network until ctx is cancelled.
+func (cmd RunCmd) sharePackages(ctx context.Context, config agentPeerSharing) {
+	server := lbpeer.Server{
+		Catalog: lbpeer.StagedCatalog{},
+		Port:    config.Port,
+	}
+	if err := server.Serve(ctx); err != nil && ctx.Err() == nil {
+		fmt.Printf("Stopped sharing packages with peers: %s\n", err)
+	}
+}
+
+// enforce invokes each of the deployments in the agent configuration once.
+func (cmd RunCmd) enforce(ctx context.Context, config agentConfig) error {
+	var errs []error
+	for _, entry := range config.Deployments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		deploy := DeployCmd{
+			ConfigFile:       entry.ConfigFile,
+			Flows:            entry.Flows,
+			Verbose:          cmd.Verbose,
+			Environment:      entry.Environment,
+			LockWait:         time.Duration(entry.LockWait),
+			Heartbeat:        time.Duration(entry.Heartbeat),
+			PeerSharing:      config.PeerSharing != nil,
+			ResultFile:       entry.ResultFile,
+			EventJournal:     entry.EventJournal,
+			RequireSignature: entry.RequireSignature,
+			TrustedKeys:      entry.TrustedKeys,
+		}
+		if err := deploy.Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.ConfigFile, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,358 @@
+// Package bits transfers files with the Background Intelligent Transfer
+// Service.
+//
+// BITS downloads files in the background using idle network bandwidth. Its
+// jobs are managed by the service rather than the calling process, so they
+// continue across process restarts and reboots, and they honor the
+// throttling policies that are applied to BITS through Group Policy.
+//
+// Jobs are identified by a JobID, which callers can record in order to
+// pick up a transfer again after an interruption.
+package bits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modole32 = windows.NewLazySystemDLL("ole32.dll")
+
+	procCoCreateInstance = modole32.NewProc("CoCreateInstance")
+)
+
+var (
+	// CLSID_BackgroundCopyManager
+	clsidBackgroundCopyManager = windows.GUID{Data1: 0x4991d34b, Data2: 0x80a1, Data3: 0x4291, Data4: [8]byte{0x83, 0xb6, 0x33, 0x28, 0x36, 0x6b, 0x90, 0x97}}
+
+	// IID_IBackgroundCopyManager
+	iidBackgroundCopyManager = windows.GUID{Data1: 0x5ce34c0d, Data2: 0x0dc9, Data3: 0x4c1f, Data4: [8]byte{0x89, 0x7c, 0xda, 0xa1, 0xb7, 0x8c, 0xee, 0x7c}}
+)
+
+// Method indices within the virtual function tables of the BITS
+// interfaces, which follow the three methods of IUnknown.
+const (
+	methodRelease = 2
+
+	// IBackgroundCopyManager
+	managerCreateJob = 3
+	managerGetJob    = 4
+
+	// IBackgroundCopyJob
+	jobAddFile     = 4
+	jobResume      = 7
+	jobCancel      = 8
+	jobComplete    = 9
+	jobGetProgress = 12
+	jobGetState    = 14
+	jobGetError    = 15
+	jobSetPriority = 21
+
+	// IBackgroundCopyError
+	errorGetError            = 3
+	errorGetErrorDescription = 5
+)
+
+// Job states used by the Windows API.
+const (
+	stateQueued         = 0 // BG_JOB_STATE_QUEUED
+	stateConnecting     = 1 // BG_JOB_STATE_CONNECTING
+	stateTransferring   = 2 // BG_JOB_STATE_TRANSFERRING
+	stateSuspended      = 3 // BG_JOB_STATE_SUSPENDED
+	stateError          = 4 // BG_JOB_STATE_ERROR
+	stateTransientError = 5 // BG_JOB_STATE_TRANSIENT_ERROR
+	stateTransferred    = 6 // BG_JOB_STATE_TRANSFERRED
+	stateAcknowledged   = 7 // BG_JOB_STATE_ACKNOWLEDGED
+	stateCancelled      = 8 // BG_JOB_STATE_CANCELLED
+)
+
+const (
+	jobTypeDownload = 0                  // BG_JOB_TYPE_DOWNLOAD
+	sizeUnknown     = ^uint64(0)         // BG_SIZE_UNKNOWN
+	errNotFound     = 0x80200001         // BG_E_NOT_FOUND
+	langUserDefault = 0x0400             // LANG_USER_DEFAULT
+	pollInterval    = 2 * time.Second    // Time between checks on a job
+	hresultFailure  = uint32(0x80000000) // Severity bit of an HRESULT
+)
+
+// ErrNotFound is returned when a job does not exist, or is no longer
+// active because it has been completed or cancelled.
+var ErrNotFound = errors.New("the transfer job was not found")
+
+// Priority is the priority of a transfer job.
+type Priority uint32
+
+// Job priorities. Only foreground jobs compete with other applications for
+// network bandwidth.
+const (
+	PriorityForeground Priority = 0 // BG_JOB_PRIORITY_FOREGROUND
+	PriorityHigh       Priority = 1 // BG_JOB_PRIORITY_HIGH
+	PriorityNormal     Priority = 2 // BG_JOB_PRIORITY_NORMAL
+	PriorityLow        Priority = 3 // BG_JOB_PRIORITY_LOW
+)
+
+// JobID identifies a transfer job.
+type JobID windows.GUID
+
+// ParseJobID parses a job ID in its string form.
+func ParseJobID(s string) (JobID, error) {
+	id, err := windows.GUIDFromString(s)
+	if err != nil {
+		return JobID{}, err
+	}
+	return JobID(id), nil
+}
+
+// String returns the job ID in its string form.
+func (id JobID) String() string {
+	return windows.GUID(id).String()
+}
+
+// Transfer describes a file to be downloaded by a transfer job.
+//
+// Path is the local path that the file is written to. BITS writes to a
+// temporary file in the same directory, which is renamed to Path when the
+// job is completed.
+type Transfer struct {
+	DisplayName string
+	URL         string
+	Path        string
+	Priority    Priority
+}
+
+// Progress describes the progress of a transfer job. Total is -1 if the
+// size of the file is not yet known.
+type Progress struct {
+	Transferred int64
+	Total       int64
+}
+
+// bgJobProgress is the BG_JOB_PROGRESS structure.
+type bgJobProgress struct {
+	BytesTotal       uint64
+	BytesTransferred uint64
+	FilesTotal       uint32
+	FilesTransferred uint32
+}
+
+// object is a COM interface pointer.
+type object struct {
+	vtbl *[64]uintptr
+}
+
+// call invokes a method of the interface and returns its HRESULT as an
+// error if it indicates failure.
+func (o *object) call(method int, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if uint32(r)&hresultFailure != 0 {
+		return hresultError(uint32(r))
+	}
+	return nil
+}
+
+// release releases the interface.
+func (o *object) release() {
+	o.call(methodRelease)
+}
+
+// hresultError is a failed HRESULT.
+type hresultError uint32
+
+// Error returns a description of the error.
+func (e hresultError) Error() string {
+	if e == errNotFound {
+		return ErrNotFound.Error()
+	}
+	return fmt.Sprintf("BITS returned HRESULT 0x%08X", uint32(e))
+}
+
+// Is returns true if target is ErrNotFound and e indicates the same.
+func (e hresultError) Is(target error) bool {
+	return target == ErrNotFound && e == errNotFound
+}
+
+// Start creates a transfer job that downloads a file, and returns its ID.
+// The job runs in the background until it is waited upon.
+func Start(t Transfer) (id JobID, err error) {
+	name, err := windows.UTF16PtrFromString(t.DisplayName)
+	if err != nil {
+		return JobID{}, err
+	}
+	remote, err := windows.UTF16PtrFromString(t.URL)
+	if err != nil {
+		return JobID{}, err
+	}
+	local, err := windows.UTF16PtrFromString(t.Path)
+	if err != nil {
+		return JobID{}, err
+	}
+
+	err = withManager(func(manager *object) error {
+		var job *object
+		if err := manager.call(managerCreateJob, uintptr(unsafe.Pointer(name)), jobTypeDownload, uintptr(unsafe.Pointer(&id)), uintptr(unsafe.Pointer(&job))); err != nil {
+			return fmt.Errorf("failed to create a transfer job: %w", err)
+		}
+		defer job.release()
+
+		err := func() error {
+			if err := job.call(jobAddFile, uintptr(unsafe.Pointer(remote)), uintptr(unsafe.Pointer(local))); err != nil {
+				return fmt.Errorf("failed to add the file to the transfer job: %w", err)
+			}
+			if err := job.call(jobSetPriority, uintptr(t.Priority)); err != nil {
+				return fmt.Errorf("failed to set the priority of the transfer job: %w", err)
+			}
+			if err := job.call(jobResume); err != nil {
+				return fmt.Errorf("failed to start the transfer job: %w", err)
+			}
+			return nil
+		}()
+		if err != nil {
+			job.call(jobCancel)
+		}
+		return err
+	})
+
+	return id, err
+}
+
+// Wait waits for a transfer job to finish. It calls progress each time it
+// checks on the job.
+//
+// When the file has been transferred, the job is completed, which makes
+// the file available at its path. If the job fails, it is cancelled and
+// its error is returned. If ctx is cancelled first, the job is left to
+// continue in the background, so that it can be waited upon again later.
+func Wait(ctx context.Context, id JobID, progress func(Progress)) error {
+	return withJob(id, func(job *object) error {
+		for {
+			var p bgJobProgress
+			if err := job.call(jobGetProgress, uintptr(unsafe.Pointer(&p))); err != nil {
+				return fmt.Errorf("failed to read the progress of the transfer job: %w", err)
+			}
+			if progress != nil {
+				total := int64(p.BytesTotal)
+				if p.BytesTotal == sizeUnknown {
+					total = -1
+				}
+				progress(Progress{Transferred: int64(p.BytesTransferred), Total: total})
+			}
+
+			var state uint32
+			if err := job.call(jobGetState, uintptr(unsafe.Pointer(&state))); err != nil {
+				return fmt.Errorf("failed to read the state of the transfer job: %w", err)
+			}
+
+			switch state {
+			case stateTransferred:
+				if err := job.call(jobComplete); err != nil {
+					return fmt.Errorf("failed to complete the transfer job: %w", err)
+				}
+				return nil
+			case stateError:
+				err := jobError(job)
+				job.call(jobCancel)
+				return err
+			case stateSuspended:
+				if err := job.call(jobResume); err != nil {
+					return fmt.Errorf("failed to resume the transfer job: %w", err)
+				}
+			case stateAcknowledged, stateCancelled:
+				return ErrNotFound
+			case stateQueued, stateConnecting, stateTransferring, stateTransientError:
+				// BITS retries transient errors on its own.
+			}
+
+			timer := time.NewTimer(pollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// Cancel cancels a transfer job and deletes its temporary files.
+func Cancel(id JobID) error {
+	return withJob(id, func(job *object) error {
+		return job.call(jobCancel)
+	})
+}
+
+// jobError returns the error that caused a job to fail.
+func jobError(job *object) error {
+	var jobErr *object
+	if err := job.call(jobGetError, uintptr(unsafe.Pointer(&jobErr))); err != nil {
+		return errors.New("the transfer job failed")
+	}
+	defer jobErr.release()
+
+	var (
+		errContext uint32
+		code       uint32
+	)
+	jobErr.call(errorGetError, uintptr(unsafe.Pointer(&errContext)), uintptr(unsafe.Pointer(&code)))
+
+	var description *uint16
+	if err := jobErr.call(errorGetErrorDescription, langUserDefault, uintptr(unsafe.Pointer(&description))); err != nil || description == nil {
+		return fmt.Errorf("the transfer job failed with HRESULT 0x%08X", code)
+	}
+	defer windows.CoTaskMemFree(unsafe.Pointer(description))
+
+	return fmt.Errorf("the transfer job failed: %s", windows.UTF16PtrToString(description))
+}
+
+// withJob runs fn with the job that has the given ID.
+func withJob(id JobID, fn func(job *object) error) error {
+	return withManager(func(manager *object) error {
+		var job *object
+		if err := manager.call(managerGetJob, uintptr(unsafe.Pointer(&id)), uintptr(unsafe.Pointer(&job))); err != nil {
+			return err
+		}
+		defer job.release()
+		return fn(job)
+	})
+}
+
+// withManager runs fn with the BITS manager, on a thread that has been
+// initialized for COM.
+func withManager(fn func(manager *object) error) error {
+	if err := procCoCreateInstance.Find(); err != nil {
+		return err
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// S_FALSE indicates that the thread was already initialized, which
+	// still requires a matching call to CoUninitialize.
+	if err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED); err != nil && err != syscall.Errno(windows.S_FALSE) {
+		if err != syscall.Errno(windows.RPC_E_CHANGED_MODE) {
+			return fmt.Errorf("failed to initialize COM: %w", err)
+		}
+	} else {
+		defer windows.CoUninitialize()
+	}
+
+	var manager *object
+	r, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidBackgroundCopyManager)),
+		0,
+		windows.CLSCTX_LOCAL_SERVER,
+		uintptr(unsafe.Pointer(&iidBackgroundCopyManager)),
+		uintptr(unsafe.Pointer(&manager)))
+	if uint32(r)&hresultFailure != 0 {
+		return fmt.Errorf("the background intelligent transfer service is not available: %w", hresultError(uint32(r)))
+	}
+	defer manager.release()
+
+	return fn(manager)
+}
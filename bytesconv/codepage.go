@@ -0,0 +1,58 @@
+package bytesconv
+
+import (
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32  = windows.NewLazySystemDLL("kernel32.dll")
+	procGetOEMCP = modkernel32.NewProc("GetOEMCP")
+)
+
+// mbErrInvalidChars causes MultiByteToWideChar to fail when it encounters
+// an invalid character.
+const mbErrInvalidChars = 0x00000008
+
+// OEMCodePage returns the system's OEM code page, which is used by console
+// programs. If it cannot be determined, it returns zero.
+func OEMCodePage() uint32 {
+	if err := procGetOEMCP.Find(); err != nil {
+		return 0
+	}
+	cp, _, _ := procGetOEMCP.Call()
+	return uint32(cp)
+}
+
+// ANSICodePage returns the system's ANSI code page, which is used by
+// graphical programs that don't use unicode.
+func ANSICodePage() uint32 {
+	return windows.GetACP()
+}
+
+// ParseCodePage parses the given bytes as text in the specified Windows
+// code page and returns the value as a string. If the bytes contain
+// characters that are not valid in the code page, it returns an error.
+func ParseCodePage(p []byte, codePage uint32) (string, error) {
+	// If there is no data, return an empty string.
+	if len(p) == 0 {
+		return "", nil
+	}
+
+	// Determine the number of UTF-16 code units needed to hold the text.
+	n, err := windows.MultiByteToWideChar(codePage, mbErrInvalidChars, &p[0], int32(len(p)), nil, 0)
+	if err != nil || n <= 0 {
+		return "", ErrInvalidCodePage
+	}
+
+	// Convert the text to UTF-16.
+	buf := make([]uint16, n)
+	n, err = windows.MultiByteToWideChar(codePage, mbErrInvalidChars, &p[0], int32(len(p)), &buf[0], n)
+	if err != nil || n <= 0 {
+		return "", ErrInvalidCodePage
+	}
+
+	// Convert the text to a string.
+	return string(utf16.Decode(buf[:n])), nil
+}
@@ -10,11 +10,17 @@ import (
 // DecodeString attempts to interpret the given bytes as a string. If the
 // bytes are valid UTF-8, they are returned as a string without modification.
 //
-// If the bytes are not valid UTF-8, it attempts to interpret them as UTF-16.
-// If successful, it converts the bytes to UTF-8 and returns the resulting
-// string.
+// If the bytes begin with a UTF-16 byte order mark, they are interpreted as
+// UTF-16 in the indicated byte order. If the bytes are not valid UTF-8 and
+// contain null bytes, it attempts to interpret them as UTF-16 without a
+// byte order mark.
 //
-// If a unicode encoding is not detected, or conversion to a string is not
+// Otherwise, the bytes are interpreted as text in the system's OEM code
+// page, which is used by console programs, and then in the system's ANSI
+// code page. If successful, it converts the text to UTF-8 and returns the
+// resulting string.
+//
+// If an encoding is not detected, or conversion to a string is not
 // successful, it returns the bytes as a Base64 raw URL-encoded string.
 func DecodeString(p []byte) string {
 	// If there is no data, return an empty string
@@ -29,6 +35,8 @@ func DecodeString(p []byte) string {
 		return DecodeUTF16(p[2:], binary.LittleEndian)
 	case HasUTF16BOM(p, binary.BigEndian):
 		return DecodeUTF16(p[2:], binary.BigEndian)
+	case bytes.HasPrefix(p, utf8BOM):
+		p = p[len(utf8BOM):]
 	}
 
 	// If the data is already valid UTF-8 and it doesn't have a null character
@@ -37,16 +45,38 @@ func DecodeString(p []byte) string {
 		return string(p)
 	}
 
-	// Attempt to parse the data as UTF-16 LE.
-	if s, err := ParseUTF16(p, binary.LittleEndian); err == nil {
-		return s
-	}
+	// Text in a Windows code page doesn't contain null characters, but
+	// UTF-16 text almost always does. Only attempt to parse the data as
+	// UTF-16 if it has them, as nearly any sequence of bytes would be
+	// accepted.
+	if bytes.IndexByte(p, 0) >= 0 {
+		// Attempt to parse the data as UTF-16 LE.
+		if s, err := ParseUTF16(p, binary.LittleEndian); err == nil {
+			return s
+		}
 
-	// Attempt to parse the data as UTF-16 BE.
-	if s, err := ParseUTF16(p, binary.BigEndian); err == nil {
-		return s
+		// Attempt to parse the data as UTF-16 BE.
+		if s, err := ParseUTF16(p, binary.BigEndian); err == nil {
+			return s
+		}
+	} else {
+		// Attempt to parse the data in the OEM code page.
+		if cp := OEMCodePage(); cp != 0 {
+			if s, err := ParseCodePage(p, cp); err == nil {
+				return s
+			}
+		}
+
+		// Attempt to parse the data in the ANSI code page.
+		if s, err := ParseCodePage(p, ANSICodePage()); err == nil {
+			return s
+		}
 	}
 
 	// Encode the data as Base64 as a last resort.
 	return base64.RawURLEncoding.EncodeToString(p)
 }
+
+// utf8BOM is the byte order mark that sometimes appears at the start of
+// UTF-8 text.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
@@ -0,0 +1,145 @@
+package cmdoutput
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// MaxLineLength is the maximum number of bytes in a line of output. Longer
+// lines are broken up into multiple lines.
+const MaxLineLength = 64 * 1024
+
+// Source is a stream of command output to be collected.
+type Source struct {
+	Stream Stream
+	Reader io.Reader
+}
+
+// Decoder converts a line of output from a command to a string.
+type Decoder func(p []byte) string
+
+// Collect reads lines of output from each of the sources until all of them
+// have been exhausted, and returns the lines in the order that they were
+// received. Lines from different sources are never mixed together, even
+// when the command writes to several streams at once.
+//
+// Sources that begin with UTF-16 text are decoded as UTF-16. Lines from
+// other sources are converted to strings by decode. If decode is nil, the
+// bytes of each line are converted to a string as-is.
+func Collect(decode Decoder, sources ...Source) Lines {
+	if decode == nil {
+		decode = func(p []byte) string { return string(p) }
+	}
+
+	ch := make(chan Line)
+
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+
+	for _, source := range sources {
+		go func() {
+			defer wg.Done()
+			readLines(source, decode, ch)
+		}()
+	}
+
+	go func() {
+		defer close(ch)
+		wg.Wait()
+	}()
+
+	var lines Lines
+	for line := range ch {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// readLines reads lines of text from source and sends them to ch until the
+// source is exhausted or returns an error.
+func readLines(source Source, decode Decoder, ch chan<- Line) {
+	send := func(text string) {
+		ch <- Line{Stream: source.Stream, Time: time.Now(), Text: text}
+	}
+
+	r := bufio.NewReaderSize(source.Reader, MaxLineLength)
+
+	// Determine whether the source contains UTF-16 text.
+	if peek, _ := r.Peek(2); isUTF16LE(peek) {
+		readUTF16Lines(r, send)
+		return
+	}
+
+	for {
+		data, err := r.ReadSlice('\n')
+		if len(data) > 0 {
+			send(decode(trimLineEnding(data)))
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return
+		}
+	}
+}
+
+// readUTF16Lines reads lines of little-endian UTF-16 text from r and passes
+// them to send until r is exhausted or returns an error.
+func readUTF16Lines(r *bufio.Reader, send func(string)) {
+	var (
+		pair  [2]byte
+		units []uint16
+		first = true
+	)
+
+	flush := func() {
+		if first && len(units) > 0 && units[0] == 0xFEFF {
+			units = units[1:]
+		}
+		first = false
+		if n := len(units); n > 0 && units[n-1] == '\r' {
+			units = units[:n-1]
+		}
+		send(string(utf16.Decode(units)))
+		units = units[:0]
+	}
+
+	for {
+		if _, err := io.ReadFull(r, pair[:]); err != nil {
+			if len(units) > 0 {
+				flush()
+			}
+			return
+		}
+
+		unit := uint16(pair[0]) | uint16(pair[1])<<8
+		if unit == '\n' {
+			flush()
+			continue
+		}
+
+		units = append(units, unit)
+		if len(units)*2 >= MaxLineLength {
+			flush()
+		}
+	}
+}
+
+// isUTF16LE returns true if p appears to be the start of little-endian
+// UTF-16 text, either because it has a byte order mark or because it holds
+// an ASCII character.
+func isUTF16LE(p []byte) bool {
+	if len(p) < 2 {
+		return false
+	}
+	return (p[0] == 0xFF && p[1] == 0xFE) || (p[0] != 0 && p[1] == 0)
+}
+
+// trimLineEnding removes a trailing line feed or carriage return and line
+// feed from p.
+func trimLineEnding(p []byte) []byte {
+	p = bytes.TrimSuffix(p, []byte{'\n'})
+	return bytes.TrimSuffix(p, []byte{'\r'})
+}
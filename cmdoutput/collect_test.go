@@ -0,0 +1,89 @@
+package cmdoutput_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/cmdoutput"
+)
+
+func TestCollect(t *testing.T) {
+	r1, r2, err := makeTwoPipes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := cmdoutput.Collect(nil,
+		cmdoutput.Source{Stream: cmdoutput.Stdout, Reader: r1},
+		cmdoutput.Source{Stream: cmdoutput.Stderr, Reader: r2})
+
+	if len(lines) != 20 {
+		t.Fatalf("expected 20 lines, got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		var want string
+		switch line.Stream {
+		case cmdoutput.Stdout:
+			want = "Hello from pipe 1"
+		case cmdoutput.Stderr:
+			want = "Hello from pipe 2"
+		default:
+			t.Fatalf("unexpected stream: %s", line.Stream)
+		}
+		if line.Text != want {
+			t.Errorf("expected %q on %s, got %q", want, line.Stream, line.Text)
+		}
+	}
+
+	t.Log(lines.String())
+}
+
+func TestCollectUTF16(t *testing.T) {
+	const text = "\xff\xfeH\x00i\x00\r\x00\n\x00t\x00h\x00e\x00r\x00e\x00"
+
+	lines := cmdoutput.Collect(nil, cmdoutput.Source{Stream: cmdoutput.Stdout, Reader: strings.NewReader(text)})
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Text != "Hi" || lines[1].Text != "there" {
+		t.Errorf("unexpected lines: %q, %q", lines[0].Text, lines[1].Text)
+	}
+}
+
+func makeTwoPipes(writes int) (r1, r2 *os.File, err error) {
+	r1, w1, err1 := os.Pipe()
+	if err1 != nil {
+		return nil, nil, err1
+	}
+
+	r2, w2, err2 := os.Pipe()
+	if err2 != nil {
+		w1.Close()
+		r1.Close()
+		return nil, nil, err2
+	}
+
+	go func() {
+		for range writes {
+			w1.WriteString("Hello from pipe 1\r\n")
+			time.Sleep(10 * time.Millisecond)
+		}
+		w1.Close()
+	}()
+
+	go func() {
+		for range writes {
+			w2.WriteString("Hello ")
+			time.Sleep(time.Millisecond)
+			w2.WriteString("from pipe 2\n")
+			time.Sleep(10 * time.Millisecond)
+		}
+		w2.Close()
+	}()
+
+	return r1, r2, nil
+}
@@ -0,0 +1,4 @@
+// Package cmdoutput collects the output of commands as a sequence of
+// lines, each of which is tagged with the stream it was written to and the
+// time it was received.
+package cmdoutput
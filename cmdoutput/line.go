@@ -0,0 +1,45 @@
+package cmdoutput
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stream identifies an output stream of a command.
+type Stream string
+
+// Standard output streams.
+const (
+	Stdout Stream = "stdout"
+	Stderr Stream = "stderr"
+)
+
+// Line is a line of output that was written by a command.
+type Line struct {
+	Stream Stream    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+}
+
+// String returns a string representation of the line, which includes the
+// time it was received and its stream.
+func (line Line) String() string {
+	return fmt.Sprintf("%s [%s] %s", line.Time.Format("15:04:05.000"), line.Stream, line.Text)
+}
+
+// Lines is a sequence of output lines in the order they were received.
+type Lines []Line
+
+// String returns a string representation of the lines, with one line of
+// output per line of text.
+func (lines Lines) String() string {
+	var out strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		out.WriteString(line.String())
+	}
+	return out.String()
+}
@@ -0,0 +1,33 @@
+package datatype
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a length of time that is encoded as text in the form
+// accepted by time.ParseDuration, such as "90s" or "1h30m".
+type Duration time.Duration
+
+// String returns a string representation of d.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalText attempts to unmarshal the given text into d.
+func (d *Duration) UnmarshalText(b []byte) error {
+	value, err := time.ParseDuration(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+	if value < 0 {
+		return fmt.Errorf("invalid duration: %s is negative", b)
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// MarshalText marshals the duration as text.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
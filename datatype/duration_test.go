@@ -0,0 +1,50 @@
+package datatype_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+func TestDuration(t *testing.T) {
+	fixtures := []struct {
+		In  string
+		Out time.Duration
+	}{
+		{In: "0s", Out: 0},
+		{In: "90s", Out: 90 * time.Second},
+		{In: "1h30m", Out: 90 * time.Minute},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.In, func(t *testing.T) {
+			var d datatype.Duration
+			if err := d.UnmarshalText([]byte(fixture.In)); err != nil {
+				t.Fatal(err)
+			}
+			if time.Duration(d) != fixture.Out {
+				t.Fatalf("unexpected duration: got %s, want %s", time.Duration(d), fixture.Out)
+			}
+
+			text, err := d.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var roundTrip datatype.Duration
+			if err := roundTrip.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+			if roundTrip != d {
+				t.Fatalf("duration not equal after round trip: %s → %s", d, roundTrip)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "ten minutes", "-5s"} {
+		var d datatype.Duration
+		if err := d.UnmarshalText([]byte(invalid)); err == nil {
+			t.Errorf("expected an error for invalid duration \"%s\"", invalid)
+		}
+	}
+}
@@ -122,3 +122,34 @@ func TestCompareVersions(t *testing.T) {
 		})
 	}
 }
+
+var semanticVersionComparisonFixtures = []versionComparison{
+	{A: "1.2.3", B: "1.2.3", Result: 0},
+	{A: "v1.2.3", B: "1.2.3", Result: 0},
+	{A: "1.2.3+build.1", B: "1.2.3+build.2", Result: 0},
+	{A: "1.2.3-rc.1+build.1", B: "1.2.3-rc.1", Result: 0},
+	{A: "1.2.3-rc.1", B: "1.2.3", Result: -1},
+	{A: "1.2.3-alpha", B: "1.2.3-alpha.1", Result: -1},
+	{A: "1.2.3-alpha.1", B: "1.2.3-alpha.beta", Result: -1},
+	{A: "1.2.3-alpha.beta", B: "1.2.3-beta", Result: -1},
+	{A: "1.2.3-beta", B: "1.2.3-beta.2", Result: -1},
+	{A: "1.2.3-beta.2", B: "1.2.3-beta.11", Result: -1},
+	{A: "1.2.3-beta.11", B: "1.2.3-rc.1", Result: -1},
+	{A: "1.2.3-rc.1", B: "1.2.4-alpha", Result: -1},
+	{A: "1.2.3", B: "1.10.0-rc.1", Result: -1},
+}
+
+func TestCompareSemanticVersions(t *testing.T) {
+	for i, fixture := range semanticVersionComparisonFixtures {
+		t.Run(fmt.Sprintf("Comparison.%d:%s%s%s", i, fixture.A, compSymbol(fixture.Result), fixture.B), func(t *testing.T) {
+			result := datatype.CompareVersionsWithMode(fixture.A, fixture.B, datatype.VersionModeSemantic)
+			if result != fixture.Result {
+				t.Fatalf("unexpected comparison result: %s (want %s)", compSymbol(result), compSymbol(fixture.Result))
+			}
+			reversed := datatype.CompareVersionsWithMode(fixture.B, fixture.A, datatype.VersionModeSemantic)
+			if reversed != -fixture.Result {
+				t.Fatalf("unexpected inverted comparison result: %s (want %s)", compSymbol(reversed), compSymbol(-fixture.Result))
+			}
+		})
+	}
+}
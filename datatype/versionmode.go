@@ -0,0 +1,123 @@
+package datatype
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionMode determines how versions are compared.
+type VersionMode string
+
+// Version comparison modes.
+const (
+	// VersionModeDotted compares versions segment by segment, as described
+	// by CompareVersions. It is the default.
+	VersionModeDotted VersionMode = "dotted"
+
+	// VersionModeSemantic compares versions according to the rules of
+	// semantic versioning, as described by CompareSemanticVersions.
+	VersionModeSemantic VersionMode = "semver"
+)
+
+// Validate returns a non-nil error if the version mode is not recognized.
+func (mode VersionMode) Validate() error {
+	switch mode {
+	case "", VersionModeDotted, VersionModeSemantic:
+		return nil
+	default:
+		return fmt.Errorf("the version comparison mode \"%s\" is not recognized", mode)
+	}
+}
+
+// CompareVersionsWithMode returns an integer comparing two versions in the
+// given mode. The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+//
+// If mode is empty or not recognized, the versions are compared with
+// CompareVersions.
+func CompareVersionsWithMode(a, b Version, mode VersionMode) int {
+	switch mode {
+	case VersionModeSemantic:
+		return CompareSemanticVersions(a, b)
+	default:
+		return CompareVersions(a, b)
+	}
+}
+
+// CompareSemanticVersions returns an integer comparing two semantic
+// versions, such as "1.2.3-rc.1+build.5". The result will be 0 if a == b,
+// -1 if a < b, and +1 if a > b.
+//
+// The portions of the versions before any pre-release or build metadata
+// are compared with CompareVersions. If they are equal, a version with a
+// pre-release is considered "less" than one without. Pre-releases are
+// compared identifier by identifier, as described by semantic versioning.
+// Build metadata is ignored.
+func CompareSemanticVersions(a, b Version) int {
+	core1, pre1 := splitSemanticVersion(a)
+	core2, pre2 := splitSemanticVersion(b)
+
+	if s := CompareVersions(core1, core2); s != 0 {
+		return s
+	}
+
+	switch {
+	case pre1 == "" && pre2 == "":
+		return 0
+	case pre1 == "":
+		return 1
+	case pre2 == "":
+		return -1
+	}
+
+	ids1 := strings.Split(pre1, ".")
+	ids2 := strings.Split(pre2, ".")
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		if s := comparePreReleaseIdentifiers(ids1[i], ids2[i]); s != 0 {
+			return s
+		}
+	}
+
+	switch len1, len2 := len(ids1), len(ids2); {
+	case len1 < len2:
+		return -1
+	case len1 > len2:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// splitSemanticVersion breaks v into its core version and its pre-release.
+// Build metadata is discarded.
+func splitSemanticVersion(v Version) (core Version, pre string) {
+	s, _, _ := strings.Cut(string(v), "+")
+	s, pre, _ = strings.Cut(s, "-")
+	return Version(s), pre
+}
+
+// comparePreReleaseIdentifiers returns an integer comparing two pre-release
+// identifiers. Numeric identifiers are compared numerically, and have lower
+// precedence than alphanumeric identifiers, which are compared lexically.
+func comparePreReleaseIdentifiers(a, b string) int {
+	i1, err1 := strconv.ParseUint(a, 10, 64)
+	i2, err2 := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case err1 == nil && err2 == nil:
+		switch {
+		case i1 < i2:
+			return -1
+		case i1 > i2:
+			return 1
+		default:
+			return 0
+		}
+	case err1 == nil:
+		return -1
+	case err2 == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
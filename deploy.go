@@ -2,31 +2,167 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"time"
 
+	"github.com/leafbridge/leafbridge-deploy/elevation"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbengine"
 	"github.com/leafbridge/leafbridge-deploy/lbevent"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 )
 
 // DeployCmd deploys software according to a LeafBridge deployment
 // configuration.
 type DeployCmd struct {
-	ConfigFile string          `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
-	Flow       lbdeploy.FlowID `kong:"required,name='flow',help='The flow to invoke within the deployment.'"`
-	Force      bool            `kong:"optional,name='force',help='Force processing of the commands that would normally be skipped.'"`
-	Verbose    bool            `kong:"optional,name='verbose',short='v',help='Show debug messages on the command line.'"`
+	ConfigFile     string                 `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Flows          []lbdeploy.FlowID      `kong:"optional,name='flow',help='The flow to invoke within the deployment. May be repeated or given as a comma-separated list to invoke several flows in order.'"`
+	Uninstall      []lbdeploy.AppID       `kong:"optional,name='uninstall',help='An app to uninstall with a flow generated from its metadata. May be repeated. Generated flows are invoked after any requested flows.'"`
+	ForceDownloads bool                   `kong:"optional,name='force-downloads',help='Download package files again, even when a verified copy is already staged.'"`
+	ForceCommands  bool                   `kong:"optional,name='force-commands',help='Invoke every command, even when the apps it installs or uninstalls are already in the desired state.'"`
+	ForceActions   []lbdeploy.ActionRef   `kong:"optional,name='force-action',help='An action whose command is invoked even when its apps are already in the desired state, given as flow:number or flow:id. May be repeated.'"`
+	SkipActions    []lbdeploy.ActionRef   `kong:"optional,name='skip-action',help='An action that is not invoked, given as flow:number or flow:id. May be repeated.'"`
+	OnlyActions    []lbdeploy.ActionRef   `kong:"optional,name='only-action',help='An action to invoke while skipping all others in every flow, given as flow:number or flow:id. May be repeated.'"`
+	Verbose        bool                   `kong:"optional,name='verbose',short='v',help='Show debug messages on the command line.'"`
+	Environment    lbdeploy.EnvironmentID `kong:"optional,name='environment',help='The environment overlay to apply to the deployment, such as pilot or broad.'"`
+	LockWait       time.Duration          `kong:"optional,name='lock-wait',help='How long to wait for locks held by other processes, such as 10m. Locks that specify their own wait time are not affected.'"`
+	ResultFile     string                 `kong:"optional,name='result-file',help='Path of a file to which a machine-readable JSON result is written when the command finishes.'"`
+	ResumeFlow     bool                   `kong:"optional,name='resume-flow',help='Record the progress of each flow, and skip the resumable actions that were completed by a previous invocation with this option that did not finish.'"`
+	Parallelism    int                    `kong:"optional,name='parallelism',default='1',help='The maximum number of independent flows to invoke at the same time.'"`
+	Elevate        bool                   `kong:"optional,name='elevate',help='Relaunch the command with an elevation prompt if the deployment requires elevation and the process is not elevated.'"`
+	EventQueue     int                    `kong:"optional,name='event-queue',default='256',help='The number of events that may be queued for the Windows event log, so that it cannot hold up the deployment. Zero records events synchronously.'"`
+	Heartbeat      time.Duration          `kong:"optional,name='heartbeat',default='30s',help='How often to record a heartbeat event while a command, download or extraction is running. Zero disables heartbeats.'"`
+	EventJournal   string                 `kong:"optional,name='event-journal',help='Path of a file to which events are appended as JSON lines, for later review with the show events command.'"`
+	PeerSharing    bool                   `kong:"optional,name='peer-sharing',help='Look for package files on peers within the local network before downloading them from their sources. Files from peers are verified like any other download.'"`
+	DetectOnly     bool                   `kong:"optional,name='detect-only',help='Evaluate the conditions and apps of the flows without invoking any actions, and exit with a non-zero code unless the system is compliant. Suitable for an Intune detection rule.'"`
+
+	RequireSignature bool     `kong:"optional,name='require-signature',help='Refuse to run the deployment unless its file carries a valid signature from a trusted key.'"`
+	TrustedKeys      []string `kong:"optional,name='trusted-key',type='existingfile',help='Path to a PEM-encoded Ed25519 public key that is trusted to sign deployment files. May be repeated.'"`
 }
 
+// errNotCompliant is returned by the deploy command in detect-only mode
+// when one or more flows would make changes if they were invoked.
+var errNotCompliant = errors.New("the system is not compliant with the deployment")
+
+// errRelaunched is returned by the deploy command when it has handed the
+// deployment off to an elevated instance of itself.
+var errRelaunched = errors.New("the deployment was relaunched with elevation")
+
 // Run executes the LeafBridge deploy command.
 func (cmd DeployCmd) Run(ctx context.Context) error {
-	// Read the deployment file.
-	dep, err := loadDeployment(cmd.ConfigFile)
+	if cmd.ResultFile == "" {
+		err := cmd.run(ctx, nil)
+		if errors.Is(err, errRelaunched) {
+			return nil
+		}
+		return err
+	}
+
+	// Collect a result for the deployment and write it when finished.
+	result := &deploymentResult{
+		Flows:       cmd.Flows,
+		Environment: cmd.Environment,
+		DetectOnly:  cmd.DetectOnly,
+		Started:     time.Now(),
+	}
+	err := cmd.run(ctx, result)
+
+	// The elevated instance is responsible for the result file.
+	if errors.Is(err, errRelaunched) {
+		return nil
+	}
+
+	if writeErr := result.write(cmd.ResultFile); writeErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to write the result file: %w", writeErr))
+	}
+	return err
+}
+
+// run executes the deploy command. If result is non-nil, information about
+// the deployment is collected in it.
+func (cmd DeployCmd) run(ctx context.Context, result *deploymentResult) (err error) {
+	// Make sure there is something to do.
+	if len(cmd.Flows) == 0 && len(cmd.Uninstall) == 0 {
+		return errors.New("at least one flow or app to uninstall must be specified")
+	}
+
+	// Load the keys that are trusted to sign deployment files.
+	keys, err := loadTrustedKeys(cmd.TrustedKeys)
 	if err != nil {
 		return err
 	}
 
+	// Record the outcome when finished.
+	var dep lbdeploy.Deployment
+	if result != nil {
+		defer func() {
+			result.Deployment = dep.ID
+			result.finish(ctx, dep, err)
+		}()
+	}
+
+	// Read the deployment file and verify its signature.
+	dep, err = loadSignedDeployment(cmd.ConfigFile, signaturePolicy{
+		Required:    cmd.RequireSignature,
+		TrustedKeys: keys,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Apply the environment overlay.
+	dep, err = dep.ForEnvironment(cmd.Environment)
+	if err != nil {
+		return err
+	}
+
+	// Select the package variants that suit the system's UI languages.
+	dep = dep.ForLanguages(sysfacts.Languages())
+
+	// If the deployment requires elevation that the process doesn't have,
+	// hand it off to an elevated instance of this command when permitted.
+	if cmd.Elevate && !cmd.DetectOnly && dep.RequiresElevation {
+		status, err := elevation.Get()
+		if err == nil && !status.Elevated && !status.System {
+			if err := elevation.Relaunch(); err != nil {
+				return fmt.Errorf("the \"%s\" deployment requires elevation, but an elevated instance could not be started: %w", dep.ID, err)
+			}
+			fmt.Printf("The \"%s\" deployment requires elevation. It will continue in an elevated window.\n", dep.ID)
+			return errRelaunched
+		}
+	}
+
+	// Generate uninstall flows for any apps that were requested.
+	flows := cmd.Flows
+	for _, app := range cmd.Uninstall {
+		var flow lbdeploy.FlowID
+		dep, flow, err = dep.WithUninstallFlow(app)
+		if err != nil {
+			return err
+		}
+		flows = append(flows, flow)
+	}
+	if result != nil {
+		result.Flows = flows
+	}
+
+	// Make sure that forced and filtered actions refer to actions that
+	// exist.
+	for _, ref := range slices.Concat(cmd.ForceActions, cmd.SkipActions, cmd.OnlyActions) {
+		if err := ref.Validate(dep.Flows); err != nil {
+			return err
+		}
+	}
+
+	// In detect-only mode, report compliance without invoking anything.
+	if cmd.DetectOnly {
+		return cmd.detect(dep, flows)
+	}
+
 	// Select an event recorder.
 	/*
 		recorder := lbevent.Recorder{Handler: lbevent.LoggedHandler{}}
@@ -46,20 +182,87 @@ func (cmd DeployCmd) Run(ctx context.Context) error {
 		}
 		basicHandler := lbevent.NewBasicHandler(os.Stdout, min)
 		windowsHandler, err := lbevent.NewWindowsHandler()
-		if err != nil {
+		switch {
+		case err != nil:
 			handler = basicHandler
-		} else {
+		case cmd.EventQueue > 0:
+			// Record events in the Windows event log asynchronously, and
+			// make sure that queued events are flushed before returning.
+			asyncHandler := lbevent.NewAsyncHandler(windowsHandler, cmd.EventQueue)
+			defer func() {
+				if err := asyncHandler.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Some events could not be recorded in the Windows event log: %s\n", err)
+				}
+			}()
+			handler = lbevent.MultiHandler{basicHandler, asyncHandler}
+		default:
 			handler = lbevent.MultiHandler{basicHandler, windowsHandler}
 		}
 	}
+	if cmd.EventJournal != "" {
+		// Like the Windows event log, the journal is best-effort.
+		journal, err := lbevent.NewJournalHandler(cmd.EventJournal)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Events will not be recorded in the event journal: %s\n", err)
+		} else {
+			defer journal.Close()
+			handler = lbevent.MultiHandler{handler, journal}
+		}
+	}
+	if result != nil {
+		handler = lbevent.MultiHandler{handler, resultHandler{result: result}}
+	}
 	recorder := lbevent.Recorder{Handler: handler}
 
 	// Prepare a new deployment engine for the deployment.
 	engine := lbengine.NewDeploymentEngine(dep, lbengine.Options{
 		Events: recorder,
-		Force:  cmd.Force,
+		Force: lbengine.ForceScope{
+			Downloads: cmd.ForceDownloads,
+			Commands:  cmd.ForceCommands,
+			Actions:   cmd.ForceActions,
+		},
+		Filter: lbengine.ActionFilter{
+			Skip: cmd.SkipActions,
+			Only: cmd.OnlyActions,
+		},
+		LockWait:    cmd.LockWait,
+		Resume:      cmd.ResumeFlow,
+		Parallelism: cmd.Parallelism,
+		Heartbeat:   cmd.Heartbeat,
+		PeerSharing: cmd.PeerSharing,
 	})
 
-	// Invoke the requested flow within the deployment.
-	return engine.Invoke(ctx, cmd.Flow)
+	// Invoke the requested flows within the deployment.
+	return engine.Invoke(ctx, flows...)
+}
+
+// detect evaluates the given flows without invoking any of their actions,
+// and prints whether the system is compliant with each of them.
+//
+// Output is only written when the system is compliant, and the returned
+// error is non-nil otherwise, which is the convention expected by Intune
+// detection rules.
+func (cmd DeployCmd) detect(dep lbdeploy.Deployment, flows []lbdeploy.FlowID) error {
+	engine := lbengine.NewDeploymentEngine(dep, lbengine.Options{})
+	detections, err := engine.Detect(flows...)
+	if err != nil {
+		return err
+	}
+
+	for _, detection := range detections {
+		if !detection.Compliant() {
+			return fmt.Errorf("%w: the \"%s\" flow would install %s and uninstall %s", errNotCompliant, detection.Flow, detection.Apps.ToInstall, detection.Apps.ToUninstall)
+		}
+	}
+
+	for _, detection := range detections {
+		if !detection.Applicable {
+			fmt.Printf("%s: Compliant (not applicable)\n", detection.Flow)
+		} else {
+			fmt.Printf("%s: Compliant\n", detection.Flow)
+		}
+	}
+
+	return nil
 }
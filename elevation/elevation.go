@@ -0,0 +1,93 @@
+// Package elevation determines the privileges held by the running process
+// and relaunches it with elevated privileges when asked to.
+package elevation
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Status describes the privileges held by the running process.
+type Status struct {
+	// Elevated is true if the process token is elevated, which is the case
+	// for administrators that have approved an elevation prompt and for
+	// services.
+	Elevated bool
+
+	// System is true if the process is running as the local system account.
+	System bool
+}
+
+// String returns a string representation of the status.
+func (s Status) String() string {
+	switch {
+	case s.System:
+		return "system"
+	case s.Elevated:
+		return "elevated"
+	default:
+		return "not elevated"
+	}
+}
+
+// Get returns the elevation status of the running process.
+func Get() (Status, error) {
+	token := windows.GetCurrentProcessToken()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return Status{}, err
+	}
+
+	system, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return Status{
+		Elevated: token.IsElevated(),
+		System:   user.User.Sid.Equals(system),
+	}, nil
+}
+
+// Relaunch starts a new instance of the running executable with the same
+// arguments and working directory, asking the user to approve its
+// elevation. It does not wait for the new instance to finish.
+func Relaunch() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, 0, len(os.Args)-1)
+	for _, arg := range os.Args[1:] {
+		args = append(args, syscall.EscapeArg(arg))
+	}
+
+	verbPtr, err := windows.UTF16PtrFromString("runas")
+	if err != nil {
+		return err
+	}
+	exePtr, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	argsPtr, err := windows.UTF16PtrFromString(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	dirPtr, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return err
+	}
+
+	return windows.ShellExecute(0, verbPtr, exePtr, argsPtr, dirPtr, windows.SW_NORMAL)
+}
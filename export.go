@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/leafbridge/leafbridge-deploy/intune"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// ExportCmd exports artifacts for other management systems from a
+// LeafBridge deployment.
+type ExportCmd struct {
+	Intune ExportIntuneCmd `kong:"cmd,help='Exports an Intune detection script and Win32 app manifest.'"`
+}
+
+// ExportIntuneCmd generates a PowerShell detection script for Microsoft
+// Intune from the app detection rules in a deployment, and optionally a
+// manifest describing a Win32 app that invokes the deployment.
+type ExportIntuneCmd struct {
+	ConfigFile    string           `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Apps          lbdeploy.AppList `kong:"optional,name='app',help='An application to detect. May be repeated. All applications are detected by default.'"`
+	Output        string           `kong:"optional,name='output',help='Path of the detection script to write. It is written to standard output by default.'"`
+	Manifest      string           `kong:"optional,name='manifest',help='Path of a Win32 app manifest to write.'"`
+	InstallFlow   lbdeploy.FlowID  `kong:"optional,name='install-flow',default='install',help='The flow that installs the application.'"`
+	UninstallFlow lbdeploy.FlowID  `kong:"optional,name='uninstall-flow',help='The flow that uninstalls the application.'"`
+}
+
+// Run executes the LeafBridge export intune command.
+func (cmd ExportIntuneCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	// Validate the deployment.
+	if err := dep.Validate(); err != nil {
+		fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Generate the detection script.
+	script, err := intune.DetectionScript(dep, cmd.Apps)
+	if err != nil {
+		return fmt.Errorf("failed to generate a detection script: %w", err)
+	}
+
+	if cmd.Output == "" {
+		fmt.Print(script)
+	} else if err := os.WriteFile(cmd.Output, []byte(script), 0644); err != nil {
+		return err
+	}
+
+	if cmd.Manifest == "" {
+		return nil
+	}
+
+	// Generate the Win32 app manifest.
+	scriptName := "detect.ps1"
+	if cmd.Output != "" {
+		scriptName = filepath.Base(cmd.Output)
+	}
+	manifest, err := intune.BuildManifest(dep, intune.ManifestOptions{
+		Apps:            cmd.Apps,
+		ConfigFile:      filepath.Base(cmd.ConfigFile),
+		InstallFlow:     cmd.InstallFlow,
+		UninstallFlow:   cmd.UninstallFlow,
+		DetectionScript: scriptName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate a manifest: %w", err)
+	}
+
+	out, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	return os.WriteFile(cmd.Manifest, out, 0644)
+}
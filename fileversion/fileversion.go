@@ -0,0 +1,50 @@
+// Package fileversion reads version information from the resources of
+// Windows executable files.
+package fileversion
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"golang.org/x/sys/windows"
+)
+
+// ErrNoVersion is returned when a file does not contain version information.
+var ErrNoVersion = errors.New("the file does not contain version information")
+
+// Get returns the file version recorded in the version resource of the file
+// at the given path, in the form "major.minor.build.revision".
+//
+// If the file exists but does not have a version resource, it returns
+// ErrNoVersion.
+func Get(path string) (datatype.Version, error) {
+	size, err := windows.GetFileVersionInfoSize(path, nil)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_RESOURCE_TYPE_NOT_FOUND) || errors.Is(err, windows.ERROR_RESOURCE_DATA_NOT_FOUND) {
+			return "", ErrNoVersion
+		}
+		return "", err
+	}
+
+	buffer := make([]byte, size)
+	if err := windows.GetFileVersionInfo(path, 0, size, unsafe.Pointer(&buffer[0])); err != nil {
+		return "", err
+	}
+
+	var (
+		info   *windows.VS_FIXEDFILEINFO
+		length uint32
+	)
+	if err := windows.VerQueryValue(unsafe.Pointer(&buffer[0]), `\`, unsafe.Pointer(&info), &length); err != nil {
+		return "", err
+	}
+	if info == nil || length < uint32(unsafe.Sizeof(*info)) {
+		return "", ErrNoVersion
+	}
+
+	return datatype.Version(fmt.Sprintf("%d.%d.%d.%d",
+		info.FileVersionMS>>16, info.FileVersionMS&0xffff,
+		info.FileVersionLS>>16, info.FileVersionLS&0xffff)), nil
+}
@@ -4,6 +4,7 @@ go 1.24.2
 
 require (
 	github.com/alecthomas/kong v1.10.0
+	github.com/gentlemanautomaton/cmdline v0.0.0-20250112024754-4dfcc3d8ef7a
 	github.com/gentlemanautomaton/structformat v0.0.0-20241022070736-a530f00cc986
 	github.com/gentlemanautomaton/volmgmt v0.0.0-20250409182909-ce74450cc0fc
 	github.com/gentlemanautomaton/winapp v0.0.0-20250412002214-a4f7f0c4cb8d
@@ -11,5 +12,3 @@ require (
 	github.com/gentlemanautomaton/winproc v0.0.0-20250324203923-17a93b0c29c0
 	golang.org/x/sys v0.32.0
 )
-
-require github.com/gentlemanautomaton/cmdline v0.0.0-20250112024754-4dfcc3d8ef7a // indirect
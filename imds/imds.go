@@ -0,0 +1,135 @@
+// Package imds acquires access tokens for Azure managed identities from
+// the Azure Instance Metadata Service, which is available to virtual
+// machines hosted in Azure.
+//
+// https://learn.microsoft.com/en-us/entra/identity/managed-identities-azure-resources/how-to-use-vm-token
+package imds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// tokenEndpoint is the instance metadata service endpoint that issues
+// managed identity tokens. It is only reachable from within the VM.
+const tokenEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// apiVersion is the version of the instance metadata service API that is
+// requested.
+const apiVersion = "2018-02-01"
+
+// client is used for requests to the instance metadata service. The
+// service is link-local, so requests must not go through a proxy.
+var client = &http.Client{
+	Transport: &http.Transport{Proxy: nil},
+	Timeout:   30 * time.Second,
+}
+
+// Token is an access token for a managed identity.
+type Token struct {
+	AccessToken string
+	Type        string
+	ExpiresOn   time.Time
+}
+
+// Valid returns true if the token has not expired and will not expire
+// within the given margin.
+func (t Token) Valid(margin time.Duration) bool {
+	return t.AccessToken != "" && time.Now().Add(margin).Before(t.ExpiresOn)
+}
+
+// Request describes a token to be acquired.
+type Request struct {
+	// Resource is the URI of the resource that the token grants access
+	// to, such as https://storage.azure.com/.
+	Resource string
+
+	// ClientID selects a user-assigned managed identity. If it is empty,
+	// the system-assigned identity of the VM is used.
+	ClientID string
+}
+
+// tokenResponse is the JSON response of the token endpoint. Numeric
+// values are returned as strings.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresOn   string `json:"expires_on"`
+}
+
+// errorResponse is the JSON response of the token endpoint when a token
+// can't be issued.
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// AcquireToken requests an access token from the instance metadata
+// service.
+func AcquireToken(ctx context.Context, r Request) (Token, error) {
+	if r.Resource == "" {
+		return Token{}, errors.New("a resource is required to acquire a managed identity token")
+	}
+
+	query := url.Values{}
+	query.Set("api-version", apiVersion)
+	query.Set("resource", r.Resource)
+	if r.ClientID != "" {
+		query.Set("client_id", r.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tokenEndpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("the instance metadata service could not be reached: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return Token{}, fmt.Errorf("the instance metadata service refused to issue a token: %s: %s", e.Error, e.Description)
+		}
+		return Token{}, fmt.Errorf("the instance metadata service returned an unexpected status code: %s", resp.Status)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return Token{}, fmt.Errorf("the instance metadata service returned an invalid token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return Token{}, errors.New("the instance metadata service returned an empty token")
+	}
+	expires, err := strconv.ParseInt(tr.ExpiresOn, 10, 64)
+	if err != nil {
+		return Token{}, fmt.Errorf("the instance metadata service returned an invalid token expiration: %w", err)
+	}
+
+	token := Token{
+		AccessToken: tr.AccessToken,
+		Type:        tr.TokenType,
+		ExpiresOn:   time.Unix(expires, 0),
+	}
+	if token.Type == "" {
+		token.Type = "Bearer"
+	}
+
+	return token, nil
+}
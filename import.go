@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbimport"
+)
+
+// ImportCmd converts a deployment definition from another deployment tool
+// into a LeafBridge deployment skeleton.
+type ImportCmd struct {
+	Format    string                `kong:"required,name='format',enum='psadt,configmgr',help='The format of the source definition (psadt, configmgr).'"`
+	Source    string                `kong:"required,name='source',help='Path to a PSADT Deploy-Application.ps1 script or a ConfigMgr application XML file.'"`
+	ID        lbdeploy.DeploymentID `kong:"required,name='id',help='The identifier of the new deployment.'"`
+	App       lbdeploy.AppID        `kong:"optional,name='app',default='app',help='The identifier of the application managed by the deployment.'"`
+	URL       string                `kong:"optional,name='url',help='A URL from which the package archive can be downloaded.'"`
+	Output    string                `kong:"required,name='output',help='Path of the deployment file to write. It must end in deploy.json.'"`
+	Overwrite bool                  `kong:"optional,name='overwrite',help='Overwrite the output file if it already exists.'"`
+}
+
+// Run executes the LeafBridge import command.
+func (cmd ImportCmd) Run(ctx context.Context) error {
+	if !strings.HasSuffix(cmd.Output, "deploy.json") {
+		return errors.New("the provided deployment file path must end in deploy.json")
+	}
+
+	// Read the source definition.
+	source, err := os.Open(cmd.Source)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var def lbimport.Definition
+	switch cmd.Format {
+	case "psadt":
+		def, err = lbimport.ParsePSADT(source)
+	case "configmgr":
+		def, err = lbimport.ParseConfigMgr(source)
+	default:
+		return fmt.Errorf("the \"%s\" import format is not recognized", cmd.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", cmd.Source, err)
+	}
+
+	// Convert it to a deployment.
+	dep, err := def.Deployment(lbimport.Options{
+		ID:  cmd.ID,
+		App: cmd.App,
+		URL: cmd.URL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the deployment file.
+	if err := writeDeployment(cmd.Output, dep, cmd.Overwrite); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %s as a new deployment in %s.\n", cmd.Source, cmd.Output)
+	fmt.Printf("  Install steps:   %d\n", len(def.Install))
+	fmt.Printf("  Uninstall steps: %d\n", len(def.Uninstall))
+	if len(dep.Resources.Packages) > 0 {
+		fmt.Printf("Package the source files into a zip archive, then fill in its size, %s hash and sources before using it.\n", filehash.SHA3_256)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package winsemaphore provides counted system-wide locks on Windows.
+//
+// A semaphore with a count of N is made up of N named mutexes, which are
+// named by appending "-0" through "-N-1" to the semaphore's name. Taking a
+// slot in the semaphore takes whichever of those mutexes is free.
+//
+// Windows semaphores are not used because a slot that is held by a process
+// when it exits is never returned. Mutexes held by a process that exits are
+// abandoned instead, which frees them for the next holder.
+package winsemaphore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gentlemanautomaton/winobj/winmutex"
+)
+
+// lockPollInterval is how often Lock checks for a free slot while every
+// slot is held.
+const lockPollInterval = 250 * time.Millisecond
+
+// Semaphore is a named system-wide semaphore.
+type Semaphore struct {
+	name  string
+	mutex sync.Mutex
+	slots []*winmutex.Mutex
+	held  []int // Indices of the slots held by this semaphore, in order
+}
+
+// New creates or opens the named system semaphore, which permits up to
+// count holders at once.
+//
+// Every process that shares the semaphore must use the same count.
+func New(name string, count int) (*Semaphore, error) {
+	if count < 1 {
+		return nil, errors.New("a semaphore must have a count of at least one")
+	}
+
+	s := &Semaphore{name: name}
+	for i := range count {
+		slot, err := winmutex.New(slotName(name, i))
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.slots = append(s.slots, slot)
+	}
+
+	return s, nil
+}
+
+// slotName returns the name of the mutex that backs the slot at index i of
+// the named semaphore.
+func slotName(name string, i int) string {
+	return fmt.Sprintf("%s-%d", name, i)
+}
+
+// Name returns the name of the semaphore.
+func (s *Semaphore) Name() string {
+	return s.name
+}
+
+// Lock waits until a slot in the semaphore is available and takes it.
+func (s *Semaphore) Lock() {
+	for !s.TryLock() {
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// TryLock takes a slot in the semaphore if one is available. It returns
+// false if none are.
+//
+// A slot that was abandoned by a process that exited while holding it is
+// considered available.
+func (s *Semaphore) TryLock() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, slot := range s.slots {
+		if s.holds(i) {
+			continue
+		}
+		if slot.TryLock() {
+			s.held = append(s.held, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Unlock releases the slot in the semaphore that was taken most recently.
+// It is a run-time error if no slots are held.
+func (s *Semaphore) Unlock() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.held) == 0 {
+		panic("winsemaphore: Semaphore.Unlock() called on a semaphore that is not locked")
+	}
+
+	last := len(s.held) - 1
+	i := s.held[last]
+	s.held = s.held[:last]
+	s.slots[i].Unlock()
+}
+
+// Close releases any slots held by the semaphore and closes the mutexes
+// that back it.
+func (s *Semaphore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var errs []error
+	for _, slot := range s.slots {
+		if err := slot.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.slots = nil
+	s.held = nil
+
+	return errors.Join(errs...)
+}
+
+// holds returns true if the semaphore holds the slot at index i.
+func (s *Semaphore) holds(i int) bool {
+	for _, held := range s.held {
+		if held == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,451 @@
+package intune
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/gentlemanautomaton/winapp/unpackaged/appscope"
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/idset"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+)
+
+// DetectionScript returns a PowerShell detection script for the given
+// applications within a deployment. The script writes output and exits with
+// a code of zero only when all of the applications are detected, which is
+// the convention expected by Intune. Applications with a target version are
+// only detected when they are installed at or above that version, so that
+// older versions are upgraded.
+//
+// If apps is empty, all of the deployment's applications are included.
+//
+// It returns an error if any of the applications rely on detection rules
+// that cannot be expressed in PowerShell.
+func DetectionScript(dep lbdeploy.Deployment, apps lbdeploy.AppList) (string, error) {
+	if len(apps) == 0 {
+		apps = sortedApps(dep)
+	}
+	if len(apps) == 0 {
+		return "", fmt.Errorf("the \"%s\" deployment does not define any applications", dep.ID)
+	}
+
+	// Build a detection expression for each application.
+	g := generator{deployment: dep}
+	var expressions []string
+	for _, app := range apps {
+		expr, err := g.app(app)
+		if err != nil {
+			return "", fmt.Errorf("app \"%s\": %w", app, err)
+		}
+		expressions = append(expressions, fmt.Sprintf("    # %s\n    %s", app, expr))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# Intune detection script for the \"%s\" LeafBridge deployment.\n", dep.ID)
+	fmt.Fprintf(&out, "# Applications: %s\n", apps)
+	out.WriteString("# This file was generated by leafbridge-deploy. Do not edit it by hand.\n\n")
+	out.WriteString(helperFunctions)
+	out.WriteString("\ntry {\n    $detected = (\n")
+	out.WriteString(strings.Join(expressions, " -and\n"))
+	out.WriteString("\n    )\n} catch {\n    exit 1\n}\n\n")
+	out.WriteString("if ($detected) {\n    Write-Output 'Detected'\n    exit 0\n}\n\nexit 1\n")
+
+	return out.String(), nil
+}
+
+// generator translates LeafBridge detection rules into PowerShell
+// expressions.
+type generator struct {
+	deployment lbdeploy.Deployment
+}
+
+// app returns a PowerShell expression that evaluates to true when the
+// application is installed at or above its target version. If the
+// application does not have a target version, the expression is true when
+// the application is installed at any version.
+func (g generator) app(id lbdeploy.AppID) (string, error) {
+	app, found := g.deployment.Apps[id]
+	if !found {
+		return "", fmt.Errorf("the app is not defined in the \"%s\" deployment", g.deployment.ID)
+	}
+
+	installed, err := g.installed(app)
+	if err != nil {
+		return "", err
+	}
+	if app.TargetVersion == "" {
+		return installed, nil
+	}
+
+	version, err := g.version(app)
+	if err != nil {
+		return "", fmt.Errorf("the app has a target version of %s: %w", app.TargetVersion, err)
+	}
+	return fmt.Sprintf("(%s -and ((Compare-LBVersion ([string]%s) %s) -ge 0))", installed, version, quote(string(app.TargetVersion))), nil
+}
+
+// installed returns a PowerShell expression that evaluates to true when the
+// application is installed at any version.
+func (g generator) installed(app lbdeploy.Application) (string, error) {
+	// Presence conditions take priority, just as they do in the app engine.
+	if app.Detection.Present != "" {
+		return g.condition(app.Detection.Present, make(idset.SetOf[lbdeploy.ConditionID]))
+	}
+
+	// File rules also take priority, just as they do in the app engine.
+	if rule := app.Detection.File; rule.Path != "" {
+		ref, err := g.deployment.Resources.FileSystem.ResolveFile(rule.Path)
+		if err != nil {
+			return "", err
+		}
+		path, err := fileExpression(ref)
+		if err != nil {
+			return "", err
+		}
+		exists := fmt.Sprintf("(Test-Path -LiteralPath %s -PathType Leaf)", path)
+		if rule.MinimumVersion == "" {
+			return exists, nil
+		}
+		return fmt.Sprintf("(%s -and ((Compare-LBVersion (Get-LBFileVersion %s) %s) -ge 0))", exists, path, quote(string(rule.MinimumVersion))), nil
+	}
+
+	if err := registryDetection(app); err != nil {
+		return "", err
+	}
+
+	var tests []string
+	for _, location := range uninstallLocations(app) {
+		tests = append(tests, fmt.Sprintf("(Test-LBRegistryKey %s %s %s)", location.Hive, location.View, quote(uninstallPath(app))))
+	}
+	if len(tests) == 1 {
+		return tests[0], nil
+	}
+	return "(" + strings.Join(tests, " -or ") + ")", nil
+}
+
+// version returns a PowerShell expression that produces the installed
+// version of the application. It is only evaluated once the application is
+// known to be installed, and follows the same order of precedence as the
+// app engine.
+func (g generator) version(app lbdeploy.Application) (string, error) {
+	// A registry value that holds the version takes priority.
+	if app.Detection.Version != "" {
+		ref, err := g.deployment.Resources.Registry.ResolveValue(app.Detection.Version)
+		if err != nil {
+			return "", err
+		}
+		args, err := registryKeyArgs(ref.Key())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(Get-LBRegistryValue %s %s)", args, quote(ref.Name)), nil
+	}
+
+	// The version of a file rule is taken from the file.
+	if rule := app.Detection.File; rule.Path != "" {
+		ref, err := g.deployment.Resources.FileSystem.ResolveFile(rule.Path)
+		if err != nil {
+			return "", err
+		}
+		path, err := fileExpression(ref)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(Get-LBFileVersion %s)", path), nil
+	}
+
+	if app.Detection.Present != "" {
+		return "", fmt.Errorf("the app is detected by a presence condition without a version registry value, so its version cannot be determined")
+	}
+
+	if err := registryDetection(app); err != nil {
+		return "", err
+	}
+
+	var locations []string
+	for _, location := range uninstallLocations(app) {
+		locations = append(locations, quote(location.Hive+" "+location.View))
+	}
+	return fmt.Sprintf("(Get-LBUninstallVersion %s @(%s))", quote(uninstallPath(app)), strings.Join(locations, ", ")), nil
+}
+
+// registryDetection returns an error if the application is not detected by
+// its product code in the uninstall registry views, which is the only
+// registry-based detection that can be expressed in PowerShell.
+func registryDetection(app lbdeploy.Application) error {
+	switch app.Scope {
+	case appscope.Machine, appscope.User, lbdeploy.AnyScope:
+	default:
+		return fmt.Errorf("unrecognized application scope: %s", app.Scope)
+	}
+	switch app.Architecture {
+	case appcode.X64, appcode.X86, lbdeploy.AnyArchitecture:
+	default:
+		return fmt.Errorf("unrecognized application architecture: %s", app.Architecture)
+	}
+
+	switch {
+	case app.Detection.Method != "" && app.Detection.Method != lbdeploy.AppDetectionRegistry:
+		return fmt.Errorf("the \"%s\" app detection method is not supported in detection scripts", app.Detection.Method)
+	case app.Detection.DisplayName != "" || app.Detection.Publisher != "":
+		return fmt.Errorf("detection by display name or publisher is not supported in detection scripts")
+	case app.ProductCode == "":
+		return fmt.Errorf("the app has neither a presence condition nor a product code")
+	}
+	return nil
+}
+
+// uninstallPath returns the path of the application's key within the
+// uninstall registry views.
+func uninstallPath(app lbdeploy.Application) string {
+	return `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall\` + string(app.ProductCode)
+}
+
+// registryLocation identifies a registry hive and view by the names
+// expected by the registry helper functions.
+type registryLocation struct {
+	Hive string
+	View string
+}
+
+// uninstallLocations returns each uninstall registry location that the
+// application may be registered in. It returns nil if the application's
+// scope or architecture is not recognized.
+func uninstallLocations(app lbdeploy.Application) []registryLocation {
+	var hives, views []string
+	switch app.Scope {
+	case appscope.Machine:
+		hives = []string{"LocalMachine"}
+	case appscope.User:
+		hives = []string{"CurrentUser"}
+	case lbdeploy.AnyScope:
+		hives = []string{"LocalMachine", "CurrentUser"}
+	}
+	switch app.Architecture {
+	case appcode.X64:
+		views = []string{"Registry64"}
+	case appcode.X86:
+		views = []string{"Registry32"}
+	case lbdeploy.AnyArchitecture:
+		views = []string{"Registry64", "Registry32"}
+	}
+
+	var locations []registryLocation
+	for _, hive := range hives {
+		for _, view := range views {
+			locations = append(locations, registryLocation{Hive: hive, View: view})
+		}
+	}
+	return locations
+}
+
+// condition returns a PowerShell expression for the identified condition.
+func (g generator) condition(id lbdeploy.ConditionID, seen idset.SetOf[lbdeploy.ConditionID]) (string, error) {
+	if seen.Contains(id) {
+		return "", fmt.Errorf("the \"%s\" condition is recursive", id)
+	}
+	seen.Add(id)
+	defer seen.Remove(id)
+
+	definition, found := g.deployment.Conditions[id]
+	if !found {
+		return "", fmt.Errorf("the \"%s\" condition is not defined in the deployment", id)
+	}
+
+	expr, err := g.expression(definition, seen)
+	if err != nil {
+		return "", fmt.Errorf("condition \"%s\": %w", id, err)
+	}
+	return expr, nil
+}
+
+// expression returns a PowerShell expression for the given condition.
+func (g generator) expression(c lbdeploy.Condition, seen idset.SetOf[lbdeploy.ConditionID]) (string, error) {
+	expr, err := func() (string, error) {
+		switch {
+		case len(c.Any) > 0:
+			return g.join(c.Any, " -or ", seen)
+		case len(c.All) > 0:
+			return g.join(c.All, " -and ", seen)
+		}
+
+		resources := g.deployment.Resources
+		switch c.Type {
+		case lbdeploy.ConditionTypeSubcondition:
+			return g.condition(lbdeploy.ConditionID(c.Subject), seen)
+		case lbdeploy.ConditionTypeProcessIsRunning:
+			process, found := resources.Processes[lbdeploy.ProcessResourceID(c.Subject)]
+			if !found {
+				return "", fmt.Errorf("the \"%s\" process is not defined in the deployment", c.Subject)
+			}
+			predicate, err := processPredicate(process.Match)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(@(Get-LBProcessNames | Where-Object { %s }).Count -gt 0)", predicate), nil
+		case lbdeploy.ConditionTypeRegistryKeyExists:
+			ref, err := resources.Registry.ResolveKey(lbdeploy.RegistryKeyResourceID(c.Subject))
+			if err != nil {
+				return "", err
+			}
+			args, err := registryKeyArgs(ref)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(Test-LBRegistryKey %s)", args), nil
+		case lbdeploy.ConditionTypeRegistryValueExists:
+			ref, err := resources.Registry.ResolveValue(lbdeploy.RegistryValueResourceID(c.Subject))
+			if err != nil {
+				return "", err
+			}
+			args, err := registryKeyArgs(ref.Key())
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(Test-LBRegistryValue %s %s)", args, quote(ref.Name)), nil
+		case lbdeploy.ConditionTypeRegistryValueComparison:
+			ref, err := resources.Registry.ResolveValue(lbdeploy.RegistryValueResourceID(c.Subject))
+			if err != nil {
+				return "", err
+			}
+			args, err := registryKeyArgs(ref.Key())
+			if err != nil {
+				return "", err
+			}
+			value := fmt.Sprintf("(Get-LBRegistryValue %s %s)", args, quote(ref.Name))
+			comparison, err := comparisonExpression(value, ref.Type.Kind(), c.Comparison, c.Value, c.VersionMode)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("((Test-LBRegistryValue %s %s) -and %s)", args, quote(ref.Name), comparison), nil
+		case lbdeploy.ConditionTypeDirectoryExists:
+			ref, err := resources.FileSystem.ResolveDirectory(lbdeploy.DirectoryResourceID(c.Subject))
+			if err != nil {
+				return "", err
+			}
+			path, err := dirExpression(ref)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(Test-Path -LiteralPath %s -PathType Container)", path), nil
+		case lbdeploy.ConditionTypeFileExists:
+			ref, err := resources.FileSystem.ResolveFile(lbdeploy.FileResourceID(c.Subject))
+			if err != nil {
+				return "", err
+			}
+			path, err := fileExpression(ref)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(Test-Path -LiteralPath %s -PathType Leaf)", path), nil
+		default:
+			return "", fmt.Errorf("the \"%s\" condition type cannot be expressed in a detection script", c.Type)
+		}
+	}()
+	if err != nil {
+		return "", err
+	}
+
+	if c.Negated {
+		return fmt.Sprintf("(-not %s)", expr), nil
+	}
+	return expr, nil
+}
+
+// join returns a PowerShell expression that joins the expressions for a set
+// of subconditions with the given operator.
+func (g generator) join(conditions []lbdeploy.Condition, op string, seen idset.SetOf[lbdeploy.ConditionID]) (string, error) {
+	parts := make([]string, 0, len(conditions))
+	for i, subcondition := range conditions {
+		expr, err := g.expression(subcondition, seen)
+		if err != nil {
+			return "", fmt.Errorf("subcondition %d: %w", i, err)
+		}
+		parts = append(parts, expr)
+	}
+	return "(" + strings.Join(parts, op) + ")", nil
+}
+
+// comparisonExpression returns a PowerShell expression that compares the
+// value produced by the given expression against v. Versions are compared
+// in the given mode.
+func comparisonExpression(expr string, kind lbvalue.Kind, comparison lbvalue.Comparison, v lbvalue.Value, mode datatype.VersionMode) (string, error) {
+	var op string
+	switch comparison {
+	case lbvalue.CompareEquals:
+		op = "-eq"
+	case lbvalue.CompareLessThan:
+		op = "-lt"
+	case lbvalue.CompareLessThanOrEquals:
+		op = "-le"
+	case lbvalue.CompareGreaterThan:
+		op = "-gt"
+	case lbvalue.CompareGreaterThanOrEquals:
+		op = "-ge"
+	default:
+		return "", fmt.Errorf("unrecognized comparison operator: %s", comparison)
+	}
+
+	if kind != v.Kind() {
+		return "", lbvalue.ComparisonError{A: kind, B: v.Kind()}
+	}
+
+	switch kind {
+	case lbvalue.KindInt64:
+		return fmt.Sprintf("(([int64]%s).CompareTo([int64]%d) %s 0)", expr, v.Int64(), op), nil
+	case lbvalue.KindString:
+		return fmt.Sprintf("([Math]::Sign([string]::CompareOrdinal([string]%s, %s)) %s 0)", expr, quote(v.String()), op), nil
+	case lbvalue.KindVersion:
+		if mode == datatype.VersionModeSemantic {
+			return fmt.Sprintf("((Compare-LBSemanticVersion ([string]%s) %s) %s 0)", expr, quote(v.String()), op), nil
+		}
+		return fmt.Sprintf("((Compare-LBVersion ([string]%s) %s) %s 0)", expr, quote(v.String()), op), nil
+	default:
+		return "", fmt.Errorf("comparisons of \"%s\" values cannot be expressed in a detection script", kind)
+	}
+}
+
+// processPredicate returns a PowerShell predicate that tests the process
+// name in $_ against the given criteria.
+func processPredicate(match lbdeploy.ProcessMatch) (string, error) {
+	if len(match.Any) > 0 || len(match.All) > 0 {
+		members, op := match.Any, " -or "
+		if len(match.Any) == 0 {
+			members, op = match.All, " -and "
+		}
+		var parts []string
+		for i, submatch := range members {
+			predicate, err := processPredicate(submatch)
+			if err != nil {
+				return "", fmt.Errorf("match %d: %w", i, err)
+			}
+			parts = append(parts, predicate)
+		}
+		return "(" + strings.Join(parts, op) + ")", nil
+	}
+
+	if match.Attribute != lbdeploy.ProcessName {
+		return "", fmt.Errorf("the process attribute \"%s\" is not recognized", match.Attribute)
+	}
+
+	switch match.Type {
+	case lbdeploy.MatchEquals:
+		return fmt.Sprintf("($_ -eq %s)", quote(match.Value)), nil
+	case lbdeploy.MatchContains:
+		return fmt.Sprintf("($_.IndexOf(%s, [StringComparison]::OrdinalIgnoreCase) -ge 0)", quote(match.Value)), nil
+	default:
+		return "", fmt.Errorf("the process match type \"%s\" is not recognized", match.Type)
+	}
+}
+
+// sortedApps returns the IDs of all of the applications in the deployment,
+// sorted in ascending order.
+func sortedApps(dep lbdeploy.Deployment) lbdeploy.AppList {
+	apps := slices.Collect(maps.Keys(dep.Apps))
+	slices.Sort(apps)
+	return apps
+}
@@ -0,0 +1,4 @@
+// Package intune generates Microsoft Intune artifacts from LeafBridge
+// deployments, so that the same configuration can drive both LeafBridge and
+// Intune.
+package intune
@@ -0,0 +1,106 @@
+package intune
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// Manifest describes a Win32 app for Intune that delivers a LeafBridge
+// deployment. It holds the properties that must be provided when the app
+// is packaged and uploaded.
+type Manifest struct {
+	DisplayName          string              `json:"displayName"`
+	Apps                 lbdeploy.AppList    `json:"apps"`
+	SetupFile            string              `json:"setupFile"`
+	InstallCommandLine   string              `json:"installCommandLine"`
+	UninstallCommandLine string              `json:"uninstallCommandLine,omitempty"`
+	InstallExperience    string              `json:"installExperience"`
+	DetectionRules       []ManifestDetection `json:"detectionRules"`
+}
+
+// ManifestDetection is a detection rule within an Intune manifest.
+type ManifestDetection struct {
+	Type                  string `json:"type"`
+	ScriptFile            string `json:"scriptFile"`
+	EnforceSignatureCheck bool   `json:"enforceSignatureCheck"`
+	RunAs32Bit            bool   `json:"runAs32Bit"`
+}
+
+// ManifestOptions are options used to build an Intune manifest.
+type ManifestOptions struct {
+	// Apps are the applications that are detected by the script.
+	Apps lbdeploy.AppList
+
+	// ConfigFile is the name of the deployment file within the package.
+	ConfigFile string
+
+	// InstallFlow is the flow invoked when the app is installed.
+	InstallFlow lbdeploy.FlowID
+
+	// UninstallFlow is the flow invoked when the app is uninstalled. It may
+	// be empty.
+	UninstallFlow lbdeploy.FlowID
+
+	// DetectionScript is the name of the detection script file.
+	DetectionScript string
+}
+
+// BuildManifest returns an Intune Win32 app manifest for the deployment.
+func BuildManifest(dep lbdeploy.Deployment, opts ManifestOptions) (Manifest, error) {
+	if _, found := dep.Flows[opts.InstallFlow]; !found {
+		return Manifest{}, fmt.Errorf("the \"%s\" install flow is not defined in the deployment", opts.InstallFlow)
+	}
+	if opts.UninstallFlow != "" {
+		if _, found := dep.Flows[opts.UninstallFlow]; !found {
+			return Manifest{}, fmt.Errorf("the \"%s\" uninstall flow is not defined in the deployment", opts.UninstallFlow)
+		}
+	}
+
+	apps := opts.Apps
+	if len(apps) == 0 {
+		apps = sortedApps(dep)
+	}
+
+	name := dep.Name
+	if name == "" {
+		name = string(dep.ID)
+	}
+
+	manifest := Manifest{
+		DisplayName:        name,
+		Apps:               apps,
+		SetupFile:          executable,
+		InstallCommandLine: commandLine(opts.ConfigFile, opts.InstallFlow),
+		InstallExperience:  "system",
+		DetectionRules: []ManifestDetection{
+			{
+				Type:       "script",
+				ScriptFile: opts.DetectionScript,
+			},
+		},
+	}
+	if opts.UninstallFlow != "" {
+		manifest.UninstallCommandLine = commandLine(opts.ConfigFile, opts.UninstallFlow)
+	}
+
+	return manifest, nil
+}
+
+// executable is the name of the LeafBridge executable within an Intune
+// package.
+const executable = "leafbridge-deploy.exe"
+
+// commandLine returns a command line that invokes the given flow.
+func commandLine(configFile string, flow lbdeploy.FlowID) string {
+	return fmt.Sprintf("%s deploy --config-file %s --flow %s", executable, argument(configFile), argument(string(flow)))
+}
+
+// argument quotes s if it contains spaces.
+func argument(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
@@ -0,0 +1,244 @@
+package intune
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows/registry"
+)
+
+// quote returns s as a single-quoted PowerShell string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// backslashes converts forward slashes in a relative path to backslashes.
+func backslashes(path string) string {
+	return strings.ReplaceAll(path, "/", `\`)
+}
+
+// knownFolderExpressions maps known folders to PowerShell expressions that
+// produce their paths on the machine running the script.
+var knownFolderExpressions = map[lbdeploy.DirectoryResourceID]string{
+	"windows":                  `$env:SystemRoot`,
+	"system":                   `(Join-Path $env:SystemRoot 'System32')`,
+	"system-x86":               `(Join-Path $env:SystemRoot 'SysWOW64')`,
+	"fonts":                    `(Join-Path $env:SystemRoot 'Fonts')`,
+	"program-data":             `$env:ProgramData`,
+	"program-files":            `$env:ProgramFiles`,
+	"program-files-x86":        `${env:ProgramFiles(x86)}`,
+	"program-files-x64":        `$env:ProgramW6432`,
+	"program-files-common":     `$env:CommonProgramFiles`,
+	"program-files-common-x86": `${env:CommonProgramFiles(x86)}`,
+	"program-files-common-x64": `$env:CommonProgramW6432`,
+	"common-start-menu":        `(Join-Path $env:ProgramData 'Microsoft\Windows\Start Menu')`,
+	"common-programs":          `(Join-Path $env:ProgramData 'Microsoft\Windows\Start Menu\Programs')`,
+	"common-startup":           `(Join-Path $env:ProgramData 'Microsoft\Windows\Start Menu\Programs\Startup')`,
+	"common-templates":         `(Join-Path $env:ProgramData 'Microsoft\Windows\Templates')`,
+	"public":                   `$env:PUBLIC`,
+	"public-desktop":           `(Join-Path $env:PUBLIC 'Desktop')`,
+	"public-documents":         `(Join-Path $env:PUBLIC 'Documents')`,
+	"public-downloads":         `(Join-Path $env:PUBLIC 'Downloads')`,
+	"user-profiles":            `(Join-Path $env:SystemDrive 'Users')`,
+	"profile":                  `$env:USERPROFILE`,
+	"desktop":                  `[Environment]::GetFolderPath('Desktop')`,
+	"documents":                `[Environment]::GetFolderPath('MyDocuments')`,
+	"downloads":                `(Join-Path $env:USERPROFILE 'Downloads')`,
+	"roaming-app-data":         `$env:APPDATA`,
+	"local-app-data":           `$env:LOCALAPPDATA`,
+	"local-app-data-low":       `(Join-Path $env:USERPROFILE 'AppData\LocalLow')`,
+	"start-menu":               `[Environment]::GetFolderPath('StartMenu')`,
+	"programs":                 `[Environment]::GetFolderPath('Programs')`,
+	"startup":                  `[Environment]::GetFolderPath('Startup')`,
+	"user-program-files":       `(Join-Path $env:LOCALAPPDATA 'Programs')`,
+}
+
+// dirExpression returns a PowerShell expression that produces the path of
+// the given directory reference.
+func dirExpression(ref lbdeploy.DirRef) (string, error) {
+	var root string
+	if !ref.Network.IsZero() {
+		if ref.Network.Credential() != "" {
+			return "", fmt.Errorf("the \"%s\" network share requires credentials, which are not supported in detection scripts", ref.Network.ID())
+		}
+		root = quote(ref.Network.Path())
+	} else {
+		expr, ok := knownFolderExpressions[ref.Root.ID()]
+		if !ok {
+			return "", fmt.Errorf("the \"%s\" known folder is not supported in detection scripts", ref.Root.ID())
+		}
+		root = expr
+	}
+
+	var parts []string
+	for _, dir := range ref.Lineage {
+		parts = append(parts, backslashes(dir.Path))
+	}
+	if len(parts) == 0 {
+		return root, nil
+	}
+
+	return fmt.Sprintf("(Join-Path %s %s)", root, quote(strings.Join(parts, `\`))), nil
+}
+
+// fileExpression returns a PowerShell expression that produces the path of
+// the given file reference.
+func fileExpression(ref lbdeploy.FileRef) (string, error) {
+	dir, err := dirExpression(ref.Dir())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(Join-Path %s %s)", dir, quote(backslashes(ref.FilePath))), nil
+}
+
+// registryKeyArgs returns the hive, view and path arguments expected by the
+// registry helper functions for the given registry key reference.
+func registryKeyArgs(ref lbdeploy.RegistryKeyRef) (string, error) {
+	var hive string
+	switch ref.Root.Key() {
+	case registry.LOCAL_MACHINE:
+		hive = "LocalMachine"
+	case registry.CURRENT_USER:
+		hive = "CurrentUser"
+	case registry.USERS:
+		hive = "Users"
+	case registry.CLASSES_ROOT:
+		hive = "ClassesRoot"
+	default:
+		return "", fmt.Errorf("the \"%s\" registry root is not supported in detection scripts", ref.Root.ID())
+	}
+
+	var parts []string
+	if path := ref.Root.Path(); path != "" {
+		parts = append(parts, path)
+	}
+	for _, key := range ref.Lineage {
+		switch {
+		case key.Name != "":
+			parts = append(parts, key.Name)
+		case key.Path != "":
+			parts = append(parts, backslashes(key.Path))
+		default:
+			return "", fmt.Errorf("a registry key resource does not specify a name or path")
+		}
+	}
+
+	view, err := ref.View()
+	if err != nil {
+		return "", err
+	}
+	regView := "Registry64"
+	if view == lbdeploy.RegistryView32 {
+		regView = "Registry32"
+	}
+
+	return fmt.Sprintf("%s %s %s", hive, regView, quote(strings.Join(parts, `\`))), nil
+}
+
+// helperFunctions are PowerShell functions that generated expressions rely
+// on. Registry access goes through explicit registry views so that the
+// results are the same in 32-bit and 64-bit PowerShell processes.
+const helperFunctions = `function Get-LBRegistryKey([string]$Hive, [string]$View, [string]$Path) {
+    $base = [Microsoft.Win32.RegistryKey]::OpenBaseKey([Microsoft.Win32.RegistryHive]::$Hive, [Microsoft.Win32.RegistryView]::$View)
+    return $base.OpenSubKey($Path)
+}
+
+function Test-LBRegistryKey([string]$Hive, [string]$View, [string]$Path) {
+    $key = Get-LBRegistryKey $Hive $View $Path
+    if ($null -eq $key) { return $false }
+    $key.Close()
+    return $true
+}
+
+function Test-LBRegistryValue([string]$Hive, [string]$View, [string]$Path, [string]$Name) {
+    $key = Get-LBRegistryKey $Hive $View $Path
+    if ($null -eq $key) { return $false }
+    try { return ($key.GetValueNames() -contains $Name) } finally { $key.Close() }
+}
+
+function Get-LBRegistryValue([string]$Hive, [string]$View, [string]$Path, [string]$Name) {
+    $key = Get-LBRegistryKey $Hive $View $Path
+    if ($null -eq $key) { throw "The registry key $Path does not exist." }
+    try { return $key.GetValue($Name) } finally { $key.Close() }
+}
+
+function Get-LBUninstallVersion([string]$Path, [string[]]$Locations) {
+    foreach ($location in $Locations) {
+        $hive, $view = $location -split ' '
+        $key = Get-LBRegistryKey $hive $view $Path
+        if ($null -eq $key) { continue }
+        try { return [string]$key.GetValue('DisplayVersion') } finally { $key.Close() }
+    }
+    return ''
+}
+
+function Get-LBFileVersion([string]$Path) {
+    $info = [System.Diagnostics.FileVersionInfo]::GetVersionInfo($Path)
+    return ('{0}.{1}.{2}.{3}' -f $info.FileMajorPart, $info.FileMinorPart, $info.FileBuildPart, $info.FilePrivatePart)
+}
+
+function Get-LBProcessNames {
+    return @(Get-CimInstance -ClassName Win32_Process | ForEach-Object { $_.Name })
+}
+
+function Compare-LBVersionSegment([string]$A, [string]$B) {
+    $x = [uint64]0
+    $y = [uint64]0
+    if ([uint64]::TryParse($A, [ref]$x) -and [uint64]::TryParse($B, [ref]$y)) {
+        return $x.CompareTo($y)
+    }
+    if ($A.Length -ne $B.Length) { return $A.Length.CompareTo($B.Length) }
+    return [Math]::Sign([string]::CompareOrdinal($A, $B))
+}
+
+function Get-LBVersionSegments([string]$Version) {
+    if ($Version.Length -gt 1 -and ($Version[0] -eq 'v' -or $Version[0] -eq 'V')) { $Version = $Version.Substring(1) }
+    if ($Version -eq '') { return ,@() }
+    $segments = $Version.Split('.')
+    if ($segments[-1] -eq '') { $segments = $segments[0..($segments.Length - 2)] }
+    return ,@($segments)
+}
+
+function Compare-LBVersion([string]$A, [string]$B) {
+    $s1 = Get-LBVersionSegments $A
+    $s2 = Get-LBVersionSegments $B
+    for ($i = 0; $i -lt [Math]::Max($s1.Length, $s2.Length); $i++) {
+        if ($i -ge $s1.Length) { return -1 }
+        if ($i -ge $s2.Length) { return 1 }
+        $result = Compare-LBVersionSegment $s1[$i] $s2[$i]
+        if ($result -ne 0) { return $result }
+    }
+    return 0
+}
+
+function Compare-LBPreReleaseIdentifier([string]$A, [string]$B) {
+    $x = [uint64]0
+    $y = [uint64]0
+    $n1 = [uint64]::TryParse($A, [ref]$x)
+    $n2 = [uint64]::TryParse($B, [ref]$y)
+    if ($n1 -and $n2) { return $x.CompareTo($y) }
+    if ($n1) { return -1 }
+    if ($n2) { return 1 }
+    return [Math]::Sign([string]::CompareOrdinal($A, $B))
+}
+
+function Compare-LBSemanticVersion([string]$A, [string]$B) {
+    $v1 = $A.Split('+')[0].Split('-', 2)
+    $v2 = $B.Split('+')[0].Split('-', 2)
+    $result = Compare-LBVersion $v1[0] $v2[0]
+    if ($result -ne 0) { return $result }
+    if ($v1.Length -eq 1 -and $v2.Length -eq 1) { return 0 }
+    if ($v1.Length -eq 1) { return 1 }
+    if ($v2.Length -eq 1) { return -1 }
+    $p1 = $v1[1].Split('.')
+    $p2 = $v2[1].Split('.')
+    for ($i = 0; $i -lt [Math]::Max($p1.Length, $p2.Length); $i++) {
+        if ($i -ge $p1.Length) { return -1 }
+        if ($i -ge $p2.Length) { return 1 }
+        $result = Compare-LBPreReleaseIdentifier $p1[$i] $p2[$i]
+        if ($result -ne 0) { return $result }
+    }
+    return 0
+}
+`
@@ -1,28 +1,154 @@
 package lbdeploy
 
+import (
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+)
+
 // ActionType identifies the type of action.
 type ActionType string
 
 // Recognized action types.
 const (
-	ActionStartFlow      ActionType = "start-flow"
-	ActionPreparePackage ActionType = "prepare-package"
-	ActionInvokeCommand  ActionType = "invoke-command"
-	ActionCopyFile       ActionType = "copy-file"
-	ActionDeleteFile     ActionType = "delete-file"
+	ActionStartFlow           ActionType = "start-flow"
+	ActionPreparePackage      ActionType = "prepare-package"
+	ActionInvokeCommand       ActionType = "invoke-command"
+	ActionCopyFile            ActionType = "copy-file"
+	ActionDeleteFile          ActionType = "delete-file"
+	ActionTransaction         ActionType = "transaction"
+	ActionStopProcesses       ActionType = "stop-processes"
+	ActionApplyUserSettings   ActionType = "apply-user-settings"
+	ActionPromptDeferral      ActionType = "prompt-deferral"
+	ActionSetRegistryValue    ActionType = "set-registry-value"
+	ActionDeleteRegistryValue ActionType = "delete-registry-value"
+	ActionWaitRegistryValue   ActionType = "wait-for-registry-value"
+	ActionCreateHardLink      ActionType = "create-hard-link"
+	ActionCreateJunction      ActionType = "create-junction"
+	ActionInstallDriver       ActionType = "install-driver"
 )
 
+// Resumable reports whether an action of this type can be skipped when a
+// flow is resumed after a previous invocation completed it.
+//
+// Actions that make lasting changes to the system, such as invoking a
+// command, copying a file or setting a registry value, are resumable. Their
+// effects survive the previous invocation, and an action that has run once
+// doesn't need to run again.
+//
+// Actions that prepare for or check on the state of the system at the time
+// they run are not resumable, because that state may have changed since the
+// previous invocation. Preparing a package, stopping processes, prompting
+// for deferral and waiting for a registry value are always invoked again,
+// as are flows started by an action, so that they can resume from their
+// own checkpoints.
+func (t ActionType) Resumable() bool {
+	switch t {
+	case ActionStartFlow, ActionPreparePackage, ActionStopProcesses, ActionPromptDeferral, ActionWaitRegistryValue:
+		return false
+	default:
+		return true
+	}
+}
+
 // Action describes an action to be taken as part of a flow.
+//
+// ID optionally identifies the action within its flow, so that it can be
+// referred to by name instead of by its position. IDs appear in events and
+// results, and may be used in action references on the command line.
+//
+// Description is a short, human-readable name for the action, such as
+// "Install the client". It is included in the events recorded for the
+// action.
+//
+// ExpectedDuration is how long the action is expected to take. If it is
+// still running after that long, a warning is recorded, which can reveal a
+// stuck installer well before any timeout would.
+//
+// DependsOn lists the IDs of earlier actions in the same flow that must
+// have completed before the action is invoked. If any of them did not
+// complete, the action is skipped. Only before, main and after actions may
+// have dependencies.
+//
+// Force causes an invoke-command action to run its command even when the
+// apps it installs or uninstalls are already in the desired state. It
+// causes an install-driver action to install its driver even on devices
+// whose current driver outranks it.
+//
+// Rollback holds compensating actions that undo the effects of the action.
+// When a flow with rollback or retry-flow behavior encounters an error, the
+// rollback actions of each previously completed action are invoked in
+// reverse order. Rollback actions may also be mapped to the action's ID by
+// its flow.
+//
+// Actions holds the members of a transaction action. Changes made by the
+// members are committed together, or undone if any of them fail. Members
+// may copy or delete files, or set or delete registry values.
+//
+// Processes holds the process resources that are terminated by a
+// stop-processes action.
+//
+// UserSettings holds the per-user configuration that is applied to every
+// user profile by an apply-user-settings action.
+//
+// OnLocked determines what a copy-file or delete-file action does when the
+// file is held open by another process.
+//
+// A copy-file action leaves an existing destination file in place unless
+// Overwrite is true. If Backup is also true, the original file is copied to
+// a timestamped backup file in the deployment's staging directory before it
+// is replaced.
+//
+// A create-hard-link action creates a hard link at DestinationFile that
+// refers to SourceFile, which must be on the same volume. A create-junction
+// action creates a directory junction at DestinationDir that refers to
+// SourceDir. Neither may refer to a protected location.
+//
+// RegistryValue identifies the registry value resource that is written by
+// a set-registry-value action, or removed by a delete-registry-value
+// action. Value holds the data that is written, and must match the type of
+// the registry value resource. Before the registry value is
+// changed, its key is exported to a backup file in the deployment's
+// staging directory.
+//
+// An install-driver action adds a driver package to the driver store and
+// installs it on the devices present that it supports. Package identifies
+// an archive package, and DriverFile identifies the INF file within it that
+// describes the driver. The driver is only installed on devices for which
+// it outranks the current driver, as determined by Plug and Play. Once it
+// has been installed, the driver package is verified to be present in the
+// driver store, and any restart that it requires is handled according to
+// the deployment's reboot policy.
+//
+// A wait-for-registry-value action waits for the registry value to appear.
+// If Value is provided, it waits until applying Comparison to the registry
+// value and Value is true instead. It fails if Timeout elapses first, or
+// after ten minutes if Timeout is not provided.
 type Action struct {
-	Type            ActionType          `json:"action"`
-	Package         PackageID           `json:"package,omitempty"`
-	Command         CommandID           `json:"command,omitempty"`
-	Force           bool                `json:"force,omitempty"`
-	Flow            FlowID              `json:"flow,omitempty"`
-	SourceFile      FileResourceID      `json:"source-file,omitempty"`
-	SourceDir       DirectoryResourceID `json:"source-directory,omitempty"`
-	DestinationFile FileResourceID      `json:"destination-file,omitempty"`
-	DestinationDir  DirectoryResourceID `json:"destination-directory,omitempty"`
+	ID               ActionID                `json:"id,omitempty"`
+	Type             ActionType              `json:"action"`
+	Description      string                  `json:"description,omitempty"`
+	ExpectedDuration datatype.Duration       `json:"expected-duration,omitempty"`
+	DependsOn        []ActionID              `json:"depends-on,omitzero"`
+	Package          PackageID               `json:"package,omitempty"`
+	Command          CommandID               `json:"command,omitempty"`
+	DriverFile       PackageFileID           `json:"driver-file,omitempty"`
+	Force            bool                    `json:"force,omitempty"`
+	Flow             FlowID                  `json:"flow,omitempty"`
+	SourceFile       FileResourceID          `json:"source-file,omitempty"`
+	SourceDir        DirectoryResourceID     `json:"source-directory,omitempty"`
+	DestinationFile  FileResourceID          `json:"destination-file,omitempty"`
+	DestinationDir   DirectoryResourceID     `json:"destination-directory,omitempty"`
+	Actions          []Action                `json:"actions,omitzero"`
+	Processes        []ProcessResourceID     `json:"processes,omitzero"`
+	UserSettings     UserSettings            `json:"user-settings,omitzero"`
+	OnLocked         FileLockAction          `json:"on-locked,omitempty"`
+	Overwrite        bool                    `json:"overwrite,omitempty"`
+	Backup           bool                    `json:"backup,omitempty"`
+	RegistryValue    RegistryValueResourceID `json:"registry-value,omitempty"`
+	Value            lbvalue.Value           `json:"value,omitzero"`
+	Comparison       lbvalue.Comparison      `json:"comparison,omitzero"`
+	Timeout          datatype.Duration       `json:"timeout,omitempty"`
+	Rollback         []Action                `json:"rollback,omitzero"`
 }
 
 /*
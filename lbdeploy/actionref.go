@@ -0,0 +1,113 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ActionID is an optional identifier for an action that is unique within
+// its flow.
+type ActionID string
+
+// Validate returns a non-nil error if the action ID is not valid. Action
+// IDs may not be numbers or contain colons, so that they can't be confused
+// with action numbers in action references.
+func (id ActionID) Validate() error {
+	if id == "" {
+		return errors.New("the action ID is empty")
+	}
+	if strings.Contains(string(id), ":") {
+		return fmt.Errorf("the action ID \"%s\" contains a colon", id)
+	}
+	if _, err := strconv.Atoi(string(id)); err == nil {
+		return fmt.Errorf("the action ID \"%s\" is a number", id)
+	}
+	return nil
+}
+
+// ActionRef refers to an action within a flow by its ID or its position.
+//
+// Actions are numbered from one across the flow's before, main and after
+// actions, in that order, which matches the numbering used in event
+// messages. The members of a transaction share the number of the
+// transaction action.
+type ActionRef struct {
+	Flow  FlowID
+	ID    ActionID
+	Index int // Zero-based, and only used when ID is empty
+}
+
+// ParseActionRef parses an action reference in the form "flow:number" or
+// "flow:id".
+func ParseActionRef(s string) (ActionRef, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return ActionRef{}, fmt.Errorf("the action reference \"%s\" must take the form flow:number or flow:id", s)
+	}
+	flow, action := FlowID(s[:i]), s[i+1:]
+	if n, err := strconv.Atoi(action); err == nil {
+		if n < 1 {
+			return ActionRef{}, fmt.Errorf("the action reference \"%s\" has an invalid action number", s)
+		}
+		return ActionRef{Flow: flow, Index: n - 1}, nil
+	}
+	return ActionRef{Flow: flow, ID: ActionID(action)}, nil
+}
+
+// String returns the action reference in the form "flow:number" or
+// "flow:id".
+func (ref ActionRef) String() string {
+	if ref.ID != "" {
+		return fmt.Sprintf("%s:%s", ref.Flow, ref.ID)
+	}
+	return fmt.Sprintf("%s:%d", ref.Flow, ref.Index+1)
+}
+
+// UnmarshalText parses text as an action reference.
+func (ref *ActionRef) UnmarshalText(text []byte) error {
+	parsed, err := ParseActionRef(string(text))
+	if err != nil {
+		return err
+	}
+	*ref = parsed
+	return nil
+}
+
+// MarshalText returns the action reference in the form "flow:number" or
+// "flow:id".
+func (ref ActionRef) MarshalText() ([]byte, error) {
+	return []byte(ref.String()), nil
+}
+
+// Matches returns true if the reference refers to the given action, which
+// is at index within flow.
+func (ref ActionRef) Matches(flow FlowID, index int, action Action) bool {
+	if ref.Flow != flow {
+		return false
+	}
+	if ref.ID != "" {
+		return ref.ID == action.ID
+	}
+	return ref.Index == index
+}
+
+// Validate returns a non-nil error if the action reference does not refer
+// to an action within the given flows.
+func (ref ActionRef) Validate(flows FlowMap) error {
+	flow, found := flows[ref.Flow]
+	if !found {
+		return fmt.Errorf("the action reference \"%s\" refers to a flow that does not exist", ref)
+	}
+	if ref.ID != "" {
+		if _, found := flow.FindAction(ref.ID); !found {
+			return fmt.Errorf("the action reference \"%s\" refers to an action that does not exist", ref)
+		}
+		return nil
+	}
+	if total := flow.ActionCount(); ref.Index >= total {
+		return fmt.Errorf("the action reference \"%s\" is out of range, because the \"%s\" flow has %d actions", ref, ref.Flow, total)
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package lbdeploy
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/gentlemanautomaton/winapp/appcode"
 	"github.com/gentlemanautomaton/winapp/unpackaged"
 	"github.com/gentlemanautomaton/winapp/unpackaged/appscope"
+	"github.com/leafbridge/leafbridge-deploy/datatype"
 )
 
 // AppMap holds a set of applications mapped by their identifiers.
@@ -55,32 +58,117 @@ type ProductCode = unpackaged.AppID
 //
 // If it defines an architecture, scope and unpackaged app ID, these will be
 // used to determine if the application is installed in the Windows app
-// registry.
+// registry. The architecture and scope may be "any", in which case every
+// matching view of the registry is checked.
 //
 // Alternatively, a condition may be specified that determines whether the
 // application is installed.
+//
+// Processes lists the processes that belong to the application. They are
+// stopped before the application is uninstalled by a generated uninstall
+// flow.
+//
+// If a target version is supplied, an install of the application is only
+// considered to be in effect when the installed version is at least the
+// target version. Older versions will be upgraded.
 type Application struct {
-	Name         string               `json:"name"`
-	Architecture appcode.Architecture `json:"architecture,omitempty"`
-	Scope        appscope.Scope       `json:"scope,omitempty"`
-	ProductCode  ProductCode          `json:"product-code,omitempty"`
-	Detection    AppDetection         `json:"detection,omitempty"`
+	Name          string               `json:"name"`
+	Architecture  appcode.Architecture `json:"architecture,omitempty"`
+	Scope         appscope.Scope       `json:"scope,omitempty"`
+	ProductCode   ProductCode          `json:"product-code,omitempty"`
+	TargetVersion datatype.Version     `json:"target-version,omitempty"`
+	Detection     AppDetection         `json:"detection,omitempty"`
+	Processes     []ProcessResourceID  `json:"processes,omitzero"`
 }
 
+// Wildcard values for the architecture and scope of an application.
+const (
+	AnyArchitecture appcode.Architecture = "any"
+	AnyScope        appscope.Scope       = "any"
+)
+
 // AppDetection describes how to detect the presence of an installed
 // application and how to determine what version is installed.
+//
+// Method selects the source used to look up applications by product code.
+// It has no effect when a presence condition or version registry value is
+// supplied.
+//
+// DisplayName and Publisher allow applications without a stable product
+// code to be found in the uninstall registry view by name. They may contain
+// the wildcards * and ?, and are matched without regard to case. When more
+// than one entry matches, the one with the highest version is used.
+//
+// File allows applications that don't register themselves with the
+// operating system to be detected by the presence of a file. It takes
+// priority over every other rule except a presence condition.
 type AppDetection struct {
-	Present ConditionID             `json:"present,omitempty"`
-	Version RegistryValueResourceID `json:"version,omitempty"`
+	Method      AppDetectionMethod      `json:"method,omitempty"`
+	Present     ConditionID             `json:"present,omitempty"`
+	Version     RegistryValueResourceID `json:"version,omitempty"`
+	DisplayName string                  `json:"display-name,omitempty"`
+	Publisher   string                  `json:"publisher,omitempty"`
+	File        AppFileRule             `json:"file,omitzero"`
 }
 
+// AppFileRule detects an application by the presence of a file on the local
+// system.
+//
+// If a minimum version is supplied, the application is only considered
+// installed when the version resource of the file is at least that version.
+// The version of the application is taken from the file's version resource.
+type AppFileRule struct {
+	Path           FileResourceID   `json:"path,omitempty"`
+	MinimumVersion datatype.Version `json:"minimum-version,omitempty"`
+}
+
+// AppDetectionMethod identifies a source of information about installed
+// applications.
+type AppDetectionMethod string
+
+// Recognized app detection methods.
+const (
+	// AppDetectionRegistry looks up applications in the uninstall registry
+	// view that matches the application's architecture and scope. It is the
+	// default.
+	AppDetectionRegistry AppDetectionMethod = "registry"
+
+	// AppDetectionMSI queries the Windows Installer for the application's
+	// product code. Only products that are fully installed in the
+	// application's scope are considered present. Advertised products are
+	// not.
+	AppDetectionMSI AppDetectionMethod = "msi"
+)
+
 // AppEvaluation is an evaluation of potential changes to the set of installed
 // applications.
+//
+// ToUpgrade holds the members of ToInstall that are already installed, but
+// at a version older than their target version.
 type AppEvaluation struct {
 	AlreadyInstalled   AppList
 	AlreadyUninstalled AppList
 	ToInstall          AppList
+	ToUpgrade          AppList
 	ToUninstall        AppList
+	Locations          AppLocationMap
+}
+
+// AppLocationMap records where installed applications were found on the
+// local system, such as "64-bit machine" or "32-bit user".
+type AppLocationMap map[AppID]string
+
+// String returns a string representation of the map, sorted by app ID.
+func (m AppLocationMap) String() string {
+	apps := slices.Sorted(maps.Keys(m))
+	var out strings.Builder
+	for i, app := range apps {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		fmt.Fprintf(&out, "%s: %s", app, m[app])
+	}
+	return out.String()
 }
 
 // IsZero returns true if the app evaluation is empty.
@@ -137,6 +225,16 @@ func (s AppSummary) IsZero() bool {
 	return true
 }
 
+// Add returns the combination of s and other.
+func (s AppSummary) Add(other AppSummary) AppSummary {
+	return AppSummary{
+		Installed:           append(slices.Clip(s.Installed), other.Installed...),
+		Uninstalled:         append(slices.Clip(s.Uninstalled), other.Uninstalled...),
+		StillNotInstalled:   append(slices.Clip(s.StillNotInstalled), other.StillNotInstalled...),
+		StillNotUninstalled: append(slices.Clip(s.StillNotUninstalled), other.StillNotUninstalled...),
+	}
+}
+
 // Err returns a non-nil error if any of the expected application changes did
 // not take effect.
 func (s AppSummary) Err() error {
@@ -1,5 +1,7 @@
 package lbdeploy
 
+import "github.com/leafbridge/leafbridge-deploy/datatype"
+
 // OnErrorBehavior identifies a response to take when an error is encountered.
 type OnErrorBehavior string
 
@@ -8,11 +10,53 @@ const (
 	OnErrorUnspecified OnErrorBehavior = ""
 	OnErrorStop        OnErrorBehavior = "stop"
 	OnErrorContinue    OnErrorBehavior = "continue"
+	OnErrorRollback    OnErrorBehavior = "rollback"
+	OnErrorRetryFlow   OnErrorBehavior = "retry-flow"
 )
 
 // Behavior describes behavior modifications for a deployment or flow.
+//
+// Timeout limits the amount of time that a flow may run once it has
+// started. When specified for a deployment, it applies to each of its flows
+// that do not specify their own.
+//
+// Retries and RetryDelay apply when OnError is retry-flow. A flow that fails
+// is invoked again, up to Retries more times, after waiting for RetryDelay.
+//
+// MaintenanceWindows restricts the invocation of commands to the given
+// windows of local time. Actions that only download or prepare packages
+// are not restricted. When a command is due outside of every window, the
+// flow pauses until one opens.
+//
+// InstallerWait is the maximum amount of time to wait for the Windows
+// Installer to become available before invoking an MSI command, when
+// Windows Update or another installation is already in progress. If it is
+// zero, a default of 15 minutes is used. If it is negative, MSI commands
+// do not wait.
+//
+// Proxy determines how package downloads reach their servers. When it is
+// specified for a flow, it replaces the proxy settings of the deployment.
+//
+// SourceProbe enables probing of package sources before each download. The
+// http, azure-blob and s3 sources that share a priority are sent a small
+// request, and are then attempted in order of how quickly they responded.
+// Sources that fail to respond within SourceProbe are attempted last. If it
+// is zero or negative, sources are not probed, which allows a flow to turn
+// off probing that its deployment enables.
+//
+// DownloadRetry determines whether and when package downloads are
+// attempted again after they fail. When it is specified for a flow, it
+// replaces the retry policy of the deployment.
 type Behavior struct {
-	OnError OnErrorBehavior `json:"on-error,omitempty"`
+	OnError            OnErrorBehavior     `json:"on-error,omitempty"`
+	Timeout            datatype.Duration   `json:"timeout,omitempty"`
+	Retries            int                 `json:"retries,omitempty"`
+	RetryDelay         datatype.Duration   `json:"retry-delay,omitempty"`
+	MaintenanceWindows MaintenanceWindows  `json:"maintenance-windows,omitzero"`
+	InstallerWait      datatype.Duration   `json:"installer-wait,omitempty"`
+	Proxy              ProxySettings       `json:"proxy,omitzero"`
+	SourceProbe        datatype.Duration   `json:"source-probe,omitempty"`
+	DownloadRetry      DownloadRetryPolicy `json:"download-retry,omitzero"`
 }
 
 // OverlayBehavior overlays the given set of behaviors, giving priority
@@ -23,6 +67,30 @@ func OverlayBehavior(behaviors ...Behavior) Behavior {
 		if next.OnError != OnErrorUnspecified {
 			out.OnError = next.OnError
 		}
+		if next.Timeout != 0 {
+			out.Timeout = next.Timeout
+		}
+		if next.Retries != 0 {
+			out.Retries = next.Retries
+		}
+		if next.RetryDelay != 0 {
+			out.RetryDelay = next.RetryDelay
+		}
+		if next.MaintenanceWindows != nil {
+			out.MaintenanceWindows = next.MaintenanceWindows
+		}
+		if next.InstallerWait != 0 {
+			out.InstallerWait = next.InstallerWait
+		}
+		if !next.Proxy.IsZero() {
+			out.Proxy = next.Proxy
+		}
+		if next.SourceProbe != 0 {
+			out.SourceProbe = next.SourceProbe
+		}
+		if !next.DownloadRetry.IsZero() {
+			out.DownloadRetry = next.DownloadRetry
+		}
 	}
 	return out
 }
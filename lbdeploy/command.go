@@ -2,9 +2,14 @@ package lbdeploy
 
 import (
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 )
 
 // CommandType identifies the type of a command.
@@ -17,12 +22,23 @@ const (
 	CommandTypeMSIUpdate               = "msi-update"
 	CommandTypeMSIUninstall            = "msi-uninstall"
 	CommandTypeMSIUninstallProductCode = "msi-uninstall-product-code"
+	CommandTypeUninstallString         = "uninstall-string"
 )
 
 // IsAppBased returns true if the command applies to an application's product
-// code, and not to a provided executable or installer file.
+// code or registration, and not to a provided executable or installer file.
+//
+// Commands of the uninstall-string type run the QuietUninstallString or
+// UninstallString recorded in the application's registry entry. Any
+// arguments supplied with the command are appended to it, which allows
+// extra silent switches to be provided.
 func (t CommandType) IsAppBased() bool {
-	return t == CommandTypeMSIUninstallProductCode
+	switch t {
+	case CommandTypeMSIUninstallProductCode, CommandTypeUninstallString:
+		return true
+	default:
+		return false
+	}
 }
 
 // IsMSI returns true if the command invokes msiexec.
@@ -44,12 +60,45 @@ type CommandID string
 
 // ExecutableID is either a FileResourceID or a PackageFileID, depending on
 // whether the command is a regular command or a package command.
+//
+// A regular command may instead refer to a program by name, such as
+// "program:dism.exe", which is found through the PATH environment variable
+// and the App Paths registry key.
 type ExecutableID string
 
+// ProgramExecutablePrefix is the prefix of executable IDs that refer to a
+// program by name.
+const ProgramExecutablePrefix = "program:"
+
+// Program returns the name of the program that the executable ID refers
+// to, if it has the program prefix. If it does not, ok will be false.
+func (id ExecutableID) Program() (name string, ok bool) {
+	return strings.CutPrefix(string(id), ProgramExecutablePrefix)
+}
+
+// Working directory values that don't refer to directory resources.
+const (
+	// PackageWorkingDirectoryPrefix is the prefix of working directories
+	// that refer to a location within a package's files.
+	PackageWorkingDirectoryPrefix = "package:"
+
+	// TempWorkingDirectory refers to an empty temporary directory that is
+	// created for a command and deleted after it has run.
+	TempWorkingDirectory DirectoryResourceID = "temp:"
+)
+
 // Command defines a command that can be invoked for a deployment or
 // package.
 //
-// TODO: Support variable expansion when building arguments.
+// Its arguments and Windows Installer property values may refer to facts
+// about the local computer with placeholders such as "{fact:system.model}",
+// which are replaced by the value of the fact when the command is invoked.
+// Commands of archive packages may also refer to package files with
+// placeholders such as "{file:answers}", which are replaced by the absolute
+// path of the extracted file. This allows answer files and transforms
+// within an archive to be passed to the installer.
+//
+// TODO: Support deployment variables in addition to facts.
 type Command struct {
 	// Installs is a list of applications that the command installs.
 	Installs AppList `json:"installs,omitzero"`
@@ -63,6 +112,15 @@ type Command struct {
 	// WorkingDirectory specifies a working directory for a command. If no
 	// working directory is specified, the directory containing the executable
 	// will be used.
+	//
+	// Package commands may use "package:" to run within the directory holding
+	// the package's files, which for archive packages is the extraction
+	// directory. It may be followed by a relative path, such as
+	// "package:setup/x64", to run within a subdirectory of the extracted
+	// files.
+	//
+	// Any command may use "temp:" to run within an empty temporary
+	// directory, which is deleted after the command has run.
 	WorkingDirectory DirectoryResourceID `json:"working-directory,omitempty"`
 
 	// Executable identifies an executable file to be run.
@@ -71,7 +129,9 @@ type Command struct {
 	// file within the archive, and will be interpreted as a PackageFileID.
 	//
 	// For non-pacakge commands, it identifies the executable file to be
-	// invoked, and will be interpreted as a FileResourceID.
+	// invoked, and will be interpreted as a FileResourceID. It may instead
+	// name a program with the "program:" prefix, such as "program:sc.exe",
+	// which is found through PATH and the App Paths registry key.
 	//
 	// For msi-based commands, the file will be provided to the msiexec
 	// utility.
@@ -80,8 +140,87 @@ type Command struct {
 	// Args is the set of arguments to be passed to the command.
 	Args []string `json:"args,omitzero"`
 
+	// Properties is a set of Windows Installer properties to be passed to
+	// msiexec. It is only valid for msi-based commands. Each property is
+	// quoted as msiexec expects, so values may contain spaces and quotes.
+	Properties MSIProperties `json:"properties,omitzero"`
+
+	// Log describes how the installer log of the command is collected.
+	Log CommandLog `json:"log,omitzero"`
+
 	// ExitCodes provide a map of known exit codes for the command.
 	ExitCodes ExitCodeMap `json:"exit-codes,omitzero"`
+
+	// Throttle limits the processor time that the command may consume,
+	// such as by running it at a below-normal priority.
+	Throttle CommandThrottle `json:"throttle,omitzero"`
+
+	// Window determines how the windows of the command are shown. If it is
+	// hidden, console programs are started without a console window, and
+	// any windows that the command or its child processes show while it
+	// runs are hidden. If it is minimized, those windows are minimized
+	// instead.
+	Window WindowStyle `json:"window,omitempty"`
+
+	// PostConditions is a list of conditions that must be true after the
+	// command has run for it to be considered successful, such as a service
+	// that is running, a file that exists or a registry value that has been
+	// stamped. They are only evaluated when the command's exit code
+	// indicates success. If any of them is not met, the command fails.
+	PostConditions ConditionList `json:"post-conditions,omitzero"`
+}
+
+// PackageWorkingDirectory returns the path of the command's working
+// directory relative to its package's files, if it has the package prefix.
+// The path is empty when it refers to the package directory itself. If the
+// prefix is not present, ok will be false.
+func (command Command) PackageWorkingDirectory() (path string, ok bool) {
+	return strings.CutPrefix(string(command.WorkingDirectory), PackageWorkingDirectoryPrefix)
+}
+
+// HasResourceWorkingDirectory returns true if the command's working
+// directory refers to a directory resource.
+func (command Command) HasResourceWorkingDirectory() bool {
+	if command.WorkingDirectory == "" || command.WorkingDirectory == TempWorkingDirectory {
+		return false
+	}
+	_, inPackage := command.PackageWorkingDirectory()
+	return !inPackage
+}
+
+// validateWorkingDirectory returns a non-nil error if the command's working
+// directory refers to package files that aren't available to it.
+func (command Command) validateWorkingDirectory(inPackage bool) error {
+	path, ok := command.PackageWorkingDirectory()
+	if !ok {
+		return nil
+	}
+	if !inPackage {
+		return fmt.Errorf("the \"%s\" working directory is only valid for package commands", command.WorkingDirectory)
+	}
+	if path != "" && !filepath.IsLocal(path) {
+		return fmt.Errorf("the \"%s\" working directory does not refer to a relative path within the package", command.WorkingDirectory)
+	}
+	return nil
+}
+
+// placeholderValues returns the command's arguments and property values,
+// which may hold placeholders.
+func (command Command) placeholderValues() []string {
+	return slices.Concat(command.Args, slices.Collect(maps.Values(command.Properties)))
+}
+
+// validateFacts returns a non-nil error if the command's arguments or
+// properties refer to facts that are not recognized.
+func (command Command) validateFacts() error {
+	for _, value := range command.placeholderValues() {
+		for _, name := range sysfacts.References(value) {
+			if err := name.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // ExitCodeMap defines a set of expected exit codes.
@@ -91,16 +230,59 @@ type ExitCodeMap map[ExitCode]ExitCodeInfo
 type ExitCode int
 
 // ExitCodeInfo stores information about an exit code.
+//
+// Reboot indicates that the exit code is returned when a restart is
+// required to complete the command's changes.
+//
+// RebootInitiated indicates that the exit code is returned when the
+// command has already initiated a restart on its own.
+//
+// Remediation suggests what a technician can do to resolve the problem
+// that the exit code reports.
 type ExitCodeInfo struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	OK          bool   `json:"ok,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Remediation     string `json:"remediation,omitempty"`
+	OK              bool   `json:"ok,omitempty"`
+	Reboot          bool   `json:"reboot,omitempty"`
+	RebootInitiated bool   `json:"reboot-initiated,omitempty"`
 }
 
+// CommandOutcome describes the outcome of a command, as determined by its
+// exit code.
+type CommandOutcome string
+
+// Command outcomes.
+const (
+	CommandSucceeded                CommandOutcome = "succeeded"
+	CommandSucceededRebootRequired  CommandOutcome = "succeeded-reboot-required"
+	CommandSucceededRebootInitiated CommandOutcome = "succeeded-reboot-initiated"
+	CommandFailed                   CommandOutcome = "failed"
+)
+
 // CommandResult stores information about an exit code returned by a command.
+//
+// SystemMessage holds the message that Windows associates with the exit
+// code, when the exit code is known to be a Windows error code.
 type CommandResult struct {
-	ExitCode ExitCode
-	Info     ExitCodeInfo
+	ExitCode      ExitCode
+	Info          ExitCodeInfo
+	SystemMessage string
+}
+
+// Outcome returns the outcome of the command. Exit codes that aren't
+// recognized are considered successful only if they are zero.
+func (r CommandResult) Outcome() CommandOutcome {
+	switch {
+	case r.Info.OK && r.Info.RebootInitiated:
+		return CommandSucceededRebootInitiated
+	case r.Info.OK && r.Info.Reboot:
+		return CommandSucceededRebootRequired
+	case r.Info.OK, r.ExitCode == 0 && r.Info.Name == "":
+		return CommandSucceeded
+	default:
+		return CommandFailed
+	}
 }
 
 // String returns a string representation of the command result.
@@ -120,3 +302,24 @@ func (r CommandResult) String() string {
 	}
 	return builder.String()
 }
+
+// WindowStyle determines how the windows of a command are shown.
+type WindowStyle string
+
+// Window styles.
+const (
+	WindowStyleNormal    WindowStyle = "normal"
+	WindowStyleMinimized WindowStyle = "minimized"
+	WindowStyleHidden    WindowStyle = "hidden"
+)
+
+// Validate returns a non-nil error if the window style is not recognized.
+// An empty window style is valid, and is treated as normal.
+func (style WindowStyle) Validate() error {
+	switch style {
+	case "", WindowStyleNormal, WindowStyleMinimized, WindowStyleHidden:
+		return nil
+	default:
+		return fmt.Errorf("the window style \"%s\" is not recognized", style)
+	}
+}
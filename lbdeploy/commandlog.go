@@ -0,0 +1,98 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LogFilePlaceholder is replaced by the path of the log file in the
+// arguments of a command log.
+const LogFilePlaceholder = "{log-file}"
+
+// CommandLog describes how the installer log of a command is collected.
+//
+// Logs are written to a logs directory for the command's package within
+// the deployment's staging directory.
+//
+// Msi-based commands are logged with the /l*v option of msiexec, unless
+// Disabled is true or the command's arguments already include a logging
+// option. Other commands are only logged when Args is provided.
+//
+// Args holds the vendor-specific arguments that cause the command to write
+// a log, such as ["/log", "{log-file}"]. Each occurrence of
+// LogFilePlaceholder is replaced by the path of the log file. When
+// provided for an msi-based command, Args replaces the /l*v option.
+//
+// Retention determines whether the log is kept after the command has run.
+// Keep limits the number of logs that are retained for the command; older
+// logs are removed. Upload identifies a directory resource, such as a
+// network share, that receives a copy of each log.
+type CommandLog struct {
+	Disabled  bool                `json:"disabled,omitempty"`
+	Args      []string            `json:"args,omitzero"`
+	Retention LogRetention        `json:"retention,omitempty"`
+	Keep      int                 `json:"keep,omitempty"`
+	Upload    DirectoryResourceID `json:"upload,omitempty"`
+}
+
+// Enabled returns true if a log should be collected for a command of the
+// given type.
+func (log CommandLog) Enabled(t CommandType) bool {
+	if log.Disabled {
+		return false
+	}
+	return t.IsMSI() || len(log.Args) > 0
+}
+
+// Validate returns a non-nil error if the command log is invalid.
+func (log CommandLog) Validate() error {
+	if err := log.Retention.Validate(); err != nil {
+		return err
+	}
+	if log.Keep < 0 {
+		return errors.New("the number of logs to keep must not be negative")
+	}
+	if len(log.Args) > 0 && !slices.ContainsFunc(log.Args, func(arg string) bool {
+		return strings.Contains(arg, LogFilePlaceholder)
+	}) {
+		return fmt.Errorf("the log arguments must include the %s placeholder", LogFilePlaceholder)
+	}
+	return nil
+}
+
+// LogRetention determines whether a command log is kept after the command
+// has run.
+type LogRetention string
+
+// Log retention policies.
+const (
+	LogRetainAlways    LogRetention = "always"
+	LogRetainOnFailure LogRetention = "on-failure"
+	LogRetainNever     LogRetention = "never"
+)
+
+// Retain returns true if a log should be kept for a command that
+// succeeded or failed. If no policy is specified, logs are always kept.
+func (r LogRetention) Retain(failed bool) bool {
+	switch r {
+	case LogRetainOnFailure:
+		return failed
+	case LogRetainNever:
+		return false
+	default:
+		return true
+	}
+}
+
+// Validate returns a non-nil error if the log retention policy is not
+// recognized.
+func (r LogRetention) Validate() error {
+	switch r {
+	case "", LogRetainAlways, LogRetainOnFailure, LogRetainNever:
+		return nil
+	default:
+		return fmt.Errorf("the log retention policy \"%s\" is not recognized", r)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/datatype"
 	"github.com/leafbridge/leafbridge-deploy/lbvalue"
 )
 
@@ -44,28 +45,60 @@ const (
 	ConditionTypeRegistryValueExists     ConditionType = "resource.registry.value:exists"
 	ConditionTypeRegistryValueComparison ConditionType = "resource.registry.value:comparison"
 	ConditionTypeDirectoryExists         ConditionType = "resource.file-system.directory:exists"
+	ConditionTypeDirectoryEmpty          ConditionType = "resource.file-system.directory:empty"
+	ConditionTypeDirectoryContains       ConditionType = "resource.file-system.directory:contains"
+	ConditionTypeDirectorySize           ConditionType = "resource.file-system.directory:size"
 	ConditionTypeFileExists              ConditionType = "resource.file-system.file:exists"
+	ConditionTypeDeferralAvailable       ConditionType = "deployment.deferral:available"
+	ConditionTypeDeferralDeadlinePassed  ConditionType = "deployment.deferral:deadline-passed"
+	ConditionTypeFactExists              ConditionType = "system.fact:exists"
+	ConditionTypeFactComparison          ConditionType = "system.fact:comparison"
+	ConditionTypeHostReachable           ConditionType = "network.host:reachable"
 )
 
 // Condition describes a condition that can be evaluated.
+//
+// A directory contains condition is true if a file or directory within the
+// subject directory matches the pattern in Value, such as "*.log" or
+// "cache/*.tmp". A directory size condition applies Comparison to the total
+// size in bytes of the files within the directory and its subdirectories,
+// and the number in Value. A directory that does not exist is considered
+// empty, with a size of zero.
+//
+// A fact condition's subject is the name of a fact about the local
+// computer, such as "system.model" or "tpm.version". A fact exists
+// condition is true if the fact could be determined. A fact comparison
+// condition applies Comparison to the fact and Value.
+//
+// A host reachable condition's subject is a host name and port, such as
+// "mirror.example.local:443". It is true if a TCP connection to the host
+// can be established within a few seconds. If the port is omitted, port
+// 443 is assumed.
+//
+// VersionMode determines how versions are compared by a registry value or
+// fact comparison condition. Semantic versions such as "1.2.3-rc.1" should
+// be compared in the "semver" mode, which orders pre-releases before their
+// release and ignores build metadata.
 type Condition struct {
-	Label      string             `json:"label,omitempty"`
-	Type       ConditionType      `json:"type,omitempty"`
-	Subject    string             `json:"subject,omitempty"`
-	Comparison lbvalue.Comparison `json:"comparison,omitzero"`
-	Value      lbvalue.Value      `json:"value,omitzero"`
-	Negated    bool               `json:"negated,omitempty"`
-	Any        []Condition        `json:"any,omitzero"`
-	All        []Condition        `json:"all,omitzero"`
-	Violation  string             `json:"violation,omitempty"`
+	Label       string               `json:"label,omitempty"`
+	Type        ConditionType        `json:"type,omitempty"`
+	Subject     string               `json:"subject,omitempty"`
+	Comparison  lbvalue.Comparison   `json:"comparison,omitzero"`
+	Value       lbvalue.Value        `json:"value,omitzero"`
+	VersionMode datatype.VersionMode `json:"version-mode,omitempty"`
+	Negated     bool                 `json:"negated,omitempty"`
+	Any         []Condition          `json:"any,omitzero"`
+	All         []Condition          `json:"all,omitzero"`
+	Violation   string               `json:"violation,omitempty"`
 }
 
 // ConditionUse identifies common uses of a condition.
 type ConditionUse string
 
 const (
-	ConditionUseConstraint   ConditionUse = "constraint"
-	ConditionUsePrecondition ConditionUse = "precondition"
+	ConditionUseConstraint    ConditionUse = "constraint"
+	ConditionUsePrecondition  ConditionUse = "precondition"
+	ConditionUsePostcondition ConditionUse = "post-condition"
 )
 
 // String returns a string representation of the use.
@@ -85,6 +118,8 @@ func (use ConditionUse) Plural() string {
 		return "constraints"
 	case ConditionUsePrecondition:
 		return "preconditions"
+	case ConditionUsePostcondition:
+		return "post-conditions"
 	default:
 		return string(use)
 	}
@@ -0,0 +1,65 @@
+package lbdeploy
+
+import (
+	"errors"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// DeferralPolicy determines how many times, and for how long, the user
+// signed in to the system may postpone a deployment when prompted by a
+// prompt-deferral action.
+//
+// If MaxDeferrals is zero, the number of deferrals is not limited. Once the
+// deferrals have been used up, or the deadline has passed, the deployment
+// proceeds without prompting.
+//
+// Message is shown to the user when prompting. Timeout is how long to wait
+// for the user to respond. A prompt without a response counts as a
+// deferral.
+type DeferralPolicy struct {
+	MaxDeferrals int               `json:"max-deferrals,omitempty"`
+	Deadline     time.Time         `json:"deadline,omitzero"`
+	Message      string            `json:"message,omitempty"`
+	Timeout      datatype.Duration `json:"timeout,omitempty"`
+}
+
+// Validate returns a non-nil error if the deferral policy is invalid.
+func (policy DeferralPolicy) Validate() error {
+	if policy.MaxDeferrals < 0 {
+		return errors.New("the maximum number of deferrals is negative")
+	}
+	if policy.Timeout < 0 {
+		return errors.New("the prompt timeout is negative")
+	}
+	return nil
+}
+
+// DeadlinePassed returns true if the policy has a deadline and it has
+// passed at the given time.
+func (policy DeferralPolicy) DeadlinePassed(now time.Time) bool {
+	return !policy.Deadline.IsZero() && !now.Before(policy.Deadline)
+}
+
+// Permits returns true if the policy permits another deferral when the
+// deployment has already been deferred the given number of times.
+func (policy DeferralPolicy) Permits(deferrals int, now time.Time) bool {
+	if policy.DeadlinePassed(now) {
+		return false
+	}
+	if policy.MaxDeferrals > 0 && deferrals >= policy.MaxDeferrals {
+		return false
+	}
+	return true
+}
+
+// Remaining returns the number of deferrals that remain when the deployment
+// has already been deferred the given number of times. It returns -1 if the
+// number of deferrals is not limited.
+func (policy DeferralPolicy) Remaining(deferrals int) int {
+	if policy.MaxDeferrals == 0 {
+		return -1
+	}
+	return max(policy.MaxDeferrals-deferrals, 0)
+}
@@ -3,7 +3,13 @@ package lbdeploy
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"path"
+	"slices"
 	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 )
 
 // DeploymentID is a unique identifier for a deployment.
@@ -18,15 +24,36 @@ func (id DeploymentID) Validate() error {
 }
 
 // Deployment defines a deployment package.
+//
+// A deployment may extend a base template, which is a deployment file that
+// holds definitions common to many deployments. The deployment is layered
+// over the template when it is loaded.
+//
+// Reboot determines what happens when a command indicates that a restart
+// is required to complete its changes.
+//
+// If RequiresElevation is true, the deployment refuses to run unless the
+// process running it is elevated or running as the local system account.
+//
+// Deferral determines how often the user may postpone the deployment when
+// a flow prompts them with a prompt-deferral action.
 type Deployment struct {
-	ID         DeploymentID `json:"id,omitempty"`
-	Name       string       `json:"name,omitempty"`
-	Behavior   Behavior     `json:"behavior,omitzero"`
-	Apps       AppMap       `json:"apps,omitzero"`
-	Conditions ConditionMap `json:"conditions,omitzero"`
-	Commands   CommandMap   `json:"commands,omitzero"`
-	Resources  Resources    `json:"resources,omitzero"`
-	Flows      FlowMap      `json:"flows,omitzero"`
+	ID                DeploymentID       `json:"id,omitempty"`
+	Extends           string             `json:"extends,omitempty"`
+	Include           []string           `json:"include,omitempty"`
+	Libraries         []LibraryReference `json:"libraries,omitzero"`
+	Name              string             `json:"name,omitempty"`
+	Behavior          Behavior           `json:"behavior,omitzero"`
+	Reboot            RebootPolicy       `json:"reboot,omitzero"`
+	RequiresElevation bool               `json:"requires-elevation,omitempty"`
+	Deferral          DeferralPolicy     `json:"deferral,omitzero"`
+	Apps              AppMap             `json:"apps,omitzero"`
+	Conditions        ConditionMap       `json:"conditions,omitzero"`
+	Commands          CommandMap         `json:"commands,omitzero"`
+	Resources         Resources          `json:"resources,omitzero"`
+	Flows             FlowMap            `json:"flows,omitzero"`
+
+	Environments EnvironmentMap `json:"environments,omitzero"`
 }
 
 // Validate returns an error if the deployment contains invalid configuration.
@@ -35,12 +62,161 @@ func (dep Deployment) Validate() error {
 		return err
 	}
 
+	if err := dep.Reboot.Validate(); err != nil {
+		return fmt.Errorf("the reboot policy of the \"%s\" deployment is not valid: %w", dep.ID, err)
+	}
+
+	if err := dep.Behavior.MaintenanceWindows.Validate(); err != nil {
+		return fmt.Errorf("the behavior of the \"%s\" deployment is not valid: %w", dep.ID, err)
+	}
+
+	if err := dep.Behavior.Proxy.Validate(); err != nil {
+		return fmt.Errorf("the proxy settings of the \"%s\" deployment are not valid: %w", dep.ID, err)
+	}
+
+	if err := dep.Behavior.DownloadRetry.Validate(); err != nil {
+		return fmt.Errorf("the download retry policy of the \"%s\" deployment is not valid: %w", dep.ID, err)
+	}
+
+	for id, flow := range dep.Flows {
+		if err := flow.Behavior.MaintenanceWindows.Validate(); err != nil {
+			return fmt.Errorf("the behavior of the \"%s\" flow is not valid: %w", id, err)
+		}
+		if err := flow.Behavior.Proxy.Validate(); err != nil {
+			return fmt.Errorf("the proxy settings of the \"%s\" flow are not valid: %w", id, err)
+		}
+		if err := flow.Behavior.DownloadRetry.Validate(); err != nil {
+			return fmt.Errorf("the download retry policy of the \"%s\" flow is not valid: %w", id, err)
+		}
+		if err := flow.validateActions(); err != nil {
+			return fmt.Errorf("the actions of the \"%s\" flow are not valid: %w", id, err)
+		}
+	}
+
+	if err := dep.Deferral.Validate(); err != nil {
+		return fmt.Errorf("the deferral policy of the \"%s\" deployment is not valid: %w", dep.ID, err)
+	}
+
 	for id := range dep.Conditions {
 		if err := dep.ValidateCondition(id); err != nil {
 			return err
 		}
 	}
 
+	for id, command := range dep.Commands {
+		if err := command.validateProperties(); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+		if err := command.Log.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" command has an invalid log: %w", id, err)
+		}
+		if err := command.validateFacts(); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+		if err := command.validateWorkingDirectory(false); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+		if err := command.validatePackageFiles(nil); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+		if err := dep.validatePostConditions(command); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+		if err := command.Throttle.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" command has an invalid throttle: %w", id, err)
+		}
+		if err := command.Window.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" command is not valid: %w", id, err)
+		}
+	}
+
+	for id, pkg := range dep.Resources.Packages {
+		if err := dep.validatePackageSourceConditions(pkg); err != nil {
+			return fmt.Errorf("the \"%s\" package is not valid: %w", id, err)
+		}
+		for commandID, command := range pkg.Commands {
+			if err := dep.validatePostConditions(command); err != nil {
+				return fmt.Errorf("the \"%s\" package is not valid: package command \"%s\": %w", id, commandID, err)
+			}
+		}
+	}
+
+	for id, mutex := range dep.Resources.Mutexes {
+		if err := mutex.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" mutex is not valid: %w", id, err)
+		}
+	}
+
+	for id, semaphore := range dep.Resources.Semaphores {
+		if err := semaphore.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" semaphore is not valid: %w", id, err)
+		}
+	}
+
+	for id, lock := range dep.Resources.Locks {
+		if lock.Mutex != "" && lock.Semaphore != "" {
+			return fmt.Errorf("the \"%s\" lock references both a mutex and a semaphore, which are mutually exclusive", id)
+		}
+		if lock.Mutex != "" {
+			if _, found := dep.Resources.Mutexes[lock.Mutex]; !found {
+				return fmt.Errorf("the \"%s\" lock references a mutex that is not defined: %s", id, lock.Mutex)
+			}
+		}
+		if lock.Semaphore != "" {
+			if _, found := dep.Resources.Semaphores[lock.Semaphore]; !found {
+				return fmt.Errorf("the \"%s\" lock references a semaphore that is not defined: %s", id, lock.Semaphore)
+			}
+		}
+	}
+
+	for id, dir := range dep.Resources.FileSystem.Directories {
+		if err := dir.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" directory is not valid: %w", id, err)
+		}
+		if _, err := dep.Resources.FileSystem.ResolveDirectory(id); err != nil {
+			return err
+		}
+	}
+
+	for id, file := range dep.Resources.FileSystem.Files {
+		if err := file.Attributes.Validate(); err != nil {
+			return fmt.Errorf("the attributes of the \"%s\" file are not valid: %w", id, err)
+		}
+		if _, err := dep.Resources.FileSystem.ResolveFile(id); err != nil {
+			return err
+		}
+	}
+
+	for id, key := range dep.Resources.Registry.Keys {
+		if _, err := key.View.Access(); err != nil {
+			return fmt.Errorf("the \"%s\" registry key is not valid: %w", id, err)
+		}
+		if _, err := dep.Resources.Registry.ResolveKey(id); err != nil {
+			return err
+		}
+	}
+
+	for id, value := range dep.Resources.Registry.Values {
+		if err := value.Type.Validate(); err != nil {
+			return fmt.Errorf("the \"%s\" registry value is not valid: %w", id, err)
+		}
+		if _, err := dep.Resources.Registry.ResolveValue(id); err != nil {
+			return err
+		}
+	}
+
+	for id, process := range dep.Resources.Processes {
+		if err := process.Conflict.Validate(); err != nil {
+			return fmt.Errorf("the conflict policy of the \"%s\" process is not valid: %w", id, err)
+		}
+	}
+
+	for id := range dep.Flows {
+		if _, err := dep.Flows.Order(id); err != nil {
+			return fmt.Errorf("the dependencies of the \"%s\" flow are not valid: %w", id, err)
+		}
+	}
+
 	return nil
 }
 
@@ -153,13 +329,32 @@ func (dep Deployment) validateCondition(condition Condition) error {
 			if _, found := dep.Resources.Registry.Values[RegistryValueResourceID(condition.Subject)]; !found {
 				return fmt.Errorf("the condition references a registry value resource ID that is not defined: %s", condition.Subject)
 			}
-		case ConditionTypeDirectoryExists:
+			if err := condition.VersionMode.Validate(); err != nil {
+				return err
+			}
+		case ConditionTypeDirectoryExists, ConditionTypeDirectoryEmpty, ConditionTypeDirectoryContains, ConditionTypeDirectorySize:
 			if condition.Subject == "" {
 				return errors.New("the condition does not provide a directory resource ID")
 			}
 			if _, found := dep.Resources.FileSystem.Directories[DirectoryResourceID(condition.Subject)]; !found {
 				return fmt.Errorf("the condition references a directory resource ID that is not defined: %s", condition.Subject)
 			}
+			switch condition.Type {
+			case ConditionTypeDirectoryContains:
+				if condition.Value.Kind() != lbvalue.KindString {
+					return errors.New("the condition does not provide a file name pattern as its value")
+				}
+				if !fs.ValidPath(condition.Value.String()) {
+					return fmt.Errorf("the condition's file name pattern is not valid: %s", condition.Value)
+				}
+				if _, err := path.Match(condition.Value.String(), ""); err != nil {
+					return fmt.Errorf("the condition's file name pattern is not valid: %w", err)
+				}
+			case ConditionTypeDirectorySize:
+				if condition.Value.Kind() != lbvalue.KindInt64 {
+					return errors.New("the condition does not provide a size in bytes as its value")
+				}
+			}
 		case ConditionTypeFileExists:
 			if condition.Subject == "" {
 				return errors.New("the condition does not provide a file resource ID")
@@ -167,6 +362,27 @@ func (dep Deployment) validateCondition(condition Condition) error {
 			if _, found := dep.Resources.FileSystem.Files[FileResourceID(condition.Subject)]; !found {
 				return fmt.Errorf("the condition references a file resource ID that is not defined: %s", condition.Subject)
 			}
+		case ConditionTypeDeferralAvailable, ConditionTypeDeferralDeadlinePassed:
+			if condition.Subject != "" {
+				return errors.New("deferral conditions do not accept a subject")
+			}
+		case ConditionTypeFactExists, ConditionTypeFactComparison:
+			if condition.Subject == "" {
+				return errors.New("the condition does not provide a fact name")
+			}
+			if err := sysfacts.Name(condition.Subject).Validate(); err != nil {
+				return fmt.Errorf("the condition references a fact that is not recognized: %s", condition.Subject)
+			}
+			if err := condition.VersionMode.Validate(); err != nil {
+				return err
+			}
+		case ConditionTypeHostReachable:
+			if condition.Subject == "" {
+				return errors.New("the condition does not provide a host name")
+			}
+			if _, err := HostAddress(condition.Subject); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("the condition type is not recognized: %s", condition.Type)
 		}
@@ -179,3 +395,35 @@ func (dep Deployment) validateCondition(condition Condition) error {
 
 	return nil
 }
+
+// validatePostConditions returns an error if any of the post-conditions of
+// a command refer to a condition that is not defined.
+func (dep Deployment) validatePostConditions(command Command) error {
+	for _, condition := range command.PostConditions {
+		if _, found := dep.Conditions[condition]; !found {
+			return fmt.Errorf("a post-condition references a condition that is not defined: %s", condition)
+		}
+	}
+	return nil
+}
+
+// validatePackageSourceConditions returns an error if any of the sources of
+// a package, or of its variants or deltas, refer to a condition that is not defined.
+func (dep Deployment) validatePackageSourceConditions(pkg Package) error {
+	sources := slices.Clone(pkg.Sources)
+	for _, variant := range pkg.Variants {
+		sources = append(sources, variant.Sources...)
+	}
+	for _, delta := range pkg.Deltas {
+		sources = append(sources, delta.Sources...)
+	}
+	for _, source := range sources {
+		if source.Condition == "" {
+			continue
+		}
+		if _, found := dep.Conditions[source.Condition]; !found {
+			return fmt.Errorf("the \"%s\" package source references a condition that is not defined: %s", source.URL, source.Condition)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// DefaultDownloadAttempts is the number of times that a package download
+// is attempted when a retry policy does not specify it.
+const DefaultDownloadAttempts = 2
+
+// DefaultRetryStatusCodes are the HTTP status codes that cause a package
+// download to be retried when a retry policy does not specify them.
+var DefaultRetryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DownloadRetryPolicy determines whether and when a package download is
+// attempted again after it fails. Each attempt tries every source of the
+// package in turn.
+//
+// Attempts is the maximum number of attempts. If it is zero,
+// DefaultDownloadAttempts is used.
+//
+// Delay is the time to wait before the second attempt. It doubles with each
+// attempt after that, up to MaxDelay if it is provided. If Delay is zero,
+// attempts are made without waiting.
+//
+// StatusCodes lists the HTTP status codes that are worth retrying. If it is
+// empty, DefaultRetryStatusCodes is used. Downloads that fail with other
+// status codes, such as 404, are not retried. Downloads that fail to
+// connect, are interrupted or fail verification are always retried.
+type DownloadRetryPolicy struct {
+	Attempts    int               `json:"attempts,omitempty"`
+	Delay       datatype.Duration `json:"delay,omitempty"`
+	MaxDelay    datatype.Duration `json:"max-delay,omitempty"`
+	StatusCodes []int             `json:"status-codes,omitzero"`
+}
+
+// IsZero returns true if the retry policy is empty.
+func (p DownloadRetryPolicy) IsZero() bool {
+	return p.Attempts == 0 && p.Delay == 0 && p.MaxDelay == 0 && len(p.StatusCodes) == 0
+}
+
+// Validate returns a non-nil error if the retry policy is invalid.
+func (p DownloadRetryPolicy) Validate() error {
+	if p.Attempts < 0 {
+		return errors.New("the number of attempts must not be negative")
+	}
+	if p.Delay < 0 || p.MaxDelay < 0 {
+		return errors.New("retry delays must not be negative")
+	}
+	if p.MaxDelay != 0 && p.MaxDelay < p.Delay {
+		return errors.New("the maximum retry delay must not be less than the retry delay")
+	}
+	for _, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("%d is not a valid HTTP status code", code)
+		}
+	}
+	return nil
+}
+
+// MaxAttempts returns the maximum number of times that a download is
+// attempted.
+func (p DownloadRetryPolicy) MaxAttempts() int {
+	if p.Attempts == 0 {
+		return DefaultDownloadAttempts
+	}
+	return p.Attempts
+}
+
+// DelayAfter returns the time to wait after the given attempt fails,
+// starting with 1 for the first attempt.
+func (p DownloadRetryPolicy) DelayAfter(attempt int) time.Duration {
+	delay := time.Duration(p.Delay)
+	for i := 1; i < attempt && delay > 0; i++ {
+		if p.MaxDelay != 0 && delay >= time.Duration(p.MaxDelay) {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay != 0 {
+		delay = min(delay, time.Duration(p.MaxDelay))
+	}
+	return delay
+}
+
+// RetriesStatus returns true if a download that fails with the given HTTP
+// status code should be retried.
+func (p DownloadRetryPolicy) RetriesStatus(code int) bool {
+	codes := p.StatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryStatusCodes
+	}
+	return slices.Contains(codes, code)
+}
@@ -0,0 +1,148 @@
+package lbdeploy
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// EnvironmentMap holds a set of environment overlays mapped by their
+// identifiers.
+type EnvironmentMap map[EnvironmentID]Environment
+
+// EnvironmentID is a unique identifier for an environment, such as pilot,
+// broad or dev.
+type EnvironmentID string
+
+// Environment is a named overlay that modifies a deployment when it is
+// invoked for a particular environment. It allows a single deployment file
+// to serve a ring-based rollout, where each ring receives a different
+// package version or behavior.
+type Environment struct {
+	// Description describes the environment.
+	Description string `json:"description,omitempty"`
+
+	// Behavior overrides the deployment's behavior.
+	Behavior Behavior `json:"behavior,omitzero"`
+
+	// Reboot overrides the deployment's reboot policy.
+	Reboot RebootPolicy `json:"reboot,omitzero"`
+
+	// Apps overrides the versions of applications in the deployment.
+	Apps map[AppID]AppOverlay `json:"apps,omitzero"`
+
+	// Packages overrides the properties of packages in the deployment.
+	Packages map[PackageID]PackageOverlay `json:"packages,omitzero"`
+
+	// Flows overrides the behavior of flows in the deployment.
+	Flows map[FlowID]FlowOverlay `json:"flows,omitzero"`
+}
+
+// AppOverlay overrides the version of an application for an environment,
+// so that each environment can receive a different release. A new release
+// of an MSI-based application usually has a new product code. Fields that
+// are empty are left unchanged.
+type AppOverlay struct {
+	ProductCode    ProductCode      `json:"product-code,omitempty"`
+	TargetVersion  datatype.Version `json:"target-version,omitempty"`
+	MinimumVersion datatype.Version `json:"minimum-version,omitempty"`
+}
+
+// PackageOverlay overrides the properties of a package for an environment.
+// Fields that are empty are left unchanged.
+type PackageOverlay struct {
+	Name       string          `json:"name,omitempty"`
+	Sources    []PackageSource `json:"sources,omitempty"`
+	Attributes FileAttributes  `json:"attributes,omitzero"`
+	Files      PackageFileMap  `json:"files,omitzero"`
+}
+
+// FlowOverlay overrides the properties of a flow for an environment.
+type FlowOverlay struct {
+	Behavior Behavior `json:"behavior,omitzero"`
+}
+
+// ForEnvironment returns a copy of the deployment with the overlay for the
+// given environment applied. If env is empty, the deployment is returned
+// unchanged.
+//
+// It returns an error if the environment is not defined by the deployment,
+// or if the overlay refers to apps, packages or flows that do not exist.
+func (dep Deployment) ForEnvironment(env EnvironmentID) (Deployment, error) {
+	if env == "" {
+		return dep, nil
+	}
+
+	overlay, found := dep.Environments[env]
+	if !found {
+		return Deployment{}, fmt.Errorf("the environment \"%s\" does not exist within the \"%s\" deployment", env, dep.ID)
+	}
+
+	dep.Behavior = OverlayBehavior(dep.Behavior, overlay.Behavior)
+	dep.Reboot = OverlayRebootPolicy(dep.Reboot, overlay.Reboot)
+
+	if len(overlay.Apps) > 0 {
+		dep.Apps = maps.Clone(dep.Apps)
+		for id, ao := range overlay.Apps {
+			app, found := dep.Apps[id]
+			if !found {
+				return Deployment{}, fmt.Errorf("the \"%s\" environment overrides the app \"%s\", which does not exist within the \"%s\" deployment", env, id, dep.ID)
+			}
+			if ao.ProductCode != "" {
+				app.ProductCode = ao.ProductCode
+			}
+			if ao.TargetVersion != "" {
+				app.TargetVersion = ao.TargetVersion
+			}
+			if ao.MinimumVersion != "" {
+				if app.Detection.File.Path == "" {
+					return Deployment{}, fmt.Errorf("the \"%s\" environment overrides the minimum file version of the app \"%s\", which is not detected by a file", env, id)
+				}
+				app.Detection.File.MinimumVersion = ao.MinimumVersion
+			}
+			dep.Apps[id] = app
+		}
+	}
+
+	if len(overlay.Packages) > 0 {
+		dep.Resources.Packages = maps.Clone(dep.Resources.Packages)
+		for id, po := range overlay.Packages {
+			pkg, found := dep.Resources.Packages[id]
+			if !found {
+				return Deployment{}, fmt.Errorf("the \"%s\" environment overrides the package \"%s\", which does not exist within the \"%s\" deployment", env, id, dep.ID)
+			}
+			if po.Name != "" {
+				pkg.Name = po.Name
+			}
+			if len(po.Sources) > 0 {
+				pkg.Sources = po.Sources
+			}
+			if po.Attributes.Size != 0 || len(po.Attributes.Hashes) > 0 {
+				pkg.Attributes = po.Attributes
+			}
+			if len(po.Files) > 0 {
+				pkg.Files = maps.Clone(pkg.Files)
+				if pkg.Files == nil {
+					pkg.Files = make(PackageFileMap)
+				}
+				maps.Copy(pkg.Files, po.Files)
+			}
+			dep.Resources.Packages[id] = pkg
+		}
+	}
+
+	if len(overlay.Flows) > 0 {
+		dep.Flows = maps.Clone(dep.Flows)
+		for id, fo := range overlay.Flows {
+			flow, found := dep.Flows[id]
+			if !found {
+				return Deployment{}, fmt.Errorf("the \"%s\" environment overrides the flow \"%s\", which does not exist within the \"%s\" deployment", env, id, dep.ID)
+			}
+			flow.Behavior = OverlayBehavior(flow.Behavior, fo.Behavior)
+			dep.Flows[id] = flow
+		}
+	}
+
+	return dep, nil
+}
@@ -0,0 +1,108 @@
+package lbdeploy_test
+
+import (
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+func TestDeploymentForEnvironment(t *testing.T) {
+	dep := lbdeploy.Deployment{
+		ID: "example",
+		Apps: lbdeploy.AppMap{
+			"app": {
+				Name:          "Example App",
+				ProductCode:   "{11111111-1111-1111-1111-111111111111}",
+				TargetVersion: "2.0",
+			},
+			"tool": {
+				Name:      "Example Tool",
+				Detection: lbdeploy.AppDetection{File: lbdeploy.AppFileRule{Path: "tool-exe", MinimumVersion: "1.0"}},
+			},
+		},
+		Resources: lbdeploy.Resources{
+			Packages: lbdeploy.PackageMap{
+				"package": {
+					Name:    "example-2.0",
+					Sources: []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceHTTP, URL: "https://example.com/example-2.0.zip"}},
+				},
+			},
+		},
+		Environments: lbdeploy.EnvironmentMap{
+			"pilot": {
+				Apps: map[lbdeploy.AppID]lbdeploy.AppOverlay{
+					"app":  {ProductCode: "{22222222-2222-2222-2222-222222222222}", TargetVersion: "2.1"},
+					"tool": {MinimumVersion: "1.1"},
+				},
+				Packages: map[lbdeploy.PackageID]lbdeploy.PackageOverlay{
+					"package": {
+						Name:    "example-2.1",
+						Sources: []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceHTTP, URL: "https://example.com/example-2.1.zip"}},
+					},
+				},
+			},
+			"broad": {
+				Description: "Leaves the versions unchanged.",
+			},
+			"missing-app": {
+				Apps: map[lbdeploy.AppID]lbdeploy.AppOverlay{"other": {TargetVersion: "3.0"}},
+			},
+			"no-file-rule": {
+				Apps: map[lbdeploy.AppID]lbdeploy.AppOverlay{"app": {MinimumVersion: "3.0"}},
+			},
+		},
+	}
+
+	fixtures := []struct {
+		Env            lbdeploy.EnvironmentID
+		Err            bool
+		ProductCode    lbdeploy.ProductCode
+		TargetVersion  string
+		MinimumVersion string
+		PackageName    string
+	}{
+		{Env: "", ProductCode: "{11111111-1111-1111-1111-111111111111}", TargetVersion: "2.0", MinimumVersion: "1.0", PackageName: "example-2.0"},
+		{Env: "pilot", ProductCode: "{22222222-2222-2222-2222-222222222222}", TargetVersion: "2.1", MinimumVersion: "1.1", PackageName: "example-2.1"},
+		{Env: "broad", ProductCode: "{11111111-1111-1111-1111-111111111111}", TargetVersion: "2.0", MinimumVersion: "1.0", PackageName: "example-2.0"},
+		{Env: "missing-app", Err: true},
+		{Env: "no-file-rule", Err: true},
+		{Env: "undefined", Err: true},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(string(fixture.Env), func(t *testing.T) {
+			out, err := dep.ForEnvironment(fixture.Env)
+			if fixture.Err {
+				if err == nil {
+					t.Fatal("the environment was applied without error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			app := out.Apps["app"]
+			if app.ProductCode != fixture.ProductCode {
+				t.Errorf("unexpected product code: got %s, want %s", app.ProductCode, fixture.ProductCode)
+			}
+			if string(app.TargetVersion) != fixture.TargetVersion {
+				t.Errorf("unexpected target version: got %s, want %s", app.TargetVersion, fixture.TargetVersion)
+			}
+			if got := string(out.Apps["tool"].Detection.File.MinimumVersion); got != fixture.MinimumVersion {
+				t.Errorf("unexpected minimum version: got %s, want %s", got, fixture.MinimumVersion)
+			}
+			if got := out.Resources.Packages["package"].Name; got != fixture.PackageName {
+				t.Errorf("unexpected package name: got %s, want %s", got, fixture.PackageName)
+			}
+		})
+	}
+
+	// The original deployment must not be modified.
+	if got := dep.Apps["app"].TargetVersion; got != "2.0" {
+		t.Fatalf("the original deployment was modified: target version is %s", got)
+	}
+	if got := dep.Resources.Packages["package"].Name; got != "example-2.0" {
+		t.Fatalf("the original deployment was modified: package name is %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package lbdeploy
+
+import "fmt"
+
+// FileLockAction identifies a response to take when a file operation fails
+// because another process holds the file open.
+type FileLockAction string
+
+// Recognized file lock actions.
+const (
+	// FileLockFail causes the action to fail. It is the default.
+	FileLockFail FileLockAction = "fail"
+
+	// FileLockCloseAndRetry asks the processes that hold the file to shut
+	// down, terminating them if they do not, then tries the operation
+	// again once.
+	FileLockCloseAndRetry FileLockAction = "close-and-retry"
+)
+
+// Validate returns a non-nil error if the file lock action is not
+// recognized.
+func (action FileLockAction) Validate() error {
+	switch action {
+	case "", FileLockFail, FileLockCloseAndRetry:
+		return nil
+	default:
+		return fmt.Errorf("the file lock action \"%s\" is not recognized", action)
+	}
+}
+
+// FileLocker describes a process that holds a file open, preventing it
+// from being changed.
+type FileLocker struct {
+	PID     uint32
+	Name    string
+	Service string
+	User    string
+}
+
+// String returns a string representation of the file locker.
+func (locker FileLocker) String() string {
+	s := fmt.Sprintf("%s (%d)", locker.Name, locker.PID)
+	if locker.Service != "" {
+		s += fmt.Sprintf(" service %s", locker.Service)
+	}
+	if locker.User != "" {
+		s += fmt.Sprintf(" as %s", locker.User)
+	}
+	return s
+}
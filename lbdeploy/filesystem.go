@@ -1,12 +1,15 @@
 package lbdeploy
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/leafbridge/leafbridge-deploy/idset"
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 // FileSystemResources describes resources accessed through the file system,
@@ -25,24 +28,30 @@ type FileSystemResources struct {
 //
 // If the directory cannot be resolved, an error is returned.
 func (fs FileSystemResources) ResolveDirectory(dir DirectoryResourceID) (ref DirRef, err error) {
-	// TODO: Consider making custom error types for resolution.
-
 	// Look up the directory by its ID.
 	data, exists := fs.Directories[dir]
 	if !exists {
 		if candidate, found := GetKnownFolder(dir); found {
 			return DirRef{Root: candidate}, nil
 		}
-		return DirRef{}, fmt.Errorf("the \"%s\" directory is not defined in the deployment's resources", dir)
+		return DirRef{}, ResourceNotDefined{Kind: ResourceKindDirectory, ID: string(dir)}
+	}
+
+	// If the directory is a network share, it is its own root.
+	if data.Share != "" {
+		return DirRef{Network: data.networkRoot(dir)}, nil
 	}
 
 	// Make sure the directory has a location.
 	if data.Location == "" {
-		return DirRef{}, fmt.Errorf("the \"%s\" directory does not have a location", dir)
+		return DirRef{}, MissingLocation{Kind: ResourceKindDirectory, ID: string(dir)}
 	}
 
-	// Successful resolution must end in a known folder.
-	var root KnownFolder
+	// Successful resolution must end in a known folder or a network share.
+	var (
+		root    KnownFolder
+		network NetworkRoot
+	)
 
 	// Keep track of the directories we traverse, which will ultimately form
 	// a lineage under the root.
@@ -54,21 +63,25 @@ func (fs FileSystemResources) ResolveDirectory(dir DirectoryResourceID) (ref Dir
 
 	// Start with the directory's location and traverse its ancestry,
 	// recording each parent along the way. Stop when we encounter a known
-	// folder.
+	// folder or a network share.
 	lineage = append(lineage, data)
 	next := data.Location
 	for {
 		// Check for cycles.
 		if seen.Contains(next) {
-			return DirRef{}, fmt.Errorf("failed to resolve the \"%s\" directory: the \"%s\" parent directory has a cyclic reference to itself in the deployment's resources", dir, next)
+			return DirRef{}, dirResolutionError(dir, CyclicReference{Kind: ResourceKindDirectory, ID: string(next)})
 		}
 		seen.Add(next)
 
 		// Look for a directory with the next directory ID.
 		if parent, found := fs.Directories[next]; found {
+			if parent.Share != "" {
+				network = parent.networkRoot(next)
+				break
+			}
 			lineage = append(lineage, parent)
 			if parent.Location == "" {
-				return DirRef{}, fmt.Errorf("failed to resolve the \"%s\" directory: the \"%s\" parent directory does not have a location", dir, next)
+				return DirRef{}, dirResolutionError(dir, MissingLocation{Kind: ResourceKindDirectory, ID: string(next)})
 			}
 			next = parent.Location
 			continue
@@ -81,7 +94,7 @@ func (fs FileSystemResources) ResolveDirectory(dir DirectoryResourceID) (ref Dir
 		}
 
 		// The location is not defined.
-		return DirRef{}, fmt.Errorf("failed to resolve the \"%s\" directory: the \"%s\" parent directory is not defined in the deployment's resources", dir, next)
+		return DirRef{}, dirResolutionError(dir, ResourceNotDefined{Kind: ResourceKindDirectory, ID: string(next)})
 	}
 
 	// Reverse the order of the directories that were recorded, so they can
@@ -90,6 +103,7 @@ func (fs FileSystemResources) ResolveDirectory(dir DirectoryResourceID) (ref Dir
 
 	return DirRef{
 		Root:    root,
+		Network: network,
 		Lineage: lineage,
 	}, nil
 }
@@ -102,33 +116,38 @@ func (fs FileSystemResources) ResolveDirectory(dir DirectoryResourceID) (ref Dir
 //
 // If the file cannot be resolved, an error is returned.
 func (fs FileSystemResources) ResolveFile(file FileResourceID) (ref FileRef, err error) {
-	// TODO: Consider making custom error types for resolution.
-
 	// Look up the file by its ID.
 	data, exists := fs.Files[file]
 	if !exists {
-		return FileRef{}, fmt.Errorf("the \"%s\" file is not defined in the deployment's resources", file)
+		return FileRef{}, ResourceNotDefined{Kind: ResourceKindFile, ID: string(file)}
 	}
 
 	// Make sure the file has a location.
 	if data.Location == "" {
-		return FileRef{}, fmt.Errorf("the \"%s\" file does not have a location", file)
+		return FileRef{}, MissingLocation{Kind: ResourceKindFile, ID: string(file)}
 	}
 
 	// Resolve the file's parent directory.
 	dir, err := fs.ResolveDirectory(data.Location)
 	if err != nil {
-		return FileRef{}, fmt.Errorf("failed to resolve the \"%s\" file: %w", file, err)
+		return FileRef{}, ResolutionError{Kind: ResourceKindFile, ID: string(file), Err: err}
 	}
 
 	return FileRef{
 		Root:     dir.Root,
+		Network:  dir.Network,
 		Lineage:  dir.Lineage,
 		FileID:   file,
 		FilePath: data.Path,
 	}, nil
 }
 
+// dirResolutionError returns a resolution error for dir, which could not
+// be resolved because of a problem with one of its parents.
+func dirResolutionError(dir DirectoryResourceID, err error) error {
+	return ResolutionError{Kind: ResourceKindDirectory, ID: string(dir), Err: err}
+}
+
 // DirectoryResourceMap holds a set of directory resources mapped by their
 // identifiers.
 type DirectoryResourceMap map[DirectoryResourceID]DirectoryResource
@@ -139,21 +158,70 @@ type DirectoryResourceID string
 // DirectoryType declares the type of a directory resource.
 type DirectoryType string
 
-// FileResource describes a directory resource.
+// DirectoryResource describes a directory resource.
+//
+// A directory is normally located within a well-known directory or another
+// directory resource. A directory that specifies a UNC path in Share is
+// instead rooted at a network share, and does not have a location. If
+// Credential is provided, it names a credential in the Windows Credential
+// Manager whose user name and password are used to connect to the share,
+// so that the deployment file never holds the password.
 type DirectoryResource struct {
-	Location DirectoryResourceID // A well-known directory, or another directory ID.
-	Path     string              // Relative to location
+	Location   DirectoryResourceID `json:"location,omitempty"`   // A well-known directory, or another directory ID.
+	Path       string              `json:"path,omitempty"`       // Relative to location
+	Share      string              `json:"share,omitempty"`      // A UNC path, such as \\server\share\dir
+	Credential string              `json:"credential,omitempty"` // Used to connect to the share
+}
+
+// Validate returns a non-nil error if the directory resource is invalid.
+func (dir DirectoryResource) Validate() error {
+	if dir.Share == "" {
+		if dir.Credential != "" {
+			return errors.New("a credential is only accepted for network share directories")
+		}
+		return nil
+	}
+	if dir.Location != "" || dir.Path != "" {
+		return errors.New("a network share directory cannot have a location or path")
+	}
+	if _, err := ShareName(dir.Share); err != nil {
+		return err
+	}
+	return nil
+}
+
+// networkRoot returns a network root for the directory, which must be a
+// network share directory.
+func (dir DirectoryResource) networkRoot(id DirectoryResourceID) NetworkRoot {
+	return NetworkRoot{
+		id:         id,
+		path:       dir.Share,
+		credential: dir.Credential,
+	}
 }
 
 // DirRef is a resolved reference to a directory on the local file system.
+//
+// The directory is rooted at a known folder, unless Network is non-zero, in
+// which case it is rooted at a network share.
 type DirRef struct {
 	Root    KnownFolder
+	Network NetworkRoot
 	Lineage []DirectoryResource
 }
 
+// RootPath returns the path of the directory's root, which is either a
+// known folder or a network share.
+func (ref DirRef) RootPath() (string, error) {
+	if !ref.Network.IsZero() {
+		return ref.Network.Path(), nil
+	}
+	return ref.Root.Path()
+}
+
 // Path returns the path of the directory on the local file system.
 func (ref DirRef) Path() (string, error) {
-	root, err := ref.Root.Path()
+	root, err := ref.RootPath()
 	if err != nil {
 		return "", err
 	}
@@ -180,14 +248,19 @@ type FileResourceMap map[FileResourceID]FileResource
 type FileResourceID string
 
 // FileResource describes a file resource.
+//
+// If Attributes are provided, files that are copied to the file resource
+// are verified against them.
 type FileResource struct {
-	Location DirectoryResourceID // A well-known directory, or another directory ID.
-	Path     string              // Relative to location
+	Location   DirectoryResourceID `json:"location"`            // A well-known directory, or another directory ID.
+	Path       string              `json:"path,omitempty"`      // Relative to location
+	Attributes FileAttributes      `json:"attributes,omitzero"` // Expected size and hashes
 }
 
 // FileRef is a resolved reference to a file on the local file system.
 type FileRef struct {
 	Root     KnownFolder
+	Network  NetworkRoot
 	Lineage  []DirectoryResource
 	FileID   FileResourceID
 	FilePath string
@@ -197,6 +270,7 @@ type FileRef struct {
 func (ref FileRef) Dir() DirRef {
 	return DirRef{
 		Root:    ref.Root,
+		Network: ref.Network,
 		Lineage: ref.Lineage,
 	}
 }
@@ -221,10 +295,17 @@ func (ref FileRef) Path() (string, error) {
 type KnownFolderMap map[DirectoryResourceID]KnownFolder
 
 // KnownFolder is a folder with a known location.
+//
+// Per-user known folders, such as the desktop or the roaming application
+// data folder, resolve to the folders of the user that runs the
+// deployment. When a deployment runs as the local system account, they
+// resolve to the folders of the system profile.
 type KnownFolder struct {
 	id        DirectoryResourceID
 	guid      *windows.KNOWNFOLDERID
+	env       string
 	protected bool
+	perUser   bool
 }
 
 // ID returns the LeafBridge directory ID of the known folder.
@@ -248,12 +329,41 @@ func (kf KnownFolder) Protected() bool {
 	return kf.protected
 }
 
+// PerUser returns true if the known folder belongs to the user that runs
+// the deployment.
+func (kf KnownFolder) PerUser() bool {
+	return kf.perUser
+}
+
 // Path retrieves the path to the known folder on the local system.
+//
+// If Windows is unable to provide the path of the known folder, and the
+// known folder has an environment-based fallback, such as %windir%, the
+// path is determined from the environment instead.
 func (kf KnownFolder) Path() (path string, err error) {
 	path, err = windows.KnownFolderPath(kf.guid, 0)
+	if err == nil || kf.env == "" {
+		return
+	}
+	if fallback, expandErr := expandFolderEnv(kf.env); expandErr == nil {
+		return fallback, nil
+	}
 	return
 }
 
+// expandFolderEnv expands the environment variables in s. It returns an
+// error if any of them are not defined.
+func expandFolderEnv(s string) (string, error) {
+	expanded, err := registry.ExpandString(s)
+	if err != nil {
+		return "", err
+	}
+	if expanded == "" || strings.Contains(expanded, "%") {
+		return "", fmt.Errorf("the environment variables in \"%s\" could not be expanded", s)
+	}
+	return expanded, nil
+}
+
 // GetKnownFolder looks for a known folder with the given directory resource
 // ID. If one is found, it is returned and ok will be true.
 func GetKnownFolder(id DirectoryResourceID) (folder KnownFolder, ok bool) {
@@ -262,11 +372,38 @@ func GetKnownFolder(id DirectoryResourceID) (folder KnownFolder, ok bool) {
 }
 
 var knownFolders = KnownFolderMap{
-	"common-start-menu": KnownFolder{guid: windows.FOLDERID_CommonStartMenu, id: "common-start-menu"},
-	"public-desktop":    KnownFolder{guid: windows.FOLDERID_PublicDesktop, id: "public-desktop"},
-	"program-data":      KnownFolder{guid: windows.FOLDERID_ProgramData, id: "program-data"},
-	"program-files":     KnownFolder{guid: windows.FOLDERID_ProgramFiles, id: "program-files"},
-	"program-files-x86": KnownFolder{guid: windows.FOLDERID_ProgramFilesX86, id: "program-files-x86"},
-	"program-files-x64": KnownFolder{guid: windows.FOLDERID_ProgramFilesX64, id: "program-files-x64"},
-	"system":            KnownFolder{guid: windows.FOLDERID_System, id: "system", protected: true},
+	// System-wide folders.
+	"windows":                  KnownFolder{guid: windows.FOLDERID_Windows, env: `%SystemRoot%`, id: "windows", protected: true},
+	"system":                   KnownFolder{guid: windows.FOLDERID_System, env: `%SystemRoot%\System32`, id: "system", protected: true},
+	"system-x86":               KnownFolder{guid: windows.FOLDERID_SystemX86, env: `%SystemRoot%\SysWOW64`, id: "system-x86", protected: true},
+	"fonts":                    KnownFolder{guid: windows.FOLDERID_Fonts, env: `%SystemRoot%\Fonts`, id: "fonts"},
+	"program-data":             KnownFolder{guid: windows.FOLDERID_ProgramData, env: `%ProgramData%`, id: "program-data"},
+	"program-files":            KnownFolder{guid: windows.FOLDERID_ProgramFiles, env: `%ProgramFiles%`, id: "program-files"},
+	"program-files-x86":        KnownFolder{guid: windows.FOLDERID_ProgramFilesX86, env: `%ProgramFiles(x86)%`, id: "program-files-x86"},
+	"program-files-x64":        KnownFolder{guid: windows.FOLDERID_ProgramFilesX64, env: `%ProgramW6432%`, id: "program-files-x64"},
+	"program-files-common":     KnownFolder{guid: windows.FOLDERID_ProgramFilesCommon, env: `%CommonProgramFiles%`, id: "program-files-common"},
+	"program-files-common-x86": KnownFolder{guid: windows.FOLDERID_ProgramFilesCommonX86, env: `%CommonProgramFiles(x86)%`, id: "program-files-common-x86"},
+	"program-files-common-x64": KnownFolder{guid: windows.FOLDERID_ProgramFilesCommonX64, env: `%CommonProgramW6432%`, id: "program-files-common-x64"},
+	"common-start-menu":        KnownFolder{guid: windows.FOLDERID_CommonStartMenu, env: `%ProgramData%\Microsoft\Windows\Start Menu`, id: "common-start-menu"},
+	"common-programs":          KnownFolder{guid: windows.FOLDERID_CommonPrograms, env: `%ProgramData%\Microsoft\Windows\Start Menu\Programs`, id: "common-programs"},
+	"common-startup":           KnownFolder{guid: windows.FOLDERID_CommonStartup, env: `%ProgramData%\Microsoft\Windows\Start Menu\Programs\Startup`, id: "common-startup"},
+	"common-templates":         KnownFolder{guid: windows.FOLDERID_CommonTemplates, env: `%ProgramData%\Microsoft\Windows\Templates`, id: "common-templates"},
+	"public":                   KnownFolder{guid: windows.FOLDERID_Public, env: `%PUBLIC%`, id: "public"},
+	"public-desktop":           KnownFolder{guid: windows.FOLDERID_PublicDesktop, env: `%PUBLIC%\Desktop`, id: "public-desktop"},
+	"public-documents":         KnownFolder{guid: windows.FOLDERID_PublicDocuments, env: `%PUBLIC%\Documents`, id: "public-documents"},
+	"public-downloads":         KnownFolder{guid: windows.FOLDERID_PublicDownloads, env: `%PUBLIC%\Downloads`, id: "public-downloads"},
+	"user-profiles":            KnownFolder{guid: windows.FOLDERID_UserProfiles, env: `%SystemDrive%\Users`, id: "user-profiles"},
+
+	// Per-user folders.
+	"profile":            KnownFolder{guid: windows.FOLDERID_Profile, env: `%USERPROFILE%`, id: "profile", perUser: true},
+	"desktop":            KnownFolder{guid: windows.FOLDERID_Desktop, env: `%USERPROFILE%\Desktop`, id: "desktop", perUser: true},
+	"documents":          KnownFolder{guid: windows.FOLDERID_Documents, env: `%USERPROFILE%\Documents`, id: "documents", perUser: true},
+	"downloads":          KnownFolder{guid: windows.FOLDERID_Downloads, env: `%USERPROFILE%\Downloads`, id: "downloads", perUser: true},
+	"roaming-app-data":   KnownFolder{guid: windows.FOLDERID_RoamingAppData, env: `%APPDATA%`, id: "roaming-app-data", perUser: true},
+	"local-app-data":     KnownFolder{guid: windows.FOLDERID_LocalAppData, env: `%LOCALAPPDATA%`, id: "local-app-data", perUser: true},
+	"local-app-data-low": KnownFolder{guid: windows.FOLDERID_LocalAppDataLow, env: `%USERPROFILE%\AppData\LocalLow`, id: "local-app-data-low", perUser: true},
+	"start-menu":         KnownFolder{guid: windows.FOLDERID_StartMenu, env: `%APPDATA%\Microsoft\Windows\Start Menu`, id: "start-menu", perUser: true},
+	"programs":           KnownFolder{guid: windows.FOLDERID_Programs, env: `%APPDATA%\Microsoft\Windows\Start Menu\Programs`, id: "programs", perUser: true},
+	"startup":            KnownFolder{guid: windows.FOLDERID_Startup, env: `%APPDATA%\Microsoft\Windows\Start Menu\Programs\Startup`, id: "startup", perUser: true},
+	"user-program-files": KnownFolder{guid: windows.FOLDERID_UserProgramFiles, env: `%LOCALAPPDATA%\Programs`, id: "user-program-files", perUser: true},
 }
@@ -1,5 +1,12 @@
 package lbdeploy
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
 // FlowMap holds a set of deployment flows mapped by their identifiers.
 type FlowMap map[FlowID]Flow
 
@@ -8,13 +15,134 @@ type FlowID string
 
 // Flow is a flow of actions within a deployment.
 //
+// Description is a short, human-readable name for the flow. It is included
+// in the events recorded for the flow.
+//
+// ExpectedDuration is how long the flow is expected to take. If it is
+// still running after that long, a warning is recorded. Unlike a timeout,
+// the flow is not stopped.
+//
+// Before actions are invoked ahead of the flow's main actions. If any of
+// them fail the main actions are skipped. After actions are always invoked
+// once the flow has started, even if earlier actions failed.
+//
+// DependsOn lists flows that must complete successfully before the flow is
+// invoked by the deployment engine.
+//
+// Rollback maps the IDs of main actions to compensating actions that undo
+// their effects. They are invoked along with any rollback actions declared
+// by the actions themselves, which lets a flow's actions be reordered
+// without disturbing its rollback plan.
+//
 // TODO: Consider renaming "Preconditions" to "Requirements".
 type Flow struct {
-	Constraints   ConditionList `json:"constraints,omitzero"`
-	Preconditions ConditionList `json:"preconditions,omitzero"`
-	Locks         []LockID      `json:"locks,omitzero"`
-	Behavior      Behavior      `json:"behavior,omitzero"`
-	Actions       []Action      `json:"actions,omitzero"`
+	Description      string            `json:"description,omitempty"`
+	ExpectedDuration datatype.Duration `json:"expected-duration,omitempty"`
+	DependsOn        []FlowID          `json:"depends-on,omitempty"`
+	Constraints      ConditionList     `json:"constraints,omitzero"`
+	Preconditions    ConditionList     `json:"preconditions,omitzero"`
+	Locks            []LockID          `json:"locks,omitzero"`
+	Behavior         Behavior          `json:"behavior,omitzero"`
+	Before           []Action          `json:"before,omitzero"`
+	Actions          []Action          `json:"actions,omitzero"`
+	After            []Action          `json:"after,omitzero"`
+	Rollback         RollbackMap       `json:"rollback,omitzero"`
+}
+
+// RollbackMap maps the IDs of actions to the rollback actions that undo
+// their effects.
+type RollbackMap map[ActionID][]Action
+
+// ActionCount returns the number of before, main and after actions in the
+// flow.
+func (flow Flow) ActionCount() int {
+	return len(flow.Before) + len(flow.Actions) + len(flow.After)
+}
+
+// FindAction returns the index of the before, main or after action with
+// the given ID.
+func (flow Flow) FindAction(id ActionID) (index int, found bool) {
+	for i, action := range flow.allActions() {
+		if action.ID == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// allActions returns the before, main and after actions of the flow, in
+// the order that they are numbered.
+func (flow Flow) allActions() []Action {
+	actions := make([]Action, 0, flow.ActionCount())
+	actions = append(actions, flow.Before...)
+	actions = append(actions, flow.Actions...)
+	actions = append(actions, flow.After...)
+	return actions
+}
+
+// validateActions returns a non-nil error if any of the flow's action IDs
+// are invalid or used more than once, or if any of its action dependencies
+// or rollback mappings refer to actions that can't be found. The IDs of
+// transaction members and rollback actions are included.
+func (flow Flow) validateActions() error {
+	seen := make(map[ActionID]bool)
+	var visit func(actions []Action, nested bool) error
+	visit = func(actions []Action, nested bool) error {
+		for _, action := range actions {
+			if action.ID != "" {
+				if err := action.ID.Validate(); err != nil {
+					return err
+				}
+				if seen[action.ID] {
+					return fmt.Errorf("the action ID \"%s\" is used more than once", action.ID)
+				}
+				seen[action.ID] = true
+			}
+			if nested && len(action.DependsOn) > 0 {
+				return errors.New("only before, main and after actions may depend on other actions")
+			}
+			if err := visit(action.Actions, true); err != nil {
+				return err
+			}
+			if err := visit(action.Rollback, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(flow.allActions(), false); err != nil {
+		return err
+	}
+	for _, actions := range flow.Rollback {
+		if err := visit(actions, true); err != nil {
+			return err
+		}
+	}
+
+	// Dependencies must refer to earlier actions, so that they can be
+	// evaluated as the flow runs.
+	for i, action := range flow.allActions() {
+		for _, dependency := range action.DependsOn {
+			index, found := flow.FindAction(dependency)
+			if !found {
+				return fmt.Errorf("action %d depends on the \"%s\" action, which does not exist", i+1, dependency)
+			}
+			if index >= i {
+				return fmt.Errorf("action %d depends on the \"%s\" action, which does not come before it", i+1, dependency)
+			}
+		}
+	}
+
+	// Rollback mappings must refer to main actions, which are the only
+	// actions that are rolled back.
+	for id := range flow.Rollback {
+		index, found := flow.FindAction(id)
+		if !found || index < len(flow.Before) || index >= len(flow.Before)+len(flow.Actions) {
+			return fmt.Errorf("rollback actions are mapped to \"%s\", which is not the ID of a main action", id)
+		}
+	}
+
+	return nil
 }
 
 // FlowStats hold statistics about a flow that has been invoked.
@@ -22,3 +150,70 @@ type FlowStats struct {
 	ActionsCompleted int
 	ActionsFailed    int
 }
+
+// Order returns the given flows and all of the flows they depend on, in an
+// order that satisfies their dependencies. Each flow appears only once, and
+// flows are otherwise kept in the order they were requested.
+//
+// It returns an error if a flow does not exist or if a dependency cycle is
+// found.
+func (flows FlowMap) Order(requested ...FlowID) ([]FlowID, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var (
+		ordered []FlowID
+		state   = make(map[FlowID]int)
+		visit   func(id FlowID, path []FlowID) error
+	)
+
+	visit = func(id FlowID, path []FlowID) error {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("the \"%s\" flow has a circular dependency: %s", id, flowPath(append(path, id)))
+		}
+
+		definition, found := flows[id]
+		if !found {
+			if len(path) > 0 {
+				return fmt.Errorf("the \"%s\" flow depends on the \"%s\" flow, which does not exist", path[len(path)-1], id)
+			}
+			return fmt.Errorf("the \"%s\" flow does not exist", id)
+		}
+
+		state[id] = visiting
+		for _, dependency := range definition.DependsOn {
+			if err := visit(dependency, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+
+		ordered = append(ordered, id)
+		return nil
+	}
+
+	for _, id := range requested {
+		if err := visit(id, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// flowPath returns a string describing a path through a set of flows.
+func flowPath(path []FlowID) string {
+	var out string
+	for i, id := range path {
+		if i > 0 {
+			out += " -> "
+		}
+		out += string(id)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package lbdeploy_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+func TestFlowMapOrder(t *testing.T) {
+	fixtures := []struct {
+		Name      string
+		Flows     lbdeploy.FlowMap
+		Requested []lbdeploy.FlowID
+		Out       []lbdeploy.FlowID
+		Err       string
+	}{
+		{
+			Name: "Independent",
+			Flows: lbdeploy.FlowMap{
+				"a": {},
+				"b": {},
+				"c": {},
+			},
+			Requested: []lbdeploy.FlowID{"c", "a", "b"},
+			Out:       []lbdeploy.FlowID{"c", "a", "b"},
+		},
+		{
+			Name: "Chain",
+			Flows: lbdeploy.FlowMap{
+				"a": {},
+				"b": {DependsOn: []lbdeploy.FlowID{"a"}},
+				"c": {DependsOn: []lbdeploy.FlowID{"b"}},
+			},
+			Requested: []lbdeploy.FlowID{"c"},
+			Out:       []lbdeploy.FlowID{"a", "b", "c"},
+		},
+		{
+			Name: "Diamond",
+			Flows: lbdeploy.FlowMap{
+				"base":  {},
+				"left":  {DependsOn: []lbdeploy.FlowID{"base"}},
+				"right": {DependsOn: []lbdeploy.FlowID{"base"}},
+				"top":   {DependsOn: []lbdeploy.FlowID{"left", "right"}},
+			},
+			Requested: []lbdeploy.FlowID{"top"},
+			Out:       []lbdeploy.FlowID{"base", "left", "right", "top"},
+		},
+		{
+			Name: "DiamondDependencyOrder",
+			Flows: lbdeploy.FlowMap{
+				"base":  {},
+				"left":  {DependsOn: []lbdeploy.FlowID{"base"}},
+				"right": {DependsOn: []lbdeploy.FlowID{"base"}},
+				"top":   {DependsOn: []lbdeploy.FlowID{"right", "left"}},
+			},
+			Requested: []lbdeploy.FlowID{"top"},
+			Out:       []lbdeploy.FlowID{"base", "right", "left", "top"},
+		},
+		{
+			Name: "RequestedDependency",
+			Flows: lbdeploy.FlowMap{
+				"a": {},
+				"b": {DependsOn: []lbdeploy.FlowID{"a"}},
+			},
+			Requested: []lbdeploy.FlowID{"b", "a"},
+			Out:       []lbdeploy.FlowID{"a", "b"},
+		},
+		{
+			Name: "Repeated",
+			Flows: lbdeploy.FlowMap{
+				"a": {},
+				"b": {},
+			},
+			Requested: []lbdeploy.FlowID{"a", "b", "a"},
+			Out:       []lbdeploy.FlowID{"a", "b"},
+		},
+		{
+			Name: "Cycle",
+			Flows: lbdeploy.FlowMap{
+				"a": {DependsOn: []lbdeploy.FlowID{"b"}},
+				"b": {DependsOn: []lbdeploy.FlowID{"c"}},
+				"c": {DependsOn: []lbdeploy.FlowID{"a"}},
+			},
+			Requested: []lbdeploy.FlowID{"a"},
+			Err:       "circular dependency: a -> b -> c -> a",
+		},
+		{
+			Name: "SelfDependency",
+			Flows: lbdeploy.FlowMap{
+				"a": {DependsOn: []lbdeploy.FlowID{"a"}},
+			},
+			Requested: []lbdeploy.FlowID{"a"},
+			Err:       "circular dependency: a -> a",
+		},
+		{
+			Name: "UnknownFlow",
+			Flows: lbdeploy.FlowMap{
+				"a": {},
+			},
+			Requested: []lbdeploy.FlowID{"missing"},
+			Err:       "the \"missing\" flow does not exist",
+		},
+		{
+			Name: "UnknownDependency",
+			Flows: lbdeploy.FlowMap{
+				"a": {DependsOn: []lbdeploy.FlowID{"missing"}},
+			},
+			Requested: []lbdeploy.FlowID{"a"},
+			Err:       "the \"a\" flow depends on the \"missing\" flow, which does not exist",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			ordered, err := fixture.Flows.Order(fixture.Requested...)
+			if fixture.Err != "" {
+				if err == nil {
+					t.Fatalf("expected an error containing \"%s\", got order %v", fixture.Err, ordered)
+				}
+				if !strings.Contains(err.Error(), fixture.Err) {
+					t.Fatalf("unexpected error: got \"%s\", want one containing \"%s\"", err, fixture.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ordered, fixture.Out) {
+				t.Fatalf("unexpected order: got %v, want %v", ordered, fixture.Out)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package lbdeploy
+
+import "fmt"
+
+// Fragment is a partial deployment that can be included by deployments. It
+// holds shared definitions, such as common locks, staging directories and
+// package sources, that would otherwise be duplicated in each deployment.
+type Fragment struct {
+	Include    []string     `json:"include,omitempty"`
+	Apps       AppMap       `json:"apps,omitzero"`
+	Conditions ConditionMap `json:"conditions,omitzero"`
+	Commands   CommandMap   `json:"commands,omitzero"`
+	Resources  Resources    `json:"resources,omitzero"`
+}
+
+// MergeFragment merges the definitions in a fragment into the deployment.
+//
+// Definitions in the deployment take precedence over definitions with the
+// same identifier in the fragment. If the same identifier has already been
+// merged from another fragment, an error is returned, because the result
+// would depend on the order of the includes.
+//
+// The merged set records the identifiers that have been merged from
+// fragments. It must be shared across all of the fragments merged into the
+// deployment.
+func (dep *Deployment) MergeFragment(fragment Fragment, merged *MergedSet) error {
+	if merged.ids == nil {
+		merged.ids = make(map[string]struct{})
+	}
+	var err error
+	if dep.Apps, err = mergeMap(dep.Apps, fragment.Apps, "app", merged); err != nil {
+		return err
+	}
+	if dep.Conditions, err = mergeMap(dep.Conditions, fragment.Conditions, "condition", merged); err != nil {
+		return err
+	}
+	if dep.Commands, err = mergeMap(dep.Commands, fragment.Commands, "command", merged); err != nil {
+		return err
+	}
+
+	r, f := &dep.Resources, fragment.Resources
+	if r.Processes, err = mergeMap(r.Processes, f.Processes, "process", merged); err != nil {
+		return err
+	}
+	if r.Mutexes, err = mergeMap(r.Mutexes, f.Mutexes, "mutex", merged); err != nil {
+		return err
+	}
+	if r.Semaphores, err = mergeMap(r.Semaphores, f.Semaphores, "semaphore", merged); err != nil {
+		return err
+	}
+	if r.Locks, err = mergeMap(r.Locks, f.Locks, "lock", merged); err != nil {
+		return err
+	}
+	if r.Registry.Keys, err = mergeMap(r.Registry.Keys, f.Registry.Keys, "registry key", merged); err != nil {
+		return err
+	}
+	if r.Registry.Values, err = mergeMap(r.Registry.Values, f.Registry.Values, "registry value", merged); err != nil {
+		return err
+	}
+	if r.FileSystem.Directories, err = mergeMap(r.FileSystem.Directories, f.FileSystem.Directories, "directory", merged); err != nil {
+		return err
+	}
+	if r.FileSystem.Files, err = mergeMap(r.FileSystem.Files, f.FileSystem.Files, "file", merged); err != nil {
+		return err
+	}
+	if r.Packages, err = mergeMap(r.Packages, f.Packages, "package", merged); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MergedSet keeps track of the definitions that have been merged into a
+// deployment from fragments. Its zero value is ready for use.
+type MergedSet struct {
+	ids map[string]struct{}
+}
+
+// mergeMap adds the entries in src that are not already present in dst.
+// It returns the resulting map, which will be allocated if dst is nil.
+func mergeMap[K ~string, V any, M ~map[K]V](dst, src M, kind string, merged *MergedSet) (M, error) {
+	for id, value := range src {
+		key := kind + ":" + string(id)
+		if _, exists := dst[id]; exists {
+			if _, fromFragment := merged.ids[key]; fromFragment {
+				return dst, fmt.Errorf("the \"%s\" %s is defined by more than one included fragment", id, kind)
+			}
+			continue // The deployment's own definition takes precedence.
+		}
+		if dst == nil {
+			dst = make(M)
+		}
+		dst[id] = value
+		merged.ids[key] = struct{}{}
+	}
+	return dst, nil
+}
@@ -0,0 +1,265 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// LibraryID is a unique identifier for a shared library.
+type LibraryID string
+
+// Library is a versioned collection of shared definitions that can be
+// referenced by many deployments. Unlike a fragment, the definitions in a
+// library are merged into a deployment under a namespace, so that they
+// cannot collide with the deployment's own definitions.
+type Library struct {
+	ID         LibraryID        `json:"id"`
+	Version    datatype.Version `json:"version,omitempty"`
+	Apps       AppMap           `json:"apps,omitzero"`
+	Conditions ConditionMap     `json:"conditions,omitzero"`
+	Commands   CommandMap       `json:"commands,omitzero"`
+	Resources  Resources        `json:"resources,omitzero"`
+}
+
+// LibraryReference is a reference from a deployment to a shared library.
+//
+// The definitions in the library are merged into the deployment with the
+// namespace and a period as a prefix. A "close-browsers" command in a
+// library with the "common" namespace is referenced by the deployment as
+// "common.close-browsers".
+//
+// If a version is specified, the library must have the same version.
+type LibraryReference struct {
+	Namespace string           `json:"namespace"`
+	Location  string           `json:"location"`
+	Version   datatype.Version `json:"version,omitempty"`
+}
+
+// Validate returns a non-nil error if the library reference is invalid.
+func (ref LibraryReference) Validate() error {
+	switch {
+	case ref.Namespace == "":
+		return errors.New("the library reference does not specify a namespace")
+	case strings.ContainsAny(ref.Namespace, ". \t"):
+		return fmt.Errorf("the library namespace \"%s\" contains periods or whitespace", ref.Namespace)
+	case ref.Location == "":
+		return fmt.Errorf("the \"%s\" library reference does not specify a location", ref.Namespace)
+	}
+	return nil
+}
+
+// CheckVersion returns a non-nil error if the library does not satisfy the
+// version required by ref.
+func (ref LibraryReference) CheckVersion(lib Library) error {
+	if ref.Version == "" {
+		return nil
+	}
+	if datatype.CompareVersions(ref.Version, lib.Version) != 0 {
+		return fmt.Errorf("version %s of the \"%s\" library is required, but version %s was found", ref.Version, lib.ID, lib.Version)
+	}
+	return nil
+}
+
+// Namespaced returns the definitions in the library as a fragment, with
+// each identifier prefixed by the given namespace. References between
+// definitions within the library are updated to match. References to
+// identifiers that the library does not define, such as well-known
+// directories and registry roots, are left as they are.
+func (lib Library) Namespaced(namespace string) Fragment {
+	ns := libraryNamespace{Library: lib, prefix: namespace + "."}
+	r := lib.Resources
+
+	return Fragment{
+		Apps:       namespaceMap(lib.Apps, ns.prefix, ns.app),
+		Conditions: namespaceMap(lib.Conditions, ns.prefix, ns.condition),
+		Commands:   namespaceMap(lib.Commands, ns.prefix, ns.command),
+		Resources: Resources{
+			Processes:  namespaceMap(r.Processes, ns.prefix, nil),
+			Mutexes:    namespaceMap(r.Mutexes, ns.prefix, nil),
+			Semaphores: namespaceMap(r.Semaphores, ns.prefix, nil),
+			Locks: namespaceMap(r.Locks, ns.prefix, func(lock Lock) Lock {
+				lock.Mutex = namespaceRef(lock.Mutex, ns.prefix, r.Mutexes)
+				lock.Semaphore = namespaceRef(lock.Semaphore, ns.prefix, r.Semaphores)
+				return lock
+			}),
+			Registry: RegistryResources{
+				Keys: namespaceMap(r.Registry.Keys, ns.prefix, func(key RegistryKeyResource) RegistryKeyResource {
+					key.Location = namespaceRef(key.Location, ns.prefix, r.Registry.Keys)
+					return key
+				}),
+				Values: namespaceMap(r.Registry.Values, ns.prefix, func(value RegistryValueResource) RegistryValueResource {
+					value.Key = namespaceRef(value.Key, ns.prefix, r.Registry.Keys)
+					return value
+				}),
+			},
+			FileSystem: FileSystemResources{
+				Directories: namespaceMap(r.FileSystem.Directories, ns.prefix, func(dir DirectoryResource) DirectoryResource {
+					dir.Location = namespaceRef(dir.Location, ns.prefix, r.FileSystem.Directories)
+					return dir
+				}),
+				Files: namespaceMap(r.FileSystem.Files, ns.prefix, func(file FileResource) FileResource {
+					file.Location = namespaceRef(file.Location, ns.prefix, r.FileSystem.Directories)
+					return file
+				}),
+			},
+			Packages: namespaceMap(r.Packages, ns.prefix, ns.pkg),
+		},
+	}
+}
+
+// libraryNamespace updates references between definitions in a library.
+type libraryNamespace struct {
+	Library
+	prefix string
+}
+
+func (ns libraryNamespace) app(app Application) Application {
+	app.Detection.Present = namespaceRef(app.Detection.Present, ns.prefix, ns.Conditions)
+	app.Detection.Version = namespaceRef(app.Detection.Version, ns.prefix, ns.Resources.Registry.Values)
+	app.Detection.File.Path = namespaceRef(app.Detection.File.Path, ns.prefix, ns.Resources.FileSystem.Files)
+	if app.Processes != nil {
+		processes := make([]ProcessResourceID, len(app.Processes))
+		for i, process := range app.Processes {
+			processes[i] = namespaceRef(process, ns.prefix, ns.Resources.Processes)
+		}
+		app.Processes = processes
+	}
+	return app
+}
+
+func (ns libraryNamespace) apps(apps AppList) AppList {
+	if apps == nil {
+		return nil
+	}
+	out := make(AppList, len(apps))
+	for i, app := range apps {
+		out[i] = namespaceRef(app, ns.prefix, ns.Apps)
+	}
+	return out
+}
+
+func (ns libraryNamespace) conditionList(list ConditionList) ConditionList {
+	if list == nil {
+		return nil
+	}
+	out := make(ConditionList, len(list))
+	for i, condition := range list {
+		out[i] = namespaceRef(condition, ns.prefix, ns.Conditions)
+	}
+	return out
+}
+
+func (ns libraryNamespace) command(command Command) Command {
+	command = ns.commandRefs(command)
+	command.Executable = ExecutableID(namespaceRef(FileResourceID(command.Executable), ns.prefix, ns.Resources.FileSystem.Files))
+	return command
+}
+
+// commandRefs updates the references of a command that are the same for
+// regular commands and package commands. The executable of a package
+// command refers to a package file, so it is handled by the caller.
+func (ns libraryNamespace) commandRefs(command Command) Command {
+	dirs := ns.Resources.FileSystem.Directories
+	command.Installs = ns.apps(command.Installs)
+	command.Uninstalls = ns.apps(command.Uninstalls)
+	command.WorkingDirectory = namespaceRef(command.WorkingDirectory, ns.prefix, dirs)
+	command.Log.Upload = namespaceRef(command.Log.Upload, ns.prefix, dirs)
+	command.PostConditions = ns.conditionList(command.PostConditions)
+	return command
+}
+
+func (ns libraryNamespace) pkg(pkg Package) Package {
+	pkg.Commands = namespaceMap(pkg.Commands, "", ns.commandRefs)
+	pkg.Sources = ns.sources(pkg.Sources)
+	if pkg.Variants != nil {
+		variants := make([]PackageVariant, len(pkg.Variants))
+		for i, variant := range pkg.Variants {
+			variant.Sources = ns.sources(variant.Sources)
+			variants[i] = variant
+		}
+		pkg.Variants = variants
+	}
+	if pkg.Deltas != nil {
+		deltas := make([]PackageDelta, len(pkg.Deltas))
+		for i, delta := range pkg.Deltas {
+			delta.Sources = ns.sources(delta.Sources)
+			deltas[i] = delta
+		}
+		pkg.Deltas = deltas
+	}
+	return pkg
+}
+
+func (ns libraryNamespace) sources(sources []PackageSource) []PackageSource {
+	if sources == nil {
+		return nil
+	}
+	out := make([]PackageSource, len(sources))
+	for i, source := range sources {
+		source.Condition = namespaceRef(source.Condition, ns.prefix, ns.Conditions)
+		out[i] = source
+	}
+	return out
+}
+
+func (ns libraryNamespace) condition(c Condition) Condition {
+	r := ns.Resources
+	switch c.Type {
+	case ConditionTypeSubcondition:
+		c.Subject = string(namespaceRef(ConditionID(c.Subject), ns.prefix, ns.Conditions))
+	case ConditionTypeProcessIsRunning:
+		c.Subject = string(namespaceRef(ProcessResourceID(c.Subject), ns.prefix, r.Processes))
+	case ConditionTypeMutexExists:
+		c.Subject = string(namespaceRef(MutexID(c.Subject), ns.prefix, r.Mutexes))
+	case ConditionTypeRegistryKeyExists:
+		c.Subject = string(namespaceRef(RegistryKeyResourceID(c.Subject), ns.prefix, r.Registry.Keys))
+	case ConditionTypeRegistryValueExists, ConditionTypeRegistryValueComparison:
+		c.Subject = string(namespaceRef(RegistryValueResourceID(c.Subject), ns.prefix, r.Registry.Values))
+	case ConditionTypeDirectoryExists, ConditionTypeDirectoryEmpty, ConditionTypeDirectoryContains, ConditionTypeDirectorySize:
+		c.Subject = string(namespaceRef(DirectoryResourceID(c.Subject), ns.prefix, r.FileSystem.Directories))
+	case ConditionTypeFileExists:
+		c.Subject = string(namespaceRef(FileResourceID(c.Subject), ns.prefix, r.FileSystem.Files))
+	}
+	c.Any = ns.conditions(c.Any)
+	c.All = ns.conditions(c.All)
+	return c
+}
+
+func (ns libraryNamespace) conditions(list []Condition) []Condition {
+	if list == nil {
+		return nil
+	}
+	out := make([]Condition, len(list))
+	for i, c := range list {
+		out[i] = ns.condition(c)
+	}
+	return out
+}
+
+// namespaceMap returns a copy of m with each key prefixed. If fix is
+// non-nil, it is applied to each value.
+func namespaceMap[K ~string, V any, M ~map[K]V](m M, prefix string, fix func(V) V) M {
+	if m == nil {
+		return nil
+	}
+	out := make(M, len(m))
+	for id, value := range m {
+		if fix != nil {
+			value = fix(value)
+		}
+		out[K(prefix+string(id))] = value
+	}
+	return out
+}
+
+// namespaceRef returns id with the prefix applied if it identifies an entry
+// in defined. Otherwise it returns id unchanged.
+func namespaceRef[K ~string, V any, M ~map[K]V](id K, prefix string, defined M) K {
+	if _, found := defined[id]; found {
+		return K(prefix + string(id))
+	}
+	return id
+}
@@ -0,0 +1,243 @@
+package lbdeploy_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// libraryFixture is a library in which every field that can refer to
+// another definition does so.
+var libraryFixture = lbdeploy.Library{
+	ID: "common",
+	Apps: lbdeploy.AppMap{
+		"editor": {
+			Name:        "Editor",
+			ProductCode: "{00000000-0000-0000-0000-000000000000}",
+			Detection: lbdeploy.AppDetection{
+				Present: "editor-present",
+				Version: "editor-version",
+				File:    lbdeploy.AppFileRule{Path: "editor-exe"},
+			},
+			Processes: []lbdeploy.ProcessResourceID{"editor-process"},
+		},
+	},
+	Conditions: lbdeploy.ConditionMap{
+		"editor-present": {
+			Type:    lbdeploy.ConditionTypeFileExists,
+			Subject: "editor-exe",
+			Any: []lbdeploy.Condition{
+				{Type: lbdeploy.ConditionTypeSubcondition, Subject: "online"},
+				{Type: lbdeploy.ConditionTypeProcessIsRunning, Subject: "editor-process"},
+				{Type: lbdeploy.ConditionTypeMutexExists, Subject: "editor-mutex"},
+			},
+			All: []lbdeploy.Condition{
+				{Type: lbdeploy.ConditionTypeRegistryKeyExists, Subject: "editor-key"},
+				{Type: lbdeploy.ConditionTypeRegistryValueExists, Subject: "editor-version"},
+				{Type: lbdeploy.ConditionTypeRegistryValueComparison, Subject: "editor-version"},
+				{Type: lbdeploy.ConditionTypeDirectoryExists, Subject: "editor-dir"},
+				{Type: lbdeploy.ConditionTypeDirectoryEmpty, Subject: "editor-dir"},
+				{Type: lbdeploy.ConditionTypeDirectoryContains, Subject: "editor-dir"},
+				{Type: lbdeploy.ConditionTypeDirectorySize, Subject: "editor-dir"},
+			},
+		},
+		"online": {
+			Type:    lbdeploy.ConditionTypeHostReachable,
+			Subject: "example.com",
+		},
+	},
+	Commands: lbdeploy.CommandMap{
+		"close-editor": {
+			Installs:         lbdeploy.AppList{"editor"},
+			Uninstalls:       lbdeploy.AppList{"editor"},
+			WorkingDirectory: "editor-dir",
+			Executable:       "editor-exe",
+			Log:              lbdeploy.CommandLog{Upload: "logs"},
+			PostConditions:   lbdeploy.ConditionList{"editor-present"},
+		},
+	},
+	Resources: lbdeploy.Resources{
+		Processes: lbdeploy.ProcessResourceMap{
+			"editor-process": {Match: lbdeploy.ProcessMatch{Attribute: "name", Value: "editor.exe"}},
+		},
+		Mutexes: lbdeploy.MutexMap{
+			"editor-mutex": {Name: "Editor", Namespace: lbdeploy.GlobalMutex},
+		},
+		Semaphores: lbdeploy.SemaphoreMap{
+			"editor-slots": {Name: "Editor", Namespace: lbdeploy.GlobalMutex, Count: 2},
+		},
+		Locks: lbdeploy.LockMap{
+			"editor-mutex-lock":     {Mutex: "editor-mutex"},
+			"editor-semaphore-lock": {Semaphore: "editor-slots"},
+		},
+		Registry: lbdeploy.RegistryResources{
+			Keys: lbdeploy.RegistryKeyResourceMap{
+				"vendor-key": {Location: "software", Name: "Vendor"},
+				"editor-key": {Location: "vendor-key", Name: "Editor"},
+			},
+			Values: lbdeploy.RegistryValueResourceMap{
+				"editor-version": {Key: "editor-key", Name: "Version", Type: lbdeploy.RegistryString},
+			},
+		},
+		FileSystem: lbdeploy.FileSystemResources{
+			Directories: lbdeploy.DirectoryResourceMap{
+				"vendor-dir": {Location: "program-files", Path: "Vendor"},
+				"editor-dir": {Location: "vendor-dir", Path: "Editor"},
+				"logs":       {Location: "editor-dir", Path: "Logs"},
+			},
+			Files: lbdeploy.FileResourceMap{
+				"editor-exe": {Location: "editor-dir", Path: "editor.exe"},
+			},
+		},
+		Packages: lbdeploy.PackageMap{
+			"editor-package": {
+				Sources: []lbdeploy.PackageSource{{Type: "http", URL: "https://example.com/editor.zip", Condition: "online"}},
+				Commands: lbdeploy.CommandMap{
+					"install": {
+						Installs:         lbdeploy.AppList{"editor"},
+						Uninstalls:       lbdeploy.AppList{"editor"},
+						WorkingDirectory: "editor-dir",
+						Executable:       "editor-exe", // A package file, not a file resource
+						Log:              lbdeploy.CommandLog{Upload: "logs"},
+						PostConditions:   lbdeploy.ConditionList{"editor-present"},
+					},
+				},
+				Variants: []lbdeploy.PackageVariant{
+					{Languages: []string{"de-DE"}, Sources: []lbdeploy.PackageSource{{Type: "http", URL: "https://example.com/editor-de.zip", Condition: "online"}}},
+				},
+				Deltas: []lbdeploy.PackageDelta{
+					{Sources: []lbdeploy.PackageSource{{Type: "http", URL: "https://example.com/editor.delta", Condition: "online"}}},
+				},
+			},
+		},
+	},
+}
+
+// libraryNonReferences lists fields with identifier types that don't refer
+// to other definitions in a library, or that refer to package files.
+var libraryNonReferences = map[string]bool{
+	".ID":                                    true,
+	".Apps{}.ProductCode":                    true,
+	".Resources.Processes{}.Match.Attribute": true,
+	".Resources.Packages{}.Commands{}.Executable": true,
+}
+
+// libraryExternalReferences lists the identifiers in the library fixture
+// that refer to well-known locations rather than library definitions.
+var libraryExternalReferences = map[string]bool{
+	"program-files": true,
+	"software":      true,
+}
+
+func TestLibraryNamespaced(t *testing.T) {
+	const namespace = "common"
+	const prefix = namespace + "."
+
+	// Make sure that the fixture exercises every reference field, so that
+	// reference fields added later can't be missed by Namespaced without
+	// failing this test.
+	for path, values := range libraryReferences(libraryFixture) {
+		if !libraryNonReferences[path] && !slices.ContainsFunc(values, func(value string) bool { return value != "" }) {
+			t.Errorf("the library fixture does not populate the %s reference field", path)
+		}
+	}
+
+	fragment := libraryFixture.Namespaced(namespace)
+
+	// Every reference should be namespaced, except for references to
+	// well-known locations.
+	for path, values := range libraryReferences(fragment) {
+		if libraryNonReferences[path] {
+			continue
+		}
+		for _, value := range values {
+			if value != "" && !strings.HasPrefix(value, prefix) && !libraryExternalReferences[value] {
+				t.Errorf("the %s reference \"%s\" was not namespaced", path, value)
+			}
+		}
+	}
+
+	// Every condition subject that refers to a library definition should be
+	// namespaced.
+	present := fragment.Conditions[prefix+"editor-present"]
+	subjects := []string{present.Subject}
+	for _, c := range append(present.Any, present.All...) {
+		subjects = append(subjects, c.Subject)
+	}
+	for _, subject := range subjects {
+		if !strings.HasPrefix(subject, prefix) {
+			t.Errorf("the condition subject \"%s\" was not namespaced", subject)
+		}
+	}
+	if online := fragment.Conditions[prefix+"online"]; online.Subject != "example.com" {
+		t.Errorf("the host condition subject was changed to \"%s\"", online.Subject)
+	}
+
+	// Regular command executables refer to file resources, but package
+	// command executables refer to package files.
+	if got := fragment.Commands[prefix+"close-editor"].Executable; got != prefix+"editor-exe" {
+		t.Errorf("unexpected command executable: %s", got)
+	}
+	if got := fragment.Resources.Packages[prefix+"editor-package"].Commands["install"].Executable; got != "editor-exe" {
+		t.Errorf("unexpected package command executable: %s", got)
+	}
+
+	// Check a few references in full.
+	if got := fragment.Resources.FileSystem.Directories[prefix+"vendor-dir"].Location; got != "program-files" {
+		t.Errorf("unexpected well-known directory location: %s", got)
+	}
+	if got := fragment.Resources.FileSystem.Directories[prefix+"editor-dir"].Location; got != prefix+"vendor-dir" {
+		t.Errorf("unexpected directory location: %s", got)
+	}
+	if got := fragment.Resources.Registry.Keys[prefix+"editor-key"].Location; got != prefix+"vendor-key" {
+		t.Errorf("unexpected registry key location: %s", got)
+	}
+
+	// The library itself must not be modified.
+	if got := libraryFixture.Resources.Packages["editor-package"].Sources[0].Condition; got != "online" {
+		t.Errorf("the library's package source condition was modified: %s", got)
+	}
+	if got := libraryFixture.Resources.Packages["editor-package"].Commands["install"].PostConditions[0]; got != "editor-present" {
+		t.Errorf("the library's package command was modified: %s", got)
+	}
+}
+
+// libraryReferences returns the values of every field within v that has a
+// string type whose name ends in "ID", mapped by the path of the field.
+// Map entries are written as "{}" and slice elements as "[]" in paths.
+func libraryReferences(v any) map[string][]string {
+	refs := make(map[string][]string)
+	var walk func(v reflect.Value, path string)
+	walk = func(v reflect.Value, path string) {
+		switch v.Kind() {
+		case reflect.String:
+			if strings.HasSuffix(v.Type().Name(), "ID") {
+				refs[path] = append(refs[path], v.String())
+			}
+		case reflect.Pointer:
+			if !v.IsNil() {
+				walk(v.Elem(), path)
+			}
+		case reflect.Slice, reflect.Array:
+			for i := range v.Len() {
+				walk(v.Index(i), path+"[]")
+			}
+		case reflect.Map:
+			iter := v.MapRange()
+			for iter.Next() {
+				walk(iter.Value(), path+"{}")
+			}
+		case reflect.Struct:
+			for i := range v.NumField() {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i), path+"."+v.Type().Field(i).Name)
+				}
+			}
+		}
+	}
+	walk(reflect.ValueOf(v), "")
+	return refs
+}
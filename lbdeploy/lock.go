@@ -1,5 +1,7 @@
 package lbdeploy
 
+import "github.com/leafbridge/leafbridge-deploy/datatype"
+
 // LockMap holds a set of lockable resources mapped by their identifiers.
 type LockMap map[LockID]Lock
 
@@ -8,15 +10,16 @@ type LockID string
 
 // Lock is a lockable resource that can be used to prevent invocations
 // from competing or interfering with each other.
+//
+// A lock is backed by either a mutex or a semaphore. A mutex permits a
+// single holder, while a semaphore permits as many concurrent holders as
+// its count allows.
 type Lock struct {
 	Description   string            `json:"description,omitempty"`
 	Mutex         MutexID           `json:"mutex,omitempty"`
+	Semaphore     SemaphoreID       `json:"semaphore,omitempty"`
 	ConflictRules LockConflictRules `json:"conflict,omitzero"`
 
-	// TODO: Consider adding these fields:
-	// Type ("mutex")
-	// Max (max number of concurrent lock holders)?
-
 	// TODO: Consider adding file-based locks that refer to a FileID:
 	// File FileID
 }
@@ -25,4 +28,10 @@ type Lock struct {
 // encountered on a lockable resource.
 type LockConflictRules struct {
 	Message string `json:"message,omitempty"`
+
+	// Wait is the maximum amount of time to wait for the lock to become
+	// available before giving up. If it is zero, the engine's default
+	// lock wait is used, which fails immediately unless configured
+	// otherwise.
+	Wait datatype.Duration `json:"wait,omitempty"`
 }
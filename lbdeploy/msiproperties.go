@@ -0,0 +1,66 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// MSIProperties holds Windows Installer properties that are passed to
+// msiexec, such as ALLUSERS, REBOOT, INSTALLDIR or ADDLOCAL, mapped by
+// their names.
+type MSIProperties map[string]string
+
+// Validate returns a non-nil error if any of the property names are not
+// valid Windows Installer property names.
+func (props MSIProperties) Validate() error {
+	for name := range props {
+		if !IsMSIPropertyName(name) {
+			return fmt.Errorf("\"%s\" is not a valid Windows Installer property name", name)
+		}
+	}
+	return nil
+}
+
+// Args returns the properties as PROPERTY=VALUE arguments, sorted by
+// property name. The values are not quoted; quoting is the responsibility
+// of whatever builds the msiexec command line.
+func (props MSIProperties) Args() []string {
+	names := slices.Sorted(maps.Keys(props))
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, name+"="+props[name])
+	}
+	return args
+}
+
+// IsMSIPropertyName returns true if name is a valid Windows Installer
+// property name. Property names start with a letter or underscore, and
+// contain only letters, digits, underscores and periods.
+func IsMSIPropertyName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case i > 0 && ('0' <= r && r <= '9' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateProperties returns a non-nil error if the command's properties
+// are invalid or are provided for a command that does not invoke msiexec.
+func (command Command) validateProperties() error {
+	if len(command.Properties) == 0 {
+		return nil
+	}
+	if !command.Type.IsMSI() {
+		return errors.New("properties are only valid for msi-based commands")
+	}
+	return command.Properties.Validate()
+}
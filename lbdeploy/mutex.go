@@ -1,6 +1,10 @@
 package lbdeploy
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // MutexMap holds a set of mutex resources mapped by their identifiers.
 type MutexMap map[MutexID]Mutex
@@ -12,14 +16,26 @@ type MutexID string
 // deployment is running on.
 type MutexName string
 
+// maxMutexNameLength is the maximum length of a mutex object name,
+// including its namespace prefix.
+const maxMutexNameLength = 260
+
 // MutexNamespace is the namespace within a mutex exists.
-// It can be "leafbridge", "global" or "system".
+// It can be "leafbridge", "global", "local" or "session".
+//
+// Mutexes in the "leafbridge" and "global" namespaces are visible to every
+// session on the system, which allows a deployment running as a service in
+// session zero to coordinate with tools running in a user's session.
+// Mutexes in the "local" namespace are only visible within the session of
+// the process that opens them. The "session" namespace is an alias for
+// "local".
 type MutexNamespace string
 
 // Mutex namespaces.
 const (
 	LeafBridgeMutex MutexNamespace = "leafbridge"
 	GlobalMutex     MutexNamespace = "global"
+	LocalMutex      MutexNamespace = "local"
 	SessionMutex    MutexNamespace = "session"
 )
 
@@ -31,6 +47,29 @@ type Mutex struct {
 	Namespace   MutexNamespace `json:"namespace"`
 }
 
+// Validate returns a non-nil error if the mutex has an invalid name or
+// namespace.
+func (mutex Mutex) Validate() error {
+	switch {
+	case mutex.Name == "":
+		return errors.New("the mutex does not have a name")
+	case strings.Contains(string(mutex.Name), `\`):
+		// This also catches names that try to pick their own namespace,
+		// such as "Global\Name".
+		return fmt.Errorf("the \"%s\" mutex name contains a backslash, which is not permitted; use the namespace field to select a namespace", mutex.Name)
+	}
+
+	name, err := mutex.ObjectName()
+	if err != nil {
+		return err
+	}
+	if len(name) > maxMutexNameLength {
+		return fmt.Errorf("the \"%s\" mutex name is longer than %d characters", mutex.Name, maxMutexNameLength)
+	}
+
+	return nil
+}
+
 // ObjectName returns the name of the mutex object in the Windows Object
 // Manager.
 func (mutex Mutex) ObjectName() (string, error) {
@@ -40,11 +79,11 @@ func (mutex Mutex) ObjectName() (string, error) {
 		return fmt.Sprintf("Global\\LeafBridge-Deployment-%s", mutex.Name), nil
 	case GlobalMutex:
 		return fmt.Sprintf("Global\\%s", mutex.Name), nil
-	case SessionMutex:
-		return fmt.Sprintf("Session\\%s", mutex.Name), nil
+	case LocalMutex, SessionMutex:
+		return fmt.Sprintf("Local\\%s", mutex.Name), nil
 	case "":
-		return "", fmt.Errorf("the \"%s\" mutex is missing a mutex namespace", mutex)
+		return "", fmt.Errorf("the \"%s\" mutex is missing a mutex namespace", mutex.Name)
 	default:
-		return "", fmt.Errorf("the \"%s\" mutex has an unrcognized namespace: %s", mutex, mutex.Namespace)
+		return "", fmt.Errorf("the \"%s\" mutex has an unrcognized namespace: %s", mutex.Name, mutex.Namespace)
 	}
 }
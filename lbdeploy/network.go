@@ -0,0 +1,81 @@
+package lbdeploy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NetworkRoot is a network share that directory and file resources are
+// rooted at.
+type NetworkRoot struct {
+	id         DirectoryResourceID
+	path       string
+	credential string
+}
+
+// ID returns the LeafBridge directory ID of the network share.
+func (nr NetworkRoot) ID() DirectoryResourceID {
+	return nr.id
+}
+
+// IsZero returns true if the network root is undefined.
+func (nr NetworkRoot) IsZero() bool {
+	return nr.path == ""
+}
+
+// Path returns the UNC path of the network share directory.
+func (nr NetworkRoot) Path() string {
+	return nr.path
+}
+
+// Share returns the UNC path of the network share itself, in the form
+// \\server\share, without any directories within it.
+func (nr NetworkRoot) Share() string {
+	share, _ := ShareName(nr.path)
+	return share
+}
+
+// Credential returns the name of the credential in the Windows Credential
+// Manager that is used to connect to the network share. It returns an
+// empty string if the share is accessed with the identity of the running
+// process.
+func (nr NetworkRoot) Credential() string {
+	return nr.credential
+}
+
+// ShareName returns the \\server\share portion of the given UNC path.
+// It returns an error if the path is not a UNC path.
+func ShareName(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, `\\`)
+	if !ok {
+		return "", fmt.Errorf("the network share path \"%s\" is not a UNC path", path)
+	}
+	parts := strings.SplitN(rest, `\`, 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" || parts[0] == "?" || parts[0] == "." {
+		return "", fmt.Errorf("the network share path \"%s\" does not include a server and share name", path)
+	}
+	return `\\` + parts[0] + `\` + parts[1], nil
+}
+
+// defaultReachablePort is the port used by host reachable conditions that
+// don't specify one.
+const defaultReachablePort = "443"
+
+// HostAddress returns the network address for the subject of a host
+// reachable condition, in the form host:port. If the subject doesn't
+// include a port, port 443 is used.
+func HostAddress(subject string) (string, error) {
+	host, port, err := net.SplitHostPort(subject)
+	if err != nil {
+		// The subject might be a host name without a port.
+		host, port = strings.Trim(subject, "[]"), defaultReachablePort
+		if strings.ContainsAny(host, "/ ") || host == "" {
+			return "", fmt.Errorf("the host \"%s\" is not valid", subject)
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("the host \"%s\" does not include a host name", subject)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -3,6 +3,7 @@ package lbdeploy
 import (
 	"errors"
 	"fmt"
+	"net/url"
 
 	"github.com/leafbridge/leafbridge-deploy/filehash"
 )
@@ -62,17 +63,42 @@ type PackageFormat string
 
 // Package defines a deployment package.
 //
+// If StreamExtraction is true, an archive package is extracted while it
+// downloads, overlapping network and disk time for large archives. Files
+// extracted this way are only used after the downloaded package has been
+// verified and found to match them. Archives that can't be extracted in
+// order are extracted after the download, as usual.
+//
+// Deltas holds binary deltas that can produce the package file from
+// previous versions of the package. When a previous version is still
+// staged, a matching delta is downloaded and applied instead of the full
+// package. If that fails, the package is downloaded from its sources.
+//
+// Variants holds alternative forms of the package for particular UI
+// languages, such as localized installers. The variant that suits the local
+// system is applied before the package is used.
+//
+// MaxSize caps the number of bytes that will be accepted when the package
+// is downloaded. Downloads are always capped at the package's file size
+// when it is known, so MaxSize is mostly useful for packages that don't
+// declare one. If it is zero, a default cap is used.
+//
 // TODO: Add support for a destination directory where an archive's extracted
 // files will be extracted to. If a destination is not provided, then fall
 // back to the current approach that extracts files to a temporary directory.
 type Package struct {
-	Name       string          `json:"name,omitempty"`
-	Type       PackageType     `json:"type,omitempty"`
-	Format     PackageFormat   `json:"format,omitempty"`
-	Sources    []PackageSource `json:"sources,omitempty"`
-	Attributes FileAttributes  `json:"attributes,omitzero"`
-	Files      PackageFileMap  `json:"files,omitzero"`
-	Commands   CommandMap      `json:"commands,omitzero"`
+	Name       string           `json:"name,omitempty"`
+	Type       PackageType      `json:"type,omitempty"`
+	Format     PackageFormat    `json:"format,omitempty"`
+	Sources    []PackageSource  `json:"sources,omitempty"`
+	Attributes FileAttributes   `json:"attributes,omitzero"`
+	Files      PackageFileMap   `json:"files,omitzero"`
+	Commands   CommandMap       `json:"commands,omitzero"`
+	Variants   []PackageVariant `json:"variants,omitzero"`
+	Deltas     []PackageDelta   `json:"deltas,omitzero"`
+
+	MaxSize          int64 `json:"max-size,omitempty"`
+	StreamExtraction bool  `json:"stream-extraction,omitempty"`
 	//Destinations []DirectoryResourceID `json:"destinations,omitempty"`
 }
 
@@ -116,6 +142,11 @@ func (pkg Package) Validate() error {
 		return fmt.Errorf("the package type \"%s\" is not recognized", pkg.Type)
 	}
 
+	// Only archive packages can be extracted while they download.
+	if pkg.StreamExtraction && !pkg.Type.IsArchive() {
+		return errors.New("stream extraction is only valid for archive packages")
+	}
+
 	// Validate package sources.
 	for i, source := range pkg.Sources {
 		if err := source.Validate(); err != nil {
@@ -123,13 +154,63 @@ func (pkg Package) Validate() error {
 		}
 	}
 
-	// Validate package file attributes.
-	if err := pkg.Attributes.Validate(); err != nil {
-		return fmt.Errorf("package file attributes: %w", err)
+	// Validate package file attributes. A package with variants may leave
+	// them to its variants.
+	if len(pkg.Variants) == 0 || pkg.Attributes.Size != 0 || len(pkg.Attributes.Hashes) > 0 {
+		if err := pkg.Attributes.Validate(); err != nil {
+			return fmt.Errorf("package file attributes: %w", err)
+		}
+	} else {
+		for i, variant := range pkg.Variants {
+			if variant.Attributes.Size == 0 && len(variant.Attributes.Hashes) == 0 {
+				return fmt.Errorf("package variant %d: the package does not provide file attributes, so each variant must", i+1)
+			}
+		}
+	}
+
+	// Validate the maximum size of the package.
+	if pkg.MaxSize < 0 {
+		return errors.New("a negative maximum package size was provided")
+	}
+	if pkg.MaxSize > 0 && pkg.Attributes.Size > pkg.MaxSize {
+		return fmt.Errorf("the package file size of %d bytes exceeds its maximum size of %d bytes", pkg.Attributes.Size, pkg.MaxSize)
+	}
+
+	// Validate package deltas.
+	for i, delta := range pkg.Deltas {
+		if err := delta.Validate(); err != nil {
+			return fmt.Errorf("package delta %d: %w", i+1, err)
+		}
+	}
+
+	// Validate package variants.
+	if err := pkg.validateVariants(); err != nil {
+		return err
 	}
 
 	// Validate package commands.
 	for id, command := range pkg.Commands {
+		if err := command.validateProperties(); err != nil {
+			return fmt.Errorf("package command \"%s\": %w", id, err)
+		}
+		if err := command.Log.Validate(); err != nil {
+			return fmt.Errorf("package command \"%s\": log: %w", id, err)
+		}
+		if err := command.validateFacts(); err != nil {
+			return fmt.Errorf("package command \"%s\": %w", id, err)
+		}
+		if err := command.validateWorkingDirectory(true); err != nil {
+			return fmt.Errorf("package command \"%s\": %w", id, err)
+		}
+		if err := command.validatePackageFiles(pkg.Files); err != nil {
+			return fmt.Errorf("package command \"%s\": %w", id, err)
+		}
+		if err := command.Throttle.Validate(); err != nil {
+			return fmt.Errorf("package command \"%s\": throttle: %w", id, err)
+		}
+		if err := command.Window.Validate(); err != nil {
+			return fmt.Errorf("package command \"%s\": %w", id, err)
+		}
 		if command.Executable != "" {
 			if pkg.Type != "archive" {
 				return fmt.Errorf("package command \"%s\": an executable file ID is only valid for archive packages", id)
@@ -145,27 +226,235 @@ func (pkg Package) Validate() error {
 // Package source types.
 const (
 	PackageSourceHTTP PackageSourceType = "http"
+	PackageSourceSMB  PackageSourceType = "smb"
+	PackageSourceFile PackageSourceType = "file"
+	PackageSourceBlob PackageSourceType = "azure-blob"
+	PackageSourceS3   PackageSourceType = "s3"
 )
 
 // PackageSourceType declares the type of source for a package.
 type PackageSourceType string
 
+// IsHTTPBased returns true if packages are retrieved from sources of the
+// type with HTTP requests.
+func (t PackageSourceType) IsHTTPBased() bool {
+	switch t {
+	case PackageSourceHTTP, PackageSourceBlob, PackageSourceS3:
+		return true
+	default:
+		return false
+	}
+}
+
+// PackageSourceBackend identifies the mechanism that downloads a package
+// from an http source.
+type PackageSourceBackend string
+
+// Package source backends.
+const (
+	PackageSourceBackendDefault PackageSourceBackend = ""
+	PackageSourceBackendBITS    PackageSourceBackend = "bits"
+)
+
 // PackageSource defines a potential source for retrieval of a package.
+//
+// If Condition is provided, the source is only attempted when the
+// condition is met. This allows a source such as an internal mirror to be
+// skipped when it isn't reachable, instead of waiting for its connection
+// to time out.
+//
+// If Auth is provided, requests to the source are authenticated, such as
+// with a managed identity token for a private Azure Blob Storage container.
+// Basic, bearer and header authentication read their secrets from the
+// Windows Credential Manager entry named by Credential, so that protected
+// repositories such as Artifactory or GitHub releases can be used without
+// placing secrets in the deployment file.
+//
+// Headers holds additional request headers for http, azure-blob and s3
+// sources. They must not hold secrets.
+//
+// Backend selects the mechanism that downloads the package from an http
+// source. With the bits backend, the Background Intelligent Transfer
+// Service downloads the package using idle network bandwidth, subject to
+// any BITS throttling policies, and the transfer continues across
+// restarts. The package is verified once the transfer is complete. The bits
+// backend does not support auth, headers or connections.
+//
+// Connections is the number of concurrent range requests that a large
+// package is downloaded with from an http, azure-blob or s3 source. Each
+// request fetches a separate part of the file, which can greatly reduce the
+// time taken to download packages over high-latency links. If the server
+// does not support range requests, the package is downloaded with a single
+// request instead. If Connections is zero or one, a single request is used.
+//
+// Priority and Weight determine the order in which sources are attempted.
+// Sources with a lower priority are attempted first. Among sources that
+// share a priority, the order is chosen at random for each download, with
+// each source's chance of going first in proportion to its weight, so that
+// load is spread across mirrors. Sources with a weight of zero follow the
+// weighted sources in the order they were defined. When the deployment's
+// behavior enables source probing, sources that share a priority are
+// instead ordered by how quickly they respond.
+//
+// For smb sources, URL holds the UNC path of the package file on a network
+// share, such as \\server\share\file.msi, or an equivalent smb URL. If
+// Credential is provided, it names a credential in the Windows Credential
+// Manager of the account running the deployment, which is used to connect
+// to the share. Otherwise the account's own identity is used.
+//
+// For file sources, URL holds the path of a package file that is already
+// present on the local system, such as one placed there by another
+// management tool or removable media, or an equivalent file URL. The file
+// is copied into the staging directory and verified like any download.
+//
+// For azure-blob sources, URL holds the https URL of a blob in Azure Blob
+// Storage. The blob is read anonymously unless Auth is provided. With sas
+// authentication, Credential names the credential in the Windows
+// Credential Manager that holds the shared access signature.
+//
+// For s3 sources, URL takes the form s3://bucket/key, and S3 holds the
+// endpoint and region of the object store. If Credential is provided, it
+// names a credential in the Windows Credential Manager whose user name is
+// an access key ID and whose password is the secret access key. Requests
+// are then signed with AWS Signature Version 4. Otherwise the object is
+// read anonymously.
 type PackageSource struct {
-	Type PackageSourceType
-	URL  string
+	Type        PackageSourceType       `json:"type"`
+	URL         string                  `json:"url,omitempty"`
+	Condition   ConditionID             `json:"condition,omitempty"`
+	Auth        PackageSourceAuth       `json:"auth,omitzero"`
+	Credential  string                  `json:"credential,omitempty"`
+	S3          PackageSourceS3Settings `json:"s3,omitzero"`
+	Headers     map[string]string       `json:"headers,omitzero"`
+	Connections int                     `json:"connections,omitempty"`
+	Backend     PackageSourceBackend    `json:"backend,omitempty"`
+	Priority    int                     `json:"priority,omitempty"`
+	Weight      int                     `json:"weight,omitempty"`
 }
 
+// MaxSourceConnections is the maximum number of concurrent connections
+// that a package can be downloaded with from a single source.
+const MaxSourceConnections = 16
+
 // Validate returns a non-nil error if the package source is invalid.
 func (source PackageSource) Validate() error {
 	switch source.Type {
 	case "":
 		return errors.New("the source type is missing")
 	case PackageSourceHTTP:
+		switch source.Auth.Type {
+		case "", PackageSourceAuthManagedIdentity, PackageSourceAuthBasic, PackageSourceAuthBearer, PackageSourceAuthHeader:
+		default:
+			return fmt.Errorf("%s authentication is not valid for http sources", source.Auth.Type)
+		}
+		if err := source.validateAuthCredential(); err != nil {
+			return err
+		}
+	case PackageSourceBlob:
+		if _, _, err := source.BlobPath(); err != nil {
+			return fmt.Errorf("the azure-blob source \"%s\" is invalid: %w", source.URL, err)
+		}
+		switch source.Auth.Type {
+		case "", PackageSourceAuthManagedIdentity, PackageSourceAuthSAS:
+		default:
+			return fmt.Errorf("%s authentication is not valid for azure-blob sources", source.Auth.Type)
+		}
+		if err := source.validateAuthCredential(); err != nil {
+			return err
+		}
+	case PackageSourceS3:
+		if _, err := source.S3ObjectURL(); err != nil {
+			return fmt.Errorf("the s3 source \"%s\" is invalid: %w", source.URL, err)
+		}
+		if !source.Auth.IsZero() {
+			return errors.New("s3 sources are authenticated by their credential instead of auth")
+		}
+	case PackageSourceSMB:
+		if _, err := source.UNCPath(); err != nil {
+			return fmt.Errorf("the smb source \"%s\" is invalid: %w", source.URL, err)
+		}
+		if !source.Auth.IsZero() {
+			return errors.New("smb sources are authenticated by their credential instead of auth")
+		}
+	case PackageSourceFile:
+		if _, err := source.LocalPath(); err != nil {
+			return fmt.Errorf("the file source \"%s\" is invalid: %w", source.URL, err)
+		}
+		if source.Credential != "" || !source.Auth.IsZero() {
+			return errors.New("file sources do not support authentication")
+		}
 	default:
 		return fmt.Errorf("the package source type \"%s\" is not recognized", source.Type)
 	}
 
+	if source.Type != PackageSourceS3 && source.S3 != (PackageSourceS3Settings{}) {
+		return errors.New("s3 settings are only valid for s3 sources")
+	}
+
+	if len(source.Headers) > 0 {
+		if !source.Type.IsHTTPBased() {
+			return fmt.Errorf("headers are not valid for %s sources", source.Type)
+		}
+		if err := validateHeaders(source.Headers); err != nil {
+			return err
+		}
+	}
+
+	switch source.Backend {
+	case PackageSourceBackendDefault:
+	case PackageSourceBackendBITS:
+		if source.Type != PackageSourceHTTP {
+			return fmt.Errorf("the bits backend is not valid for %s sources", source.Type)
+		}
+		if !source.Auth.IsZero() || len(source.Headers) > 0 || source.Connections != 0 {
+			return errors.New("the bits backend does not support auth, headers or connections")
+		}
+		if u, err := url.Parse(source.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("the bits backend requires an http or https URL")
+		}
+	default:
+		return fmt.Errorf("the package source backend \"%s\" is not recognized", source.Backend)
+	}
+
+	if source.Connections != 0 {
+		if !source.Type.IsHTTPBased() {
+			return fmt.Errorf("connections are not valid for %s sources", source.Type)
+		}
+		if source.Connections < 0 || source.Connections > MaxSourceConnections {
+			return fmt.Errorf("the number of connections must be between 1 and %d", MaxSourceConnections)
+		}
+	}
+
+	if source.Priority < 0 {
+		return errors.New("the source priority must not be negative")
+	}
+	if source.Weight < 0 {
+		return errors.New("the source weight must not be negative")
+	}
+
+	if !source.Auth.IsZero() {
+		if err := source.Auth.Validate(); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+		// Secrets must not be sent in the clear.
+		if u, err := url.Parse(source.URL); err != nil || u.Scheme != "https" {
+			return errors.New("authenticated sources must use https")
+		}
+	}
+
+	return nil
+}
+
+// validateAuthCredential returns a non-nil error if the source has a
+// credential that its authentication doesn't use, or lacks one that it
+// needs.
+func (source PackageSource) validateAuthCredential() error {
+	switch {
+	case source.Auth.UsesCredential() && source.Credential == "":
+		return fmt.Errorf("%s authentication requires a credential", source.Auth.Type)
+	case !source.Auth.UsesCredential() && source.Credential != "":
+		return fmt.Errorf("credentials are only valid for %s sources with authentication that uses them", source.Type)
+	}
 	return nil
 }
 
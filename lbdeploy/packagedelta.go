@@ -0,0 +1,80 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+)
+
+// DeltaFormat identifies the format of a binary delta.
+type DeltaFormat string
+
+// Supported delta formats.
+const (
+	// DeltaFormatMSDelta is the format produced by the Windows delta
+	// compression API, such as by the CreateDelta function.
+	DeltaFormatMSDelta DeltaFormat = "msdelta"
+)
+
+// Validate returns a non-nil error if the delta format is not recognized.
+func (format DeltaFormat) Validate() error {
+	switch format {
+	case "":
+		return errors.New("the delta format is missing")
+	case DeltaFormatMSDelta:
+		return nil
+	default:
+		return fmt.Errorf("the delta format \"%s\" is not recognized", format)
+	}
+}
+
+// PackageDelta is a binary delta that produces a package file from a
+// previous version of the package, which is much smaller to download than
+// the package itself.
+//
+// Base holds the hashes of the previous version of the package file. A
+// delta is only used when that version is still staged for the same
+// package and matches those hashes. Attributes describe the delta file
+// itself, and are used to verify it before it is applied. The file
+// produced by the delta is verified against the package's attributes, as
+// usual.
+type PackageDelta struct {
+	Format     DeltaFormat     `json:"format"`
+	Base       filehash.Map    `json:"base"`
+	Sources    []PackageSource `json:"sources,omitempty"`
+	Attributes FileAttributes  `json:"attributes,omitzero"`
+}
+
+// Validate returns a non-nil error if the package delta is invalid.
+func (delta PackageDelta) Validate() error {
+	if err := delta.Format.Validate(); err != nil {
+		return err
+	}
+	if len(delta.Base) == 0 {
+		return errors.New("the hashes of the base package file are missing")
+	}
+	if len(delta.Sources) == 0 {
+		return errors.New("no sources were provided for the delta")
+	}
+	for i, source := range delta.Sources {
+		if err := source.Validate(); err != nil {
+			return fmt.Errorf("delta source %d: %w", i, err)
+		}
+	}
+	if err := delta.Attributes.Validate(); err != nil {
+		return fmt.Errorf("delta file attributes: %w", err)
+	}
+	return nil
+}
+
+// FileName returns the name of the file that the delta is downloaded to,
+// which is kept alongside the package file. The name includes the primary
+// hash of the base, so that deltas from different bases don't collide.
+func (delta PackageDelta) FileName(pkg Package) string {
+	base := delta.Base.Primary().Value.String()
+	if len(base) > 16 {
+		base = base[:16]
+	}
+	return pkg.FileName() + "." + base + "." + string(delta.Format)
+}
@@ -0,0 +1,70 @@
+package lbdeploy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholders that refer to package files take the form "{file:id}", such
+// as "{file:answers}". When a package command is invoked, they are replaced
+// by the absolute path of the file within the package's extraction
+// directory.
+const (
+	fileRefPrefix = "{file:"
+	fileRefSuffix = "}"
+)
+
+// PackageFilePlaceholder returns the placeholder that refers to the given
+// package file.
+func PackageFilePlaceholder(id PackageFileID) string {
+	return fileRefPrefix + string(id) + fileRefSuffix
+}
+
+// PackageFileReferences returns the IDs of the package files that are
+// referred to by placeholders in s, in the order that they appear.
+func PackageFileReferences(s string) []PackageFileID {
+	var ids []PackageFileID
+	for {
+		start := strings.Index(s, fileRefPrefix)
+		if start < 0 {
+			return ids
+		}
+		s = s[start+len(fileRefPrefix):]
+		end := strings.Index(s, fileRefSuffix)
+		if end < 0 {
+			return ids
+		}
+		ids = append(ids, PackageFileID(s[:end]))
+		s = s[end+len(fileRefSuffix):]
+	}
+}
+
+// ExpandPackageFiles replaces each package file placeholder in s with the
+// path returned by path for the file it refers to.
+func ExpandPackageFiles(s string, path func(PackageFileID) (string, error)) (string, error) {
+	for _, id := range PackageFileReferences(s) {
+		value, err := path(id)
+		if err != nil {
+			return "", err
+		}
+		s = strings.ReplaceAll(s, PackageFilePlaceholder(id), value)
+	}
+	return s, nil
+}
+
+// validatePackageFiles returns a non-nil error if the command's arguments
+// or properties refer to package files that are not present in files.
+// Deployment commands have no package files, so files is nil for them.
+func (command Command) validatePackageFiles(files PackageFileMap) error {
+	for _, value := range command.placeholderValues() {
+		for _, id := range PackageFileReferences(value) {
+			if files == nil {
+				return fmt.Errorf("the \"%s\" package file placeholder is only valid for archive package commands", PackageFilePlaceholder(id))
+			}
+			if _, ok := files[id]; !ok {
+				return fmt.Errorf("the \"%s\" placeholder refers to package file \"%s\", which is not defined in the package file set", PackageFilePlaceholder(id), id)
+			}
+		}
+	}
+	return nil
+}
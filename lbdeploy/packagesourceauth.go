@@ -0,0 +1,115 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PackageSourceAuthType declares a method of authenticating to a package
+// source.
+type PackageSourceAuthType string
+
+// Package source authentication types.
+const (
+	// PackageSourceAuthManagedIdentity authenticates with an access token
+	// for the Azure managed identity of the local system, which is
+	// acquired from the Azure Instance Metadata Service.
+	PackageSourceAuthManagedIdentity PackageSourceAuthType = "managed-identity"
+
+	// PackageSourceAuthSAS authenticates to Azure Storage with a shared
+	// access signature. The signature is the secret of the credential in
+	// the Windows Credential Manager that is named by the source.
+	PackageSourceAuthSAS PackageSourceAuthType = "sas"
+
+	// PackageSourceAuthBasic authenticates with HTTP basic authentication,
+	// using the user name and password of the credential in the Windows
+	// Credential Manager that is named by the source.
+	PackageSourceAuthBasic PackageSourceAuthType = "basic"
+
+	// PackageSourceAuthBearer authenticates with a bearer token, such as a
+	// personal access token, which is the password of the credential in
+	// the Windows Credential Manager that is named by the source.
+	PackageSourceAuthBearer PackageSourceAuthType = "bearer"
+
+	// PackageSourceAuthHeader authenticates by sending the password of the
+	// credential in the Windows Credential Manager that is named by the
+	// source in the header named by Header, such as an API key header.
+	PackageSourceAuthHeader PackageSourceAuthType = "header"
+)
+
+// DefaultManagedIdentityResource is the resource that managed identity
+// tokens are requested for when a source doesn't specify one. It grants
+// access to Azure Storage, including Azure Blob Storage.
+const DefaultManagedIdentityResource = "https://storage.azure.com/"
+
+// PackageSourceAuth describes how to authenticate to a package source.
+//
+// Authentication never relies on secrets held within the deployment file.
+// A managed identity token is issued to the local system by the cloud
+// platform it runs on, while shared access signatures, passwords, tokens
+// and keys are read from the Windows Credential Manager.
+type PackageSourceAuth struct {
+	Type PackageSourceAuthType `json:"type"`
+
+	// Resource identifies the resource that the token is requested for,
+	// either by URI or by application ID. Azure Artifacts, for example, is
+	// identified by the application ID of Azure DevOps. If it is empty,
+	// DefaultManagedIdentityResource is used.
+	Resource string `json:"resource,omitempty"`
+
+	// ClientID selects a user-assigned managed identity. If it is empty,
+	// the system-assigned identity is used.
+	ClientID string `json:"client-id,omitempty"`
+
+	// Header is the name of the header that carries the secret for header
+	// authentication.
+	Header string `json:"header,omitempty"`
+}
+
+// UsesCredential returns true if the authentication relies upon a
+// credential in the Windows Credential Manager.
+func (auth PackageSourceAuth) UsesCredential() bool {
+	switch auth.Type {
+	case PackageSourceAuthSAS, PackageSourceAuthBasic, PackageSourceAuthBearer, PackageSourceAuthHeader:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsZero returns true if no authentication is specified.
+func (auth PackageSourceAuth) IsZero() bool {
+	return auth == PackageSourceAuth{}
+}
+
+// ResourceOrDefault returns the resource that tokens are requested for.
+func (auth PackageSourceAuth) ResourceOrDefault() string {
+	if auth.Resource == "" {
+		return DefaultManagedIdentityResource
+	}
+	return auth.Resource
+}
+
+// Validate returns a non-nil error if the authentication is invalid.
+func (auth PackageSourceAuth) Validate() error {
+	switch auth.Type {
+	case PackageSourceAuthManagedIdentity, PackageSourceAuthSAS, PackageSourceAuthBasic, PackageSourceAuthBearer, PackageSourceAuthHeader:
+	case "":
+		return errors.New("the authentication type is missing")
+	default:
+		return fmt.Errorf("the authentication type \"%s\" is not recognized", auth.Type)
+	}
+
+	if auth.Type != PackageSourceAuthManagedIdentity && (auth.Resource != "" || auth.ClientID != "") {
+		return fmt.Errorf("%s authentication does not use a resource or client ID", auth.Type)
+	}
+	if auth.Type == PackageSourceAuthHeader {
+		if err := validateHeaderName(auth.Header); err != nil {
+			return err
+		}
+	} else if auth.Header != "" {
+		return fmt.Errorf("%s authentication does not use a header", auth.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package lbdeploy
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// BlobPath returns the container and blob names of the blob that is
+// referred to by an azure-blob package source. The source's URL must be an
+// https URL in the form https://account.blob.core.windows.net/container/blob.
+func (source PackageSource) BlobPath() (container, blob string, err error) {
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "https" {
+		return "", "", errors.New("azure blob URLs must use https")
+	}
+	if u.Host == "" {
+		return "", "", errors.New("the URL is missing its storage account host")
+	}
+	container, blob, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if container == "" || blob == "" {
+		return "", "", errors.New("the URL must include a container and a blob")
+	}
+	return container, blob, nil
+}
@@ -0,0 +1,38 @@
+package lbdeploy
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// LocalPath returns the path of a package file that is referred to by a
+// file package source. The source's URL may hold either an absolute path,
+// such as C:\Packages\file.msi, or a file URL, such as
+// file:///C:/Packages/file.msi.
+func (source PackageSource) LocalPath() (string, error) {
+	location := source.URL
+	if strings.HasPrefix(location, "file:") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", err
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return "", errors.New("file URLs must refer to the local system")
+		}
+		location = filepath.FromSlash(strings.TrimPrefix(u.Path, "/"))
+	}
+
+	if location == "" {
+		return "", errors.New("the path is empty")
+	}
+	if strings.HasPrefix(location, `\\`) {
+		return "", errors.New("the path refers to a network share, which requires an smb source")
+	}
+	if !filepath.IsAbs(location) {
+		return "", errors.New("the path must be absolute")
+	}
+
+	return filepath.Clean(location), nil
+}
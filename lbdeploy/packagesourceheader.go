@@ -0,0 +1,46 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// reservedHeaders are the request headers that are managed by the download
+// engine and can't be set by a package source.
+var reservedHeaders = []string{"Authorization", "Host", "Range", "Content-Length", "Transfer-Encoding", "Connection"}
+
+// validateHeaderName returns a non-nil error if name is not a valid HTTP
+// header name, or if it is a header that the download engine manages.
+func validateHeaderName(name string) error {
+	if name == "" {
+		return errors.New("the header name is empty")
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return fmt.Errorf("the header name \"%s\" contains an invalid character", name)
+		}
+	}
+	canonical := textproto.CanonicalMIMEHeaderKey(name)
+	for _, reserved := range reservedHeaders {
+		if canonical == reserved {
+			return fmt.Errorf("the \"%s\" header can't be set by a package source", canonical)
+		}
+	}
+	return nil
+}
+
+// validateHeaders returns a non-nil error if any of the headers of a
+// package source are invalid.
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if err := validateHeaderName(name); err != nil {
+			return err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("the value of the \"%s\" header contains a line break", name)
+		}
+	}
+	return nil
+}
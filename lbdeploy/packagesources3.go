@@ -0,0 +1,75 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultS3Region is the region that requests to an s3 source are signed
+// for when the source doesn't specify one. S3-compatible object stores
+// such as MinIO accept it by default.
+const DefaultS3Region = "us-east-1"
+
+// PackageSourceS3Settings holds the settings of an s3 package source.
+type PackageSourceS3Settings struct {
+	// Endpoint is the base URL of the object store, such as
+	// https://minio.example.com. If it is empty, the Amazon S3 endpoint
+	// for the region is used.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region is the region that the bucket is located in. If it is empty,
+	// DefaultS3Region is used.
+	Region string `json:"region,omitempty"`
+}
+
+// RegionOrDefault returns the region that requests are signed for.
+func (s3 PackageSourceS3Settings) RegionOrDefault() string {
+	if s3.Region == "" {
+		return DefaultS3Region
+	}
+	return s3.Region
+}
+
+// S3Object returns the bucket and key of the object that is referred to
+// by an s3 package source. The source's URL must take the form
+// s3://bucket/key.
+func (source PackageSource) S3Object() (bucket, key string, err error) {
+	rest, ok := strings.CutPrefix(source.URL, "s3://")
+	if !ok {
+		return "", "", errors.New("the URL must take the form s3://bucket/key")
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" || key == "" {
+		return "", "", errors.New("the URL must include a bucket and a key")
+	}
+	return bucket, key, nil
+}
+
+// S3ObjectURL returns the https or http URL of the object that is referred
+// to by an s3 package source. Objects are addressed in path style, which is
+// supported by Amazon S3 and S3-compatible object stores alike.
+func (source PackageSource) S3ObjectURL() (*url.URL, error) {
+	bucket, key, err := source.S3Object()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := source.S3.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", source.S3.RegionOrDefault())
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("the endpoint is invalid: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("the endpoint must be an https or http URL")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+	u.RawPath = ""
+	u.RawQuery = ""
+	return u, nil
+}
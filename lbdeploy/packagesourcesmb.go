@@ -0,0 +1,46 @@
+package lbdeploy
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// UNCPath returns the UNC path of a file on a network share that is
+// referred to by an smb package source. The source's URL may hold either
+// a UNC path, such as \\server\share\file.msi, or an smb URL, such as
+// smb://server/share/file.msi.
+func (source PackageSource) UNCPath() (string, error) {
+	location := source.URL
+	if rest, ok := strings.CutPrefix(location, "smb://"); ok {
+		decoded, err := url.PathUnescape(rest)
+		if err != nil {
+			return "", err
+		}
+		location = `\\` + strings.ReplaceAll(decoded, "/", `\`)
+	}
+
+	if _, _, err := SplitUNCPath(location); err != nil {
+		return "", err
+	}
+
+	return location, nil
+}
+
+// SplitUNCPath splits a UNC path to a file into the path of its network
+// share, in the form \\server\share, and the path of the file within the
+// share.
+func SplitUNCPath(path string) (share, file string, err error) {
+	rest, ok := strings.CutPrefix(path, `\\`)
+	if !ok {
+		return "", "", errors.New("the path is not a UNC path")
+	}
+	parts := strings.SplitN(rest, `\`, 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", errors.New("the UNC path must include a server, a share and a file")
+	}
+	if parts[0] == "?" || parts[0] == "." {
+		return "", "", errors.New("the UNC path refers to a device namespace instead of a network share")
+	}
+	return `\\` + parts[0] + `\` + parts[1], parts[2], nil
+}
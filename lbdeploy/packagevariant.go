@@ -0,0 +1,169 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// PackageVariant is an alternative form of a package, such as a localized
+// installer or a language pack, that is selected for systems that use one
+// of its UI languages.
+//
+// Languages holds language names such as "de-DE". A name without a region,
+// such as "de", matches any region of the language. A variant without any
+// languages is the fallback variant, which is selected when no other
+// variant matches.
+//
+// Fields that are empty are taken from the package.
+type PackageVariant struct {
+	Languages  []string        `json:"languages,omitempty"`
+	Sources    []PackageSource `json:"sources,omitempty"`
+	Attributes FileAttributes  `json:"attributes,omitzero"`
+	Files      PackageFileMap  `json:"files,omitzero"`
+}
+
+// IsFallback returns true if the variant is the fallback variant.
+func (v PackageVariant) IsFallback() bool {
+	return len(v.Languages) == 0
+}
+
+// Validate returns a non-nil error if the package variant is invalid.
+func (v PackageVariant) Validate() error {
+	for i, language := range v.Languages {
+		if language == "" {
+			return fmt.Errorf("language %d is empty", i+1)
+		}
+	}
+	for i, source := range v.Sources {
+		if err := source.Validate(); err != nil {
+			return fmt.Errorf("package source %d: %w", i, err)
+		}
+	}
+	if v.Attributes.Size != 0 || len(v.Attributes.Hashes) > 0 {
+		if err := v.Attributes.Validate(); err != nil {
+			return fmt.Errorf("package file attributes: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchLanguage reports how well the variant matches the given language.
+// It returns 2 for an exact match, 1 for a match of the language without
+// its region, and 0 if the variant doesn't match.
+func (v PackageVariant) matchLanguage(language string) int {
+	best := 0
+	for _, candidate := range v.Languages {
+		switch {
+		case strings.EqualFold(candidate, language):
+			return 2
+		case strings.EqualFold(candidate, baseLanguage(language)):
+			best = 1
+		}
+	}
+	return best
+}
+
+// baseLanguage returns the language of a language name without its region
+// or script, such as "de" for "de-DE".
+func baseLanguage(language string) string {
+	base, _, _ := strings.Cut(language, "-")
+	return base
+}
+
+// validateVariants returns a non-nil error if the package's variants are
+// invalid.
+func (pkg Package) validateVariants() error {
+	fallbacks := 0
+	for i, variant := range pkg.Variants {
+		if err := variant.Validate(); err != nil {
+			return fmt.Errorf("package variant %d: %w", i+1, err)
+		}
+		if variant.IsFallback() {
+			fallbacks++
+		}
+	}
+	if fallbacks > 1 {
+		return errors.New("only one package variant may omit its languages")
+	}
+	if len(pkg.Variants) > 0 && fallbacks == 0 && len(pkg.Sources) == 0 {
+		return errors.New("a package with variants must provide sources or a fallback variant for systems that don't match any of its languages")
+	}
+	return nil
+}
+
+// ForLanguages returns a copy of the package with the variant that best
+// suits the given languages applied. Languages are given in order of
+// preference, such as the system's preferred UI languages.
+//
+// For each language in turn, a variant that matches the language exactly is
+// preferred over one that matches it without its region. If no variant
+// matches any of the languages, the fallback variant is applied. If there
+// is no fallback variant, the package is returned without a variant
+// applied.
+//
+// The returned package does not have any variants.
+func (pkg Package) ForLanguages(languages []string) Package {
+	if len(pkg.Variants) == 0 {
+		return pkg
+	}
+
+	selected := -1
+	for _, language := range languages {
+		best := 0
+		for i, variant := range pkg.Variants {
+			if match := variant.matchLanguage(language); match > best {
+				selected, best = i, match
+			}
+		}
+		if selected >= 0 {
+			break
+		}
+	}
+	if selected < 0 {
+		for i, variant := range pkg.Variants {
+			if variant.IsFallback() {
+				selected = i
+				break
+			}
+		}
+	}
+
+	if selected >= 0 {
+		variant := pkg.Variants[selected]
+		if len(variant.Sources) > 0 {
+			pkg.Sources = variant.Sources
+		}
+		if variant.Attributes.Size != 0 || len(variant.Attributes.Hashes) > 0 {
+			pkg.Attributes = variant.Attributes
+		}
+		if len(variant.Files) > 0 {
+			pkg.Files = maps.Clone(pkg.Files)
+			if pkg.Files == nil {
+				pkg.Files = make(PackageFileMap)
+			}
+			maps.Copy(pkg.Files, variant.Files)
+		}
+	}
+
+	pkg.Variants = nil
+	return pkg
+}
+
+// ForLanguages returns a copy of the deployment with the variant of each
+// package that best suits the given languages applied.
+func (dep Deployment) ForLanguages(languages []string) Deployment {
+	var cloned bool
+	for id, pkg := range dep.Resources.Packages {
+		if len(pkg.Variants) == 0 {
+			continue
+		}
+		if !cloned {
+			dep.Resources.Packages = maps.Clone(dep.Resources.Packages)
+			cloned = true
+		}
+		dep.Resources.Packages[id] = pkg.ForLanguages(languages)
+	}
+	return dep
+}
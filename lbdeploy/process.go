@@ -1,5 +1,11 @@
 package lbdeploy
 
+import (
+	"fmt"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
 // ProcessResourceMap holds a set of process resources mapped by their
 // identifiers.
 type ProcessResourceMap map[ProcessResourceID]ProcessResource
@@ -14,6 +20,58 @@ type ProcessResource struct {
 
 	// Match describes criteria for identification of a running process.
 	Match ProcessMatch `json:"match,omitzero"`
+
+	// Conflict determines what happens when the process is running and an
+	// application it belongs to is about to be installed or uninstalled.
+	Conflict ProcessConflictPolicy `json:"conflict,omitzero"`
+}
+
+// ProcessConflictAction identifies how a running process that conflicts
+// with an application change is handled.
+type ProcessConflictAction string
+
+// Process conflict actions.
+const (
+	// ProcessConflictFail causes the command making the change to fail
+	// without being invoked. It is the default.
+	ProcessConflictFail ProcessConflictAction = "fail"
+
+	// ProcessConflictWait waits for the process to exit on its own. If it
+	// is still running when the timeout elapses, the command fails.
+	ProcessConflictWait ProcessConflictAction = "wait"
+
+	// ProcessConflictPrompt asks the user signed in to the console session
+	// for permission to close the process. If the user agrees, or if no
+	// user is signed in, the process is closed. Otherwise the command
+	// fails.
+	ProcessConflictPrompt ProcessConflictAction = "prompt"
+
+	// ProcessConflictForceClose terminates the process without asking.
+	ProcessConflictForceClose ProcessConflictAction = "force-close"
+)
+
+// ProcessConflictPolicy describes how a running process is handled before
+// the application it belongs to is installed or uninstalled.
+//
+// Timeout is how long to wait for the process to exit, or for the user to
+// respond to a prompt. Message is shown to the user when prompting.
+type ProcessConflictPolicy struct {
+	Action  ProcessConflictAction `json:"action,omitempty"`
+	Timeout datatype.Duration     `json:"timeout,omitempty"`
+	Message string                `json:"message,omitempty"`
+}
+
+// Validate returns a non-nil error if the policy is invalid.
+func (policy ProcessConflictPolicy) Validate() error {
+	switch policy.Action {
+	case "", ProcessConflictFail, ProcessConflictWait, ProcessConflictPrompt, ProcessConflictForceClose:
+	default:
+		return fmt.Errorf("the process conflict action \"%s\" is not recognized", policy.Action)
+	}
+	if policy.Timeout < 0 {
+		return fmt.Errorf("the process conflict timeout is negative")
+	}
+	return nil
 }
 
 // ProcessAttributeID identifies an attribute of a process.
@@ -0,0 +1,90 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ProxySettings determine how package downloads from http, azure-blob and
+// s3 sources reach their servers.
+//
+// If URL is provided, requests are sent through the proxy server at that
+// URL. Its scheme may be http, https or socks5. If UseSystem is true,
+// requests are sent through the WinHTTP default proxy of the local system
+// instead, which can be managed with "netsh winhttp set proxy". If neither
+// is provided, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables are honored.
+//
+// Bypass lists the hosts that are always reached directly. An entry may be
+// a host name, an IP address, a pattern with wildcards such as
+// "*.example.com", or "<local>", which matches host names without a dot.
+//
+// Package files obtained from peers on the local network are never
+// requested through a proxy. Sources that use the bits backend are
+// downloaded with the proxy configuration of BITS instead.
+type ProxySettings struct {
+	URL       string   `json:"url,omitempty"`
+	Bypass    []string `json:"bypass,omitzero"`
+	UseSystem bool     `json:"use-system,omitempty"`
+}
+
+// IsZero returns true if the proxy settings are empty.
+func (s ProxySettings) IsZero() bool {
+	return s.URL == "" && len(s.Bypass) == 0 && !s.UseSystem
+}
+
+// Validate returns a non-nil error if the proxy settings are invalid.
+func (s ProxySettings) Validate() error {
+	if s.URL != "" {
+		if s.UseSystem {
+			return errors.New("a proxy URL cannot be used with the system proxy")
+		}
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("the proxy URL is invalid: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("the proxy URL has a \"%s\" scheme that is not supported", u.Scheme)
+		}
+		if u.Host == "" {
+			return errors.New("the proxy URL does not include a host")
+		}
+	}
+	for _, entry := range s.Bypass {
+		if _, err := path.Match(strings.ToLower(entry), ""); err != nil {
+			return fmt.Errorf("the \"%s\" proxy bypass entry is not a valid pattern", entry)
+		}
+	}
+	return nil
+}
+
+// Bypasses returns true if requests to the given host are reached directly
+// instead of through a proxy. The host must not include a port.
+func (s ProxySettings) Bypasses(host string) bool {
+	return BypassesProxy(s.Bypass, host)
+}
+
+// BypassesProxy returns true if host matches an entry in the given proxy
+// bypass list. The host must not include a port.
+func BypassesProxy(bypass []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range bypass {
+		entry = strings.ToLower(entry)
+		if entry == "<local>" {
+			if !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(entry, host); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package lbdeploy
+
+import (
+	"fmt"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// RebootMode determines what happens when a deployment learns that the
+// system must be restarted to complete its changes.
+type RebootMode string
+
+// Recognized reboot modes.
+const (
+	// RebootSuppress records that a restart is required but never performs
+	// one. It is the default.
+	RebootSuppress RebootMode = "suppress"
+
+	// RebootImmediate restarts the system as soon as a command indicates that
+	// a restart is required. The rest of the deployment is abandoned.
+	RebootImmediate RebootMode = "immediate"
+
+	// RebootEndOfFlow restarts the system when a flow that required a restart
+	// has finished. Any flows that remain are skipped.
+	RebootEndOfFlow RebootMode = "end-of-flow"
+
+	// RebootScheduled schedules a restart for some time after the deployment
+	// has finished, giving users time to save their work.
+	RebootScheduled RebootMode = "scheduled"
+)
+
+// RebootPolicy describes how a deployment responds when a command indicates
+// that a restart is required.
+//
+// Delay is the amount of time between the notification shown to users and
+// the restart itself. Message is included in the notification.
+type RebootPolicy struct {
+	Mode    RebootMode        `json:"mode,omitempty"`
+	Delay   datatype.Duration `json:"delay,omitempty"`
+	Message string            `json:"message,omitempty"`
+}
+
+// Validate returns a non-nil error if the reboot policy is invalid.
+func (policy RebootPolicy) Validate() error {
+	switch policy.Mode {
+	case "", RebootSuppress, RebootImmediate, RebootEndOfFlow, RebootScheduled:
+	default:
+		return fmt.Errorf("the reboot mode \"%s\" is not recognized", policy.Mode)
+	}
+	return nil
+}
+
+// OverlayRebootPolicy overlays the given set of reboot policies, giving
+// priority to later members.
+func OverlayRebootPolicy(policies ...RebootPolicy) RebootPolicy {
+	var out RebootPolicy
+	for _, next := range policies {
+		if next.Mode != "" {
+			out.Mode = next.Mode
+		}
+		if next.Delay != 0 {
+			out.Delay = next.Delay
+		}
+		if next.Message != "" {
+			out.Message = next.Message
+		}
+	}
+	return out
+}
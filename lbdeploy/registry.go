@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/leafbridge/leafbridge-deploy/idset"
 	"github.com/leafbridge/leafbridge-deploy/lbvalue"
@@ -27,20 +28,18 @@ type RegistryResources struct {
 //
 // If the registry key cannot be resolved, an error is returned.
 func (reg RegistryResources) ResolveKey(key RegistryKeyResourceID) (ref RegistryKeyRef, err error) {
-	// TODO: Consider making custom error types for resolution.
-
 	// Look up the registry key by its ID.
 	data, exists := reg.Keys[key]
 	if !exists {
 		if candidate, found := GetRegistryRoot(key); found {
 			return RegistryKeyRef{Root: candidate}, nil
 		}
-		return RegistryKeyRef{}, fmt.Errorf("the \"%s\" registry key is not defined in the deployment's resources", key)
+		return RegistryKeyRef{}, ResourceNotDefined{Kind: ResourceKindRegistryKey, ID: string(key)}
 	}
 
 	// Make sure the registry key has a location.
 	if data.Location == "" {
-		return RegistryKeyRef{}, fmt.Errorf("the \"%s\" registry key does not have a location", key)
+		return RegistryKeyRef{}, MissingLocation{Kind: ResourceKindRegistryKey, ID: string(key)}
 	}
 
 	// Successful resolution must end in a known registry root.
@@ -62,7 +61,7 @@ func (reg RegistryResources) ResolveKey(key RegistryKeyResourceID) (ref Registry
 	for {
 		// Check for cycles.
 		if seen.Contains(next) {
-			return RegistryKeyRef{}, fmt.Errorf("failed to resolve the \"%s\" registry key: the \"%s\" parent key has a cyclic reference to itself in the deployment's registry resources", key, next)
+			return RegistryKeyRef{}, keyResolutionError(key, CyclicReference{Kind: ResourceKindRegistryKey, ID: string(next)})
 		}
 		seen.Add(next)
 
@@ -70,7 +69,7 @@ func (reg RegistryResources) ResolveKey(key RegistryKeyResourceID) (ref Registry
 		if parent, found := reg.Keys[next]; found {
 			lineage = append(lineage, parent)
 			if parent.Location == "" {
-				return RegistryKeyRef{}, fmt.Errorf("failed to resolve the \"%s\" registry key: the \"%s\" parent key does not have a location", key, next)
+				return RegistryKeyRef{}, keyResolutionError(key, MissingLocation{Kind: ResourceKindRegistryKey, ID: string(next)})
 			}
 			next = parent.Location
 			continue
@@ -83,7 +82,7 @@ func (reg RegistryResources) ResolveKey(key RegistryKeyResourceID) (ref Registry
 		}
 
 		// The location is not defined.
-		return RegistryKeyRef{}, fmt.Errorf("failed to resolve the \"%s\" registry key: the \"%s\" prent key is not defined in the deployment's resources", key, next)
+		return RegistryKeyRef{}, keyResolutionError(key, ResourceNotDefined{Kind: ResourceKindRegistryKey, ID: string(next)})
 	}
 
 	// Reverse the order of the registry keys that were recorded, so they can
@@ -106,23 +105,21 @@ func (reg RegistryResources) ResolveKey(key RegistryKeyResourceID) (ref Registry
 //
 // If the registry value cannot be resolved, an error is returned.
 func (reg RegistryResources) ResolveValue(value RegistryValueResourceID) (ref RegistryValueRef, err error) {
-	// TODO: Consider making custom error types for resolution.
-
 	// Look up the registry value by its ID.
 	data, exists := reg.Values[value]
 	if !exists {
-		return RegistryValueRef{}, fmt.Errorf("the \"%s\" registry value is not defined in the deployment's resources", value)
+		return RegistryValueRef{}, ResourceNotDefined{Kind: ResourceKindRegistryValue, ID: string(value)}
 	}
 
 	// Make sure the registry value has a key.
 	if data.Key == "" {
-		return RegistryValueRef{}, fmt.Errorf("the \"%s\" registry value does not have a key", value)
+		return RegistryValueRef{}, MissingLocation{Kind: ResourceKindRegistryValue, ID: string(value)}
 	}
 
 	// Resolve the value's registry key.
 	key, err := reg.ResolveKey(data.Key)
 	if err != nil {
-		return RegistryValueRef{}, fmt.Errorf("failed to resolve the \"%s\" registry value: %w", value, err)
+		return RegistryValueRef{}, ResolutionError{Kind: ResourceKindRegistryValue, ID: string(value), Err: err}
 	}
 
 	return RegistryValueRef{
@@ -134,6 +131,12 @@ func (reg RegistryResources) ResolveValue(value RegistryValueResourceID) (ref Re
 	}, nil
 }
 
+// keyResolutionError returns a resolution error for key, which could not
+// be resolved because of a problem with one of its parents.
+func keyResolutionError(key RegistryKeyResourceID, err error) error {
+	return ResolutionError{Kind: ResourceKindRegistryKey, ID: string(key), Err: err}
+}
+
 // RegistryKeyResourceMap holds a set of registry key resources mapped by
 // their identifiers.
 type RegistryKeyResourceMap map[RegistryKeyResourceID]RegistryKeyResource
@@ -144,6 +147,11 @@ type RegistryKeyResourceID string
 // RegistryKeyResource describes a registry key in the Windows registry.
 //
 // Its name and path fields are mutually exclusive.
+//
+// View selects the 32-bit or 64-bit view of the registry on 64-bit
+// systems. It applies to the key and to every key and value beneath it, so
+// that 32-bit applications can be found without hardcoding WOW6432Node
+// paths. If it is empty, the native view is used.
 type RegistryKeyResource struct {
 	// Location is a well-known registry root ID, or another key's
 	// resource ID.
@@ -156,6 +164,33 @@ type RegistryKeyResource struct {
 	// Both forward slashes and backslashes will be interpreted as path
 	// separators.
 	Path string `json:"path,omitempty"`
+
+	// View is the registry view used to access the key.
+	View RegistryView `json:"view,omitempty"`
+}
+
+// RegistryView identifies a view of the registry on 64-bit systems.
+type RegistryView string
+
+// Registry views.
+const (
+	RegistryViewNative RegistryView = ""
+	RegistryView32     RegistryView = "32-bit"
+	RegistryView64     RegistryView = "64-bit"
+)
+
+// Access returns the registry access flag that selects the view.
+func (view RegistryView) Access() (uint32, error) {
+	switch view {
+	case RegistryViewNative:
+		return 0, nil
+	case RegistryView32:
+		return registry.WOW64_32KEY, nil
+	case RegistryView64:
+		return registry.WOW64_64KEY, nil
+	default:
+		return 0, fmt.Errorf("the registry view \"%s\" is not recognized", view)
+	}
 }
 
 // RegistryKeyRef is a resolved reference to a registry key on the local
@@ -165,6 +200,26 @@ type RegistryKeyRef struct {
 	Lineage []RegistryKeyResource
 }
 
+// View returns the registry view used to access the key. A view specified
+// by a key applies to all of its descendants. If keys in the lineage
+// specify different views, an error is returned.
+func (ref RegistryKeyRef) View() (RegistryView, error) {
+	var view RegistryView
+	for _, key := range ref.Lineage {
+		if key.View == RegistryViewNative {
+			continue
+		}
+		if _, err := key.View.Access(); err != nil {
+			return RegistryViewNative, err
+		}
+		if view != RegistryViewNative && view != key.View {
+			return RegistryViewNative, fmt.Errorf("the registry key lineage specifies both the %s and %s registry views", view, key.View)
+		}
+		view = key.View
+	}
+	return view, nil
+}
+
 // Path returns the path of the registry key on the local system.
 func (ref RegistryKeyRef) Path() (string, error) {
 	path, err := ref.Root.AbsolutePath()
@@ -210,7 +265,63 @@ type RegistryValueResource struct {
 	Name string `json:"name"`
 
 	// Type is the type of data the value holds.
-	Type lbvalue.Kind `json:"type"`
+	Type RegistryValueType `json:"type"`
+}
+
+// RegistryValueType identifies the type of data held by a registry value,
+// and how it is interpreted.
+type RegistryValueType string
+
+// Registry value types.
+//
+// The string, expand-string, multi-string, binary, dword and qword types
+// correspond directly to the REG_SZ, REG_EXPAND_SZ, REG_MULTI_SZ,
+// REG_BINARY, REG_DWORD and REG_QWORD registry types. Environment
+// variables in expand-string values are expanded when they are read.
+//
+// The bool and version types are read from REG_SZ values and parsed.
+// The int64 type is read from either REG_DWORD or REG_QWORD values.
+const (
+	RegistryString       RegistryValueType = "string"
+	RegistryExpandString RegistryValueType = "expand-string"
+	RegistryMultiString  RegistryValueType = "multi-string"
+	RegistryBinary       RegistryValueType = "binary"
+	RegistryDWord        RegistryValueType = "dword"
+	RegistryQWord        RegistryValueType = "qword"
+	RegistryBool         RegistryValueType = "bool"
+	RegistryInt64        RegistryValueType = "int64"
+	RegistryVersion      RegistryValueType = "version"
+)
+
+// Kind returns the kind of [lbvalue.Value] that values of the registry
+// type are read as. It returns [lbvalue.KindUnknown] if the type is not
+// recognized.
+func (t RegistryValueType) Kind() lbvalue.Kind {
+	switch t {
+	case RegistryString, RegistryExpandString:
+		return lbvalue.KindString
+	case RegistryMultiString:
+		return lbvalue.KindStrings
+	case RegistryBinary:
+		return lbvalue.KindBytes
+	case RegistryDWord, RegistryQWord, RegistryInt64:
+		return lbvalue.KindInt64
+	case RegistryBool:
+		return lbvalue.KindBool
+	case RegistryVersion:
+		return lbvalue.KindVersion
+	default:
+		return lbvalue.KindUnknown
+	}
+}
+
+// Validate returns a non-nil error if the registry value type is not
+// recognized.
+func (t RegistryValueType) Validate() error {
+	if t.Kind() == lbvalue.KindUnknown {
+		return fmt.Errorf("the registry value type \"%s\" is not recognized", t)
+	}
+	return nil
 }
 
 // RegistryValueRef is a resolved reference to a registry key on the local
@@ -220,7 +331,7 @@ type RegistryValueRef struct {
 	Lineage []RegistryKeyResource
 	ID      RegistryValueResourceID
 	Name    string
-	Type    lbvalue.Kind
+	Type    RegistryValueType
 }
 
 // Key returns a reference to the values's registry key.
@@ -263,6 +374,12 @@ func (root RegistryRoot) AbsolutePath() (path string, err error) {
 	switch root.key {
 	case registry.LOCAL_MACHINE:
 		path = "HKEY_LOCAL_MACHINE"
+	case registry.CURRENT_USER:
+		path = "HKEY_CURRENT_USER"
+	case registry.USERS:
+		path = "HKEY_USERS"
+	case registry.CLASSES_ROOT:
+		path = "HKEY_CLASSES_ROOT"
 	default:
 		return "", fmt.Errorf("the \"%s\" registry root relies on an unsupported root key", root.id)
 	}
@@ -277,13 +394,64 @@ func (root RegistryRoot) IsZero() bool {
 	return root.id == ""
 }
 
+// UserRegistryRootPrefix is the prefix of registry root IDs that identify
+// the hive of a particular user in HKEY_USERS by security identifier, such
+// as "user:S-1-5-18".
+//
+// The user's hive is only present while the user is signed in, or while
+// their profile is loaded by some other means.
+const UserRegistryRootPrefix = "user:"
+
 // GetRegistryRoot looks for a well-known registry root with the given
 // resource ID. If one is found, it is returned and ok will be true.
+//
+// In addition to the well-known roots, IDs that start with
+// [UserRegistryRootPrefix] followed by a security identifier resolve to
+// that user's hive.
 func GetRegistryRoot(id RegistryKeyResourceID) (root RegistryRoot, ok bool) {
+	if sid, found := strings.CutPrefix(string(id), UserRegistryRootPrefix); found {
+		if !isSecurityIdentifier(sid) {
+			return RegistryRoot{}, false
+		}
+		return RegistryRoot{id: id, key: registry.USERS, path: sid}, true
+	}
 	root, ok = registryRoots[id]
 	return
 }
 
+// isSecurityIdentifier returns true if s looks like a security identifier
+// in string form, such as "S-1-5-21-1004336348-1177238915-682003330-512".
+func isSecurityIdentifier(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) < 3 || !strings.EqualFold(parts[0], "S") {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if part == "" {
+			return false
+		}
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// registryRoots holds the well-known registry roots.
+//
+// The roots under HKEY_CURRENT_USER refer to the hive of the user that
+// LeafBridge is running as. When LeafBridge runs as the local system
+// account, that is the system account's own hive, not the hive of the
+// signed-in user.
 var registryRoots = RegistryRootMap{
-	"software": RegistryRoot{id: "software", key: registry.LOCAL_MACHINE, path: "SOFTWARE"},
+	"local-machine": RegistryRoot{id: "local-machine", key: registry.LOCAL_MACHINE},
+	"software":      RegistryRoot{id: "software", key: registry.LOCAL_MACHINE, path: "SOFTWARE"},
+	"system":        RegistryRoot{id: "system", key: registry.LOCAL_MACHINE, path: "SYSTEM"},
+	"services":      RegistryRoot{id: "services", key: registry.LOCAL_MACHINE, path: `SYSTEM\CurrentControlSet\Services`},
+	"current-user":  RegistryRoot{id: "current-user", key: registry.CURRENT_USER},
+	"user-software": RegistryRoot{id: "user-software", key: registry.CURRENT_USER, path: "Software"},
+	"users":         RegistryRoot{id: "users", key: registry.USERS},
+	"classes-root":  RegistryRoot{id: "classes-root", key: registry.CLASSES_ROOT},
 }
@@ -0,0 +1,87 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ResourceKind identifies a kind of resource that can be resolved.
+type ResourceKind string
+
+// Kinds of resources that can be resolved.
+const (
+	ResourceKindDirectory     ResourceKind = "directory"
+	ResourceKindFile          ResourceKind = "file"
+	ResourceKindRegistryKey   ResourceKind = "registry key"
+	ResourceKindRegistryValue ResourceKind = "registry value"
+)
+
+// ResourceNotDefined is returned when a resource refers to a resource that
+// is not defined in the deployment's resources.
+type ResourceNotDefined struct {
+	Kind ResourceKind
+	ID   string
+}
+
+// Error returns the error as a string.
+func (e ResourceNotDefined) Error() string {
+	return fmt.Sprintf("the \"%s\" %s is not defined in the deployment's resources", e.ID, e.Kind)
+}
+
+// CyclicReference is returned when the ancestry of a resource refers back
+// to itself.
+type CyclicReference struct {
+	Kind ResourceKind
+	ID   string
+}
+
+// Error returns the error as a string.
+func (e CyclicReference) Error() string {
+	return fmt.Sprintf("the \"%s\" %s has a cyclic reference to itself in the deployment's resources", e.ID, e.Kind)
+}
+
+// MissingLocation is returned when a resource does not specify the
+// location it belongs to. For a registry value, the location is its key.
+type MissingLocation struct {
+	Kind ResourceKind
+	ID   string
+}
+
+// Error returns the error as a string.
+func (e MissingLocation) Error() string {
+	if e.Kind == ResourceKindRegistryValue {
+		return fmt.Sprintf("the \"%s\" %s does not have a key", e.ID, e.Kind)
+	}
+	return fmt.Sprintf("the \"%s\" %s does not have a location", e.ID, e.Kind)
+}
+
+// ResolutionError is returned when a resource cannot be resolved because
+// one of its ancestors cannot be resolved. Err describes the problem with
+// the ancestor.
+type ResolutionError struct {
+	Kind ResourceKind
+	ID   string
+	Err  error
+}
+
+// Unwrap returns the underlying error for the resolution.
+func (e ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the error as a string.
+func (e ResolutionError) Error() string {
+	return fmt.Sprintf("failed to resolve the \"%s\" %s: %v", e.ID, e.Kind, e.Err)
+}
+
+// IsResolutionError returns true if err was caused by a resource that
+// could not be resolved because of the deployment's configuration, as
+// opposed to a problem encountered on the local system.
+func IsResolutionError(err error) bool {
+	var (
+		notDefined ResourceNotDefined
+		cyclic     CyclicReference
+		missing    MissingLocation
+	)
+	return errors.As(err, &notDefined) || errors.As(err, &cyclic) || errors.As(err, &missing)
+}
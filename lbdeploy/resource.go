@@ -7,6 +7,7 @@ import "fmt"
 type Resources struct {
 	Processes  ProcessResourceMap  `json:"processes,omitzero"`
 	Mutexes    MutexMap            `json:"mutexes,omitzero"`
+	Semaphores SemaphoreMap        `json:"semaphores,omitzero"`
 	Locks      LockMap             `json:"locks,omitzero"`
 	Registry   RegistryResources   `json:"registry,omitzero"`
 	FileSystem FileSystemResources `json:"file-system,omitzero"`
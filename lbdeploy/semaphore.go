@@ -0,0 +1,78 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemaphoreMap holds a set of semaphore resources mapped by their
+// identifiers.
+type SemaphoreMap map[SemaphoreID]Semaphore
+
+// SemaphoreID is a unique identifier for a system-wide semaphore resource.
+type SemaphoreID string
+
+// SemaphoreName is the name of a system-wide semaphore on the machine a
+// deployment is running on.
+type SemaphoreName string
+
+// Semaphore is a system-wide semaphore that can be used by locks to limit
+// the number of lock holders across every process on the system.
+//
+// Count is the maximum number of concurrent holders. A semaphore with a
+// count of one allows a single holder at a time, which can be used to
+// ensure that only one bandwidth-heavy download or one installer runs on
+// the machine at once.
+//
+// Semaphores use the same namespaces as mutexes. A semaphore is made up of
+// one mutex for each of its holders, which are named by appending "-0"
+// through "-N-1" to the semaphore's object name. This allows a slot that is
+// held by a process when it exits to be recovered by other processes.
+type Semaphore struct {
+	Description string         `json:"description,omitempty"`
+	Name        SemaphoreName  `json:"name"`
+	Namespace   MutexNamespace `json:"namespace"`
+	Count       int            `json:"count"`
+}
+
+// Validate returns a non-nil error if the semaphore is invalid.
+func (semaphore Semaphore) Validate() error {
+	switch {
+	case semaphore.Name == "":
+		return errors.New("the semaphore does not have a name")
+	case strings.Contains(string(semaphore.Name), `\`):
+		return fmt.Errorf("the \"%s\" semaphore name contains a backslash, which is not permitted; use the namespace field to select a namespace", semaphore.Name)
+	case semaphore.Count < 1:
+		return fmt.Errorf("the \"%s\" semaphore must have a count of at least one", semaphore.Name)
+	}
+
+	name, err := semaphore.ObjectName()
+	if err != nil {
+		return err
+	}
+	// Leave room for the suffix of the last mutex that backs the semaphore.
+	if len(name)+len(strconv.Itoa(semaphore.Count-1))+1 > maxMutexNameLength {
+		return fmt.Errorf("the \"%s\" semaphore name is longer than %d characters", semaphore.Name, maxMutexNameLength)
+	}
+
+	return nil
+}
+
+// ObjectName returns the name of the semaphore in the Windows Object
+// Manager, which is the base name of the mutexes that back it.
+func (semaphore Semaphore) ObjectName() (string, error) {
+	switch semaphore.Namespace {
+	case LeafBridgeMutex:
+		return fmt.Sprintf("Global\\LeafBridge-Semaphore-%s", semaphore.Name), nil
+	case GlobalMutex:
+		return fmt.Sprintf("Global\\%s", semaphore.Name), nil
+	case LocalMutex, SessionMutex:
+		return fmt.Sprintf("Local\\%s", semaphore.Name), nil
+	case "":
+		return "", fmt.Errorf("the \"%s\" semaphore is missing a namespace", semaphore.Name)
+	default:
+		return "", fmt.Errorf("the \"%s\" semaphore has an unrecognized namespace: %s", semaphore.Name, semaphore.Namespace)
+	}
+}
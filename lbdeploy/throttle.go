@@ -0,0 +1,48 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProcessPriority is the scheduling priority of a command's process.
+type ProcessPriority string
+
+// Process priorities.
+const (
+	ProcessPriorityNormal      ProcessPriority = "normal"
+	ProcessPriorityBelowNormal ProcessPriority = "below-normal"
+	ProcessPriorityIdle        ProcessPriority = "idle"
+)
+
+// CommandThrottle limits the processor time consumed by a command, so that
+// deployments running in the background don't make interactive sessions
+// unusable.
+//
+// Priority is the scheduling priority of the command's process, which the
+// processes it starts inherit. If it is empty, the normal priority is used.
+//
+// CPULimit caps the share of the system's total processor time, as a
+// percentage from 1 to 100, that the command and the processes it starts
+// may consume. It is enforced with a job object. If it is zero, processor
+// time is not capped.
+//
+// Windows Installer performs much of an installation within its own
+// service, which neither setting affects.
+type CommandThrottle struct {
+	Priority ProcessPriority `json:"priority,omitempty"`
+	CPULimit int             `json:"cpu-limit,omitempty"`
+}
+
+// Validate returns a non-nil error if the throttle is invalid.
+func (t CommandThrottle) Validate() error {
+	switch t.Priority {
+	case "", ProcessPriorityNormal, ProcessPriorityBelowNormal, ProcessPriorityIdle:
+	default:
+		return fmt.Errorf("the process priority \"%s\" is not recognized", t.Priority)
+	}
+	if t.CPULimit < 0 || t.CPULimit > 100 {
+		return errors.New("the CPU limit must be a percentage between 1 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package lbdeploy
+
+import (
+	"fmt"
+	"maps"
+)
+
+// UninstallFlowID returns the ID of the flow that is synthesized to
+// uninstall the given application.
+func UninstallFlowID(app AppID) FlowID {
+	return FlowID("uninstall-" + string(app))
+}
+
+// WithUninstallFlow returns a copy of the deployment with a flow that
+// uninstalls the given application, along with the ID of that flow. The
+// flow and its command are synthesized from the application's metadata.
+//
+// Applications with a Windows Installer product code are removed with
+// msiexec. Other applications are removed by running the uninstall string
+// recorded in their registry entry. Any processes listed by the application
+// are stopped first.
+//
+// The command declares that it uninstalls the application, so the engine
+// skips it when the application is already missing, and verifies that the
+// application is gone when it finishes.
+func (dep Deployment) WithUninstallFlow(app AppID) (Deployment, FlowID, error) {
+	definition, found := dep.Apps[app]
+	if !found {
+		return dep, "", fmt.Errorf("the \"%s\" app does not exist within the \"%s\" deployment", app, dep.ID)
+	}
+
+	// Choose a command type based on what is known about the app.
+	var commandType CommandType
+	switch {
+	case isMSIProductCode(definition.ProductCode):
+		commandType = CommandTypeMSIUninstallProductCode
+	case definition.ProductCode != "" || definition.Detection.DisplayName != "":
+		commandType = CommandTypeUninstallString
+	default:
+		return dep, "", fmt.Errorf("the \"%s\" app has neither a product code nor a display name that could be used to uninstall it", app)
+	}
+
+	// Make sure the synthesized identifiers are available.
+	flowID := UninstallFlowID(app)
+	commandID := CommandID(flowID)
+	if _, exists := dep.Flows[flowID]; exists {
+		return dep, "", fmt.Errorf("an uninstall flow cannot be generated for the \"%s\" app because the \"%s\" flow already exists", app, flowID)
+	}
+	if _, exists := dep.Commands[commandID]; exists {
+		return dep, "", fmt.Errorf("an uninstall flow cannot be generated for the \"%s\" app because the \"%s\" command already exists", app, commandID)
+	}
+
+	// Build the flow.
+	var flow Flow
+	if len(definition.Processes) > 0 {
+		flow.Actions = append(flow.Actions, Action{
+			Type:      ActionStopProcesses,
+			Processes: definition.Processes,
+		})
+	}
+	flow.Actions = append(flow.Actions, Action{
+		Type:    ActionInvokeCommand,
+		Command: commandID,
+	})
+
+	// Add the command and flow to copies of the deployment's maps, so that
+	// the original deployment is left untouched.
+	dep.Commands = maps.Clone(dep.Commands)
+	if dep.Commands == nil {
+		dep.Commands = make(CommandMap)
+	}
+	dep.Commands[commandID] = Command{
+		Type:       commandType,
+		Uninstalls: AppList{app},
+	}
+
+	dep.Flows = maps.Clone(dep.Flows)
+	if dep.Flows == nil {
+		dep.Flows = make(FlowMap)
+	}
+	dep.Flows[flowID] = flow
+
+	return dep, flowID, nil
+}
+
+// isMSIProductCode returns true if code is formatted as a Windows Installer
+// product code.
+func isMSIProductCode(code ProductCode) bool {
+	return len(code) == 38 && code[0] == '{' && code[37] == '}'
+}
@@ -0,0 +1,106 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+)
+
+// UserSettings describes per-user configuration that is applied to every
+// user profile on the system by an apply-user-settings action, including
+// the default profile that new users are created from.
+//
+// These are typically settings that an installer only stamps for the user
+// that installed it.
+type UserSettings struct {
+	Registry []UserRegistryValue `json:"registry,omitzero"`
+	Files    []UserFile          `json:"files,omitzero"`
+}
+
+// Validate returns a non-nil error if the user settings are invalid.
+func (settings UserSettings) Validate() error {
+	if len(settings.Registry) == 0 && len(settings.Files) == 0 {
+		return errors.New("no registry values or files are specified")
+	}
+	for i, value := range settings.Registry {
+		if err := value.Validate(); err != nil {
+			return fmt.Errorf("registry value %d: %w", i+1, err)
+		}
+	}
+	for i, file := range settings.Files {
+		if err := file.Validate(); err != nil {
+			return fmt.Errorf("file %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// UserRegistryValue is a registry value that is written to the registry
+// hive of each user, as if it were written beneath HKEY_CURRENT_USER.
+//
+// Key is the path of the value's key within the user's hive, such as
+// "Software\Contoso\Viewer". Both forward slashes and backslashes are
+// interpreted as path separators.
+//
+// Type is the registry type the value is written as. If it is empty, the
+// type is inferred from the value: string and version values are written
+// as strings, lists of strings as multi-strings and byte sequences as
+// binary values. Integer values are written as 32-bit integers when they
+// fit, and as 64-bit integers otherwise.
+type UserRegistryValue struct {
+	Key   string            `json:"key"`
+	Name  string            `json:"name"`
+	Type  RegistryValueType `json:"type,omitempty"`
+	Value lbvalue.Value     `json:"value"`
+}
+
+// Validate returns a non-nil error if the registry value is invalid.
+func (value UserRegistryValue) Validate() error {
+	if value.Key == "" {
+		return errors.New("a key is not specified")
+	}
+	if !filepath.IsLocal(value.Key) {
+		return fmt.Errorf("the key \"%s\" is not a relative path", value.Key)
+	}
+	if value.Type != "" {
+		if err := value.Type.Validate(); err != nil {
+			return err
+		}
+		if kind := value.Type.Kind(); kind != value.Value.Kind() {
+			return fmt.Errorf("the \"%s\" value is a %s value, but the %s registry type requires a %s value", value.Name, value.Value.Kind(), value.Type, kind)
+		}
+		return nil
+	}
+	switch value.Value.Kind() {
+	case lbvalue.KindInt64, lbvalue.KindString, lbvalue.KindVersion, lbvalue.KindStrings, lbvalue.KindBytes:
+	default:
+		return fmt.Errorf("the \"%s\" value has an unsupported type", value.Name)
+	}
+	return nil
+}
+
+// UserFile is a file that is copied into each user profile, such as a
+// shortcut.
+//
+// Source is the file resource that is copied. Path is the destination of
+// the file relative to the root of the profile, such as
+// "AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Viewer.lnk".
+type UserFile struct {
+	Source FileResourceID `json:"source"`
+	Path   string         `json:"path"`
+}
+
+// Validate returns a non-nil error if the file is invalid.
+func (file UserFile) Validate() error {
+	switch {
+	case file.Source == "":
+		return errors.New("a source file is not specified")
+	case file.Path == "":
+		return errors.New("a destination path is not specified")
+	case !filepath.IsLocal(file.Path):
+		return fmt.Errorf("the destination \"%s\" is not a relative path", file.Path)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package lbdeploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a recurring period of local time during which
+// commands may be invoked.
+//
+// Start and End are times of day in 24-hour "HH:MM" form. If End is not
+// after Start, the window extends past midnight into the following day.
+// Days holds the days of the week on which the window opens, such as
+// "saturday". If no days are specified, the window opens every day.
+type MaintenanceWindow struct {
+	Days  []string `json:"days,omitzero"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// Validate returns a non-nil error if the maintenance window is invalid.
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := parseTimeOfDay(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	for _, day := range w.Days {
+		if _, err := parseWeekday(day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains returns true if t falls within the maintenance window.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	start, end, ok := w.bounds()
+	if !ok {
+		return false
+	}
+
+	// Compare wall clock times rather than the time elapsed since midnight,
+	// which differs on days when daylight saving time begins or ends.
+	day := midnight(t)
+	offset := timeOfDay(t)
+
+	if end > start {
+		return w.opensOn(t.Weekday()) && offset >= start && offset < end
+	}
+
+	// The window crosses midnight.
+	if w.opensOn(t.Weekday()) && offset >= start {
+		return true
+	}
+	return w.opensOn(day.AddDate(0, 0, -1).Weekday()) && offset < end
+}
+
+// Next returns the earliest time at or after t that falls within the
+// maintenance window. It returns false if the window never opens.
+func (w MaintenanceWindow) Next(t time.Time) (time.Time, bool) {
+	if w.Contains(t) {
+		return t, true
+	}
+
+	start, _, ok := w.bounds()
+	if !ok {
+		return time.Time{}, false
+	}
+
+	day := midnight(t)
+	for i := 0; i <= 7; i++ {
+		candidate := day.AddDate(0, 0, i)
+		if !w.opensOn(candidate.Weekday()) {
+			continue
+		}
+		opens := atTimeOfDay(candidate, start)
+		if opens.After(t) {
+			return opens, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (w MaintenanceWindow) bounds() (start, end time.Duration, ok bool) {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = parseTimeOfDay(w.End)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func (w MaintenanceWindow) opensOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if d, err := parseWeekday(day); err == nil && d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceWindows is a set of maintenance windows.
+type MaintenanceWindows []MaintenanceWindow
+
+// Validate returns a non-nil error if any of the windows are invalid.
+func (windows MaintenanceWindows) Validate() error {
+	for i, w := range windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("maintenance window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Open returns true if no windows are defined, or if t falls within any of
+// them.
+func (windows MaintenanceWindows) Open(t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the earliest time at or after t at which one of the windows
+// is open. It returns false if none of the windows ever open.
+func (windows MaintenanceWindows) Next(t time.Time) (next time.Time, ok bool) {
+	if len(windows) == 0 {
+		return t, true
+	}
+	for _, w := range windows {
+		if candidate, found := w.Next(t); found && (!ok || candidate.Before(next)) {
+			next, ok = candidate, true
+		}
+	}
+	return
+}
+
+// midnight returns the start of the day on which t falls, in t's location.
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// timeOfDay returns the wall clock time of t as an offset from midnight.
+func timeOfDay(t time.Time) time.Duration {
+	hour, minute, sec := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// atTimeOfDay returns the time on the same day as t at which the wall clock
+// reads the given offset from midnight, in t's location.
+func atTimeOfDay(t time.Time, offset time.Duration) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// parseTimeOfDay parses a time of day in "HH:MM" form and returns it as an
+// offset from midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("a time of day is not specified")
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("\"%s\" is not a valid time of day", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseWeekday parses the name of a day of the week.
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("\"%s\" is not a day of the week", s)
+}
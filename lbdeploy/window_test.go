@@ -0,0 +1,124 @@
+package lbdeploy_test
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+
+	fixtures := []struct {
+		Name   string
+		Window lbdeploy.MaintenanceWindow
+		Time   time.Time
+		Out    bool
+	}{
+		// 2025-06-02 is a Monday.
+		{"DayStart", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), true},
+		{"DayMiddle", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 12, 30, 0, 0, time.UTC), true},
+		{"DayBefore", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 8, 59, 59, 0, time.UTC), false},
+		{"DayEnd", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 17, 0, 0, 0, time.UTC), false},
+
+		{"DayFilterMatch", lbdeploy.MaintenanceWindow{Days: []string{"Monday"}, Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), true},
+		{"DayFilterMiss", lbdeploy.MaintenanceWindow{Days: []string{"tuesday", "saturday"}, Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), false},
+
+		// 2025-06-07 is a Saturday.
+		{"MidnightBeforeMidnight", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 7, 23, 0, 0, 0, time.UTC), true},
+		{"MidnightAfterMidnight", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 8, 1, 30, 0, 0, time.UTC), true},
+		{"MidnightEnd", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 8, 2, 0, 0, 0, time.UTC), false},
+		{"MidnightWrongDayEvening", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 8, 23, 0, 0, 0, time.UTC), false},
+		{"MidnightWrongDayMorning", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 7, 1, 30, 0, 0, time.UTC), false},
+
+		// Daylight saving time began at 02:00 on 2025-03-09 and ended at
+		// 02:00 on 2025-11-02 in New York.
+		{"SpringForwardInside", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 3, 9, 4, 30, 0, 0, newYork), true},
+		{"SpringForwardBefore", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 3, 9, 3, 30, 0, 0, newYork), false},
+		{"SpringForwardAfter", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 3, 9, 6, 30, 0, 0, newYork), false},
+		{"FallBackInside", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 11, 2, 5, 30, 0, 0, newYork), true},
+		{"FallBackBefore", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 11, 2, 3, 30, 0, 0, newYork), false},
+		{"FallBackMidnight", lbdeploy.MaintenanceWindow{Start: "23:00", End: "05:00"}, time.Date(2025, 11, 2, 4, 30, 0, 0, newYork), true},
+
+		{"Invalid", lbdeploy.MaintenanceWindow{Start: "9am", End: "17:00"}, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			if got := fixture.Window.Contains(fixture.Time); got != fixture.Out {
+				t.Fatalf("unexpected result for %s: got %t, want %t", fixture.Time, got, fixture.Out)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowNext(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+
+	fixtures := []struct {
+		Name   string
+		Window lbdeploy.MaintenanceWindow
+		Time   time.Time
+		Out    time.Time
+		Never  bool
+	}{
+		{"Open", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 10, 15, 0, 0, time.UTC), time.Date(2025, 6, 2, 10, 15, 0, 0, time.UTC), false},
+		{"LaterToday", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), false},
+		{"Tomorrow", lbdeploy.MaintenanceWindow{Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC), time.Date(2025, 6, 3, 9, 0, 0, 0, time.UTC), false},
+		{"DayFilter", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC), time.Date(2025, 6, 7, 22, 0, 0, 0, time.UTC), false},
+		{"DayFilterNextWeek", lbdeploy.MaintenanceWindow{Days: []string{"monday"}, Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC), time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC), false},
+		{"AfterMidnight", lbdeploy.MaintenanceWindow{Days: []string{"saturday"}, Start: "22:00", End: "02:00"}, time.Date(2025, 6, 8, 1, 0, 0, 0, time.UTC), time.Date(2025, 6, 8, 1, 0, 0, 0, time.UTC), false},
+		{"SpringForward", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 3, 9, 0, 30, 0, 0, newYork), time.Date(2025, 3, 9, 4, 0, 0, 0, newYork), false},
+		{"FallBack", lbdeploy.MaintenanceWindow{Start: "04:00", End: "06:00"}, time.Date(2025, 11, 2, 0, 30, 0, 0, newYork), time.Date(2025, 11, 2, 4, 0, 0, 0, newYork), false},
+		{"UnknownDay", lbdeploy.MaintenanceWindow{Days: []string{"someday"}, Start: "09:00", End: "17:00"}, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), time.Time{}, true},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			next, ok := fixture.Window.Next(fixture.Time)
+			if fixture.Never {
+				if ok {
+					t.Fatalf("expected the window to never open, got %s", next)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected the window to open")
+			}
+			if !next.Equal(fixture.Out) {
+				t.Fatalf("unexpected next time: got %s, want %s", next, fixture.Out)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowsNext(t *testing.T) {
+	windows := lbdeploy.MaintenanceWindows{
+		{Days: []string{"saturday"}, Start: "22:00", End: "02:00"},
+		{Days: []string{"wednesday"}, Start: "12:00", End: "13:00"},
+	}
+
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	next, ok := windows.Next(now)
+	if want := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC); !ok || !next.Equal(want) {
+		t.Fatalf("unexpected next time: got %s, want %s", next, want)
+	}
+
+	if windows.Open(now) {
+		t.Fatal("the windows should be closed")
+	}
+	if !(lbdeploy.MaintenanceWindows{}).Open(now) {
+		t.Fatal("an empty set of windows should always be open")
+	}
+}
@@ -16,6 +16,8 @@ type ActionStarted struct {
 	Flow        lbdeploy.FlowID
 	ActionIndex int
 	ActionType  lbdeploy.ActionType
+	ActionID    lbdeploy.ActionID
+	Description string
 }
 
 // Component identifies the component that generated the event.
@@ -36,7 +38,14 @@ func (e ActionStarted) Message() string {
 	builder.WritePrimary(string(e.Flow))
 	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
 	builder.WritePrimary(string(e.ActionType))
-	builder.WriteStandard("Starting action")
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
+	if e.ActionID != "" {
+		builder.WriteStandard(fmt.Sprintf("Starting the \"%s\" action", e.ActionID))
+	} else {
+		builder.WriteStandard("Starting action")
+	}
 
 	return builder.String()
 }
@@ -53,7 +62,7 @@ func (e ActionStarted) Attrs() []slog.Attr {
 	return []slog.Attr{
 		slog.String("deployment", string(e.Deployment)),
 		slog.String("flow", string(e.Flow)),
-		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		actionAttr(e.ActionIndex, e.ActionType, e.ActionID, e.Description),
 	}
 }
 
@@ -63,6 +72,8 @@ type ActionStopped struct {
 	Flow        lbdeploy.FlowID
 	ActionIndex int
 	ActionType  lbdeploy.ActionType
+	ActionID    lbdeploy.ActionID
+	Description string
 	Started     time.Time
 	Stopped     time.Time
 	Err         error
@@ -92,9 +103,17 @@ func (e ActionStopped) Message() string {
 	builder.WritePrimary(string(e.Flow))
 	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
 	builder.WritePrimary(string(e.ActionType))
-	if e.Err != nil {
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
+	switch {
+	case e.Err != nil && e.ActionID != "":
+		builder.WriteStandard(fmt.Sprintf("Stopped the \"%s\" action due to an error: %s", e.ActionID, e.Err))
+	case e.Err != nil:
 		builder.WriteStandard(fmt.Sprintf("Stopped action due to an error: %s", e.Err))
-	} else {
+	case e.ActionID != "":
+		builder.WriteStandard(fmt.Sprintf("Completed the \"%s\" action", e.ActionID))
+	default:
 		builder.WriteStandard(fmt.Sprintf("Completed action"))
 	}
 	builder.WriteNote(e.Duration().Round(time.Millisecond * 10).String())
@@ -114,7 +133,7 @@ func (e ActionStopped) Attrs() []slog.Attr {
 	attrs := []slog.Attr{
 		slog.String("deployment", string(e.Deployment)),
 		slog.String("flow", string(e.Flow)),
-		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		actionAttr(e.ActionIndex, e.ActionType, e.ActionID, e.Description),
 		slog.Time("started", e.Started),
 		slog.Time("stopped", e.Stopped),
 	}
@@ -128,3 +147,139 @@ func (e ActionStopped) Attrs() []slog.Attr {
 func (e ActionStopped) Duration() time.Duration {
 	return e.Stopped.Sub(e.Started)
 }
+
+// ActionSkipped is an event that occurs when a deployment action is not
+// invoked because it was excluded by an action filter, or because an
+// action that it depends on did not complete.
+type ActionSkipped struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	ActionID    lbdeploy.ActionID
+	Description string
+	DependsOn   lbdeploy.ActionID // The unmet dependency, if any
+}
+
+// Component identifies the component that generated the event.
+func (e ActionSkipped) Component() string {
+	return "action"
+}
+
+// Level returns the level of the event.
+func (e ActionSkipped) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e ActionSkipped) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
+	action := "the action"
+	if e.ActionID != "" {
+		action = fmt.Sprintf("the \"%s\" action", e.ActionID)
+	}
+	if e.DependsOn != "" {
+		builder.WriteStandard(fmt.Sprintf("Skipping %s because the \"%s\" action that it depends on did not complete.", action, e.DependsOn))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Skipping %s because it was excluded by an action filter.", action))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e ActionSkipped) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e ActionSkipped) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		actionAttr(e.ActionIndex, e.ActionType, e.ActionID, e.Description),
+	}
+	if e.DependsOn != "" {
+		attrs = append(attrs, slog.String("depends-on", string(e.DependsOn)))
+	}
+	return attrs
+}
+
+// actionAttr returns a structured log attribute that identifies and
+// describes an action.
+func actionAttr(index int, actionType lbdeploy.ActionType, id lbdeploy.ActionID, description string) slog.Attr {
+	args := []any{"index", index, "type", actionType}
+	if id != "" {
+		args = append(args, "id", string(id))
+	}
+	if description != "" {
+		args = append(args, "description", description)
+	}
+	return slog.Group("action", args...)
+}
+
+// ActionOverdue is an event that occurs when a deployment action has been
+// running for longer than its expected duration. It gives early warning of
+// an action that may be stuck, such as an installer waiting for input.
+type ActionOverdue struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	ActionID    lbdeploy.ActionID
+	Description string
+	Expected    time.Duration
+}
+
+// Component identifies the component that generated the event.
+func (e ActionOverdue) Component() string {
+	return "action"
+}
+
+// Level returns the level of the event.
+func (e ActionOverdue) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e ActionOverdue) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
+	builder.WriteStandard(fmt.Sprintf("The action is still running after its expected duration of %s.", e.Expected))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e ActionOverdue) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e ActionOverdue) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		actionAttr(e.ActionIndex, e.ActionType, e.ActionID, e.Description),
+		slog.Duration("expected", e.Expected),
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gentlemanautomaton/structformat"
 	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/cmdoutput"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 )
 
@@ -53,6 +54,9 @@ func (e CommandSkipped) Message() string {
 	if len(e.Apps.AlreadyUninstalled) > 0 {
 		builder.WriteNote(fmt.Sprintf("[%s]", e.Apps.AlreadyUninstalled), fieldformat.Label("already uninstalled"))
 	}
+	if len(e.Apps.Locations) > 0 {
+		builder.WriteNote(fmt.Sprintf("[%s]", e.Apps.Locations), fieldformat.Label("found in"))
+	}
 
 	return builder.String()
 }
@@ -80,7 +84,9 @@ func (e CommandSkipped) Attrs() []slog.Attr {
 			"already-installed", e.Apps.AlreadyInstalled,
 			"already-uninstalled", e.Apps.AlreadyUninstalled,
 			"to-install", e.Apps.ToInstall,
-			"to-uninstall", e.Apps.ToUninstall))
+			"to-upgrade", e.Apps.ToUpgrade,
+			"to-uninstall", e.Apps.ToUninstall,
+			"locations", e.Apps.Locations))
 	}
 	return attrs
 }
@@ -96,6 +102,7 @@ type CommandStarted struct {
 	CommandLine          string
 	WorkingDirectory     lbdeploy.DirectoryResourceID
 	WorkingDirectoryPath string
+	LogFile              string
 	Apps                 lbdeploy.AppEvaluation
 }
 
@@ -133,6 +140,9 @@ func (e CommandStarted) Message() string {
 		builder.WritePrimary("Starting command")
 	}
 	builder.WriteStandard(e.CommandLine)
+	if len(e.Apps.ToUpgrade) > 0 {
+		builder.WriteNote(fmt.Sprintf("[%s]", e.Apps.ToUpgrade), fieldformat.Label("upgrading"))
+	}
 
 	return builder.String()
 }
@@ -141,14 +151,23 @@ func (e CommandStarted) Message() string {
 // multiple lines of text. An empty string is returned when no details
 // are available.
 func (e CommandStarted) Details() string {
+	var out strings.Builder
+
 	switch {
 	case e.WorkingDirectoryPath != "":
-		return fmt.Sprintf("Working Directory: %s", e.WorkingDirectoryPath)
+		out.WriteString(fmt.Sprintf("Working Directory: %s", e.WorkingDirectoryPath))
 	case e.WorkingDirectory != "":
-		return fmt.Sprintf("Working Directory: %s", e.WorkingDirectory)
-	default:
-		return ""
+		out.WriteString(fmt.Sprintf("Working Directory: %s", e.WorkingDirectory))
+	}
+
+	if e.LogFile != "" {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("Log File: %s", e.LogFile))
 	}
+
+	return out.String()
 }
 
 // Attrs returns a set of structured log attributes for the event.
@@ -165,12 +184,17 @@ func (e CommandStarted) Attrs() []slog.Attr {
 	if e.WorkingDirectory != "" || e.WorkingDirectoryPath != "" {
 		attrs = append(attrs, slog.Group("working-directory", "id", e.WorkingDirectory, "path", e.WorkingDirectoryPath))
 	}
+	if e.LogFile != "" {
+		attrs = append(attrs, slog.String("log-file", e.LogFile))
+	}
 	if !e.Apps.IsZero() {
 		attrs = append(attrs, slog.Group("affected-apps",
 			"already-installed", e.Apps.AlreadyInstalled,
 			"already-uninstalled", e.Apps.AlreadyUninstalled,
 			"to-install", e.Apps.ToInstall,
-			"to-uninstall", e.Apps.ToUninstall))
+			"to-upgrade", e.Apps.ToUpgrade,
+			"to-uninstall", e.Apps.ToUninstall,
+			"locations", e.Apps.Locations))
 	}
 	return attrs
 }
@@ -185,11 +209,14 @@ type CommandStopped struct {
 	Command              lbdeploy.CommandID
 	CommandLine          string
 	Result               lbdeploy.CommandResult
-	Output               string
+	Output               cmdoutput.Lines
 	WorkingDirectory     lbdeploy.DirectoryResourceID
 	WorkingDirectoryPath string
+	LogFile              string
 	AppsBefore           lbdeploy.AppEvaluation
 	AppsAfter            lbdeploy.AppSummary
+	PostConditionsPassed lbdeploy.ConditionList
+	PostConditionsFailed lbdeploy.ConditionList
 	Started              time.Time
 	Stopped              time.Time
 	Err                  error
@@ -226,7 +253,14 @@ func (e CommandStopped) Message() string {
 	} else if err := e.AppsAfter.Err(); err != nil {
 		builder.WriteStandard(fmt.Sprintf("Completed command but %s", err))
 	} else {
-		builder.WriteStandard(fmt.Sprintf("Completed command"))
+		switch e.Result.Outcome() {
+		case lbdeploy.CommandSucceededRebootRequired:
+			builder.WriteStandard("Completed command, which requires a restart")
+		case lbdeploy.CommandSucceededRebootInitiated:
+			builder.WriteStandard("Completed command, which has initiated a restart")
+		default:
+			builder.WriteStandard(fmt.Sprintf("Completed command"))
+		}
 	}
 	builder.WriteNote(e.Duration().Round(time.Millisecond * 10).String())
 	if e.Result.ExitCode != 0 {
@@ -250,6 +284,27 @@ func (e CommandStopped) Details() string {
 	default:
 	}
 
+	if e.LogFile != "" {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("Log File: %s", e.LogFile))
+	}
+
+	if len(e.PostConditionsPassed) > 0 {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("Post-Conditions Passed: %s", e.PostConditionsPassed))
+	}
+
+	if len(e.PostConditionsFailed) > 0 {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("Post-Conditions Failed: %s", e.PostConditionsFailed))
+	}
+
 	if e.CommandLine != "" {
 		if out.Len() > 0 {
 			out.WriteString("\n\n")
@@ -257,11 +312,27 @@ func (e CommandStopped) Details() string {
 		out.WriteString(e.CommandLine)
 	}
 
-	if e.Output != "" {
+	if result := e.Result; result.ExitCode != 0 && (result.SystemMessage != "" || result.Info.Remediation != "") {
+		if out.Len() > 0 {
+			out.WriteString("\n\n")
+		}
+		out.WriteString(fmt.Sprintf("Exit Code: %d", result.ExitCode))
+		if result.Info.Name != "" {
+			out.WriteString(fmt.Sprintf(" (%s)", result.Info.Name))
+		}
+		if result.SystemMessage != "" {
+			out.WriteString(fmt.Sprintf("\nSystem Message: %s", result.SystemMessage))
+		}
+		if result.Info.Remediation != "" {
+			out.WriteString(fmt.Sprintf("\nRemediation: %s", result.Info.Remediation))
+		}
+	}
+
+	if len(e.Output) > 0 {
 		if out.Len() > 0 {
 			out.WriteString("\n\n")
 		}
-		out.WriteString(e.Output)
+		out.WriteString(e.Output.String())
 	}
 
 	return out.String()
@@ -285,12 +356,17 @@ func (e CommandStopped) Attrs() []slog.Attr {
 	if e.WorkingDirectory != "" || e.WorkingDirectoryPath != "" {
 		attrs = append(attrs, slog.Group("working-directory", "id", e.WorkingDirectory, "path", e.WorkingDirectoryPath))
 	}
+	if e.LogFile != "" {
+		attrs = append(attrs, slog.String("log-file", e.LogFile))
+	}
 	if !e.AppsBefore.IsZero() {
 		attrs = append(attrs, slog.Group("affected-apps-before",
 			"already-installed", e.AppsBefore.AlreadyInstalled,
 			"already-uninstalled", e.AppsBefore.AlreadyUninstalled,
 			"to-install", e.AppsBefore.ToInstall,
-			"to-uninstall", e.AppsBefore.ToUninstall))
+			"to-upgrade", e.AppsBefore.ToUpgrade,
+			"to-uninstall", e.AppsBefore.ToUninstall,
+			"locations", e.AppsBefore.Locations))
 	}
 	if !e.AppsAfter.IsZero() {
 		attrs = append(attrs, slog.Group("affected-apps-after",
@@ -299,8 +375,21 @@ func (e CommandStopped) Attrs() []slog.Attr {
 			"still-not-installed", e.AppsAfter.StillNotInstalled,
 			"still-not-uninstalled", e.AppsAfter.StillNotUninstalled))
 	}
-	if e.Output != "" {
-		attrs = append(attrs, slog.String("output", e.Output))
+	if len(e.PostConditionsPassed) > 0 || len(e.PostConditionsFailed) > 0 {
+		attrs = append(attrs, slog.Group("post-conditions",
+			"passed", e.PostConditionsPassed,
+			"failed", e.PostConditionsFailed))
+	}
+	if e.Result.ExitCode != 0 {
+		attrs = append(attrs, slog.Group("result",
+			"exit-code", int(e.Result.ExitCode),
+			"outcome", e.Result.Outcome(),
+			"name", e.Result.Info.Name,
+			"system-message", e.Result.SystemMessage,
+			"remediation", e.Result.Info.Remediation))
+	}
+	if len(e.Output) > 0 {
+		attrs = append(attrs, slog.Any("output", e.Output))
 	}
 	err := e.Err
 	if err == nil {
@@ -316,3 +405,72 @@ func (e CommandStopped) Attrs() []slog.Attr {
 func (e CommandStopped) Duration() time.Duration {
 	return e.Stopped.Sub(e.Started)
 }
+
+// InstallerBusy is an event that occurs when an MSI command waits for the
+// Windows Installer to finish another installation.
+type InstallerBusy struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Package     lbdeploy.PackageID
+	Command     lbdeploy.CommandID
+	Reason      string
+	Delay       time.Duration
+	Deadline    time.Time
+}
+
+// Component identifies the component that generated the event.
+func (e InstallerBusy) Component() string {
+	return "command"
+}
+
+// Level returns the level of the event.
+func (e InstallerBusy) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e InstallerBusy) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Package == "" {
+		builder.WritePrimary(string(e.Command))
+	} else {
+		builder.WritePrimary(fmt.Sprintf("%s.%s", e.Package, e.Command))
+	}
+	builder.WriteStandard(fmt.Sprintf("The Windows Installer is busy. Trying again in %s.", e.Delay))
+	builder.WriteNote(e.Reason)
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e InstallerBusy) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e InstallerBusy) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+	}
+	if e.Package != "" {
+		attrs = append(attrs, slog.String("package", string(e.Package)))
+	}
+	attrs = append(attrs,
+		slog.String("command", string(e.Command)),
+		slog.String("reason", e.Reason),
+		slog.Duration("delay", e.Delay),
+		slog.Time("deadline", e.Deadline),
+	)
+	return attrs
+}
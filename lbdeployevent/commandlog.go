@@ -0,0 +1,107 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// CommandLogCollected is an event that occurs when the installer log of a
+// command has been collected after the command stopped.
+type CommandLogCollected struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Package     lbdeploy.PackageID
+	Command     lbdeploy.CommandID
+	LogFile     string
+	Missing     bool
+	Uploaded    string
+	Retained    bool
+	Pruned      []string
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e CommandLogCollected) Component() string {
+	return "command"
+}
+
+// Level returns the level of the event.
+func (e CommandLogCollected) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e CommandLogCollected) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Package == "" {
+		builder.WritePrimary(string(e.Command))
+	} else {
+		builder.WritePrimary(fmt.Sprintf("%s.%s", e.Package, e.Command))
+	}
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Collection of the command log failed due to an error: %s.", e.Err))
+	case e.Missing:
+		builder.WriteStandard("The command did not write a log.")
+	case e.Uploaded != "" && e.Retained:
+		builder.WriteStandard(fmt.Sprintf("Retained the command log and uploaded a copy to %s.", e.Uploaded))
+	case e.Uploaded != "":
+		builder.WriteStandard(fmt.Sprintf("Uploaded the command log to %s and removed the local copy.", e.Uploaded))
+	case e.Retained:
+		builder.WriteStandard("Retained the command log.")
+	default:
+		builder.WriteStandard("Removed the command log.")
+	}
+	builder.WriteNote(e.LogFile)
+	if len(e.Pruned) > 0 {
+		builder.WriteNote(fmt.Sprintf("pruned %d older logs", len(e.Pruned)))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e CommandLogCollected) Details() string {
+	if len(e.Pruned) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Pruned Logs:\n%s", strings.Join(e.Pruned, "\n"))
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e CommandLogCollected) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+	}
+	if e.Package != "" {
+		attrs = append(attrs, slog.String("package", string(e.Package)))
+	}
+	attrs = append(attrs,
+		slog.Group("command", "id", e.Command),
+		slog.Group("log", "path", e.LogFile, "missing", e.Missing, "uploaded", e.Uploaded, "retained", e.Retained, "pruned", e.Pruned),
+	)
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
@@ -0,0 +1,108 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// DeferralOutcome describes the result of a deferral prompt.
+type DeferralOutcome string
+
+// Deferral outcomes.
+const (
+	DeferralAccepted DeferralOutcome = "accepted" // The user agreed to proceed
+	DeferralDeferred DeferralOutcome = "deferred" // The user postponed the deployment
+	DeferralForced   DeferralOutcome = "forced"   // No deferrals remained
+	DeferralNoUser   DeferralOutcome = "no-user"  // Nobody was signed in to ask
+)
+
+// DeferralPrompted is an event that occurs when a prompt-deferral action
+// has determined whether the deployment may proceed.
+//
+// Deferrals is the number of times the deployment has been deferred,
+// including any deferral recorded by this prompt.
+type DeferralPrompted struct {
+	Deployment   lbdeploy.DeploymentID
+	Flow         lbdeploy.FlowID
+	ActionIndex  int
+	ActionType   lbdeploy.ActionType
+	Outcome      DeferralOutcome
+	Deferrals    int
+	MaxDeferrals int
+	Deadline     time.Time
+	Err          error
+}
+
+// Component identifies the component that generated the event.
+func (e DeferralPrompted) Component() string {
+	return "deferral"
+}
+
+// Level returns the level of the event.
+func (e DeferralPrompted) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e DeferralPrompted) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Failed to determine whether the deployment may proceed: %s.", e.Err))
+	case e.Outcome == DeferralDeferred:
+		builder.WriteStandard("The user postponed the deployment.")
+	case e.Outcome == DeferralAccepted:
+		builder.WriteStandard("The user agreed to proceed with the deployment.")
+	case e.Outcome == DeferralForced:
+		builder.WriteStandard("No deferrals remain. The deployment will proceed.")
+	case e.Outcome == DeferralNoUser:
+		builder.WriteStandard("No user is signed in. The deployment will proceed.")
+	}
+
+	if e.MaxDeferrals > 0 {
+		builder.WriteNote(fmt.Sprintf("%d of %d", e.Deferrals, e.MaxDeferrals), fieldformat.Label("deferrals"))
+	} else {
+		builder.WriteNote(strconv.Itoa(e.Deferrals), fieldformat.Label("deferrals"))
+	}
+	if !e.Deadline.IsZero() {
+		builder.WriteNote(e.Deadline.Format(time.RFC3339), fieldformat.Label("deadline"))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DeferralPrompted) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DeferralPrompted) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("deferral", "outcome", e.Outcome, "count", e.Deferrals, "max", e.MaxDeferrals, "deadline", e.Deadline),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
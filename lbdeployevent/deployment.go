@@ -0,0 +1,235 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// FlowResult summarizes the outcome of a single flow within a deployment
+// invocation.
+type FlowResult struct {
+	Flow    lbdeploy.FlowID
+	Stats   lbdeploy.FlowStats
+	Skipped bool
+	Err     error
+}
+
+// DeploymentSummary is an event that occurs when a deployment invocation
+// with more than one flow has finished.
+type DeploymentSummary struct {
+	Deployment lbdeploy.DeploymentID
+	Flows      []FlowResult
+	Started    time.Time
+	Stopped    time.Time
+	Err        error
+}
+
+// Component identifies the component that generated the event.
+func (e DeploymentSummary) Component() string {
+	return "deployment"
+}
+
+// Level returns the level of the event.
+func (e DeploymentSummary) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e DeploymentSummary) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+
+	var completed, failed, skipped int
+	for _, flow := range e.Flows {
+		switch {
+		case flow.Skipped:
+			skipped++
+		case flow.Err != nil:
+			failed++
+		default:
+			completed++
+		}
+	}
+
+	parts := []string{fmt.Sprintf("%d %s completed successfully", completed, plural(completed, "flow", "flows"))}
+	if failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", failed))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", skipped))
+	}
+	builder.WriteStandard(fmt.Sprintf("Finished: %s.", strings.Join(parts, ", ")))
+
+	builder.WriteNote(e.Duration().Round(time.Millisecond * 10).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DeploymentSummary) Details() string {
+	var lines []string
+	for _, flow := range e.Flows {
+		var status string
+		switch {
+		case flow.Skipped:
+			status = "Skipped."
+		case flow.Err != nil:
+			status = fmt.Sprintf("Failed: %s", flow.Err)
+		default:
+			status = fmt.Sprintf("Completed %d %s.", flow.Stats.ActionsCompleted, plural(flow.Stats.ActionsCompleted, "action", "actions"))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", flow.Flow, status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DeploymentSummary) Attrs() []slog.Attr {
+	flows := make([]any, 0, len(e.Flows))
+	for _, flow := range e.Flows {
+		attrs := []any{
+			slog.Bool("skipped", flow.Skipped),
+			slog.Group("actions", "completed", flow.Stats.ActionsCompleted, "failed", flow.Stats.ActionsFailed),
+		}
+		if flow.Err != nil {
+			attrs = append(attrs, slog.String("error", flow.Err.Error()))
+		}
+		flows = append(flows, slog.Group(string(flow.Flow), attrs...))
+	}
+
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+		slog.Group("flows", flows...),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// Duration returns the duration of the deployment invocation.
+func (e DeploymentSummary) Duration() time.Duration {
+	return e.Stopped.Sub(e.Started)
+}
+
+// DeploymentResult is an event that occurs when a deployment invocation has
+// finished. It reports the changes that were made to the set of installed
+// applications and whether a restart of the system is needed for those
+// changes to be fully activated.
+//
+// RebootReasons holds the reasons given by the deployment's commands.
+// PendingRestart holds the restart indicators that were present on the
+// system when the deployment finished, including those that were present
+// before the deployment started.
+type DeploymentResult struct {
+	Deployment       lbdeploy.DeploymentID
+	Apps             lbdeploy.AppSummary
+	RebootReasons    []string
+	PendingRestart   []string
+	RestartInitiated bool
+	Err              error
+}
+
+// Component identifies the component that generated the event.
+func (e DeploymentResult) Component() string {
+	return "deployment"
+}
+
+// Level returns the level of the event.
+func (e DeploymentResult) Level() slog.Level {
+	switch {
+	case e.Err != nil:
+		return slog.LevelError
+	case e.RebootRequired():
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Message returns a description of the event.
+func (e DeploymentResult) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+
+	switch {
+	case e.RestartInitiated:
+		builder.WriteStandard("A restart of the system has been initiated to finish activating changes.")
+	case e.RebootRequired():
+		builder.WriteStandard("A restart of the system is required to finish activating changes.")
+	default:
+		builder.WriteStandard("No restart of the system is required.")
+	}
+
+	if len(e.Apps.Installed) > 0 {
+		builder.WriteNote(fmt.Sprintf("installed: %s", e.Apps.Installed))
+	}
+	if len(e.Apps.Uninstalled) > 0 {
+		builder.WriteNote(fmt.Sprintf("uninstalled: %s", e.Apps.Uninstalled))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DeploymentResult) Details() string {
+	var lines []string
+	if len(e.Apps.StillNotInstalled) > 0 {
+		lines = append(lines, fmt.Sprintf("Still not installed: %s", e.Apps.StillNotInstalled))
+	}
+	if len(e.Apps.StillNotUninstalled) > 0 {
+		lines = append(lines, fmt.Sprintf("Still not uninstalled: %s", e.Apps.StillNotUninstalled))
+	}
+	for _, reason := range e.RebootReasons {
+		lines = append(lines, fmt.Sprintf("Restart required: %s", reason))
+	}
+	for _, indicator := range e.PendingRestart {
+		lines = append(lines, fmt.Sprintf("Restart pending: %s", indicator))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DeploymentResult) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+	}
+	if !e.Apps.IsZero() {
+		attrs = append(attrs, slog.Group("apps",
+			"installed", e.Apps.Installed,
+			"uninstalled", e.Apps.Uninstalled,
+			"still-not-installed", e.Apps.StillNotInstalled,
+			"still-not-uninstalled", e.Apps.StillNotUninstalled))
+	}
+	attrs = append(attrs, slog.Group("reboot",
+		"required", e.RebootRequired(),
+		"reasons", e.RebootReasons,
+		"pending", e.PendingRestart,
+		"initiated", e.RestartInitiated))
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// RebootRequired returns true if a restart of the system is required to
+// finish activating changes.
+func (e DeploymentResult) RebootRequired() bool {
+	return len(e.RebootReasons) > 0 || len(e.PendingRestart) > 0
+}
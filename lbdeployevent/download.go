@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gentlemanautomaton/structformat"
@@ -20,6 +21,8 @@ type DownloadStarted struct {
 	FileName    string
 	Path        string
 	Offset      int64
+	Connections int
+	Latency     time.Duration
 }
 
 // Component identifies the component that generated the event.
@@ -45,6 +48,12 @@ func (e DownloadStarted) Message() string {
 	} else {
 		builder.WriteStandard(fmt.Sprintf("Starting download of \"%s\" from \"%s\".", e.FileName, e.Source.URL))
 	}
+	if e.Connections > 1 {
+		builder.WriteNote(fmt.Sprintf("%d connections", e.Connections))
+	}
+	if e.Latency > 0 {
+		builder.WriteNote(fmt.Sprintf("responded to probe in %s", e.Latency.Round(time.Millisecond)))
+	}
 
 	return builder.String()
 }
@@ -58,14 +67,19 @@ func (e DownloadStarted) Details() string {
 
 // Attrs returns a set of structured log attributes for the event.
 func (e DownloadStarted) Attrs() []slog.Attr {
-	return []slog.Attr{
+	attrs := []slog.Attr{
 		slog.String("deployment", string(e.Deployment)),
 		slog.String("flow", string(e.Flow)),
 		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
-		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL),
+		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL, "priority", e.Source.Priority, "weight", e.Source.Weight),
 		slog.String("path", string(e.Path)),
 		slog.Int64("offset", e.Offset),
+		slog.Int("connections", max(e.Connections, 1)),
+	}
+	if e.Latency > 0 {
+		attrs = append(attrs, slog.Duration("latency", e.Latency))
 	}
+	return attrs
 }
 
 // DownloadStopped is an event that occurs when a file download has stopped.
@@ -173,6 +187,9 @@ const (
 	ExistingFileVerificationFailed   DownloadResetReason = "existing-file-verification-failed"
 	HTTPServerDoesNotSupportResume   DownloadResetReason = "http-server-does-not-support-resume"
 	DownloadedFileVerificationFailed DownloadResetReason = "downloaded-file-verification-failed"
+	DeltaResultVerificationFailed    DownloadResetReason = "delta-result-verification-failed"
+	SourceFileSmallerThanDownload    DownloadResetReason = "source-file-smaller-than-download"
+	DownloadForced                   DownloadResetReason = "download-forced"
 )
 
 // Description returns a string describing the reason that the download was
@@ -187,6 +204,12 @@ func (reason DownloadResetReason) Description() string {
 		return "the HTTP server does not support resuming downloads"
 	case DownloadedFileVerificationFailed:
 		return "the downloaded file did not pass verification"
+	case DeltaResultVerificationFailed:
+		return "the file produced from a delta did not pass verification"
+	case SourceFileSmallerThanDownload:
+		return "the source file is smaller than the content that was already downloaded"
+	case DownloadForced:
+		return "the download was forced"
 	default:
 		return string(reason)
 	}
@@ -213,10 +236,14 @@ func (e DownloadReset) Component() string {
 
 // Level returns the level of the event.
 func (e DownloadReset) Level() slog.Level {
-	if e.Reason == HTTPServerDoesNotSupportResume {
+	switch e.Reason {
+	case DownloadForced:
+		return slog.LevelInfo
+	case HTTPServerDoesNotSupportResume:
 		return slog.LevelWarn
+	default:
+		return slog.LevelError
 	}
-	return slog.LevelError
 }
 
 // Message returns a description of the event.
@@ -262,3 +289,509 @@ func (e DownloadReset) Attrs() []slog.Attr {
 	}
 	return attrs
 }
+
+// DownloadAbortReason identifies the reason that a download was aborted
+// because of the server's response.
+type DownloadAbortReason string
+
+// Possible reasons for a download being aborted.
+const (
+	ContentLengthMismatch  DownloadAbortReason = "content-length-mismatch"
+	ContentRangeMismatch   DownloadAbortReason = "content-range-mismatch"
+	ResponseTooLarge       DownloadAbortReason = "response-too-large"
+	ResponseTruncated      DownloadAbortReason = "response-truncated"
+	SourceFileSizeMismatch DownloadAbortReason = "source-file-size-mismatch"
+)
+
+// Description returns a string describing the reason that the download was
+// aborted.
+func (reason DownloadAbortReason) Description() string {
+	switch reason {
+	case ContentLengthMismatch:
+		return "the Content-Length of the response does not match the expected size"
+	case ContentRangeMismatch:
+		return "the Content-Range of the response does not match the requested range"
+	case ResponseTooLarge:
+		return "the response exceeds the maximum accepted file size"
+	case ResponseTruncated:
+		return "the response ended before all of the file was received"
+	case SourceFileSizeMismatch:
+		return "the size of the source file does not match the expected size"
+	default:
+		return string(reason)
+	}
+}
+
+// DownloadAborted is an event that occurs when a download is abandoned
+// because the server's response is inconsistent with the file being
+// downloaded, such as a server that sends more or less content than it
+// claimed.
+//
+// Content that was received before a truncation is kept, so that the
+// download can be resumed from another source.
+type DownloadAborted struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Source      lbdeploy.PackageSource
+	FileName    string
+	Path        string
+	Reason      DownloadAbortReason
+	Expected    int64
+	Actual      int64
+}
+
+// Component identifies the component that generated the event.
+func (e DownloadAborted) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e DownloadAborted) Level() slog.Level {
+	return slog.LevelError
+}
+
+// Message returns a description of the event.
+func (e DownloadAborted) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	builder.WriteStandard(fmt.Sprintf("The download of \"%s\" from \"%s\" was aborted because %s (expected %d, actual %d).",
+		e.FileName,
+		e.Source.URL,
+		e.Reason.Description(),
+		e.Expected,
+		e.Actual))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DownloadAborted) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DownloadAborted) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL),
+		slog.String("file", e.FileName),
+		slog.String("path", e.Path),
+		slog.String("reason", string(e.Reason)),
+		slog.Int64("expected", e.Expected),
+		slog.Int64("actual", e.Actual),
+	}
+}
+
+// DownloadSourceSkipped is an event that occurs when a package source is
+// not attempted because its condition was not met, such as an internal
+// mirror that is not reachable.
+type DownloadSourceSkipped struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Source      lbdeploy.PackageSource
+	FileName    string
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e DownloadSourceSkipped) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e DownloadSourceSkipped) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e DownloadSourceSkipped) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Err != nil {
+		builder.WriteStandard(fmt.Sprintf("The \"%s\" source for \"%s\" was skipped because its \"%s\" condition could not be evaluated: %s.", e.Source.URL, e.FileName, e.Source.Condition, e.Err))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("The \"%s\" source for \"%s\" was skipped because its \"%s\" condition was not met.", e.Source.URL, e.FileName, e.Source.Condition))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DownloadSourceSkipped) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DownloadSourceSkipped) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("file", e.FileName),
+		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL, "condition", string(e.Source.Condition)),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// DownloadRetrying is an event that occurs when a package download has
+// failed and will be attempted again.
+type DownloadRetrying struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	FileName    string
+	Attempt     int
+	Attempts    int
+	Delay       time.Duration
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e DownloadRetrying) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e DownloadRetrying) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e DownloadRetrying) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Delay > 0 {
+		builder.WriteStandard(fmt.Sprintf("Attempt %d of %d to download \"%s\" failed. Retrying in %s.", e.Attempt, e.Attempts, e.FileName, e.Delay))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Attempt %d of %d to download \"%s\" failed. Retrying.", e.Attempt, e.Attempts, e.FileName))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DownloadRetrying) Details() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DownloadRetrying) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("file", e.FileName),
+		slog.Int("attempt", e.Attempt),
+		slog.Int("attempts", e.Attempts),
+		slog.Duration("delay", e.Delay),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// DownloadSourceProbed is an event that occurs when a package source has
+// been probed to determine how quickly it responds.
+type DownloadSourceProbed struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Source      lbdeploy.PackageSource
+	FileName    string
+	Latency     time.Duration
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e DownloadSourceProbed) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e DownloadSourceProbed) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelWarn
+	}
+	return slog.LevelDebug
+}
+
+// Message returns a description of the event.
+func (e DownloadSourceProbed) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Err != nil {
+		builder.WriteStandard(fmt.Sprintf("The \"%s\" source for \"%s\" did not respond to its probe and will be attempted last: %s.", e.Source.URL, e.FileName, e.Err))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("The \"%s\" source for \"%s\" responded to its probe in %s.", e.Source.URL, e.FileName, e.Latency.Round(time.Millisecond)))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DownloadSourceProbed) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DownloadSourceProbed) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("file", e.FileName),
+		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL, "priority", e.Source.Priority, "weight", e.Source.Weight),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	} else {
+		attrs = append(attrs, slog.Duration("latency", e.Latency))
+	}
+	return attrs
+}
+
+// DeltaApplied is an event that occurs when an attempt has been made to
+// produce a package file by applying a binary delta to a previous version
+// of the package.
+type DeltaApplied struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	FileName    string
+	Path        string
+	BasePath    string
+	Delta       lbdeploy.PackageDelta
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e DeltaApplied) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e DeltaApplied) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e DeltaApplied) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Err != nil {
+		builder.WriteStandard(fmt.Sprintf("A %s delta could not be used to produce \"%s\" from \"%s\", so the full package will be downloaded: %s.", e.Delta.Format, e.FileName, e.BasePath, e.Err))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("\"%s\" was produced from \"%s\" with a %s delta of %d bytes.", e.FileName, e.BasePath, e.Delta.Format, e.Delta.Attributes.Size))
+		builder.WriteNote(e.Stopped.Sub(e.Started).Round(time.Millisecond * 10).String())
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DeltaApplied) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DeltaApplied) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("path", e.Path),
+		slog.String("base", e.BasePath),
+		slog.Group("delta", "format", string(e.Delta.Format), "size", e.Delta.Attributes.Size),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// PeersFound is an event that occurs when the local network has been
+// searched for peers that hold a package file.
+type PeersFound struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	FileName    string
+	Peers       []string
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e PeersFound) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e PeersFound) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e PeersFound) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Failed to search the local network for peers holding \"%s\": %s.", e.FileName, e.Err))
+	case len(e.Peers) == 0:
+		builder.WriteStandard(fmt.Sprintf("No peers on the local network hold \"%s\".", e.FileName))
+	case len(e.Peers) == 1:
+		builder.WriteStandard(fmt.Sprintf("Found 1 peer on the local network holding \"%s\".", e.FileName))
+	default:
+		builder.WriteStandard(fmt.Sprintf("Found %d peers on the local network holding \"%s\".", len(e.Peers), e.FileName))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e PeersFound) Details() string {
+	return strings.Join(e.Peers, "\n")
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e PeersFound) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("file", e.FileName),
+		slog.Any("peers", e.Peers),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// TransferJobStarted is an event that occurs when a background transfer
+// job has been started or resumed to download a file.
+type TransferJobStarted struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Source      lbdeploy.PackageSource
+	FileName    string
+	Path        string
+	Job         string
+	Resumed     bool
+}
+
+// Component identifies the component that generated the event.
+func (e TransferJobStarted) Component() string {
+	return "download"
+}
+
+// Level returns the level of the event.
+func (e TransferJobStarted) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e TransferJobStarted) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary("download-package")
+	if e.Resumed {
+		builder.WriteStandard(fmt.Sprintf("Resuming background transfer of \"%s\" from \"%s\".", e.FileName, e.Source.URL))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Starting background transfer of \"%s\" from \"%s\".", e.FileName, e.Source.URL))
+	}
+	builder.WriteNote(e.Job)
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e TransferJobStarted) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e TransferJobStarted) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("source", "type", string(e.Source.Type), "url", e.Source.URL),
+		slog.String("path", string(e.Path)),
+		slog.String("job", e.Job),
+		slog.Bool("resumed", e.Resumed),
+	}
+}
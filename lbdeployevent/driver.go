@@ -0,0 +1,103 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// DriverInstalled is an event that occurs when an attempt has been made to
+// install a driver package.
+//
+// Applied is true if the driver was installed on at least one device. If it
+// is false, the driver package was added to the driver store without being
+// installed on any device, because no devices are present that it supports
+// or their current drivers outrank it.
+type DriverInstalled struct {
+	Deployment      lbdeploy.DeploymentID
+	Flow            lbdeploy.FlowID
+	ActionIndex     int
+	ActionType      lbdeploy.ActionType
+	Package         lbdeploy.PackageID
+	File            lbdeploy.PackageFileID
+	Path            string
+	Forced          bool
+	Applied         bool
+	RebootRequired  bool
+	DriverStorePath string
+	Started         time.Time
+	Stopped         time.Time
+	Err             error
+}
+
+// Component identifies the component that generated the event.
+func (e DriverInstalled) Component() string {
+	return "driver"
+}
+
+// Level returns the level of the event.
+func (e DriverInstalled) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e DriverInstalled) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	builder.WritePrimary(fmt.Sprintf("%s.%s", e.Package, e.File))
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Installation of the driver failed due to an error: %s.", e.Err))
+	case e.Applied:
+		builder.WriteStandard("Installed the driver on the devices that it supports.")
+	default:
+		builder.WriteStandard("Added the driver to the driver store. No devices are present for which it outranks the current driver.")
+	}
+	if e.Forced {
+		builder.WriteNote("forced")
+	}
+	if e.RebootRequired {
+		builder.WriteNote("restart required")
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e DriverInstalled) Details() string {
+	if e.DriverStorePath == "" {
+		return ""
+	}
+	return fmt.Sprintf("Driver store: %s", e.DriverStorePath)
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e DriverInstalled) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("package", string(e.Package)),
+		slog.Group("driver", "file", string(e.File), "path", e.Path, "store-path", e.DriverStorePath, "forced", e.Forced, "applied", e.Applied, "reboot-required", e.RebootRequired),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
@@ -0,0 +1,83 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// ElevationDetected is an event that occurs when a deployment determines
+// the privileges held by the process that is running it.
+type ElevationDetected struct {
+	Deployment lbdeploy.DeploymentID
+	Elevated   bool
+	System     bool
+	Required   bool
+	Err        error
+}
+
+// Component identifies the component that generated the event.
+func (e ElevationDetected) Component() string {
+	return "elevation"
+}
+
+// Level returns the level of the event.
+func (e ElevationDetected) Level() slog.Level {
+	switch {
+	case e.Err != nil:
+		return slog.LevelError
+	case e.Required && !e.Elevated:
+		return slog.LevelError
+	case !e.Elevated:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// Message returns a description of the event.
+func (e ElevationDetected) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Failed to determine whether the process is elevated: %s.", e.Err))
+	case e.System:
+		builder.WriteStandard("The deployment is running as the local system account.")
+	case e.Elevated:
+		builder.WriteStandard("The deployment is running with elevated privileges.")
+	case e.Required:
+		builder.WriteStandard("The deployment requires elevated privileges, but the process is not elevated.")
+	default:
+		builder.WriteStandard("The deployment is running without elevated privileges. Changes to protected locations will fail.")
+	}
+
+	if e.Required {
+		builder.WriteNote("requires elevation")
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e ElevationDetected) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e ElevationDetected) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.Group("elevation", "elevated", e.Elevated, "system", e.System, "required", e.Required),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
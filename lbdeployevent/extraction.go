@@ -53,6 +53,7 @@ type ExtractionStarted struct {
 	SourcePath      string
 	DestinationPath string
 	SourceStats     ExtractionStats
+	Streaming       bool
 }
 
 // Component identifies the component that generated the event.
@@ -73,7 +74,11 @@ func (e ExtractionStarted) Message() string {
 	builder.WritePrimary(string(e.Flow))
 	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
 	builder.WritePrimary("extract-package")
-	builder.WriteStandard(fmt.Sprintf("Starting extraction of %s contained in the \"%s\" archive to \"%s\".", e.SourceStats, e.SourcePath, e.DestinationPath))
+	if e.Streaming {
+		builder.WriteStandard(fmt.Sprintf("Starting extraction of the \"%s\" archive to \"%s\" while it downloads.", e.SourcePath, e.DestinationPath))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Starting extraction of %s contained in the \"%s\" archive to \"%s\".", e.SourceStats, e.SourcePath, e.DestinationPath))
+	}
 
 	return builder.String()
 }
@@ -93,6 +98,7 @@ func (e ExtractionStarted) Attrs() []slog.Attr {
 		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
 		slog.Group("source", "path", e.SourcePath, slog.Group("stats", "files", e.SourceStats.Files, "directories", e.SourceStats.Directories, "total-bytes", e.SourceStats.TotalBytes)),
 		slog.Group("destination", "path", e.DestinationPath),
+		slog.Bool("streaming", e.Streaming),
 	}
 }
 
@@ -109,6 +115,7 @@ type ExtractionStopped struct {
 	DestinationStats ExtractionStats
 	Started          time.Time
 	Stopped          time.Time
+	Streaming        bool
 	Err              error
 }
 
@@ -120,6 +127,11 @@ func (e ExtractionStopped) Component() string {
 // Level returns the level of the event.
 func (e ExtractionStopped) Level() slog.Level {
 	if e.Err != nil {
+		// A streaming extraction that fails is followed by a regular
+		// extraction, so it isn't fatal.
+		if e.Streaming {
+			return slog.LevelWarn
+		}
 		return slog.LevelError
 	}
 	return slog.LevelInfo
@@ -135,7 +147,9 @@ func (e ExtractionStopped) Message() string {
 	builder.WritePrimary(string(e.Flow))
 	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
 	builder.WritePrimary("extract-package")
-	if e.Err != nil {
+	if e.Err != nil && e.Streaming {
+		builder.WriteStandard(fmt.Sprintf("The extraction of \"%s\" to \"%s\" while it downloads was abandoned after %s, and will be performed after the download instead: %s.", e.SourcePath, e.DestinationPath, e.DestinationStats, e.Err))
+	} else if e.Err != nil {
 		if e.DestinationStats.Files > 0 || e.DestinationStats.Directories > 0 {
 			builder.WriteStandard(fmt.Sprintf("The extraction of %s from \"%s\" to \"%s\" failed after %s (%s mbps): %s.", e.SourceStats, e.SourcePath, e.DestinationPath, duration, e.BitrateInMbps(), e.Err))
 		} else {
@@ -165,6 +179,7 @@ func (e ExtractionStopped) Attrs() []slog.Attr {
 		slog.Group("destination", "path", e.DestinationPath, slog.Group("stats", "files", e.SourceStats.Files, "directories", e.SourceStats.Directories, "total-bytes", e.SourceStats.TotalBytes)),
 		slog.Time("started", e.Started),
 		slog.Time("stopped", e.Stopped),
+		slog.Bool("streaming", e.Streaming),
 	}
 	if e.Err != nil {
 		attrs = append(attrs, slog.String("error", e.Err.Error()))
@@ -191,6 +206,7 @@ type ExtractedFile struct {
 	FileNumber int
 	Path       string
 	FileSize   int64
+	Lockers    []lbdeploy.FileLocker
 	Started    time.Time
 	Stopped    time.Time
 	Err        error
@@ -222,7 +238,7 @@ func (e ExtractedFile) Message() string {
 // multiple lines of text. An empty string is returned when no details
 // are available.
 func (e ExtractedFile) Details() string {
-	return ""
+	return fileLockerDetails(e.Lockers)
 }
 
 // Attrs returns a set of structured log attributes for the event.
@@ -237,6 +253,9 @@ func (e ExtractedFile) Attrs() []slog.Attr {
 		slog.Time("started", e.Started),
 		slog.Time("stopped", e.Stopped),
 	}
+	if len(e.Lockers) > 0 {
+		attrs = append(attrs, fileLockerAttr(e.Lockers))
+	}
 	if e.Err != nil {
 		attrs = append(attrs, slog.String("error", e.Err.Error()))
 	}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 )
 
@@ -103,6 +104,9 @@ type FileCopy struct {
 	DestinationPath    string
 	DestinationExisted bool
 	FileSize           int64
+	Overwritten        bool
+	Backup             string
+	Lockers            []lbdeploy.FileLocker
 	Started            time.Time
 	Stopped            time.Time
 	Err                error
@@ -147,9 +151,14 @@ func (e FileCopy) Message() string {
 		builder.WriteStandard(fmt.Sprintf("The file copy from %s to %s failed due to an error: %s.", from, to, e.Err))
 	} else if !e.DestinationExisted {
 		builder.WriteStandard(fmt.Sprintf("The file copy from %s to %s was completed in %s (%s mbps).", from, to, duration, e.BitrateInMbps()))
+	} else if e.Overwritten {
+		builder.WriteStandard(fmt.Sprintf("The file copy from %s to %s replaced the existing file in %s (%s mbps).", from, to, duration, e.BitrateInMbps()))
 	} else {
 		builder.WriteStandard(fmt.Sprintf("The file copy from %s to %s was unnecessary as the file already exists in the destination.", from, to))
 	}
+	if e.Backup != "" {
+		builder.WriteNote(e.Backup, fieldformat.Label("backup"))
+	}
 
 	return builder.String()
 }
@@ -158,7 +167,7 @@ func (e FileCopy) Message() string {
 // multiple lines of text. An empty string is returned when no details
 // are available.
 func (e FileCopy) Details() string {
-	return ""
+	return fileLockerDetails(e.Lockers)
 }
 
 // Attrs returns a set of structured log attributes for the event.
@@ -168,11 +177,17 @@ func (e FileCopy) Attrs() []slog.Attr {
 		slog.String("flow", string(e.Flow)),
 		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
 		slog.Group("source", "path", e.SourcePath),
-		slog.Group("destination", "path", e.DestinationPath, "existed", e.DestinationExisted),
+		slog.Group("destination", "path", e.DestinationPath, "existed", e.DestinationExisted, "overwritten", e.Overwritten),
 		slog.Group("file", "size", e.FileSize),
 		slog.Time("started", e.Started),
 		slog.Time("stopped", e.Stopped),
 	}
+	if e.Backup != "" {
+		attrs = append(attrs, slog.String("backup", e.Backup))
+	}
+	if len(e.Lockers) > 0 {
+		attrs = append(attrs, fileLockerAttr(e.Lockers))
+	}
 	if e.Err != nil {
 		attrs = append(attrs, slog.String("error", e.Err.Error()))
 	}
@@ -199,6 +214,7 @@ type FileDelete struct {
 	FilePath    string
 	FileSize    int64
 	FileExisted bool
+	Lockers     []lbdeploy.FileLocker
 	Started     time.Time
 	Stopped     time.Time
 	Err         error
@@ -249,7 +265,7 @@ func (e FileDelete) Message() string {
 // multiple lines of text. An empty string is returned when no details
 // are available.
 func (e FileDelete) Details() string {
-	return ""
+	return fileLockerDetails(e.Lockers)
 }
 
 // Attrs returns a set of structured log attributes for the event.
@@ -262,6 +278,9 @@ func (e FileDelete) Attrs() []slog.Attr {
 		slog.Time("started", e.Started),
 		slog.Time("stopped", e.Stopped),
 	}
+	if len(e.Lockers) > 0 {
+		attrs = append(attrs, fileLockerAttr(e.Lockers))
+	}
 	if e.Err != nil {
 		attrs = append(attrs, slog.String("error", e.Err.Error()))
 	}
@@ -277,3 +296,160 @@ func (e FileDelete) Duration() time.Duration {
 func (e FileDelete) BitrateInMbps() string {
 	return bitrate(e.FileSize, e.Duration())
 }
+
+// FileLockersClosed is an event that occurs when the processes holding
+// files open are closed so that a file operation can be tried again.
+type FileLockersClosed struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Paths       []string
+	Lockers     []lbdeploy.FileLocker
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e FileLockersClosed) Component() string {
+	return "file"
+}
+
+// Level returns the level of the event.
+func (e FileLockersClosed) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FileLockersClosed) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	files := strings.Join(e.Paths, ", ")
+	if e.Err != nil {
+		builder.WriteStandard(fmt.Sprintf("Failed to close the %d %s holding %s open: %s.", len(e.Lockers), plural(len(e.Lockers), "process", "processes"), files, e.Err))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Closed the %d %s holding %s open. Trying again.", len(e.Lockers), plural(len(e.Lockers), "process", "processes"), files))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FileLockersClosed) Details() string {
+	return fileLockerDetails(e.Lockers)
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FileLockersClosed) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Any("paths", e.Paths),
+		fileLockerAttr(e.Lockers),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// fileLockerDetails returns a description of the processes holding a file
+// open, one per line.
+func fileLockerDetails(lockers []lbdeploy.FileLocker) string {
+	if len(lockers) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	out.WriteString("Held open by:")
+	for _, locker := range lockers {
+		out.WriteString("\n  ")
+		out.WriteString(locker.String())
+	}
+	return out.String()
+}
+
+// fileLockerAttr returns a structured log attribute that describes the
+// processes holding a file open.
+func fileLockerAttr(lockers []lbdeploy.FileLocker) slog.Attr {
+	values := make([]any, 0, len(lockers))
+	for i, locker := range lockers {
+		values = append(values, slog.Group(strconv.Itoa(i),
+			"pid", locker.PID,
+			"name", locker.Name,
+			"service", locker.Service,
+			"user", locker.User))
+	}
+	return slog.Group("lockers", values...)
+}
+
+// FileOperationRetry is an event that occurs when a file operation fails
+// for a reason that is likely to be temporary, and will be tried again
+// after a delay.
+type FileOperationRetry struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Paths       []string
+	Attempt     int
+	Delay       time.Duration
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e FileOperationRetry) Component() string {
+	return "file"
+}
+
+// Level returns the level of the event.
+func (e FileOperationRetry) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FileOperationRetry) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	files := strings.Join(e.Paths, ", ")
+	builder.WriteStandard(fmt.Sprintf("Attempt %d to access %s failed: %s. Trying again in %s.", e.Attempt, files, e.Err, e.Delay))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FileOperationRetry) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FileOperationRetry) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Any("paths", e.Paths),
+		slog.Int("attempt", e.Attempt),
+		slog.Duration("delay", e.Delay),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
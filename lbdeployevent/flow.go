@@ -3,6 +3,7 @@ package lbdeployevent
 import (
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/gentlemanautomaton/structformat"
@@ -13,8 +14,9 @@ import (
 
 // FlowStarted is an event that occurs when a deployment flow has started.
 type FlowStarted struct {
-	Deployment lbdeploy.DeploymentID
-	Flow       lbdeploy.FlowID
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	Description string
 }
 
 // Component identifies the component that generated the event.
@@ -33,6 +35,9 @@ func (e FlowStarted) Message() string {
 
 	builder.WritePrimary(string(e.Deployment))
 	builder.WritePrimary(string(e.Flow))
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
 	builder.WriteStandard(fmt.Sprintf("Starting."))
 
 	return builder.String()
@@ -47,20 +52,25 @@ func (e FlowStarted) Details() string {
 
 // Attrs returns a set of structured log attributes for the event.
 func (e FlowStarted) Attrs() []slog.Attr {
-	return []slog.Attr{
+	attrs := []slog.Attr{
 		slog.String("deployment", string(e.Deployment)),
 		slog.String("flow", string(e.Flow)),
 	}
+	if e.Description != "" {
+		attrs = append(attrs, slog.String("description", e.Description))
+	}
+	return attrs
 }
 
 // FlowStopped is an event that occurs when a deployment flow has stopped.
 type FlowStopped struct {
-	Deployment lbdeploy.DeploymentID
-	Flow       lbdeploy.FlowID
-	Stats      lbdeploy.FlowStats
-	Started    time.Time
-	Stopped    time.Time
-	Err        error
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	Description string
+	Stats       lbdeploy.FlowStats
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
 }
 
 // Component identifies the component that generated the event.
@@ -82,6 +92,9 @@ func (e FlowStopped) Message() string {
 
 	builder.WritePrimary(string(e.Deployment))
 	builder.WritePrimary(string(e.Flow))
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
 
 	var (
 		completed = fmt.Sprintf("%d %s", e.Stats.ActionsCompleted, plural(e.Stats.ActionsCompleted, "action", "actions"))
@@ -126,6 +139,9 @@ func (e FlowStopped) Attrs() []slog.Attr {
 		slog.Time("stopped", e.Stopped),
 		slog.Group("actions", "completed", e.Stats.ActionsCompleted, "failed", e.Stats.ActionsFailed),
 	}
+	if e.Description != "" {
+		attrs = append(attrs, slog.String("description", e.Description))
+	}
 	if e.Err != nil {
 		attrs = append(attrs, slog.String("error", e.Err.Error()))
 	}
@@ -202,6 +218,54 @@ func (e FlowCondition) Attrs() []slog.Attr {
 	return attrs
 }
 
+// FlowLockWaiting is an event that occurs when a deployment flow is waiting
+// for a lock that is held by another process.
+type FlowLockWaiting struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Lock       lbdeploy.LockID
+	Timeout    time.Duration
+}
+
+// Component identifies the component that generated the event.
+func (e FlowLockWaiting) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowLockWaiting) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e FlowLockWaiting) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WriteStandard(fmt.Sprintf("Waiting for the %s lock, which is held by another process.", e.Lock))
+	builder.WriteNote(fmt.Sprintf("timeout %s", e.Timeout))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowLockWaiting) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowLockWaiting) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.String("lock", string(e.Lock)),
+		slog.Duration("timeout", e.Timeout),
+	}
+}
+
 // FlowLockNotAcquired is an event that occurs when a deployment flow cannot
 // be started because one of its locks could not be acquired.
 type FlowLockNotAcquired struct {
@@ -301,3 +365,372 @@ func (e FlowAlreadyRunning) Attrs() []slog.Attr {
 		slog.String("flow", string(e.Flow)),
 	}
 }
+
+// FlowRollbackStarted is an event that occurs when a deployment flow starts
+// rolling back the actions it has completed.
+type FlowRollbackStarted struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Actions    int
+}
+
+// Component identifies the component that generated the event.
+func (e FlowRollbackStarted) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowRollbackStarted) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FlowRollbackStarted) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WriteStandard(fmt.Sprintf("Rolling back with %d %s.", e.Actions, plural(e.Actions, "action", "actions")))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowRollbackStarted) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowRollbackStarted) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Int("actions", e.Actions),
+	}
+}
+
+// FlowRollbackStopped is an event that occurs when a deployment flow has
+// finished rolling back the actions it completed.
+type FlowRollbackStopped struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Stats      lbdeploy.FlowStats
+	Err        error
+}
+
+// Component identifies the component that generated the event.
+func (e FlowRollbackStopped) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowRollbackStopped) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FlowRollbackStopped) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+
+	if e.Stats.ActionsFailed > 0 {
+		builder.WriteStandard(fmt.Sprintf("Rollback finished with %d of %d %s failing.",
+			e.Stats.ActionsFailed, e.Stats.ActionsCompleted+e.Stats.ActionsFailed,
+			plural(e.Stats.ActionsCompleted+e.Stats.ActionsFailed, "action", "actions")))
+	} else {
+		builder.WriteStandard("Rollback completed.")
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowRollbackStopped) Details() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowRollbackStopped) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("actions", "completed", e.Stats.ActionsCompleted, "failed", e.Stats.ActionsFailed),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// FlowResumed is an event that occurs when a deployment flow resumes from
+// a checkpoint recorded by a previous invocation.
+type FlowResumed struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Skipped    int
+}
+
+// Component identifies the component that generated the event.
+func (e FlowResumed) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowResumed) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e FlowResumed) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WriteStandard(fmt.Sprintf("Resuming after skipping %d %s completed by a previous invocation.", e.Skipped, plural(e.Skipped, "action", "actions")))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowResumed) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowResumed) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Int("skipped", e.Skipped),
+	}
+}
+
+// FlowOverdue is an event that occurs when a deployment flow has been
+// running for longer than its expected duration.
+type FlowOverdue struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	Description string
+	Expected    time.Duration
+}
+
+// Component identifies the component that generated the event.
+func (e FlowOverdue) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowOverdue) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FlowOverdue) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	if e.Description != "" {
+		builder.WritePrimary(e.Description)
+	}
+	builder.WriteStandard(fmt.Sprintf("The flow is still running after its expected duration of %s.", e.Expected))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowOverdue) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowOverdue) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Duration("expected", e.Expected),
+	}
+	if e.Description != "" {
+		attrs = append(attrs, slog.String("description", e.Description))
+	}
+	return attrs
+}
+
+// FlowTimedOut is an event that occurs when a deployment flow is stopped
+// because it did not finish within its timeout.
+type FlowTimedOut struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Timeout    time.Duration
+	Started    time.Time
+	Stopped    time.Time
+}
+
+// Component identifies the component that generated the event.
+func (e FlowTimedOut) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowTimedOut) Level() slog.Level {
+	return slog.LevelError
+}
+
+// Message returns a description of the event.
+func (e FlowTimedOut) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WriteStandard(fmt.Sprintf("The flow was stopped because it did not finish within its %s timeout.", e.Timeout))
+	builder.WriteNote(e.Stopped.Sub(e.Started).Round(time.Millisecond * 10).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowTimedOut) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowTimedOut) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Duration("timeout", e.Timeout),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+}
+
+// FlowRetrying is an event that occurs when a deployment flow has failed and
+// is about to be invoked again.
+type FlowRetrying struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Attempt    int
+	Retries    int
+	Delay      time.Duration
+	Err        error
+}
+
+// Component identifies the component that generated the event.
+func (e FlowRetrying) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e FlowRetrying) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e FlowRetrying) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	if e.Delay > 0 {
+		builder.WriteStandard(fmt.Sprintf("Attempt %d failed. Retrying in %s (%d of %d).", e.Attempt, e.Delay, e.Attempt, e.Retries))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Attempt %d failed. Retrying (%d of %d).", e.Attempt, e.Attempt, e.Retries))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e FlowRetrying) Details() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e FlowRetrying) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Int("attempt", e.Attempt),
+		slog.Int("retries", e.Retries),
+		slog.Duration("delay", e.Delay),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// MaintenanceWindowWaiting is an event that occurs when a flow pauses
+// before invoking a command because none of its maintenance windows are
+// open.
+type MaintenanceWindowWaiting struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Opens       time.Time
+}
+
+// Component identifies the component that generated the event.
+func (e MaintenanceWindowWaiting) Component() string {
+	return "flow"
+}
+
+// Level returns the level of the event.
+func (e MaintenanceWindowWaiting) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e MaintenanceWindowWaiting) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	builder.WriteStandard(fmt.Sprintf("Waiting for the maintenance window that opens at %s.", e.Opens.Format(time.RFC3339)))
+	builder.WriteNote(time.Until(e.Opens).Round(time.Second).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e MaintenanceWindowWaiting) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e MaintenanceWindowWaiting) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Time("opens", e.Opens),
+	}
+}
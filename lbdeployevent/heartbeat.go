@@ -0,0 +1,99 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// HeartbeatOperation identifies the kind of long-running operation that a
+// heartbeat is recorded for.
+type HeartbeatOperation string
+
+// Operations that record heartbeats.
+const (
+	HeartbeatCommand    HeartbeatOperation = "command"
+	HeartbeatDownload   HeartbeatOperation = "download"
+	HeartbeatExtraction HeartbeatOperation = "extraction"
+)
+
+// Heartbeat is an event that is recorded periodically while a long-running
+// operation is in progress, so that an operation that is still working can
+// be told apart from one that has stopped responding.
+//
+// Subject describes what the operation is working on, such as a command ID
+// or a file name. For downloads and extractions, Progress is the number of
+// bytes processed so far and Total is the number expected, if known.
+type Heartbeat struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Operation   HeartbeatOperation
+	Subject     string
+	Started     time.Time
+	Elapsed     time.Duration
+	Progress    int64
+	Total       int64
+}
+
+// Component identifies the component that generated the event.
+func (e Heartbeat) Component() string {
+	return string(e.Operation)
+}
+
+// Level returns the level of the event.
+func (e Heartbeat) Level() slog.Level {
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e Heartbeat) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	elapsed := e.Elapsed.Round(time.Second)
+	switch {
+	case e.Total > 0:
+		percent := float64(e.Progress) / float64(e.Total) * 100
+		builder.WriteStandard(fmt.Sprintf("The %s of %s is still running after %s (%d of %d bytes, %.1f%%).", e.Operation, e.Subject, elapsed, e.Progress, e.Total, percent))
+	case e.Progress > 0:
+		builder.WriteStandard(fmt.Sprintf("The %s of %s is still running after %s (%d bytes).", e.Operation, e.Subject, elapsed, e.Progress))
+	default:
+		builder.WriteStandard(fmt.Sprintf("The %s %s is still running after %s.", e.Subject, e.Operation, elapsed))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e Heartbeat) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e Heartbeat) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.String("operation", string(e.Operation)),
+		slog.String("subject", e.Subject),
+		slog.Time("started", e.Started),
+		slog.Duration("elapsed", e.Elapsed),
+	}
+	if e.Progress > 0 || e.Total > 0 {
+		attrs = append(attrs, slog.Int64("progress", e.Progress), slog.Int64("total", e.Total))
+	}
+	return attrs
+}
@@ -0,0 +1,99 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// LinkCreated is an event that occurs when a hard link or directory
+// junction is created.
+type LinkCreated struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	TargetID    string
+	TargetPath  string
+	LinkID      string
+	LinkPath    string
+	Existed     bool
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e LinkCreated) Component() string {
+	return "file"
+}
+
+// Level returns the level of the event.
+func (e LinkCreated) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e LinkCreated) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	kind := "hard link"
+	if e.ActionType == lbdeploy.ActionCreateJunction {
+		kind = "junction"
+	}
+
+	link, target := e.LinkID, e.TargetID
+	if e.LinkPath != "" {
+		link = fmt.Sprintf("%s (%s)", e.LinkID, e.LinkPath)
+	}
+	if e.TargetPath != "" {
+		target = fmt.Sprintf("%s (%s)", e.TargetID, e.TargetPath)
+	}
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Creation of the %s %s to %s failed due to an error: %s.", link, kind, target, e.Err))
+	case e.Existed:
+		builder.WriteStandard(fmt.Sprintf("Creation of the %s %s was unnecessary as it already refers to %s.", link, kind, target))
+	default:
+		builder.WriteStandard(fmt.Sprintf("Created the %s %s to %s.", link, kind, target))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e LinkCreated) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e LinkCreated) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("target", "id", e.TargetID, "path", e.TargetPath),
+		slog.Group("link", "id", e.LinkID, "path", e.LinkPath, "existed", e.Existed),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
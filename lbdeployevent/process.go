@@ -0,0 +1,187 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// ProcessesStopped is an event that occurs when a stop-processes action has
+// finished stopping running processes.
+type ProcessesStopped struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Process     lbdeploy.ProcessResourceID
+	Stopped     int
+	Started     time.Time
+	Finished    time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e ProcessesStopped) Component() string {
+	return "process"
+}
+
+// Level returns the level of the event.
+func (e ProcessesStopped) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e ProcessesStopped) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	switch {
+	case e.Err != nil && e.Stopped > 0:
+		builder.WriteStandard(fmt.Sprintf("Stopped %d \"%s\" process(es) before encountering an error: %s.", e.Stopped, e.Process, e.Err))
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Stopping \"%s\" processes failed due to an error: %s.", e.Process, e.Err))
+	case e.Stopped > 0:
+		builder.WriteStandard(fmt.Sprintf("Stopped %d \"%s\" process(es).", e.Stopped, e.Process))
+	default:
+		builder.WriteStandard(fmt.Sprintf("No \"%s\" processes were running.", e.Process))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e ProcessesStopped) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e ProcessesStopped) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("process", "id", e.Process, "stopped", e.Stopped),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Finished),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// ProcessConflict is an event that occurs when a process that belongs to an
+// application was running when the application was about to be installed
+// or uninstalled, and the process's conflict policy has been applied.
+//
+// Response holds the user's response when the policy prompted the user.
+// Stopped is the number of processes that were terminated.
+type ProcessConflict struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Package     lbdeploy.PackageID
+	Command     lbdeploy.CommandID
+	Process     lbdeploy.ProcessResourceID
+	Action      lbdeploy.ProcessConflictAction
+	Running     int
+	Response    string
+	Stopped     int
+	Started     time.Time
+	Finished    time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e ProcessConflict) Component() string {
+	return "process"
+}
+
+// Level returns the level of the event.
+func (e ProcessConflict) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e ProcessConflict) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	if e.Package == "" {
+		builder.WritePrimary(string(e.Command))
+	} else {
+		builder.WritePrimary(fmt.Sprintf("%s.%s", e.Package, e.Command))
+	}
+
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("%d \"%s\" process(es) conflicted with the command: %s.", e.Running, e.Process, e.Err))
+	case e.Stopped > 0:
+		builder.WriteStandard(fmt.Sprintf("Closed %d conflicting \"%s\" process(es).", e.Stopped, e.Process))
+	default:
+		builder.WriteStandard(fmt.Sprintf("The conflicting \"%s\" process(es) exited.", e.Process))
+	}
+
+	builder.WriteNote(string(e.Action), fieldformat.Label("policy"))
+	if e.Response != "" {
+		builder.WriteNote(e.Response, fieldformat.Label("user"))
+	}
+	builder.WriteNote(e.Duration().Round(time.Millisecond).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e ProcessConflict) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e ProcessConflict) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+	}
+	if e.Package != "" {
+		attrs = append(attrs, slog.String("package", string(e.Package)))
+	}
+	attrs = append(attrs,
+		slog.Group("command", "id", e.Command),
+		slog.Group("process", "id", e.Process, "running", e.Running, "stopped", e.Stopped),
+		slog.Group("conflict", "action", e.Action, "response", e.Response),
+		slog.Time("started", e.Started),
+		slog.Time("finished", e.Finished))
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// Duration returns the amount of time it took to resolve the conflict.
+func (e ProcessConflict) Duration() time.Duration {
+	return e.Finished.Sub(e.Started)
+}
@@ -0,0 +1,176 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// RebootRequired is an event that occurs when a command indicates that a
+// restart is required to complete its changes. It also occurs for actions
+// that require a restart without invoking a command, such as driver
+// installations, in which case Command is empty.
+//
+// Initiated is true if the command has already initiated the restart on
+// its own, in which case the reboot mode does not apply.
+type RebootRequired struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Package     lbdeploy.PackageID
+	Command     lbdeploy.CommandID
+	Reasons     []string
+	Mode        lbdeploy.RebootMode
+	Initiated   bool
+}
+
+// Component identifies the component that generated the event.
+func (e RebootRequired) Component() string {
+	return "reboot"
+}
+
+// Level returns the level of the event.
+func (e RebootRequired) Level() slog.Level {
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e RebootRequired) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+	switch {
+	case e.Command == "":
+		if e.Package != "" {
+			builder.WritePrimary(string(e.Package))
+		}
+	case e.Package == "":
+		builder.WritePrimary(string(e.Command))
+	default:
+		builder.WritePrimary(fmt.Sprintf("%s.%s", e.Package, e.Command))
+	}
+	subject := "command"
+	if e.Command == "" {
+		subject = "action"
+	}
+	if e.Initiated {
+		builder.WriteStandard(fmt.Sprintf("The %s has initiated a restart to complete its changes", subject))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("A restart is required to complete the %s's changes", subject))
+	}
+	for _, reason := range e.Reasons {
+		builder.WriteNote(reason)
+	}
+	if !e.Initiated {
+		builder.WriteNote(string(e.rebootMode()), fieldformat.Label("reboot mode"))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e RebootRequired) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e RebootRequired) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+	}
+	if e.Package != "" {
+		attrs = append(attrs, slog.String("package", string(e.Package)))
+	}
+	if e.Command != "" {
+		attrs = append(attrs, slog.Group("command", "id", e.Command))
+	}
+	attrs = append(attrs,
+		slog.Group("reboot", "reasons", e.Reasons, "mode", e.rebootMode(), "initiated", e.Initiated))
+	return attrs
+}
+
+func (e RebootRequired) rebootMode() lbdeploy.RebootMode {
+	if e.Mode == "" {
+		return lbdeploy.RebootSuppress
+	}
+	return e.Mode
+}
+
+// RestartInitiated is an event that occurs when a deployment initiates a
+// restart of the system in accordance with its reboot policy.
+type RestartInitiated struct {
+	Deployment lbdeploy.DeploymentID
+	Flow       lbdeploy.FlowID
+	Mode       lbdeploy.RebootMode
+	Delay      time.Duration
+	Err        error
+}
+
+// Component identifies the component that generated the event.
+func (e RestartInitiated) Component() string {
+	return "reboot"
+}
+
+// Level returns the level of the event.
+func (e RestartInitiated) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// Message returns a description of the event.
+func (e RestartInitiated) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	if e.Flow != "" {
+		builder.WritePrimary(string(e.Flow))
+	}
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Failed to initiate a restart of the system: %s.", e.Err))
+	case e.Delay > 0:
+		builder.WriteStandard(fmt.Sprintf("A restart of the system has been scheduled in %s.", e.Delay))
+	default:
+		builder.WriteStandard("A restart of the system has been initiated.")
+	}
+	builder.WriteNote(string(e.Mode), fieldformat.Label("reboot mode"))
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e RestartInitiated) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e RestartInitiated) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+	}
+	if e.Flow != "" {
+		attrs = append(attrs, slog.String("flow", string(e.Flow)))
+	}
+	attrs = append(attrs, slog.Group("reboot", "mode", e.Mode, "delay", e.Delay))
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
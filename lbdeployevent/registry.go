@@ -0,0 +1,207 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+)
+
+// RegistryValueChanged is an event that occurs when a registry value is
+// written or deleted.
+//
+// Backup is the path of the file that the value's key was exported to
+// before it was changed. It is empty if the key did not exist.
+type RegistryValueChanged struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Value       lbdeploy.RegistryValueResourceID
+	KeyPath     string
+	Name        string
+	Data        string
+	Deleted     bool
+	Existed     bool
+	Backup      string
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e RegistryValueChanged) Component() string {
+	return "registry"
+}
+
+// Level returns the level of the event.
+func (e RegistryValueChanged) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e RegistryValueChanged) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	target := string(e.Value)
+	if e.KeyPath != "" {
+		target = fmt.Sprintf("%s (%s\\%s)", e.Value, e.KeyPath, e.Name)
+	}
+	switch {
+	case e.Err != nil && e.Deleted:
+		builder.WriteStandard(fmt.Sprintf("Deletion of the %s registry value failed due to an error: %s.", target, e.Err))
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Writing the %s registry value failed due to an error: %s.", target, e.Err))
+	case e.Deleted && !e.Existed:
+		builder.WriteStandard(fmt.Sprintf("Deletion of the %s registry value was unnecessary as it did not exist.", target))
+	case e.Deleted:
+		builder.WriteStandard(fmt.Sprintf("Deleted the %s registry value.", target))
+	default:
+		builder.WriteStandard(fmt.Sprintf("Set the %s registry value to \"%s\".", target, e.Data))
+	}
+	if e.Backup != "" {
+		builder.WriteNote(e.Backup, fieldformat.Label("backup"))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e RegistryValueChanged) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e RegistryValueChanged) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("registry-value", "id", e.Value, "key", e.KeyPath, "name", e.Name),
+	}
+	if e.Deleted {
+		attrs = append(attrs, slog.Bool("existed", e.Existed))
+	} else {
+		attrs = append(attrs, slog.String("data", e.Data))
+	}
+	if e.Backup != "" {
+		attrs = append(attrs, slog.String("backup", e.Backup))
+	}
+	attrs = append(attrs,
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	)
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// RegistryValueWait is an event that occurs when a wait for a registry
+// value to appear or to reach a target value has finished.
+//
+// If Target has an unknown kind, the wait was for the value to appear.
+type RegistryValueWait struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Value       lbdeploy.RegistryValueResourceID
+	KeyPath     string
+	Name        string
+	Comparison  lbvalue.Comparison
+	Target      lbvalue.Value
+	Data        string
+	Satisfied   bool
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e RegistryValueWait) Component() string {
+	return "registry"
+}
+
+// Level returns the level of the event.
+func (e RegistryValueWait) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e RegistryValueWait) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	target := string(e.Value)
+	if e.KeyPath != "" {
+		target = fmt.Sprintf("%s (%s\\%s)", e.Value, e.KeyPath, e.Name)
+	}
+	condition := "to appear"
+	if e.Target.Kind() != lbvalue.KindUnknown {
+		condition = fmt.Sprintf("to be %s \"%s\"", e.Comparison, e.Target)
+	}
+	switch {
+	case e.Err != nil:
+		builder.WriteStandard(fmt.Sprintf("Waiting for the %s registry value %s failed due to an error: %s.", target, condition, e.Err))
+	default:
+		builder.WriteStandard(fmt.Sprintf("The %s registry value has the value \"%s\" after waiting %s for it %s.", target, e.Data, e.Stopped.Sub(e.Started).Round(time.Millisecond), condition))
+	}
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e RegistryValueWait) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e RegistryValueWait) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("registry-value", "id", e.Value, "key", e.KeyPath, "name", e.Name),
+	}
+	if e.Target.Kind() != lbvalue.KindUnknown {
+		attrs = append(attrs,
+			slog.String("comparison", e.Comparison.String()),
+			slog.String("target", e.Target.String()),
+		)
+	}
+	attrs = append(attrs,
+		slog.String("data", e.Data),
+		slog.Bool("satisfied", e.Satisfied),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	)
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
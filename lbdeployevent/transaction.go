@@ -0,0 +1,97 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// TransactionStopped is an event that occurs when a transaction action has
+// either committed or rolled back its changes.
+type TransactionStopped struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Changes     int
+	Committed   bool
+	Started     time.Time
+	Stopped     time.Time
+	Err         error // The error that caused the transaction to roll back
+	RollbackErr error // An error encountered while rolling back
+}
+
+// Component identifies the component that generated the event.
+func (e TransactionStopped) Component() string {
+	return "transaction"
+}
+
+// Level returns the level of the event.
+func (e TransactionStopped) Level() slog.Level {
+	switch {
+	case e.RollbackErr != nil:
+		return slog.LevelError
+	case e.Err != nil:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Message returns a description of the event.
+func (e TransactionStopped) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	changes := fmt.Sprintf("%d %s", e.Changes, plural(e.Changes, "change", "changes"))
+	switch {
+	case e.Committed:
+		builder.WriteStandard(fmt.Sprintf("Committed %s.", changes))
+	case e.RollbackErr != nil:
+		builder.WriteStandard(fmt.Sprintf("Failed to roll back %s: %s.", changes, e.RollbackErr))
+	default:
+		builder.WriteStandard(fmt.Sprintf("Rolled back %s.", changes))
+	}
+
+	builder.WriteNote(e.Stopped.Sub(e.Started).Round(time.Millisecond * 10).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e TransactionStopped) Details() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e TransactionStopped) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Int("changes", e.Changes),
+		slog.Bool("committed", e.Committed),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	if e.RollbackErr != nil {
+		attrs = append(attrs, slog.String("rollback-error", e.RollbackErr.Error()))
+	}
+	return attrs
+}
@@ -0,0 +1,97 @@
+package lbdeployevent
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/structformat"
+	"github.com/gentlemanautomaton/structformat/fieldformat"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// UserSettingsApplied is an event that occurs when an apply-user-settings
+// action has applied its settings to a user profile.
+//
+// Profile is the security identifier of the profile's user, or "default"
+// for the default profile.
+type UserSettingsApplied struct {
+	Deployment  lbdeploy.DeploymentID
+	Flow        lbdeploy.FlowID
+	ActionIndex int
+	ActionType  lbdeploy.ActionType
+	Profile     string
+	ProfilePath string
+	Values      int
+	Files       int
+	Started     time.Time
+	Stopped     time.Time
+	Err         error
+}
+
+// Component identifies the component that generated the event.
+func (e UserSettingsApplied) Component() string {
+	return "user-settings"
+}
+
+// Level returns the level of the event.
+func (e UserSettingsApplied) Level() slog.Level {
+	if e.Err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Message returns a description of the event.
+func (e UserSettingsApplied) Message() string {
+	var builder structformat.Builder
+
+	builder.WritePrimary(string(e.Deployment))
+	builder.WritePrimary(string(e.Flow))
+	builder.WritePrimary(strconv.Itoa(e.ActionIndex + 1))
+	builder.WritePrimary(string(e.ActionType))
+
+	if e.Err != nil {
+		builder.WriteStandard(fmt.Sprintf("Applying user settings to the \"%s\" profile failed due to an error: %s.", e.ProfilePath, e.Err))
+	} else {
+		builder.WriteStandard(fmt.Sprintf("Applied %d registry %s and %d %s to the \"%s\" profile.",
+			e.Values, plural(e.Values, "value", "values"),
+			e.Files, plural(e.Files, "file", "files"),
+			e.ProfilePath))
+	}
+
+	builder.WriteNote(e.Profile, fieldformat.Label("profile"))
+	builder.WriteNote(e.Duration().Round(time.Millisecond).String())
+
+	return builder.String()
+}
+
+// Details returns additional details about the event. It might include
+// multiple lines of text. An empty string is returned when no details
+// are available.
+func (e UserSettingsApplied) Details() string {
+	return ""
+}
+
+// Attrs returns a set of structured log attributes for the event.
+func (e UserSettingsApplied) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("deployment", string(e.Deployment)),
+		slog.String("flow", string(e.Flow)),
+		slog.Group("action", "index", e.ActionIndex, "type", e.ActionType),
+		slog.Group("profile", "id", e.Profile, "path", e.ProfilePath),
+		slog.Group("applied", "values", e.Values, "files", e.Files),
+		slog.Time("started", e.Started),
+		slog.Time("stopped", e.Stopped),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return attrs
+}
+
+// Duration returns the amount of time it took to apply the settings.
+func (e UserSettingsApplied) Duration() time.Duration {
+	return e.Stopped.Sub(e.Started)
+}
@@ -2,6 +2,7 @@ package lbengine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -22,8 +23,9 @@ type actionEngine struct {
 	flow       flowData
 	action     actionData
 	events     lbevent.Recorder
-	force      bool
+	force      ForceScope
 	state      *engineState
+	tx         *transaction // The transaction the action belongs to, if any
 }
 
 func (engine *actionEngine) Invoke(ctx context.Context) error {
@@ -33,11 +35,24 @@ func (engine *actionEngine) Invoke(ctx context.Context) error {
 		Flow:        engine.flow.ID,
 		ActionIndex: engine.action.Index,
 		ActionType:  engine.action.Definition.Type,
+		ActionID:    engine.action.Definition.ID,
+		Description: engine.action.Definition.Description,
 	})
 
 	// Record the time that the action started.
 	started := time.Now()
 
+	// Warn if the action runs for longer than expected.
+	stopOverdue := startOverdueTimer(engine.events, time.Duration(engine.action.Definition.ExpectedDuration), lbdeployevent.ActionOverdue{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		ActionID:    engine.action.Definition.ID,
+		Description: engine.action.Definition.Description,
+		Expected:    time.Duration(engine.action.Definition.ExpectedDuration),
+	})
+
 	// Execute the action.
 	err := func() error {
 		switch engine.action.Definition.Type {
@@ -61,12 +76,54 @@ func (engine *actionEngine) Invoke(ctx context.Context) error {
 			if err := engine.deleteFile(ctx); err != nil {
 				return err
 			}
+		case lbdeploy.ActionTransaction:
+			if err := engine.transaction(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionStopProcesses:
+			if err := engine.stopProcesses(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionApplyUserSettings:
+			if err := engine.applyUserSettings(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionPromptDeferral:
+			if err := engine.promptDeferral(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionSetRegistryValue:
+			if err := engine.setRegistryValue(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionDeleteRegistryValue:
+			if err := engine.deleteRegistryValue(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionWaitRegistryValue:
+			if err := engine.waitForRegistryValue(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionCreateHardLink:
+			if err := engine.createHardLink(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionCreateJunction:
+			if err := engine.createJunction(ctx); err != nil {
+				return err
+			}
+		case lbdeploy.ActionInstallDriver:
+			if err := engine.installDriver(ctx); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("unrecognized deployment action type \"%s\"", engine.action.Definition.Type)
 		}
 		return nil
 	}()
 
+	stopOverdue()
+
 	// Record the time that the action stopped.
 	stopped := time.Now()
 
@@ -76,6 +133,8 @@ func (engine *actionEngine) Invoke(ctx context.Context) error {
 		Flow:        engine.flow.ID,
 		ActionIndex: engine.action.Index,
 		ActionType:  engine.action.Definition.Type,
+		ActionID:    engine.action.Definition.ID,
+		Description: engine.action.Definition.Description,
 		Started:     started,
 		Stopped:     stopped,
 		Err:         err,
@@ -176,7 +235,7 @@ func (engine *actionEngine) invokeCommand(ctx context.Context) error {
 	}
 
 	// Determine whether any app changes are anticipated.
-	ae := NewAppEngine(engine.deployment)
+	ae := newCachedAppEngine(engine.deployment, engine.state.apps)
 	appEvaluation, err := ae.EvaluateAppChanges(command.Definition.Installs, command.Definition.Uninstalls)
 	if err != nil {
 		return fmt.Errorf("the evaluation of potential application changes did not succeed: %w", err)
@@ -189,7 +248,7 @@ func (engine *actionEngine) invokeCommand(ctx context.Context) error {
 		if !appEvaluation.ActionsNeeded() {
 			// If all app installs and uninstalls are already in effect,
 			// and command invocation isn't forced, skip this command.
-			if !engine.force && !engine.action.Definition.Force {
+			if !engine.force.Command(engine.flow.ID, engine.action) {
 				// Record that this command is being skipped.
 				engine.events.Record(lbdeployevent.CommandSkipped{
 					Deployment:  engine.deployment.ID,
@@ -236,6 +295,7 @@ func (engine *actionEngine) copyFile(ctx context.Context) error {
 		action:     engine.action,
 		events:     engine.events,
 		state:      engine.state,
+		tx:         engine.tx,
 	}
 
 	// Execute the copy-file action via the file engine.
@@ -251,8 +311,238 @@ func (engine *actionEngine) deleteFile(ctx context.Context) error {
 		action:     engine.action,
 		events:     engine.events,
 		state:      engine.state,
+		tx:         engine.tx,
 	}
 
 	// Execute the delete-file action via the file engine.
 	return fe.DeleteFile(ctx)
 }
+
+// setRegistryValue performs a registry value write operation.
+func (engine *actionEngine) setRegistryValue(ctx context.Context) error {
+	// Prepare a registry engine.
+	re := registryEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+		tx:         engine.tx,
+	}
+
+	// Execute the set-registry-value action via the registry engine.
+	return re.SetValue(ctx)
+}
+
+// deleteRegistryValue performs a registry value delete operation.
+func (engine *actionEngine) deleteRegistryValue(ctx context.Context) error {
+	// Prepare a registry engine.
+	re := registryEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+		tx:         engine.tx,
+	}
+
+	// Execute the delete-registry-value action via the registry engine.
+	return re.DeleteValue(ctx)
+}
+
+// waitForRegistryValue waits for a registry value to appear or to reach a
+// target value.
+func (engine *actionEngine) waitForRegistryValue(ctx context.Context) error {
+	// Prepare a registry engine.
+	re := registryEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+	}
+
+	// Execute the wait-for-registry-value action via the registry engine.
+	return re.WaitForValue(ctx)
+}
+
+// stopProcesses terminates any running processes that match the process
+// resources of the action.
+func (engine *actionEngine) stopProcesses(ctx context.Context) error {
+	for _, id := range engine.action.Definition.Processes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Find the process resource within the deployment.
+		process, found := engine.deployment.Resources.Processes[id]
+		if !found {
+			return fmt.Errorf("the \"%s\" process does not exist within the \"%s\" deployment", id, engine.deployment.ID)
+		}
+
+		// Stop any matching processes.
+		started := time.Now()
+		stopped, err := StopProcesses(process.Match)
+
+		// Record the outcome.
+		engine.events.Record(lbdeployevent.ProcessesStopped{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Process:     id,
+			Stopped:     stopped,
+			Started:     started,
+			Finished:    time.Now(),
+			Err:         err,
+		})
+
+		if err != nil {
+			return fmt.Errorf("the \"%s\" processes could not be stopped: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// createHardLink creates a hard link to a file.
+func (engine *actionEngine) createHardLink(ctx context.Context) error {
+	// Prepare a file engine.
+	fe := fileEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+	}
+
+	// Execute the create-hard-link action via the file engine.
+	return fe.CreateHardLink(ctx)
+}
+
+// createJunction creates a directory junction.
+func (engine *actionEngine) createJunction(ctx context.Context) error {
+	// Prepare a file engine.
+	fe := fileEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+	}
+
+	// Execute the create-junction action via the file engine.
+	return fe.CreateJunction(ctx)
+}
+
+// installDriver installs a driver package contained in an archive package.
+func (engine *actionEngine) installDriver(ctx context.Context) error {
+	// Look up the package by its ID.
+	pkg, found := engine.deployment.Resources.Packages[engine.action.Definition.Package]
+	if !found {
+		return fmt.Errorf("the \"%s\" package does not exist within the \"%s\" deployment", engine.action.Definition.Package, engine.deployment.ID)
+	}
+
+	// Prepare a package engine.
+	pe := packageEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		pkg: packageData{
+			ID:         engine.action.Definition.Package,
+			Definition: pkg,
+		},
+		events: engine.events,
+		force:  engine.force,
+		state:  engine.state,
+	}
+
+	// Execute the install-driver action via the package engine.
+	return pe.InstallDriver(ctx)
+}
+
+// transaction invokes a group of file and registry actions as a single
+// transaction. If any member of the group fails, the changes made by the
+// group are undone.
+func (engine *actionEngine) transaction(ctx context.Context) error {
+	// Make sure that every member of the transaction can be undone.
+	members := engine.action.Definition.Actions
+	for i, member := range members {
+		switch member.Type {
+		case lbdeploy.ActionCopyFile, lbdeploy.ActionDeleteFile:
+		case lbdeploy.ActionSetRegistryValue, lbdeploy.ActionDeleteRegistryValue:
+		default:
+			return fmt.Errorf("transaction member %d: the \"%s\" action type cannot be used within a transaction", i+1, member.Type)
+		}
+	}
+
+	// Prepare the transaction.
+	tx, err := newTransaction()
+	if err != nil {
+		return err
+	}
+
+	// Record the time that the transaction started.
+	started := time.Now()
+
+	// Invoke each member of the transaction, stopping at the first error.
+	// Members share the index of the transaction action.
+	err = func() error {
+		for _, member := range members {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			ae := actionEngine{
+				deployment: engine.deployment,
+				flow:       engine.flow,
+				action: actionData{
+					Index:      engine.action.Index,
+					Definition: member,
+				},
+				events: engine.events,
+				force:  engine.force,
+				state:  engine.state,
+				tx:     tx,
+			}
+			if err := ae.Invoke(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+
+	// Commit the changes if all members succeeded, otherwise roll them back.
+	// A failure to clean up after a successful commit is not treated as a
+	// failure of the transaction.
+	var rollbackErr error
+	if err == nil {
+		tx.Commit()
+	} else {
+		rollbackErr = tx.Rollback()
+		engine.state.apps.Clear()
+	}
+
+	// Record the time that the transaction stopped.
+	stopped := time.Now()
+
+	// Record the outcome of the transaction.
+	engine.events.Record(lbdeployevent.TransactionStopped{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Changes:     tx.Changes(),
+		Committed:   err == nil,
+		Started:     started,
+		Stopped:     stopped,
+		Err:         err,
+		RollbackErr: rollbackErr,
+	})
+
+	if rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("the transaction could not be fully rolled back: %w", rollbackErr))
+	}
+
+	return err
+}
@@ -1,20 +1,31 @@
 package lbengine
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/gentlemanautomaton/winapp/unpackaged"
 	"github.com/gentlemanautomaton/winapp/unpackaged/appregistry"
+	"github.com/gentlemanautomaton/winapp/unpackaged/appscope"
 	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/fileversion"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"github.com/leafbridge/leafbridge-deploy/localfs"
 	"github.com/leafbridge/leafbridge-deploy/localregistry"
+	"github.com/leafbridge/leafbridge-deploy/msi/msiapi"
 )
 
 // AppEngine is responsible for evaluating the status of applications on the
 // local system.
 type AppEngine struct {
 	deployment lbdeploy.Deployment
+	cache      *appCache
 }
 
 // NewAppEngine prepares an app engine for the given deployment.
@@ -24,11 +35,34 @@ func NewAppEngine(dep lbdeploy.Deployment) AppEngine {
 	}
 }
 
+// newCachedAppEngine prepares an app engine for the given deployment that
+// remembers its results in the given cache.
+func newCachedAppEngine(dep lbdeploy.Deployment, cache *appCache) AppEngine {
+	return AppEngine{
+		deployment: dep,
+		cache:      cache,
+	}
+}
+
 // IsInstalled returns true if the application is installed on the local
 // system.
 //
 // If it is unable to make a determination, it returns an error.
 func (engine AppEngine) IsInstalled(app lbdeploy.AppID) (bool, error) {
+	if engine.cache == nil {
+		return engine.isInstalled(app)
+	}
+	if installed, found := engine.cache.IsInstalled(app); found {
+		return installed, nil
+	}
+	installed, err := engine.isInstalled(app)
+	if err == nil {
+		engine.cache.SetInstalled(app, installed)
+	}
+	return installed, err
+}
+
+func (engine AppEngine) isInstalled(app lbdeploy.AppID) (bool, error) {
 	// Find the app within the deployment.
 	definition, found := engine.deployment.Apps[app]
 	if !found {
@@ -42,15 +76,40 @@ func (engine AppEngine) IsInstalled(app lbdeploy.AppID) (bool, error) {
 		return ce.Evaluate(definition.Detection.Present)
 	}
 
-	// Use the application registry that matches the application's
-	// architecture (x64 or x86) and scope (machine or user).
-	view, err := appregistry.ViewFor(definition.Architecture, definition.Scope)
-	if err != nil {
-		return false, err
+	// If a file rule has been supplied, look for the file.
+	if rule := definition.Detection.File; rule.Path != "" {
+		path, err := engine.findFile(rule.Path)
+		if err != nil || path == "" {
+			return false, err
+		}
+		if rule.MinimumVersion == "" {
+			return true, nil
+		}
+		version, err := fileversion.Get(path)
+		if err != nil {
+			if errors.Is(err, fileversion.ErrNoVersion) {
+				return false, nil
+			}
+			return false, fmt.Errorf("unable to read the version of \"%s\": %w", path, err)
+		}
+		return datatype.CompareVersions(version, rule.MinimumVersion) >= 0, nil
+	}
+
+	// Ask the Windows Installer if requested.
+	if definition.Detection.Method == lbdeploy.AppDetectionMSI {
+		state, err := msiProductInfo(definition, msiapi.PropertyProductState)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return false, nil
+			}
+			return false, err
+		}
+		return state == strconv.Itoa(int(msiapi.StateDefault)), nil
 	}
 
 	// Look for the application in the registry.
-	return view.Contains(definition.ProductCode)
+	_, _, found, err := findRegisteredApp(definition)
+	return found, err
 }
 
 // Version returns the version number of the application if it is installed
@@ -58,6 +117,20 @@ func (engine AppEngine) IsInstalled(app lbdeploy.AppID) (bool, error) {
 //
 // If it is unable to make a determination, it returns an error.
 func (engine AppEngine) Version(app lbdeploy.AppID) (datatype.Version, error) {
+	if engine.cache == nil {
+		return engine.version(app)
+	}
+	if version, found := engine.cache.Version(app); found {
+		return version, nil
+	}
+	version, err := engine.version(app)
+	if err == nil {
+		engine.cache.SetVersion(app, version)
+	}
+	return version, err
+}
+
+func (engine AppEngine) version(app lbdeploy.AppID) (datatype.Version, error) {
 	// Find the app within the deployment.
 	definition, found := engine.deployment.Apps[app]
 	if !found {
@@ -92,16 +165,37 @@ func (engine AppEngine) Version(app lbdeploy.AppID) (datatype.Version, error) {
 		return "", fmt.Errorf("the \"%s\" registry value exists but does not contain a version", ref.Name)
 	}
 
-	// Use the application registry that matches the application's
-	// architecture (x64 or x86) and scope (machine or user).
-	view, err := appregistry.ViewFor(definition.Architecture, definition.Scope)
-	if err != nil {
-		return "", err
+	// If a file rule has been supplied, return the version of the file.
+	if rule := definition.Detection.File; rule.Path != "" {
+		path, err := engine.findFile(rule.Path)
+		if err != nil || path == "" {
+			return "", err
+		}
+		version, err := fileversion.Get(path)
+		if err != nil {
+			if errors.Is(err, fileversion.ErrNoVersion) {
+				return "", nil
+			}
+			return "", fmt.Errorf("unable to read the version of \"%s\": %w", path, err)
+		}
+		return version, nil
+	}
+
+	// Ask the Windows Installer if requested.
+	if definition.Detection.Method == lbdeploy.AppDetectionMSI {
+		version, err := msiProductInfo(definition, msiapi.PropertyVersionString)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", nil
+			}
+			return "", err
+		}
+		return datatype.Version(version), nil
 	}
 
 	// Retrieve the properties of the app from the registry.
-	properties, err := view.Get(definition.ProductCode)
-	if err != nil {
+	_, properties, found, err := findRegisteredApp(definition)
+	if err != nil || !found {
 		return "", err
 	}
 
@@ -109,6 +203,96 @@ func (engine AppEngine) Version(app lbdeploy.AppID) (datatype.Version, error) {
 	return datatype.Version(properties.Attributes.GetString("DisplayVersion")), nil
 }
 
+// Location returns the name of the application registry view in which the
+// application was found, such as "64-bit machine". For applications that are
+// detected by a file rule, it returns the path of the file. It returns an
+// empty string if the application is not installed, or if its presence is
+// not determined by the application registry or a file.
+//
+// If it is unable to make a determination, it returns an error.
+func (engine AppEngine) Location(app lbdeploy.AppID) (string, error) {
+	if engine.cache == nil {
+		return engine.location(app)
+	}
+	if location, found := engine.cache.Location(app); found {
+		return location, nil
+	}
+	location, err := engine.location(app)
+	if err == nil {
+		engine.cache.SetLocation(app, location)
+	}
+	return location, err
+}
+
+func (engine AppEngine) location(app lbdeploy.AppID) (string, error) {
+	// Find the app within the deployment.
+	definition, found := engine.deployment.Apps[app]
+	if !found {
+		return "", fmt.Errorf("the \"%s\" app does not exist within the \"%s\" deployment", app, engine.deployment.ID)
+	}
+
+	// Only file-based and registry-based detection have a location to
+	// report.
+	if definition.Detection.Present != "" {
+		return "", nil
+	}
+	if rule := definition.Detection.File; rule.Path != "" {
+		return engine.findFile(rule.Path)
+	}
+	if definition.Detection.Method == lbdeploy.AppDetectionMSI {
+		return "", nil
+	}
+
+	view, _, found, err := findRegisteredApp(definition)
+	if err != nil || !found {
+		return "", err
+	}
+	return view.Name(), nil
+}
+
+// IsCurrent returns true if the application is installed on the local
+// system at or above its target version. If the application does not have
+// a target version, it returns true if the application is installed at any
+// version.
+//
+// If it is unable to make a determination, it returns an error.
+func (engine AppEngine) IsCurrent(app lbdeploy.AppID) (bool, error) {
+	installed, err := engine.IsInstalled(app)
+	if err != nil || !installed {
+		return false, err
+	}
+
+	target := engine.deployment.Apps[app].TargetVersion
+	if target == "" {
+		return true, nil
+	}
+
+	version, err := engine.Version(app)
+	if err != nil {
+		return false, err
+	}
+	if version == "" {
+		return false, fmt.Errorf("the \"%s\" app is installed but its version could not be determined", app)
+	}
+
+	return datatype.CompareVersions(version, target) >= 0, nil
+}
+
+// CurrentApps returns any of the apps in the list that are installed on the
+// local system at or above their target version.
+func (engine AppEngine) CurrentApps(list lbdeploy.AppList) (current lbdeploy.AppList, err error) {
+	for _, appID := range list {
+		appIsCurrent, err := engine.IsCurrent(appID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine the installed version of application \"%s\": %w", appID, err)
+		}
+		if appIsCurrent {
+			current = append(current, appID)
+		}
+	}
+	return
+}
+
 // InstalledApps returns any of the apps in the list that are installed on the
 // local system.
 func (engine AppEngine) InstalledApps(list lbdeploy.AppList) (installed lbdeploy.AppList, err error) {
@@ -141,35 +325,64 @@ func (engine AppEngine) MissingApps(list lbdeploy.AppList) (missing lbdeploy.App
 
 // EvaluateAppChanges evaluates the changes needed to effect the given set of
 // application installs and uninstalls.
+//
+// An install is only considered to be in effect when the app is installed at
+// or above its target version. Apps that are installed at an older version
+// are planned as upgrades.
 func (engine AppEngine) EvaluateAppChanges(installs, uninstalls lbdeploy.AppList) (changes lbdeploy.AppEvaluation, err error) {
-	alreadyInstalled, err := engine.InstalledApps(installs)
+	alreadyInstalled, err := engine.CurrentApps(installs)
 	if err != nil {
 		return changes, err
 	}
 	toInstall := installs.Difference(alreadyInstalled)
 
+	toUpgrade, err := engine.InstalledApps(toInstall)
+	if err != nil {
+		return changes, err
+	}
+
 	alreadyUninstalled, err := engine.MissingApps(uninstalls)
 	if err != nil {
 		return changes, err
 	}
 	toUninstall := uninstalls.Difference(alreadyUninstalled)
 
+	// Record where each of the installed apps was found.
+	var locations lbdeploy.AppLocationMap
+	for _, list := range []lbdeploy.AppList{alreadyInstalled, toUninstall} {
+		for _, appID := range list {
+			location, err := engine.Location(appID)
+			if err != nil {
+				return changes, fmt.Errorf("unable to determine the location of application \"%s\": %w", appID, err)
+			}
+			if location == "" {
+				continue
+			}
+			if locations == nil {
+				locations = make(lbdeploy.AppLocationMap)
+			}
+			locations[appID] = location
+		}
+	}
+
 	return lbdeploy.AppEvaluation{
 		AlreadyInstalled:   alreadyInstalled,
 		AlreadyUninstalled: alreadyUninstalled,
 		ToInstall:          toInstall,
+		ToUpgrade:          toUpgrade,
 		ToUninstall:        toUninstall,
+		Locations:          locations,
 	}, nil
 }
 
 // SummarizeAppChanges summarizes the effectiveness of application installs
 // and uninstalls anticipated by a previous evaluation.
 func (engine AppEngine) SummarizeAppChanges(evaluation lbdeploy.AppEvaluation) (changes lbdeploy.AppSummary, err error) {
-	stillNotInstalled, err := engine.MissingApps(evaluation.ToInstall)
+	installed, err := engine.CurrentApps(evaluation.ToInstall)
 	if err != nil {
 		return changes, err
 	}
-	installed := evaluation.ToInstall.Difference(stillNotInstalled)
+	stillNotInstalled := evaluation.ToInstall.Difference(installed)
 
 	stillNotUninstalled, err := engine.InstalledApps(evaluation.ToUninstall)
 	if err != nil {
@@ -184,3 +397,229 @@ func (engine AppEngine) SummarizeAppChanges(evaluation lbdeploy.AppEvaluation) (
 		StillNotUninstalled: stillNotUninstalled,
 	}, nil
 }
+
+// findFile returns the path of the identified file if it is present on the
+// local system. If it is not present, it returns an empty string.
+func (engine AppEngine) findFile(file lbdeploy.FileResourceID) (string, error) {
+	ref, err := engine.deployment.Resources.FileSystem.ResolveFile(file)
+	if err != nil {
+		return "", err
+	}
+	dir, err := localfs.OpenDir(ref.Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer dir.Close()
+
+	fi, err := dir.System().Stat(ref.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	localized, err := filepath.Localize(ref.FilePath)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir.Path(), localized)
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("file \"%s\": the \"%s\" path exists but it is not a regular file", file, path)
+	}
+
+	return path, nil
+}
+
+// msiProductInfo queries the Windows Installer for a property of the
+// application's product, in each installation context that matches the
+// application's scope. It returns the value from the first context in which
+// the product is found.
+//
+// If the product is not found in any context, it returns an error that
+// satisfies errors.Is(err, os.ErrNotExist).
+func msiProductInfo(app lbdeploy.Application, property string) (string, error) {
+	if app.ProductCode == "" {
+		return "", errors.New("the app does not have a product code for the Windows Installer to look up")
+	}
+
+	var contexts []msiapi.Context
+	switch app.Scope {
+	case appscope.Machine:
+		contexts = []msiapi.Context{msiapi.ContextMachine}
+	case appscope.User:
+		contexts = []msiapi.Context{msiapi.ContextUserManaged, msiapi.ContextUserUnmanaged}
+	case lbdeploy.AnyScope:
+		contexts = []msiapi.Context{msiapi.ContextMachine, msiapi.ContextUserManaged, msiapi.ContextUserUnmanaged}
+	default:
+		return "", fmt.Errorf("unrecognized application scope: %s", app.Scope)
+	}
+
+	var err error
+	for _, context := range contexts {
+		var value string
+		value, err = msiapi.GetProductInfo(string(app.ProductCode), context, property)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+// appViews returns the application registry views that match the
+// application's architecture (x64 or x86) and scope (machine or user).
+// Either of them may be "any", in which case all matching views are
+// returned.
+func appViews(app lbdeploy.Application) ([]appregistry.View, error) {
+	arch, scope := app.Architecture, app.Scope
+	if arch != lbdeploy.AnyArchitecture && scope != lbdeploy.AnyScope {
+		view, err := appregistry.ViewFor(arch, scope)
+		if err != nil {
+			return nil, err
+		}
+		return []appregistry.View{view}, nil
+	}
+
+	// Make sure that whichever value isn't "any" is valid.
+	if arch == lbdeploy.AnyArchitecture {
+		arch = appcode.X64
+	}
+	if scope == lbdeploy.AnyScope {
+		scope = appscope.Machine
+	}
+	if _, err := appregistry.ViewFor(arch, scope); err != nil {
+		return nil, err
+	}
+
+	var views []appregistry.View
+	for _, view := range appregistry.Views {
+		if app.Architecture != lbdeploy.AnyArchitecture && view.Architecture() != app.Architecture {
+			continue
+		}
+		if app.Scope != lbdeploy.AnyScope && view.Scope() != app.Scope {
+			continue
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// findRegisteredApp looks for the application in each of the application
+// registry views that match its architecture and scope. It returns the
+// view in which it was found and the properties of the application.
+//
+// If the application is located by name and it is found in more than one
+// view, the entry with the highest version is returned.
+func findRegisteredApp(definition lbdeploy.Application) (view appregistry.View, app unpackaged.App, found bool, err error) {
+	views, err := appViews(definition)
+	if err != nil {
+		return view, app, false, err
+	}
+
+	for _, candidateView := range views {
+		// Look for the application by name if requested.
+		if definition.Detection.DisplayName != "" {
+			candidate, candidateFound, err := findAppByName(candidateView, definition.Detection)
+			if err != nil {
+				return view, app, false, err
+			}
+			if !candidateFound {
+				continue
+			}
+			if found {
+				a := datatype.Version(app.Attributes.GetString("DisplayVersion"))
+				b := datatype.Version(candidate.Attributes.GetString("DisplayVersion"))
+				if datatype.CompareVersions(b, a) <= 0 {
+					continue
+				}
+			}
+			view, app, found = candidateView, candidate, true
+			continue
+		}
+
+		// Look for the application by product code.
+		present, err := candidateView.Contains(definition.ProductCode)
+		if err != nil {
+			return view, app, false, err
+		}
+		if !present {
+			continue
+		}
+		candidate, err := candidateView.Get(definition.ProductCode)
+		if err != nil {
+			return view, app, false, err
+		}
+		return candidateView, candidate, true, nil
+	}
+
+	return view, app, found, nil
+}
+
+// findAppByName looks for an entry in the registry view with a display name
+// and publisher that match the patterns in the detection rules. If more
+// than one entry matches, it returns the one with the highest version.
+func findAppByName(view appregistry.View, detection lbdeploy.AppDetection) (app unpackaged.App, found bool, err error) {
+	apps, err := view.List()
+	if err != nil {
+		return app, false, err
+	}
+
+	for _, candidate := range apps {
+		if !matchWildcard(detection.DisplayName, candidate.Attributes.GetString("DisplayName")) {
+			continue
+		}
+		if detection.Publisher != "" && !matchWildcard(detection.Publisher, candidate.Attributes.GetString("Publisher")) {
+			continue
+		}
+		if found {
+			a := datatype.Version(app.Attributes.GetString("DisplayVersion"))
+			b := datatype.Version(candidate.Attributes.GetString("DisplayVersion"))
+			if datatype.CompareVersions(b, a) <= 0 {
+				continue
+			}
+		}
+		app, found = candidate, true
+	}
+
+	return app, found, nil
+}
+
+// matchWildcard reports whether s matches pattern, without regard to case.
+// In the pattern, * matches any sequence of characters and ? matches any
+// single character.
+func matchWildcard(pattern, s string) bool {
+	p, t := []rune(strings.ToLower(pattern)), []rune(strings.ToLower(s))
+
+	// Track the position of the last star, so that we can backtrack to it
+	// when a match fails.
+	var (
+		pi, ti    int
+		star      = -1
+		starMatch int
+	)
+	for ti < len(t) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == t[ti]):
+			pi++
+			ti++
+		case pi < len(p) && p[pi] == '*':
+			star, starMatch = pi, ti
+			pi++
+		case star >= 0:
+			starMatch++
+			pi, ti = star+1, starMatch
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
@@ -0,0 +1,90 @@
+package lbengine
+
+import (
+	"sync"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// appCache remembers the installation state and version of applications
+// within a single deployment invocation, so that the system doesn't have
+// to be queried again each time an application is evaluated.
+//
+// The cache must be cleared whenever something happens that might change
+// the state of an application, such as the invocation of a command.
+type appCache struct {
+	mutex     sync.Mutex
+	installed map[lbdeploy.AppID]bool
+	versions  map[lbdeploy.AppID]datatype.Version
+	locations map[lbdeploy.AppID]string
+}
+
+func newAppCache() *appCache {
+	return &appCache{
+		installed: make(map[lbdeploy.AppID]bool),
+		versions:  make(map[lbdeploy.AppID]datatype.Version),
+		locations: make(map[lbdeploy.AppID]string),
+	}
+}
+
+// IsInstalled returns the cached installation state of an app, if known.
+func (cache *appCache) IsInstalled(app lbdeploy.AppID) (installed, found bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	installed, found = cache.installed[app]
+	return
+}
+
+// SetInstalled records the installation state of an app.
+func (cache *appCache) SetInstalled(app lbdeploy.AppID, installed bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.installed[app] = installed
+}
+
+// Version returns the cached version of an app, if known.
+func (cache *appCache) Version(app lbdeploy.AppID) (version datatype.Version, found bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	version, found = cache.versions[app]
+	return
+}
+
+// SetVersion records the version of an app.
+func (cache *appCache) SetVersion(app lbdeploy.AppID, version datatype.Version) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.versions[app] = version
+}
+
+// Location returns the cached location of an app, if known.
+func (cache *appCache) Location(app lbdeploy.AppID) (location string, found bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	location, found = cache.locations[app]
+	return
+}
+
+// SetLocation records the location of an app.
+func (cache *appCache) SetLocation(app lbdeploy.AppID, location string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.locations[app] = location
+}
+
+// Clear discards everything in the cache.
+func (cache *appCache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	clear(cache.installed)
+	clear(cache.versions)
+	clear(cache.locations)
+}
@@ -0,0 +1,162 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/bits"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// openBITSSource downloads a package file from an http source with a
+// background transfer job, then opens the transferred file so that it is
+// copied into the staging directory and verified like any other download.
+func (engine *downloadEngine) openBITSSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize, limit int64) (downloadStream, error) {
+	if err := engine.transfer(ctx, source, file, expectedSize); err != nil {
+		return downloadStream{}, err
+	}
+
+	path := file.TransferPath()
+	f, err := os.Open(path)
+	if err != nil {
+		file.RemoveTransferJob()
+		return downloadStream{}, err
+	}
+
+	return engine.openSourceFile(source, file, verifier, path, &transferFile{File: f, pkg: file}, expectedSize, limit)
+}
+
+// transfer waits for a background transfer job to download the package
+// file from source to its transfer path.
+//
+// The job is recorded alongside the package file. If the transfer is
+// interrupted, including by a restart, the recorded job is resumed on the
+// next attempt instead of starting over.
+func (engine *downloadEngine) transfer(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, expectedSize int64) error {
+	// Look for a job that was recorded by an earlier attempt.
+	var (
+		id      bits.JobID
+		resumed bool
+	)
+	if record, err := file.ReadTransferJob(); err == nil {
+		if record.URL == source.URL {
+			if record.Completed {
+				if _, err := os.Stat(file.TransferPath()); err == nil {
+					return nil
+				}
+			} else if id, err = bits.ParseJobID(record.ID); err == nil {
+				resumed = true
+			}
+		} else if old, err := bits.ParseJobID(record.ID); err == nil {
+			// The job belongs to a different source.
+			bits.Cancel(old)
+		}
+	}
+
+	// Start a new job if there isn't one to resume.
+	if !resumed {
+		file.RemoveTransferJob()
+
+		var err error
+		id, err = bits.Start(bits.Transfer{
+			DisplayName: fmt.Sprintf("LeafBridge %s %s", engine.deployment.ID, file.Name),
+			URL:         source.URL,
+			Path:        file.TransferPath(),
+			Priority:    bits.PriorityNormal,
+		})
+		if err != nil {
+			return err
+		}
+		if err := file.WriteTransferJob(stagingfs.TransferJob{ID: id.String(), URL: source.URL, Updated: time.Now()}); err != nil {
+			bits.Cancel(id)
+			return fmt.Errorf("failed to record the transfer job: %w", err)
+		}
+	}
+
+	// Record the start of the transfer.
+	engine.events.Record(lbdeployevent.TransferJobStarted{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Source:      source,
+		FileName:    file.Name,
+		Path:        file.Path,
+		Job:         id.String(),
+		Resumed:     resumed,
+	})
+
+	// Record heartbeats until the transfer has stopped.
+	var progress atomic.Int64
+	stopHeartbeat := startHeartbeat(engine.events, engine.state.heartbeat, func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat {
+		return lbdeployevent.Heartbeat{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Operation:   lbdeployevent.HeartbeatDownload,
+			Subject:     file.Name,
+			Started:     started,
+			Elapsed:     elapsed,
+			Progress:    progress.Load(),
+			Total:       expectedSize,
+		}
+	})
+
+	err := bits.Wait(ctx, id, func(p bits.Progress) {
+		progress.Store(p.Transferred)
+	})
+
+	stopHeartbeat()
+
+	switch {
+	case err == nil:
+		file.WriteTransferJob(stagingfs.TransferJob{ID: id.String(), URL: source.URL, Completed: true, Updated: time.Now()})
+		return nil
+	case ctx.Err() != nil:
+		// Leave the job running, so that it can be resumed later.
+		return err
+	case resumed && errors.Is(err, bits.ErrNotFound):
+		// The recorded job no longer exists, so start a new one.
+		file.RemoveTransferJob()
+		return engine.transfer(ctx, source, file, expectedSize)
+	default:
+		file.RemoveTransferJob()
+		return err
+	}
+}
+
+// transferFile is a file that was downloaded by a background transfer job.
+// Once it has been read to the end, closing it removes the file and the
+// record of its job.
+type transferFile struct {
+	*os.File
+	pkg  stagingfs.PackageFile
+	done bool
+}
+
+// Read reads from the file and notes when its end has been reached.
+func (f *transferFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	if err == io.EOF {
+		f.done = true
+	}
+	return n, err
+}
+
+// Close closes the file. If the file has been read to the end, it is
+// removed along with the record of its job.
+func (f *transferFile) Close() error {
+	err := f.File.Close()
+	if f.done {
+		f.pkg.RemoveTransferJob()
+	}
+	return err
+}
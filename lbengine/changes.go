@@ -0,0 +1,35 @@
+package lbengine
+
+import (
+	"sync"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// changeTracker accumulates the changes made to the set of installed
+// applications by the commands invoked by a deployment.
+type changeTracker struct {
+	mutex sync.Mutex
+	apps  lbdeploy.AppSummary
+}
+
+func newChangeTracker() *changeTracker {
+	return &changeTracker{}
+}
+
+// AddApps adds the given summary of application changes to the tracker.
+func (tracker *changeTracker) AddApps(summary lbdeploy.AppSummary) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.apps = tracker.apps.Add(summary)
+}
+
+// Apps returns a summary of all of the application changes that have been
+// added to the tracker.
+func (tracker *changeTracker) Apps() lbdeploy.AppSummary {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return tracker.apps
+}
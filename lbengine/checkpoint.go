@@ -0,0 +1,102 @@
+package lbengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// flowCheckpoint keeps track of the progress of a flow's main actions in
+// the deployment's staging directory. Checkpoints are only kept when
+// resumption has been requested.
+//
+// Checkpoints are maintained on a best-effort basis. A failure to read or
+// write a checkpoint does not cause the flow to fail.
+type flowCheckpoint struct {
+	deployment  lbdeploy.DeploymentID
+	flow        lbdeploy.FlowID
+	fingerprint string
+	completed   int
+}
+
+// newFlowCheckpoint prepares a checkpoint for the given flow.
+func newFlowCheckpoint(deployment lbdeploy.DeploymentID, flow flowData) *flowCheckpoint {
+	return &flowCheckpoint{
+		deployment:  deployment,
+		flow:        flow.ID,
+		fingerprint: flowFingerprint(flow.Definition),
+	}
+}
+
+// Resume reads the previously recorded checkpoint for the flow and returns
+// the number of main actions that were completed by the previous
+// invocation. Only those that are resumable may be skipped.
+//
+// It returns zero if no checkpoint was recorded, or if the checkpoint was
+// recorded for a different version of the flow.
+func (c *flowCheckpoint) Resume(actions []lbdeploy.Action) int {
+	dir, err := stagingfs.OpenExistingDeployment(c.deployment)
+	if err != nil {
+		return 0
+	}
+	defer dir.Close()
+
+	checkpoint, err := dir.ReadCheckpoint(c.flow)
+	if err != nil || checkpoint.Fingerprint != c.fingerprint {
+		return 0
+	}
+
+	c.completed = min(checkpoint.Completed, len(actions))
+	return c.completed
+}
+
+// Advance records that the main action at index i completed successfully.
+// The checkpoint only advances when every action before i has also
+// completed.
+func (c *flowCheckpoint) Advance(i int) {
+	if i != c.completed {
+		return
+	}
+	c.completed++
+
+	dir, err := stagingfs.OpenDeployment(c.deployment)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+
+	dir.WriteCheckpoint(stagingfs.Checkpoint{
+		Flow:        c.flow,
+		Fingerprint: c.fingerprint,
+		Completed:   c.completed,
+		Updated:     time.Now(),
+	})
+}
+
+// Clear removes the checkpoint for the flow. It is called when all of the
+// flow's main actions have completed, so that the next invocation starts
+// from the beginning.
+func (c *flowCheckpoint) Clear() {
+	dir, err := stagingfs.OpenExistingDeployment(c.deployment)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+
+	dir.RemoveCheckpoint(c.flow)
+}
+
+// flowFingerprint returns a hash of the flow definition, which is used to
+// detect changes to a flow between invocations.
+func flowFingerprint(flow lbdeploy.Flow) string {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
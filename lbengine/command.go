@@ -1,7 +1,6 @@
 package lbengine
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,16 +8,22 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/gentlemanautomaton/cmdline"
+
 	"github.com/leafbridge/leafbridge-deploy/bytesconv"
-	"github.com/leafbridge/leafbridge-deploy/internal/mergereader"
+	"github.com/leafbridge/leafbridge-deploy/cmdoutput"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
 	"github.com/leafbridge/leafbridge-deploy/lbevent"
 	"github.com/leafbridge/leafbridge-deploy/localfs"
 	"github.com/leafbridge/leafbridge-deploy/msi/msiresult"
 	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 	"github.com/leafbridge/leafbridge-deploy/tempfs"
 )
 
@@ -37,12 +42,25 @@ type commandEngine struct {
 	command    commandData
 	apps       lbdeploy.AppEvaluation
 	events     lbevent.Recorder
-	force      bool
+	force      ForceScope
 	state      *engineState
+
+	// packageDir is the directory holding the package's files, if the
+	// command is being run for a package.
+	packageDir string
 }
 
 // InvokeStandard runs the command without a package affiliation.
 func (engine *commandEngine) InvokeStandard(ctx context.Context) error {
+	// If the command refers to a program by name, find it.
+	if name, ok := engine.command.Definition.Executable.Program(); ok {
+		execPath, err := findProgram(name)
+		if err != nil {
+			return fmt.Errorf("%s refers to a program \"%s\" that could not be found: %w", engine.cmdDesc(), name, err)
+		}
+		return engine.invokePath(ctx, execPath)
+	}
+
 	// Get information about the executable file from the file system.
 	fileID := lbdeploy.FileResourceID(engine.command.Definition.Executable)
 	fileRef, err := engine.deployment.Resources.FileSystem.ResolveFile(fileID)
@@ -93,6 +111,7 @@ func (engine *commandEngine) InvokePackage(ctx context.Context, dir stagingfs.Pa
 	if err != nil {
 		return fmt.Errorf("an executable file path could not be prepared for %s: %w", engine.cmdDesc(), err)
 	}
+	engine.packageDir = filepath.Dir(execPath)
 
 	return engine.invokePath(ctx, execPath)
 }
@@ -120,6 +139,7 @@ func (engine *commandEngine) InvokeArchive(ctx context.Context, files tempfs.Ext
 	if err != nil {
 		return fmt.Errorf("an executable file path could not be prepared for %s: %w", engine.cmdDesc(), err)
 	}
+	engine.packageDir = files.Path()
 
 	return engine.invokePath(ctx, execPath)
 }
@@ -129,7 +149,7 @@ func (engine *commandEngine) InvokeApp(ctx context.Context) error {
 	// Determine what application we will be operting on.
 	var app lbdeploy.AppID
 	switch engine.command.Definition.Type {
-	case lbdeploy.CommandTypeMSIUninstallProductCode:
+	case lbdeploy.CommandTypeMSIUninstallProductCode, lbdeploy.CommandTypeUninstallString:
 		if len(engine.command.Definition.Uninstalls) != 1 {
 			return fmt.Errorf("%s must provide a single application ID to be uninstalled", engine.cmdDesc())
 		}
@@ -144,6 +164,12 @@ func (engine *commandEngine) InvokeApp(ctx context.Context) error {
 		return fmt.Errorf("%s refers to an application \"%s\" that is not defined in the \"%s\" deployment", engine.cmdDesc(), app, engine.deployment.ID)
 	}
 
+	// Commands that run an application's uninstall string are handled
+	// separately.
+	if engine.command.Definition.Type == lbdeploy.CommandTypeUninstallString {
+		return engine.invokeUninstallString(ctx, app, appData)
+	}
+
 	// Make sure a product code is defined.
 	if appData.ProductCode == "" {
 		return fmt.Errorf("%s refers to an application \"%s\" that does not have a product code", engine.cmdDesc(), app)
@@ -164,10 +190,11 @@ func (engine *commandEngine) InvokeApp(ctx context.Context) error {
 	}
 
 	// If a working directory was specified, resolve it.
-	workingDir, err := engine.workingDirectory()
+	workingDir, release, err := engine.workingDirectory()
 	if err != nil {
 		return fmt.Errorf("a working directory could not be determined for %s: %w", engine.cmdDesc(), err)
 	}
+	defer release()
 
 	// Find the msiexec executable.
 	execPath, err := exec.LookPath("msiexec.exe")
@@ -178,12 +205,100 @@ func (engine *commandEngine) InvokeApp(ctx context.Context) error {
 	return engine.invoke(ctx, workingDir, execPath, args)
 }
 
+// invokeUninstallString runs the uninstall command recorded in the
+// application's registry entry. The quiet uninstall string is preferred if
+// one is present.
+func (engine *commandEngine) invokeUninstallString(ctx context.Context, app lbdeploy.AppID, appData lbdeploy.Application) error {
+	// Find the application's registry entry.
+	_, entry, found, err := findRegisteredApp(appData)
+	if err != nil {
+		return fmt.Errorf("%s was unable to look up the \"%s\" application in the registry: %w", engine.cmdDesc(), app, err)
+	}
+	if !found {
+		return fmt.Errorf("%s refers to an application \"%s\" that could not be found in the registry", engine.cmdDesc(), app)
+	}
+
+	// Read the uninstall string.
+	uninstallString := entry.Attributes.GetString("QuietUninstallString")
+	if uninstallString == "" {
+		uninstallString = entry.Attributes.GetString("UninstallString")
+	}
+	if uninstallString == "" {
+		return fmt.Errorf("%s refers to an application \"%s\" that does not have an uninstall string", engine.cmdDesc(), app)
+	}
+
+	// Break the uninstall string into an executable and its arguments.
+	name, args := splitUninstallString(uninstallString)
+	if name == "" {
+		return fmt.Errorf("%s was unable to parse the uninstall string of the \"%s\" application: %s", engine.cmdDesc(), app, uninstallString)
+	}
+
+	// Find the executable.
+	execPath, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s was unable to locate the uninstall executable for the \"%s\" application: %w", engine.cmdDesc(), app, err)
+	}
+
+	// Windows Installer entries often record a command that opens the
+	// maintenance interface. Make sure it removes the product silently
+	// instead.
+	if strings.EqualFold(filepath.Base(execPath), "msiexec.exe") {
+		for i, arg := range args {
+			if len(arg) >= 2 && (arg[0] == '/' || arg[0] == '-') && (arg[1] == 'i' || arg[1] == 'I') {
+				args[i] = "/X" + arg[2:]
+			}
+		}
+		args = append(args, "/quiet", "/norestart")
+	}
+
+	// Append any extra arguments supplied by the command.
+	args = append(args, engine.command.Definition.Args...)
+
+	// Determine a working directory for the command.
+	workingDir, release, err := engine.workingDirectoryForExecutable(execPath)
+	if err != nil {
+		return fmt.Errorf("a working directory could not be determined for %s: %w", engine.cmdDesc(), err)
+	}
+	defer release()
+
+	return engine.invoke(ctx, workingDir, execPath, args)
+}
+
+// splitUninstallString breaks an uninstall string into an executable path and
+// its arguments.
+//
+// Uninstall strings frequently contain paths with spaces that aren't quoted.
+// When the executable path isn't quoted, everything up to the first ".exe"
+// is treated as the path.
+func splitUninstallString(s string) (name string, args []string) {
+	s = strings.TrimSpace(s)
+	if s == "" || s[0] == '"' {
+		return cmdline.SplitCommand(s)
+	}
+
+	lower := strings.ToLower(s)
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], ".exe")
+		if i < 0 {
+			break
+		}
+		end := offset + i + len(".exe")
+		if end == len(s) || s[end] == ' ' {
+			return s[:end], cmdline.Split(s[end:])
+		}
+		offset = end
+	}
+
+	return cmdline.SplitCommand(s)
+}
+
 func (engine *commandEngine) invokePath(ctx context.Context, execPath string) (err error) {
 	// Determine a working directory for the command.
-	workingDir, err := engine.workingDirectoryForExecutable(execPath)
+	workingDir, release, err := engine.workingDirectoryForExecutable(execPath)
 	if err != nil {
 		return fmt.Errorf("a working directory could not be determined for %s: %w", engine.cmdDesc(), err)
 	}
+	defer release()
 
 	// Prepare the command arguments.
 	args := engine.command.Definition.Args
@@ -214,18 +329,162 @@ func (engine *commandEngine) invokePath(ctx context.Context, execPath string) (e
 	return engine.invoke(ctx, workingDir, execPath, args)
 }
 
-func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath string, args []string) (err error) {
+func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath string, args []string) error {
+	// Prepare a log file for the command, if it will be logged.
+	logPath, logArgs, err := engine.prepareLog(args)
+	if err != nil {
+		return fmt.Errorf("a log file could not be prepared for %s: %w", engine.cmdDesc(), err)
+	}
+
+	// Commands that don't invoke msiexec are run once.
+	if !engine.command.Definition.Type.IsMSI() {
+		if len(engine.command.Definition.Properties) > 0 {
+			return fmt.Errorf("%s has properties, which are only valid for msi-based commands", engine.cmdDesc())
+		}
+		args = append(slices.Clone(args), logArgs...)
+		if args, err = engine.expandPlaceholders(args); err != nil {
+			return fmt.Errorf("%s: %w", engine.cmdDesc(), err)
+		}
+		return engine.invokeOnce(ctx, workingDir, execPath, args, logPath)
+	}
+
+	// Append the command's Windows Installer properties and logging
+	// options.
+	if err := engine.command.Definition.Properties.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", engine.cmdDesc(), err)
+	}
+	args = append(slices.Clone(args), engine.command.Definition.Properties.Args()...)
+	args = append(args, logArgs...)
+	if args, err = engine.expandPlaceholders(args); err != nil {
+		return fmt.Errorf("%s: %w", engine.cmdDesc(), err)
+	}
+
+	// Commands that invoke msiexec wait for the Windows Installer to be
+	// free, and try again if another installation beats them to it.
+	iw := newInstallerWait(engine.installerWait())
+	for {
+		if err := engine.waitForInstaller(ctx, &iw); err != nil {
+			return err
+		}
+
+		err := engine.invokeOnce(ctx, workingDir, execPath, args, logPath)
+		if exitCode, ok := err.(msiresult.ExitCode); !ok || exitCode != msiresult.InstallAlreadyRunning {
+			return err
+		}
+
+		if err := engine.backoffInstaller(ctx, &iw, installerBusyExitCode); err != nil {
+			return err
+		}
+	}
+}
+
+// expandPlaceholders returns a copy of args with fact and package file
+// placeholders replaced by the values they refer to.
+func (engine *commandEngine) expandPlaceholders(args []string) ([]string, error) {
+	args, err := expandFacts(args)
+	if err != nil {
+		return nil, err
+	}
+	return engine.expandPackageFiles(args)
+}
+
+// expandPackageFiles returns a copy of args with package file placeholders
+// replaced by the absolute paths of the files within the package's
+// extraction directory.
+func (engine *commandEngine) expandPackageFiles(args []string) ([]string, error) {
+	if !slices.ContainsFunc(args, func(arg string) bool { return len(lbdeploy.PackageFileReferences(arg)) > 0 }) {
+		return args, nil
+	}
+
+	out := make([]string, len(args))
+	for i, arg := range args {
+		expanded, err := lbdeploy.ExpandPackageFiles(arg, engine.packageFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		out[i] = expanded
+	}
+	return out, nil
+}
+
+// packageFilePath returns the absolute path of the given package file
+// within the package's extraction directory. It returns an error if the
+// file is not defined or has not been extracted.
+func (engine *commandEngine) packageFilePath(id lbdeploy.PackageFileID) (string, error) {
+	fileData, exists := engine.pkg.Definition.Files[id]
+	if !exists {
+		return "", fmt.Errorf("the \"%s\" package file is not defined in the \"%s\" package", id, engine.pkg.ID)
+	}
+	if engine.packageDir == "" || engine.pkg.Definition.Type != "archive" {
+		return "", fmt.Errorf("the \"%s\" package file is not available because the package has not been extracted", id)
+	}
+
+	localized, err := filepath.Localize(fileData.Path)
+	if err != nil {
+		return "", fmt.Errorf("localization of the \"%s\" package file path failed: %w", id, err)
+	}
+	path := filepath.Join(engine.packageDir, localized)
+
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("the \"%s\" package file could not be found: %w", id, err)
+	}
+
+	return path, nil
+}
+
+// expandFacts returns a copy of args with fact placeholders replaced by the
+// values of the facts they refer to. Facts are only gathered when args
+// include a placeholder.
+func expandFacts(args []string) ([]string, error) {
+	if !slices.ContainsFunc(args, func(arg string) bool { return len(sysfacts.References(arg)) > 0 }) {
+		return args, nil
+	}
+
+	facts, _ := sysfacts.Snapshot()
+	out := make([]string, len(args))
+	for i, arg := range args {
+		expanded, err := sysfacts.Expand(arg, facts)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		out[i] = expanded
+	}
+	return out, nil
+}
+
+// invokeOnce runs the command a single time. If logPath is not empty, the
+// command has been asked to write its log to that path, and the log is
+// collected when the command stops.
+func (engine *commandEngine) invokeOnce(ctx context.Context, workingDir, execPath string, args []string, logPath string) (err error) {
 	// Check for cancellation before starting the command.
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
+	// Deal with any running processes that belong to the applications the
+	// command is about to change.
+	if err := engine.resolveProcessConflicts(ctx); err != nil {
+		return err
+	}
+
 	// Prepare a command that will be terminated when ctx is cancelled.
 	cmd := exec.CommandContext(ctx, execPath, args...)
 
 	// Set the command's working directory.
 	cmd.Dir = workingDir
 
+	// msiexec parses its command line differently than most programs, so
+	// build it by hand for msi-based commands.
+	if engine.command.Definition.Type.IsMSI() {
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: msiCommandLine(execPath, args)}
+	}
+
+	// Run the command at a lower priority, if requested.
+	applyPriority(cmd, engine.command.Definition.Throttle)
+
+	// Hide the command's console window, if requested.
+	applyWindowStyle(cmd, engine.command.Definition.Window)
+
 	// Configure the command to wait up to one minute for the command to close
 	// out gracefully when its context is cancelled.
 	//
@@ -254,11 +513,16 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 		CommandLine:          cmd.String(),
 		WorkingDirectory:     engine.command.Definition.WorkingDirectory,
 		WorkingDirectoryPath: workingDir,
+		LogFile:              logPath,
 		Apps:                 engine.apps,
 	})
 
-	// Prepare a buffer to hold the combined command output.
-	var output bytes.Buffer
+	// Take note of any restarts that were already pending before the command
+	// was invoked, so that only new indicators are attributed to it.
+	pendingBefore, pendingErr := pendingRebootIndicators()
+
+	// Prepare a slice to hold the combined command output.
+	var output cmdoutput.Lines
 
 	// Record the time that the command started.
 	started := time.Now()
@@ -266,21 +530,52 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 	// Start the command.
 	err = cmd.Start()
 
+	// Cap the processor time of the command, if requested. If the cap can't
+	// be applied, stop the command rather than let it run unrestrained.
+	if limit := engine.command.Definition.Throttle.CPULimit; err == nil && limit > 0 {
+		release, limitErr := limitCPU(cmd.Process.Pid, limit)
+		if limitErr != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			err = fmt.Errorf("failed to limit the processor time of the command: %w", limitErr)
+		} else {
+			defer release()
+		}
+	}
+
 	// If the command started successfully, send its output to stdout and
-	// stderr as well as the output buffer, then wait for it to finish.
+	// stderr as well as the output lines, then wait for it to finish.
 	if err == nil {
+		// Record heartbeats until the command has completed.
+		stopHeartbeat := startHeartbeat(engine.events, engine.state.heartbeat, func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat {
+			return lbdeployevent.Heartbeat{
+				Deployment:  engine.deployment.ID,
+				Flow:        engine.flow.ID,
+				ActionIndex: engine.action.Index,
+				ActionType:  engine.action.Definition.Type,
+				Operation:   lbdeployevent.HeartbeatCommand,
+				Subject:     string(engine.command.ID),
+				Started:     started,
+				Elapsed:     elapsed,
+			}
+		})
+
+		// Hide or minimize any windows that the command shows, if requested.
+		stopWatchingWindows := watchWindows(cmd.Process.Pid, engine.command.Definition.Window)
+
 		// Tee stdout and stderr to the console.
 		r1 := io.TeeReader(stdout, os.Stdout)
 		r2 := io.TeeReader(stderr, os.Stderr)
 
-		// Combine the output of both stdout and stderr.
-		merged := mergereader.New(r1, r2)
-
-		// Read the combined output from the command.
-		io.Copy(&output, merged)
+		// Collect the output of both stdout and stderr, one line at a time.
+		output = cmdoutput.Collect(bytesconv.DecodeString,
+			cmdoutput.Source{Stream: cmdoutput.Stdout, Reader: r1},
+			cmdoutput.Source{Stream: cmdoutput.Stderr, Reader: r2})
 
 		// Wait for the command to be completed.
 		err = cmd.Wait()
+		stopWatchingWindows()
+		stopHeartbeat()
 	}
 
 	// Record the time that the command stopped.
@@ -299,8 +594,12 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 		}
 	}
 
+	// The command might have changed the state of any application, so
+	// forget what we knew about them.
+	engine.state.apps.Clear()
+
 	// Evaluate the effectiveness of any expected application changes.
-	ae := NewAppEngine(engine.deployment)
+	ae := newCachedAppEngine(engine.deployment, engine.state.apps)
 	appSummary, appSummaryErr := ae.SummarizeAppChanges(engine.apps)
 	if appSummaryErr != nil {
 		appSummaryErr = fmt.Errorf("failed to determine the state of installed applications after the command was invoked: %w", appSummaryErr)
@@ -309,6 +608,15 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 		}
 	}
 
+	// Keep track of the application changes made by the deployment.
+	engine.state.changes.AddApps(appSummary)
+
+	// Test whether the command had its intended effect.
+	var postPassed, postFailed lbdeploy.ConditionList
+	if err == nil && len(engine.command.Definition.PostConditions) > 0 {
+		postPassed, postFailed, err = engine.evaluatePostConditions()
+	}
+
 	// Record the end of the command.
 	engine.events.Record(lbdeployevent.CommandStopped{
 		Deployment:           engine.deployment.ID,
@@ -319,16 +627,24 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 		Command:              engine.command.ID,
 		CommandLine:          cmd.String(),
 		Result:               result,
-		Output:               bytesconv.DecodeString(output.Bytes()),
+		Output:               output,
 		WorkingDirectory:     engine.command.Definition.WorkingDirectory,
 		WorkingDirectoryPath: workingDir,
+		LogFile:              logPath,
 		AppsBefore:           engine.apps,
 		AppsAfter:            appSummary,
+		PostConditionsPassed: postPassed,
+		PostConditionsFailed: postFailed,
 		Started:              started,
 		Stopped:              stopped,
 		Err:                  err,
 	})
 
+	// Collect the command's log.
+	if logPath != "" {
+		engine.collectLog(logPath, err != nil)
+	}
+
 	// Wait 5 seconds to let the file system and file locks quiesce before
 	// continuing on. This is especially important if this command is the last
 	// action running for an extracted archive, and LeafBridge attempts to
@@ -350,11 +666,88 @@ func (engine *commandEngine) invoke(ctx context.Context, workingDir, execPath st
 		return err
 	}
 
+	// Determine whether the command requires a restart, and act on it in
+	// accordance with the deployment's reboot policy.
+	if reasons := engine.rebootReasons(result, pendingBefore, pendingErr); len(reasons) > 0 {
+		engine.state.reboot.Require(reasons...)
+
+		// If the command has already initiated a restart on its own, the
+		// reboot policy can't be applied. Abandon the rest of the
+		// deployment.
+		if result.Outcome() == lbdeploy.CommandSucceededRebootInitiated {
+			engine.state.reboot.initiate()
+			engine.events.Record(lbdeployevent.RebootRequired{
+				Deployment:  engine.deployment.ID,
+				Flow:        engine.flow.ID,
+				ActionIndex: engine.action.Index,
+				ActionType:  engine.action.Definition.Type,
+				Package:     engine.pkg.ID,
+				Command:     engine.command.ID,
+				Reasons:     reasons,
+				Mode:        engine.deployment.Reboot.Mode,
+				Initiated:   true,
+			})
+			return errRestartInitiated
+		}
+
+		engine.events.Record(lbdeployevent.RebootRequired{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Package:     engine.pkg.ID,
+			Command:     engine.command.ID,
+			Reasons:     reasons,
+			Mode:        engine.deployment.Reboot.Mode,
+		})
+
+		if engine.deployment.Reboot.Mode == lbdeploy.RebootImmediate {
+			if err := restartSystem(engine.deployment, engine.flow.ID, engine.events, engine.state); err != nil {
+				return fmt.Errorf("a restart is required after %s but could not be initiated: %w", engine.cmdDesc(), err)
+			}
+			return errRestartInitiated
+		}
+	}
+
 	// If the application summary indicates that an expected change to the
 	// installed set of applications didn't take effect, return the error.
 	return appSummary.Err()
 }
 
+// rebootReasons returns the reasons that a restart is required after the
+// command was invoked. It considers the command's exit code as well as any
+// restart indicators that appeared in the registry while the command ran.
+func (engine *commandEngine) rebootReasons(result lbdeploy.CommandResult, pendingBefore []string, pendingErr error) []string {
+	var reasons []string
+
+	if result.Info.Reboot {
+		if result.Info.Name != "" {
+			reasons = append(reasons, fmt.Sprintf("exit code %d (%s)", result.ExitCode, result.Info.Name))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("exit code %d", result.ExitCode))
+		}
+	}
+
+	// If the indicators couldn't be read before the command was invoked,
+	// there's no reliable way to tell which ones it was responsible for.
+	if pendingErr != nil {
+		return reasons
+	}
+
+	pendingAfter, err := pendingRebootIndicators()
+	if err != nil {
+		return reasons
+	}
+
+	for _, indicator := range pendingAfter {
+		if !slices.Contains(pendingBefore, indicator) {
+			reasons = append(reasons, indicator)
+		}
+	}
+
+	return reasons
+}
+
 // cmdDesc returns a string describing the command. It is used to build
 // error messages.
 func (engine *commandEngine) cmdDesc() string {
@@ -368,44 +761,106 @@ func (engine *commandEngine) cmdDesc() string {
 // working directory. If a working directory was not provided for the command,
 // it returns the directory containing the executable.
 //
+// The caller must call release when the command has finished.
+//
 // If the working directory could not be resolved or does not exist, it
 // returns an error.
-func (engine *commandEngine) workingDirectoryForExecutable(execPath string) (path string, err error) {
-	path, err = engine.workingDirectory()
+func (engine *commandEngine) workingDirectoryForExecutable(execPath string) (path string, release func(), err error) {
+	path, release, err = engine.workingDirectory()
 	if err != nil || path != "" {
-		return path, err
+		return path, release, err
 	}
 	path = filepath.Dir(execPath)
 	if path == "" {
-		return "", fmt.Errorf("a directory could not be determined for the executable's path: %s", execPath)
+		return "", release, fmt.Errorf("a directory could not be determined for the executable's path: %s", execPath)
 	}
-	return path, nil
+	return path, release, nil
 }
 
 // workingDirectory returns an absolute path to the command's working
 // directory. If a working directory was not provided for the command, it
 // returns an empty string.
 //
+// If the command runs within a temporary directory, it is created here and
+// deleted when release is called. The caller must call release when the
+// command has finished, even if the path is empty.
+//
 // If the working directory could not be resolved or does not exist, it
 // returns an error.
-func (engine *commandEngine) workingDirectory() (path string, err error) {
+func (engine *commandEngine) workingDirectory() (path string, release func(), err error) {
+	release = func() {}
+
 	dirID := engine.command.Definition.WorkingDirectory
 	if dirID == "" {
-		return "", nil
+		return "", release, nil
+	}
+
+	// Create an empty temporary directory if one was requested.
+	if dirID == lbdeploy.TempWorkingDirectory {
+		dir, err := tempfs.OpenWorkingDirForCommand(engine.command.ID)
+		if err != nil {
+			return "", release, err
+		}
+		return dir.Path(), func() { dir.Close() }, nil
+	}
+
+	// Look for the working directory within the package's files.
+	if rel, ok := engine.command.Definition.PackageWorkingDirectory(); ok {
+		if engine.packageDir == "" {
+			return "", release, fmt.Errorf("the \"%s\" working directory refers to package files that are not available", dirID)
+		}
+		if rel == "" {
+			return engine.packageDir, release, nil
+		}
+		localized, err := filepath.Localize(rel)
+		if err != nil {
+			return "", release, fmt.Errorf("localization of the working directory path failed: %w", err)
+		}
+		path := filepath.Join(engine.packageDir, localized)
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", release, err
+		}
+		if !fi.IsDir() {
+			return "", release, fmt.Errorf("the \"%s\" working directory is not a directory", dirID)
+		}
+		return path, release, nil
 	}
 
 	dirRef, err := engine.deployment.Resources.FileSystem.ResolveDirectory(dirID)
 	if err != nil {
-		return "", err
+		return "", release, err
 	}
 
 	dir, err := localfs.OpenDir(dirRef)
 	if err != nil {
-		return "", err
+		return "", release, err
 	}
 	defer dir.Close()
 
-	return dir.Path(), nil
+	return dir.Path(), release, nil
+}
+
+// evaluatePostConditions evaluates the command's post-conditions. It
+// returns a non-nil error if any of them could not be evaluated or were not
+// met.
+func (engine *commandEngine) evaluatePostConditions() (passed, failed lbdeploy.ConditionList, err error) {
+	ce := NewConditionEngine(engine.deployment)
+	for i, condition := range engine.command.Definition.PostConditions {
+		result, err := ce.Evaluate(condition)
+		if err != nil {
+			return passed, failed, fmt.Errorf("failed to evaluate post-condition %d: %w", i+1, err)
+		}
+		if result {
+			passed = append(passed, condition)
+		} else {
+			failed = append(failed, condition)
+		}
+	}
+	if len(failed) > 0 {
+		return passed, failed, fmt.Errorf("one or more post-conditions were not met: %s", failed)
+	}
+	return passed, failed, nil
 }
 
 func (engine *commandEngine) buildResult(cmdError error) (result lbdeploy.CommandResult, err error) {
@@ -452,17 +907,26 @@ func (engine *commandEngine) buildResult(cmdError error) (result lbdeploy.Comman
 
 	// If this is an msiexec command, look for an exit code that is well
 	// known.
+	code := msiresult.ExitCode(result.ExitCode)
 	if engine.command.Definition.Type.IsMSI() {
-		code := msiresult.ExitCode(result.ExitCode)
 		if info, found := msiresult.InfoMap[code]; found {
 			result.Info = info
+			result.SystemMessage = code.SystemMessage()
 			if info.OK {
 				err = nil
 			} else {
 				err = code // Return an msiexec exit code.
 			}
-			return
 		}
+		return
+	}
+
+	// Other installers commonly return the Windows Installer's restart exit
+	// codes as well, so recognize them for every command.
+	switch code {
+	case msiresult.SuccessRebootRequired, msiresult.SuccessRebootInitiated:
+		result.Info = code.Info()
+		err = nil
 	}
 
 	return
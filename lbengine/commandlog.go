@@ -0,0 +1,163 @@
+package lbengine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/localfs"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// prepareLog returns the path of a new log file for the command and the
+// arguments that cause the command to write to it. If the command will not
+// be logged, it returns an empty path and no arguments.
+//
+// Msi-based commands with arguments that already include a logging option
+// are not logged, so that the option provided by the author is honored.
+func (engine *commandEngine) prepareLog(args []string) (logPath string, logArgs []string, err error) {
+	def := engine.command.Definition
+	if !def.Log.Enabled(def.Type) {
+		return "", nil, nil
+	}
+	if len(def.Log.Args) == 0 && hasMSILogOption(args) {
+		return "", nil, nil
+	}
+
+	dir, err := stagingfs.OpenDeployment(engine.deployment.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to open the staging directory for the command log: %w", err)
+	}
+	defer dir.Close()
+
+	logPath, err = dir.CommandLogPath(engine.pkg.ID, engine.command.ID, time.Now())
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to prepare the command log directory: %w", err)
+	}
+
+	if len(def.Log.Args) == 0 {
+		return logPath, []string{"/l*v", logPath}, nil
+	}
+
+	for _, arg := range def.Log.Args {
+		logArgs = append(logArgs, strings.ReplaceAll(arg, lbdeploy.LogFilePlaceholder, logPath))
+	}
+	return logPath, logArgs, nil
+}
+
+// collectLog uploads and applies the retention policy to the log file at
+// logPath, which was written by the command. It records the outcome as an
+// event. Problems with the log do not affect the outcome of the command.
+func (engine *commandEngine) collectLog(logPath string, failed bool) {
+	def := engine.command.Definition.Log
+
+	event := lbdeployevent.CommandLogCollected{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Package:     engine.pkg.ID,
+		Command:     engine.command.ID,
+		LogFile:     logPath,
+	}
+
+	// If the command didn't write a log, there's nothing to collect.
+	if _, err := os.Stat(logPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			event.Missing = true
+		} else {
+			event.Err = err
+		}
+	} else {
+		event.Uploaded, event.Retained, event.Pruned, event.Err = engine.processLog(def, logPath, failed)
+	}
+
+	engine.events.Record(event)
+}
+
+// processLog uploads the log file at logPath and applies the retention
+// policy to it.
+func (engine *commandEngine) processLog(def lbdeploy.CommandLog, logPath string, failed bool) (uploaded string, retained bool, pruned []string, err error) {
+	// Upload a copy of the log, if requested.
+	if def.Upload != "" {
+		uploaded, err = engine.uploadLog(def.Upload, logPath)
+		if err != nil {
+			return "", true, nil, fmt.Errorf("the log could not be uploaded to the \"%s\" directory: %w", def.Upload, err)
+		}
+	}
+
+	// Remove the log if the retention policy calls for it.
+	if !def.Retention.Retain(failed) {
+		if err := os.Remove(logPath); err != nil {
+			return uploaded, true, nil, fmt.Errorf("the log could not be removed: %w", err)
+		}
+		return uploaded, false, nil, nil
+	}
+
+	// Remove older logs for the command, if a limit has been set.
+	if def.Keep > 0 {
+		dir, err := stagingfs.OpenDeployment(engine.deployment.ID)
+		if err != nil {
+			return uploaded, true, nil, fmt.Errorf("unable to open the staging directory to prune older logs: %w", err)
+		}
+		defer dir.Close()
+
+		pruned, err = dir.PruneCommandLogs(engine.pkg.ID, engine.command.ID, def.Keep)
+		if err != nil {
+			return uploaded, true, pruned, err
+		}
+	}
+
+	return uploaded, true, pruned, nil
+}
+
+// uploadLog copies the log file at logPath to the given directory
+// resource. The name of the copy includes the computer name, so that logs
+// from many computers can be collected in one place. It returns the path
+// of the copy.
+func (engine *commandEngine) uploadLog(dirID lbdeploy.DirectoryResourceID, logPath string) (string, error) {
+	ref, err := engine.deployment.Resources.FileSystem.ResolveDirectory(dirID)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := localfs.OpenDir(ref)
+	if err != nil {
+		return "", err
+	}
+	defer dir.Close()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("the computer name could not be determined: %w", err)
+	}
+
+	parts := []string{hostname, string(engine.deployment.ID)}
+	if engine.pkg.ID != "" {
+		parts = append(parts, string(engine.pkg.ID))
+	}
+	parts = append(parts, filepath.Base(logPath))
+
+	dest := filepath.Join(dir.Path(), strings.Join(parts, "."))
+	if err := copyFileContents(logPath, dest); err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// hasMSILogOption returns true if args include a logging option for
+// msiexec, such as /l*v or /log.
+func hasMSILogOption(args []string) bool {
+	for _, arg := range args {
+		if len(arg) >= 2 && (arg[0] == '/' || arg[0] == '-') && (arg[1] == 'l' || arg[1] == 'L') {
+			return true
+		}
+	}
+	return false
+}
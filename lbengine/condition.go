@@ -2,7 +2,9 @@ package lbengine
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"time"
 
 	"github.com/gentlemanautomaton/winobj/winmutex"
 	"github.com/leafbridge/leafbridge-deploy/idset"
@@ -10,8 +12,13 @@ import (
 	"github.com/leafbridge/leafbridge-deploy/lbvalue"
 	"github.com/leafbridge/leafbridge-deploy/localfs"
 	"github.com/leafbridge/leafbridge-deploy/localregistry"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 )
 
+// reachabilityTimeout is the amount of time that a host reachable
+// condition waits for a connection to be established.
+const reachabilityTimeout = 3 * time.Second
+
 // conditionSet keeps track of a set of conditions as they are evaluated.
 type conditionSet = idset.SetOf[lbdeploy.ConditionID]
 
@@ -172,7 +179,7 @@ func (engine ConditionEngine) evaluate(id lbdeploy.ConditionID, condition lbdepl
 				if err != nil {
 					return false, conditionSelfError(id, condition, err)
 				}
-				result, err := lbvalue.TryCompare(value, condition.Value)
+				result, err := lbvalue.TryCompareWithMode(value, condition.Value, condition.VersionMode)
 				if err != nil {
 					return false, conditionSelfError(id, condition, err)
 				}
@@ -194,6 +201,57 @@ func (engine ConditionEngine) evaluate(id lbdeploy.ConditionID, condition lbdepl
 			}
 			defer dir.Close()
 			return true, nil
+		case lbdeploy.ConditionTypeDirectoryEmpty, lbdeploy.ConditionTypeDirectoryContains, lbdeploy.ConditionTypeDirectorySize:
+			ref, err := engine.deployment.Resources.FileSystem.ResolveDirectory(lbdeploy.DirectoryResourceID(condition.Subject))
+			if err != nil {
+				return false, conditionSelfError(id, condition, err)
+			}
+			dir, err := localfs.OpenDir(ref)
+			if err != nil {
+				if os.IsNotExist(err) {
+					// A missing directory is empty, with a size of zero.
+					switch condition.Type {
+					case lbdeploy.ConditionTypeDirectoryEmpty:
+						return true, nil
+					case lbdeploy.ConditionTypeDirectorySize:
+						result, err := lbvalue.TryCompare(lbvalue.Int64(0), condition.Value)
+						if err != nil {
+							return false, conditionSelfError(id, condition, err)
+						}
+						return condition.Comparison.Evaluate(result), nil
+					default:
+						return false, nil
+					}
+				}
+				return false, conditionSelfError(id, condition, err)
+			}
+			defer dir.Close()
+			switch condition.Type {
+			case lbdeploy.ConditionTypeDirectoryEmpty:
+				empty, err := dir.IsEmpty()
+				if err != nil {
+					return false, conditionSelfError(id, condition, err)
+				}
+				return empty, nil
+			case lbdeploy.ConditionTypeDirectoryContains:
+				found, err := dir.Contains(condition.Value.String())
+				if err != nil {
+					return false, conditionSelfError(id, condition, err)
+				}
+				return found, nil
+			case lbdeploy.ConditionTypeDirectorySize:
+				size, err := dir.Size()
+				if err != nil {
+					return false, conditionSelfError(id, condition, err)
+				}
+				result, err := lbvalue.TryCompare(lbvalue.Int64(size), condition.Value)
+				if err != nil {
+					return false, conditionSelfError(id, condition, err)
+				}
+				return condition.Comparison.Evaluate(result), nil
+			default:
+				panic("unhandled condition type")
+			}
 		case lbdeploy.ConditionTypeFileExists:
 			ref, err := engine.deployment.Resources.FileSystem.ResolveFile(lbdeploy.FileResourceID(condition.Subject))
 			if err != nil {
@@ -219,6 +277,44 @@ func (engine ConditionEngine) evaluate(id lbdeploy.ConditionID, condition lbdepl
 				return false, conditionSelfError(id, condition, fmt.Errorf("file \"%s\": the path exists but it is not a regular file", condition.Subject))
 			}
 			return false, conditionSelfError(id, condition, fmt.Errorf("file \"%s\": the \"%s\" path exists but it is not a regular file", condition.Subject, path))
+		case lbdeploy.ConditionTypeDeferralAvailable:
+			available, err := deferralAvailable(engine.deployment)
+			if err != nil {
+				return false, conditionSelfError(id, condition, err)
+			}
+			return available, nil
+		case lbdeploy.ConditionTypeDeferralDeadlinePassed:
+			return engine.deployment.Deferral.DeadlinePassed(time.Now()), nil
+		case lbdeploy.ConditionTypeFactExists, lbdeploy.ConditionTypeFactComparison:
+			// Facts that could not be gathered are treated as absent.
+			facts, _ := sysfacts.Snapshot()
+			value, found := facts.Get(sysfacts.Name(condition.Subject))
+			switch condition.Type {
+			case lbdeploy.ConditionTypeFactExists:
+				return found, nil
+			case lbdeploy.ConditionTypeFactComparison:
+				if !found {
+					return false, nil
+				}
+				result, err := lbvalue.TryCompareWithMode(value, condition.Value, condition.VersionMode)
+				if err != nil {
+					return false, conditionSelfError(id, condition, err)
+				}
+				return condition.Comparison.Evaluate(result), nil
+			default:
+				panic("unhandled condition type")
+			}
+		case lbdeploy.ConditionTypeHostReachable:
+			address, err := lbdeploy.HostAddress(condition.Subject)
+			if err != nil {
+				return false, conditionSelfError(id, condition, err)
+			}
+			conn, err := net.DialTimeout("tcp", address, reachabilityTimeout)
+			if err != nil {
+				return false, nil
+			}
+			conn.Close()
+			return true, nil
 		default:
 			return false, conditionSelfError(id, condition, fmt.Errorf("unrecognized condition type: %s", condition.Type))
 		}
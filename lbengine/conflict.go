@@ -0,0 +1,161 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/usersession"
+)
+
+// defaultProcessConflictTimeout is used when a process conflict policy
+// waits or prompts without specifying a timeout.
+const defaultProcessConflictTimeout = 5 * time.Minute
+
+// processConflictPollInterval is how often running processes are checked
+// while waiting for them to exit.
+const processConflictPollInterval = 5 * time.Second
+
+// resolveProcessConflicts applies the conflict policies of any running
+// processes that belong to the applications the command is about to
+// install or uninstall. It returns an error if a conflict could not be
+// resolved.
+func (engine *commandEngine) resolveProcessConflicts(ctx context.Context) error {
+	// Collect the processes of the applications that are changing.
+	var processes []lbdeploy.ProcessResourceID
+	seen := make(map[lbdeploy.ProcessResourceID]bool)
+	for _, list := range []lbdeploy.AppList{engine.apps.ToInstall, engine.apps.ToUninstall} {
+		for _, app := range list {
+			for _, process := range engine.deployment.Apps[app].Processes {
+				if !seen[process] {
+					seen[process] = true
+					processes = append(processes, process)
+				}
+			}
+		}
+	}
+
+	for _, id := range processes {
+		process, found := engine.deployment.Resources.Processes[id]
+		if !found {
+			return fmt.Errorf("the \"%s\" process does not exist within the \"%s\" deployment", id, engine.deployment.ID)
+		}
+
+		running, err := NumberOfRunningProcesses(process.Match)
+		if err != nil {
+			return fmt.Errorf("failed to determine whether the \"%s\" process is running: %w", id, err)
+		}
+		if running == 0 {
+			continue
+		}
+
+		started := time.Now()
+		response, stopped, err := engine.resolveProcessConflict(ctx, id, process)
+
+		engine.events.Record(lbdeployevent.ProcessConflict{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Package:     engine.pkg.ID,
+			Command:     engine.command.ID,
+			Process:     id,
+			Action:      processConflictAction(process.Conflict),
+			Running:     running,
+			Response:    response,
+			Stopped:     stopped,
+			Started:     started,
+			Finished:    time.Now(),
+			Err:         err,
+		})
+
+		if err != nil {
+			return fmt.Errorf("the \"%s\" process conflicts with %s: %w", id, engine.cmdDesc(), err)
+		}
+	}
+
+	return nil
+}
+
+// resolveProcessConflict applies the conflict policy of a running process.
+// It returns the user's response if the user was prompted, and the number of
+// processes that were stopped.
+func (engine *commandEngine) resolveProcessConflict(ctx context.Context, id lbdeploy.ProcessResourceID, process lbdeploy.ProcessResource) (response string, stopped int, err error) {
+	policy := process.Conflict
+	timeout := time.Duration(policy.Timeout)
+	if timeout == 0 {
+		timeout = defaultProcessConflictTimeout
+	}
+
+	switch processConflictAction(policy) {
+	case lbdeploy.ProcessConflictWait:
+		return "", 0, waitForProcessExit(ctx, process.Match, timeout)
+	case lbdeploy.ProcessConflictPrompt:
+		name := process.Description
+		if name == "" {
+			name = string(id)
+		}
+		message := policy.Message
+		if message == "" {
+			message = fmt.Sprintf("%s must be closed so that software can be updated. Save your work, then press OK to close it.", name)
+		}
+		answer, err := usersession.Prompt("Software Update", message, timeout)
+		switch {
+		case errors.Is(err, usersession.ErrNoUser):
+			response = "not signed in"
+		case err != nil:
+			return "", 0, fmt.Errorf("the user could not be prompted: %w", err)
+		case answer == usersession.ResponseAccepted:
+			response = answer.String()
+		default:
+			return answer.String(), 0, fmt.Errorf("the user did not agree to close the process (%s)", answer)
+		}
+		stopped, err = StopProcesses(process.Match)
+		return response, stopped, err
+	case lbdeploy.ProcessConflictForceClose:
+		stopped, err = StopProcesses(process.Match)
+		return "", stopped, err
+	default:
+		return "", 0, errors.New("the process is running")
+	}
+}
+
+// waitForProcessExit waits until no processes match the given criteria. It
+// returns an error if they are still running when the timeout elapses.
+func waitForProcessExit(ctx context.Context, match lbdeploy.ProcessMatch, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(processConflictPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("the process was still running after %s", timeout)
+		case <-ticker.C:
+		}
+
+		running, err := NumberOfRunningProcesses(match)
+		if err != nil {
+			return err
+		}
+		if running == 0 {
+			return nil
+		}
+	}
+}
+
+// processConflictAction returns the action of policy, or the default action
+// if the policy does not specify one.
+func processConflictAction(policy lbdeploy.ProcessConflictPolicy) lbdeploy.ProcessConflictAction {
+	if policy.Action == "" {
+		return lbdeploy.ProcessConflictFail
+	}
+	return policy.Action
+}
@@ -0,0 +1,76 @@
+package lbengine
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxDownloadSize is the maximum number of bytes that are accepted
+// for a download when neither the size nor the maximum size of the file
+// is known.
+const defaultMaxDownloadSize = 32 << 30 // 32 GiB
+
+// downloadLimit returns the maximum number of bytes that may be accepted
+// for a file with the given expected size and maximum size. Either may be
+// zero if it is not known.
+func downloadLimit(expectedSize, maxSize int64) int64 {
+	switch {
+	case expectedSize > 0:
+		return expectedSize
+	case maxSize > 0:
+		return maxSize
+	default:
+		return defaultMaxDownloadSize
+	}
+}
+
+// contentRange is a parsed HTTP Content-Range header for a byte range.
+type contentRange struct {
+	Start int64
+	End   int64 // Inclusive
+	Total int64 // -1 if unknown
+}
+
+// Length returns the number of bytes in the range.
+func (r contentRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// parseContentRange parses the value of an HTTP Content-Range header in
+// the form "bytes start-end/total", where total may be "*".
+func parseContentRange(value string) (contentRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !ok {
+		return contentRange{}, errors.New("the content range is not a byte range")
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return contentRange{}, errors.New("the content range is missing its total length")
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return contentRange{}, errors.New("the content range is missing its span")
+	}
+
+	var (
+		r   contentRange
+		err error
+	)
+	if r.Start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return contentRange{}, errors.New("the content range has an invalid start")
+	}
+	if r.End, err = strconv.ParseInt(last, 10, 64); err != nil {
+		return contentRange{}, errors.New("the content range has an invalid end")
+	}
+	if r.Start < 0 || r.End < r.Start {
+		return contentRange{}, errors.New("the content range has an invalid span")
+	}
+	if total == "*" {
+		r.Total = -1
+	} else if r.Total, err = strconv.ParseInt(total, 10, 64); err != nil || r.Total <= r.End {
+		return contentRange{}, errors.New("the content range has an invalid total length")
+	}
+
+	return r, nil
+}
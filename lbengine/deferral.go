@@ -0,0 +1,190 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/usersession"
+	"golang.org/x/sys/windows/registry"
+)
+
+// ErrDeferred is returned when the user has postponed a deployment.
+var ErrDeferred = errors.New("the deployment was deferred by the user")
+
+// defaultDeferralTimeout is how long to wait for the user to respond to a
+// deferral prompt when the deferral policy doesn't specify a timeout.
+const defaultDeferralTimeout = 5 * time.Minute
+
+// deferralRegistryPath is the location beneath HKEY_LOCAL_MACHINE where the
+// deferral state of each deployment is stored.
+const deferralRegistryPath = `SOFTWARE\LeafBridge\Deferrals`
+
+// deferralState records how many times a deployment has been deferred.
+type deferralState struct {
+	Count int
+	Last  time.Time
+}
+
+// readDeferralState returns the deferral state of a deployment. It returns
+// a zero state if the deployment has never been deferred.
+func readDeferralState(dep lbdeploy.DeploymentID) (deferralState, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, deferralRegistryPath+`\`+string(dep), registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return deferralState{}, nil
+		}
+		return deferralState{}, err
+	}
+	defer key.Close()
+
+	var state deferralState
+
+	count, _, err := key.GetIntegerValue("Count")
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return deferralState{}, err
+	}
+	state.Count = int(count)
+
+	if last, _, err := key.GetStringValue("Last"); err == nil {
+		state.Last, _ = time.Parse(time.RFC3339, last)
+	}
+
+	return state, nil
+}
+
+// writeDeferralState records the deferral state of a deployment.
+func writeDeferralState(dep lbdeploy.DeploymentID, state deferralState) error {
+	key, _, err := registry.CreateKey(registry.LOCAL_MACHINE, deferralRegistryPath+`\`+string(dep), registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	if err := key.SetDWordValue("Count", uint32(state.Count)); err != nil {
+		return err
+	}
+	return key.SetStringValue("Last", state.Last.Format(time.RFC3339))
+}
+
+// clearDeferralState removes the deferral state of a deployment, so that
+// the next rollout of it starts with a full set of deferrals.
+func clearDeferralState(dep lbdeploy.DeploymentID) error {
+	err := registry.DeleteKey(registry.LOCAL_MACHINE, deferralRegistryPath+`\`+string(dep))
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// deferralAvailable returns true if the deployment's deferral policy
+// permits the user to defer it again.
+func deferralAvailable(dep lbdeploy.Deployment) (bool, error) {
+	state, err := readDeferralState(dep.ID)
+	if err != nil {
+		return false, err
+	}
+	return dep.Deferral.Permits(state.Count, time.Now()), nil
+}
+
+// promptDeferral asks the user signed in to the system whether the
+// deployment may proceed. If the user postpones it, the deferral is
+// recorded and ErrDeferred is returned, which stops the flow.
+//
+// If no more deferrals are permitted or no user is signed in, the
+// deployment proceeds without asking.
+func (engine *actionEngine) promptDeferral(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	policy := engine.deployment.Deferral
+
+	state, err := readDeferralState(engine.deployment.ID)
+	if err != nil {
+		return fmt.Errorf("the deferral state of the deployment could not be read: %w", err)
+	}
+
+	event := lbdeployevent.DeferralPrompted{
+		Deployment:   engine.deployment.ID,
+		Flow:         engine.flow.ID,
+		ActionIndex:  engine.action.Index,
+		ActionType:   engine.action.Definition.Type,
+		Deferrals:    state.Count,
+		MaxDeferrals: policy.MaxDeferrals,
+		Deadline:     policy.Deadline,
+	}
+
+	err = func() error {
+		now := time.Now()
+
+		// If the user has used up their deferrals, proceed.
+		if !policy.Permits(state.Count, now) {
+			event.Outcome = lbdeployevent.DeferralForced
+			return clearDeferralState(engine.deployment.ID)
+		}
+
+		// Ask the user.
+		timeout := time.Duration(policy.Timeout)
+		if timeout == 0 {
+			timeout = defaultDeferralTimeout
+		}
+		response, err := usersession.Prompt("Software Update", deferralMessage(engine.deployment, state.Count), timeout)
+		switch {
+		case errors.Is(err, usersession.ErrNoUser):
+			event.Outcome = lbdeployevent.DeferralNoUser
+			return clearDeferralState(engine.deployment.ID)
+		case err != nil:
+			return fmt.Errorf("the user could not be prompted: %w", err)
+		case response == usersession.ResponseAccepted:
+			event.Outcome = lbdeployevent.DeferralAccepted
+			return clearDeferralState(engine.deployment.ID)
+		}
+
+		// Record the deferral.
+		state.Count++
+		state.Last = now
+		event.Outcome = lbdeployevent.DeferralDeferred
+		event.Deferrals = state.Count
+		if err := writeDeferralState(engine.deployment.ID, state); err != nil {
+			return fmt.Errorf("the deferral could not be recorded: %w", err)
+		}
+		return ErrDeferred
+	}()
+
+	if !errors.Is(err, ErrDeferred) {
+		event.Err = err
+	}
+	engine.events.Record(event)
+
+	return err
+}
+
+// deferralMessage returns the message shown to users when prompting them
+// to defer a deployment.
+func deferralMessage(dep lbdeploy.Deployment, deferrals int) string {
+	message := dep.Deferral.Message
+	if message == "" {
+		name := dep.Name
+		if name == "" {
+			name = string(dep.ID)
+		}
+		message = fmt.Sprintf("%s is ready to be installed. Save your work, then press OK to install it now, or press Cancel to postpone it.", name)
+	}
+
+	switch remaining := dep.Deferral.Remaining(deferrals); {
+	case remaining == 1:
+		message += "\n\nYou may postpone this 1 more time."
+	case remaining > 1:
+		message += fmt.Sprintf("\n\nYou may postpone this %d more times.", remaining)
+	}
+
+	if !dep.Deferral.Deadline.IsZero() {
+		message += fmt.Sprintf("\n\nIt will be installed automatically after %s.", dep.Deferral.Deadline.Local().Format("Monday, January 2 at 3:04 PM"))
+	}
+
+	return message
+}
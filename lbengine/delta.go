@@ -0,0 +1,230 @@
+package lbengine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/msdelta"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// errDeltaBaseNotStaged is returned when the base of a delta is not staged
+// on the local system.
+var errDeltaBaseNotStaged = errors.New("the base package file is not staged")
+
+// applyDelta attempts to produce the package file by applying one of the
+// package's deltas to a previous version of the package that is still
+// staged. The produced content is written to file and to verifier.
+//
+// It returns true if a delta was applied. The caller is responsible for
+// verifying the produced file. If no delta could be applied, file and
+// verifier are left empty and the package should be downloaded in full.
+func (engine *downloadEngine) applyDelta(ctx context.Context, pkg packageData, file stagingfs.PackageFile, verifier *FileVerifier) bool {
+	for _, delta := range pkg.Definition.Deltas {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		// Look for the base of the delta. Deltas whose base isn't staged
+		// are skipped silently, since that's the common case for systems
+		// that skipped a release.
+		basePath, err := engine.findDeltaBase(ctx, pkg, delta)
+		if errors.Is(err, errDeltaBaseNotStaged) {
+			continue
+		}
+
+		started := time.Now()
+		if err == nil {
+			err = engine.applyDeltaFrom(ctx, pkg, delta, basePath, file, verifier)
+		}
+
+		// Record the outcome of the attempt.
+		engine.events.Record(lbdeployevent.DeltaApplied{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			FileName:    file.Name,
+			Path:        file.Path,
+			BasePath:    basePath,
+			Delta:       delta,
+			Started:     started,
+			Stopped:     time.Now(),
+			Err:         err,
+		})
+
+		if err == nil {
+			return true
+		}
+
+		// Discard anything that was written before the failure.
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+		if err := file.Truncate(0); err != nil {
+			return false
+		}
+		verifier.Reset()
+	}
+
+	return false
+}
+
+// findDeltaBase returns the path of the staged package file that the delta
+// applies to, after confirming that its content matches the delta's base.
+//
+// It returns errDeltaBaseNotStaged if the base is not staged.
+func (engine *downloadEngine) findDeltaBase(ctx context.Context, pkg packageData, delta lbdeploy.PackageDelta) (string, error) {
+	deployDir, err := stagingfs.OpenExistingDeployment(engine.deployment.ID)
+	if err != nil {
+		return "", errDeltaBaseNotStaged
+	}
+	defer deployDir.Close()
+
+	packageDir, err := deployDir.OpenExistingPackage(lbdeploy.PackageContent{
+		ID:          pkg.ID,
+		PrimaryHash: delta.Base.Primary(),
+	})
+	if err != nil {
+		return "", errDeltaBaseNotStaged
+	}
+	defer packageDir.Close()
+
+	base, err := packageDir.OpenExistingFile(pkg.Definition)
+	if err != nil {
+		return "", errDeltaBaseNotStaged
+	}
+	defer base.Close()
+
+	// Hash the base and make sure that it matches.
+	baseVerifier, err := NewFileVerifier(delta.Base.Types()...)
+	if err != nil {
+		return "", err
+	}
+	if _, err := baseVerifier.ReadFrom(newReaderWithContext(ctx, base)); err != nil {
+		return "", fmt.Errorf("failed to verify the base package file: %w", err)
+	}
+	actual := baseVerifier.State().Hashes
+	for hashType, expected := range delta.Base {
+		if !bytes.Equal(actual[hashType], expected) {
+			return "", fmt.Errorf("the staged base package file \"%s\" does not match its %s hash", base.Path, hashType)
+		}
+	}
+
+	return base.Path, nil
+}
+
+// applyDeltaFrom downloads the delta, verifies it and applies it to the
+// base package file at basePath. The produced content is written to file
+// and to verifier.
+func (engine *downloadEngine) applyDeltaFrom(ctx context.Context, pkg packageData, delta lbdeploy.PackageDelta, basePath string, file stagingfs.PackageFile, verifier *FileVerifier) error {
+	// Download the delta alongside the package file.
+	deltaPath, err := engine.downloadDelta(ctx, pkg, delta, file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(deltaPath)
+
+	// Apply the delta to a separate file, then copy the result into the
+	// package file so that it passes through the verifier.
+	targetPath := file.Path + ".patched"
+	defer os.Remove(targetPath)
+
+	switch delta.Format {
+	case lbdeploy.DeltaFormatMSDelta:
+		if err := msdelta.Apply(basePath, deltaPath, targetPath); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("the delta format \"%s\" is not supported", delta.Format)
+	}
+
+	target, err := os.Open(targetPath)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	if _, err := io.Copy(io.MultiWriter(file, verifier), newReaderWithContext(ctx, target)); err != nil {
+		return fmt.Errorf("failed to copy the file produced by the delta: %w", err)
+	}
+	if engine.written != nil {
+		engine.written(verifier.Size())
+	}
+
+	return nil
+}
+
+// downloadDelta downloads the delta to a file alongside the package file,
+// resuming a previous download of it if possible, and verifies it. It
+// returns the path of the downloaded delta.
+func (engine *downloadEngine) downloadDelta(ctx context.Context, pkg packageData, delta lbdeploy.PackageDelta, file stagingfs.PackageFile) (string, error) {
+	name := delta.FileName(pkg.Definition)
+	path := filepath.Join(filepath.Dir(file.Path), name)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	deltaFile := stagingfs.PackageFile{
+		Name: name,
+		Path: path,
+		File: f,
+	}
+
+	// The delta isn't part of the package file, so don't report its
+	// progress to anything that is watching the package file.
+	written := engine.written
+	engine.written = nil
+	defer func() { engine.written = written }()
+
+	// Prepare a verifier for the delta and read in anything that was
+	// downloaded previously.
+	deltaVerifier, err := NewFileVerifier(delta.Attributes.Hashes.Types()...)
+	if err != nil {
+		return "", err
+	}
+	if _, err := deltaVerifier.ReadFrom(newReaderWithContext(ctx, f)); err != nil {
+		return "", err
+	}
+
+	// Download the remainder of the delta.
+	if deltaVerifier.Size() < delta.Attributes.Size {
+		sources := engine.state.sources.Order(engine.deployment.ID, engine.availableSources(delta.Sources, deltaFile))
+		if len(sources) == 0 {
+			return "", errors.New("none of the delta's sources are available")
+		}
+		var errs []error
+		for _, source := range sources {
+			err := engine.downloadPackageFromSource(ctx, source, deltaFile, deltaVerifier, delta.Attributes.Size, 0)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err)
+		}
+		if err := errors.Join(errs...); err != nil {
+			return "", err
+		}
+	}
+
+	// Verify the delta before it is applied.
+	if !lbdeploy.EqualFileAttributes(delta.Attributes, deltaVerifier.State()) {
+		f.Truncate(0)
+		deltaFile.RemoveHashState()
+		return "", errors.New("the downloaded delta did not pass verification")
+	}
+	deltaFile.RemoveHashState()
+
+	return path, nil
+}
@@ -2,48 +2,90 @@ package lbengine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
 	"github.com/leafbridge/leafbridge-deploy/lbevent"
 )
 
 // DeploymentEngine is a LeafBridge engine that is responsible for invocation
 // of deployments.
 type DeploymentEngine struct {
-	deployment lbdeploy.Deployment
-	events     lbevent.Recorder
-	force      bool
-	state      *engineState
+	deployment  lbdeploy.Deployment
+	events      lbevent.Recorder
+	force       ForceScope
+	parallelism int
+	state       *engineState
 }
 
 // NewDeploymentEngine returns a new LeafBridge deployment engine for the
 // given deployment and options.
 func NewDeploymentEngine(deployment lbdeploy.Deployment, opts Options) DeploymentEngine {
 	return DeploymentEngine{
-		deployment: deployment,
-		events:     opts.Events,
-		force:      opts.Force,
-		state:      newEngineState(),
+		deployment:  deployment,
+		events:      opts.Events,
+		force:       opts.Force,
+		parallelism: opts.Parallelism,
+		state:       newEngineState(opts.LockWait, opts.Resume, opts.Heartbeat, opts.PeerSharing, opts.Filter),
 	}
 }
 
-// Invoke executes a flow within a LeafBridge deployment.
-func (engine DeploymentEngine) Invoke(ctx context.Context, flow lbdeploy.FlowID) error {
+// Invoke executes one or more flows within a LeafBridge deployment.
+//
+// Flows that the requested flows depend on are added to the set and invoked
+// first. Each flow is invoked at most once.
+//
+// When more than one flow is provided, the flows are executed in order and
+// share the same engine state. The locks required by all of the flows are
+// acquired before the first flow starts and are held until the last flow
+// finishes. If a flow fails, the remaining flows are skipped unless the
+// deployment's behavior is to continue on error. Flows that have no locks or
+// resources in common may be invoked concurrently, as permitted by the
+// engine's parallelism option. A summary of all of the
+// flows is recorded when they have finished.
+func (engine DeploymentEngine) Invoke(ctx context.Context, flows ...lbdeploy.FlowID) (err error) {
 	// TODO: Generate some sort of random UUID for the deployment invocation
 	// that can be used for log analysis?
 
+	if len(flows) == 0 {
+		return fmt.Errorf("no flows were specified for the \"%s\" deployment", engine.deployment.ID)
+	}
+
 	// Ensure that the deployment is valid.
 	if err := engine.deployment.Validate(); err != nil {
 		return err
 	}
 
-	// Find the requested flow within the deployment.
-	definition, found := engine.deployment.Flows[flow]
-	if !found {
-		return fmt.Errorf("the flow \"%s\" does not exist within the \"%s\" deployment", flow, engine.deployment.ID)
+	// Make sure the process has the privileges the deployment requires.
+	if err := engine.checkElevation(); err != nil {
+		return err
+	}
+
+	// Determine the order in which the flows will be invoked, including
+	// any flows that they depend on.
+	flows, err = engine.deployment.Flows.Order(flows...)
+	if err != nil {
+		return fmt.Errorf("the requested flows cannot be invoked within the \"%s\" deployment: %w", engine.deployment.ID, err)
 	}
 
+	// Find the requested flows within the deployment.
+	definitions := make([]lbdeploy.Flow, len(flows))
+	for i, flow := range flows {
+		definition, found := engine.deployment.Flows[flow]
+		if !found {
+			return fmt.Errorf("the flow \"%s\" does not exist within the \"%s\" deployment", flow, engine.deployment.ID)
+		}
+		definitions[i] = definition
+	}
+
+	// Report the changes made by the deployment when we are finished.
+	defer func() {
+		engine.recordResult(err)
+	}()
+
 	// Release resources when we are finished.
 	defer func() {
 		// Close and remove any extracted files in temporary directories.
@@ -62,17 +104,118 @@ func (engine DeploymentEngine) Invoke(ctx context.Context, flow lbdeploy.FlowID)
 		engine.state.locks.CloseAll()
 	}()
 
-	// Invoke the requested flow.
-	fe := flowEngine{
-		deployment: engine.deployment,
-		flow: flowData{
-			ID:         flow,
-			Definition: definition,
-		},
-		events: engine.events,
-		force:  engine.force,
-		state:  engine.state,
+	// Invoke a single flow directly.
+	if len(flows) == 1 {
+		fe := flowEngine{
+			deployment: engine.deployment,
+			flow: flowData{
+				ID:         flows[0],
+				Definition: definitions[0],
+			},
+			events: engine.events,
+			force:  engine.force,
+			state:  engine.state,
+		}
+
+		if err := fe.Invoke(ctx); err != nil {
+			return err
+		}
+		if err := engine.restartAfterFlow(flows[0]); err != nil {
+			return err
+		}
+		return engine.restartAfterDeployment()
 	}
 
-	return fe.Invoke(ctx)
+	// Acquire the locks for all of the flows up front, so that they are held
+	// for the entire sequence. Each flow will still lock its own group, but
+	// because the locks are reentrant it will succeed without contention.
+	{
+		var (
+			locks  []lbdeploy.LockID
+			owners = make(map[lbdeploy.LockID]lbdeploy.FlowID)
+		)
+		for i, definition := range definitions {
+			for _, lock := range definition.Locks {
+				if _, seen := owners[lock]; !seen {
+					owners[lock] = flows[i]
+					locks = append(locks, lock)
+				}
+			}
+		}
+
+		if len(locks) > 0 {
+			group, err := engine.state.locks.Create(engine.deployment.Resources, locks...)
+			if err != nil {
+				return fmt.Errorf("the \"%s\" deployment failed to prepare its lock group: %w", engine.deployment.ID, err)
+			}
+
+			onWait := func(lock lbdeploy.LockID, timeout time.Duration) {
+				engine.events.Record(lbdeployevent.FlowLockWaiting{
+					Deployment: engine.deployment.ID,
+					Flow:       owners[lock],
+					Lock:       lock,
+					Timeout:    timeout,
+				})
+			}
+			if err := group.Lock(ctx, onWait); err != nil {
+				// Find out which lock failed and which flow needed it.
+				var lockErr LockError
+				if errors.As(err, &lockErr) {
+					engine.events.Record(lbdeployevent.FlowLockNotAcquired{
+						Deployment: engine.deployment.ID,
+						Flow:       owners[lockErr.LockID],
+						Lock:       lockErr.LockID,
+						Err:        err,
+					})
+				}
+				return fmt.Errorf("the \"%s\" deployment failed to acquire locks for its flows: %w", engine.deployment.ID, err)
+			}
+			defer group.Unlock()
+		}
+	}
+
+	// Invoke each flow in order, running independent flows concurrently
+	// if permitted.
+	summary := lbdeployevent.DeploymentSummary{
+		Deployment: engine.deployment.ID,
+		Started:    time.Now(),
+	}
+
+	var errs []error
+	summary.Flows, errs = engine.invokeFlows(ctx, flows, definitions)
+
+	if err := ctx.Err(); err != nil && len(errs) == 0 {
+		errs = append(errs, err)
+	}
+
+	// Schedule a restart if one is needed and the reboot policy calls for it.
+	if len(errs) == 0 {
+		if err := engine.restartAfterDeployment(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Record a summary of all of the flows.
+	summary.Stopped = time.Now()
+	summary.Err = errors.Join(errs...)
+	engine.events.Record(summary)
+
+	return summary.Err
+}
+
+// recordResult records the changes made by the deployment and whether a
+// restart of the system is needed to finish activating them.
+func (engine DeploymentEngine) recordResult(err error) {
+	// Failure to read the restart indicators is not fatal, and only limits
+	// the information that can be reported.
+	pending, _ := pendingRebootIndicators()
+
+	engine.events.Record(lbdeployevent.DeploymentResult{
+		Deployment:       engine.deployment.ID,
+		Apps:             engine.state.changes.Apps(),
+		RebootReasons:    engine.state.reboot.Reasons(),
+		PendingRestart:   pending,
+		RestartInitiated: engine.state.reboot.Initiated(),
+		Err:              err,
+	})
 }
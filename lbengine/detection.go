@@ -0,0 +1,165 @@
+package lbengine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/leafbridge/leafbridge-deploy/idset"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// FlowDetection is the result of evaluating a flow without invoking any of
+// its actions.
+//
+// Applicable is false when one of the flow's constraints failed, in which
+// case the flow would have been skipped. Preconditions holds any of the
+// flow's preconditions that failed, which would have stopped the flow.
+//
+// Apps holds the app changes anticipated by the commands that the flow
+// invokes, including the commands of flows that it starts.
+type FlowDetection struct {
+	Flow          lbdeploy.FlowID
+	Applicable    bool
+	Preconditions lbdeploy.ConditionList
+	Apps          lbdeploy.AppEvaluation
+}
+
+// Compliant returns true if invoking the flow would not be expected to
+// change anything, either because it doesn't apply to the local system or
+// because all of the app installs and uninstalls it performs are already
+// in effect.
+func (d FlowDetection) Compliant() bool {
+	return !d.Applicable || !d.Apps.ActionsNeeded()
+}
+
+// Detect evaluates the constraints, preconditions and app changes of the
+// given flows without invoking any of their actions. It is intended to
+// answer whether a deployment is in compliance, such as for an Intune
+// detection rule.
+//
+// Unlike Invoke, flows that the requested flows depend on are not added.
+func (engine DeploymentEngine) Detect(flows ...lbdeploy.FlowID) ([]FlowDetection, error) {
+	if len(flows) == 0 {
+		return nil, fmt.Errorf("no flows were specified for the \"%s\" deployment", engine.deployment.ID)
+	}
+
+	// Ensure that the deployment is valid.
+	if err := engine.deployment.Validate(); err != nil {
+		return nil, err
+	}
+
+	detections := make([]FlowDetection, 0, len(flows))
+	for _, flow := range flows {
+		detection, err := engine.detectFlow(flow)
+		if err != nil {
+			return nil, err
+		}
+		detections = append(detections, detection)
+	}
+
+	return detections, nil
+}
+
+// detectFlow evaluates a single flow without invoking its actions.
+func (engine DeploymentEngine) detectFlow(flow lbdeploy.FlowID) (FlowDetection, error) {
+	definition, found := engine.deployment.Flows[flow]
+	if !found {
+		return FlowDetection{}, fmt.Errorf("the flow \"%s\" does not exist within the \"%s\" deployment", flow, engine.deployment.ID)
+	}
+
+	detection := FlowDetection{Flow: flow}
+	ce := NewConditionEngine(engine.deployment)
+
+	// Evaluate all constraints for the flow. If any of them fail, the flow
+	// doesn't apply.
+	for i, condition := range definition.Constraints {
+		result, err := ce.Evaluate(condition)
+		if err != nil {
+			return detection, fmt.Errorf("the \"%s\" flow failed to evaluate constraint %d: %w", flow, i+1, err)
+		}
+		if !result {
+			return detection, nil
+		}
+	}
+	detection.Applicable = true
+
+	// Evaluate all preconditions for the flow.
+	for i, condition := range definition.Preconditions {
+		result, err := ce.Evaluate(condition)
+		if err != nil {
+			return detection, fmt.Errorf("the \"%s\" flow failed to evaluate precondition %d: %w", flow, i+1, err)
+		}
+		if !result {
+			detection.Preconditions = append(detection.Preconditions, condition)
+		}
+	}
+
+	// Collect the apps installed and uninstalled by the flow's commands.
+	var installs, uninstalls lbdeploy.AppList
+	seen := make(idset.SetOf[lbdeploy.FlowID])
+	var collect func(actions []lbdeploy.Action)
+	collect = func(actions []lbdeploy.Action) {
+		for _, action := range actions {
+			switch action.Type {
+			case lbdeploy.ActionInvokeCommand:
+				command, found := engine.findCommand(action)
+				if !found {
+					continue
+				}
+				installs = appendMissing(installs, command.Installs)
+				uninstalls = appendMissing(uninstalls, command.Uninstalls)
+			case lbdeploy.ActionTransaction:
+				collect(action.Actions)
+			case lbdeploy.ActionStartFlow:
+				if seen.Contains(action.Flow) {
+					continue
+				}
+				seen.Add(action.Flow)
+				started := engine.deployment.Flows[action.Flow]
+				collect(started.Before)
+				collect(started.Actions)
+				collect(started.After)
+			}
+		}
+	}
+	seen.Add(flow)
+	collect(definition.Before)
+	collect(definition.Actions)
+	collect(definition.After)
+
+	// Determine whether any app changes are anticipated.
+	ae := newCachedAppEngine(engine.deployment, engine.state.apps)
+	apps, err := ae.EvaluateAppChanges(installs, uninstalls)
+	if err != nil {
+		return detection, fmt.Errorf("the evaluation of potential application changes for the \"%s\" flow did not succeed: %w", flow, err)
+	}
+	detection.Apps = apps
+
+	return detection, nil
+}
+
+// findCommand returns the definition of the command invoked by an
+// invoke-command action.
+func (engine DeploymentEngine) findCommand(action lbdeploy.Action) (command lbdeploy.Command, found bool) {
+	if action.Package != "" {
+		pkg, ok := engine.deployment.Resources.Packages[action.Package]
+		if !ok {
+			return lbdeploy.Command{}, false
+		}
+		command, found = pkg.Commands[action.Command]
+		return command, found
+	}
+	command, found = engine.deployment.Commands[action.Command]
+	return command, found
+}
+
+// appendMissing appends the members of apps that are not already present
+// in list.
+func appendMissing(list, apps lbdeploy.AppList) lbdeploy.AppList {
+	for _, app := range apps {
+		if !slices.Contains(list, app) {
+			list = append(list, app)
+		}
+	}
+	return list
+}
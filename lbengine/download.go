@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
@@ -20,7 +23,26 @@ type downloadEngine struct {
 	flow       flowData
 	action     actionData
 	events     lbevent.Recorder
+	force      ForceScope
 	state      *engineState
+
+	// written is called with the size of the file each time data is
+	// written to it or it is reset. It may be nil.
+	written func(size int64)
+
+	// peerSources holds the sources for peers on the local network that
+	// were found to hold the package file.
+	peerSources []lbdeploy.PackageSource
+
+	// transport sends requests according to the proxy settings of the
+	// flow. It is prepared when it is first needed, and is guarded by
+	// transportMutex because requests may be sent concurrently.
+	transport      *http.Transport
+	transportMutex sync.Mutex
+
+	// probes holds the results of probing the package's sources, mapped by
+	// their URLs. It is nil if the sources were not probed.
+	probes map[string]sourceProbe
 }
 
 // DownloadAndVerifyPackage will attempt to download and verify a package
@@ -40,10 +62,26 @@ func (engine *downloadEngine) DownloadAndVerifyPackage(ctx context.Context, pkg
 		return errors.New("packages must provide at least one file hash for verification")
 	}
 
-	// Move to the beginning of the file.
-	file.Seek(0, io.SeekStart)
+	// If downloads are forced, discard any existing content the first time
+	// the package is prepared.
+	if engine.force.Downloads && engine.state.startForcedDownload(pkg.ID) {
+		if fi, err := file.Stat(); err == nil && fi.Size() > 0 {
+			if err := engine.resetFileDownload(lbdeploy.PackageSource{}, file, verifier, lbdeployevent.DownloadForced); err != nil {
+				return err
+			}
+		}
+	}
+
+	// If the file was partially downloaded, try to pick up where its
+	// hashing left off.
+	offset := engine.restoreHashState(pkg, file, verifier)
 
-	// Read any existing file content into the verifier.
+	// Move to the first byte that hasn't been hashed.
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to verify existing file content for package \"%s\": %w", pkg.ID, err)
+	}
+
+	// Read any remaining file content into the verifier.
 	// This effectively seeks to the end of the file.
 	if _, err := verifier.ReadFrom(newReaderWithContext(ctx, file)); err != nil {
 		return fmt.Errorf("failed to verify existing file content for package \"%s\": %w", pkg.ID, err)
@@ -70,6 +108,8 @@ func (engine *downloadEngine) DownloadAndVerifyPackage(ctx context.Context, pkg
 		if lbdeploy.EqualFileAttributes(pkg.Definition.Attributes, existingFileAttributes) {
 			// The file attributes match what was expected.
 			// Verification is complete and we're done.
+			file.RemoveHashState()
+			file.WriteVerified(pkg.Definition.Attributes)
 			return nil
 		}
 
@@ -85,19 +125,69 @@ func (engine *downloadEngine) DownloadAndVerifyPackage(ctx context.Context, pkg
 		}
 	}
 
+	// If nothing has been downloaded yet and a previous version of the
+	// package is staged, try to produce the file from a delta instead.
+	// Forced downloads always fetch the full package.
+	if verifier.Size() == 0 && len(pkg.Definition.Deltas) > 0 && !engine.force.Downloads {
+		if engine.applyDelta(ctx, pkg, file, verifier) {
+			// Record the file verification result.
+			producedFileAttributes := verifier.State()
+			engine.events.Record(lbdeployevent.FileVerification{
+				Deployment:  engine.deployment.ID,
+				Flow:        engine.flow.ID,
+				ActionIndex: engine.action.Index,
+				ActionType:  engine.action.Definition.Type,
+				FileName:    file.Name,
+				Path:        file.Path,
+				Expected:    pkg.Definition.Attributes,
+				Actual:      producedFileAttributes,
+			})
+
+			if lbdeploy.EqualFileAttributes(pkg.Definition.Attributes, producedFileAttributes) {
+				file.RemoveHashState()
+				file.WriteVerified(pkg.Definition.Attributes)
+				return nil
+			}
+
+			// The produced file failed verification. Truncate it and
+			// download the full package.
+			if err := engine.resetFileDownload(lbdeploy.PackageSource{}, file, verifier, lbdeployevent.DeltaResultVerificationFailed); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Verify that at least one source has been specified.
 	if len(pkg.Definition.Sources) == 0 {
 		return errors.New("no sources were provided for the package")
 	}
 
-	// Start or resume the download. Attempt the download up to two times.
-	for attempt := 0; attempt < 2; attempt++ {
+	// Select the sources whose conditions are met, such as mirrors that are
+	// reachable, then rank them by their priorities, weights and probes and
+	// order them by their health.
+	sources := engine.availableSources(pkg.Definition.Sources, file)
+	if len(sources) == 0 {
+		return errors.New("none of the package's sources are available, because their conditions were not met")
+	}
+	sources = engine.state.sources.Order(engine.deployment.ID, engine.rankSources(ctx, sources, file))
+
+	// If peer sharing is enabled, try peers on the local network before
+	// the package's own sources.
+	if engine.state.peers && verifier.Size() == 0 {
+		sources = append(engine.findPeerSources(ctx, pkg, file), sources...)
+	}
+
+	// Start or resume the download. Attempt the download as many times as
+	// the retry policy allows.
+	policy := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior).DownloadRetry
+	attempts := policy.MaxAttempts()
+	for attempt := 1; ; attempt++ {
 		var (
 			errs   []error
 			source lbdeploy.PackageSource
 		)
-		for _, candidate := range pkg.Definition.Sources {
-			err := engine.downloadPackageFromSource(ctx, candidate, file, verifier)
+		for _, candidate := range sources {
+			err := engine.downloadPackageFromSource(ctx, candidate, file, verifier, pkg.Definition.Attributes.Size, pkg.Definition.MaxSize)
 			if err == nil {
 				// The download completed successfully.
 				source = candidate
@@ -106,9 +196,16 @@ func (engine *downloadEngine) DownloadAndVerifyPackage(ctx context.Context, pkg
 			errs = append(errs, err)
 		}
 
-		// If the download failed, so we stop.
+		// If the download failed, try again if the policy allows it.
+		// Otherwise we stop.
 		if err := errors.Join(errs...); err != nil {
-			return err
+			if attempt >= attempts || !isRetryableDownload(ctx, policy, errs) {
+				return err
+			}
+			if err := engine.waitForRetry(ctx, file, policy, attempt, attempts, err); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// The download was completed.
@@ -134,65 +231,170 @@ func (engine *downloadEngine) DownloadAndVerifyPackage(ctx context.Context, pkg
 		if lbdeploy.EqualFileAttributes(pkg.Definition.Attributes, downloadedFileAttributes) {
 			// The file attributes match what was expected.
 			// Verification is complete and we're done.
+			file.RemoveHashState()
+			file.WriteVerified(pkg.Definition.Attributes)
 			return nil
 		}
 
-		// The file failed verification. Truncate it and try again.
-		if attempt == 0 {
-			if err := engine.resetFileDownload(source, file, verifier, lbdeployevent.DownloadedFileVerificationFailed); err != nil {
-				return err
-			}
+		// The file failed verification. Note it against the source,
+		// then truncate the file and try again.
+		if !engine.isPeerSource(source) {
+			engine.state.sources.RecordRejection(engine.deployment.ID, source)
+		}
+		if attempt >= attempts {
+			break
+		}
+		if err := engine.resetFileDownload(source, file, verifier, lbdeployevent.DownloadedFileVerificationFailed); err != nil {
+			return err
+		}
+		if err := engine.waitForRetry(ctx, file, policy, attempt, attempts, errVerificationFailed); err != nil {
+			return err
 		}
 	}
 
 	// We've exhausted the maximum number of retries, but still failed to
 	// produce a downloaded package with the expected file attributes.
-	return errors.New("the downloaded package did not pass its file verification checks")
+	return errVerificationFailed
 }
 
-func (engine *downloadEngine) downloadPackageFromSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier) (err error) {
-	if source.Type != lbdeploy.PackageSourceHTTP {
-		return fmt.Errorf("unrecognized package source type: %s", source.Type)
+// errVerificationFailed is returned when a downloaded package does not
+// have the expected file attributes.
+var errVerificationFailed = errors.New("the downloaded package did not pass its file verification checks")
+
+// isRetryableDownload returns true if a download that failed with the
+// given errors, one for each source, should be attempted again according
+// to policy. Downloads are retried unless they were cancelled or every
+// source failed with a status code that the policy does not retry.
+func isRetryableDownload(ctx context.Context, policy lbdeploy.DownloadRetryPolicy, errs []error) bool {
+	if ctx.Err() != nil {
+		return false
 	}
+	for _, err := range errs {
+		var status statusError
+		if !errors.As(err, &status) || policy.RetriesStatus(status.Code) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Start at an offset when resuming downloads.
-	offset := verifier.Size()
+// waitForRetry records that a download will be retried after the given
+// attempt failed with err, then waits for the delay called for by policy.
+func (engine *downloadEngine) waitForRetry(ctx context.Context, file stagingfs.PackageFile, policy lbdeploy.DownloadRetryPolicy, attempt, attempts int, err error) error {
+	delay := policy.DelayAfter(attempt)
 
-	// Prepare an HTTP request. If offset is greater than zero, include a
-	// range header.
-	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
-	if err != nil {
-		return err
+	engine.events.Record(lbdeployevent.DownloadRetrying{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		FileName:    file.Name,
+		Attempt:     attempt,
+		Attempts:    attempts,
+		Delay:       delay,
+		Err:         err,
+	})
+
+	if delay <= 0 {
+		return nil
 	}
-	if offset > 0 {
-		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
 
-	// Make the HTTP request.
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+// availableSources returns the sources that have no condition, or whose
+// condition is met. Conditions are evaluated once per download.
+func (engine *downloadEngine) availableSources(sources []lbdeploy.PackageSource, file stagingfs.PackageFile) []lbdeploy.PackageSource {
+	ce := NewConditionEngine(engine.deployment)
+
+	var available []lbdeploy.PackageSource
+	for _, source := range sources {
+		if source.Condition == "" {
+			available = append(available, source)
+			continue
+		}
+		met, err := ce.Evaluate(source.Condition)
+		if err == nil && met {
+			available = append(available, source)
+			continue
+		}
+
+		// Record that the source was skipped.
+		engine.events.Record(lbdeployevent.DownloadSourceSkipped{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Source:      source,
+			FileName:    file.Name,
+			Err:         err,
+		})
 	}
-	defer resp.Body.Close()
 
-	// Record the time that the download started.
-	started := time.Now()
+	return available
+}
 
-	// Examine the status code of the response.
-	switch resp.StatusCode {
-	case http.StatusOK:
-		if offset > 0 {
-			offset = 0
-			if err := engine.resetFileDownload(source, file, verifier, lbdeployevent.HTTPServerDoesNotSupportResume); err != nil {
-				return err
-			}
+// downloadPackageFromSource downloads the remainder of a file from source,
+// writing it to file and verifier.
+//
+// The source's response is checked against expectedSize, which may be zero
+// if it isn't known. The download is aborted if the source claims or sends
+// a different amount of content, or more than maxSize bytes in total.
+func (engine *downloadEngine) downloadPackageFromSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize, maxSize int64) (err error) {
+	var open func(context.Context, lbdeploy.PackageSource, stagingfs.PackageFile, *FileVerifier, int64, int64) (downloadStream, error)
+	switch source.Type {
+	case lbdeploy.PackageSourceHTTP, lbdeploy.PackageSourceBlob, lbdeploy.PackageSourceS3:
+		open = engine.openHTTPSource
+		if source.Backend == lbdeploy.PackageSourceBackendBITS {
+			open = engine.openBITSSource
 		}
-	case http.StatusPartialContent:
-		// This indicates that the range header was accepted and the download
-		// can be resumed.
+	case lbdeploy.PackageSourceSMB:
+		open = engine.openSMBSource
+	case lbdeploy.PackageSourceFile:
+		open = engine.openFileSource
 	default:
-		return fmt.Errorf("the server returned an unexpected status code: %s", resp.Status)
+		return fmt.Errorf("unrecognized package source type: %s", source.Type)
+	}
+
+	// Record the outcome of the download in the source journal, unless the
+	// download was cancelled.
+	var downloaded int64
+	requested := time.Now()
+	defer func() {
+		if ctx.Err() != nil || engine.isPeerSource(source) {
+			return
+		}
+		engine.state.sources.RecordDownload(engine.deployment.ID, source, downloaded, time.Since(requested), err)
+	}()
+
+	// Split large downloads into concurrent range requests when the source
+	// allows it. If the server doesn't support them, fall back to a single
+	// request.
+	if n := rangeCount(source, verifier.Size(), expectedSize); n > 1 {
+		downloaded, err = engine.downloadRanges(ctx, source, file, verifier, expectedSize, n)
+		if !errors.Is(err, errRangesNotSupported) {
+			return err
+		}
+	}
+
+	// Open the source, starting at an offset when resuming downloads.
+	limit := downloadLimit(expectedSize, maxSize)
+	stream, err := open(ctx, source, file, verifier, expectedSize, limit)
+	if err != nil {
+		return err
 	}
+	defer stream.body.Close()
+	offset := stream.offset
+
+	// Record the time that the download started.
+	started := time.Now()
 
 	// Record the start of the download.
 	engine.events.Record(lbdeployevent.DownloadStarted{
@@ -204,19 +406,44 @@ func (engine *downloadEngine) downloadPackageFromSource(ctx context.Context, sou
 		FileName:    file.Name,
 		Path:        file.Path,
 		Offset:      offset,
+		Latency:     engine.probeLatency(source),
 	})
 
 	// Download the file, writing to both the file and the verifier.
+	// Periodically record the state of the verifier, so that an
+	// interrupted download can be resumed without hashing the file again.
 	var buf [262144]byte // 256 KB
-	var downloaded int64
+	var recorded int64
+
+	// Record heartbeats until the download has stopped.
+	var progress atomic.Int64
+	progress.Store(offset)
+	stopHeartbeat := startHeartbeat(engine.events, engine.state.heartbeat, func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat {
+		return lbdeployevent.Heartbeat{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Operation:   lbdeployevent.HeartbeatDownload,
+			Subject:     file.Name,
+			Started:     started,
+			Elapsed:     elapsed,
+			Progress:    progress.Load(),
+			Total:       expectedSize,
+		}
+	})
+
 	err = func() error {
 		for {
 			if err := ctx.Err(); err != nil {
 				return err
 			}
 
-			chunk, err := resp.Body.Read(buf[:])
+			chunk, err := stream.body.Read(buf[:])
 			if chunk > 0 {
+				if total := offset + downloaded + int64(chunk); total > limit {
+					return engine.abortDownload(source, file, lbdeployevent.ResponseTooLarge, limit, total)
+				}
 				downloaded += int64(chunk)
 				if _, err := file.Write(buf[:chunk]); err != nil {
 					return err
@@ -224,17 +451,39 @@ func (engine *downloadEngine) downloadPackageFromSource(ctx context.Context, sou
 				if _, err := verifier.Write(buf[:chunk]); err != nil {
 					return err
 				}
+				progress.Store(verifier.Size())
+				if engine.written != nil {
+					engine.written(verifier.Size())
+				}
+				if downloaded-recorded >= hashStateInterval {
+					engine.saveHashState(file, verifier)
+					recorded = downloaded
+				}
 			}
 
 			if err != nil {
-				if err == io.EOF {
+				switch {
+				case err == io.EOF && stream.length >= 0 && downloaded < stream.length:
+					return engine.abortDownload(source, file, lbdeployevent.ResponseTruncated, stream.length, downloaded)
+				case err == io.EOF && expectedSize > 0 && offset+downloaded < expectedSize:
+					return engine.abortDownload(source, file, lbdeployevent.ResponseTruncated, expectedSize, offset+downloaded)
+				case err == io.EOF:
 					return nil
+				case errors.Is(err, io.ErrUnexpectedEOF) && stream.length >= 0:
+					return engine.abortDownload(source, file, lbdeployevent.ResponseTruncated, stream.length, downloaded)
 				}
 				return err
 			}
 		}
 	}()
 
+	stopHeartbeat()
+
+	// Record the state of the verifier if the download was interrupted.
+	if err != nil && downloaded > recorded {
+		engine.saveHashState(file, verifier)
+	}
+
 	// Record the time that the download stopped.
 	stopped := time.Now()
 
@@ -257,6 +506,202 @@ func (engine *downloadEngine) downloadPackageFromSource(ctx context.Context, sou
 	return err
 }
 
+// downloadStream is the content of a package file that is read from a
+// source.
+type downloadStream struct {
+	body   io.ReadCloser
+	offset int64 // The offset within the file that body starts at
+	length int64 // The length of body, or -1 if it is unknown
+}
+
+// openHTTPSource requests the remainder of a file from an http, azure-blob
+// or s3 source. If the server doesn't support resuming downloads, the file
+// is reset and the returned stream starts at the beginning.
+func (engine *downloadEngine) openHTTPSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize, limit int64) (downloadStream, error) {
+	// Start at an offset when resuming downloads.
+	offset := verifier.Size()
+
+	// Make an HTTP request. If offset is greater than zero, include a
+	// range header.
+	var byteRange string
+	if offset > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-", offset)
+	}
+	resp, err := engine.sendRequest(ctx, source, byteRange)
+	if err != nil {
+		return downloadStream{}, err
+	}
+
+	// Examine the status code of the response.
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if offset > 0 {
+			offset = 0
+			if err := engine.resetFileDownload(source, file, verifier, lbdeployevent.HTTPServerDoesNotSupportResume); err != nil {
+				resp.Body.Close()
+				return downloadStream{}, err
+			}
+		}
+	case http.StatusPartialContent:
+		// This indicates that the range header was accepted and the download
+		// can be resumed.
+	default:
+		resp.Body.Close()
+		return downloadStream{}, engine.unexpectedStatus(source, resp)
+	}
+
+	// Make sure that the response describes the content that was asked
+	// for before any of it is written.
+	if err := engine.checkResponse(resp, source, file, offset, expectedSize, limit); err != nil {
+		resp.Body.Close()
+		return downloadStream{}, err
+	}
+
+	return downloadStream{
+		body:   resp.Body,
+		offset: offset,
+		length: resp.ContentLength,
+	}, nil
+}
+
+// sendRequest makes a request for the content of source. If byteRange is
+// not empty, it is sent as the value of a range header.
+func (engine *downloadEngine) sendRequest(ctx context.Context, source lbdeploy.PackageSource, byteRange string) (*http.Response, error) {
+	location, err := requestURL(source)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if err := engine.authorizeRequest(ctx, req, source); err != nil {
+		return nil, err
+	}
+
+	// Make the HTTP request. If it fails, report the source's URL instead
+	// of the request's, which may include a shared access signature.
+	transport, err := engine.transportFor(source)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := clientFor(source, transport).Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = source.URL
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// unexpectedStatus returns an error that describes the unexpected status
+// code of resp.
+func (engine *downloadEngine) unexpectedStatus(source lbdeploy.PackageSource, resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// The token may have been revoked or its permissions changed, so
+		// acquire a new one for the next attempt.
+		if !source.Auth.IsZero() {
+			engine.state.tokens.Discard(source.Auth)
+		}
+	}
+	return statusError{Code: resp.StatusCode, Status: resp.Status}
+}
+
+// statusError is returned when a server responds to a download request
+// with an unexpected status code.
+type statusError struct {
+	Code   int
+	Status string
+}
+
+// Error returns a description of the error.
+func (e statusError) Error() string {
+	return fmt.Sprintf("the server returned an unexpected status code: %s", e.Status)
+}
+
+// requestURL returns the URL that is requested for source.
+func requestURL(source lbdeploy.PackageSource) (string, error) {
+	if source.Type == lbdeploy.PackageSourceS3 {
+		u, err := source.S3ObjectURL()
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	return source.URL, nil
+}
+
+// checkResponse returns a non-nil error if the length or range of resp is
+// inconsistent with a request for the content of a file from offset
+// onward. The file is expected to be expectedSize bytes, if it is known,
+// and may not exceed limit bytes.
+func (engine *downloadEngine) checkResponse(resp *http.Response, source lbdeploy.PackageSource, file stagingfs.PackageFile, offset, expectedSize, limit int64) error {
+	// Work out the amount of content that the response claims to hold,
+	// and the total file size it claims, if either is declared.
+	length := resp.ContentLength
+	total := int64(-1)
+	if resp.StatusCode == http.StatusPartialContent {
+		r, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			// Treat a missing or malformed range as one that starts
+			// somewhere other than where it should.
+			return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, offset, -1)
+		}
+		if r.Start != offset {
+			return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, offset, r.Start)
+		}
+		if length >= 0 && length != r.Length() {
+			return engine.abortDownload(source, file, lbdeployevent.ContentLengthMismatch, r.Length(), length)
+		}
+		length = r.Length()
+		total = r.Total
+	} else if length >= 0 {
+		total = length
+	}
+
+	// Compare the claimed size of the file with its expected size.
+	if total >= 0 {
+		if expectedSize > 0 && total != expectedSize {
+			if resp.StatusCode == http.StatusPartialContent {
+				return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, expectedSize, total)
+			}
+			return engine.abortDownload(source, file, lbdeployevent.ContentLengthMismatch, expectedSize, total)
+		}
+		if total > limit {
+			return engine.abortDownload(source, file, lbdeployevent.ResponseTooLarge, limit, total)
+		}
+	} else if length >= 0 && offset+length > limit {
+		return engine.abortDownload(source, file, lbdeployevent.ResponseTooLarge, limit, offset+length)
+	}
+
+	return nil
+}
+
+// abortDownload records that a download from source was abandoned for the
+// given reason, and returns an error that describes it.
+func (engine *downloadEngine) abortDownload(source lbdeploy.PackageSource, file stagingfs.PackageFile, reason lbdeployevent.DownloadAbortReason, expected, actual int64) error {
+	engine.events.Record(lbdeployevent.DownloadAborted{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Source:      source,
+		FileName:    file.Name,
+		Path:        file.Path,
+		Reason:      reason,
+		Expected:    expected,
+		Actual:      actual,
+	})
+
+	return fmt.Errorf("the download was aborted because %s (expected %d, actual %d)", reason.Description(), expected, actual)
+}
+
 func (engine *downloadEngine) resetFileDownload(source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, reason lbdeployevent.DownloadResetReason) error {
 	// Record the reset of the download.
 	engine.events.Record(lbdeployevent.DownloadReset{
@@ -280,8 +725,56 @@ func (engine *downloadEngine) resetFileDownload(source lbdeploy.PackageSource, f
 		return err
 	}
 
-	// Reset the file verifier.
+	// Reset the file verifier and discard its recorded state.
 	verifier.Reset()
+	file.RemoveHashState()
+	file.RemoveVerified()
+	if engine.written != nil {
+		engine.written(0)
+	}
 
 	return nil
 }
+
+// hashStateInterval is the number of downloaded bytes between each
+// recording of the verifier's hash state.
+const hashStateInterval = 64 << 20 // 64 MiB
+
+// restoreHashState attempts to restore the verifier to the hash state
+// recorded for a partially downloaded file. It returns the number of bytes
+// at the start of the file that the verifier has already absorbed, which
+// is zero if the state could not be restored.
+//
+// Hash states are only used for files that are smaller than the expected
+// size. Files that appear to be complete are always hashed in full.
+func (engine *downloadEngine) restoreHashState(pkg packageData, file stagingfs.PackageFile, verifier *FileVerifier) int64 {
+	fi, err := file.Stat()
+	if err != nil || fi.Size() == 0 || fi.Size() >= pkg.Definition.Attributes.Size {
+		return 0
+	}
+
+	state, err := file.ReadHashState()
+	if err != nil || state.Size <= 0 || state.Size > fi.Size() {
+		return 0
+	}
+
+	if err := verifier.RestoreHashState(state); err != nil {
+		return 0
+	}
+
+	return state.Size
+}
+
+// saveHashState records the verifier's hash state for the file. The file
+// is flushed first, so that the state never describes data that hasn't
+// been written. Failure to record the state does not affect the download.
+func (engine *downloadEngine) saveHashState(file stagingfs.PackageFile, verifier *FileVerifier) {
+	if err := file.Sync(); err != nil {
+		return
+	}
+	state, err := verifier.HashState()
+	if err != nil {
+		return
+	}
+	file.WriteHashState(state)
+}
@@ -0,0 +1,113 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/pnpdriver"
+)
+
+// InstallDriver installs a driver package contained in an archive package.
+// The driver is described by the INF file identified by the action's
+// driver file.
+func (engine *packageEngine) InstallDriver(ctx context.Context) error {
+	// Find the INF file within the package.
+	fileID := engine.action.Definition.DriverFile
+	if fileID == "" {
+		return errors.New("the install-driver action does not specify a driver file")
+	}
+	if !engine.pkg.Definition.Type.IsArchive() {
+		return fmt.Errorf("drivers can only be installed from archive packages, but the \"%s\" package is not an archive", engine.pkg.ID)
+	}
+	fileData, exists := engine.pkg.Definition.Files[fileID]
+	if !exists {
+		return fmt.Errorf("the driver file \"%s\" is not defined in the \"%s\" package", fileID, engine.pkg.ID)
+	}
+	if !strings.EqualFold(filepath.Ext(fileData.Path), ".inf") {
+		return fmt.Errorf("the driver file \"%s\" in the \"%s\" package is not an INF file", fileID, engine.pkg.ID)
+	}
+
+	// Download, verify and extract the package.
+	files, err := engine.prepareArchive(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Verify that the INF file exists within the extracted file set.
+	fi, err := files.Stat(fileData.Path)
+	if err != nil {
+		return fmt.Errorf("verification of the driver file failed: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return errors.New("verification of the driver file failed: the driver file path is not a regular file")
+	}
+	infPath, err := files.FilePath(fileData.Path)
+	if err != nil {
+		return fmt.Errorf("a path could not be prepared for the \"%s\" driver file: %w", fileID, err)
+	}
+
+	// Check for cancellation before installing the driver.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Install the driver.
+	force := engine.action.Definition.Force
+	started := time.Now()
+	result, err := pnpdriver.Install(infPath, force)
+	stopped := time.Now()
+
+	// Record the outcome.
+	engine.events.Record(lbdeployevent.DriverInstalled{
+		Deployment:      engine.deployment.ID,
+		Flow:            engine.flow.ID,
+		ActionIndex:     engine.action.Index,
+		ActionType:      engine.action.Definition.Type,
+		Package:         engine.pkg.ID,
+		File:            fileID,
+		Path:            infPath,
+		Forced:          force,
+		Applied:         result.Applied,
+		RebootRequired:  result.RebootRequired,
+		DriverStorePath: result.DriverStorePath,
+		Started:         started,
+		Stopped:         stopped,
+		Err:             err,
+	})
+
+	if err != nil {
+		return fmt.Errorf("the \"%s\" driver could not be installed: %w", fileID, err)
+	}
+
+	// Act on a required restart in accordance with the deployment's reboot
+	// policy.
+	if result.RebootRequired {
+		reason := fmt.Sprintf("driver %s.%s", engine.pkg.ID, fileID)
+		engine.state.reboot.Require(reason)
+
+		engine.events.Record(lbdeployevent.RebootRequired{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Package:     engine.pkg.ID,
+			Reasons:     []string{reason},
+			Mode:        engine.deployment.Reboot.Mode,
+		})
+
+		if engine.deployment.Reboot.Mode == lbdeploy.RebootImmediate {
+			if err := restartSystem(engine.deployment, engine.flow.ID, engine.events, engine.state); err != nil {
+				return fmt.Errorf("a restart is required after the \"%s\" driver was installed but could not be initiated: %w", fileID, err)
+			}
+			return errRestartInitiated
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package lbengine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leafbridge/leafbridge-deploy/elevation"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+)
+
+// ErrElevationRequired is returned when a deployment requires elevation
+// and the process running it is not elevated.
+var ErrElevationRequired = errors.New("the deployment requires elevation")
+
+// checkElevation records the elevation status of the running process. If
+// the deployment requires elevation and the process is not elevated, it
+// returns an error.
+func (engine DeploymentEngine) checkElevation() error {
+	required := engine.deployment.RequiresElevation
+
+	status, err := elevation.Get()
+
+	engine.events.Record(lbdeployevent.ElevationDetected{
+		Deployment: engine.deployment.ID,
+		Elevated:   status.Elevated,
+		System:     status.System,
+		Required:   required,
+		Err:        err,
+	})
+
+	if !required {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("the \"%s\" deployment requires elevation, but the elevation of the process could not be determined: %w", engine.deployment.ID, err)
+	}
+
+	if !status.Elevated && !status.System {
+		return fmt.Errorf("%w: the \"%s\" deployment must be run from an elevated prompt or as the local system account", ErrElevationRequired, engine.deployment.ID)
+	}
+
+	return nil
+}
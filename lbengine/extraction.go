@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
@@ -66,6 +68,23 @@ func (engine *extractionEngine) ExtractPackage(ctx context.Context, source stagi
 		SourceStats:     sourceStats,
 	})
 
+	// Record heartbeats until the extraction has stopped.
+	var progress atomic.Int64
+	stopHeartbeat := startHeartbeat(engine.events, engine.state.heartbeat, func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat {
+		return lbdeployevent.Heartbeat{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Operation:   lbdeployevent.HeartbeatExtraction,
+			Subject:     source.Name,
+			Started:     started,
+			Elapsed:     elapsed,
+			Progress:    progress.Load(),
+			Total:       sourceStats.TotalBytes,
+		}
+	})
+
 	// Process each file and directory in the archive.
 	var destinationStats lbdeployevent.ExtractionStats
 	err = func() error {
@@ -119,10 +138,17 @@ func (engine *extractionEngine) ExtractPackage(ctx context.Context, source stagi
 				// Update statistics.
 				destinationStats.Files++
 				destinationStats.TotalBytes += written
+				progress.Store(destinationStats.TotalBytes)
 
 				return nil
 			}()
 
+			// If another process holds the file open, find out which one.
+			var lockers []lbdeploy.FileLocker
+			if isSharingViolation(err) {
+				lockers, _ = findFileLockers(filepath.Join(destination.Path(), filepath.FromSlash(zipFile.Name)))
+			}
+
 			// Record the time that the extraction of this file stopped.
 			fileStopped := time.Now()
 
@@ -134,6 +160,7 @@ func (engine *extractionEngine) ExtractPackage(ctx context.Context, source stagi
 				FileNumber: i,
 				Path:       zipFile.Name,
 				FileSize:   fileInfo.Size(),
+				Lockers:    lockers,
 				Started:    fileStarted,
 				Stopped:    fileStopped,
 				Err:        err,
@@ -148,6 +175,8 @@ func (engine *extractionEngine) ExtractPackage(ctx context.Context, source stagi
 		return nil
 	}()
 
+	stopHeartbeat()
+
 	// Record the time that the extraction stopped.
 	stopped := time.Now()
 
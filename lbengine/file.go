@@ -14,6 +14,7 @@ import (
 	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
 	"github.com/leafbridge/leafbridge-deploy/lbevent"
 	"github.com/leafbridge/leafbridge-deploy/localfs"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
 )
 
 // fileEngine handles file system operations within a deployment.
@@ -23,6 +24,7 @@ type fileEngine struct {
 	action     actionData
 	events     lbevent.Recorder
 	state      *engineState
+	tx         *transaction
 }
 
 // CopyFile performs a file copy operation.
@@ -46,6 +48,21 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 		return fmt.Errorf("the destination file is located in the \"%s\" root, which is protected", destFileRef.Root.ID())
 	}
 
+	// Make sure that the action's file lock policy is recognized.
+	if err := engine.action.Definition.OnLocked.Validate(); err != nil {
+		return err
+	}
+
+	// Determine the attributes that the copied file is expected to have,
+	// which are declared by the destination file or the source file.
+	expected := engine.deployment.Resources.FileSystem.Files[destFileID].Attributes
+	if len(expected.Features()) == 0 {
+		expected = engine.deployment.Resources.FileSystem.Files[sourceFileID].Attributes
+	}
+	if err := expected.Validate(); err != nil {
+		return fmt.Errorf("the attributes of the \"%s\" file are not valid: %w", destFileID, err)
+	}
+
 	// Record the time that the file copy started.
 	started := time.Now()
 
@@ -54,8 +71,11 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 		destFilePath    string
 		destFileExisted bool
 		fileSize        int64
+		copied          bool
+		backupPath      string
+		lockers         []lbdeploy.FileLocker
 	)
-	err = func() error {
+	attempt := func() error {
 		// Open the root above the destination file.
 		destDir, err := localfs.OpenDir(destFileRef.Dir())
 		if err != nil {
@@ -71,7 +91,8 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 			}
 		}
 
-		// If there is an existing file, stop.
+		// If there is an existing file, stop unless the action calls for it
+		// to be replaced.
 		fi, err := destDir.System().Stat(destFileRef.FilePath)
 		if err != nil {
 			if !os.IsNotExist(err) {
@@ -79,11 +100,21 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 			}
 		} else if fi.Mode().IsRegular() {
 			// The file already exists.
-			//
-			// TODO: Support replacing existing files, optionally via
-			// configuration.
 			destFileExisted = true
-			return nil
+			if !engine.action.Definition.Overwrite {
+				return nil
+			}
+
+			// Back up the existing file before it is replaced, if the
+			// action calls for it. This is only done once, even if the
+			// copy is attempted more than once.
+			if engine.action.Definition.Backup && backupPath == "" {
+				path, err := engine.backupFile(destFileID, destFilePath)
+				if err != nil {
+					return err
+				}
+				backupPath = path
+			}
 		} else {
 			return errors.New("the destination file path already exists but is not a regular file")
 		}
@@ -101,6 +132,14 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 			fileSize = fi.Size()
 		}
 
+		// If the copy is part of a transaction, record that the destination
+		// file is about to be created.
+		if engine.tx != nil {
+			if err := engine.tx.PreserveFile(destFilePath); err != nil {
+				return fmt.Errorf("unable to record the destination file in the transaction: %w", err)
+			}
+		}
+
 		// Open the destination file.
 		destFile, err := destDir.System().Create(destFileRef.FilePath)
 		if err != nil {
@@ -126,12 +165,48 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 				return fmt.Errorf("failed to set file modification time: %w", err)
 			}
 		}
+		copied = true
 		return nil
-	}()
+	}
+
+	// Perform the copy, retrying if it fails for a transient reason. If
+	// another process holds one of the files open, find out which one, and
+	// close it and try again if the action calls for it. The paths are
+	// determined from their references, because they won't have been
+	// recorded if the files couldn't be opened.
+	var lockPaths []string
+	for _, ref := range []lbdeploy.FileRef{destFileRef, sourceFileRef} {
+		if path, err := ref.Path(); err == nil {
+			lockPaths = append(lockPaths, path)
+		}
+	}
+	lockers, err = engine.perform(ctx, attempt, lockPaths...)
+
+	// If the copied file is expected to have certain attributes, verify
+	// it. If it fails verification, remove it and copy it one more time.
+	if err == nil && copied && len(expected.Features()) > 0 {
+		err = engine.verifyCopy(ctx, destFileRef, destFilePath, expected)
+		if err != nil {
+			if removeErr := engine.removeCopy(destFileRef); removeErr != nil {
+				err = fmt.Errorf("%w (the file could not be removed: %v)", err, removeErr)
+			} else {
+				copied = false
+				if err = attempt(); err == nil && copied {
+					if err = engine.verifyCopy(ctx, destFileRef, destFilePath, expected); err != nil {
+						engine.removeCopy(destFileRef)
+					}
+				}
+			}
+		}
+	}
 
 	// Record the time that the file copy stopped.
 	stopped := time.Now()
 
+	// Applications might be detected by the presence of files, so forget
+	// what we knew about them.
+	engine.state.apps.Clear()
+
 	// Record the file copy.
 	engine.events.Record(lbdeployevent.FileCopy{
 		Deployment:         engine.deployment.ID,
@@ -144,12 +219,15 @@ func (engine *fileEngine) CopyFile(ctx context.Context) error {
 		DestinationPath:    destFilePath,
 		DestinationExisted: destFileExisted,
 		FileSize:           fileSize,
+		Overwritten:        destFileExisted && copied,
+		Backup:             backupPath,
+		Lockers:            lockers,
 		Started:            started,
 		Stopped:            stopped,
 		Err:                err,
 	})
 
-	return nil
+	return err
 }
 
 // DeleteFile performs a file delete operation.
@@ -166,6 +244,11 @@ func (engine *fileEngine) DeleteFile(ctx context.Context) error {
 		return fmt.Errorf("the file is located in the \"%s\" root, which is protected", fileRef.Root.ID())
 	}
 
+	// Make sure that the action's file lock policy is recognized.
+	if err := engine.action.Definition.OnLocked.Validate(); err != nil {
+		return err
+	}
+
 	// Record the time that the file deletion started.
 	started := time.Now()
 
@@ -173,8 +256,9 @@ func (engine *fileEngine) DeleteFile(ctx context.Context) error {
 		filePath    string
 		fileSize    int64
 		fileExisted bool
+		lockers     []lbdeploy.FileLocker
 	)
-	err = func() error {
+	attempt := func() error {
 		// Open the root above the destination file.
 		fileDir, err := localfs.OpenDir(fileRef.Dir())
 		if err != nil {
@@ -208,13 +292,34 @@ func (engine *fileEngine) DeleteFile(ctx context.Context) error {
 		// Record that the file exixted.
 		fileExisted = true
 
+		// If the deletion is part of a transaction, back up the file so
+		// that it can be restored.
+		if engine.tx != nil {
+			if err := engine.tx.PreserveFile(filePath); err != nil {
+				return fmt.Errorf("unable to back up the file for the transaction: %w", err)
+			}
+		}
+
 		// Delete the file.
 		return fileDir.System().Remove(fileRef.FilePath)
-	}()
+	}
+
+	// Perform the deletion, retrying if it fails for a transient reason. If
+	// another process holds the file open, find out which one, and close it
+	// and try again if the action calls for it.
+	var lockPath string
+	if path, err := fileRef.Path(); err == nil {
+		lockPath = path
+	}
+	lockers, err = engine.perform(ctx, attempt, lockPath)
 
 	// Record the time that the file deletion stopped.
 	stopped := time.Now()
 
+	// Applications might be detected by the presence of files, so forget
+	// what we knew about them.
+	engine.state.apps.Clear()
+
 	// Record the file deletion.
 	engine.events.Record(lbdeployevent.FileDelete{
 		Deployment:  engine.deployment.ID,
@@ -225,10 +330,88 @@ func (engine *fileEngine) DeleteFile(ctx context.Context) error {
 		FilePath:    filePath,
 		FileSize:    fileSize,
 		FileExisted: fileExisted,
+		Lockers:     lockers,
 		Started:     started,
 		Stopped:     stopped,
 		Err:         err,
 	})
 
+	return err
+}
+
+// verifyCopy reads the copied file and compares its attributes against the
+// expected attributes. It records the result of the verification and
+// returns an error if they don't match.
+func (engine *fileEngine) verifyCopy(ctx context.Context, ref lbdeploy.FileRef, path string, expected lbdeploy.FileAttributes) error {
+	verifier, err := NewFileVerifier(expected.Hashes.Types()...)
+	if err != nil {
+		return fmt.Errorf("failed to prepare a file content verifier: %w", err)
+	}
+
+	file, err := localfs.OpenFile(ref)
+	if err != nil {
+		return fmt.Errorf("unable to open the copied file for verification: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := verifier.ReadFrom(newReaderWithContext(ctx, file.System())); err != nil {
+		return fmt.Errorf("failed to verify the copied file: %w", err)
+	}
+
+	// If only some of the file's attributes were provided, compare only
+	// those.
+	actual := verifier.State()
+	if expected.Size == 0 && len(expected.Hashes) > 0 {
+		actual.Size = 0
+	}
+
+	// Record the file verification result.
+	engine.events.Record(lbdeployevent.FileVerification{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		FileName:    string(ref.FileID),
+		Path:        path,
+		Expected:    expected,
+		Actual:      actual,
+	})
+
+	if !lbdeploy.EqualFileAttributes(expected, actual) {
+		return errors.New("the copied file does not have the expected file attributes and has failed verification")
+	}
+
 	return nil
 }
+
+// removeCopy removes a copied file that failed verification.
+func (engine *fileEngine) removeCopy(ref lbdeploy.FileRef) error {
+	dir, err := localfs.OpenDir(ref.Dir())
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.System().Remove(ref.FilePath)
+}
+
+// backupFile copies the file at path to a new backup file in the
+// deployment's staging directory, and returns the path of the backup.
+func (engine *fileEngine) backupFile(file lbdeploy.FileResourceID, path string) (string, error) {
+	dir, err := stagingfs.OpenDeployment(engine.deployment.ID)
+	if err != nil {
+		return "", fmt.Errorf("unable to open the staging directory for the file backup: %w", err)
+	}
+	defer dir.Close()
+
+	backup, err := dir.FileBackupPath(file, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare the file backup directory: %w", err)
+	}
+
+	if err := copyFileContents(path, backup); err != nil {
+		return "", fmt.Errorf("unable to back up the existing file: %w", err)
+	}
+
+	return backup, nil
+}
@@ -0,0 +1,103 @@
+package lbengine
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/restartmanager"
+	"golang.org/x/sys/windows"
+)
+
+// isSharingViolation returns true if err indicates that a file operation
+// failed because another process holds the file open.
+func isSharingViolation(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
+
+// findFileLockers returns the processes that hold any of the files at the
+// given paths open. Empty paths are ignored.
+func findFileLockers(paths ...string) ([]lbdeploy.FileLocker, error) {
+	paths = slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return path == "" })
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	procs, err := restartmanager.Lockers(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	lockers := make([]lbdeploy.FileLocker, 0, len(procs))
+	for _, proc := range procs {
+		locker := lbdeploy.FileLocker{
+			PID:     proc.ID,
+			Name:    proc.AppName,
+			Service: proc.ServiceName,
+		}
+		if user, err := proc.User(); err == nil {
+			locker.User = user
+		}
+		lockers = append(lockers, locker)
+	}
+
+	return lockers, nil
+}
+
+// closeFileLockers asks the processes that hold any of the files at the
+// given paths to shut down, terminating them if they do not.
+func closeFileLockers(paths ...string) error {
+	paths = slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return path == "" })
+	if len(paths) == 0 {
+		return nil
+	}
+
+	session, err := restartmanager.Start()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if err := session.RegisterFiles(paths...); err != nil {
+		return err
+	}
+
+	return session.Shutdown(true)
+}
+
+// resolveFileLock is called when a file operation fails. If the failure
+// was caused by a sharing violation, it looks up the processes that hold
+// the files at the given paths and returns them.
+//
+// If the action's lock policy is close-and-retry and lockers were found,
+// it closes them and returns true to indicate that the operation should be
+// tried again.
+func (engine *fileEngine) resolveFileLock(err error, paths ...string) (lockers []lbdeploy.FileLocker, retry bool) {
+	if err == nil || !isSharingViolation(err) {
+		return nil, false
+	}
+
+	lockers, lookupErr := findFileLockers(paths...)
+	if lookupErr != nil || len(lockers) == 0 {
+		return lockers, false
+	}
+
+	if engine.action.Definition.OnLocked != lbdeploy.FileLockCloseAndRetry {
+		return lockers, false
+	}
+
+	closeErr := closeFileLockers(paths...)
+
+	engine.events.Record(lbdeployevent.FileLockersClosed{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Paths:       slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return path == "" }),
+		Lockers:     lockers,
+		Err:         closeErr,
+	})
+
+	return lockers, closeErr == nil
+}
@@ -0,0 +1,77 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// fileRetryAttempts is the maximum number of times that a file
+	// operation is attempted when it fails with a transient error.
+	fileRetryAttempts = 5
+
+	// fileRetryDelay is the delay before the first retry of a file
+	// operation. It doubles with each subsequent retry.
+	fileRetryDelay = 500 * time.Millisecond
+)
+
+// isTransientFileError returns true if err indicates that a file operation
+// failed for a reason that is likely to be temporary, such as an antivirus
+// scanner holding a freshly written file open for a moment.
+func isTransientFileError(err error) bool {
+	return isSharingViolation(err) ||
+		errors.Is(err, windows.ERROR_ACCESS_DENIED) ||
+		errors.Is(err, windows.ERROR_DELETE_PENDING)
+}
+
+// perform runs a file operation that affects the files at the given paths.
+//
+// If the operation fails with a transient error, it is retried with an
+// increasing delay, and an event is recorded for each failed attempt. If
+// the operation still fails due to a sharing violation, the processes that
+// hold the files open are returned, and they are closed and the operation
+// tried one last time if the action's lock policy calls for it.
+func (engine *fileEngine) perform(ctx context.Context, op func() error, paths ...string) (lockers []lbdeploy.FileLocker, err error) {
+	delay := fileRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !isTransientFileError(err) || attempt >= fileRetryAttempts {
+			break
+		}
+
+		engine.events.Record(lbdeployevent.FileOperationRetry{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Paths:       slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return path == "" }),
+			Attempt:     attempt,
+			Delay:       delay,
+			Err:         err,
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+
+	found, retry := engine.resolveFileLock(err, paths...)
+	if !retry {
+		return found, err
+	}
+
+	err = op()
+	lockers, _ = engine.resolveFileLock(err, paths...)
+	return lockers, err
+}
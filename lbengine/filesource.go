@@ -0,0 +1,70 @@
+package lbengine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// openFileSource opens the remainder of a package file that is already
+// present on the local system. Its content is copied into the staging
+// directory in the same way as a download.
+func (engine *downloadEngine) openFileSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize, limit int64) (downloadStream, error) {
+	path, err := source.LocalPath()
+	if err != nil {
+		return downloadStream{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return downloadStream{}, err
+	}
+
+	return engine.openSourceFile(source, file, verifier, path, f, expectedSize, limit)
+}
+
+// openSourceFile prepares a stream for the remainder of f, which is the
+// source file at path. The size of f is checked against expectedSize and
+// limit before any of it is read. If the stream can't be prepared, f is
+// closed.
+func (engine *downloadEngine) openSourceFile(source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, path string, f io.ReadSeekCloser, expectedSize, limit int64) (downloadStream, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return downloadStream{}, fmt.Errorf("failed to determine the size of \"%s\": %w", path, err)
+	}
+	if expectedSize > 0 && size != expectedSize {
+		f.Close()
+		return downloadStream{}, engine.abortDownload(source, file, lbdeployevent.SourceFileSizeMismatch, expectedSize, size)
+	}
+	if size > limit {
+		f.Close()
+		return downloadStream{}, engine.abortDownload(source, file, lbdeployevent.ResponseTooLarge, limit, size)
+	}
+
+	// Resume the copy where it left off. If the file is smaller than what
+	// was already copied, it must have changed, so start over.
+	offset := verifier.Size()
+	if offset > size {
+		offset = 0
+		if err := engine.resetFileDownload(source, file, verifier, lbdeployevent.SourceFileSmallerThanDownload); err != nil {
+			f.Close()
+			return downloadStream{}, err
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return downloadStream{}, fmt.Errorf("failed to seek to offset %d in \"%s\": %w", offset, path, err)
+	}
+
+	return downloadStream{
+		body:   f,
+		offset: offset,
+		length: size - offset,
+	}, nil
+}
@@ -2,13 +2,16 @@ package lbengine
 
 import (
 	"crypto/sha3"
+	"encoding"
 	"fmt"
 	"hash"
 	"io"
 	"slices"
+	"time"
 
 	"github.com/leafbridge/leafbridge-deploy/filehash"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
 )
 
 // FileVerifier is capable of absorbing file content as a file is read or
@@ -112,3 +115,56 @@ func (v *FileVerifier) State() lbdeploy.FileAttributes {
 	}
 	return attrs
 }
+
+// HashState returns the internal state of the verifier's hashes, which can
+// be recorded so that verification can be resumed later.
+func (v *FileVerifier) HashState() (stagingfs.HashState, error) {
+	state := stagingfs.HashState{
+		Size:    v.size,
+		Hashes:  make(map[filehash.Type][]byte, len(v.hashes)),
+		Updated: time.Now(),
+	}
+	for t, hash := range v.hashes {
+		marshaler, ok := hash.(encoding.BinaryMarshaler)
+		if !ok {
+			return stagingfs.HashState{}, fmt.Errorf("%s: the hash state cannot be recorded", t)
+		}
+		data, err := marshaler.MarshalBinary()
+		if err != nil {
+			return stagingfs.HashState{}, fmt.Errorf("%s: %w", t, err)
+		}
+		state.Hashes[t] = data
+	}
+	return state, nil
+}
+
+// RestoreHashState restores the verifier to a hash state that was
+// previously returned by HashState. The state must include each of the
+// verifier's hash types.
+//
+// If the state cannot be restored, an error is returned and the verifier
+// is reset.
+func (v *FileVerifier) RestoreHashState(state stagingfs.HashState) error {
+	if len(state.Hashes) != len(v.hashes) {
+		v.Reset()
+		return fmt.Errorf("the hash state has %d hashes instead of %d", len(state.Hashes), len(v.hashes))
+	}
+	for t, hash := range v.hashes {
+		unmarshaler, ok := hash.(encoding.BinaryUnmarshaler)
+		if !ok {
+			v.Reset()
+			return fmt.Errorf("%s: the hash state cannot be restored", t)
+		}
+		data, found := state.Hashes[t]
+		if !found {
+			v.Reset()
+			return fmt.Errorf("%s: the hash state does not include the hash", t)
+		}
+		if err := unmarshaler.UnmarshalBinary(data); err != nil {
+			v.Reset()
+			return fmt.Errorf("%s: %w", t, err)
+		}
+	}
+	v.size = state.Size
+	return nil
+}
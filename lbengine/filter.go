@@ -0,0 +1,43 @@
+package lbengine
+
+import "github.com/leafbridge/leafbridge-deploy/lbdeploy"
+
+// ActionFilter selects the actions of each flow that are invoked. It is
+// intended for troubleshooting, when only part of a flow needs to be run
+// again. The zero value selects every action.
+//
+// Only the before, main and after actions of a flow are filtered. The
+// members of a transaction and rollback actions are never filtered on
+// their own.
+type ActionFilter struct {
+	// Skip identifies actions that are not invoked.
+	Skip []lbdeploy.ActionRef
+
+	// Only identifies the actions that are invoked, if it is not empty.
+	// All other actions in every flow are skipped.
+	Only []lbdeploy.ActionRef
+}
+
+// IsZero returns true if the filter selects every action.
+func (filter ActionFilter) IsZero() bool {
+	return len(filter.Skip) == 0 && len(filter.Only) == 0
+}
+
+// Skipped returns true if the given action, which is at index within
+// flow, is excluded by the filter.
+func (filter ActionFilter) Skipped(flow lbdeploy.FlowID, index int, action lbdeploy.Action) bool {
+	for _, ref := range filter.Skip {
+		if ref.Matches(flow, index, action) {
+			return true
+		}
+	}
+	if len(filter.Only) == 0 {
+		return false
+	}
+	for _, ref := range filter.Only {
+		if ref.Matches(flow, index, action) {
+			return false
+		}
+	}
+	return true
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/leafbridge/leafbridge-deploy/idset"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
 	"github.com/leafbridge/leafbridge-deploy/lbevent"
@@ -22,26 +23,101 @@ type flowEngine struct {
 	deployment lbdeploy.Deployment
 	flow       flowData
 	events     lbevent.Recorder
-	force      bool
+	force      ForceScope
 	state      *engineState
+
+	// resumed is the number of main actions that were completed by a
+	// previous invocation of the flow. Those that are resumable are
+	// skipped.
+	resumed int
+
+	// finished holds the IDs of the actions that have completed or been
+	// excluded by the action filter during the current attempt at the
+	// flow. Actions that depend on others are only invoked once their
+	// dependencies are in it.
+	finished idset.SetOf[lbdeploy.ActionID]
 }
 
+// Invoke runs the flow.
 func (engine flowEngine) Invoke(ctx context.Context) error {
+	_, err := engine.invoke(ctx)
+	return err
+}
+
+// invoke runs the flow and returns statistics about the actions that it
+// invoked.
+//
+// If the flow fails and its behavior is to retry the flow, it is invoked
+// again until it succeeds or its retries have been used up. The statistics
+// returned are those of the last attempt.
+func (engine flowEngine) invoke(ctx context.Context) (stats lbdeploy.FlowStats, err error) {
+	behavior := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior)
+
+	for attempt := 1; ; attempt++ {
+		stats, err = engine.invokeOnce(ctx)
+		if err == nil || behavior.OnError != lbdeploy.OnErrorRetryFlow || attempt > behavior.Retries {
+			return stats, err
+		}
+
+		// Don't retry flows that were cancelled.
+		if ctx.Err() != nil {
+			return stats, err
+		}
+
+		// Don't retry flows that were abandoned for a restart or deferred
+		// by the user.
+		if errors.Is(err, errRestartInitiated) || errors.Is(err, ErrDeferred) {
+			return stats, err
+		}
+
+		// Record the retry.
+		delay := time.Duration(behavior.RetryDelay)
+		engine.events.Record(lbdeployevent.FlowRetrying{
+			Deployment: engine.deployment.ID,
+			Flow:       engine.flow.ID,
+			Attempt:    attempt,
+			Retries:    behavior.Retries,
+			Delay:      delay,
+			Err:        err,
+		})
+
+		// Wait before trying again.
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return stats, err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// invokeOnce makes a single attempt to run the flow and returns statistics
+// about the actions that it invoked.
+func (engine flowEngine) invokeOnce(ctx context.Context) (stats lbdeploy.FlowStats, err error) {
 	// Check for context cancellation.
 	if err := ctx.Err(); err != nil {
-		return err
+		return stats, err
 	}
 
+	// Start each attempt without any finished actions.
+	engine.finished = make(idset.SetOf[lbdeploy.ActionID])
+
 	// Check for a flow cycle and stop if one is detected.
-	if engine.state.activeFlows.Contains(engine.flow.ID) {
+	if !engine.state.startFlow(engine.flow.ID) {
 		// Record the failure to start the flow.
 		engine.events.Record(lbdeployevent.FlowAlreadyRunning{
 			Deployment: engine.deployment.ID,
 			Flow:       engine.flow.ID,
 		})
-		return fmt.Errorf("the \"%s\" flow is already running", engine.flow.ID)
+		return stats, fmt.Errorf("the \"%s\" flow is already running", engine.flow.ID)
 	}
 
+	// Keep this recorded as a running flow as long as it is running.
+	defer engine.state.stopFlow(engine.flow.ID)
+
 	// Evaluate all constraints for the flow.
 	if conditions := engine.flow.Definition.Constraints; len(conditions) > 0 {
 		// Prepare a condition engine.
@@ -60,7 +136,7 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 					Err:        err,
 				})
 
-				return fmt.Errorf("the \"%s\" flow failed to evaluate constraint %d: %w", engine.flow.ID, i+1, err)
+				return stats, fmt.Errorf("the \"%s\" flow failed to evaluate constraint %d: %w", engine.flow.ID, i+1, err)
 			}
 			if !result {
 				failed = append(failed, condition)
@@ -80,7 +156,7 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 
 		// If any of the constrains failed, skip execution.
 		if len(failed) > 0 {
-			return nil
+			return stats, nil
 		}
 	}
 
@@ -102,7 +178,7 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 					Err:        err,
 				})
 
-				return fmt.Errorf("the \"%s\" flow failed to evaluate precondition %d: %w", engine.flow.ID, i+1, err)
+				return stats, fmt.Errorf("the \"%s\" flow failed to evaluate precondition %d: %w", engine.flow.ID, i+1, err)
 			}
 			if !result {
 				failed = append(failed, condition)
@@ -122,7 +198,7 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 
 		// If any of the preconditions failed, stop execution.
 		if len(failed) > 0 {
-			return fmt.Errorf("the \"%s\" flow is unable to run because one or more preconditions failed: %s", engine.flow.ID, failed)
+			return stats, fmt.Errorf("the \"%s\" flow is unable to run because one or more preconditions failed: %s", engine.flow.ID, failed)
 		}
 	}
 
@@ -138,11 +214,20 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 		// Create a lock group.
 		group, err := engine.state.locks.Create(engine.deployment.Resources, locks...)
 		if err != nil {
-			return fmt.Errorf("the \"%s\" flow failed to prepare its lock group: %w", engine.flow.ID, err)
+			return stats, fmt.Errorf("the \"%s\" flow failed to prepare its lock group: %w", engine.flow.ID, err)
 		}
 
-		// Try to lock all members of the group.
-		if err := group.Lock(); err != nil {
+		// Try to lock all members of the group, waiting for locks held by
+		// other processes if configured to do so.
+		onWait := func(lock lbdeploy.LockID, timeout time.Duration) {
+			engine.events.Record(lbdeployevent.FlowLockWaiting{
+				Deployment: engine.deployment.ID,
+				Flow:       engine.flow.ID,
+				Lock:       lock,
+				Timeout:    timeout,
+			})
+		}
+		if err := group.Lock(ctx, onWait); err != nil {
 			// We failed to acquire one of the locks. Find out which one
 			// failed.
 			var lockID lbdeploy.LockID
@@ -161,7 +246,7 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 				Err:        err,
 			})
 
-			return fmt.Errorf("the \"%s\" flow failed to acquire locks for its entire lock group: %w", engine.flow.ID, err)
+			return stats, fmt.Errorf("the \"%s\" flow failed to acquire locks for its entire lock group: %w", engine.flow.ID, err)
 		}
 
 		// Unlock all members when finished.
@@ -171,76 +256,354 @@ func (engine flowEngine) Invoke(ctx context.Context) error {
 	// Prepare the behavior for this flow.
 	behavior := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior)
 
-	// Record this as a running flow as long as it is running.
-	engine.state.activeFlows.Add(engine.flow.ID)
-	defer engine.state.activeFlows.Remove(engine.flow.ID)
+	// If the flow has a timeout, enforce it from this point on.
+	timeout := time.Duration(behavior.Timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, errFlowTimedOut)
+		defer cancel()
+	}
 
 	// Record the start of the flow.
 	engine.events.Record(lbdeployevent.FlowStarted{
-		Deployment: engine.deployment.ID,
-		Flow:       engine.flow.ID,
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		Description: engine.flow.Definition.Description,
 	})
 
 	// Record the time that the flow started.
 	started := time.Now()
 
-	// Collect statistics.
-	var stats lbdeploy.FlowStats
+	// Warn if the flow runs for longer than expected.
+	expected := time.Duration(engine.flow.Definition.ExpectedDuration)
+	stopOverdue := startOverdueTimer(engine.events, expected, lbdeployevent.FlowOverdue{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		Description: engine.flow.Definition.Description,
+		Expected:    expected,
+	})
 
-	// Execute each action in the flow.
-	err := func() error {
-		var errs []error
-		for i, action := range engine.flow.Definition.Actions {
-			// Check for context cancellation.
-			if err := ctx.Err(); err != nil {
-				errs = append(errs, err)
-				break
+	// Execute the flow's before actions, followed by its main actions. If
+	// any of the before actions fail, the main actions are skipped.
+	//
+	// Actions are numbered in the order they appear across the before, main
+	// and after action lists.
+	var (
+		def         = engine.flow.Definition
+		stopOnError = behavior.OnError != lbdeploy.OnErrorContinue
+		errs        []error
+	)
+	if _, err := engine.invokeActions(ctx, def.Before, 0, true, &stats, nil); err != nil {
+		errs = append(errs, err)
+	} else {
+		// If resumption was requested, keep track of progress through the
+		// main actions, so that a later invocation can resume where this
+		// one left off, and skip the resumable actions that were completed
+		// previously.
+		var (
+			checkpoint = newFlowCheckpoint(engine.deployment.ID, engine.flow)
+			resume     = engine.state.resume
+			main       = engine
+			onComplete func(i int)
+		)
+		if resume {
+			main.resumed = checkpoint.Resume(def.Actions)
+			skipped := 0
+			for _, action := range def.Actions[:main.resumed] {
+				if action.Type.Resumable() {
+					skipped++
+				}
 			}
-
-			// Create an action engine.
-			ae := actionEngine{
-				deployment: engine.deployment,
-				flow:       engine.flow,
-				action: actionData{
-					Index:      i,
-					Definition: action,
-				},
-				events: engine.events,
-				force:  engine.force,
-				state:  engine.state,
+			if skipped > 0 {
+				engine.events.Record(lbdeployevent.FlowResumed{
+					Deployment: engine.deployment.ID,
+					Flow:       engine.flow.ID,
+					Skipped:    skipped,
+				})
 			}
+			onComplete = checkpoint.Advance
+		} else {
+			// Discard a checkpoint left behind by an earlier invocation
+			// that requested resumption, as it no longer reflects the
+			// progress of the flow.
+			checkpoint.Clear()
+		}
 
-			// Invoke the action.
-			if err := ae.Invoke(ctx); err != nil {
-				if ctx.Err() == err {
-					break // Always stop when the context is cancelled.
+		completed, err := main.invokeActions(ctx, def.Actions, len(def.Before), stopOnError, &stats, onComplete)
+		if err != nil {
+			errs = append(errs, err)
+
+			// If the flow is configured to roll back or retry on error,
+			// unwind the actions that were completed and start over next
+			// time.
+			if behavior.OnError == lbdeploy.OnErrorRollback || behavior.OnError == lbdeploy.OnErrorRetryFlow {
+				if resume {
+					checkpoint.Clear()
 				}
-
-				stats.ActionsFailed++
-
-				errs = append(errs, err)
-				if behavior.OnError != lbdeploy.OnErrorContinue {
-					break
+				if err := engine.rollback(ctx, completed); err != nil {
+					errs = append(errs, err)
 				}
-			} else {
-				stats.ActionsCompleted++
 			}
+		} else if resume {
+			// All of the main actions have completed.
+			checkpoint.Clear()
 		}
-		return errors.Join(errs...)
-	}()
+	}
+
+	// Determine whether the before or main actions were stopped by the
+	// flow's timeout. This must be decided before the after actions run,
+	// because the deadline may pass while they run even though everything
+	// else succeeded.
+	timedOut := timeout > 0 && flowTimedOut(ctx, errors.Join(errs...))
+
+	// Execute the flow's after actions regardless of the outcome, like a
+	// finally block. They run even if the context has been cancelled, so
+	// that cleanup can take place, and they all run even if some of them
+	// fail.
+	if len(def.After) > 0 {
+		afterCtx := context.WithoutCancel(ctx)
+		if _, err := engine.invokeActions(afterCtx, def.After, len(def.Before)+len(def.Actions), false, &stats, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = errors.Join(errs...)
+
+	stopOverdue()
 
 	// Record the time that the flow stopped.
 	stopped := time.Now()
 
+	// If the flow ran out of time, say so.
+	if timedOut {
+		engine.events.Record(lbdeployevent.FlowTimedOut{
+			Deployment: engine.deployment.ID,
+			Flow:       engine.flow.ID,
+			Timeout:    timeout,
+			Started:    started,
+			Stopped:    stopped,
+		})
+		err = fmt.Errorf("the \"%s\" flow did not finish within its %s timeout: %w", engine.flow.ID, timeout, err)
+	}
+
 	// Record the end of the flow.
 	engine.events.Record(lbdeployevent.FlowStopped{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		Description: engine.flow.Definition.Description,
+		Stats:       stats,
+		Started:     started,
+		Stopped:     stopped,
+		Err:         err,
+	})
+
+	return stats, err
+}
+
+// errFlowTimedOut is the cause given when a flow's context expires because
+// the flow has exceeded its timeout.
+var errFlowTimedOut = errors.New("the flow timed out")
+
+// flowTimedOut reports whether err was the result of ctx expiring because
+// of the flow's timeout. It returns false if err is nil.
+func flowTimedOut(ctx context.Context, err error) bool {
+	return err != nil && context.Cause(ctx) == errFlowTimedOut
+}
+
+// invokeActions invokes a list of actions in order, updating stats as it
+// goes. The index of each action is offset by the given amount. It returns
+// the actions that completed successfully.
+//
+// If onComplete is non-nil, it is called with the position of each action
+// in the list as it completes successfully.
+//
+// If stopOnError is true, it stops after the first action that fails. It
+// always stops when the context is cancelled.
+func (engine flowEngine) invokeActions(ctx context.Context, actions []lbdeploy.Action, offset int, stopOnError bool, stats *lbdeploy.FlowStats, onComplete func(i int)) (completed []lbdeploy.Action, err error) {
+	var errs []error
+	for i, action := range actions {
+		// Check for context cancellation.
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		// Skip resumable actions that were completed by a previous
+		// invocation of the flow.
+		if i < engine.resumed && action.Type.Resumable() {
+			engine.markFinished(action)
+			continue
+		}
+
+		// Skip actions that are excluded by the action filter. Rollback
+		// actions are numbered after the flow's own actions and are never
+		// filtered.
+		if index := offset + i; index < engine.flow.Definition.ActionCount() && engine.state.filter.Skipped(engine.flow.ID, index, action) {
+			engine.events.Record(lbdeployevent.ActionSkipped{
+				Deployment:  engine.deployment.ID,
+				Flow:        engine.flow.ID,
+				ActionIndex: index,
+				ActionType:  action.Type,
+				ActionID:    action.ID,
+				Description: action.Description,
+			})
+			engine.markFinished(action)
+			continue
+		}
+
+		// Skip actions whose dependencies did not complete.
+		if dependency, unmet := engine.unmetDependency(action); unmet {
+			engine.events.Record(lbdeployevent.ActionSkipped{
+				Deployment:  engine.deployment.ID,
+				Flow:        engine.flow.ID,
+				ActionIndex: offset + i,
+				ActionType:  action.Type,
+				ActionID:    action.ID,
+				Description: action.Description,
+				DependsOn:   dependency,
+			})
+			continue
+		}
+
+		// Wait for a maintenance window before invoking commands.
+		if action.Type == lbdeploy.ActionInvokeCommand {
+			if err := engine.waitForMaintenanceWindow(ctx, offset+i, action.Type); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+
+		// Create an action engine.
+		ae := actionEngine{
+			deployment: engine.deployment,
+			flow:       engine.flow,
+			action: actionData{
+				Index:      offset + i,
+				Definition: action,
+			},
+			events: engine.events,
+			force:  engine.force,
+			state:  engine.state,
+		}
+
+		// Invoke the action.
+		if err := ae.Invoke(ctx); err != nil {
+			if ctx.Err() == err {
+				errs = append(errs, err)
+				break // Always stop when the context is cancelled.
+			}
+
+			stats.ActionsFailed++
+
+			errs = append(errs, err)
+			if stopOnError {
+				break
+			}
+		} else {
+			stats.ActionsCompleted++
+			completed = append(completed, action)
+			engine.markFinished(action)
+			if onComplete != nil {
+				onComplete(i)
+			}
+		}
+	}
+	return completed, errors.Join(errs...)
+}
+
+// markFinished records that an action has finished, if it has an ID.
+func (engine flowEngine) markFinished(action lbdeploy.Action) {
+	if action.ID != "" && engine.finished != nil {
+		engine.finished.Add(action.ID)
+	}
+}
+
+// unmetDependency returns the first dependency of action that has not
+// finished, if there is one.
+func (engine flowEngine) unmetDependency(action lbdeploy.Action) (lbdeploy.ActionID, bool) {
+	for _, dependency := range action.DependsOn {
+		if !engine.finished.Contains(dependency) {
+			return dependency, true
+		}
+	}
+	return "", false
+}
+
+// rollback invokes the rollback actions of the given completed actions,
+// starting with the most recently completed action and working backwards.
+//
+// Rollback actions are invoked even if the context has been cancelled, and
+// a failure in one of them does not prevent the others from running.
+func (engine flowEngine) rollback(ctx context.Context, completed []lbdeploy.Action) error {
+	// Collect the rollback actions in reverse order of completion.
+	var actions []lbdeploy.Action
+	for i := len(completed) - 1; i >= 0; i-- {
+		actions = append(actions, completed[i].Rollback...)
+		if id := completed[i].ID; id != "" {
+			actions = append(actions, engine.flow.Definition.Rollback[id]...)
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	// Rollback actions are numbered after all of the flow's own actions.
+	def := engine.flow.Definition
+	offset := len(def.Before) + len(def.Actions) + len(def.After)
+
+	engine.events.Record(lbdeployevent.FlowRollbackStarted{
+		Deployment: engine.deployment.ID,
+		Flow:       engine.flow.ID,
+		Actions:    len(actions),
+	})
+
+	var stats lbdeploy.FlowStats
+	_, err := engine.invokeActions(context.WithoutCancel(ctx), actions, offset, false, &stats, nil)
+
+	engine.events.Record(lbdeployevent.FlowRollbackStopped{
 		Deployment: engine.deployment.ID,
 		Flow:       engine.flow.ID,
 		Stats:      stats,
-		Started:    started,
-		Stopped:    stopped,
 		Err:        err,
 	})
 
-	return err
+	if err != nil {
+		return fmt.Errorf("the \"%s\" flow failed to roll back: %w", engine.flow.ID, err)
+	}
+	return nil
+}
+
+// waitForMaintenanceWindow pauses until one of the maintenance windows that
+// apply to the flow is open. It returns immediately if no windows apply.
+func (engine flowEngine) waitForMaintenanceWindow(ctx context.Context, index int, actionType lbdeploy.ActionType) error {
+	windows := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior).MaintenanceWindows
+
+	for {
+		now := time.Now()
+		if windows.Open(now) {
+			return nil
+		}
+
+		opens, ok := windows.Next(now)
+		if !ok {
+			return fmt.Errorf("none of the maintenance windows of the \"%s\" flow ever open", engine.flow.ID)
+		}
+
+		engine.events.Record(lbdeployevent.MaintenanceWindowWaiting{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: index,
+			ActionType:  actionType,
+			Opens:       opens,
+		})
+
+		// Check again when the window is expected to open.
+		timer := time.NewTimer(time.Until(opens))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
 }
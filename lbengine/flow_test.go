@@ -0,0 +1,44 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlowTimedOut(t *testing.T) {
+	expired := func() context.Context {
+		ctx, cancel := context.WithTimeoutCause(context.Background(), time.Nanosecond, errFlowTimedOut)
+		t.Cleanup(cancel)
+		<-ctx.Done()
+		return ctx
+	}
+	cancelled := func() context.Context {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errors.New("cancelled by the user"))
+		return ctx
+	}
+
+	failure := errors.New("the action failed")
+
+	fixtures := []struct {
+		Name string
+		Ctx  context.Context
+		Err  error
+		Out  bool
+	}{
+		{Name: "ExpiredWithError", Ctx: expired(), Err: failure, Out: true},
+		{Name: "ExpiredWithoutError", Ctx: expired(), Err: nil, Out: false},
+		{Name: "RunningWithError", Ctx: context.Background(), Err: failure, Out: false},
+		{Name: "CancelledWithError", Ctx: cancelled(), Err: failure, Out: false},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			if got := flowTimedOut(fixture.Ctx, fixture.Err); got != fixture.Out {
+				t.Fatalf("unexpected result: got %t, want %t", got, fixture.Out)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package lbengine
+
+import "github.com/leafbridge/leafbridge-deploy/lbdeploy"
+
+// ForceScope identifies the work that a deployment engine performs even
+// when it would normally be skipped. The zero value forces nothing.
+type ForceScope struct {
+	// Downloads causes package files to be downloaded again, even when a
+	// verified copy of them is already staged. Each package is downloaded
+	// at most once per invocation.
+	Downloads bool
+
+	// Commands causes every command to be invoked, even when the apps
+	// that it installs or uninstalls are already in the desired state.
+	Commands bool
+
+	// Actions identifies individual actions whose commands are invoked
+	// even when the apps they install or uninstall are already in the
+	// desired state.
+	Actions []lbdeploy.ActionRef
+}
+
+// IsZero returns true if the scope forces nothing.
+func (scope ForceScope) IsZero() bool {
+	return !scope.Downloads && !scope.Commands && len(scope.Actions) == 0
+}
+
+// Command returns true if the command invoked by the given action in flow
+// should run regardless of the state of its apps. Actions that set their
+// own force field are always forced.
+func (scope ForceScope) Command(flow lbdeploy.FlowID, action actionData) bool {
+	if scope.Commands || action.Definition.Force {
+		return true
+	}
+	for _, ref := range scope.Actions {
+		if ref.Matches(flow, action.Index, action.Definition) {
+			return true
+		}
+	}
+	return false
+}
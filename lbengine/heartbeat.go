@@ -0,0 +1,45 @@
+package lbengine
+
+import (
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+)
+
+// startHeartbeat records a heartbeat event every interval until the
+// returned stop function is called. The event function is called to
+// prepare each heartbeat, and is given the time elapsed since the
+// heartbeat was started.
+//
+// If interval is not positive, no heartbeats are recorded.
+func startHeartbeat(events lbevent.Recorder, interval time.Duration, event func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	started := time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				events.Record(event(started, now.Sub(started)))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
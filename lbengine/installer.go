@@ -0,0 +1,118 @@
+package lbengine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gentlemanautomaton/winobj/winmutex"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+)
+
+// installerMutex is the name of the mutex held by the Windows Installer
+// while it is executing an installation.
+//
+// https://learn.microsoft.com/en-us/windows/win32/msi/-msiexecute-mutex
+const installerMutex = `Global\_MSIExecute`
+
+// Installer wait settings.
+const (
+	defaultInstallerWait = 15 * time.Minute
+	minInstallerBackoff  = 5 * time.Second
+	maxInstallerBackoff  = time.Minute
+)
+
+// Reasons that the Windows Installer is considered busy.
+const (
+	installerBusyMutex    = "another installation holds the _MSIExecute mutex"
+	installerBusyExitCode = "msiexec returned ERROR_INSTALL_ALREADY_RUNNING"
+)
+
+// installerWait keeps track of the time spent waiting for the Windows
+// Installer to become available.
+type installerWait struct {
+	deadline time.Time
+	backoff  time.Duration
+}
+
+// newInstallerWait prepares an installer wait that gives up after limit.
+// If limit is zero or negative, it gives up immediately.
+func newInstallerWait(limit time.Duration) installerWait {
+	return installerWait{
+		deadline: time.Now().Add(max(limit, 0)),
+		backoff:  minInstallerBackoff,
+	}
+}
+
+// next returns the amount of time to wait before checking again, and
+// increases the backoff for the following attempt. It returns false if the
+// deadline has passed.
+func (iw *installerWait) next() (time.Duration, bool) {
+	remaining := time.Until(iw.deadline)
+	if remaining <= 0 {
+		return 0, false
+	}
+	delay := min(iw.backoff, remaining)
+	iw.backoff = min(iw.backoff*2, maxInstallerBackoff)
+	return delay, true
+}
+
+// installerWait returns the maximum amount of time that the command will
+// wait for the Windows Installer to become available.
+func (engine *commandEngine) installerWait() time.Duration {
+	wait := time.Duration(lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior).InstallerWait)
+	if wait == 0 {
+		return defaultInstallerWait
+	}
+	return wait
+}
+
+// waitForInstaller waits until the Windows Installer's execution mutex is
+// free, backing off between checks. It returns an error if the mutex is
+// still held when the wait runs out.
+func (engine *commandEngine) waitForInstaller(ctx context.Context, iw *installerWait) error {
+	for {
+		busy, err := winmutex.Exists(installerMutex)
+		if err != nil {
+			return fmt.Errorf("failed to determine whether the Windows Installer is available: %w", err)
+		}
+		if !busy {
+			return nil
+		}
+		if err := engine.backoffInstaller(ctx, iw, installerBusyMutex); err != nil {
+			return err
+		}
+	}
+}
+
+// backoffInstaller records that the Windows Installer is busy for the given
+// reason and waits before the next attempt. It returns an error if there is
+// no time left to wait.
+func (engine *commandEngine) backoffInstaller(ctx context.Context, iw *installerWait, reason string) error {
+	delay, ok := iw.next()
+	if !ok {
+		return fmt.Errorf("the Windows Installer is still busy and %s cannot be invoked: %s", engine.cmdDesc(), reason)
+	}
+
+	engine.events.Record(lbdeployevent.InstallerBusy{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Package:     engine.pkg.ID,
+		Command:     engine.command.ID,
+		Reason:      reason,
+		Delay:       delay,
+		Deadline:    iw.deadline,
+	})
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/localfs"
+)
+
+// CreateHardLink creates a hard link at the destination file that refers
+// to the source file.
+func (engine *fileEngine) CreateHardLink(ctx context.Context) error {
+	// Find the relevant target file within the deployment.
+	targetID := engine.action.Definition.SourceFile
+	targetRef, err := engine.deployment.Resources.FileSystem.ResolveFile(targetID)
+	if err != nil {
+		return fmt.Errorf("source file: %w", err)
+	}
+
+	// Find the relevant link file within the deployment.
+	linkID := engine.action.Definition.DestinationFile
+	linkRef, err := engine.deployment.Resources.FileSystem.ResolveFile(linkID)
+	if err != nil {
+		return fmt.Errorf("destination file: %w", err)
+	}
+
+	// Make sure that neither file is in a protected location. A hard link
+	// to a protected file would allow the file to be modified through it.
+	if linkRef.Root.Protected() {
+		return fmt.Errorf("the destination file is located in the \"%s\" root, which is protected", linkRef.Root.ID())
+	}
+	if targetRef.Root.Protected() {
+		return fmt.Errorf("the source file is located in the \"%s\" root, which is protected", targetRef.Root.ID())
+	}
+
+	// Record the time that the link creation started.
+	started := time.Now()
+
+	var (
+		targetPath string
+		linkPath   string
+		existed    bool
+	)
+	err = func() error {
+		if targetPath, err = targetRef.Path(); err != nil {
+			return fmt.Errorf("unable to determine the path of the source file: %w", err)
+		}
+		if linkPath, err = linkRef.Path(); err != nil {
+			return fmt.Errorf("unable to determine the path of the destination file: %w", err)
+		}
+
+		// Make sure the target exists and is a regular file.
+		target, err := os.Stat(targetPath)
+		if err != nil {
+			return fmt.Errorf("unable to evaluate the source file: %w", err)
+		}
+		if !target.Mode().IsRegular() {
+			return errors.New("the source file path exists but is not a regular file")
+		}
+
+		// If the link already exists and refers to the target, there is
+		// nothing to do.
+		if link, err := os.Stat(linkPath); err == nil {
+			if os.SameFile(target, link) {
+				existed = true
+				return nil
+			}
+			return errors.New("the destination file path already exists and is not a hard link to the source file")
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("unable to evaluate the destination file: %w", err)
+		}
+
+		return os.Link(targetPath, linkPath)
+	}()
+
+	// Applications might be detected by the presence of files, so forget
+	// what we knew about them.
+	engine.state.apps.Clear()
+
+	// Record the link creation.
+	engine.events.Record(lbdeployevent.LinkCreated{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		TargetID:    string(targetID),
+		TargetPath:  targetPath,
+		LinkID:      string(linkID),
+		LinkPath:    linkPath,
+		Existed:     existed,
+		Started:     started,
+		Stopped:     time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
+
+// CreateJunction creates a directory junction at the destination directory
+// that refers to the source directory.
+func (engine *fileEngine) CreateJunction(ctx context.Context) error {
+	// Find the relevant target directory within the deployment.
+	targetID := engine.action.Definition.SourceDir
+	targetRef, err := engine.deployment.Resources.FileSystem.ResolveDirectory(targetID)
+	if err != nil {
+		return fmt.Errorf("source directory: %w", err)
+	}
+
+	// Find the relevant link directory within the deployment.
+	linkID := engine.action.Definition.DestinationDir
+	linkRef, err := engine.deployment.Resources.FileSystem.ResolveDirectory(linkID)
+	if err != nil {
+		return fmt.Errorf("destination directory: %w", err)
+	}
+
+	// Make sure that neither directory is in a protected location, and
+	// that the junction is not a known folder itself.
+	if linkRef.Root.Protected() {
+		return fmt.Errorf("the destination directory is located in the \"%s\" root, which is protected", linkRef.Root.ID())
+	}
+	if targetRef.Root.Protected() {
+		return fmt.Errorf("the source directory is located in the \"%s\" root, which is protected", targetRef.Root.ID())
+	}
+	if len(linkRef.Lineage) == 0 {
+		return errors.New("the destination directory must be a subdirectory of a known folder")
+	}
+	if !linkRef.Network.IsZero() || !targetRef.Network.IsZero() {
+		return errors.New("directory junctions cannot be created on or refer to network shares")
+	}
+
+	// Record the time that the junction creation started.
+	started := time.Now()
+
+	var (
+		targetPath string
+		linkPath   string
+		existed    bool
+	)
+	err = func() error {
+		if targetPath, err = targetRef.Path(); err != nil {
+			return fmt.Errorf("unable to determine the path of the source directory: %w", err)
+		}
+		if linkPath, err = linkRef.Path(); err != nil {
+			return fmt.Errorf("unable to determine the path of the destination directory: %w", err)
+		}
+
+		// Make sure the target exists and is a directory.
+		target, err := os.Stat(targetPath)
+		if err != nil {
+			return fmt.Errorf("unable to evaluate the source directory: %w", err)
+		}
+		if !target.IsDir() {
+			return errors.New("the source directory path exists but is not a directory")
+		}
+
+		// If the junction already exists and refers to the target, there
+		// is nothing to do.
+		if _, err := os.Lstat(linkPath); err == nil {
+			current, err := localfs.JunctionTarget(linkPath)
+			if err == nil && strings.EqualFold(filepath.Clean(current), filepath.Clean(targetPath)) {
+				existed = true
+				return nil
+			}
+			return errors.New("the destination directory path already exists and is not a junction to the source directory")
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("unable to evaluate the destination directory: %w", err)
+		}
+
+		return localfs.CreateJunction(linkPath, targetPath)
+	}()
+
+	// Applications might be detected by the presence of files, so forget
+	// what we knew about them.
+	engine.state.apps.Clear()
+
+	// Record the junction creation.
+	engine.events.Record(lbdeployevent.LinkCreated{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		TargetID:    string(targetID),
+		TargetPath:  targetPath,
+		LinkID:      string(linkID),
+		LinkPath:    linkPath,
+		Existed:     existed,
+		Started:     started,
+		Stopped:     time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
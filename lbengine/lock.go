@@ -1,22 +1,30 @@
 package lbengine
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/gentlemanautomaton/winobj/winmutex"
 	"github.com/leafbridge/leafbridge-deploy/internal/reentrantlock"
+	"github.com/leafbridge/leafbridge-deploy/internal/winsemaphore"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 )
 
 // lockManager is responsible for acquiring locks on system-wide resources.
 type lockManager struct {
+	mutex sync.Mutex
 	locks map[lbdeploy.LockID]Lock
+	wait  time.Duration
 }
 
-// newLockManager prepares a lock manager.
-func newLockManager() *lockManager {
+// newLockManager prepares a lock manager. The given wait time is used for
+// locks that do not specify their own.
+func newLockManager(wait time.Duration) *lockManager {
 	return &lockManager{
 		locks: make(map[lbdeploy.LockID]Lock),
+		wait:  wait,
 	}
 }
 
@@ -26,7 +34,10 @@ func newLockManager() *lockManager {
 // If any of the requested locks already exist within the lock manager, the
 // existing lock will be included in the group membership.
 func (lm *lockManager) Create(resources lbdeploy.Resources, locks ...lbdeploy.LockID) (LockGroup, error) {
-	var group LockGroup
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	group := LockGroup{wait: lm.wait}
 
 	for _, id := range locks {
 		if lock, exists := lm.locks[id]; exists {
@@ -47,6 +58,9 @@ func (lm *lockManager) Create(resources lbdeploy.Resources, locks ...lbdeploy.Lo
 // CloseAll attempts to release and close all locks currently held by the
 // lock manager.
 func (lm *lockManager) CloseAll() error {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
 	for id, lock := range lm.locks {
 		lock.locker.Close()
 		delete(lm.locks, id)
@@ -61,38 +75,76 @@ func createLock(resources lbdeploy.Resources, lock lbdeploy.LockID) (Lock, error
 	if !found {
 		return Lock{}, fmt.Errorf("the requested lock ID \"%s\" is not declared in the deployment's resources", lock)
 	}
-	mutex := lockDefinition.Mutex
-	if mutex == "" {
-		return Lock{}, fmt.Errorf("the \"%s\" lock does not identify a mutex that it locks", lock)
+
+	// Create or open the object that backs the lock.
+	var locker reentrantlock.Locker
+	switch {
+	case lockDefinition.Mutex != "" && lockDefinition.Semaphore != "":
+		return Lock{}, fmt.Errorf("the \"%s\" lock identifies both a mutex and a semaphore", lock)
+	case lockDefinition.Mutex != "":
+		m, err := openMutex(resources, lockDefinition.Mutex)
+		if err != nil {
+			return Lock{}, err
+		}
+		locker = reentrantlock.Wrap(m)
+	case lockDefinition.Semaphore != "":
+		s, err := openSemaphore(resources, lockDefinition.Semaphore)
+		if err != nil {
+			return Lock{}, err
+		}
+		locker = reentrantlock.Wrap(s)
+	default:
+		return Lock{}, fmt.Errorf("the \"%s\" lock does not identify a mutex or semaphore that it locks", lock)
 	}
 
+	// Return a lock that includes a reentrant variant of the object.
+	return Lock{
+		id:     lock,
+		def:    lockDefinition,
+		locker: locker,
+	}, nil
+}
+
+// openMutex creates or opens the system-wide mutex with the given ID.
+func openMutex(resources lbdeploy.Resources, mutex lbdeploy.MutexID) (*winmutex.Mutex, error) {
 	// Find the mutex with the deployment's resources and verify it.
 	mutexDefinition, found := resources.Mutexes[mutex]
 	if !found {
-		return Lock{}, fmt.Errorf("the requested mutex ID \"%s\" is not declared in the deployment's resources", mutex)
+		return nil, fmt.Errorf("the requested mutex ID \"%s\" is not declared in the deployment's resources", mutex)
 	}
-	if mutexDefinition.Name == "" {
-		return Lock{}, fmt.Errorf("the \"%s\" mutex is missing mutex name", mutex)
+	if err := mutexDefinition.Validate(); err != nil {
+		return nil, fmt.Errorf("the \"%s\" mutex is not valid: %w", mutex, err)
 	}
 
 	// Determine the name of the mutex.
 	mutexName, err := mutexDefinition.ObjectName()
 	if err != nil {
-		return Lock{}, err
+		return nil, err
 	}
 
 	// Create or open the mutex.
-	m, err := winmutex.New(mutexName)
+	return winmutex.New(mutexName)
+}
+
+// openSemaphore creates or opens the system-wide semaphore with the given ID.
+func openSemaphore(resources lbdeploy.Resources, semaphore lbdeploy.SemaphoreID) (*winsemaphore.Semaphore, error) {
+	// Find the semaphore with the deployment's resources and verify it.
+	semaphoreDefinition, found := resources.Semaphores[semaphore]
+	if !found {
+		return nil, fmt.Errorf("the requested semaphore ID \"%s\" is not declared in the deployment's resources", semaphore)
+	}
+	if err := semaphoreDefinition.Validate(); err != nil {
+		return nil, fmt.Errorf("the \"%s\" semaphore is not valid: %w", semaphore, err)
+	}
+
+	// Determine the name of the semaphore.
+	semaphoreName, err := semaphoreDefinition.ObjectName()
 	if err != nil {
-		return Lock{}, err
+		return nil, err
 	}
 
-	// Return a lock that includes a reentrant variant of the mutex.
-	return Lock{
-		id:     lock,
-		def:    lockDefinition,
-		locker: reentrantlock.Wrap(m),
-	}, nil
+	// Create or open the semaphore.
+	return winsemaphore.New(semaphoreName, semaphoreDefinition.Count)
 }
 
 // Lock is a lockable resource.
@@ -106,36 +158,53 @@ type Lock struct {
 type LockError struct {
 	LockID lbdeploy.LockID
 	Lock   lbdeploy.Lock
+	Waited time.Duration
 }
 
 // Error returns a string describing the error.
 func (e LockError) Error() string {
+	desc := fmt.Sprintf("failed to acquire \"%s\" lock", e.LockID)
+	if e.Waited > 0 {
+		desc += fmt.Sprintf(" after waiting %s", e.Waited)
+	}
 	if e.Lock.ConflictRules.Message != "" {
-		return fmt.Sprintf("failed to acquire \"%s\" lock: %s", e.LockID, e.Lock.ConflictRules.Message)
+		return fmt.Sprintf("%s: %s", desc, e.Lock.ConflictRules.Message)
 	}
-	return fmt.Sprintf("failed to acquire \"%s\" lock", e.LockID)
+	return desc
 }
 
 // LockGroup facilitates locking and unlocking a group of lockable resources
 // together.
 type LockGroup struct {
 	members []Lock
+	wait    time.Duration
 }
 
+// lockPollInterval is the interval at which a lock group checks whether a
+// lock held by another process has become available.
+const lockPollInterval = time.Second
+
+// LockWaitFunc is called when a lock group begins waiting for a lock that is
+// held by another process.
+type LockWaitFunc func(id lbdeploy.LockID, timeout time.Duration)
+
 // Lock attempts to lock all entries in the group.
 //
+// If a member of the group is held by another process, it waits for up to
+// the member's wait time for the lock to become available, falling back to
+// the lock manager's wait time if the member doesn't specify one. If onWait
+// is non-nil, it is called when waiting begins.
+//
 // If any member of the group fails to acquire its lock, all locks in the
-// group are released and it returns an error of type LockError.
-func (group LockGroup) Lock() error {
+// group are released and it returns an error of type LockError. If ctx is
+// cancelled while waiting, it returns the context's error.
+func (group LockGroup) Lock(ctx context.Context, onWait LockWaitFunc) error {
 	for i, member := range group.members {
-		if !member.locker.TryLock() {
+		if err := member.lock(ctx, group.wait, onWait); err != nil {
 			for j := i - 1; j >= 0; j-- {
 				group.members[j].locker.Unlock()
 			}
-			return LockError{
-				LockID: member.id,
-				Lock:   member.def,
-			}
+			return err
 		}
 	}
 	return nil
@@ -148,3 +217,44 @@ func (group LockGroup) Unlock() {
 		member.locker.Unlock()
 	}
 }
+
+// lock attempts to acquire the lock, waiting for it if necessary.
+func (lock Lock) lock(ctx context.Context, defaultWait time.Duration, onWait LockWaitFunc) error {
+	if lock.locker.TryLock() {
+		return nil
+	}
+
+	wait := time.Duration(lock.def.ConflictRules.Wait)
+	if wait == 0 {
+		wait = defaultWait
+	}
+	if wait <= 0 {
+		return LockError{LockID: lock.id, Lock: lock.def}
+	}
+
+	if onWait != nil {
+		onWait(lock.id, wait)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if lock.locker.TryLock() {
+				return nil
+			}
+			return LockError{LockID: lock.id, Lock: lock.def, Waited: wait}
+		case <-ticker.C:
+			if lock.locker.TryLock() {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package lbengine
+
+import (
+	"strings"
+	"syscall"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// msiCommandLine builds a command line for msiexec.
+//
+// Arguments in the form PROPERTY=VALUE are written as PROPERTY="VALUE",
+// with any quotes in the value doubled, which is the form that msiexec
+// expects. Other arguments are escaped in the usual manner.
+func msiCommandLine(execPath string, args []string) string {
+	var out strings.Builder
+	out.WriteString(syscall.EscapeArg(execPath))
+	for _, arg := range args {
+		out.WriteByte(' ')
+		if name, value, ok := strings.Cut(arg, "="); ok && lbdeploy.IsMSIPropertyName(name) {
+			out.WriteString(name)
+			out.WriteByte('=')
+			out.WriteString(msiQuote(value))
+		} else {
+			out.WriteString(syscall.EscapeArg(arg))
+		}
+	}
+	return out.String()
+}
+
+// msiQuote quotes a property value for msiexec if it is empty or contains
+// spaces or quotes.
+func msiQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
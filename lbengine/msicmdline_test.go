@@ -0,0 +1,105 @@
+package lbengine
+
+import "testing"
+
+func TestMSIQuote(t *testing.T) {
+	fixtures := []struct {
+		In  string
+		Out string
+	}{
+		{In: "1", Out: `1`},
+		{In: `C:\Program`, Out: `C:\Program`},
+		{In: "", Out: `""`},
+		{In: `C:\Program Files\App`, Out: `"C:\Program Files\App"`},
+		{In: "a\tb", Out: "\"a\tb\""},
+		{In: `say "hi"`, Out: `"say ""hi"""`},
+		{In: `"`, Out: `""""`},
+		{In: `a=b`, Out: `a=b`},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.In, func(t *testing.T) {
+			if got := msiQuote(fixture.In); got != fixture.Out {
+				t.Fatalf("unexpected quoted value: got %s, want %s", got, fixture.Out)
+			}
+		})
+	}
+}
+
+func TestMSICommandLine(t *testing.T) {
+	const msiexec = `C:\Windows\System32\msiexec.exe`
+
+	fixtures := []struct {
+		Name string
+		Args []string
+		Out  string
+	}{
+		{
+			Name: "NoArguments",
+			Out:  msiexec,
+		},
+		{
+			Name: "Switches",
+			Args: []string{"/i", `C:\Staging\app.msi`, "/qn", "/norestart"},
+			Out:  msiexec + ` /i C:\Staging\app.msi /qn /norestart`,
+		},
+		{
+			Name: "PackagePathWithSpaces",
+			Args: []string{"/i", `C:\Staging Area\app.msi`},
+			Out:  msiexec + ` /i "C:\Staging Area\app.msi"`,
+		},
+		{
+			Name: "PropertyWithSpaces",
+			Args: []string{`INSTALLDIR=C:\Program Files\App`},
+			Out:  msiexec + ` INSTALLDIR="C:\Program Files\App"`,
+		},
+		{
+			Name: "PropertyWithQuotes",
+			Args: []string{`GREETING=say "hi"`},
+			Out:  msiexec + ` GREETING="say ""hi"""`,
+		},
+		{
+			Name: "PropertyEmpty",
+			Args: []string{"REBOOT="},
+			Out:  msiexec + ` REBOOT=""`,
+		},
+		{
+			Name: "PropertyValueWithEquals",
+			Args: []string{"OPTIONS=a=b c=d"},
+			Out:  msiexec + ` OPTIONS="a=b c=d"`,
+		},
+		{
+			Name: "PropertyNameWithDigitsAndDots",
+			Args: []string{"_Config.2=on"},
+			Out:  msiexec + ` _Config.2=on`,
+		},
+		{
+			Name: "LogPathWithEquals",
+			Args: []string{"/l*v", `C:\Logs\a=b.log`},
+			Out:  msiexec + ` /l*v C:\Logs\a=b.log`,
+		},
+		{
+			Name: "NonPropertyWithEqualsAndSpaces",
+			Args: []string{`/l*v`, `C:\Log Files\a=b.log`},
+			Out:  msiexec + ` /l*v "C:\Log Files\a=b.log"`,
+		},
+		{
+			Name: "NonPropertyWithLeadingDigit",
+			Args: []string{"1ABC=x y"},
+			Out:  msiexec + ` "1ABC=x y"`,
+		},
+		{
+			Name: "NonPropertyWithQuotes",
+			Args: []string{`/x`, `say "hi"`},
+			Out:  msiexec + ` /x "say \"hi\""`,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			if got := msiCommandLine(msiexec, fixture.Args); got != fixture.Out {
+				t.Fatalf("unexpected command line:\ngot  %s\nwant %s", got, fixture.Out)
+			}
+		})
+	}
+}
@@ -1,9 +1,47 @@
 package lbengine
 
-import "github.com/leafbridge/leafbridge-deploy/lbevent"
+import (
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+)
 
 // Options hold configuration options for a LeafBridge deployment engine.
 type Options struct {
 	Events lbevent.Recorder
-	Force  bool
+
+	// Force identifies the downloads and commands that are performed even
+	// when they would normally be skipped.
+	Force ForceScope
+
+	// LockWait is the default amount of time to wait for a lock that is
+	// held by another process. It applies to locks that do not specify
+	// their own wait time. If it is zero, lock acquisition fails
+	// immediately when a lock is held by another process.
+	LockWait time.Duration
+
+	// Resume causes each flow to skip the actions that were completed by
+	// a previous invocation of the same flow that did not finish. Progress
+	// is always recorded, whether or not Resume is set.
+	Resume bool
+
+	// Parallelism is the maximum number of flows that may be invoked at
+	// the same time when several flows are requested. Only flows that have
+	// no locks or resources in common are invoked concurrently. A value of
+	// zero or one causes flows to be invoked one at a time.
+	Parallelism int
+
+	// Heartbeat is the interval at which heartbeat events are recorded
+	// while commands, downloads and extractions are running. If it is
+	// zero, heartbeat events are not recorded.
+	Heartbeat time.Duration
+
+	// PeerSharing causes package files to be sought from peers on the
+	// local network before they are downloaded from their own sources.
+	// Files obtained from peers are verified like any other download.
+	PeerSharing bool
+
+	// Filter selects the actions of each flow that are invoked. Actions
+	// that it excludes are skipped and recorded as such.
+	Filter ActionFilter
 }
@@ -0,0 +1,25 @@
+package lbengine
+
+import (
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+)
+
+// startOverdueTimer records event if the returned stop function hasn't
+// been called within expected. The event is recorded at most once.
+//
+// If expected is not positive, no event is recorded.
+func startOverdueTimer(events lbevent.Recorder, expected time.Duration, event lbevent.Interface) (stop func()) {
+	if expected <= 0 {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(expected, func() {
+		events.Record(event)
+	})
+
+	return func() {
+		timer.Stop()
+	}
+}
@@ -3,6 +3,7 @@ package lbengine
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
@@ -24,7 +25,7 @@ type packageEngine struct {
 	action     actionData
 	pkg        packageData
 	events     lbevent.Recorder
-	force      bool
+	force      ForceScope
 	state      *engineState
 }
 
@@ -43,6 +44,7 @@ func (engine *packageEngine) PreparePackage(ctx context.Context) error {
 		flow:       engine.flow,
 		action:     engine.action,
 		events:     engine.events,
+		force:      engine.force,
 		state:      engine.state,
 	}
 
@@ -65,7 +67,7 @@ func (engine *packageEngine) InvokeCommand(ctx context.Context, command lbdeploy
 	data := commandData{ID: command, Definition: commandDefinition}
 
 	// Determine whether any app changes are anticipated.
-	ae := NewAppEngine(engine.deployment)
+	ae := newCachedAppEngine(engine.deployment, engine.state.apps)
 	appEvaluation, err := ae.EvaluateAppChanges(commandDefinition.Installs, commandDefinition.Uninstalls)
 	if err != nil {
 		return fmt.Errorf("the evaluation of potential application changes did not succeed: %w", err)
@@ -78,7 +80,7 @@ func (engine *packageEngine) InvokeCommand(ctx context.Context, command lbdeploy
 		if !appEvaluation.ActionsNeeded() {
 			// If all app installs and uninstalls are already in effect,
 			// and command invocation isn't forced, skip this command.
-			if !engine.force && !engine.action.Definition.Force {
+			if !engine.force.Command(engine.flow.ID, engine.action) {
 				// Record that this command is being skipped.
 				engine.events.Record(lbdeployevent.CommandSkipped{
 					Deployment:  engine.deployment.ID,
@@ -116,7 +118,7 @@ func (engine *packageEngine) InvokeCommand(ctx context.Context, command lbdeploy
 func (engine *packageEngine) invokePackageCommand(ctx context.Context, command commandData, apps lbdeploy.AppEvaluation) error {
 	// Check the state to see whether we've already downloaded and verified
 	// the package file.
-	packageDir, alreadyVerified := engine.state.verifiedPackageFiles[engine.pkg.ID]
+	packageDir, alreadyVerified := engine.state.verifiedPackageFile(engine.pkg.ID)
 	if !alreadyVerified {
 		// Prepare the package directory.
 		var err error
@@ -140,6 +142,7 @@ func (engine *packageEngine) invokePackageCommand(ctx context.Context, command c
 				flow:       engine.flow,
 				action:     engine.action,
 				events:     engine.events,
+				force:      engine.force,
 				state:      engine.state,
 			}
 
@@ -170,7 +173,7 @@ func (engine *packageEngine) invokePackageCommand(ctx context.Context, command c
 		//
 		// This will also cause the deployment engine to close the package
 		// directory after the deployment's invocation has finished.
-		engine.state.verifiedPackageFiles[engine.pkg.ID] = packageDir
+		engine.state.addVerifiedPackageFile(engine.pkg.ID, packageDir)
 	}
 
 	// Prepare a command engine.
@@ -192,87 +195,180 @@ func (engine *packageEngine) invokePackageCommand(ctx context.Context, command c
 
 // invokeArchiveCommand runs a command on an archive package.
 func (engine *packageEngine) invokeArchiveCommand(ctx context.Context, command commandData, apps lbdeploy.AppEvaluation) error {
+	// Download, verify and extract the package.
+	extractedFiles, err := engine.prepareArchive(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Prepare a command engine.
+	ce := commandEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		pkg:        engine.pkg,
+		command:    command,
+		apps:       apps,
+		events:     engine.events,
+		force:      engine.force,
+		state:      engine.state,
+	}
+
+	// Invoke the command.
+	return ce.InvokeArchive(ctx, extractedFiles)
+}
+
+// prepareArchive downloads, verifies and extracts an archive package, and
+// returns the directory that holds its extracted files. If the package has
+// already been extracted, the existing directory is returned.
+func (engine *packageEngine) prepareArchive(ctx context.Context) (tempfs.ExtractionDir, error) {
 	// Check the state to see whether we've already downloaded, verified and
 	// extracted the files in this package.
-	extractedFiles, alreadyExtracted := engine.state.extractedPackages[engine.pkg.ID]
+	extractedFiles, alreadyExtracted := engine.state.extractedPackage(engine.pkg.ID)
+	if alreadyExtracted {
+		return extractedFiles, nil
+	}
 
-	// Download, verify and extract the package if we haven't done so already.
-	if !alreadyExtracted {
-		// Open the package file, or create it if it doesn't exist.
-		packageFile, err := engine.openPackageFile()
-		if err != nil {
-			return fmt.Errorf("failed to prepare package file: %w", err)
-		}
-		defer packageFile.Close()
-
-		// Prepare a download engine.
-		de := downloadEngine{
-			deployment: engine.deployment,
-			flow:       engine.flow,
-			action:     engine.action,
-			events:     engine.events,
-			state:      engine.state,
-		}
+	// Open the package file, or create it if it doesn't exist.
+	packageFile, err := engine.openPackageFile()
+	if err != nil {
+		return tempfs.ExtractionDir{}, fmt.Errorf("failed to prepare package file: %w", err)
+	}
+	defer packageFile.Close()
 
-		// Download and verify the package data.
-		//
-		// If the file already contains the expected data, the download will be
-		// skipped.
-		//
-		// If the file was partially downloaded, the download will be resumed.
-		if err := de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile); err != nil {
-			return err
-		}
+	// Prepare a download engine.
+	de := downloadEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		force:      engine.force,
+		state:      engine.state,
+	}
+
+	// Prepare an extraction engine.
+	ee := extractionEngine{
+		deployment: engine.deployment,
+		flow:       engine.flow,
+		action:     engine.action,
+		events:     engine.events,
+		state:      engine.state,
+	}
 
+	// Download and verify the package data.
+	//
+	// If the file already contains the expected data, the download will be
+	// skipped.
+	//
+	// If the file was partially downloaded, the download will be resumed.
+	//
+	// If requested, the package will be extracted while it downloads.
+	var streamed bool
+	extractedFiles, streamed, err = engine.downloadArchive(ctx, &de, &ee, packageFile)
+	if err != nil {
+		return tempfs.ExtractionDir{}, err
+	}
+
+	// Extract the files if they weren't extracted during the download.
+	if !streamed {
 		// Create a temporary directory to hold the extracted files.
-		extractedFiles, err = tempfs.OpenExtractionDirForPackage(lbdeploy.PackageContent{
-			ID:          engine.pkg.ID,
-			PrimaryHash: engine.pkg.Definition.Attributes.Hashes.Primary(),
-		}, tempfs.Options{
-			DeleteOnClose: true,
-		})
+		extractedFiles, err = engine.openExtractionDir()
 		if err != nil {
-			return fmt.Errorf("failed to prepare a directory for file extraction: %w", err)
-		}
-
-		// Prepare an extraction engine.
-		ee := extractionEngine{
-			deployment: engine.deployment,
-			flow:       engine.flow,
-			action:     engine.action,
-			events:     engine.events,
-			state:      engine.state,
+			return tempfs.ExtractionDir{}, fmt.Errorf("failed to prepare a directory for file extraction: %w", err)
 		}
 
 		// Extract the files.
 		if err := ee.ExtractPackage(ctx, packageFile, extractedFiles); err != nil {
 			extractedFiles.Close()
-			return fmt.Errorf("extraction failed: %w", err)
+			return tempfs.ExtractionDir{}, fmt.Errorf("extraction failed: %w", err)
 		}
+	}
 
-		// Add the extracted files to the engine's state, so that they'll be
-		// available for other flows.
-		//
-		// This will also cause the deployment engine to close the extracted
-		// files after the deployment's invocation has finished.
-		engine.state.extractedPackages[engine.pkg.ID] = extractedFiles
+	// Add the extracted files to the engine's state, so that they'll be
+	// available for other flows.
+	//
+	// This will also cause the deployment engine to close the extracted
+	// files after the deployment's invocation has finished.
+	engine.state.addExtractedPackage(engine.pkg.ID, extractedFiles)
+
+	return extractedFiles, nil
+}
+
+// downloadArchive downloads and verifies an archive package. If the
+// package calls for stream extraction and the file hasn't been fully
+// downloaded, the archive is extracted while it downloads.
+//
+// If streamed is true, the returned directory holds the extracted files,
+// which match the verified archive. Otherwise the archive must still be
+// extracted.
+func (engine *packageEngine) downloadArchive(ctx context.Context, de *downloadEngine, ee *extractionEngine, packageFile stagingfs.PackageFile) (extracted tempfs.ExtractionDir, streamed bool, err error) {
+	// Determine whether stream extraction is worthwhile.
+	if !engine.pkg.Definition.StreamExtraction {
+		return tempfs.ExtractionDir{}, false, de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile)
+	}
+	fi, err := packageFile.Stat()
+	if err != nil || fi.Size() >= engine.pkg.Definition.Attributes.Size {
+		return tempfs.ExtractionDir{}, false, de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile)
 	}
 
-	// Prepare a command engine.
-	ce := commandEngine{
-		deployment: engine.deployment,
-		flow:       engine.flow,
-		action:     engine.action,
-		pkg:        engine.pkg,
-		command:    command,
-		apps:       apps,
-		events:     engine.events,
-		force:      engine.force,
-		state:      engine.state,
+	// Open a separate handle for reading the file as it grows.
+	reader, err := os.Open(packageFile.Path)
+	if err != nil {
+		return tempfs.ExtractionDir{}, false, de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile)
 	}
+	defer reader.Close()
 
-	// Invoke the command.
-	return ce.InvokeArchive(ctx, extractedFiles)
+	// Create a temporary directory to hold the extracted files.
+	extracted, err = engine.openExtractionDir()
+	if err != nil {
+		return tempfs.ExtractionDir{}, false, de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile)
+	}
+
+	// Extract the archive in the background as data is written to it.
+	source := newGrowingFile(reader, fi.Size())
+	de.written = source.Grow
+
+	var (
+		entries   []streamedEntry
+		streamErr error
+		done      = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		entries, streamErr = ee.StreamPackage(ctx, source, packageFile.Path, extracted)
+	}()
+
+	// Download and verify the package, then wait for the extraction to
+	// finish.
+	err = de.DownloadAndVerifyPackage(ctx, engine.pkg, packageFile)
+	source.Finish(err)
+	<-done
+
+	if err != nil {
+		extracted.Close()
+		return tempfs.ExtractionDir{}, false, err
+	}
+
+	// Only use the extracted files if they match the verified archive.
+	if streamErr == nil {
+		if match, _ := matchesStreamedEntries(packageFile, entries); match {
+			return extracted, true, nil
+		}
+	}
+
+	extracted.Close()
+	return tempfs.ExtractionDir{}, false, nil
+}
+
+// openExtractionDir creates a temporary directory to hold the extracted
+// files of the package. The directory is deleted when it is closed.
+func (engine *packageEngine) openExtractionDir() (tempfs.ExtractionDir, error) {
+	return tempfs.OpenExtractionDirForPackage(lbdeploy.PackageContent{
+		ID:          engine.pkg.ID,
+		PrimaryHash: engine.pkg.Definition.Attributes.Hashes.Primary(),
+	}, tempfs.Options{
+		DeleteOnClose: true,
+	})
 }
 
 // invokeAppCommand runs a command on an application.
@@ -0,0 +1,56 @@
+package lbengine
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbpeer"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// peerSearchTimeout is the amount of time to wait for peers on the local
+// network to answer a search for a package file.
+const peerSearchTimeout = 2 * time.Second
+
+// findPeerSources searches the local network for peers that hold the
+// package file, and returns a package source for each of them.
+//
+// Peers are only trusted to hold a file with the package's content hash.
+// The downloaded file must pass verification like any other download.
+func (engine *downloadEngine) findPeerSources(ctx context.Context, pkg packageData, file stagingfs.PackageFile) []lbdeploy.PackageSource {
+	peers, err := lbpeer.Find(ctx, pkg.Definition.Attributes.Hashes.Primary(), peerSearchTimeout)
+
+	// Record the outcome of the search.
+	engine.events.Record(lbdeployevent.PeersFound{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		FileName:    file.Name,
+		Peers:       peers,
+		Err:         err,
+	})
+
+	sources := make([]lbdeploy.PackageSource, 0, len(peers))
+	for _, peer := range peers {
+		sources = append(sources, lbdeploy.PackageSource{
+			Type: lbdeploy.PackageSourceHTTP,
+			URL:  peer,
+		})
+	}
+
+	engine.peerSources = sources
+	return sources
+}
+
+// isPeerSource returns true if source is a peer on the local network.
+// Peers come and go, so their statistics are not kept in the source
+// journal.
+func (engine *downloadEngine) isPeerSource(source lbdeploy.PackageSource) bool {
+	return slices.ContainsFunc(engine.peerSources, func(peer lbdeploy.PackageSource) bool {
+		return peer.Type == source.Type && peer.URL == source.URL
+	})
+}
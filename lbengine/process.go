@@ -1,10 +1,13 @@
 package lbengine
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/gentlemanautomaton/winproc"
+	"github.com/gentlemanautomaton/winproc/processaccess"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
 )
 
 // NumberOfRunningProcesses returns the number of processes running on the
@@ -23,6 +26,42 @@ func NumberOfRunningProcesses(match lbdeploy.ProcessMatch) (n int, err error) {
 	return len(procs), nil
 }
 
+// StopProcesses terminates the processes running on the local system that
+// match the given criteria. It returns the number of processes that were
+// stopped.
+//
+// Processes that exit on their own before they can be terminated are not
+// counted.
+func StopProcesses(match lbdeploy.ProcessMatch) (stopped int, err error) {
+	filter, err := buildProcessFilter(match)
+	if err != nil {
+		return 0, err
+	}
+
+	procs, err := winproc.List(winproc.Include(filter))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, proc := range procs {
+		ref, err := proc.Ref(processaccess.Terminate)
+		if err != nil {
+			if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+				continue // The process has already exited.
+			}
+			return stopped, fmt.Errorf("unable to open process %d (%s): %w", proc.ID, proc.Name, err)
+		}
+		err = ref.Terminate(1)
+		ref.Close()
+		if err != nil {
+			return stopped, fmt.Errorf("unable to terminate process %d (%s): %w", proc.ID, proc.Name, err)
+		}
+		stopped++
+	}
+
+	return stopped, nil
+}
+
 // buildProcessFilter prepares a Windows process filter for the given
 // criteria.
 func buildProcessFilter(match lbdeploy.ProcessMatch) (winproc.Filter, error) {
@@ -0,0 +1,77 @@
+package lbengine
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// appPathsKey is the registry key in which applications register the
+// location of their executables.
+const appPathsKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\App Paths`
+
+// findProgram returns the absolute path of the program with the given name.
+// If the name does not have an extension, ".exe" is assumed.
+//
+// The directories in the PATH environment variable are searched first,
+// followed by the App Paths registry key.
+func findProgram(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("a program name was not provided")
+	}
+	if strings.ContainsAny(name, `\/:`) {
+		return "", errors.New("the program name must not include a path")
+	}
+	if filepath.Ext(name) == "" {
+		name += ".exe"
+	}
+
+	// Look for the program in PATH.
+	if path, err := exec.LookPath(name); err == nil {
+		return filepath.Abs(path)
+	}
+
+	// Look for the program in App Paths.
+	if path, err := appPath(name); err == nil {
+		return path, nil
+	}
+
+	return "", exec.ErrNotFound
+}
+
+// appPath returns the path of the program with the given name from the App
+// Paths registry key.
+func appPath(name string) (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, appPathsKey+`\`+name, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	value, _, err := key.GetStringValue("")
+	if err != nil {
+		return "", err
+	}
+
+	path, err := registry.ExpandString(strings.Trim(value, `"`))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		return "", errors.New("the registered program path is not absolute")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", errors.New("the registered program path is not a regular file")
+	}
+
+	return path, nil
+}
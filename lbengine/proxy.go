@@ -0,0 +1,92 @@
+package lbengine
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/winproxy"
+)
+
+// proxyFunc selects the proxy that a request is sent through. It returns
+// nil if the request is sent directly.
+type proxyFunc func(req *http.Request) (*url.URL, error)
+
+// directTransport sends requests directly, without a proxy. It is used for
+// peers on the local network.
+var directTransport = func() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return transport
+}()
+
+// transportFor returns the transport that requests to source are sent
+// through.
+func (engine *downloadEngine) transportFor(source lbdeploy.PackageSource) (http.RoundTripper, error) {
+	if engine.isPeerSource(source) {
+		return directTransport, nil
+	}
+
+	engine.transportMutex.Lock()
+	defer engine.transportMutex.Unlock()
+
+	if engine.transport == nil {
+		settings := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior).Proxy
+		proxy, err := newProxyFunc(settings)
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		engine.transport = transport
+	}
+
+	return engine.transport, nil
+}
+
+// newProxyFunc returns a proxy function that honors the given proxy
+// settings.
+func newProxyFunc(settings lbdeploy.ProxySettings) (proxyFunc, error) {
+	switch {
+	case settings.URL != "":
+		proxyURL, err := url.Parse(settings.URL)
+		if err != nil {
+			return nil, fmt.Errorf("the proxy URL is invalid: %w", err)
+		}
+		return func(req *http.Request) (*url.URL, error) {
+			if settings.Bypasses(req.URL.Hostname()) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}, nil
+	case settings.UseSystem:
+		config, err := winproxy.Default()
+		if err != nil {
+			return nil, fmt.Errorf("the system proxy configuration could not be read: %w", err)
+		}
+		bypass := slices.Concat(settings.Bypass, config.Bypass)
+		return func(req *http.Request) (*url.URL, error) {
+			if lbdeploy.BypassesProxy(bypass, req.URL.Hostname()) {
+				return nil, nil
+			}
+			server := config.ProxyFor(req.URL.Scheme)
+			if server == "" {
+				return nil, nil
+			}
+			if !strings.Contains(server, "://") {
+				server = "http://" + server
+			}
+			return url.Parse(server)
+		}, nil
+	default:
+		return func(req *http.Request) (*url.URL, error) {
+			if settings.Bypasses(req.URL.Hostname()) {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}, nil
+	}
+}
@@ -0,0 +1,330 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// minRangeSize is the smallest part of a file that is downloaded by a
+// single range request. Files that are too small to give each connection
+// at least this much are downloaded with fewer connections.
+const minRangeSize = 16 << 20 // 16 MiB
+
+// errRangesNotSupported is returned when a server answers a range request
+// with the whole file.
+var errRangesNotSupported = errors.New("the server does not support range requests")
+
+// rangeCount returns the number of concurrent range requests that the
+// remainder of a file is downloaded with from source, starting at offset.
+// It returns 1 if the file should be downloaded with a single request.
+func rangeCount(source lbdeploy.PackageSource, offset, expectedSize int64) int {
+	if !source.Type.IsHTTPBased() || source.Connections < 2 || expectedSize <= offset {
+		return 1
+	}
+	n := (expectedSize - offset) / minRangeSize
+	return int(max(min(n, int64(source.Connections)), 1))
+}
+
+// byteRange is an inclusive range of bytes within a file.
+type byteRange struct {
+	Start int64
+	End   int64
+}
+
+// splitRanges divides the bytes from offset up to size into n ranges of
+// roughly equal length.
+func splitRanges(offset, size int64, n int) []byteRange {
+	ranges := make([]byteRange, 0, n)
+	length := (size - offset) / int64(n)
+	for i := range n {
+		r := byteRange{Start: offset + int64(i)*length, End: offset + int64(i+1)*length - 1}
+		if i == n-1 {
+			r.End = size - 1
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// downloadRanges downloads the remainder of a file from source with n
+// concurrent range requests, writing each range to its place in file. When
+// every range has been written, the new content is read back into the
+// verifier. It returns the number of bytes that were downloaded.
+//
+// If any range fails, the others are cancelled and the file is truncated
+// to the content that the verifier has absorbed, so that the file and the
+// verifier stay consistent. An interrupted download is therefore resumed
+// from where the verifier left off. If the server doesn't support range
+// requests, errRangesNotSupported is returned and the file is left as it
+// was.
+func (engine *downloadEngine) downloadRanges(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize int64, n int) (downloaded int64, err error) {
+	offset := verifier.Size()
+
+	// Record the time that the download started.
+	started := time.Now()
+
+	// Record the start of the download.
+	engine.events.Record(lbdeployevent.DownloadStarted{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Source:      source,
+		FileName:    file.Name,
+		Path:        file.Path,
+		Offset:      offset,
+		Connections: n,
+		Latency:     engine.probeLatency(source),
+	})
+
+	// Record heartbeats until the download has stopped.
+	var progress atomic.Int64
+	stopHeartbeat := startHeartbeat(engine.events, engine.state.heartbeat, func(started time.Time, elapsed time.Duration) lbdeployevent.Heartbeat {
+		return lbdeployevent.Heartbeat{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Operation:   lbdeployevent.HeartbeatDownload,
+			Subject:     file.Name,
+			Started:     started,
+			Elapsed:     elapsed,
+			Progress:    offset + progress.Load(),
+			Total:       expectedSize,
+		}
+	})
+
+	// Download each range concurrently. The first failure cancels the
+	// rest.
+	//
+	// Ranges finish out of order, so only the contiguous prefix of the file
+	// that has been written is reported to engine.written. Readers of a
+	// growing file rely on every byte before that point being present.
+	rangeCtx, cancel := context.WithCancel(ctx)
+	ranges := splitRanges(offset, expectedSize, n)
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		errs   []error
+		prefix = newRangeProgress(offset, ranges)
+	)
+	for i, r := range ranges {
+		written := func(n int64) {
+			progress.Add(n)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if contiguous, advanced := prefix.Add(i, n); advanced && engine.written != nil {
+				engine.written(contiguous)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := engine.downloadRange(rangeCtx, source, file, r, expectedSize, written); err != nil {
+				mutex.Lock()
+				errs = append(errs, err)
+				mutex.Unlock()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	cancel()
+	downloaded = progress.Load()
+
+	// Report the cause of the failure instead of the cancellations that
+	// it led to.
+	switch {
+	case len(errs) == 0:
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case slices.ContainsFunc(errs, func(err error) bool { return errors.Is(err, errRangesNotSupported) }):
+		err = errRangesNotSupported
+	default:
+		err = errs[0]
+	}
+
+	// Read the downloaded content into the verifier. If the download failed,
+	// or the content could not be read, discard whatever the verifier
+	// hasn't absorbed.
+	if err == nil {
+		err = engine.absorbRanges(ctx, file, verifier, offset)
+	}
+	if err != nil {
+		if truncErr := truncateDownload(file, verifier.Size()); truncErr != nil {
+			err = errors.Join(err, truncErr)
+		}
+		if engine.written != nil {
+			engine.written(verifier.Size())
+		}
+	}
+
+	stopHeartbeat()
+
+	// The download was abandoned in favor of a single request.
+	if errors.Is(err, errRangesNotSupported) {
+		return 0, err
+	}
+
+	// Record the time that the download stopped.
+	stopped := time.Now()
+
+	// Record the end of the download.
+	engine.events.Record(lbdeployevent.DownloadStopped{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Source:      source,
+		FileName:    file.Name,
+		Path:        file.Path,
+		Downloaded:  downloaded,
+		FileSize:    offset + downloaded,
+		Started:     started,
+		Stopped:     stopped,
+		Err:         err,
+	})
+
+	return downloaded, err
+}
+
+// rangeProgress tracks how much of each range of a file has been written,
+// and the extent of the contiguous prefix of the file that has been
+// written. It is not safe for concurrent use.
+type rangeProgress struct {
+	ranges     []byteRange
+	written    []int64
+	contiguous int64
+}
+
+// newRangeProgress returns a rangeProgress for the given ranges, which
+// must be in order and follow one another from offset.
+func newRangeProgress(offset int64, ranges []byteRange) *rangeProgress {
+	return &rangeProgress{
+		ranges:     ranges,
+		written:    make([]int64, len(ranges)),
+		contiguous: offset,
+	}
+}
+
+// Add records that n more bytes have been written at the start of the
+// range at index i. Each range is written in order from its start. It
+// returns the end of the contiguous prefix of the file that has been
+// written, and whether it has advanced.
+func (p *rangeProgress) Add(i int, n int64) (contiguous int64, advanced bool) {
+	p.written[i] += n
+
+	previous := p.contiguous
+	for j, r := range p.ranges {
+		end := r.Start + p.written[j]
+		if end <= p.contiguous {
+			continue
+		}
+		if r.Start > p.contiguous {
+			break
+		}
+		p.contiguous = end
+		if end <= r.End {
+			break
+		}
+	}
+	return p.contiguous, p.contiguous > previous
+}
+
+// downloadRange downloads a single range of a file from source and writes
+// it to its place in file. It calls written with the number of bytes each
+// time data is written.
+func (engine *downloadEngine) downloadRange(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, r byteRange, expectedSize int64, written func(n int64)) error {
+	resp, err := engine.sendRequest(ctx, source, fmt.Sprintf("bytes=%d-%d", r.Start, r.End))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Make sure that the server returned the range that was asked for.
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return errRangesNotSupported
+	default:
+		return engine.unexpectedStatus(source, resp)
+	}
+	cr, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, r.Start, -1)
+	}
+	if cr.Start != r.Start {
+		return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, r.Start, cr.Start)
+	}
+	if cr.End != r.End {
+		return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, r.End, cr.End)
+	}
+	if cr.Total >= 0 && cr.Total != expectedSize {
+		return engine.abortDownload(source, file, lbdeployevent.ContentRangeMismatch, expectedSize, cr.Total)
+	}
+
+	// Write the range to the file at its offset.
+	var buf [262144]byte // 256 KB
+	pos := r.Start
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		chunk, err := resp.Body.Read(buf[:])
+		if chunk > 0 {
+			if pos+int64(chunk) > r.End+1 {
+				return engine.abortDownload(source, file, lbdeployevent.ResponseTooLarge, r.End+1-r.Start, pos+int64(chunk)-r.Start)
+			}
+			if _, err := file.WriteAt(buf[:chunk], pos); err != nil {
+				return err
+			}
+			pos += int64(chunk)
+			written(int64(chunk))
+		}
+
+		if err != nil {
+			switch {
+			case (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) && pos <= r.End:
+				return engine.abortDownload(source, file, lbdeployevent.ResponseTruncated, r.End+1-r.Start, pos-r.Start)
+			case err == io.EOF:
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// absorbRanges reads the content of file from offset onward into the
+// verifier, leaving the file positioned at its end.
+func (engine *downloadEngine) absorbRanges(ctx context.Context, file stagingfs.PackageFile, verifier *FileVerifier, offset int64) error {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := verifier.ReadFrom(newReaderWithContext(ctx, file)); err != nil {
+		return err
+	}
+	engine.saveHashState(file, verifier)
+	return nil
+}
+
+// truncateDownload discards the content of file beyond offset and moves
+// to its end.
+func truncateDownload(file stagingfs.PackageFile, offset int64) error {
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := file.Seek(offset, io.SeekStart)
+	return err
+}
@@ -0,0 +1,67 @@
+package lbengine
+
+import "testing"
+
+func TestRangeProgress(t *testing.T) {
+	type write struct {
+		Range      int
+		N          int64
+		Contiguous int64
+		Advanced   bool
+	}
+
+	fixtures := []struct {
+		Name   string
+		Offset int64
+		Size   int64
+		Ranges int
+		Writes []write
+	}{
+		{
+			Name:   "InOrder",
+			Size:   300,
+			Ranges: 3,
+			Writes: []write{
+				{Range: 0, N: 50, Contiguous: 50, Advanced: true},
+				{Range: 0, N: 50, Contiguous: 100, Advanced: true},
+				{Range: 1, N: 100, Contiguous: 200, Advanced: true},
+				{Range: 2, N: 100, Contiguous: 300, Advanced: true},
+			},
+		},
+		{
+			Name:   "OutOfOrder",
+			Size:   300,
+			Ranges: 3,
+			Writes: []write{
+				{Range: 2, N: 100, Contiguous: 0},
+				{Range: 1, N: 40, Contiguous: 0},
+				{Range: 0, N: 60, Contiguous: 60, Advanced: true},
+				{Range: 0, N: 40, Contiguous: 140, Advanced: true},
+				{Range: 1, N: 60, Contiguous: 300, Advanced: true},
+			},
+		},
+		{
+			Name:   "Resumed",
+			Offset: 100,
+			Size:   300,
+			Ranges: 2,
+			Writes: []write{
+				{Range: 1, N: 100, Contiguous: 100},
+				{Range: 0, N: 99, Contiguous: 199, Advanced: true},
+				{Range: 0, N: 1, Contiguous: 300, Advanced: true},
+			},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			progress := newRangeProgress(fixture.Offset, splitRanges(fixture.Offset, fixture.Size, fixture.Ranges))
+			for i, w := range fixture.Writes {
+				contiguous, advanced := progress.Add(w.Range, w.N)
+				if contiguous != w.Contiguous || advanced != w.Advanced {
+					t.Fatalf("write %d: unexpected progress: got (%d, %t), want (%d, %t)", i, contiguous, advanced, w.Contiguous, w.Advanced)
+				}
+			}
+		})
+	}
+}
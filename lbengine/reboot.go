@@ -0,0 +1,208 @@
+package lbengine
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+	"golang.org/x/sys/windows/registry"
+)
+
+// errRestartInitiated is returned when a restart of the system has been
+// initiated and the remainder of the deployment has been abandoned.
+var errRestartInitiated = errors.New("a restart of the system has been initiated")
+
+// defaultScheduledRestartDelay is the amount of time that a scheduled
+// restart is delayed when the reboot policy doesn't specify a delay.
+const defaultScheduledRestartDelay = time.Hour
+
+// defaultRestartMessage is shown to users when the reboot policy doesn't
+// supply a message.
+const defaultRestartMessage = "A restart is required to finish installing software. Please save your work."
+
+// rebootTracker keeps track of the restarts required by the commands
+// invoked by a deployment.
+type rebootTracker struct {
+	mutex     sync.Mutex
+	reasons   []string
+	initiated bool
+}
+
+func newRebootTracker() *rebootTracker {
+	return &rebootTracker{}
+}
+
+// Require records that a restart is required for the given reasons.
+func (tracker *rebootTracker) Require(reasons ...string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.reasons = append(tracker.reasons, reasons...)
+}
+
+// Required returns true if a restart is required.
+func (tracker *rebootTracker) Required() bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return len(tracker.reasons) > 0
+}
+
+// Reasons returns the reasons that a restart is required.
+func (tracker *rebootTracker) Reasons() []string {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return slices.Clone(tracker.reasons)
+}
+
+// Initiated returns true if a restart has been initiated.
+func (tracker *rebootTracker) Initiated() bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return tracker.initiated
+}
+
+// initiate marks the restart as initiated. It returns false if a restart
+// was already initiated.
+func (tracker *rebootTracker) initiate() bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.initiated {
+		return false
+	}
+	tracker.initiated = true
+	return true
+}
+
+// restartAfterFlow initiates a restart after a flow requested by the
+// deployment has finished, if the reboot policy calls for it and a restart
+// is required.
+func (engine DeploymentEngine) restartAfterFlow(flow lbdeploy.FlowID) error {
+	if engine.deployment.Reboot.Mode != lbdeploy.RebootEndOfFlow || !engine.state.reboot.Required() {
+		return nil
+	}
+	return restartSystem(engine.deployment, flow, engine.events, engine.state)
+}
+
+// restartAfterDeployment schedules a restart after all of the flows
+// requested by the deployment have finished, if the reboot policy calls for
+// it and a restart is required.
+func (engine DeploymentEngine) restartAfterDeployment() error {
+	if engine.deployment.Reboot.Mode != lbdeploy.RebootScheduled || !engine.state.reboot.Required() {
+		return nil
+	}
+	return restartSystem(engine.deployment, "", engine.events, engine.state)
+}
+
+// restartSystem initiates a restart of the system in accordance with the
+// deployment's reboot policy, notifying users with the policy's message
+// and waiting for the policy's delay. The restart is only initiated once
+// per deployment invocation.
+//
+// If flow is non-empty, the restart is attributed to it.
+func restartSystem(dep lbdeploy.Deployment, flow lbdeploy.FlowID, events lbevent.Recorder, state *engineState) error {
+	if !state.reboot.initiate() {
+		return nil
+	}
+
+	delay := time.Duration(dep.Reboot.Delay)
+	if delay == 0 && dep.Reboot.Mode == lbdeploy.RebootScheduled {
+		delay = defaultScheduledRestartDelay
+	}
+
+	message := dep.Reboot.Message
+	if message == "" {
+		message = defaultRestartMessage
+	}
+
+	err := initiateRestart(delay, message)
+
+	events.Record(lbdeployevent.RestartInitiated{
+		Deployment: dep.ID,
+		Flow:       flow,
+		Mode:       dep.Reboot.Mode,
+		Delay:      delay,
+		Err:        err,
+	})
+
+	return err
+}
+
+// initiateRestart asks the operating system to restart after the given
+// delay, displaying message to any users that are signed in.
+func initiateRestart(delay time.Duration, message string) error {
+	// The shutdown utility limits the length of its comment.
+	const maxMessageLength = 512
+	if runes := []rune(message); len(runes) > maxMessageLength {
+		message = string(runes[:maxMessageLength])
+	}
+
+	seconds := int(delay.Round(time.Second) / time.Second)
+
+	// Record the restart as a planned restart for an application
+	// installation.
+	cmd := exec.Command("shutdown.exe", "/r", "/t", strconv.Itoa(seconds), "/d", "p:4:2", "/c", message)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// pendingRebootIndicators returns descriptions of the registry entries that
+// indicate that the system has a restart pending.
+func pendingRebootIndicators() ([]string, error) {
+	var indicators []string
+
+	// Look for file rename operations that will be performed during the
+	// next restart.
+	{
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager`, registry.QUERY_VALUE)
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = key.GetValue("PendingFileRenameOperations", nil)
+		key.Close()
+		switch {
+		case err == nil:
+			indicators = append(indicators, "pending file rename operations")
+		case !errors.Is(err, registry.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	// Look for keys that are created by servicing and Windows Update when a
+	// restart is needed.
+	keys := []struct {
+		Path        string
+		Description string
+	}{
+		{`SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`, "component servicing restart pending"},
+		{`SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`, "windows update restart required"},
+	}
+	for _, entry := range keys {
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, entry.Path, registry.QUERY_VALUE)
+		switch {
+		case err == nil:
+			key.Close()
+			indicators = append(indicators, entry.Description)
+		case !errors.Is(err, registry.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	return indicators, nil
+}
@@ -0,0 +1,317 @@
+package lbengine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"github.com/leafbridge/leafbridge-deploy/localregistry"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// registryEngine handles registry operations within a deployment.
+type registryEngine struct {
+	deployment lbdeploy.Deployment
+	flow       flowData
+	action     actionData
+	events     lbevent.Recorder
+	state      *engineState
+	tx         *transaction
+}
+
+// SetValue writes a registry value.
+func (engine *registryEngine) SetValue(ctx context.Context) error {
+	// Find the relevant registry value within the deployment.
+	valueID := engine.action.Definition.RegistryValue
+	ref, err := engine.deployment.Resources.Registry.ResolveValue(valueID)
+	if err != nil {
+		return fmt.Errorf("registry value: %w", err)
+	}
+
+	// Make sure that the value can be written as the registry value's type.
+	data := engine.action.Definition.Value
+	if kind := ref.Type.Kind(); kind != data.Kind() {
+		return fmt.Errorf("the \"%s\" registry value holds %s data, but a %s value was provided", valueID, ref.Type, data.Kind())
+	}
+
+	// Record the time that the change started.
+	started := time.Now()
+
+	// Back up the registry key before it is changed.
+	backup, err := engine.backupKey(ref)
+
+	// Record the original state of the value if it is being changed
+	// within a transaction.
+	if err == nil && engine.tx != nil {
+		err = engine.tx.PreserveRegistryValue(ref)
+	}
+
+	var keyPath string
+	if err == nil {
+		err = func() error {
+			key, err := localregistry.CreateKey(ref.Key())
+			if err != nil {
+				return fmt.Errorf("unable to open the registry key: %w", err)
+			}
+			defer key.Close()
+			keyPath = key.Path()
+
+			return key.SetValue(ref.Name, ref.Type, data)
+		}()
+	}
+
+	// Applications might be detected by registry values, so forget what we
+	// knew about them.
+	engine.state.apps.Clear()
+
+	// Record the registry change.
+	engine.events.Record(lbdeployevent.RegistryValueChanged{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Value:       valueID,
+		KeyPath:     keyPath,
+		Name:        ref.Name,
+		Data:        data.String(),
+		Backup:      backup,
+		Started:     started,
+		Stopped:     time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
+
+// DeleteValue removes a registry value.
+func (engine *registryEngine) DeleteValue(ctx context.Context) error {
+	// Find the relevant registry value within the deployment.
+	valueID := engine.action.Definition.RegistryValue
+	ref, err := engine.deployment.Resources.Registry.ResolveValue(valueID)
+	if err != nil {
+		return fmt.Errorf("registry value: %w", err)
+	}
+
+	// Record the time that the change started.
+	started := time.Now()
+
+	var (
+		keyPath string
+		backup  string
+		existed bool
+	)
+	err = func() error {
+		// If the key does not exist, there is nothing to delete.
+		key, err := localregistry.OpenKeyForWriting(ref.Key())
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("unable to open the registry key: %w", err)
+		}
+		defer key.Close()
+		keyPath = key.Path()
+
+		// If the value does not exist, there is nothing to delete.
+		existed, err = key.HasValue(ref.Name)
+		if err != nil || !existed {
+			return err
+		}
+
+		// Back up the registry key before it is changed.
+		backup, err = engine.backupKey(ref)
+		if err != nil {
+			return err
+		}
+
+		// Record the original state of the value if it is being deleted
+		// within a transaction.
+		if engine.tx != nil {
+			if err := engine.tx.PreserveRegistryValue(ref); err != nil {
+				return err
+			}
+		}
+
+		return key.DeleteValue(ref.Name)
+	}()
+
+	// Applications might be detected by registry values, so forget what we
+	// knew about them.
+	engine.state.apps.Clear()
+
+	// Record the registry change.
+	engine.events.Record(lbdeployevent.RegistryValueChanged{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Value:       valueID,
+		KeyPath:     keyPath,
+		Name:        ref.Name,
+		Deleted:     true,
+		Existed:     existed,
+		Backup:      backup,
+		Started:     started,
+		Stopped:     time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
+
+// defaultRegistryWaitTimeout is the length of time that a
+// wait-for-registry-value action waits when it does not specify a timeout.
+const defaultRegistryWaitTimeout = 10 * time.Minute
+
+// registryKeyPollInterval is how often a wait-for-registry-value action
+// checks for the creation of a registry key that does not exist yet, which
+// can't be waited on directly.
+const registryKeyPollInterval = 5 * time.Second
+
+// WaitForValue waits for a registry value to appear, or to reach a target
+// value if the action provides one.
+func (engine *registryEngine) WaitForValue(ctx context.Context) error {
+	// Find the relevant registry value within the deployment.
+	valueID := engine.action.Definition.RegistryValue
+	ref, err := engine.deployment.Resources.Registry.ResolveValue(valueID)
+	if err != nil {
+		return fmt.Errorf("registry value: %w", err)
+	}
+
+	// Determine how long to wait.
+	timeout := time.Duration(engine.action.Definition.Timeout)
+	if timeout <= 0 {
+		timeout = defaultRegistryWaitTimeout
+	}
+
+	// Record the time that the wait started.
+	started := time.Now()
+	deadline := started.Add(timeout)
+
+	var (
+		keyPath   string
+		satisfied bool
+		data      string
+	)
+	err = func() error {
+		for {
+			// Check the current state of the registry value. If its key
+			// exists, keep it open so that changes to it can be waited on.
+			key, err := localregistry.OpenKeyForNotification(ref.Key())
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to open the registry key: %w", err)
+			}
+			exists := err == nil
+			if exists {
+				keyPath = key.Path()
+				satisfied, data, err = engine.checkValue(key, ref)
+				if err != nil || satisfied {
+					key.Close()
+					return err
+				}
+			}
+
+			// Stop if the deadline has passed.
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				if exists {
+					key.Close()
+				}
+				return fmt.Errorf("the registry value did not reach the expected state within %s", timeout)
+			}
+
+			// Wait for the key to change, or poll for its creation.
+			if exists {
+				_, err = key.WaitForChange(ctx, remaining)
+				key.Close()
+				if err != nil {
+					return err
+				}
+			} else {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(min(remaining, registryKeyPollInterval)):
+				}
+			}
+		}
+	}()
+
+	// Record the outcome of the wait.
+	engine.events.Record(lbdeployevent.RegistryValueWait{
+		Deployment:  engine.deployment.ID,
+		Flow:        engine.flow.ID,
+		ActionIndex: engine.action.Index,
+		ActionType:  engine.action.Definition.Type,
+		Value:       valueID,
+		KeyPath:     keyPath,
+		Name:        ref.Name,
+		Comparison:  engine.action.Definition.Comparison,
+		Target:      engine.action.Definition.Value,
+		Data:        data,
+		Satisfied:   satisfied,
+		Started:     started,
+		Stopped:     time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
+
+// checkValue reports whether the registry value in key has reached the
+// state that the action waits for, along with its current data.
+func (engine *registryEngine) checkValue(key localregistry.Key, ref lbdeploy.RegistryValueRef) (satisfied bool, data string, err error) {
+	exists, err := key.HasValue(ref.Name)
+	if err != nil || !exists {
+		return false, "", err
+	}
+
+	value, err := key.GetValue(ref.Name, ref.Type)
+	if err != nil {
+		return false, "", err
+	}
+
+	target := engine.action.Definition.Value
+	if target.Kind() == lbvalue.KindUnknown {
+		return true, value.String(), nil
+	}
+
+	result, err := lbvalue.TryCompare(value, target)
+	if err != nil {
+		return false, value.String(), err
+	}
+
+	return engine.action.Definition.Comparison.Evaluate(result), value.String(), nil
+}
+
+// backupKey exports the key that holds the registry value to a backup file
+// in the deployment's staging directory, and returns the path of the file.
+//
+// If the key does not exist yet, there is nothing to back up, and an empty
+// path is returned.
+func (engine *registryEngine) backupKey(ref lbdeploy.RegistryValueRef) (string, error) {
+	dir, err := stagingfs.OpenDeployment(engine.deployment.ID)
+	if err != nil {
+		return "", fmt.Errorf("unable to open the staging directory for the registry backup: %w", err)
+	}
+	defer dir.Close()
+
+	path, err := dir.RegistryBackupPath(ref.ID, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare the registry backup directory: %w", err)
+	}
+
+	if err := localregistry.ExportKey(ref.Key(), path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to back up the registry key: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,282 @@
+package lbengine
+
+import (
+	"context"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+)
+
+// flowStatus is the scheduling status of a flow within a deployment.
+type flowStatus int
+
+const (
+	flowPending flowStatus = iota
+	flowRunning
+	flowFinished
+)
+
+// invokeFlows invokes the given flows, which must already be ordered so that
+// each flow follows the flows it depends on. It returns a result for each
+// flow, in the same order, and the errors returned by the flows that failed.
+//
+// Flows are started in the order chosen by a flowScheduler, which invokes
+// independent flows concurrently up to the engine's parallelism limit.
+func (engine DeploymentEngine) invokeFlows(ctx context.Context, flows []lbdeploy.FlowID, definitions []lbdeploy.Flow) (results []lbdeployevent.FlowResult, errs []error) {
+	limit := max(engine.parallelism, 1)
+
+	// Event handlers are not expected to be safe for concurrent use, so
+	// serialize calls to them when flows run concurrently.
+	events := engine.events
+	if limit > 1 && events.Handler != nil {
+		events.Handler = lbevent.NewSyncHandler(events.Handler)
+	}
+
+	// Determine what each flow might touch.
+	footprints := make([]flowFootprint, len(flows))
+	for i := range flows {
+		footprints[i] = newFlowFootprint(engine.deployment, definitions[i])
+	}
+
+	scheduler := newFlowScheduler(flows, definitions, footprints, limit, engine.deployment.Behavior.OnError == lbdeploy.OnErrorContinue)
+	done := make(chan int)
+
+	results = make([]lbdeployevent.FlowResult, len(flows))
+	for i, flow := range flows {
+		results[i].Flow = flow
+	}
+
+	for {
+		// Skip the remaining flows if the context has been cancelled or a
+		// restart has been initiated.
+		halt := ctx.Err() != nil || engine.state.reboot.Initiated()
+
+		// Skip or start as many pending flows as we can.
+		start, skipped := scheduler.Next(halt)
+		for _, i := range skipped {
+			results[i].Skipped = true
+		}
+		for _, i := range start {
+			flow := flows[i]
+			fe := flowEngine{
+				deployment: engine.deployment,
+				flow: flowData{
+					ID:         flow,
+					Definition: definitions[i],
+				},
+				events: events,
+				force:  engine.force,
+				state:  engine.state,
+			}
+			go func() {
+				results[i].Stats, results[i].Err = fe.invoke(ctx)
+				if results[i].Err == nil {
+					results[i].Err = engine.restartAfterFlow(flow)
+				}
+				done <- i
+			}()
+		}
+
+		// The earliest pending flow can always be started when nothing else
+		// is running, so once nothing is running every flow has finished.
+		if scheduler.Active() == 0 {
+			break
+		}
+
+		// Wait for a flow to finish.
+		i := <-done
+		err := results[i].Err
+		scheduler.Finish(i, err != nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return results, errs
+}
+
+// flowScheduler decides when each flow in a deployment can be started. It
+// tracks the status of each flow, but does not invoke them.
+//
+// A flow is only started once the flows it depends on have finished, and
+// never alongside a flow that shares any of its locks or resources. A flow
+// is also never started ahead of an earlier flow that it shares locks or
+// resources with, so that the requested order is preserved for those flows.
+// No more than limit flows are running at once.
+//
+// When a flow fails, the remaining flows are skipped unless the scheduler
+// continues on error. Even then, flows that depend on a flow that did not
+// finish are skipped.
+type flowScheduler struct {
+	flows           []lbdeploy.FlowID
+	dependsOn       [][]lbdeploy.FlowID
+	footprints      []flowFootprint
+	limit           int
+	continueOnError bool
+
+	status     []flowStatus
+	unfinished map[lbdeploy.FlowID]bool // Flows that failed or were skipped
+	active     int
+	stopping   bool
+}
+
+// newFlowScheduler returns a scheduler for the given flows, which must
+// already be ordered so that each flow follows the flows it depends on.
+func newFlowScheduler(flows []lbdeploy.FlowID, definitions []lbdeploy.Flow, footprints []flowFootprint, limit int, continueOnError bool) *flowScheduler {
+	dependsOn := make([][]lbdeploy.FlowID, len(definitions))
+	for i, definition := range definitions {
+		dependsOn[i] = definition.DependsOn
+	}
+	return &flowScheduler{
+		flows:           flows,
+		dependsOn:       dependsOn,
+		footprints:      footprints,
+		limit:           max(limit, 1),
+		continueOnError: continueOnError,
+		status:          make([]flowStatus, len(flows)),
+		unfinished:      make(map[lbdeploy.FlowID]bool),
+	}
+}
+
+// Next returns the indices of the pending flows that should be started now,
+// and of the pending flows that should be skipped. The returned flows are
+// considered running and finished, respectively.
+//
+// If halt is true, every pending flow is skipped.
+func (s *flowScheduler) Next(halt bool) (start, skipped []int) {
+	for i, flow := range s.flows {
+		if s.status[i] != flowPending {
+			continue
+		}
+
+		skip := halt || s.stopping
+		for _, dependency := range s.dependsOn[i] {
+			if s.unfinished[dependency] {
+				skip = true
+			}
+		}
+		if skip {
+			s.status[i] = flowFinished
+			s.unfinished[flow] = true
+			skipped = append(skipped, i)
+			continue
+		}
+
+		if s.active >= s.limit || !s.ready(i) {
+			continue
+		}
+
+		s.status[i] = flowRunning
+		s.active++
+		start = append(start, i)
+	}
+	return start, skipped
+}
+
+// Finish records that the running flow at index i has finished, and
+// whether it failed.
+func (s *flowScheduler) Finish(i int, failed bool) {
+	s.active--
+	s.status[i] = flowFinished
+	if failed {
+		s.unfinished[s.flows[i]] = true
+		if !s.continueOnError {
+			s.stopping = true
+		}
+	}
+}
+
+// Active returns the number of flows that are running.
+func (s *flowScheduler) Active() int {
+	return s.active
+}
+
+// ready returns true if the pending flow at index i can be started now.
+func (s *flowScheduler) ready(i int) bool {
+	for _, dependency := range s.dependsOn[i] {
+		for j, flow := range s.flows {
+			if flow == dependency && s.status[j] != flowFinished {
+				return false
+			}
+		}
+	}
+	for j := range s.flows {
+		if j == i {
+			continue
+		}
+		if s.status[j] == flowRunning || (s.status[j] == flowPending && j < i) {
+			if s.footprints[i].Overlaps(s.footprints[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flowFootprint is the set of locks and resources that a flow might use,
+// including those used by any flows that it starts.
+type flowFootprint map[string]bool
+
+// newFlowFootprint returns the footprint of the given flow.
+func newFlowFootprint(dep lbdeploy.Deployment, flow lbdeploy.Flow) flowFootprint {
+	fp := make(flowFootprint)
+	fp.addFlow(dep, flow, make(map[lbdeploy.FlowID]bool))
+	return fp
+}
+
+// Overlaps returns true if fp and other have any locks or resources in
+// common.
+func (fp flowFootprint) Overlaps(other flowFootprint) bool {
+	for key := range fp {
+		if other[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func (fp flowFootprint) addFlow(dep lbdeploy.Deployment, flow lbdeploy.Flow, seen map[lbdeploy.FlowID]bool) {
+	for _, lock := range flow.Locks {
+		fp.add("lock", string(lock))
+	}
+	for _, actions := range [][]lbdeploy.Action{flow.Before, flow.Actions, flow.After} {
+		fp.addActions(dep, actions, seen)
+	}
+}
+
+func (fp flowFootprint) addActions(dep lbdeploy.Deployment, actions []lbdeploy.Action, seen map[lbdeploy.FlowID]bool) {
+	for _, action := range actions {
+		fp.add("package", string(action.Package))
+		fp.add("command", string(action.Command))
+		fp.add("file", string(action.SourceFile))
+		fp.add("file", string(action.DestinationFile))
+		fp.add("directory", string(action.SourceDir))
+		fp.add("directory", string(action.DestinationDir))
+		fp.add("registry-value", string(action.RegistryValue))
+		for _, file := range action.UserSettings.Files {
+			fp.add("file", string(file.Source))
+		}
+
+		// Registry hives can only be loaded by one flow at a time.
+		if action.Type == lbdeploy.ActionApplyUserSettings {
+			fp.add("user-profiles", "all")
+		}
+
+		if action.Type == lbdeploy.ActionStartFlow && !seen[action.Flow] {
+			seen[action.Flow] = true
+			if definition, found := dep.Flows[action.Flow]; found {
+				fp.addFlow(dep, definition, seen)
+			}
+		}
+
+		fp.addActions(dep, action.Actions, seen)
+		fp.addActions(dep, action.Rollback, seen)
+	}
+}
+
+func (fp flowFootprint) add(kind, id string) {
+	if id != "" {
+		fp[kind+":"+id] = true
+	}
+}
@@ -0,0 +1,270 @@
+package lbengine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// scheduleFixture describes a set of flows for a flowScheduler test, in the
+// order that they were requested.
+type scheduleFixture struct {
+	Flows           []lbdeploy.FlowID
+	DependsOn       map[lbdeploy.FlowID][]lbdeploy.FlowID
+	Footprints      map[lbdeploy.FlowID]flowFootprint
+	Limit           int
+	ContinueOnError bool
+}
+
+func (fixture scheduleFixture) scheduler() *flowScheduler {
+	definitions := make([]lbdeploy.Flow, len(fixture.Flows))
+	footprints := make([]flowFootprint, len(fixture.Flows))
+	for i, flow := range fixture.Flows {
+		definitions[i].DependsOn = fixture.DependsOn[flow]
+		footprints[i] = fixture.Footprints[flow]
+	}
+	return newFlowScheduler(fixture.Flows, definitions, footprints, fixture.Limit, fixture.ContinueOnError)
+}
+
+// expectNext calls s.Next and checks the flows that it starts and skips.
+func expectNext(t *testing.T, s *flowScheduler, halt bool, start, skipped []lbdeploy.FlowID) {
+	t.Helper()
+	gotStart, gotSkipped := s.Next(halt)
+	if got := flowIDs(s, gotStart); !slices.Equal(got, start) {
+		t.Fatalf("unexpected flows started: got %v, want %v", got, start)
+	}
+	if got := flowIDs(s, gotSkipped); !slices.Equal(got, skipped) {
+		t.Fatalf("unexpected flows skipped: got %v, want %v", got, skipped)
+	}
+}
+
+// finish marks the running flow with the given ID as finished.
+func finish(t *testing.T, s *flowScheduler, flow lbdeploy.FlowID, failed bool) {
+	t.Helper()
+	i := slices.Index(s.flows, flow)
+	if i < 0 || s.status[i] != flowRunning {
+		t.Fatalf("the \"%s\" flow is not running", flow)
+	}
+	s.Finish(i, failed)
+}
+
+func flowIDs(s *flowScheduler, indices []int) []lbdeploy.FlowID {
+	var ids []lbdeploy.FlowID
+	for _, i := range indices {
+		ids = append(ids, s.flows[i])
+	}
+	return ids
+}
+
+func TestFlowSchedulerSequential(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c"},
+		Limit: 1,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a"}, nil)
+	expectNext(t, s, false, nil, nil)
+	finish(t, s, "a", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"b"}, nil)
+	finish(t, s, "b", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"c"}, nil)
+	finish(t, s, "c", false)
+	expectNext(t, s, false, nil, nil)
+
+	if s.Active() != 0 {
+		t.Fatalf("unexpected number of active flows: %d", s.Active())
+	}
+}
+
+func TestFlowSchedulerParallelismLimit(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c", "d"},
+		Limit: 2,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a", "b"}, nil)
+	if s.Active() != 2 {
+		t.Fatalf("unexpected number of active flows: %d", s.Active())
+	}
+	finish(t, s, "b", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"c"}, nil)
+	finish(t, s, "a", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"d"}, nil)
+}
+
+func TestFlowSchedulerDependencies(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c", "d"},
+		DependsOn: map[lbdeploy.FlowID][]lbdeploy.FlowID{
+			"b": {"a"},
+			"c": {"a"},
+			"d": {"b", "c"},
+		},
+		Limit: 4,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a"}, nil)
+	finish(t, s, "a", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"b", "c"}, nil)
+	finish(t, s, "c", false)
+	expectNext(t, s, false, nil, nil)
+	finish(t, s, "b", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"d"}, nil)
+}
+
+func TestFlowSchedulerSkipFailedDependency(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c", "d"},
+		DependsOn: map[lbdeploy.FlowID][]lbdeploy.FlowID{
+			"b": {"a"},
+			"d": {"b"},
+		},
+		Limit:           1,
+		ContinueOnError: true,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a"}, nil)
+	finish(t, s, "a", true)
+
+	// Flows that depend on the failed flow, directly or through a skipped
+	// flow, are skipped. Independent flows still run.
+	expectNext(t, s, false, []lbdeploy.FlowID{"c"}, []lbdeploy.FlowID{"b", "d"})
+	finish(t, s, "c", false)
+	expectNext(t, s, false, nil, nil)
+}
+
+func TestFlowSchedulerStopOnError(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c"},
+		Limit: 2,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a", "b"}, nil)
+	finish(t, s, "a", true)
+
+	// The remaining pending flows are skipped, but the running flow is
+	// allowed to finish.
+	expectNext(t, s, false, nil, []lbdeploy.FlowID{"c"})
+	if s.Active() != 1 {
+		t.Fatalf("unexpected number of active flows: %d", s.Active())
+	}
+	finish(t, s, "b", false)
+	expectNext(t, s, false, nil, nil)
+}
+
+func TestFlowSchedulerHalt(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c"},
+		Limit: 1,
+	}.scheduler()
+
+	expectNext(t, s, false, []lbdeploy.FlowID{"a"}, nil)
+	expectNext(t, s, true, nil, []lbdeploy.FlowID{"b", "c"})
+	finish(t, s, "a", false)
+	expectNext(t, s, false, nil, nil)
+}
+
+func TestFlowSchedulerFootprintOverlap(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"a", "b", "c"},
+		Footprints: map[lbdeploy.FlowID]flowFootprint{
+			"a": {"lock:x": true},
+			"b": {"lock:x": true, "file:y": true},
+			"c": {"file:z": true},
+		},
+		Limit: 3,
+	}.scheduler()
+
+	// The second flow shares a lock with the first, so it waits.
+	expectNext(t, s, false, []lbdeploy.FlowID{"a", "c"}, nil)
+	finish(t, s, "a", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"b"}, nil)
+}
+
+func TestFlowSchedulerFootprintOrder(t *testing.T) {
+	s := scheduleFixture{
+		Flows: []lbdeploy.FlowID{"setup", "a", "b"},
+		DependsOn: map[lbdeploy.FlowID][]lbdeploy.FlowID{
+			"a": {"setup"},
+		},
+		Footprints: map[lbdeploy.FlowID]flowFootprint{
+			"a": {"package:p": true},
+			"b": {"package:p": true},
+		},
+		Limit: 3,
+	}.scheduler()
+
+	// The last flow could run now, but it would overtake an earlier flow
+	// that shares a package with it.
+	expectNext(t, s, false, []lbdeploy.FlowID{"setup"}, nil)
+	finish(t, s, "setup", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"a"}, nil)
+	finish(t, s, "a", false)
+	expectNext(t, s, false, []lbdeploy.FlowID{"b"}, nil)
+}
+
+func TestFlowFootprint(t *testing.T) {
+	dep := lbdeploy.Deployment{
+		Flows: lbdeploy.FlowMap{
+			"install": {
+				Locks: []lbdeploy.LockID{"installer"},
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionInvokeCommand, Package: "app", Command: "install"},
+					{Type: lbdeploy.ActionStartFlow, Flow: "configure"},
+				},
+			},
+			"configure": {
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionSetRegistryValue, RegistryValue: "setting"},
+					{Type: lbdeploy.ActionStartFlow, Flow: "install"},
+				},
+			},
+			"settings": {
+				Before: []lbdeploy.Action{
+					{Type: lbdeploy.ActionApplyUserSettings, UserSettings: lbdeploy.UserSettings{
+						Files: []lbdeploy.UserFile{{Source: "template"}},
+					}},
+				},
+			},
+			"copy": {
+				After: []lbdeploy.Action{
+					{Type: lbdeploy.ActionTransaction, Actions: []lbdeploy.Action{
+						{Type: lbdeploy.ActionCopyFile, SourceFile: "template", DestinationFile: "config"},
+					}},
+				},
+			},
+		},
+	}
+
+	footprint := func(flow lbdeploy.FlowID) flowFootprint {
+		return newFlowFootprint(dep, dep.Flows[flow])
+	}
+
+	install := footprint("install")
+	for _, key := range []string{"lock:installer", "package:app", "command:install", "registry-value:setting"} {
+		if !install[key] {
+			t.Errorf("the install flow's footprint does not include \"%s\": %v", key, install)
+		}
+	}
+
+	settings := footprint("settings")
+	copying := footprint("copy")
+	for _, key := range []string{"user-profiles:all", "file:template"} {
+		if !settings[key] {
+			t.Errorf("the settings flow's footprint does not include \"%s\": %v", key, settings)
+		}
+	}
+	for _, key := range []string{"file:template", "file:config"} {
+		if !copying[key] {
+			t.Errorf("the copy flow's footprint does not include \"%s\": %v", key, copying)
+		}
+	}
+
+	if !settings.Overlaps(copying) {
+		t.Error("flows that use the same file should overlap")
+	}
+	if install.Overlaps(settings) {
+		t.Error("flows with nothing in common should not overlap")
+	}
+}
@@ -0,0 +1,53 @@
+package lbengine
+
+import (
+	"context"
+	"os"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/netshare"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// openSMBSource opens the remainder of a file from a network share. If the
+// source has a credential, a connection to the share is made with it for
+// the duration of the download.
+func (engine *downloadEngine) openSMBSource(ctx context.Context, source lbdeploy.PackageSource, file stagingfs.PackageFile, verifier *FileVerifier, expectedSize, limit int64) (downloadStream, error) {
+	path, err := source.UNCPath()
+	if err != nil {
+		return downloadStream{}, err
+	}
+	share, _, err := lbdeploy.SplitUNCPath(path)
+	if err != nil {
+		return downloadStream{}, err
+	}
+
+	// Connect to the share.
+	conn, err := netshare.Connect(share, source.Credential)
+	if err != nil {
+		return downloadStream{}, err
+	}
+
+	// Open the file. The connection is closed along with it.
+	f, err := os.Open(path)
+	if err != nil {
+		conn.Close()
+		return downloadStream{}, err
+	}
+
+	return engine.openSourceFile(source, file, verifier, path, &shareFile{File: f, conn: conn}, expectedSize, limit)
+}
+
+// shareFile is a file on a network share that closes its connection to
+// the share when it is closed.
+type shareFile struct {
+	*os.File
+	conn netshare.Connection
+}
+
+// Close closes the file and its connection to the share.
+func (f *shareFile) Close() error {
+	err := f.File.Close()
+	f.conn.Close()
+	return err
+}
@@ -0,0 +1,210 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/imds"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/sigv4"
+	"github.com/leafbridge/leafbridge-deploy/wincred"
+)
+
+// tokenExpiryMargin is the minimum amount of time that a cached token must
+// remain valid for it to be used. It leaves room for long downloads to
+// start before the token expires.
+const tokenExpiryMargin = 5 * time.Minute
+
+// azureStorageVersion is the Azure Storage API version that is requested
+// when authenticating to Azure Storage with a token. Bearer tokens are only
+// accepted by version 2017-11-09 and later.
+const azureStorageVersion = "2020-04-08"
+
+// tokenCache holds the access tokens that have been acquired for package
+// sources, so that each token is only requested once while it is valid.
+type tokenCache struct {
+	mutex  sync.Mutex
+	tokens map[lbdeploy.PackageSourceAuth]imds.Token
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{
+		tokens: make(map[lbdeploy.PackageSourceAuth]imds.Token),
+	}
+}
+
+// Token returns a valid access token for auth, acquiring a new one if
+// necessary.
+func (cache *tokenCache) Token(ctx context.Context, auth lbdeploy.PackageSourceAuth) (imds.Token, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if token, ok := cache.tokens[auth]; ok && token.Valid(tokenExpiryMargin) {
+		return token, nil
+	}
+
+	switch auth.Type {
+	case lbdeploy.PackageSourceAuthManagedIdentity:
+		token, err := imds.AcquireToken(ctx, imds.Request{
+			Resource: auth.ResourceOrDefault(),
+			ClientID: auth.ClientID,
+		})
+		if err != nil {
+			return imds.Token{}, err
+		}
+		cache.tokens[auth] = token
+		return token, nil
+	default:
+		return imds.Token{}, fmt.Errorf("the authentication type \"%s\" is not recognized", auth.Type)
+	}
+}
+
+// Discard removes any cached token for auth, so that a new one is acquired
+// the next time it is needed.
+func (cache *tokenCache) Discard(auth lbdeploy.PackageSourceAuth) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.tokens, auth)
+}
+
+// authorizeRequest adds the headers and credentials for source to req, if
+// the source has any.
+func (engine *downloadEngine) authorizeRequest(ctx context.Context, req *http.Request, source lbdeploy.PackageSource) error {
+	// Add the source's own headers first, so that they can't displace
+	// the headers that are needed for authentication.
+	for name, value := range source.Headers {
+		req.Header.Set(name, value)
+	}
+
+	// Requests to Azure Blob Storage declare the API version they rely
+	// upon, whether or not they are authenticated.
+	if source.Type == lbdeploy.PackageSourceBlob {
+		req.Header.Set("x-ms-version", azureStorageVersion)
+	}
+
+	// Requests to s3 sources with a credential are signed.
+	if source.Type == lbdeploy.PackageSourceS3 {
+		if source.Credential == "" {
+			return nil
+		}
+		return authorizeS3(req, source)
+	}
+
+	switch source.Auth.Type {
+	case "":
+		return nil
+	case lbdeploy.PackageSourceAuthSAS:
+		return authorizeSAS(req, source.Credential)
+	case lbdeploy.PackageSourceAuthBasic, lbdeploy.PackageSourceAuthBearer, lbdeploy.PackageSourceAuthHeader:
+		return authorizeSecret(req, source)
+	}
+
+	token, err := engine.state.tokens.Token(ctx, source.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to acquire an access token for the package source: %w", err)
+	}
+
+	req.Header.Set("Authorization", token.Type+" "+token.AccessToken)
+	if source.Auth.ResourceOrDefault() == lbdeploy.DefaultManagedIdentityResource {
+		req.Header.Set("x-ms-version", azureStorageVersion)
+	}
+
+	return nil
+}
+
+// authorizeSAS adds the shared access signature held by the named
+// credential to the query of req. The signature is read for each request,
+// so that a replacement takes effect without restarting the agent.
+func authorizeSAS(req *http.Request, credential string) error {
+	cred, err := wincred.Read(credential)
+	if err != nil {
+		return fmt.Errorf("failed to read the \"%s\" credential: %w", credential, err)
+	}
+
+	signature, err := url.ParseQuery(strings.TrimPrefix(cred.Secret, "?"))
+	if err != nil || !signature.Has("sig") {
+		return fmt.Errorf("the \"%s\" credential does not hold a valid shared access signature", credential)
+	}
+
+	query := req.URL.Query()
+	for key, values := range signature {
+		query[key] = values
+	}
+	req.URL.RawQuery = query.Encode()
+
+	return nil
+}
+
+// authorizeSecret adds the secret held by the credential of source to req,
+// in the manner called for by its authentication type.
+func authorizeSecret(req *http.Request, source lbdeploy.PackageSource) error {
+	cred, err := wincred.Read(source.Credential)
+	if err != nil {
+		return fmt.Errorf("failed to read the \"%s\" credential: %w", source.Credential, err)
+	}
+	if cred.Secret == "" {
+		return fmt.Errorf("the \"%s\" credential does not hold a secret", source.Credential)
+	}
+
+	switch source.Auth.Type {
+	case lbdeploy.PackageSourceAuthBasic:
+		req.SetBasicAuth(cred.UserName, cred.Secret)
+	case lbdeploy.PackageSourceAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+cred.Secret)
+	case lbdeploy.PackageSourceAuthHeader:
+		req.Header.Set(source.Auth.Header, cred.Secret)
+	}
+
+	return nil
+}
+
+// authorizeS3 signs req with the access keys held by the credential of an
+// s3 source.
+func authorizeS3(req *http.Request, source lbdeploy.PackageSource) error {
+	cred, err := wincred.Read(source.Credential)
+	if err != nil {
+		return fmt.Errorf("failed to read the \"%s\" credential: %w", source.Credential, err)
+	}
+	if cred.UserName == "" || cred.Secret == "" {
+		return fmt.Errorf("the \"%s\" credential must hold an access key ID and a secret access key", source.Credential)
+	}
+
+	sigv4.Sign(req, sigv4.Credentials{
+		AccessKeyID:     cred.UserName,
+		SecretAccessKey: cred.Secret,
+	}, sigv4.Scope{
+		Region:  source.S3.RegionOrDefault(),
+		Service: "s3",
+	}, time.Now())
+
+	return nil
+}
+
+// clientFor returns the HTTP client that requests to source are made with,
+// which sends them through transport.
+//
+// The client follows redirects like the default client, which drops the
+// Authorization header when a redirect leads to another host. Secrets in
+// other headers are dropped in the same way.
+func clientFor(source lbdeploy.PackageSource, transport http.RoundTripper) *http.Client {
+	client := &http.Client{Transport: transport}
+	if source.Auth.Type == lbdeploy.PackageSourceAuthHeader {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if req.URL.Host != via[0].URL.Host {
+				req.Header.Del(source.Auth.Header)
+			}
+			return nil
+		}
+	}
+	return client
+}
@@ -0,0 +1,188 @@
+package lbengine
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// sourceProbe is the result of probing a package source.
+type sourceProbe struct {
+	Latency time.Duration
+	Err     error
+}
+
+// rankSources returns the sources in the order they should be attempted,
+// according to their priorities and weights. If source probing is enabled,
+// sources that share a priority are ordered by their probe results instead
+// of their weights.
+func (engine *downloadEngine) rankSources(ctx context.Context, sources []lbdeploy.PackageSource, file stagingfs.PackageFile) []lbdeploy.PackageSource {
+	ranked := weightedOrder(sources)
+
+	timeout := lbdeploy.OverlayBehavior(engine.deployment.Behavior, engine.flow.Definition.Behavior).SourceProbe
+	if timeout <= 0 {
+		return ranked
+	}
+
+	engine.probes = engine.probeSources(ctx, ranked, file, time.Duration(timeout))
+
+	// Sources that responded come first, fastest first, followed by those
+	// that weren't probed, followed by those that failed their probes.
+	rank := func(source lbdeploy.PackageSource) (int, time.Duration) {
+		probe, ok := engine.probes[source.URL]
+		switch {
+		case !ok:
+			return 1, 0
+		case probe.Err != nil:
+			return 2, 0
+		default:
+			return 0, probe.Latency
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b lbdeploy.PackageSource) int {
+		if c := cmp.Compare(a.Priority, b.Priority); c != 0 {
+			return c
+		}
+		aRank, aLatency := rank(a)
+		bRank, bLatency := rank(b)
+		if c := cmp.Compare(aRank, bRank); c != 0 {
+			return c
+		}
+		return cmp.Compare(aLatency, bLatency)
+	})
+
+	return ranked
+}
+
+// probeLatency returns the time that source took to respond to its probe,
+// or zero if it was not probed or failed to respond.
+func (engine *downloadEngine) probeLatency(source lbdeploy.PackageSource) time.Duration {
+	probe, ok := engine.probes[source.URL]
+	if !ok || probe.Err != nil {
+		return 0
+	}
+	return probe.Latency
+}
+
+// probeSources sends a small request to each http, azure-blob and s3 source
+// concurrently, and returns the results mapped by the sources' URLs.
+// Sources that do not respond within timeout fail their probes.
+func (engine *downloadEngine) probeSources(ctx context.Context, sources []lbdeploy.PackageSource, file stagingfs.PackageFile, timeout time.Duration) map[string]sourceProbe {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results = make(map[string]sourceProbe)
+	)
+	for _, source := range sources {
+		if !source.Type.IsHTTPBased() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			latency, err := engine.probeSource(ctx, source)
+			mutex.Lock()
+			results[source.URL] = sourceProbe{Latency: latency, Err: err}
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Record the result of each probe, in the order of the sources.
+	for _, source := range sources {
+		probe, ok := results[source.URL]
+		if !ok {
+			continue
+		}
+		engine.events.Record(lbdeployevent.DownloadSourceProbed{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Source:      source,
+			FileName:    file.Name,
+			Latency:     probe.Latency,
+			Err:         probe.Err,
+		})
+	}
+
+	return results
+}
+
+// probeSource requests the first byte of the package file from source,
+// and returns the time taken for the server to respond.
+func (engine *downloadEngine) probeSource(ctx context.Context, source lbdeploy.PackageSource) (time.Duration, error) {
+	started := time.Now()
+
+	resp, err := engine.sendRequest(ctx, source, "bytes=0-0")
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(started)
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return latency, nil
+	default:
+		return 0, engine.unexpectedStatus(source, resp)
+	}
+}
+
+// weightedOrder returns the sources ordered by priority. Sources that share
+// a priority are shuffled according to their weights.
+func weightedOrder(sources []lbdeploy.PackageSource) []lbdeploy.PackageSource {
+	ordered := slices.Clone(sources)
+	slices.SortStableFunc(ordered, func(a, b lbdeploy.PackageSource) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		for end < len(ordered) && ordered[end].Priority == ordered[start].Priority {
+			end++
+		}
+		shuffleWeighted(ordered[start:end])
+		start = end
+	}
+
+	return ordered
+}
+
+// shuffleWeighted reorders group in place by repeatedly choosing the next
+// source at random, in proportion to the weights of those that remain.
+// Sources with a weight of zero are left at the end in their original
+// order.
+func shuffleWeighted(group []lbdeploy.PackageSource) {
+	for i := range group {
+		var total int
+		for _, source := range group[i:] {
+			total += source.Weight
+		}
+		if total == 0 {
+			return
+		}
+
+		r := rand.IntN(total)
+		for j := i; j < len(group); j++ {
+			if r < group[j].Weight {
+				chosen := group[j]
+				copy(group[i+1:j+1], group[i:j])
+				group[i] = chosen
+				break
+			}
+			r -= group[j].Weight
+		}
+	}
+}
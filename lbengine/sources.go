@@ -0,0 +1,151 @@
+package lbengine
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// unhealthySourceThreshold is the number of consecutive failures after
+// which a package source is considered unhealthy. Unhealthy sources are
+// tried after healthy ones.
+const unhealthySourceThreshold = 3
+
+// sourceTracker records the download history of package sources in the
+// source journal of each deployment.
+//
+// The journal is maintained on a best-effort basis. A failure to read or
+// write it does not cause a download to fail.
+type sourceTracker struct {
+	mutex sync.Mutex
+}
+
+func newSourceTracker() *sourceTracker {
+	return &sourceTracker{}
+}
+
+// Order returns the sources in the order they should be attempted.
+//
+// Healthy sources are attempted first, in the order they were defined.
+// Unhealthy sources follow, starting with those that have failed the
+// fewest consecutive times.
+func (tracker *sourceTracker) Order(deployment lbdeploy.DeploymentID, sources []lbdeploy.PackageSource) []lbdeploy.PackageSource {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	ordered := slices.Clone(sources)
+
+	journal, err := readSourceJournal(deployment)
+	if err != nil || len(journal.Sources) == 0 {
+		return ordered
+	}
+
+	penalty := func(source lbdeploy.PackageSource) int {
+		failures := journal.Sources[source.URL].ConsecutiveFailures
+		if failures < unhealthySourceThreshold {
+			return 0
+		}
+		return failures
+	}
+
+	slices.SortStableFunc(ordered, func(a, b lbdeploy.PackageSource) int {
+		return cmp.Compare(penalty(a), penalty(b))
+	})
+
+	return ordered
+}
+
+// RecordDownload records the outcome of a download from a source. If err
+// is nil the download is counted as a success.
+func (tracker *sourceTracker) RecordDownload(deployment lbdeploy.DeploymentID, source lbdeploy.PackageSource, downloaded int64, duration time.Duration, err error) {
+	tracker.update(deployment, source, func(stats *stagingfs.SourceStats, now time.Time) {
+		stats.Bytes += downloaded
+		stats.Duration += datatype.Duration(duration)
+		if err != nil {
+			stats.Failures++
+			stats.ConsecutiveFailures++
+			stats.LastFailure = now
+			stats.LastError = err.Error()
+		} else {
+			stats.Successes++
+			stats.ConsecutiveFailures = 0
+			stats.LastSuccess = now
+		}
+	})
+}
+
+// RecordRejection records that a download from a source was completed but
+// failed verification.
+func (tracker *sourceTracker) RecordRejection(deployment lbdeploy.DeploymentID, source lbdeploy.PackageSource) {
+	tracker.update(deployment, source, func(stats *stagingfs.SourceStats, now time.Time) {
+		stats.Rejections++
+		stats.ConsecutiveFailures++
+		stats.LastFailure = now
+		stats.LastError = "the downloaded file did not pass verification"
+	})
+}
+
+// update applies fn to the statistics for a source and records the
+// result in the deployment's source journal.
+func (tracker *sourceTracker) update(deployment lbdeploy.DeploymentID, source lbdeploy.PackageSource, fn func(stats *stagingfs.SourceStats, now time.Time)) {
+	if source.URL == "" {
+		return
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	dir, err := stagingfs.OpenDeployment(deployment)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+
+	// Start over if the journal is missing or can't be read.
+	journal, _ := dir.ReadSourceJournal()
+	if journal.Sources == nil {
+		journal.Sources = make(map[string]stagingfs.SourceStats)
+	}
+
+	stats := journal.Sources[source.URL]
+	fn(&stats, time.Now())
+	journal.Sources[source.URL] = stats
+
+	dir.WriteSourceJournal(journal)
+}
+
+// readSourceJournal reads the source journal for a deployment.
+func readSourceJournal(deployment lbdeploy.DeploymentID) (stagingfs.SourceJournal, error) {
+	dir, err := stagingfs.OpenExistingDeployment(deployment)
+	if err != nil {
+		return stagingfs.SourceJournal{}, err
+	}
+	defer dir.Close()
+
+	return dir.ReadSourceJournal()
+}
+
+// SourceStats returns the recorded download statistics for the package
+// sources used by a deployment, mapped by their URLs.
+//
+// If no statistics have been recorded, an empty map is returned.
+func SourceStats(deployment lbdeploy.DeploymentID) (map[string]stagingfs.SourceStats, error) {
+	journal, err := readSourceJournal(deployment)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]stagingfs.SourceStats{}, nil
+		}
+		return nil, err
+	}
+	if journal.Sources == nil {
+		journal.Sources = map[string]stagingfs.SourceStats{}
+	}
+	return journal.Sources, nil
+}
@@ -1,6 +1,9 @@
 package lbengine
 
 import (
+	"sync"
+	"time"
+
 	"github.com/leafbridge/leafbridge-deploy/idset"
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/stagingfs"
@@ -8,21 +11,118 @@ import (
 )
 
 // engineState keeps track of the overall state of an flow.
+//
+// Flows may be invoked concurrently, so access to the maps held by the
+// state must be guarded by mutex.
 type engineState struct {
+	mutex                sync.Mutex
 	activeFlows          flowSet
 	verifiedPackageFiles map[lbdeploy.PackageID]stagingfs.PackageDir
 	extractedPackages    map[lbdeploy.PackageID]tempfs.ExtractionDir
+	forcedDownloads      idset.SetOf[lbdeploy.PackageID]
 	locks                *lockManager
+	apps                 *appCache
+	reboot               *rebootTracker
+	changes              *changeTracker
+	sources              *sourceTracker
+	tokens               *tokenCache
+	resume               bool
+	heartbeat            time.Duration
+	peers                bool
+	filter               ActionFilter
 }
 
-func newEngineState() *engineState {
+func newEngineState(lockWait time.Duration, resume bool, heartbeat time.Duration, peers bool, filter ActionFilter) *engineState {
 	return &engineState{
 		activeFlows:          make(flowSet),
 		verifiedPackageFiles: make(map[lbdeploy.PackageID]stagingfs.PackageDir),
 		extractedPackages:    make(map[lbdeploy.PackageID]tempfs.ExtractionDir),
-		locks:                newLockManager(),
+		forcedDownloads:      make(idset.SetOf[lbdeploy.PackageID]),
+		locks:                newLockManager(lockWait),
+		apps:                 newAppCache(),
+		reboot:               newRebootTracker(),
+		changes:              newChangeTracker(),
+		sources:              newSourceTracker(),
+		tokens:               newTokenCache(),
+		resume:               resume,
+		heartbeat:            heartbeat,
+		peers:                peers,
+		filter:               filter,
 	}
 }
 
 // flowSet keeps track of a set of flows.
 type flowSet = idset.SetOf[lbdeploy.FlowID]
+
+// startFlow adds a flow to the set of active flows. It returns false if the
+// flow is already active.
+func (state *engineState) startFlow(flow lbdeploy.FlowID) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.activeFlows.Contains(flow) {
+		return false
+	}
+	state.activeFlows.Add(flow)
+	return true
+}
+
+// stopFlow removes a flow from the set of active flows.
+func (state *engineState) stopFlow(flow lbdeploy.FlowID) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.activeFlows.Remove(flow)
+}
+
+// verifiedPackageFile returns the staging directory of a package whose file
+// has already been verified.
+func (state *engineState) verifiedPackageFile(pkg lbdeploy.PackageID) (dir stagingfs.PackageDir, found bool) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	dir, found = state.verifiedPackageFiles[pkg]
+	return
+}
+
+// addVerifiedPackageFile records the staging directory of a package whose
+// file has been verified.
+func (state *engineState) addVerifiedPackageFile(pkg lbdeploy.PackageID, dir stagingfs.PackageDir) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.verifiedPackageFiles[pkg] = dir
+}
+
+// startForcedDownload records that the download of a package is being
+// forced. It returns false if the package's download has already been
+// forced, so that each package is downloaded at most once.
+func (state *engineState) startForcedDownload(pkg lbdeploy.PackageID) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.forcedDownloads.Contains(pkg) {
+		return false
+	}
+	state.forcedDownloads.Add(pkg)
+	return true
+}
+
+// extractedPackage returns the extraction directory of a package that has
+// already been extracted.
+func (state *engineState) extractedPackage(pkg lbdeploy.PackageID) (dir tempfs.ExtractionDir, found bool) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	dir, found = state.extractedPackages[pkg]
+	return
+}
+
+// addExtractedPackage records the extraction directory of a package that
+// has been extracted.
+func (state *engineState) addExtractedPackage(pkg lbdeploy.PackageID, dir tempfs.ExtractionDir) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.extractedPackages[pkg] = dir
+}
@@ -0,0 +1,426 @@
+package lbengine
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+	"github.com/leafbridge/leafbridge-deploy/tempfs"
+)
+
+// errStreamReset is returned by a growing file when the file it reads
+// from has been truncated.
+var errStreamReset = errors.New("the download was reset")
+
+// errStreamUnsupported is returned when an archive contains an entry that
+// cannot be extracted before the archive has been fully downloaded.
+var errStreamUnsupported = errors.New("the archive cannot be extracted in order")
+
+// growingFile reads from a file that is still being written. Reads block
+// until more data has been written or the writer has finished.
+type growingFile struct {
+	file *os.File
+
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	offset    int64
+	available int64
+	finished  bool
+	err       error
+}
+
+// newGrowingFile returns a growing file that reads from file, of which
+// the first available bytes have already been written.
+func newGrowingFile(file *os.File, available int64) *growingFile {
+	g := &growingFile{
+		file:      file,
+		available: available,
+	}
+	g.cond = sync.NewCond(&g.mutex)
+	return g
+}
+
+// Grow records that the file now holds size bytes. If the file has become
+// smaller, the reader fails with errStreamReset.
+func (g *growingFile) Grow(size int64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if size < g.available && g.err == nil {
+		g.err = errStreamReset
+	}
+	g.available = size
+	g.cond.Broadcast()
+}
+
+// Finish records that nothing more will be written to the file. If err is
+// non-nil, the reader fails with err once it has read all of the data that
+// was written.
+func (g *growingFile) Finish(err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.finished = true
+	if g.err == nil {
+		g.err = err
+	}
+	g.cond.Broadcast()
+}
+
+// Read reads up to len(p) bytes that have been written to the file.
+func (g *growingFile) Read(p []byte) (n int, err error) {
+	g.mutex.Lock()
+	for g.offset >= g.available && !g.finished && g.err == nil {
+		g.cond.Wait()
+	}
+	offset, available, finished, err := g.offset, g.available, g.finished, g.err
+	g.mutex.Unlock()
+
+	if err == errStreamReset {
+		return 0, err
+	}
+	if offset >= available {
+		if err != nil {
+			return 0, err
+		}
+		if finished {
+			return 0, io.EOF
+		}
+	}
+
+	if remaining := available - offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err = g.file.ReadAt(p, offset)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+
+	g.mutex.Lock()
+	g.offset += int64(n)
+	g.mutex.Unlock()
+
+	return n, err
+}
+
+// streamedEntry records an entry that was extracted from a zip archive
+// while it downloaded.
+type streamedEntry struct {
+	Name  string
+	Size  uint64
+	CRC32 uint32
+}
+
+// Values used to read the local headers of a zip archive.
+const (
+	zipLocalHeaderSignature           = 0x04034b50
+	zipCentralHeaderSignature         = 0x02014b50
+	zipEndOfDirectorySignature        = 0x06054b50
+	zipLocalHeaderLength              = 26
+	zipFlagEncrypted                  = 0x1
+	zipFlagDataDescriptor             = 0x8
+	zipExtraZip64                     = 0x0001
+	zipExtraExtendedTime              = 0x5455
+	zipMaxUint32                      = 0xffffffff
+	zipMethodStore             uint16 = 0
+	zipMethodDeflate           uint16 = 8
+)
+
+// StreamPackage extracts a zip archive from source while it is being
+// downloaded, by reading the local header that precedes each entry. It
+// stops when it reaches the archive's central directory.
+//
+// It returns errStreamUnsupported if the archive contains entries that
+// don't record their size in their local header, or that are encrypted or
+// compressed with an unsupported method.
+func (engine *extractionEngine) StreamPackage(ctx context.Context, source *growingFile, sourcePath string, destination tempfs.ExtractionDir) (entries []streamedEntry, err error) {
+	// Record the time that the extraction started.
+	started := time.Now()
+
+	// Record the start of the extraction.
+	engine.events.Record(lbdeployevent.ExtractionStarted{
+		Deployment:      engine.deployment.ID,
+		Flow:            engine.flow.ID,
+		ActionIndex:     engine.action.Index,
+		ActionType:      engine.action.Definition.Type,
+		SourcePath:      sourcePath,
+		DestinationPath: destination.Path(),
+		Streaming:       true,
+	})
+
+	var stats lbdeployevent.ExtractionStats
+	r := bufio.NewReaderSize(newReaderWithContext(ctx, source), 262144)
+	err = func() error {
+		for i := 0; ; i++ {
+			// Read the signature of the next record.
+			var signature uint32
+			if err := binary.Read(r, binary.LittleEndian, &signature); err != nil {
+				return err
+			}
+			switch signature {
+			case zipLocalHeaderSignature:
+			case zipCentralHeaderSignature, zipEndOfDirectorySignature:
+				return nil
+			default:
+				return fmt.Errorf("%w: unexpected record signature 0x%08x", errStreamUnsupported, signature)
+			}
+
+			// Extract the entry.
+			fileStarted := time.Now()
+			entry, isDir, err := engine.streamEntry(ctx, r, destination)
+			if err != nil && entry.Name == "" {
+				return err
+			}
+			if err == nil {
+				if isDir {
+					stats.Directories++
+				} else {
+					stats.Files++
+					stats.TotalBytes += int64(entry.Size)
+				}
+				entries = append(entries, entry)
+			}
+
+			// Record the extraction of the entry.
+			engine.events.Record(lbdeployevent.ExtractedFile{
+				Deployment: engine.deployment.ID,
+				Flow:       engine.flow.ID,
+				Action:     engine.action.Definition.Type,
+				FileNumber: i,
+				Path:       entry.Name,
+				FileSize:   int64(entry.Size),
+				Started:    fileStarted,
+				Stopped:    time.Now(),
+				Err:        err,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}()
+
+	// Record the end of the extraction.
+	engine.events.Record(lbdeployevent.ExtractionStopped{
+		Deployment:       engine.deployment.ID,
+		Flow:             engine.flow.ID,
+		ActionIndex:      engine.action.Index,
+		ActionType:       engine.action.Definition.Type,
+		SourcePath:       sourcePath,
+		DestinationPath:  destination.Path(),
+		SourceStats:      stats,
+		DestinationStats: stats,
+		Started:          started,
+		Stopped:          time.Now(),
+		Streaming:        true,
+		Err:              err,
+	})
+
+	return entries, err
+}
+
+// streamEntry extracts the zip entry that follows a local header
+// signature in r. If the entry's name could be read, it is returned even
+// when an error occurs.
+func (engine *extractionEngine) streamEntry(ctx context.Context, r io.Reader, destination tempfs.ExtractionDir) (entry streamedEntry, isDir bool, err error) {
+	// Read the local header.
+	var header [zipLocalHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return entry, false, err
+	}
+	var (
+		flags          = binary.LittleEndian.Uint16(header[2:])
+		method         = binary.LittleEndian.Uint16(header[4:])
+		modTime        = binary.LittleEndian.Uint16(header[6:])
+		modDate        = binary.LittleEndian.Uint16(header[8:])
+		crc            = binary.LittleEndian.Uint32(header[10:])
+		compressedSize = uint64(binary.LittleEndian.Uint32(header[14:]))
+		size           = uint64(binary.LittleEndian.Uint32(header[18:]))
+		nameLength     = binary.LittleEndian.Uint16(header[22:])
+		extraLength    = binary.LittleEndian.Uint16(header[24:])
+	)
+
+	// Read the name and extra fields.
+	name := make([]byte, nameLength)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return entry, false, err
+	}
+	extra := make([]byte, extraLength)
+	if _, err := io.ReadFull(r, extra); err != nil {
+		return entry, false, err
+	}
+	entry = streamedEntry{Name: string(name), Size: size, CRC32: crc}
+
+	// Entries that are encrypted, or whose sizes are recorded after their
+	// data, can't be extracted in order.
+	if flags&zipFlagEncrypted != 0 {
+		return entry, false, fmt.Errorf("%w: the entry is encrypted", errStreamUnsupported)
+	}
+	if flags&zipFlagDataDescriptor != 0 {
+		return entry, false, fmt.Errorf("%w: the entry's size is recorded after its data", errStreamUnsupported)
+	}
+
+	// Large entries record their sizes in a zip64 extra field.
+	if size == zipMaxUint32 || compressedSize == zipMaxUint32 {
+		size, compressedSize, err = zip64Sizes(extra, size, compressedSize)
+		if err != nil {
+			return entry, false, err
+		}
+		entry.Size = size
+	}
+
+	// Prepare a reader for the entry's data.
+	data := io.LimitReader(r, int64(compressedSize))
+	var content io.Reader
+	switch method {
+	case zipMethodStore:
+		content = data
+	case zipMethodDeflate:
+		decompressor := flate.NewReader(data)
+		defer decompressor.Close()
+		content = decompressor
+	default:
+		return entry, false, fmt.Errorf("%w: the entry uses compression method %d", errStreamUnsupported, method)
+	}
+
+	// If this is a directory, make sure it exists.
+	if strings.HasSuffix(entry.Name, "/") {
+		if err := destination.MkdirAll(entry.Name); err != nil {
+			return entry, true, fmt.Errorf("failed to create parent directory: %w", err)
+		}
+		return entry, true, nil
+	}
+
+	// If this is a file, make sure the directory it goes in exists.
+	if dir := path.Dir(entry.Name); dir != "" && dir != "." {
+		if err := destination.MkdirAll(dir); err != nil {
+			return entry, false, fmt.Errorf("failed to create parent directory: %w", err)
+		}
+	}
+
+	// Write the file, checking its size and checksum as it is written.
+	checksum := crc32.NewIEEE()
+	modified := msDosTime(modDate, modTime)
+	if t, ok := zipExtendedTime(extra); ok {
+		modified = t
+	}
+	written, err := destination.WriteFile(entry.Name, io.TeeReader(newReaderWithContext(ctx, content), checksum), modified)
+	if err != nil {
+		return entry, false, fmt.Errorf("failed to write file to its destination: %w", err)
+	}
+	if uint64(written) != size || checksum.Sum32() != crc {
+		return entry, false, errors.New("the extracted file does not match its size and checksum")
+	}
+
+	// Consume any data that remains, so that the next header can be read.
+	if _, err := io.Copy(io.Discard, data); err != nil {
+		return entry, false, err
+	}
+
+	return entry, false, nil
+}
+
+// zip64Sizes returns the sizes recorded in the zip64 extra field of a
+// local header. Only the sizes that overflowed the header are recorded.
+func zip64Sizes(extra []byte, size, compressedSize uint64) (uint64, uint64, error) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:])
+		length := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if length > len(extra) {
+			break
+		}
+		field := extra[:length]
+		extra = extra[length:]
+		if tag != zipExtraZip64 {
+			continue
+		}
+		if size == zipMaxUint32 {
+			if len(field) < 8 {
+				break
+			}
+			size = binary.LittleEndian.Uint64(field)
+			field = field[8:]
+		}
+		if compressedSize == zipMaxUint32 {
+			if len(field) < 8 {
+				break
+			}
+			compressedSize = binary.LittleEndian.Uint64(field)
+		}
+		return size, compressedSize, nil
+	}
+	return 0, 0, fmt.Errorf("%w: the entry's zip64 sizes could not be read", errStreamUnsupported)
+}
+
+// zipExtendedTime returns the modification time recorded in the extended
+// timestamp extra field of a local header, if there is one.
+func zipExtendedTime(extra []byte) (time.Time, bool) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:])
+		length := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if length > len(extra) {
+			break
+		}
+		field := extra[:length]
+		extra = extra[length:]
+		if tag != zipExtraExtendedTime || len(field) < 5 || field[0]&0x1 == 0 {
+			continue
+		}
+		return time.Unix(int64(binary.LittleEndian.Uint32(field[1:])), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// msDosTime converts an MS-DOS date and time to a time in UTC, as the zip
+// package does for archives that don't record an extended timestamp.
+func msDosTime(dosDate, dosTime uint16) time.Time {
+	return time.Date(
+		int(dosDate>>9+1980),
+		time.Month(dosDate>>5&0xf),
+		int(dosDate&0x1f),
+		int(dosTime>>11),
+		int(dosTime>>5&0x3f),
+		int(dosTime&0x1f*2),
+		0,
+		time.UTC,
+	)
+}
+
+// matchesStreamedEntries returns true if the archive in source contains
+// exactly the entries that were extracted while it downloaded.
+func matchesStreamedEntries(source stagingfs.PackageFile, entries []streamedEntry) (bool, error) {
+	fi, err := source.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	reader, err := zip.NewReader(source, fi.Size())
+	if err != nil {
+		return false, err
+	}
+
+	if len(reader.File) != len(entries) {
+		return false, nil
+	}
+	for i, zipFile := range reader.File {
+		entry := entries[i]
+		if zipFile.Name != entry.Name || zipFile.UncompressedSize64 != entry.Size || zipFile.CRC32 != entry.CRC32 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package lbengine
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
+)
+
+// Flags used by JOBOBJECT_CPU_RATE_CONTROL_INFORMATION.
+const (
+	jobCPURateControlEnable  = 0x1 // JOB_OBJECT_CPU_RATE_CONTROL_ENABLE
+	jobCPURateControlHardCap = 0x4 // JOB_OBJECT_CPU_RATE_CONTROL_HARD_CAP
+)
+
+// jobCPURateControl is the JOBOBJECT_CPU_RATE_CONTROL_INFORMATION
+// structure, with its union taken as a CPU rate.
+type jobCPURateControl struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+// applyPriority configures cmd to start its process with the scheduling
+// priority of the throttle.
+func applyPriority(cmd *exec.Cmd, throttle lbdeploy.CommandThrottle) {
+	var class uint32
+	switch throttle.Priority {
+	case lbdeploy.ProcessPriorityBelowNormal:
+		class = windows.BELOW_NORMAL_PRIORITY_CLASS
+	case lbdeploy.ProcessPriorityIdle:
+		class = windows.IDLE_PRIORITY_CLASS
+	default:
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= class
+}
+
+// limitCPU assigns the process with the given ID to a new job object that
+// caps its processor time at the given percentage of the system's total.
+// Processes that it starts afterward are assigned to the same job, and are
+// subject to the same cap.
+//
+// The returned function closes the job object. Closing it does not
+// terminate the processes within it.
+func limitCPU(pid int, percent int) (release func(), err error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a job object: %w", err)
+	}
+
+	info := jobCPURateControl{
+		ControlFlags: jobCPURateControlEnable | jobCPURateControlHardCap,
+		CPURate:      uint32(percent) * 100, // In hundredths of a percent
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return nil, fmt.Errorf("failed to set the CPU limit of the job object: %w", err)
+	}
+
+	proc, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return nil, fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(proc)
+
+	if err := windows.AssignProcessToJobObject(job, proc); err != nil {
+		windows.CloseHandle(job)
+		return nil, fmt.Errorf("failed to assign process %d to the job object: %w", pid, err)
+	}
+
+	return func() { windows.CloseHandle(job) }, nil
+}
@@ -0,0 +1,235 @@
+package lbengine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/localregistry"
+)
+
+// transaction keeps a journal of the changes made by a group of actions, so
+// that the changes can be undone if any action in the group fails.
+//
+// Before a file is modified or deleted, a backup copy of it is written to a
+// temporary directory. Files that are created within the transaction are
+// recorded so that they can be removed.
+//
+// Before a registry value is written or deleted, its original type and data
+// are recorded in memory. Values that are created within the transaction are
+// recorded so that they can be removed. Registry keys that are created to
+// hold new values are left in place.
+type transaction struct {
+	dir     string
+	entries []transactionEntry
+	seen    map[string]bool
+}
+
+// transactionEntry records the original state of a file or registry value
+// that has been changed within a transaction.
+type transactionEntry struct {
+	path   string         // The absolute path of the file or registry key
+	backup string         // The path of the backup copy, or empty if the file did not exist
+	value  *registryEntry // The original state of a registry value, if the entry is for one
+}
+
+// registryEntry records the original state of a registry value that has
+// been changed within a transaction.
+type registryEntry struct {
+	ref      lbdeploy.RegistryValueRef
+	existed  bool
+	original localregistry.RawValue
+}
+
+// newTransaction prepares a new transaction with its own backup directory.
+//
+// It is the caller's responsibility to commit or roll back the transaction
+// when finished with it.
+func newTransaction() (*transaction, error) {
+	dir, err := os.MkdirTemp("", "leafbridge-transaction-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a backup directory for the transaction: %w", err)
+	}
+	return &transaction{
+		dir:  dir,
+		seen: make(map[string]bool),
+	}, nil
+}
+
+// Changes returns the number of files and registry values that have been
+// recorded in the transaction.
+func (tx *transaction) Changes() int {
+	return len(tx.entries)
+}
+
+// PreserveFile records the current state of the file at path so that it can
+// be restored if the transaction is rolled back. It must be called before
+// the file is changed.
+//
+// If the file has already been preserved within the transaction, it does
+// nothing, because the original state has already been captured.
+func (tx *transaction) PreserveFile(path string) error {
+	if path == "" {
+		return errors.New("the file path could not be determined")
+	}
+	if tx.seen[path] {
+		return nil
+	}
+
+	entry := transactionEntry{path: path}
+
+	fi, err := os.Stat(path)
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("unable to evaluate the file: %w", err)
+	case err == nil && !fi.Mode().IsRegular():
+		return errors.New("the file path exists but is not a regular file")
+	case err == nil:
+		entry.backup = filepath.Join(tx.dir, strconv.Itoa(len(tx.entries)))
+		if err := copyFileContents(path, entry.backup); err != nil {
+			return fmt.Errorf("failed to back up the file: %w", err)
+		}
+	}
+
+	tx.entries = append(tx.entries, entry)
+	tx.seen[path] = true
+
+	return nil
+}
+
+// PreserveRegistryValue records the current state of the registry value
+// identified by ref so that it can be restored if the transaction is rolled
+// back. It must be called before the value is changed.
+//
+// If the value has already been preserved within the transaction, it does
+// nothing, because the original state has already been captured.
+func (tx *transaction) PreserveRegistryValue(ref lbdeploy.RegistryValueRef) error {
+	// Registry values are identified by their resource ID, because the key
+	// path can't be determined before the key exists.
+	id := "registry:" + string(ref.ID)
+	if tx.seen[id] {
+		return nil
+	}
+
+	entry := transactionEntry{value: &registryEntry{ref: ref}}
+
+	key, err := localregistry.OpenKey(ref.Key())
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("unable to open the registry key: %w", err)
+	case err == nil:
+		entry.path = key.Path()
+		entry.value.original, entry.value.existed, err = key.GetRawValue(ref.Name)
+		key.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read the registry value: %w", err)
+		}
+	}
+
+	tx.entries = append(tx.entries, entry)
+	tx.seen[id] = true
+
+	return nil
+}
+
+// Commit accepts the changes made within the transaction and discards the
+// backups.
+func (tx *transaction) Commit() error {
+	return os.RemoveAll(tx.dir)
+}
+
+// Rollback restores every file recorded in the transaction to its original
+// state, in the reverse order that the files were recorded. It attempts to
+// restore every file even if some of them fail.
+//
+// The backups are only discarded if all of the files were restored.
+func (tx *transaction) Rollback() error {
+	var errs []error
+	for i := len(tx.entries) - 1; i >= 0; i-- {
+		entry := tx.entries[i]
+		if entry.value != nil {
+			if err := restoreRegistryValue(*entry.value); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore the \"%s\" registry value: %w", entry.value.ref.ID, err))
+			}
+			continue
+		}
+		if entry.backup == "" {
+			if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove \"%s\": %w", entry.path, err))
+			}
+			continue
+		}
+		if err := copyFileContents(entry.backup, entry.path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore \"%s\" from \"%s\": %w", entry.path, entry.backup, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		// Leave the backups in place so that they can be recovered by hand.
+		return errors.Join(errs...)
+	}
+
+	return os.RemoveAll(tx.dir)
+}
+
+// restoreRegistryValue returns a registry value to the state recorded in
+// entry. Values that did not exist are removed.
+func restoreRegistryValue(entry registryEntry) error {
+	if !entry.existed {
+		key, err := localregistry.OpenKeyForWriting(entry.ref.Key())
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		defer key.Close()
+
+		if err := key.DeleteValue(entry.ref.Name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	key, err := localregistry.CreateKey(entry.ref.Key())
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	return key.SetRawValue(entry.ref.Name, entry.original)
+}
+
+// copyFileContents copies the contents and modification time of the file at
+// source to dest, replacing dest if it exists.
+func copyFileContents(source, dest string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(dest, fi.ModTime(), fi.ModTime())
+}
@@ -0,0 +1,163 @@
+package lbengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/localfs"
+	"github.com/leafbridge/leafbridge-deploy/localregistry"
+	"github.com/leafbridge/leafbridge-deploy/userprofile"
+	"golang.org/x/sys/windows/registry"
+)
+
+// applyUserSettings applies per-user configuration to every user profile
+// on the system, including the default profile.
+//
+// A failure to apply the settings to one profile does not prevent them from
+// being applied to the others, but it causes the action to fail.
+func (engine *actionEngine) applyUserSettings(ctx context.Context) error {
+	settings := engine.action.Definition.UserSettings
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("the user settings are not valid: %w", err)
+	}
+
+	// Resolve the source files up front.
+	sources := make([]lbdeploy.FileRef, len(settings.Files))
+	for i, file := range settings.Files {
+		ref, err := engine.deployment.Resources.FileSystem.ResolveFile(file.Source)
+		if err != nil {
+			return fmt.Errorf("source file: %w", err)
+		}
+		sources[i] = ref
+	}
+
+	// Find the user profiles on the system.
+	profiles, err := userprofile.List()
+	if err != nil {
+		return fmt.Errorf("the user profiles on the system could not be determined: %w", err)
+	}
+
+	var errs []error
+	for _, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		started := time.Now()
+		values, files, err := applyUserSettingsToProfile(profile, settings, sources)
+
+		engine.events.Record(lbdeployevent.UserSettingsApplied{
+			Deployment:  engine.deployment.ID,
+			Flow:        engine.flow.ID,
+			ActionIndex: engine.action.Index,
+			ActionType:  engine.action.Definition.Type,
+			Profile:     profile.String(),
+			ProfilePath: profile.Path,
+			Values:      values,
+			Files:       files,
+			Started:     started,
+			Stopped:     time.Now(),
+			Err:         err,
+		})
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("the \"%s\" profile: %w", profile.Path, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("user settings could not be applied to every profile: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// applyUserSettingsToProfile applies settings to a single user profile. It
+// returns the number of registry values and files that were written.
+func applyUserSettingsToProfile(profile userprofile.Profile, settings lbdeploy.UserSettings, sources []lbdeploy.FileRef) (values, files int, err error) {
+	if len(settings.Registry) > 0 {
+		hive, err := profile.OpenHive()
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to open the registry hive: %w", err)
+		}
+
+		for _, value := range settings.Registry {
+			if err := setUserRegistryValue(hive.Key(), value); err != nil {
+				hive.Close()
+				return values, files, err
+			}
+			values++
+		}
+
+		if err := hive.Close(); err != nil {
+			return values, files, fmt.Errorf("unable to close the registry hive: %w", err)
+		}
+	}
+
+	for i, file := range settings.Files {
+		if err := copyUserFile(profile, file, sources[i]); err != nil {
+			return values, files, err
+		}
+		files++
+	}
+
+	return values, files, nil
+}
+
+// setUserRegistryValue writes value within the given user hive.
+func setUserRegistryValue(hive registry.Key, value lbdeploy.UserRegistryValue) error {
+	path, err := filepath.Localize(filepath.ToSlash(value.Key))
+	if err != nil {
+		return fmt.Errorf("the \"%s\" registry key path is invalid: %w", value.Key, err)
+	}
+
+	key, _, err := registry.CreateKey(hive, path, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("unable to create the \"%s\" registry key: %w", value.Key, err)
+	}
+	defer key.Close()
+
+	if err := localregistry.SetValue(key, value.Name, value.Type, value.Value); err != nil {
+		return fmt.Errorf("unable to set the \"%s\" value of the \"%s\" registry key: %w", value.Name, value.Key, err)
+	}
+
+	return nil
+}
+
+// copyUserFile copies the source file into the profile, replacing any file
+// that is already present.
+//
+// The profile belongs to its user, who could plant a junction or symbolic
+// link within it to redirect the write elsewhere. The destination is
+// therefore opened without following reparse points.
+func copyUserFile(profile userprofile.Profile, file lbdeploy.UserFile, source lbdeploy.FileRef) error {
+	localized, err := filepath.Localize(filepath.ToSlash(file.Path))
+	if err != nil {
+		return fmt.Errorf("the \"%s\" file path is invalid: %w", file.Path, err)
+	}
+	destPath := filepath.Join(profile.Path, localized)
+
+	sourceFile, err := localfs.OpenFile(source)
+	if err != nil {
+		return fmt.Errorf("unable to open the \"%s\" source file: %w", file.Source, err)
+	}
+	defer sourceFile.Close()
+
+	destFile, err := localfs.CreateFileBeneath(profile.Path, localized)
+	if err != nil {
+		return fmt.Errorf("unable to create \"%s\": %w", destPath, err)
+	}
+
+	if _, err := io.Copy(destFile, sourceFile.System()); err != nil {
+		destFile.Close()
+		return fmt.Errorf("unable to copy \"%s\" to \"%s\": %w", file.Source, destPath, err)
+	}
+
+	return destFile.Close()
+}
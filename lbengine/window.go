@@ -0,0 +1,115 @@
+package lbengine
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/gentlemanautomaton/winproc"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/procwindow"
+	"golang.org/x/sys/windows"
+)
+
+// windowCheckInterval is the time between checks for windows shown by a
+// command that should be hidden or minimized.
+const windowCheckInterval = 250 * time.Millisecond
+
+// applyWindowStyle configures cmd to start its process with the given
+// window style. Hidden commands are started without a console window.
+func applyWindowStyle(cmd *exec.Cmd, style lbdeploy.WindowStyle) {
+	if style != lbdeploy.WindowStyleHidden {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.HideWindow = true
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NO_WINDOW
+}
+
+// watchWindows hides or minimizes the windows shown by the process with
+// the given ID and its descendants, according to style, until the returned
+// function is called.
+//
+// Hidden windows are hidden again each time they reappear. Minimized
+// windows are only minimized once, so that the user can restore them.
+func watchWindows(pid int, style lbdeploy.WindowStyle) (stop func()) {
+	var cmd procwindow.Command
+	switch style {
+	case lbdeploy.WindowStyleHidden:
+		cmd = procwindow.Hide
+	case lbdeploy.WindowStyleMinimized:
+		cmd = procwindow.Minimize
+	default:
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(windowCheckInterval)
+		defer ticker.Stop()
+
+		handled := make(map[windows.HWND]bool)
+		for {
+			if tree, err := processTree(uint32(pid)); err == nil {
+				found, _ := procwindow.List(func(pid uint32) bool { return tree[pid] })
+				for _, w := range found {
+					if cmd == procwindow.Minimize && handled[w.Handle] {
+						continue
+					}
+					if w.Show(cmd) == nil {
+						handled[w.Handle] = true
+					}
+				}
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// processTree returns the IDs of the process with the given ID and all of
+// its running descendants.
+func processTree(root uint32) (map[uint32]bool, error) {
+	procs, err := winproc.List()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint32][]uint32)
+	for _, proc := range procs {
+		if proc.ID != proc.ParentID {
+			children[uint32(proc.ParentID)] = append(children[uint32(proc.ParentID)], uint32(proc.ID))
+		}
+	}
+
+	tree := map[uint32]bool{root: true}
+	pending := []uint32{root}
+	for len(pending) > 0 {
+		pid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, child := range children[pid] {
+			if !tree[child] {
+				tree[child] = true
+				pending = append(pending, child)
+			}
+		}
+	}
+
+	return tree, nil
+}
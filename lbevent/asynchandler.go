@@ -0,0 +1,130 @@
+package lbevent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by an asynchronous event handler when its
+	// queue is full and the event has been dropped.
+	ErrQueueFull = errors.New("the event queue is full")
+
+	// ErrHandlerClosed is returned by an asynchronous event handler when
+	// it has already been closed.
+	ErrHandlerClosed = errors.New("the event handler has been closed")
+)
+
+// maxAsyncErrors is the maximum number of errors that are retained by an
+// asynchronous event handler.
+const maxAsyncErrors = 10
+
+// AsyncHandler is a LeafBridge event handler that passes events to an
+// underlying handler on a separate goroutine, so that a slow handler
+// can't hold up the code that records events. Events are passed to the
+// underlying handler in the order that they were recorded.
+//
+// Events are held in a bounded queue. If the queue is full when an event
+// is recorded, the event is dropped and ErrQueueFull is returned.
+//
+// The underlying handler can't report errors to the code that records
+// events. Instead, they are reported when the handler is closed. The
+// handler must be closed when it is no longer needed, which waits for any
+// queued events to be handled.
+type AsyncHandler struct {
+	state *asyncState
+}
+
+type asyncState struct {
+	handler Handler
+	queue   chan Record
+	done    chan struct{}
+
+	mutex   sync.Mutex
+	closed  bool
+	dropped int
+	failed  int
+	errs    []error
+}
+
+// NewAsyncHandler returns a handler that passes events to h on a separate
+// goroutine. Up to size events can be queued.
+func NewAsyncHandler(h Handler, size int) AsyncHandler {
+	state := &asyncState{
+		handler: h,
+		queue:   make(chan Record, size),
+		done:    make(chan struct{}),
+	}
+	go state.run()
+	return AsyncHandler{state: state}
+}
+
+// Name returns a name for the handler.
+func (h AsyncHandler) Name() string {
+	return "async-handler"
+}
+
+// Handle adds the given event record to the handler's queue.
+func (h AsyncHandler) Handle(r Record) error {
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	if h.state.closed {
+		return ErrHandlerClosed
+	}
+
+	select {
+	case h.state.queue <- r:
+		return nil
+	default:
+		h.state.dropped++
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting events and waits for the events in the queue to
+// be handled. It returns any errors that were encountered by the
+// underlying handler, as well as an error if any events were dropped.
+func (h AsyncHandler) Close() error {
+	h.state.mutex.Lock()
+	if h.state.closed {
+		h.state.mutex.Unlock()
+		return nil
+	}
+	h.state.closed = true
+	close(h.state.queue)
+	h.state.mutex.Unlock()
+
+	// Wait for the queue to be drained.
+	<-h.state.done
+
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	errs := h.state.errs
+	if n := h.state.failed - len(errs); n > 0 {
+		errs = append(errs, fmt.Errorf("%d more events could not be recorded by the \"%s\" event handler", n, h.state.handler.Name()))
+	}
+	if h.state.dropped > 0 {
+		errs = append(errs, fmt.Errorf("%d events were dropped by the \"%s\" event handler: %w", h.state.dropped, h.state.handler.Name(), ErrQueueFull))
+	}
+	return errors.Join(errs...)
+}
+
+// run passes queued events to the underlying handler until the queue is
+// closed.
+func (state *asyncState) run() {
+	defer close(state.done)
+
+	for r := range state.queue {
+		if err := state.handler.Handle(r); err != nil {
+			state.mutex.Lock()
+			state.failed++
+			if len(state.errs) < maxAsyncErrors {
+				state.errs = append(state.errs, WrapHandlerError(state.handler, r, err))
+			}
+			state.mutex.Unlock()
+		}
+	}
+}
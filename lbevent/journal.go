@@ -0,0 +1,161 @@
+package lbevent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalEntry is an event record as it is stored in an event journal.
+//
+// The message and details of the event are stored as they were rendered
+// when the event was recorded, so that the journal can be read without
+// knowledge of the event types that produced it.
+type JournalEntry struct {
+	Time      time.Time      `json:"time"`
+	Level     slog.Level     `json:"level"`
+	Component string         `json:"component,omitempty"`
+	Message   string         `json:"message"`
+	Details   string         `json:"details,omitempty"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+}
+
+// NewJournalEntry returns a journal entry for r.
+func NewJournalEntry(r Record) JournalEntry {
+	return JournalEntry{
+		Time:      r.Time(),
+		Level:     r.Level(),
+		Component: r.Component(),
+		Message:   r.Message(),
+		Details:   r.Details(),
+		Attrs:     attrMap(r.Attrs()),
+	}
+}
+
+// Attr returns the string value of the named attribute, if it is present.
+// Attributes within groups are named by joining the group and attribute
+// names with a dot, such as "action.type".
+func (entry JournalEntry) Attr(name string) (string, bool) {
+	var value any = entry.Attrs
+	for len(name) > 0 {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if v, ok := m[name]; ok {
+			return fmt.Sprint(v), true
+		}
+		group, rest, found := strings.Cut(name, ".")
+		if !found {
+			return "", false
+		}
+		value, name = m[group], rest
+	}
+	return "", false
+}
+
+// attrMap converts a set of structured logging attributes to a map that
+// can be stored as JSON. Groups become nested maps.
+func attrMap(attrs []slog.Attr) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindGroup:
+			m[attr.Key] = attrMap(value.Group())
+		case slog.KindTime:
+			m[attr.Key] = value.Time()
+		case slog.KindDuration:
+			m[attr.Key] = value.Duration().String()
+		case slog.KindAny:
+			if err, ok := value.Any().(error); ok {
+				m[attr.Key] = err.Error()
+			} else {
+				m[attr.Key] = value.Any()
+			}
+		default:
+			m[attr.Key] = value.Any()
+		}
+	}
+	return m
+}
+
+// JournalHandler is a LeafBridge event handler that appends events to an
+// event journal file, with one JSON-encoded entry per line.
+//
+// The journal can be read back with ReadJournal, such as by the show
+// events command when reviewing a deployment after an incident.
+type JournalHandler struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewJournalHandler returns a JournalHandler that appends events to the
+// journal file at path, which is created if it doesn't exist.
+//
+// The handler must be closed when it is no longer needed.
+func NewJournalHandler(path string) (*JournalHandler, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event journal: %w", err)
+	}
+	return &JournalHandler{file: file}, nil
+}
+
+// Name returns a name for the handler.
+func (h *JournalHandler) Name() string {
+	return "journal"
+}
+
+// Handle processes the given event record.
+func (h *JournalHandler) Handle(r Record) error {
+	data, err := json.Marshal(NewJournalEntry(r))
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	_, err = h.file.Write(data)
+	return err
+}
+
+// Close closes the journal file.
+func (h *JournalHandler) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.file.Close()
+}
+
+// ReadJournal reads the entries of an event journal from r. Lines that
+// can't be parsed, such as a line that was only partially written, are
+// skipped.
+func ReadJournal(r io.Reader) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
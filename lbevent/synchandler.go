@@ -0,0 +1,32 @@
+package lbevent
+
+import "sync"
+
+// SyncHandler is a LeafBridge event handler that serializes calls to an
+// underlying handler, so that events can be recorded by multiple
+// goroutines at the same time.
+type SyncHandler struct {
+	mutex   *sync.Mutex
+	handler Handler
+}
+
+// NewSyncHandler returns a handler that serializes calls to h.
+func NewSyncHandler(h Handler) SyncHandler {
+	return SyncHandler{
+		mutex:   new(sync.Mutex),
+		handler: h,
+	}
+}
+
+// Name returns a name for the handler.
+func (h SyncHandler) Name() string {
+	return "sync-handler"
+}
+
+// Handle processes the given event record.
+func (h SyncHandler) Handle(r Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.handler.Handle(r)
+}
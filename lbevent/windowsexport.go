@@ -0,0 +1,88 @@
+package lbevent
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// windowsEvent is an event record exported from the Windows event log in
+// XML form, such as by "wevtutil qe Application /f:xml".
+type windowsEvent struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		Level       int `xml:"Level"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	EventData struct {
+		Data []string `xml:"Data"`
+	} `xml:"EventData"`
+}
+
+// ReadWindowsExport reads the LeafBridge event records within Windows event
+// log records that were exported in XML form. Records from other event
+// sources are skipped.
+//
+// The records may be wrapped in a root element or simply concatenated, as
+// they are by wevtutil. Exported records don't carry structured attributes,
+// so only the time, level, message and details of each event are returned.
+func ReadWindowsExport(r io.Reader) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Event" {
+			continue
+		}
+
+		var event windowsEvent
+		if err := decoder.DecodeElement(&event, &start); err != nil {
+			return entries, err
+		}
+		if event.System.Provider.Name != lbEventSource {
+			continue
+		}
+
+		entry := JournalEntry{
+			Level: windowsEventLevel(event.System.Level),
+		}
+		entry.Time, _ = time.Parse(time.RFC3339Nano, event.System.TimeCreated.SystemTime)
+
+		// Details are appended to the message after a blank line when the
+		// event is written to the Windows event log.
+		text := strings.Join(event.EventData.Data, "\n")
+		entry.Message, entry.Details, _ = strings.Cut(text, "\n\n")
+
+		entries = append(entries, entry)
+	}
+}
+
+// windowsEventLevel returns the event level that corresponds to a Windows
+// event log level.
+func windowsEventLevel(level int) slog.Level {
+	switch level {
+	case 1, 2: // Critical, Error
+		return slog.LevelError
+	case 3: // Warning
+		return slog.LevelWarn
+	case 5: // Verbose
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,365 @@
+package lbgraph
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// Build returns a graph of the references within dep.
+func Build(dep lbdeploy.Deployment) Graph {
+	b := builder{
+		nodes: make(map[string]bool),
+		edges: make(map[Edge]bool),
+	}
+
+	// Add a node for every definition, so that definitions that nothing
+	// refers to still appear.
+	for _, id := range sortedKeys(dep.Flows) {
+		b.node(flowNode(id), KindFlow, string(id))
+	}
+	for _, id := range sortedKeys(dep.Commands) {
+		b.node(commandNode(id), KindCommand, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Packages) {
+		b.node(packageNode(id), KindPackage, string(id))
+		for _, cmdID := range sortedKeys(dep.Resources.Packages[id].Commands) {
+			b.node(packageCommandNode(id, cmdID), KindCommand, fmt.Sprintf("%s.%s", id, cmdID))
+		}
+	}
+	for _, id := range sortedKeys(dep.Conditions) {
+		b.node(conditionNode(id), KindCondition, string(id))
+	}
+	for _, id := range sortedKeys(dep.Apps) {
+		b.node(appNode(id), KindApp, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Locks) {
+		b.node(lockNode(id), KindLock, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Mutexes) {
+		b.node(mutexNode(id), KindMutex, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Semaphores) {
+		b.node(semaphoreNode(id), KindSemaphore, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Processes) {
+		b.node(processNode(id), KindProcess, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Registry.Keys) {
+		b.node(registryKeyNode(id), KindRegistryKey, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.Registry.Values) {
+		b.node(registryValueNode(id), KindRegistryValue, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.FileSystem.Directories) {
+		b.node(directoryNode(id), KindDirectory, string(id))
+	}
+	for _, id := range sortedKeys(dep.Resources.FileSystem.Files) {
+		b.node(fileNode(id), KindFile, string(id))
+	}
+
+	// Add the references made by each definition.
+	for _, id := range sortedKeys(dep.Flows) {
+		flow := dep.Flows[id]
+		from := flowNode(id)
+		for _, dependency := range flow.DependsOn {
+			b.edge(from, flowNode(dependency), "depends on")
+		}
+		for _, condition := range flow.Constraints {
+			b.edge(from, conditionNode(condition), "constraint")
+		}
+		for _, condition := range flow.Preconditions {
+			b.edge(from, conditionNode(condition), "precondition")
+		}
+		for _, lock := range flow.Locks {
+			b.edge(from, lockNode(lock), "lock")
+		}
+		b.actions(from, from+"/before", "before", flow.Before)
+		b.actions(from, from+"/actions", "", flow.Actions)
+		b.actions(from, from+"/after", "after", flow.After)
+	}
+
+	for _, id := range sortedKeys(dep.Commands) {
+		b.command(commandNode(id), dep.Commands[id], false)
+	}
+	for _, id := range sortedKeys(dep.Resources.Packages) {
+		pkg := dep.Resources.Packages[id]
+		from := packageNode(id)
+		for _, cmdID := range sortedKeys(pkg.Commands) {
+			b.edge(from, packageCommandNode(id, cmdID), "")
+			b.command(packageCommandNode(id, cmdID), pkg.Commands[cmdID], true)
+		}
+		for _, source := range pkg.Sources {
+			if source.Condition != "" {
+				b.edge(from, conditionNode(source.Condition), "source condition")
+			}
+		}
+	}
+
+	for _, id := range sortedKeys(dep.Conditions) {
+		b.condition(conditionNode(id), dep.Conditions[id])
+	}
+
+	for _, id := range sortedKeys(dep.Apps) {
+		app := dep.Apps[id]
+		from := appNode(id)
+		if app.Detection.Present != "" {
+			b.edge(from, conditionNode(app.Detection.Present), "present")
+		}
+		if app.Detection.Version != "" {
+			b.edge(from, registryValueNode(app.Detection.Version), "version")
+		}
+		if app.Detection.File.Path != "" {
+			b.edge(from, fileNode(app.Detection.File.Path), "detection")
+		}
+		for _, process := range app.Processes {
+			b.edge(from, processNode(process), "")
+		}
+	}
+
+	for _, id := range sortedKeys(dep.Resources.Locks) {
+		lock := dep.Resources.Locks[id]
+		if lock.Mutex != "" {
+			b.edge(lockNode(id), mutexNode(lock.Mutex), "")
+		}
+		if lock.Semaphore != "" {
+			b.edge(lockNode(id), semaphoreNode(lock.Semaphore), "")
+		}
+	}
+
+	for _, id := range sortedKeys(dep.Resources.Registry.Keys) {
+		if location := dep.Resources.Registry.Keys[id].Location; location != "" {
+			b.edge(registryKeyNode(id), registryKeyNode(location), "location")
+		}
+	}
+	for _, id := range sortedKeys(dep.Resources.Registry.Values) {
+		if key := dep.Resources.Registry.Values[id].Key; key != "" {
+			b.edge(registryValueNode(id), registryKeyNode(key), "key")
+		}
+	}
+	for _, id := range sortedKeys(dep.Resources.FileSystem.Directories) {
+		if location := dep.Resources.FileSystem.Directories[id].Location; location != "" {
+			b.edge(directoryNode(id), directoryNode(location), "location")
+		}
+	}
+	for _, id := range sortedKeys(dep.Resources.FileSystem.Files) {
+		if location := dep.Resources.FileSystem.Files[id].Location; location != "" {
+			b.edge(fileNode(id), directoryNode(location), "location")
+		}
+	}
+
+	return b.graph
+}
+
+// builder accumulates the nodes and edges of a graph.
+type builder struct {
+	graph Graph
+	nodes map[string]bool
+	edges map[Edge]bool
+}
+
+// node adds a node to the graph, unless it is already present.
+func (b *builder) node(id string, kind NodeKind, label string) {
+	if b.nodes[id] {
+		return
+	}
+	b.nodes[id] = true
+	b.graph.Nodes = append(b.graph.Nodes, Node{ID: id, Kind: kind, Label: label})
+}
+
+// edge adds an edge to the graph, unless it is already present. Edges that
+// refer to undefined nodes are kept, and the missing node is added with
+// a label that marks it as undefined.
+func (b *builder) edge(from, to, label string) {
+	e := Edge{From: from, To: to, Label: label}
+	if b.edges[e] {
+		return
+	}
+	if !b.nodes[to] {
+		kind, id, _ := cutNodeID(to)
+		b.node(to, kind, id+" (undefined)")
+	}
+	b.edges[e] = true
+	b.graph.Edges = append(b.graph.Edges, e)
+}
+
+// actions adds a node for each action in a list and the references they
+// make. Each action is linked to parent, and the list is identified by
+// prefix.
+func (b *builder) actions(parent, prefix, label string, actions []lbdeploy.Action) {
+	for i, action := range actions {
+		id := fmt.Sprintf("%s/%d", prefix, i+1)
+		b.node(id, KindAction, fmt.Sprintf("%d. %s", i+1, action.Type))
+		edgeLabel := label
+		if edgeLabel == "" && len(actions) > 1 {
+			edgeLabel = fmt.Sprintf("%d", i+1)
+		}
+		b.edge(parent, id, edgeLabel)
+
+		if action.Flow != "" {
+			b.edge(id, flowNode(action.Flow), "")
+		}
+		if action.Package != "" {
+			b.edge(id, packageNode(action.Package), "")
+		}
+		if action.Command != "" {
+			if action.Package != "" {
+				b.edge(id, packageCommandNode(action.Package, action.Command), "")
+			} else {
+				b.edge(id, commandNode(action.Command), "")
+			}
+		}
+		if action.SourceFile != "" {
+			b.edge(id, fileNode(action.SourceFile), "source")
+		}
+		if action.SourceDir != "" {
+			b.edge(id, directoryNode(action.SourceDir), "source")
+		}
+		if action.DestinationFile != "" {
+			b.edge(id, fileNode(action.DestinationFile), "destination")
+		}
+		if action.DestinationDir != "" {
+			b.edge(id, directoryNode(action.DestinationDir), "destination")
+		}
+		if action.RegistryValue != "" {
+			b.edge(id, registryValueNode(action.RegistryValue), "")
+		}
+		for _, process := range action.Processes {
+			b.edge(id, processNode(process), "")
+		}
+		for _, file := range action.UserSettings.Files {
+			b.edge(id, fileNode(file.Source), "user settings")
+		}
+
+		b.actions(id, id, "", action.Actions)
+		b.actions(id, id+"/rollback", "rollback", action.Rollback)
+	}
+}
+
+// command adds the references made by a command. The executable of a
+// package command refers to a package file, not a file resource.
+func (b *builder) command(from string, command lbdeploy.Command, inPackage bool) {
+	if command.HasResourceWorkingDirectory() {
+		b.edge(from, directoryNode(command.WorkingDirectory), "working directory")
+	}
+	for _, condition := range command.PostConditions {
+		b.edge(from, conditionNode(condition), "post-condition")
+	}
+	if command.Log.Upload != "" {
+		b.edge(from, directoryNode(command.Log.Upload), "log upload")
+	}
+	if _, isProgram := command.Executable.Program(); !inPackage && !isProgram && command.Executable != "" {
+		b.edge(from, fileNode(lbdeploy.FileResourceID(command.Executable)), "executable")
+	}
+	for _, app := range command.Installs {
+		b.edge(from, appNode(app), "installs")
+	}
+	for _, app := range command.Uninstalls {
+		b.edge(from, appNode(app), "uninstalls")
+	}
+}
+
+// condition adds the references made by a condition and its
+// subconditions.
+func (b *builder) condition(from string, c lbdeploy.Condition) {
+	switch c.Type {
+	case lbdeploy.ConditionTypeSubcondition:
+		b.edge(from, conditionNode(lbdeploy.ConditionID(c.Subject)), "")
+	case lbdeploy.ConditionTypeProcessIsRunning:
+		b.edge(from, processNode(lbdeploy.ProcessResourceID(c.Subject)), "")
+	case lbdeploy.ConditionTypeMutexExists:
+		b.edge(from, mutexNode(lbdeploy.MutexID(c.Subject)), "")
+	case lbdeploy.ConditionTypeRegistryKeyExists:
+		b.edge(from, registryKeyNode(lbdeploy.RegistryKeyResourceID(c.Subject)), "")
+	case lbdeploy.ConditionTypeRegistryValueExists, lbdeploy.ConditionTypeRegistryValueComparison:
+		b.edge(from, registryValueNode(lbdeploy.RegistryValueResourceID(c.Subject)), "")
+	case lbdeploy.ConditionTypeDirectoryExists, lbdeploy.ConditionTypeDirectoryEmpty, lbdeploy.ConditionTypeDirectoryContains, lbdeploy.ConditionTypeDirectorySize:
+		b.edge(from, directoryNode(lbdeploy.DirectoryResourceID(c.Subject)), "")
+	case lbdeploy.ConditionTypeFileExists:
+		b.edge(from, fileNode(lbdeploy.FileResourceID(c.Subject)), "")
+	}
+	for _, sub := range c.Any {
+		b.condition(from, sub)
+	}
+	for _, sub := range c.All {
+		b.condition(from, sub)
+	}
+}
+
+// Node IDs take the form "kind:id", which keeps the IDs of different kinds
+// of definitions from colliding.
+
+func flowNode(id lbdeploy.FlowID) string {
+	return nodeID(KindFlow, string(id))
+}
+
+func commandNode(id lbdeploy.CommandID) string {
+	return nodeID(KindCommand, string(id))
+}
+
+func packageNode(id lbdeploy.PackageID) string {
+	return nodeID(KindPackage, string(id))
+}
+
+func conditionNode(id lbdeploy.ConditionID) string {
+	return nodeID(KindCondition, string(id))
+}
+
+func appNode(id lbdeploy.AppID) string {
+	return nodeID(KindApp, string(id))
+}
+
+func lockNode(id lbdeploy.LockID) string {
+	return nodeID(KindLock, string(id))
+}
+
+func mutexNode(id lbdeploy.MutexID) string {
+	return nodeID(KindMutex, string(id))
+}
+
+func semaphoreNode(id lbdeploy.SemaphoreID) string {
+	return nodeID(KindSemaphore, string(id))
+}
+
+func processNode(id lbdeploy.ProcessResourceID) string {
+	return nodeID(KindProcess, string(id))
+}
+
+func registryKeyNode(id lbdeploy.RegistryKeyResourceID) string {
+	return nodeID(KindRegistryKey, string(id))
+}
+
+func registryValueNode(id lbdeploy.RegistryValueResourceID) string {
+	return nodeID(KindRegistryValue, string(id))
+}
+
+func directoryNode(id lbdeploy.DirectoryResourceID) string {
+	return nodeID(KindDirectory, string(id))
+}
+
+func fileNode(id lbdeploy.FileResourceID) string {
+	return nodeID(KindFile, string(id))
+}
+
+func packageCommandNode(pkg lbdeploy.PackageID, command lbdeploy.CommandID) string {
+	return nodeID(KindCommand, fmt.Sprintf("%s.%s", pkg, command))
+}
+
+// nodeID returns the ID of the node for a definition.
+func nodeID(kind NodeKind, id string) string {
+	return string(kind) + ":" + id
+}
+
+// cutNodeID returns the kind and definition ID of a node ID.
+func cutNodeID(node string) (kind NodeKind, id string, ok bool) {
+	k, id, ok := strings.Cut(node, ":")
+	return NodeKind(k), id, ok
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[K ~string, V any, M ~map[K]V](m M) []K {
+	return slices.Sorted(maps.Keys(m))
+}
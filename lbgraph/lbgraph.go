@@ -0,0 +1,143 @@
+// Package lbgraph builds a graph of the references between the flows,
+// actions, commands, conditions and resources of a LeafBridge deployment.
+//
+// The graph can be written in the DOT language, to be rendered by Graphviz
+// or a similar tool. Definitions that nothing refers to are highlighted,
+// which makes orphaned resources easy to spot in large deployments.
+package lbgraph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NodeKind identifies the kind of definition that a node represents.
+type NodeKind string
+
+// Kinds of nodes.
+const (
+	KindFlow          NodeKind = "flow"
+	KindAction        NodeKind = "action"
+	KindCommand       NodeKind = "command"
+	KindPackage       NodeKind = "package"
+	KindCondition     NodeKind = "condition"
+	KindApp           NodeKind = "app"
+	KindLock          NodeKind = "lock"
+	KindMutex         NodeKind = "mutex"
+	KindSemaphore     NodeKind = "semaphore"
+	KindProcess       NodeKind = "process"
+	KindRegistryKey   NodeKind = "registry-key"
+	KindRegistryValue NodeKind = "registry-value"
+	KindDirectory     NodeKind = "directory"
+	KindFile          NodeKind = "file"
+)
+
+// shape returns the DOT shape used for nodes of the kind.
+func (kind NodeKind) shape() string {
+	switch kind {
+	case KindFlow:
+		return "box3d"
+	case KindAction:
+		return "box"
+	case KindCommand:
+		return "cds"
+	case KindPackage:
+		return "folder"
+	case KindCondition:
+		return "diamond"
+	case KindApp:
+		return "component"
+	case KindLock, KindMutex, KindSemaphore:
+		return "octagon"
+	case KindRegistryKey, KindRegistryValue:
+		return "hexagon"
+	case KindDirectory, KindFile:
+		return "note"
+	default:
+		return "ellipse"
+	}
+}
+
+// Node is a definition within a deployment.
+type Node struct {
+	ID    string
+	Kind  NodeKind
+	Label string
+}
+
+// Edge is a reference from one definition to another.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// Graph holds the definitions of a deployment and the references between
+// them.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Orphans returns the nodes that no other node refers to. Flows and the
+// actions within them are not included, since they are invoked directly.
+func (g Graph) Orphans() []Node {
+	referenced := make(map[string]bool)
+	for _, edge := range g.Edges {
+		referenced[edge.To] = true
+	}
+
+	var orphans []Node
+	for _, node := range g.Nodes {
+		if node.Kind == KindFlow || node.Kind == KindAction {
+			continue
+		}
+		if !referenced[node.ID] {
+			orphans = append(orphans, node)
+		}
+	}
+	return orphans
+}
+
+// WriteDOT writes the graph to w in the DOT language. The graph is
+// given the provided name.
+func (g Graph) WriteDOT(w io.Writer, name string) error {
+	orphans := make(map[string]bool)
+	for _, node := range g.Orphans() {
+		orphans[node.ID] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", quote(name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Segoe UI\", fontsize=10];\n")
+	b.WriteString("  edge [fontname=\"Segoe UI\", fontsize=8];\n")
+
+	for _, node := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s, shape=%s", quote(node.Label), node.Kind.shape())
+		if orphans[node.ID] {
+			attrs += ", style=dashed, color=red, fontcolor=red"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", quote(node.ID), attrs)
+	}
+	for _, edge := range g.Edges {
+		if edge.Label != "" {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", quote(edge.From), quote(edge.To), quote(edge.Label))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", quote(edge.From), quote(edge.To))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quote returns s as a DOT string literal.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
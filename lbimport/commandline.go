@@ -0,0 +1,108 @@
+package lbimport
+
+import (
+	"path"
+	"strings"
+)
+
+// splitCommandLine splits a Windows command line into its arguments. It
+// honors double quotes but does not attempt to reproduce every quirk of
+// CommandLineToArgvW.
+func splitCommandLine(s string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// isMSIExec returns true if the given executable is msiexec.
+func isMSIExec(executable string) bool {
+	name := strings.ToLower(baseName(executable))
+	return name == "msiexec" || name == "msiexec.exe"
+}
+
+// baseName returns the last element of a Windows or slash-separated path.
+func baseName(p string) string {
+	return path.Base(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// slashPath converts a Windows relative path to a slash-separated path.
+func slashPath(p string) string {
+	return strings.TrimPrefix(strings.ReplaceAll(p, `\`, "/"), "./")
+}
+
+// fileID returns a package file ID derived from a file path.
+func fileID(p string) string {
+	name := strings.ToLower(baseName(p))
+	var out strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			out.WriteRune(r)
+		default:
+			out.WriteRune('-')
+		}
+	}
+	return strings.Trim(out.String(), "-")
+}
+
+// msiCommand describes an msiexec command line.
+type msiCommand struct {
+	Action string // i, x, p, ...
+	Target string // A file path or product code
+	Args   []string
+}
+
+// parseMSICommand parses the arguments that follow msiexec on a command
+// line. It returns false if the arguments do not identify an install or
+// uninstall action.
+func parseMSICommand(args []string) (cmd msiCommand, ok bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		lower := strings.ToLower(arg)
+		switch lower {
+		case "/i", "-i", "/x", "-x", "/uninstall", "-uninstall", "/package", "-package":
+			if i+1 >= len(args) {
+				return msiCommand{}, false
+			}
+			switch lower[1:] {
+			case "i", "package":
+				cmd.Action = "i"
+			default:
+				cmd.Action = "x"
+			}
+			cmd.Target = args[i+1]
+			i++
+		default:
+			cmd.Args = append(cmd.Args, arg)
+		}
+	}
+	return cmd, cmd.Action != ""
+}
+
+// isProductCode returns true if s looks like a product code GUID.
+func isProductCode(s string) bool {
+	return len(s) == 38 && s[0] == '{' && s[37] == '}'
+}
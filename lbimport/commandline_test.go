@@ -0,0 +1,91 @@
+package lbimport
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	fixtures := []struct {
+		In   string
+		Args []string
+	}{
+		{In: ``, Args: nil},
+		{In: `setup.exe`, Args: []string{`setup.exe`}},
+		{In: `setup.exe /S /norestart`, Args: []string{`setup.exe`, `/S`, `/norestart`}},
+		{In: "setup.exe\t/S  /norestart ", Args: []string{`setup.exe`, `/S`, `/norestart`}},
+		{In: `"Setup Files\setup.exe" /S`, Args: []string{`Setup Files\setup.exe`, `/S`}},
+		{In: `setup.exe /D="C:\Program Files\App"`, Args: []string{`setup.exe`, `/D=C:\Program Files\App`}},
+		{In: `setup.exe ""`, Args: []string{`setup.exe`, ``}},
+		{In: `msiexec /i "App Installer.msi" /qn`, Args: []string{`msiexec`, `/i`, `App Installer.msi`, `/qn`}},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.In, func(t *testing.T) {
+			if got := splitCommandLine(fixture.In); !slices.Equal(got, fixture.Args) {
+				t.Fatalf("unexpected arguments: got %q, want %q", got, fixture.Args)
+			}
+		})
+	}
+}
+
+func TestParseMSICommand(t *testing.T) {
+	fixtures := []struct {
+		In  string
+		OK  bool
+		Cmd msiCommand
+	}{
+		{
+			In:  `msiexec /i app.msi /qn`,
+			OK:  true,
+			Cmd: msiCommand{Action: "i", Target: "app.msi", Args: []string{"/qn"}},
+		},
+		{
+			In:  `MsiExec.exe /I "App Installer.msi" REBOOT=ReallySuppress /qn`,
+			OK:  true,
+			Cmd: msiCommand{Action: "i", Target: "App Installer.msi", Args: []string{"REBOOT=ReallySuppress", "/qn"}},
+		},
+		{
+			In:  `"C:\Windows\System32\msiexec.exe" -package app.msi`,
+			OK:  true,
+			Cmd: msiCommand{Action: "i", Target: "app.msi"},
+		},
+		{
+			In:  `msiexec /x {12345678-1234-1234-1234-123456789ABC} /qn`,
+			OK:  true,
+			Cmd: msiCommand{Action: "x", Target: "{12345678-1234-1234-1234-123456789ABC}", Args: []string{"/qn"}},
+		},
+		{
+			In:  `msiexec /uninstall app.msi`,
+			OK:  true,
+			Cmd: msiCommand{Action: "x", Target: "app.msi"},
+		},
+		{
+			In: `msiexec /p patch.msp /qn`,
+			OK: false,
+		},
+		{
+			In: `msiexec /i`,
+			OK: false,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.In, func(t *testing.T) {
+			args := splitCommandLine(fixture.In)
+			if !isMSIExec(args[0]) {
+				t.Fatalf("%s was not recognized as msiexec", args[0])
+			}
+			cmd, ok := parseMSICommand(args[1:])
+			if ok != fixture.OK {
+				t.Fatalf("unexpected result: got %t, want %t", ok, fixture.OK)
+			}
+			if !ok {
+				return
+			}
+			if cmd.Action != fixture.Cmd.Action || cmd.Target != fixture.Cmd.Target || !slices.Equal(cmd.Args, fixture.Cmd.Args) {
+				t.Fatalf("unexpected command: got %+v, want %+v", cmd, fixture.Cmd)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package lbimport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// configMgrDigest is the subset of a Configuration Manager application
+// definition (SDMPackageXML) that is used for import.
+type configMgrDigest struct {
+	XMLName     xml.Name `xml:"AppMgmtDigest"`
+	Application struct {
+		Title       string `xml:"Title"`
+		DisplayInfo struct {
+			Info []struct {
+				Title     string `xml:"Title"`
+				Publisher string `xml:"Publisher"`
+				Version   string `xml:"Version"`
+			} `xml:"Info"`
+		} `xml:"DisplayInfo"`
+		SoftwareVersion string `xml:"SoftwareVersion"`
+		Publisher       string `xml:"Publisher"`
+	} `xml:"Application"`
+	DeploymentTypes []configMgrDeploymentType `xml:"DeploymentType"`
+}
+
+// configMgrDeploymentType is a deployment type within a Configuration
+// Manager application.
+type configMgrDeploymentType struct {
+	Title     string `xml:"Title"`
+	Installer struct {
+		Technology   string              `xml:"Technology,attr"`
+		DetectAction configMgrAction     `xml:"DetectAction"`
+		Install      configMgrAction     `xml:"InstallAction"`
+		Uninstall    configMgrAction     `xml:"UninstallAction"`
+		CustomData   configMgrCustomData `xml:"CustomData"`
+	} `xml:"Installer"`
+}
+
+// configMgrAction is an install, uninstall or detection action within a
+// deployment type.
+type configMgrAction struct {
+	Args []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"Args>Arg"`
+}
+
+// Arg returns the value of the named argument.
+func (action configMgrAction) Arg(name string) string {
+	for _, arg := range action.Args {
+		if strings.EqualFold(arg.Name, name) {
+			return strings.TrimSpace(arg.Value)
+		}
+	}
+	return ""
+}
+
+// configMgrCustomData holds technology-specific data for a deployment type.
+type configMgrCustomData struct {
+	InstallCommandLine   string `xml:"InstallCommandLine"`
+	UninstallCommandLine string `xml:"UninstallCommandLine"`
+	ProductCode          string `xml:"ProductCode"`
+}
+
+// ParseConfigMgr reads a Configuration Manager application definition in the
+// SDMPackageXML (AppMgmtDigest) format and returns a definition describing
+// it.
+//
+// If the application has more than one deployment type, only the first one
+// with an install command line is imported.
+func ParseConfigMgr(r io.Reader) (Definition, error) {
+	// Definitions exported from Configuration Manager declare a UTF-16
+	// encoding even when they have already been converted to UTF-8 text,
+	// such as when saved from the SDMPackageXML property of an application.
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "utf-16") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported character set: %s", charset)
+	}
+
+	var digest configMgrDigest
+	if err := decoder.Decode(&digest); err != nil {
+		return Definition{}, fmt.Errorf("failed to parse the application XML: %w", err)
+	}
+
+	var def Definition
+	app := digest.Application
+	if len(app.DisplayInfo.Info) > 0 {
+		info := app.DisplayInfo.Info[0]
+		def.Name = strings.TrimSpace(info.Title)
+		def.Vendor = strings.TrimSpace(info.Publisher)
+		def.Version = strings.TrimSpace(info.Version)
+	}
+	if def.Name == "" {
+		def.Name = strings.TrimSpace(app.Title)
+	}
+	if def.Vendor == "" {
+		def.Vendor = strings.TrimSpace(app.Publisher)
+	}
+	if def.Version == "" {
+		def.Version = strings.TrimSpace(app.SoftwareVersion)
+	}
+
+	for i, dt := range digest.DeploymentTypes {
+		installer := dt.Installer
+		install := installer.Install.Arg("InstallCommandLine")
+		if install == "" {
+			install = strings.TrimSpace(installer.CustomData.InstallCommandLine)
+		}
+		if install == "" {
+			continue
+		}
+		uninstall := installer.Uninstall.Arg("UninstallCommandLine")
+		if uninstall == "" {
+			uninstall = strings.TrimSpace(installer.CustomData.UninstallCommandLine)
+		}
+
+		step, err := configMgrStep(install)
+		if err != nil {
+			return Definition{}, fmt.Errorf("deployment type %d: install command line: %w", i, err)
+		}
+		def.Install = []Step{step}
+
+		if uninstall != "" {
+			step, err := configMgrStep(uninstall)
+			if err != nil {
+				return Definition{}, fmt.Errorf("deployment type %d: uninstall command line: %w", i, err)
+			}
+			def.Uninstall = []Step{step}
+		}
+
+		code := installer.DetectAction.Arg("ProductCode")
+		if code == "" {
+			code = strings.TrimSpace(installer.CustomData.ProductCode)
+		}
+		if isProductCode(code) {
+			def.ProductCode = lbdeploy.ProductCode(code)
+		}
+
+		break
+	}
+
+	if len(def.Install) == 0 {
+		return Definition{}, fmt.Errorf("the application does not contain a deployment type with an install command line")
+	}
+
+	return def, nil
+}
+
+// configMgrStep converts a deployment type command line to a step.
+// Executable paths are relative to the root of the deployment type's
+// content, which becomes the root of the package.
+func configMgrStep(commandLine string) (Step, error) {
+	args := splitCommandLine(commandLine)
+	if len(args) == 0 {
+		return Step{}, fmt.Errorf("the command line is empty")
+	}
+
+	if isMSIExec(args[0]) {
+		msi, ok := parseMSICommand(args[1:])
+		if !ok {
+			return Step{}, fmt.Errorf("the msiexec command line does not install or uninstall a product")
+		}
+		return msiStep(msi, slashPath), nil
+	}
+
+	return Step{
+		Type: StepExe,
+		Path: slashPath(args[0]),
+		Args: args[1:],
+	}, nil
+}
@@ -0,0 +1,142 @@
+package lbimport_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbimport"
+)
+
+const configMgrSample = `<?xml version="1.0" encoding="utf-16"?>
+<AppMgmtDigest xmlns="http://schemas.microsoft.com/SystemCenterConfigurationManager/2009/AppMgmtDigest">
+  <Application AuthoringScopeId="ScopeId_1" LogicalName="Application_1" Version="3">
+    <DisplayInfo DefaultLanguage="en-US">
+      <Info Language="en-US">
+        <Title>Example App</Title>
+        <Publisher>Example Corp</Publisher>
+        <Version>2.1.0</Version>
+      </Info>
+    </DisplayInfo>
+    <Title ResourceId="Res_1">Example App (Title)</Title>
+    <Publisher ResourceId="Res_2">Example Corp (Publisher)</Publisher>
+    <SoftwareVersion ResourceId="Res_3">2.1</SoftwareVersion>
+  </Application>
+  <DeploymentType AuthoringScopeId="ScopeId_1" LogicalName="DeploymentType_1" Version="3">
+    <Title ResourceId="Res_4">Example App - Script</Title>
+    <Installer Technology="Script">
+      <InstallAction>
+        <Args>
+          <Arg Name="InstallCommandLine" Type="String"></Arg>
+        </Args>
+      </InstallAction>
+    </Installer>
+  </DeploymentType>
+  <DeploymentType AuthoringScopeId="ScopeId_1" LogicalName="DeploymentType_2" Version="3">
+    <Title ResourceId="Res_5">Example App - Windows Installer (*.msi file)</Title>
+    <Installer Technology="MSI">
+      <DetectAction>
+        <Args>
+          <Arg Name="ProductCode" Type="String">{12345678-1234-1234-1234-123456789ABC}</Arg>
+        </Args>
+      </DetectAction>
+      <InstallAction>
+        <Args>
+          <Arg Name="InstallCommandLine" Type="String">msiexec /i "Example App.msi" /qn REBOOT=ReallySuppress</Arg>
+        </Args>
+      </InstallAction>
+      <UninstallAction>
+        <Args>
+          <Arg Name="UninstallCommandLine" Type="String">msiexec /x {12345678-1234-1234-1234-123456789ABC} /qn</Arg>
+        </Args>
+      </UninstallAction>
+    </Installer>
+  </DeploymentType>
+</AppMgmtDigest>
+`
+
+func TestParseConfigMgr(t *testing.T) {
+	def, err := lbimport.ParseConfigMgr(strings.NewReader(configMgrSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := lbimport.Definition{
+		Name:        "Example App",
+		Vendor:      "Example Corp",
+		Version:     "2.1.0",
+		ProductCode: "{12345678-1234-1234-1234-123456789ABC}",
+		Install: []lbimport.Step{{
+			Type: lbimport.StepMSIInstall,
+			Path: "Example App.msi",
+			Args: []string{"/qn", "REBOOT=ReallySuppress"},
+		}},
+		Uninstall: []lbimport.Step{{
+			Type:        lbimport.StepMSIUninstallProductCode,
+			ProductCode: "{12345678-1234-1234-1234-123456789ABC}",
+			Args:        []string{"/qn"},
+		}},
+	}
+	if !reflect.DeepEqual(def, want) {
+		t.Fatalf("unexpected definition:\n got %+v\nwant %+v", def, want)
+	}
+}
+
+func TestParseConfigMgrCommandLines(t *testing.T) {
+	fixtures := []struct {
+		Name    string
+		Install string
+		Step    lbimport.Step
+	}{
+		{
+			Name:    "UnquotedSetup",
+			Install: `setup.exe /S /norestart`,
+			Step:    lbimport.Step{Type: lbimport.StepExe, Path: "setup.exe", Args: []string{"/S", "/norestart"}},
+		},
+		{
+			Name:    "QuotedSetup",
+			Install: `"Setup Files\setup.exe" /S /D="C:\Program Files\App"`,
+			Step:    lbimport.Step{Type: lbimport.StepExe, Path: "Setup Files/setup.exe", Args: []string{"/S", `/D=C:\Program Files\App`}},
+		},
+		{
+			Name:    "RelativeSetup",
+			Install: `.\bin\setup.exe`,
+			Step:    lbimport.Step{Type: lbimport.StepExe, Path: "bin/setup.exe", Args: []string{}},
+		},
+		{
+			Name:    "UnquotedMSI",
+			Install: `msiexec.exe /i app.msi /qn`,
+			Step:    lbimport.Step{Type: lbimport.StepMSIInstall, Path: "app.msi", Args: []string{"/qn"}},
+		},
+		{
+			Name:    "QuotedMSI",
+			Install: `"msiexec.exe" /i "x64\App Installer.msi" /qn`,
+			Step:    lbimport.Step{Type: lbimport.StepMSIInstall, Path: "x64/App Installer.msi", Args: []string{"/qn"}},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			xml := `<AppMgmtDigest><Application><Title>App</Title></Application><DeploymentType><Installer>` +
+				`<CustomData><InstallCommandLine>` + fixture.Install + `</InstallCommandLine></CustomData>` +
+				`</Installer></DeploymentType></AppMgmtDigest>`
+			def, err := lbimport.ParseConfigMgr(strings.NewReader(xml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(def.Install) != 1 {
+				t.Fatalf("unexpected number of install steps: %d", len(def.Install))
+			}
+			if got := def.Install[0]; !reflect.DeepEqual(got, fixture.Step) {
+				t.Fatalf("unexpected step:\n got %+v\nwant %+v", got, fixture.Step)
+			}
+		})
+	}
+}
+
+func TestParseConfigMgrWithoutInstall(t *testing.T) {
+	const xml = `<AppMgmtDigest><Application><Title>App</Title></Application><DeploymentType><Installer/></DeploymentType></AppMgmtDigest>`
+	if _, err := lbimport.ParseConfigMgr(strings.NewReader(xml)); err == nil {
+		t.Fatal("an application without an install command line was accepted")
+	}
+}
@@ -0,0 +1,266 @@
+package lbimport
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/gentlemanautomaton/winapp/unpackaged/appscope"
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// Definition is a tool-neutral description of an application deployment
+// that has been read from another deployment tool.
+type Definition struct {
+	Name         string
+	Vendor       string
+	Version      string
+	Architecture appcode.Architecture
+	ProductCode  lbdeploy.ProductCode
+	Install      []Step
+	Uninstall    []Step
+}
+
+// StepType identifies the type of an imported deployment step.
+type StepType string
+
+// Step types.
+const (
+	StepExe                     StepType = "exe"
+	StepMSIInstall              StepType = "msi-install"
+	StepMSIUninstall            StepType = "msi-uninstall"
+	StepMSIUninstallProductCode StepType = "msi-uninstall-product-code"
+)
+
+// Step is a single command invoked while installing or uninstalling an
+// application.
+type Step struct {
+	Type StepType
+
+	// Path is the path of the executable or installer file, relative to the
+	// root of the package. It is empty for product code uninstallation.
+	Path string
+
+	// ProductCode is the product code to be uninstalled, if the step
+	// uninstalls a product by its product code.
+	ProductCode lbdeploy.ProductCode
+
+	// Args are additional arguments for the command.
+	Args []string
+}
+
+// Options are options used when converting a definition to a deployment.
+type Options struct {
+	// ID is the identifier of the deployment.
+	ID lbdeploy.DeploymentID
+
+	// App is the identifier of the primary application. If empty, "app"
+	// is used.
+	App lbdeploy.AppID
+
+	// URL is a URL from which the package archive can be downloaded. If
+	// empty, a placeholder is used.
+	URL string
+}
+
+// Deployment converts the definition into a LeafBridge deployment skeleton.
+//
+// All of the files referenced by the definition are expected to be present
+// in a single zip archive package. Product code uninstallation steps are
+// converted to deployment commands.
+func (def Definition) Deployment(opts Options) (lbdeploy.Deployment, error) {
+	if opts.ID == "" {
+		return lbdeploy.Deployment{}, fmt.Errorf("a deployment ID is missing")
+	}
+	if len(def.Install) == 0 && len(def.Uninstall) == 0 {
+		return lbdeploy.Deployment{}, fmt.Errorf("the definition does not contain any install or uninstall commands")
+	}
+
+	b := builder{def: def, opts: opts}
+	if b.opts.App == "" {
+		b.opts.App = "app"
+	}
+	return b.build(), nil
+}
+
+// builder accumulates the components of a deployment as steps are
+// converted.
+type builder struct {
+	def     Definition
+	opts    Options
+	apps    lbdeploy.AppMap
+	codes   map[lbdeploy.ProductCode]lbdeploy.AppID
+	files   lbdeploy.PackageFileMap
+	pkgCmds lbdeploy.CommandMap
+	depCmds lbdeploy.CommandMap
+	flows   lbdeploy.FlowMap
+}
+
+func (b *builder) build() lbdeploy.Deployment {
+	name := b.def.Name
+	if b.def.Vendor != "" && name != "" {
+		name = b.def.Vendor + " " + name
+	}
+	if name == "" {
+		name = string(b.opts.ID)
+	}
+	if b.def.Version != "" {
+		name += " " + b.def.Version
+	}
+
+	arch := b.def.Architecture
+	if arch == "" {
+		arch = appcode.X64
+	}
+
+	b.apps = lbdeploy.AppMap{b.opts.App: {
+		Name:         name,
+		Architecture: arch,
+		Scope:        appscope.Machine,
+		ProductCode:  b.def.ProductCode,
+	}}
+	b.codes = make(map[lbdeploy.ProductCode]lbdeploy.AppID)
+	if b.def.ProductCode != "" {
+		b.codes[b.def.ProductCode] = b.opts.App
+	}
+	b.files = make(lbdeploy.PackageFileMap)
+	b.pkgCmds = make(lbdeploy.CommandMap)
+	b.depCmds = make(lbdeploy.CommandMap)
+	b.flows = make(lbdeploy.FlowMap)
+
+	b.addFlow("install", b.def.Install)
+	b.addFlow("uninstall", b.def.Uninstall)
+
+	dep := lbdeploy.Deployment{
+		ID:    b.opts.ID,
+		Name:  name,
+		Apps:  b.apps,
+		Flows: b.flows,
+	}
+	if len(b.depCmds) > 0 {
+		dep.Commands = b.depCmds
+	}
+	if len(b.pkgCmds) > 0 {
+		url := b.opts.URL
+		if url == "" {
+			url = "https://example.com/path/to/package.zip"
+		}
+		dep.Resources.Packages = lbdeploy.PackageMap{
+			"package": {
+				Name:       string(b.opts.ID),
+				Type:       "archive",
+				Format:     "zip",
+				Sources:    []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceHTTP, URL: url}},
+				Attributes: lbdeploy.FileAttributes{Hashes: filehash.Map{filehash.SHA3_256: nil}},
+				Files:      b.files,
+				Commands:   b.pkgCmds,
+			},
+		}
+	}
+
+	return dep
+}
+
+// addFlow adds a flow with the given ID that invokes each of the steps in
+// order. It does nothing if steps is empty.
+func (b *builder) addFlow(id lbdeploy.FlowID, steps []Step) {
+	if len(steps) == 0 {
+		return
+	}
+
+	var flow lbdeploy.Flow
+	for i, step := range steps {
+		cmdID := lbdeploy.CommandID(fmt.Sprintf("%s-%d", id, i+1))
+		if len(steps) == 1 {
+			cmdID = lbdeploy.CommandID(id)
+		}
+
+		var cmd lbdeploy.Command
+		if len(step.Args) > 0 {
+			cmd.Args = step.Args
+		}
+		switch id {
+		case "install":
+			cmd.Installs = lbdeploy.AppList{b.opts.App}
+		case "uninstall":
+			cmd.Uninstalls = lbdeploy.AppList{b.opts.App}
+		}
+
+		if step.Type == StepMSIUninstallProductCode {
+			cmd.Type = lbdeploy.CommandTypeMSIUninstallProductCode
+			cmd.Installs = nil
+			cmd.Uninstalls = lbdeploy.AppList{b.appForProductCode(step.ProductCode)}
+			b.depCmds[cmdID] = cmd
+			flow.Actions = append(flow.Actions, lbdeploy.Action{
+				Type:    lbdeploy.ActionInvokeCommand,
+				Command: cmdID,
+			})
+			continue
+		}
+
+		switch step.Type {
+		case StepMSIInstall:
+			cmd.Type = lbdeploy.CommandTypeMSIInstall
+		case StepMSIUninstall:
+			cmd.Type = lbdeploy.CommandTypeMSIUninstall
+		default:
+			cmd.Type = lbdeploy.CommandTypeExe
+		}
+		cmd.Executable = lbdeploy.ExecutableID(b.addFile(step.Path))
+		b.pkgCmds[cmdID] = cmd
+		flow.Actions = append(flow.Actions, lbdeploy.Action{
+			Type:    lbdeploy.ActionInvokeCommand,
+			Package: "package",
+			Command: cmdID,
+		})
+	}
+
+	b.flows[id] = flow
+}
+
+// addFile adds a file with the given path to the package file map and
+// returns its identifier.
+func (b *builder) addFile(path string) lbdeploy.PackageFileID {
+	path = slashPath(path)
+	id := lbdeploy.PackageFileID(fileID(path))
+	if id == "" {
+		id = "file"
+	}
+	for i := 2; ; i++ {
+		existing, found := b.files[id]
+		if !found {
+			b.files[id] = lbdeploy.PackageFile{Path: path}
+			return id
+		}
+		if existing.Path == path {
+			return id
+		}
+		id = lbdeploy.PackageFileID(fmt.Sprintf("%s-%d", fileID(path), i))
+	}
+}
+
+// appForProductCode returns the ID of the application with the given
+// product code, adding an application to the deployment if necessary.
+func (b *builder) appForProductCode(code lbdeploy.ProductCode) lbdeploy.AppID {
+	if id, found := b.codes[code]; found {
+		return id
+	}
+
+	// Adopt the product code for the primary app if it doesn't have one.
+	if primary := b.apps[b.opts.App]; primary.ProductCode == "" {
+		primary.ProductCode = code
+		b.apps[b.opts.App] = primary
+		b.codes[code] = b.opts.App
+		return b.opts.App
+	}
+
+	id := lbdeploy.AppID(fmt.Sprintf("%s-%d", b.opts.App, len(b.codes)+1))
+	b.apps[id] = lbdeploy.Application{
+		Name:         fmt.Sprintf("%s (%s)", b.apps[b.opts.App].Name, code),
+		Architecture: b.apps[b.opts.App].Architecture,
+		Scope:        appscope.Machine,
+		ProductCode:  code,
+	}
+	b.codes[code] = id
+	return id
+}
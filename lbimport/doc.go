@@ -0,0 +1,6 @@
+// Package lbimport converts deployment definitions used by other software
+// deployment tools into LeafBridge deployment skeletons.
+//
+// The resulting deployments are starting points. Package sources, sizes and
+// hashes must be filled in before they can be used.
+package lbimport
@@ -0,0 +1,370 @@
+package lbimport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// ParsePSADT reads a PSAppDeployToolkit Deploy-Application.ps1 script (or an
+// Invoke-AppDeployToolkit.ps1 script from version 4) and returns a
+// definition describing it.
+//
+// The application variables are read from the script's variable
+// declarations. Install and uninstall steps are read from the Execute-MSI,
+// Execute-Process, Start-ADTMsiProcess and Start-ADTProcess calls found in
+// the installation and uninstallation phases. Other PowerShell logic in the
+// script is ignored.
+func ParsePSADT(r io.Reader) (Definition, error) {
+	var (
+		def   Definition
+		phase string
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// Look for variable assignments, and for hashtable entries used by
+		// version 4 of the toolkit.
+		if name, value, ok := psadtAssignment(text); ok {
+			if strings.EqualFold(name, "installPhase") {
+				phase = strings.ToLower(value)
+			} else {
+				def.setVariable(name, value)
+			}
+			continue
+		}
+		if name, value, ok := psadtHashEntry(text); ok {
+			def.setVariable(name, value)
+			continue
+		}
+
+		// Version 4 scripts use functions for each phase.
+		if fields := strings.Fields(text); len(fields) >= 2 && strings.EqualFold(fields[0], "function") {
+			phase = psadtFunctionPhase(fields[1])
+			continue
+		}
+
+		// Look for commands.
+		step, ok, err := psadtStep(text)
+		if err != nil {
+			return Definition{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !ok {
+			continue
+		}
+
+		switch phase {
+		case "pre-installation", "installation", "post-installation":
+			def.Install = append(def.Install, step)
+		case "pre-uninstallation", "uninstallation", "post-uninstallation":
+			def.Uninstall = append(def.Uninstall, step)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Definition{}, err
+	}
+
+	// Adopt the product code of an MSI installer if one is uninstalled by
+	// product code.
+	for _, step := range def.Uninstall {
+		if step.Type == StepMSIUninstallProductCode {
+			def.ProductCode = step.ProductCode
+			break
+		}
+	}
+
+	return def, nil
+}
+
+// setVariable applies a toolkit application variable to the definition.
+// Unrecognized variables are ignored.
+func (def *Definition) setVariable(name, value string) {
+	switch strings.ToLower(name) {
+	case "appvendor":
+		def.Vendor = value
+	case "appname":
+		def.Name = value
+	case "appversion":
+		def.Version = value
+	case "apparch":
+		switch strings.ToLower(value) {
+		case "x64", "amd64", "64":
+			def.Architecture = appcode.X64
+		case "x86", "32":
+			def.Architecture = appcode.X86
+		}
+	}
+}
+
+// psadtFunctionPhase returns the install phase implemented by the given
+// version 4 function name, or an empty string.
+func psadtFunctionPhase(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), "{")
+	switch name {
+	case "install-adtdeployment":
+		return "installation"
+	case "uninstall-adtdeployment":
+		return "uninstallation"
+	default:
+		return ""
+	}
+}
+
+// psadtAssignment parses a PowerShell variable assignment with a literal
+// string value, such as [String]$appName = 'Example'.
+func psadtAssignment(text string) (name, value string, ok bool) {
+	// Strip a type constraint.
+	if strings.HasPrefix(text, "[") {
+		end := strings.Index(text, "]")
+		if end < 0 {
+			return "", "", false
+		}
+		text = strings.TrimSpace(text[end+1:])
+	}
+	if !strings.HasPrefix(text, "$") {
+		return "", "", false
+	}
+	text = text[1:]
+
+	name, rest, found := strings.Cut(text, "=")
+	if !found {
+		return "", "", false
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " .[(") {
+		return "", "", false
+	}
+
+	value, ok = psadtLiteral(strings.TrimSpace(rest))
+	return name, value, ok
+}
+
+// psadtHashEntry parses a hashtable entry with a literal string value, such
+// as AppName = 'Example'.
+func psadtHashEntry(text string) (name, value string, ok bool) {
+	name, rest, found := strings.Cut(text, "=")
+	if !found {
+		return "", "", false
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " $.[(") {
+		return "", "", false
+	}
+	value, ok = psadtLiteral(strings.TrimSpace(rest))
+	return name, value, ok
+}
+
+// psadtLiteral parses a single-quoted or double-quoted PowerShell string
+// literal at the start of text. Variables within double-quoted strings are
+// not expanded.
+func psadtLiteral(text string) (string, bool) {
+	if len(text) < 2 {
+		return "", false
+	}
+	quote := text[0]
+	if quote != '\'' && quote != '"' {
+		return "", false
+	}
+
+	var out strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		if c == quote {
+			// A doubled quote is an escaped quote.
+			if i+1 < len(text) && text[i+1] == quote {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			return out.String(), true
+		}
+		out.WriteByte(c)
+	}
+	return "", false
+}
+
+// psadtParameters parses the named parameters of a PowerShell command
+// invocation. Only parameters with literal string values or no value are
+// returned. Switch parameters are given an empty value.
+func psadtParameters(text string) map[string]string {
+	params := make(map[string]string)
+	for len(text) > 0 {
+		text = strings.TrimSpace(text)
+		if !strings.HasPrefix(text, "-") {
+			// Skip a token we don't understand.
+			if i := strings.IndexAny(text, " \t"); i >= 0 {
+				text = text[i:]
+				continue
+			}
+			break
+		}
+
+		// Read the parameter name.
+		end := strings.IndexAny(text, " \t:")
+		if end < 0 {
+			params[strings.ToLower(text[1:])] = ""
+			break
+		}
+		name := strings.ToLower(text[1:end])
+		text = strings.TrimLeft(text[end:], ": \t")
+
+		// Read the parameter value, if present.
+		if strings.HasPrefix(text, "-") || text == "" {
+			params[name] = ""
+			continue
+		}
+		if text[0] == '\'' || text[0] == '"' {
+			value, ok := psadtLiteral(text)
+			if ok {
+				params[name] = value
+			}
+			text = skipLiteral(text)
+			continue
+		}
+		value := text
+		if i := strings.IndexAny(text, " \t"); i >= 0 {
+			value = text[:i]
+		}
+		text = text[len(value):]
+
+		// Variables and expressions are not literal values.
+		if !strings.HasPrefix(value, "$") && !strings.HasPrefix(value, "(") {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// skipLiteral returns the text that follows a quoted string literal at the
+// start of text.
+func skipLiteral(text string) string {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		if text[i] == quote {
+			if i+1 < len(text) && text[i+1] == quote {
+				i++
+				continue
+			}
+			return text[i+1:]
+		}
+	}
+	return ""
+}
+
+// psadtStep parses a toolkit command that installs or uninstalls software.
+// It returns false if the text does not contain such a command.
+func psadtStep(text string) (step Step, ok bool, err error) {
+	command, rest, _ := strings.Cut(text, " ")
+	switch strings.ToLower(command) {
+	case "execute-msi", "start-adtmsiprocess":
+		params := psadtParameters(rest)
+		target := params["path"]
+		if target == "" {
+			target = params["filepath"]
+		}
+		if target == "" {
+			target = params["productcode"]
+		}
+		if target == "" {
+			return Step{}, false, fmt.Errorf("the %s command does not specify a literal path", command)
+		}
+		step.Args = splitCommandLine(params["parameters"])
+		if step.Args == nil {
+			step.Args = splitCommandLine(params["argumentlist"])
+		}
+		if extra := params["addparameters"]; extra != "" {
+			step.Args = append(step.Args, splitCommandLine(extra)...)
+		}
+		action := params["action"]
+		if action == "" {
+			action = "install"
+		}
+		switch strings.ToLower(action) {
+		case "install":
+			step.Type = StepMSIInstall
+			step.Path = psadtFilePath(target)
+		case "uninstall":
+			if isProductCode(target) {
+				step.Type = StepMSIUninstallProductCode
+				step.ProductCode = lbdeploy.ProductCode(target)
+			} else {
+				step.Type = StepMSIUninstall
+				step.Path = psadtFilePath(target)
+			}
+		default:
+			// Repair, patch and other actions are not imported.
+			return Step{}, false, nil
+		}
+		return step, true, nil
+	case "execute-process", "start-adtprocess":
+		params := psadtParameters(rest)
+		target := params["path"]
+		if target == "" {
+			target = params["filepath"]
+		}
+		if target == "" {
+			return Step{}, false, fmt.Errorf("the %s command does not specify a literal path", command)
+		}
+		args := params["parameters"]
+		if args == "" {
+			args = params["argumentlist"]
+		}
+		if isMSIExec(target) {
+			msi, ok := parseMSICommand(splitCommandLine(args))
+			if !ok {
+				return Step{}, false, nil
+			}
+			return msiStep(msi, psadtFilePath), true, nil
+		}
+		step.Type = StepExe
+		step.Path = psadtFilePath(target)
+		step.Args = splitCommandLine(args)
+		return step, true, nil
+	default:
+		return Step{}, false, nil
+	}
+}
+
+// psadtFilePath converts a toolkit file path to a path relative to the root
+// of the package. Files referenced without a directory are assumed to be in
+// the toolkit's Files directory.
+func psadtFilePath(p string) string {
+	p = slashPath(p)
+	for _, prefix := range []string{"$dirFiles/", "$adtSession.DirFiles/", "$($adtSession.DirFiles)/"} {
+		if rest, found := strings.CutPrefix(p, prefix); found {
+			return path.Join("Files", rest)
+		}
+	}
+	if !strings.Contains(p, "/") {
+		return path.Join("Files", p)
+	}
+	return p
+}
+
+// msiStep converts an msiexec command to a step. The given function maps
+// file paths to paths relative to the package root.
+func msiStep(msi msiCommand, mapPath func(string) string) Step {
+	step := Step{Args: msi.Args}
+	switch {
+	case msi.Action == "i":
+		step.Type = StepMSIInstall
+		step.Path = mapPath(msi.Target)
+	case isProductCode(msi.Target):
+		step.Type = StepMSIUninstallProductCode
+		step.ProductCode = lbdeploy.ProductCode(msi.Target)
+	default:
+		step.Type = StepMSIUninstall
+		step.Path = mapPath(msi.Target)
+	}
+	return step
+}
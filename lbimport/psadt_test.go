@@ -0,0 +1,135 @@
+package lbimport_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/leafbridge/leafbridge-deploy/lbimport"
+)
+
+const psadtSample = `
+##*===============================================
+##* VARIABLE DECLARATION
+##*===============================================
+[String]$appVendor = 'Example Corp'
+[String]$appName = 'Example App'
+[String]$appVersion = '2.1.0'
+[String]$appArch = 'x64'
+
+If ($deploymentType -ine 'Uninstall' -and $deploymentType -ine 'Repair') {
+	[String]$installPhase = 'Pre-Installation'
+	Show-InstallationWelcome -CloseApps 'example' -CheckDiskSpace -PersistPrompt
+
+	[String]$installPhase = 'Installation'
+	Execute-MSI -Action 'Install' -Path 'Example App.msi' -Parameters '/qn REBOOT=ReallySuppress'
+	Execute-Process -Path "$dirFiles\Plugins\plugin-setup.exe" -Parameters '/S /D="C:\Program Files\Example"'
+
+	[String]$installPhase = 'Post-Installation'
+}
+ElseIf ($deploymentType -ieq 'Uninstall') {
+	[String]$installPhase = 'Uninstallation'
+	Execute-MSI -Action Uninstall -Path '{12345678-1234-1234-1234-123456789ABC}'
+	Execute-Process -Path 'msiexec.exe' -Parameters '/x "Plugin.msi" /qn'
+}
+`
+
+func TestParsePSADT(t *testing.T) {
+	def, err := lbimport.ParsePSADT(strings.NewReader(psadtSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := lbimport.Definition{
+		Name:         "Example App",
+		Vendor:       "Example Corp",
+		Version:      "2.1.0",
+		Architecture: appcode.X64,
+		ProductCode:  "{12345678-1234-1234-1234-123456789ABC}",
+		Install: []lbimport.Step{
+			{Type: lbimport.StepMSIInstall, Path: "Files/Example App.msi", Args: []string{"/qn", "REBOOT=ReallySuppress"}},
+			{Type: lbimport.StepExe, Path: "Files/Plugins/plugin-setup.exe", Args: []string{"/S", `/D=C:\Program Files\Example`}},
+		},
+		Uninstall: []lbimport.Step{
+			{Type: lbimport.StepMSIUninstallProductCode, ProductCode: "{12345678-1234-1234-1234-123456789ABC}"},
+			{Type: lbimport.StepMSIUninstall, Path: "Files/Plugin.msi", Args: []string{"/qn"}},
+		},
+	}
+	if !reflect.DeepEqual(def, want) {
+		t.Fatalf("unexpected definition:\n got %+v\nwant %+v", def, want)
+	}
+}
+
+func TestParsePSADTCommands(t *testing.T) {
+	fixtures := []struct {
+		Name    string
+		Command string
+		Steps   []lbimport.Step
+	}{
+		{
+			Name:    "ExecuteMSI",
+			Command: `Execute-MSI -Action 'Install' -Path 'app.msi'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepMSIInstall, Path: "Files/app.msi"}},
+		},
+		{
+			Name:    "ExecuteMSIDefaultAction",
+			Command: `Execute-MSI -Path "$dirFiles\x64\app.msi" -AddParameters 'ALLUSERS=1'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepMSIInstall, Path: "Files/x64/app.msi", Args: []string{"ALLUSERS=1"}}},
+		},
+		{
+			Name:    "ExecuteMSIParametersAndAddParameters",
+			Command: `Execute-MSI -Path 'app.msi' -Parameters '/qn' -AddParameters 'TRANSFORMS="Custom Settings.mst"'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepMSIInstall, Path: "Files/app.msi", Args: []string{"/qn", "TRANSFORMS=Custom Settings.mst"}}},
+		},
+		{
+			Name:    "ExecuteMSIRepair",
+			Command: `Execute-MSI -Action 'Repair' -Path 'app.msi'`,
+		},
+		{
+			Name:    "StartADTMsiProcess",
+			Command: `Start-ADTMsiProcess -Action Install -FilePath 'app.msi' -ArgumentList '/qn'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepMSIInstall, Path: "Files/app.msi", Args: []string{"/qn"}}},
+		},
+		{
+			Name:    "ExecuteProcessUnquoted",
+			Command: `Execute-Process -Path setup.exe -Parameters '/S'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepExe, Path: "Files/setup.exe", Args: []string{"/S"}}},
+		},
+		{
+			Name:    "ExecuteProcessQuoted",
+			Command: `Execute-Process -Path "$dirFiles\Setup Files\setup.exe" -Parameters '/S /norestart' -WindowStyle 'Hidden'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepExe, Path: "Files/Setup Files/setup.exe", Args: []string{"/S", "/norestart"}}},
+		},
+		{
+			Name:    "ExecuteProcessMSIExec",
+			Command: `Execute-Process -Path 'msiexec.exe' -Parameters '/i "App Installer.msi" /qn'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepMSIInstall, Path: "Files/App Installer.msi", Args: []string{"/qn"}}},
+		},
+		{
+			Name:    "StartADTProcess",
+			Command: `Start-ADTProcess -FilePath "$($adtSession.DirFiles)\setup.exe" -ArgumentList '/quiet'`,
+			Steps:   []lbimport.Step{{Type: lbimport.StepExe, Path: "Files/setup.exe", Args: []string{"/quiet"}}},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			script := "[String]$installPhase = 'Installation'\n" + fixture.Command + "\n"
+			def, err := lbimport.ParsePSADT(strings.NewReader(script))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(def.Install, fixture.Steps) {
+				t.Fatalf("unexpected steps:\n got %+v\nwant %+v", def.Install, fixture.Steps)
+			}
+		})
+	}
+}
+
+func TestParsePSADTWithoutLiteralPath(t *testing.T) {
+	const script = "[String]$installPhase = 'Installation'\nExecute-Process -Path $setupPath\n"
+	if _, err := lbimport.ParsePSADT(strings.NewReader(script)); err == nil {
+		t.Fatal("a command without a literal path was accepted")
+	}
+}
@@ -0,0 +1,150 @@
+package lblint
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// checkIDs returns a problem for each identifier in dep that does not
+// follow the ID conventions.
+func checkIDs(dep lbdeploy.Deployment) []problem {
+	var problems []problem
+	check := func(kind string, ids []string) {
+		for _, id := range ids {
+			if !IsConventionalID(id) {
+				problems = append(problems, problem{
+					Subject: id,
+					Message: fmt.Sprintf("the \"%s\" %s ID should be made up of lowercase letters, digits and hyphens", id, kind),
+				})
+			}
+		}
+	}
+
+	r := dep.Resources
+	check("app", keys(dep.Apps))
+	check("condition", keys(dep.Conditions))
+	check("command", keys(dep.Commands))
+	check("flow", keys(dep.Flows))
+	check("process", keys(r.Processes))
+	check("mutex", keys(r.Mutexes))
+	check("semaphore", keys(r.Semaphores))
+	check("lock", keys(r.Locks))
+	check("registry key", keys(r.Registry.Keys))
+	check("registry value", keys(r.Registry.Values))
+	check("directory", keys(r.FileSystem.Directories))
+	check("file", keys(r.FileSystem.Files))
+	check("package", keys(r.Packages))
+	for pkgID, pkg := range r.Packages {
+		for _, id := range keys(pkg.Commands) {
+			if !IsConventionalID(id) {
+				problems = append(problems, problem{
+					Subject: fmt.Sprintf("%s.%s", pkgID, id),
+					Message: fmt.Sprintf("the \"%s\" command ID in the \"%s\" package should be made up of lowercase letters, digits and hyphens", id, pkgID),
+				})
+			}
+		}
+		for _, id := range keys(pkg.Files) {
+			if !IsConventionalID(id) {
+				problems = append(problems, problem{
+					Subject: fmt.Sprintf("%s.%s", pkgID, id),
+					Message: fmt.Sprintf("the \"%s\" file ID in the \"%s\" package should be made up of lowercase letters, digits and hyphens", id, pkgID),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// IsConventionalID returns true if id follows the ID conventions of
+// LeafBridge. Conventional IDs are made up of lowercase letters, digits and
+// hyphens, such as "install-app". Periods may divide an ID into segments,
+// such as the namespace of a library and the ID within it. Each segment
+// must begin and end with a letter or digit.
+func IsConventionalID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for segment := range strings.SplitSeq(id, ".") {
+		if segment == "" || segment[0] == '-' || segment[len(segment)-1] == '-' {
+			return false
+		}
+		for _, r := range segment {
+			switch {
+			case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '-':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkProductCodes returns a problem for each product code that is shared
+// by more than one application in dep.
+func checkProductCodes(dep lbdeploy.Deployment) []problem {
+	apps := make(map[string][]string)
+	for _, id := range slices.Sorted(maps.Keys(dep.Apps)) {
+		code := dep.Apps[id].ProductCode
+		if code == "" {
+			continue
+		}
+		key := strings.ToUpper(string(code))
+		apps[key] = append(apps[key], string(id))
+	}
+
+	var problems []problem
+	for code, ids := range apps {
+		if len(ids) < 2 {
+			continue
+		}
+		problems = append(problems, problem{
+			Subject: ids[0],
+			Message: fmt.Sprintf("the %s product code is shared by the %s apps", code, strings.Join(ids, ", ")),
+		})
+	}
+	return problems
+}
+
+// checkBroadDeletes returns a problem for each delete action in dep that
+// deletes a file located directly within a well-known directory.
+func checkBroadDeletes(dep lbdeploy.Deployment) []problem {
+	var problems []problem
+	var check func(flow lbdeploy.FlowID, actions []lbdeploy.Action)
+	check = func(flow lbdeploy.FlowID, actions []lbdeploy.Action) {
+		for _, action := range actions {
+			if action.Type == lbdeploy.ActionDeleteFile && action.DestinationFile != "" {
+				ref, err := dep.Resources.FileSystem.ResolveFile(action.DestinationFile)
+				if err == nil && ref.Network.IsZero() && len(ref.Lineage) == 0 {
+					problems = append(problems, problem{
+						Subject: string(flow),
+						Message: fmt.Sprintf("a delete action in the \"%s\" flow deletes the \"%s\" file directly within the \"%s\" directory", flow, action.DestinationFile, ref.Root.ID()),
+					})
+				}
+			}
+			check(flow, action.Actions)
+			check(flow, action.Rollback)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(dep.Flows)) {
+		flow := dep.Flows[id]
+		check(id, flow.Before)
+		check(id, flow.Actions)
+		check(id, flow.After)
+	}
+	return problems
+}
+
+// keys returns the keys of m as sorted strings.
+func keys[K ~string, V any, M ~map[K]V](m M) []string {
+	out := make([]string, 0, len(m))
+	for id := range m {
+		out = append(out, string(id))
+	}
+	slices.Sort(out)
+	return out
+}
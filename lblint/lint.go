@@ -0,0 +1,171 @@
+// Package lblint examines LeafBridge deployments for problems that are not
+// strictly invalid, but that are likely to be mistakes.
+package lblint
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// Rule identifies a lint rule.
+type Rule string
+
+// Lint rules.
+const (
+	// RuleIDConvention flags identifiers that are not made up of lowercase
+	// letters, digits and hyphens, optionally divided by periods.
+	RuleIDConvention Rule = "id-convention"
+
+	// RuleUnusedResource flags resources that nothing refers to.
+	RuleUnusedResource Rule = "unused-resource"
+
+	// RuleUnusedCommand flags commands that no action invokes.
+	RuleUnusedCommand Rule = "unused-command"
+
+	// RuleUnusedCondition flags conditions that nothing refers to.
+	RuleUnusedCondition Rule = "unused-condition"
+
+	// RuleDuplicateProductCode flags applications that share a product code.
+	RuleDuplicateProductCode Rule = "duplicate-product-code"
+
+	// RuleBroadDelete flags delete actions on files that are located
+	// directly within a well-known directory, such as the program files
+	// directory.
+	RuleBroadDelete Rule = "broad-delete"
+)
+
+// Rules returns all of the lint rules, in the order that they are applied.
+func Rules() []Rule {
+	return []Rule{
+		RuleIDConvention,
+		RuleUnusedResource,
+		RuleUnusedCommand,
+		RuleUnusedCondition,
+		RuleDuplicateProductCode,
+		RuleBroadDelete,
+	}
+}
+
+// Validate returns a non-nil error if the rule is not recognized.
+func (rule Rule) Validate() error {
+	if !slices.Contains(Rules(), rule) {
+		return fmt.Errorf("the lint rule \"%s\" is not recognized", rule)
+	}
+	return nil
+}
+
+// DefaultSeverity returns the severity of findings for the rule, when it
+// has not been configured.
+func (rule Rule) DefaultSeverity() Severity {
+	switch rule {
+	case RuleDuplicateProductCode:
+		return SeverityError
+	default:
+		return SeverityWarning
+	}
+}
+
+// Severity is the severity of a lint finding.
+type Severity string
+
+// Lint severities.
+const (
+	SeverityIgnore  Severity = "ignore"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Config determines how a deployment is linted.
+//
+// Severities overrides the default severity of each rule. Rules with a
+// severity of SeverityIgnore are not applied.
+type Config struct {
+	Severities map[Rule]Severity
+}
+
+// Severity returns the configured severity of rule.
+func (config Config) Severity(rule Rule) Severity {
+	if severity, ok := config.Severities[rule]; ok {
+		return severity
+	}
+	return rule.DefaultSeverity()
+}
+
+// Finding is a problem found by a lint rule.
+type Finding struct {
+	Rule     Rule
+	Severity Severity
+	Subject  string
+	Message  string
+}
+
+// String returns a string representation of the finding.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", f.Severity, f.Subject, f.Message, f.Rule)
+}
+
+// Findings is a list of lint findings.
+type Findings []Finding
+
+// Errors returns the number of findings with an error severity.
+func (findings Findings) Errors() int {
+	var n int
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Lint applies each of the lint rules to dep, and returns the resulting
+// findings, sorted by rule and subject. The deployment is expected to be
+// valid.
+func Lint(dep lbdeploy.Deployment, config Config) Findings {
+	var findings Findings
+	for _, rule := range Rules() {
+		severity := config.Severity(rule)
+		if severity == SeverityIgnore {
+			continue
+		}
+
+		var problems []problem
+		switch rule {
+		case RuleIDConvention:
+			problems = checkIDs(dep)
+		case RuleUnusedResource:
+			problems = checkUnusedResources(dep)
+		case RuleUnusedCommand:
+			problems = checkUnusedCommands(dep)
+		case RuleUnusedCondition:
+			problems = checkUnusedConditions(dep)
+		case RuleDuplicateProductCode:
+			problems = checkProductCodes(dep)
+		case RuleBroadDelete:
+			problems = checkBroadDeletes(dep)
+		}
+
+		slices.SortFunc(problems, func(a, b problem) int {
+			return cmp.Compare(a.Subject, b.Subject)
+		})
+
+		for _, p := range problems {
+			findings = append(findings, Finding{
+				Rule:     rule,
+				Severity: severity,
+				Subject:  p.Subject,
+				Message:  p.Message,
+			})
+		}
+	}
+	return findings
+}
+
+// problem is a problem found by a lint rule.
+type problem struct {
+	Subject string
+	Message string
+}
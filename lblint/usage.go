@@ -0,0 +1,262 @@
+package lblint
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/leafbridge/leafbridge-deploy/idset"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// packageCommand identifies a command within a package.
+type packageCommand struct {
+	Package lbdeploy.PackageID
+	Command lbdeploy.CommandID
+}
+
+// usage records the definitions in a deployment that are referred to.
+type usage struct {
+	conditions  idset.SetOf[lbdeploy.ConditionID]
+	commands    idset.SetOf[lbdeploy.CommandID]
+	pkgCommands map[packageCommand]bool
+	packages    idset.SetOf[lbdeploy.PackageID]
+	processes   idset.SetOf[lbdeploy.ProcessResourceID]
+	mutexes     idset.SetOf[lbdeploy.MutexID]
+	semaphores  idset.SetOf[lbdeploy.SemaphoreID]
+	locks       idset.SetOf[lbdeploy.LockID]
+	keys        idset.SetOf[lbdeploy.RegistryKeyResourceID]
+	values      idset.SetOf[lbdeploy.RegistryValueResourceID]
+	dirs        idset.SetOf[lbdeploy.DirectoryResourceID]
+	files       idset.SetOf[lbdeploy.FileResourceID]
+}
+
+// findUsage returns the definitions in dep that are referred to by other
+// definitions.
+func findUsage(dep lbdeploy.Deployment) usage {
+	u := usage{
+		conditions:  make(idset.SetOf[lbdeploy.ConditionID]),
+		commands:    make(idset.SetOf[lbdeploy.CommandID]),
+		pkgCommands: make(map[packageCommand]bool),
+		packages:    make(idset.SetOf[lbdeploy.PackageID]),
+		processes:   make(idset.SetOf[lbdeploy.ProcessResourceID]),
+		mutexes:     make(idset.SetOf[lbdeploy.MutexID]),
+		semaphores:  make(idset.SetOf[lbdeploy.SemaphoreID]),
+		locks:       make(idset.SetOf[lbdeploy.LockID]),
+		keys:        make(idset.SetOf[lbdeploy.RegistryKeyResourceID]),
+		values:      make(idset.SetOf[lbdeploy.RegistryValueResourceID]),
+		dirs:        make(idset.SetOf[lbdeploy.DirectoryResourceID]),
+		files:       make(idset.SetOf[lbdeploy.FileResourceID]),
+	}
+
+	for _, flow := range dep.Flows {
+		addAll(u.conditions, flow.Constraints...)
+		addAll(u.conditions, flow.Preconditions...)
+		addAll(u.locks, flow.Locks...)
+		u.addActions(flow.Before)
+		u.addActions(flow.Actions)
+		u.addActions(flow.After)
+	}
+
+	for _, app := range dep.Apps {
+		if app.Detection.Present != "" {
+			u.conditions.Add(app.Detection.Present)
+		}
+		if app.Detection.Version != "" {
+			u.values.Add(app.Detection.Version)
+		}
+		if app.Detection.File.Path != "" {
+			u.files.Add(app.Detection.File.Path)
+		}
+		addAll(u.processes, app.Processes...)
+	}
+
+	for _, condition := range dep.Conditions {
+		u.addCondition(condition)
+	}
+
+	for _, command := range dep.Commands {
+		u.addCommand(command, false)
+	}
+	for _, pkg := range dep.Resources.Packages {
+		for _, command := range pkg.Commands {
+			u.addCommand(command, true)
+		}
+	}
+
+	for _, lock := range dep.Resources.Locks {
+		if lock.Mutex != "" {
+			u.mutexes.Add(lock.Mutex)
+		}
+		if lock.Semaphore != "" {
+			u.semaphores.Add(lock.Semaphore)
+		}
+	}
+
+	for _, key := range dep.Resources.Registry.Keys {
+		if key.Location != "" {
+			u.keys.Add(key.Location)
+		}
+	}
+	for _, value := range dep.Resources.Registry.Values {
+		if value.Key != "" {
+			u.keys.Add(value.Key)
+		}
+	}
+
+	for _, dir := range dep.Resources.FileSystem.Directories {
+		if dir.Location != "" {
+			u.dirs.Add(dir.Location)
+		}
+	}
+	for _, file := range dep.Resources.FileSystem.Files {
+		if file.Location != "" {
+			u.dirs.Add(file.Location)
+		}
+	}
+
+	return u
+}
+
+// addActions records the references made by actions, including the
+// members of transactions and rollback actions.
+func (u *usage) addActions(actions []lbdeploy.Action) {
+	for _, action := range actions {
+		if action.Package != "" {
+			u.packages.Add(action.Package)
+		}
+		if action.Command != "" {
+			if action.Package != "" {
+				u.pkgCommands[packageCommand{Package: action.Package, Command: action.Command}] = true
+			} else {
+				u.commands.Add(action.Command)
+			}
+		}
+		for _, id := range []lbdeploy.FileResourceID{action.SourceFile, action.DestinationFile} {
+			if id != "" {
+				u.files.Add(id)
+			}
+		}
+		for _, id := range []lbdeploy.DirectoryResourceID{action.SourceDir, action.DestinationDir} {
+			if id != "" {
+				u.dirs.Add(id)
+			}
+		}
+		if action.RegistryValue != "" {
+			u.values.Add(action.RegistryValue)
+		}
+		addAll(u.processes, action.Processes...)
+		for _, file := range action.UserSettings.Files {
+			u.files.Add(file.Source)
+		}
+		u.addActions(action.Actions)
+		u.addActions(action.Rollback)
+	}
+}
+
+// addCondition records the references made by a condition and its
+// subconditions.
+func (u *usage) addCondition(c lbdeploy.Condition) {
+	switch c.Type {
+	case lbdeploy.ConditionTypeSubcondition:
+		u.conditions.Add(lbdeploy.ConditionID(c.Subject))
+	case lbdeploy.ConditionTypeProcessIsRunning:
+		u.processes.Add(lbdeploy.ProcessResourceID(c.Subject))
+	case lbdeploy.ConditionTypeMutexExists:
+		u.mutexes.Add(lbdeploy.MutexID(c.Subject))
+	case lbdeploy.ConditionTypeRegistryKeyExists:
+		u.keys.Add(lbdeploy.RegistryKeyResourceID(c.Subject))
+	case lbdeploy.ConditionTypeRegistryValueExists, lbdeploy.ConditionTypeRegistryValueComparison:
+		u.values.Add(lbdeploy.RegistryValueResourceID(c.Subject))
+	case lbdeploy.ConditionTypeDirectoryExists, lbdeploy.ConditionTypeDirectoryEmpty, lbdeploy.ConditionTypeDirectoryContains, lbdeploy.ConditionTypeDirectorySize:
+		u.dirs.Add(lbdeploy.DirectoryResourceID(c.Subject))
+	case lbdeploy.ConditionTypeFileExists:
+		u.files.Add(lbdeploy.FileResourceID(c.Subject))
+	}
+	for _, sub := range c.Any {
+		u.addCondition(sub)
+	}
+	for _, sub := range c.All {
+		u.addCondition(sub)
+	}
+}
+
+// addCommand records the references made by a command. The executable of
+// a package command refers to a package file, not a file resource.
+func (u *usage) addCommand(command lbdeploy.Command, inPackage bool) {
+	if command.HasResourceWorkingDirectory() {
+		u.dirs.Add(command.WorkingDirectory)
+	}
+	addAll(u.conditions, command.PostConditions...)
+	if command.Log.Upload != "" {
+		u.dirs.Add(command.Log.Upload)
+	}
+	if _, isProgram := command.Executable.Program(); !inPackage && !isProgram && command.Executable != "" {
+		u.files.Add(lbdeploy.FileResourceID(command.Executable))
+	}
+}
+
+// checkUnusedResources returns a problem for each resource in dep that
+// nothing refers to.
+func checkUnusedResources(dep lbdeploy.Deployment) []problem {
+	u := findUsage(dep)
+	r := dep.Resources
+
+	var problems []problem
+	problems = append(problems, unused("process", r.Processes, u.processes)...)
+	problems = append(problems, unused("mutex", r.Mutexes, u.mutexes)...)
+	problems = append(problems, unused("semaphore", r.Semaphores, u.semaphores)...)
+	problems = append(problems, unused("lock", r.Locks, u.locks)...)
+	problems = append(problems, unused("registry key", r.Registry.Keys, u.keys)...)
+	problems = append(problems, unused("registry value", r.Registry.Values, u.values)...)
+	problems = append(problems, unused("directory", r.FileSystem.Directories, u.dirs)...)
+	problems = append(problems, unused("file", r.FileSystem.Files, u.files)...)
+	problems = append(problems, unused("package", r.Packages, u.packages)...)
+	return problems
+}
+
+// checkUnusedCommands returns a problem for each command in dep that no
+// action invokes.
+func checkUnusedCommands(dep lbdeploy.Deployment) []problem {
+	u := findUsage(dep)
+
+	problems := unused("command", dep.Commands, u.commands)
+	for pkgID, pkg := range dep.Resources.Packages {
+		for cmdID := range pkg.Commands {
+			if !u.pkgCommands[packageCommand{Package: pkgID, Command: cmdID}] {
+				problems = append(problems, problem{
+					Subject: fmt.Sprintf("%s.%s", pkgID, cmdID),
+					Message: fmt.Sprintf("the \"%s\" command in the \"%s\" package is not invoked by any action", cmdID, pkgID),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// checkUnusedConditions returns a problem for each condition in dep that
+// nothing refers to.
+func checkUnusedConditions(dep lbdeploy.Deployment) []problem {
+	return unused("condition", dep.Conditions, findUsage(dep).conditions)
+}
+
+// unused returns a problem for each entry in defined that is not in used.
+func unused[K ~string, V any, M ~map[K]V](kind string, defined M, used idset.SetOf[K]) []problem {
+	var problems []problem
+	for _, id := range slices.Sorted(maps.Keys(defined)) {
+		if !used.Contains(id) {
+			problems = append(problems, problem{
+				Subject: string(id),
+				Message: fmt.Sprintf("the \"%s\" %s is not used", id, kind),
+			})
+		}
+	}
+	return problems
+}
+
+// addAll adds each of the given ids to set.
+func addAll[T comparable](set idset.SetOf[T], ids ...T) {
+	for _, id := range ids {
+		set.Add(id)
+	}
+}
@@ -0,0 +1,201 @@
+package lbpeer
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// DNS record types and classes used by the peer protocol.
+const (
+	dnsTypeTXT   = 16
+	dnsClassIN   = 1
+	dnsFlagReply = 0x8400 // QR and AA
+)
+
+// errMalformed is returned when a DNS message cannot be parsed.
+var errMalformed = errors.New("the DNS message is malformed")
+
+// dnsQuestion is a question within a DNS message.
+type dnsQuestion struct {
+	Name string
+	Type uint16
+}
+
+// dnsRecord is a resource record within a DNS message.
+type dnsRecord struct {
+	Name string
+	Type uint16
+	TTL  uint32
+	Data []byte
+}
+
+// dnsMessage is a DNS message. Only the sections used by the peer protocol
+// are kept. Authority and additional records are ignored.
+type dnsMessage struct {
+	ID        uint16
+	Response  bool
+	Questions []dnsQuestion
+	Answers   []dnsRecord
+}
+
+// Marshal returns the wire format of the message. Names are not
+// compressed.
+func (m dnsMessage) Marshal() []byte {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	if m.Response {
+		binary.BigEndian.PutUint16(b[2:], dnsFlagReply)
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+
+	for _, q := range m.Questions {
+		b = appendName(b, q.Name)
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, dnsClassIN)
+	}
+	for _, r := range m.Answers {
+		b = appendName(b, r.Name)
+		b = binary.BigEndian.AppendUint16(b, r.Type)
+		b = binary.BigEndian.AppendUint16(b, dnsClassIN)
+		b = binary.BigEndian.AppendUint32(b, r.TTL)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(r.Data)))
+		b = append(b, r.Data...)
+	}
+	return b
+}
+
+// appendName appends a domain name in wire format to b.
+func appendName(b []byte, name string) []byte {
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// parseMessage parses a DNS message in wire format.
+func parseMessage(b []byte) (dnsMessage, error) {
+	if len(b) < 12 {
+		return dnsMessage{}, errMalformed
+	}
+
+	m := dnsMessage{
+		ID:       binary.BigEndian.Uint16(b[0:]),
+		Response: binary.BigEndian.Uint16(b[2:])&0x8000 != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(b[4:]))
+	ancount := int(binary.BigEndian.Uint16(b[6:]))
+
+	offset := 12
+	for range qdcount {
+		name, next, err := readName(b, offset)
+		if err != nil {
+			return dnsMessage{}, err
+		}
+		if next+4 > len(b) {
+			return dnsMessage{}, errMalformed
+		}
+		m.Questions = append(m.Questions, dnsQuestion{
+			Name: name,
+			Type: binary.BigEndian.Uint16(b[next:]),
+		})
+		offset = next + 4
+	}
+
+	for range ancount {
+		name, next, err := readName(b, offset)
+		if err != nil {
+			return dnsMessage{}, err
+		}
+		if next+10 > len(b) {
+			return dnsMessage{}, errMalformed
+		}
+		length := int(binary.BigEndian.Uint16(b[next+8:]))
+		start := next + 10
+		if start+length > len(b) {
+			return dnsMessage{}, errMalformed
+		}
+		m.Answers = append(m.Answers, dnsRecord{
+			Name: name,
+			Type: binary.BigEndian.Uint16(b[next:]),
+			TTL:  binary.BigEndian.Uint32(b[next+4:]),
+			Data: b[start : start+length],
+		})
+		offset = start + length
+	}
+
+	return m, nil
+}
+
+// readName reads a possibly compressed domain name from b at offset. It
+// returns the name and the offset of the data that follows it.
+func readName(b []byte, offset int) (name string, next int, err error) {
+	var labels []string
+	next = -1
+	for jumps := 0; ; {
+		if offset >= len(b) {
+			return "", 0, errMalformed
+		}
+		length := int(b[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			// A pointer to a name elsewhere in the message.
+			if offset+1 >= len(b) {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			jumps++
+			if jumps > 16 {
+				return "", 0, errMalformed
+			}
+			offset = int(binary.BigEndian.Uint16(b[offset:]) & 0x3FFF)
+		case length&0xC0 != 0:
+			return "", 0, errMalformed
+		default:
+			if offset+1+length > len(b) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(b[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// txtData returns the data of a TXT record holding the given strings.
+func txtData(values ...string) []byte {
+	var b []byte
+	for _, value := range values {
+		if len(value) > 255 {
+			value = value[:255]
+		}
+		b = append(b, byte(len(value)))
+		b = append(b, value...)
+	}
+	return b
+}
+
+// parseTXT returns the strings held by the data of a TXT record.
+func parseTXT(data []byte) []string {
+	var values []string
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			break
+		}
+		values = append(values, string(data[1:1+length]))
+		data = data[1+length:]
+	}
+	return values
+}
@@ -0,0 +1,111 @@
+package lbpeer
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+)
+
+// Find asks the LeafBridge instances on the local network whether they
+// hold the package file with the given content hash. It waits up to
+// timeout for answers, and returns the URLs of the package file on each
+// peer that answered.
+//
+// It returns an empty list if no peers answered.
+func Find(ctx context.Context, hash filehash.Entry, timeout time.Duration) ([]string, error) {
+	if hash.Type == "" || len(hash.Value) == 0 {
+		return nil, errors.New("a content hash is required to find a package")
+	}
+
+	// Send the query from an ephemeral port, so that peers answer it
+	// directly instead of sending their answers to the multicast group.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	name := queryName(hash)
+	query := dnsMessage{
+		ID:        uint16(rand.N(1 << 16)),
+		Questions: []dnsQuestion{{Name: name, Type: dnsTypeTXT}},
+	}
+	if _, err := conn.WriteToUDP(query.Marshal(), mdnsAddr); err != nil {
+		return nil, err
+	}
+
+	// Stop waiting when the timeout elapses or ctx is cancelled.
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	// Collect answers until the deadline.
+	var urls []string
+	var buf [9000]byte
+	for {
+		n, from, err := conn.ReadFromUDP(buf[:])
+		if err != nil {
+			break
+		}
+		response, err := parseMessage(buf[:n])
+		if err != nil || !response.Response || response.ID != query.ID {
+			continue
+		}
+		for _, answer := range response.Answers {
+			if answer.Type != dnsTypeTXT || !strings.EqualFold(answer.Name, name) {
+				continue
+			}
+			if u, ok := answerURL(from.IP, parseTXT(answer.Data)); ok && !slices.Contains(urls, u) {
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// answerURL returns the URL of a package file described by the TXT values
+// of an answer from the peer at ip.
+func answerURL(ip net.IP, values []string) (string, bool) {
+	var (
+		port int
+		path string
+	)
+	for _, value := range values {
+		key, v, _ := strings.Cut(value, "=")
+		switch key {
+		case "port":
+			port, _ = strconv.Atoi(v)
+		case "path":
+			path = v
+		}
+	}
+	if port <= 0 || port > 65535 || !strings.HasPrefix(path, "/packages/") {
+		return "", false
+	}
+
+	u := url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(ip.String(), strconv.Itoa(port)),
+		Path:   path,
+	}
+	return u.String(), true
+}
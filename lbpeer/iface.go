@@ -0,0 +1,51 @@
+package lbpeer
+
+import (
+	"net"
+	"slices"
+)
+
+// peerInterface is a network interface that packages are shared on,
+// along with the IPv4 subnets that it is attached to.
+type peerInterface struct {
+	Interface net.Interface
+	Subnets   []*net.IPNet
+}
+
+// peerInterfaces returns the network interfaces that are up, support
+// multicast and have an IPv4 address. Loopback interfaces are excluded.
+func peerInterfaces() ([]peerInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []peerInterface
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		var subnets []*net.IPNet
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				subnets = append(subnets, ipnet)
+			}
+		}
+		if len(subnets) > 0 {
+			out = append(out, peerInterface{Interface: ifi, Subnets: subnets})
+		}
+	}
+
+	return out, nil
+}
+
+// onSubnet returns true if ip is within any of the given subnets.
+func onSubnet(subnets []*net.IPNet, ip net.IP) bool {
+	return slices.ContainsFunc(subnets, func(subnet *net.IPNet) bool {
+		return subnet.Contains(ip)
+	})
+}
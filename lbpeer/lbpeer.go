@@ -0,0 +1,69 @@
+// Package lbpeer shares verified package files between LeafBridge instances
+// on the same local network, so that a package only has to be downloaded
+// over the WAN once per site.
+//
+// Instances that share packages run a Server, which answers multicast DNS
+// queries for the packages it holds and serves them over HTTP. Instances
+// looking for a package call Find with the package's content hash, which
+// returns the URLs of peers that hold it. Packages are identified only by
+// their content hash, and a package obtained from a peer must be verified
+// against its expected attributes just like one from any other source.
+package lbpeer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+)
+
+// DefaultPort is the TCP port that package files are served on when a
+// server doesn't specify one.
+const DefaultPort = 47815
+
+// serviceName is the multicast DNS domain that package names are queried
+// within.
+const serviceName = "_leafbridge-pkg._tcp.local."
+
+// mdnsAddr is the IPv4 multicast DNS group address.
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// hashLabelLength is the number of hexadecimal digits of a content hash
+// that are included in a query name. DNS labels are limited to 63
+// characters, which is shorter than most hashes.
+const hashLabelLength = 32
+
+// queryName returns the multicast DNS name that is queried for the package
+// with the given content hash.
+func queryName(hash filehash.Entry) string {
+	value := hash.Value.String()
+	if len(value) > hashLabelLength {
+		value = value[:hashLabelLength]
+	}
+	return fmt.Sprintf("%s.%s.%s", value, hash.Type, serviceName)
+}
+
+// parseQueryName returns the hash type and the hash prefix of a query name.
+// It returns false if the name isn't a package query name, including when
+// its hash label is not exactly hashLabelLength hexadecimal digits.
+func parseQueryName(name string) (hashType filehash.Type, prefix string, ok bool) {
+	rest, ok := strings.CutSuffix(strings.ToLower(name), "."+serviceName)
+	if !ok {
+		return "", "", false
+	}
+	prefix, t, ok := strings.Cut(rest, ".")
+	if !ok || t == "" || strings.Contains(t, ".") {
+		return "", "", false
+	}
+	if len(prefix) != hashLabelLength || strings.Trim(prefix, "0123456789abcdef") != "" {
+		return "", "", false
+	}
+	return filehash.Type(t), prefix, true
+}
+
+// packagePath returns the HTTP path that the package with the given
+// content hash is served at.
+func packagePath(hash filehash.Entry) string {
+	return fmt.Sprintf("/packages/%s/%s", hash.Type, hash.Value)
+}
@@ -0,0 +1,249 @@
+package lbpeer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+)
+
+// Catalog looks up the package files that a server is able to share.
+type Catalog interface {
+	// Lookup returns the path of a verified package file whose content hash
+	// of the given type begins with prefix, which is a hexadecimal string.
+	// Prefixes shorter than a query name's hash label never match, so that
+	// peers can't enumerate the catalog.
+	Lookup(hashType filehash.Type, prefix string) (path string, hash filehash.Value, found bool)
+}
+
+// StagedCatalog is a catalog of the package files that have been verified
+// within the staging directories of all deployments on the local system.
+type StagedCatalog struct{}
+
+// Lookup returns the path of a verified package file whose content hash
+// of the given type begins with prefix.
+func (StagedCatalog) Lookup(hashType filehash.Type, prefix string) (path string, hash filehash.Value, found bool) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) < hashLabelLength {
+		return "", nil, false
+	}
+	packages, err := stagingfs.VerifiedPackages()
+	if err != nil {
+		return "", nil, false
+	}
+	for _, pkg := range packages {
+		value, ok := pkg.Attributes.Hashes[hashType]
+		if ok && strings.HasPrefix(value.String(), prefix) {
+			return pkg.Path, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// Server answers multicast DNS queries for the package files in its
+// catalog and serves them over HTTP.
+//
+// The server only listens on the IPv4 addresses of network interfaces
+// that support multicast, and it only answers queries and requests from
+// hosts on the subnets of those interfaces.
+type Server struct {
+	// Catalog holds the package files that are shared.
+	Catalog Catalog
+
+	// Port is the TCP port that package files are served on. If it is
+	// zero, DefaultPort is used.
+	Port int
+}
+
+// Serve runs the server until ctx is cancelled.
+func (s Server) Serve(ctx context.Context) error {
+	port := s.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	// Find the interfaces that packages are shared on.
+	ifaces, err := peerInterfaces()
+	if err != nil {
+		return fmt.Errorf("failed to determine the local network interfaces: %w", err)
+	}
+	if len(ifaces) == 0 {
+		return errors.New("no network interfaces are available for sharing packages")
+	}
+	var subnets []*net.IPNet
+	for _, iface := range ifaces {
+		subnets = append(subnets, iface.Subnets...)
+	}
+
+	// Listen for package requests and multicast DNS queries on each
+	// interface.
+	var (
+		listeners []net.Listener
+		conns     []*net.UDPConn
+	)
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	for _, iface := range ifaces {
+		for _, subnet := range iface.Subnets {
+			listener, err := net.Listen("tcp4", net.JoinHostPort(subnet.IP.String(), strconv.Itoa(port)))
+			if err != nil {
+				closeAll()
+				return fmt.Errorf("failed to listen for package requests on %s: %w", subnet.IP, err)
+			}
+			listeners = append(listeners, listener)
+		}
+
+		conn, err := net.ListenMulticastUDP("udp4", &iface.Interface, mdnsAddr)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to listen for multicast DNS queries on %s: %w", iface.Interface.Name, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /packages/{type}/{hash}", s.servePackage)
+	server := &http.Server{
+		Handler:           localOnly(subnets, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, len(listeners)+len(conns))
+	for _, listener := range listeners {
+		go func() {
+			errc <- server.Serve(listener)
+		}()
+	}
+	for i, conn := range conns {
+		go func() {
+			errc <- s.answer(conn, ifaces[i].Subnets, port)
+		}()
+	}
+
+	// Wait for cancellation or for any listener to fail.
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-errc:
+	}
+
+	server.Close()
+	closeAll()
+
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// localOnly returns a handler that refuses requests from hosts outside of
+// the given subnets before passing them on to next.
+func localOnly(subnets []*net.IPNet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		ip := net.ParseIP(host)
+		if err != nil || ip == nil || !onSubnet(subnets, ip) {
+			http.Error(w, "package requests are only accepted from the local network", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// servePackage serves a package file by its full content hash.
+func (s Server) servePackage(w http.ResponseWriter, r *http.Request) {
+	hashType := filehash.Type(r.PathValue("type"))
+	hash := strings.ToLower(r.PathValue("hash"))
+
+	path, value, found := s.Catalog.Lookup(hashType, hash)
+	if !found || value.String() != hash {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, "the package file could not be read", http.StatusInternalServerError)
+		return
+	}
+
+	// ServeContent supports range requests, so that interrupted downloads
+	// from a peer can be resumed.
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+}
+
+// answer responds to multicast DNS queries for the packages in the
+// catalog until conn is closed. Queries from hosts outside of the given
+// subnets are ignored.
+//
+// Responses are sent directly to the querier, which is the convention for
+// one-shot queries that are sent from a port other than 5353.
+func (s Server) answer(conn *net.UDPConn, subnets []*net.IPNet, port int) error {
+	var buf [9000]byte
+	for {
+		n, from, err := conn.ReadFromUDP(buf[:])
+		if err != nil {
+			return err
+		}
+
+		if !onSubnet(subnets, from.IP) {
+			continue
+		}
+
+		query, err := parseMessage(buf[:n])
+		if err != nil || query.Response {
+			continue
+		}
+
+		response := dnsMessage{
+			ID:       query.ID,
+			Response: true,
+		}
+		for _, q := range query.Questions {
+			if q.Type != dnsTypeTXT {
+				continue
+			}
+			hashType, prefix, ok := parseQueryName(q.Name)
+			if !ok {
+				continue
+			}
+			_, value, found := s.Catalog.Lookup(hashType, prefix)
+			if !found {
+				continue
+			}
+			response.Questions = append(response.Questions, q)
+			response.Answers = append(response.Answers, dnsRecord{
+				Name: q.Name,
+				Type: dnsTypeTXT,
+				TTL:  120,
+				Data: txtData("port="+strconv.Itoa(port), "path="+packagePath(filehash.Entry{Type: hashType, Value: value})),
+			})
+		}
+		if len(response.Answers) == 0 {
+			continue
+		}
+
+		conn.WriteToUDP(response.Marshal(), from)
+	}
+}
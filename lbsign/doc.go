@@ -0,0 +1,11 @@
+// Package lbsign signs and verifies LeafBridge deployment files.
+//
+// Signatures are JSON Web Signatures (RFC 7515) using the EdDSA algorithm
+// with Ed25519 keys (RFC 8037). Two forms are supported:
+//
+//   - A detached signature is stored in a separate file next to the
+//     deployment file. It uses the compact serialization with a detached
+//     payload, as described in RFC 7515 Appendix F.
+//   - An embedded signature replaces the deployment file with a flattened
+//     JWS JSON serialization that carries the deployment as its payload.
+package lbsign
@@ -0,0 +1,168 @@
+package lbsign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Algorithm is the JWS algorithm used for LeafBridge signatures.
+const Algorithm = "EdDSA"
+
+// DetachedExtension is the file extension appended to the path of a
+// deployment file to form the path of its detached signature.
+const DetachedExtension = ".sig"
+
+// ErrUntrustedKey is returned when a signature was produced by a key that is
+// not trusted.
+var ErrUntrustedKey = errors.New("the signature was not produced by a trusted key")
+
+// ErrInvalidSignature is returned when a signature does not match its
+// payload.
+var ErrInvalidSignature = errors.New("the signature is not valid")
+
+// header is a JWS protected header.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// embedded is a flattened JWS JSON serialization.
+type embedded struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Signature string `json:"signature"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// sign returns the encoded protected header and signature for payload.
+func sign(payload []byte, key ed25519.PrivateKey) (protected, signature string, err error) {
+	h, err := json.Marshal(header{
+		Algorithm: Algorithm,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	protected = encoding.EncodeToString(h)
+	input := protected + "." + encoding.EncodeToString(payload)
+	signature = encoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+	return protected, signature, nil
+}
+
+// verify verifies the encoded protected header and signature against
+// payload using the trusted keys. It returns the ID of the key that produced
+// the signature.
+func verify(payload []byte, protected, signature string, keys KeySet) (keyID string, err error) {
+	data, err := encoding.DecodeString(protected)
+	if err != nil {
+		return "", fmt.Errorf("the signature header is not valid: %w", err)
+	}
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return "", fmt.Errorf("the signature header is not valid: %w", err)
+	}
+	if h.Algorithm != Algorithm {
+		return "", fmt.Errorf("the \"%s\" signature algorithm is not supported", h.Algorithm)
+	}
+
+	key, ok := keys.Find(h.KeyID)
+	if !ok {
+		return h.KeyID, ErrUntrustedKey
+	}
+
+	sig, err := encoding.DecodeString(signature)
+	if err != nil {
+		return h.KeyID, fmt.Errorf("the signature is not valid: %w", err)
+	}
+
+	input := protected + "." + encoding.EncodeToString(payload)
+	if !ed25519.Verify(key, []byte(input), sig) {
+		return h.KeyID, ErrInvalidSignature
+	}
+
+	return h.KeyID, nil
+}
+
+// SignDetached returns a detached signature for payload.
+func SignDetached(payload []byte, key ed25519.PrivateKey) ([]byte, error) {
+	protected, signature, err := sign(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(protected + ".." + signature + "\n"), nil
+}
+
+// VerifyDetached verifies a detached signature for payload using the trusted
+// keys. It returns the ID of the key that produced the signature.
+func VerifyDetached(payload, sig []byte, keys KeySet) (keyID string, err error) {
+	parts := bytes.Split(bytes.TrimSpace(sig), []byte("."))
+	if len(parts) != 3 || len(parts[1]) != 0 {
+		return "", errors.New("the detached signature is not a JWS compact serialization with a detached payload")
+	}
+	return verify(payload, string(parts[0]), string(parts[2]), keys)
+}
+
+// SignEmbedded returns a signed document that embeds payload.
+func SignEmbedded(payload []byte, key ed25519.PrivateKey) ([]byte, error) {
+	protected, signature, err := sign(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(embedded{
+		Payload:   encoding.EncodeToString(payload),
+		Protected: protected,
+		Signature: signature,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// IsEmbedded returns true if data is a signed document with an embedded
+// payload.
+func IsEmbedded(data []byte) bool {
+	var doc embedded
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.Payload != "" && doc.Protected != "" && doc.Signature != ""
+}
+
+// OpenEmbedded verifies a signed document using the trusted keys and returns
+// its payload. It also returns the ID of the key that produced the
+// signature.
+func OpenEmbedded(data []byte, keys KeySet) (payload []byte, keyID string, err error) {
+	payload, doc, err := decodeEmbedded(data)
+	if err != nil {
+		return nil, "", err
+	}
+	keyID, err = verify(payload, doc.Protected, doc.Signature, keys)
+	if err != nil {
+		return nil, keyID, err
+	}
+	return payload, keyID, nil
+}
+
+// EmbeddedPayload returns the payload of a signed document without verifying
+// its signature.
+func EmbeddedPayload(data []byte) ([]byte, error) {
+	payload, _, err := decodeEmbedded(data)
+	return payload, err
+}
+
+func decodeEmbedded(data []byte) (payload []byte, doc embedded, err error) {
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, doc, fmt.Errorf("the signed document is not valid: %w", err)
+	}
+	payload, err = encoding.DecodeString(doc.Payload)
+	if err != nil {
+		return nil, doc, fmt.Errorf("the signed document payload is not valid: %w", err)
+	}
+	return payload, doc, nil
+}
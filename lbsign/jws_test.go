@@ -0,0 +1,69 @@
+package lbsign_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/leafbridge/leafbridge-deploy/lbsign"
+)
+
+const payload = `{"id":"example","name":"Example"}`
+
+func TestDetached(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := lbsign.SignDetached([]byte(payload), private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyID, err := lbsign.VerifyDetached([]byte(payload), sig, lbsign.KeySet{public})
+	if err != nil {
+		t.Fatalf("valid signature failed verification: %v", err)
+	}
+	if keyID != lbsign.KeyID(public) {
+		t.Fatalf("unexpected key ID: %s", keyID)
+	}
+
+	if _, err := lbsign.VerifyDetached([]byte(payload+" "), sig, lbsign.KeySet{public}); !errors.Is(err, lbsign.ErrInvalidSignature) {
+		t.Fatalf("modified payload: expected ErrInvalidSignature, got %v", err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lbsign.VerifyDetached([]byte(payload), sig, lbsign.KeySet{other}); !errors.Is(err, lbsign.ErrUntrustedKey) {
+		t.Fatalf("untrusted key: expected ErrUntrustedKey, got %v", err)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := lbsign.SignEmbedded([]byte(payload), private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lbsign.IsEmbedded(doc) {
+		t.Fatal("signed document was not recognized as embedded")
+	}
+	if lbsign.IsEmbedded([]byte(payload)) {
+		t.Fatal("unsigned document was recognized as embedded")
+	}
+
+	out, _, err := lbsign.OpenEmbedded(doc, lbsign.KeySet{public})
+	if err != nil {
+		t.Fatalf("valid signature failed verification: %v", err)
+	}
+	if string(out) != payload {
+		t.Fatalf("payload not equal after round trip: %s", out)
+	}
+}
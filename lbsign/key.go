@@ -0,0 +1,89 @@
+package lbsign
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeyID returns an identifier for the given public key. It is derived from
+// a hash of the key.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeySet is a set of trusted public keys.
+type KeySet []ed25519.PublicKey
+
+// Find returns the key with the given key ID. It returns false if the key
+// is not a member of the set.
+func (keys KeySet) Find(id string) (ed25519.PublicKey, bool) {
+	for _, key := range keys {
+		if KeyID(key) == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// LoadPublicKey reads a PEM-encoded Ed25519 public key from the file at
+// path. The key must be in PKIX form, as produced by
+// "openssl pkey -pubout".
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: the public key is not an Ed25519 key", path)
+	}
+	return public, nil
+}
+
+// LoadPrivateKey reads a PEM-encoded Ed25519 private key from the file at
+// path. The key must be in PKCS #8 form, as produced by
+// "openssl genpkey -algorithm ed25519".
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: the private key is not an Ed25519 key", path)
+	}
+	return private, nil
+}
+
+// readPEM reads the first PEM block of the given type from the file at path.
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: %w", path, errors.New("no \""+blockType+"\" PEM block was found"))
+		}
+		if block.Type == blockType {
+			return block, nil
+		}
+	}
+}
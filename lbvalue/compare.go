@@ -1,6 +1,7 @@
 package lbvalue
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/leafbridge/leafbridge-deploy/datatype"
@@ -11,7 +12,16 @@ import (
 //
 // If the values cannot be compared, it returns an error.
 func TryCompare(a, b Value) (int, error) {
-	n := Compare(a, b)
+	return TryCompareWithMode(a, b, "")
+}
+
+// TryCompareWithMode returns an integer comparing values a and b, using
+// the given mode to compare versions. The result will be 0 if a == b, -1 if
+// a < b, and +1 if a > b.
+//
+// If the values cannot be compared, it returns an error.
+func TryCompareWithMode(a, b Value, mode datatype.VersionMode) (int, error) {
+	n := CompareWithMode(a, b, mode)
 	if n < -1 {
 		return n, ComparisonError{A: a.Kind(), B: b.Kind()}
 	}
@@ -23,6 +33,15 @@ func TryCompare(a, b Value) (int, error) {
 //
 // If the values cannot be compared, it returns -2.
 func Compare(a, b Value) int {
+	return CompareWithMode(a, b, "")
+}
+
+// CompareWithMode returns an integer comparing values a and b, using the
+// given mode to compare versions. The result will be 0 if a == b, -1 if
+// a < b, and +1 if a > b.
+//
+// If the values cannot be compared, it returns -2.
+func CompareWithMode(a, b Value, mode datatype.VersionMode) int {
 	switch data1 := a.data.(type) {
 	case Kind:
 		switch data1 {
@@ -53,12 +72,20 @@ func Compare(a, b Value) int {
 		}
 	case datatype.Version:
 		if data2, ok := b.data.(datatype.Version); ok {
-			return datatype.CompareVersions(data1, data2)
+			return datatype.CompareVersionsWithMode(data1, data2, mode)
 		}
 	case string:
 		if data2, ok := b.data.(string); ok {
 			return strings.Compare(data1, data2)
 		}
+	case stringList:
+		if data2, ok := b.data.(stringList); ok {
+			return slices.Compare(data1, data2)
+		}
+	case byteString:
+		if data2, ok := b.data.(byteString); ok {
+			return strings.Compare(string(data1), string(data2))
+		}
 	}
 
 	return -2
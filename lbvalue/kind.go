@@ -14,6 +14,8 @@ const (
 	KindInt64
 	KindString
 	KindVersion
+	KindStrings
+	KindBytes
 
 	// TODO: Add types from the netip package to be used in network detection.
 	//KindNetAddr
@@ -26,6 +28,8 @@ var kindStrings = []string{
 	"Int64",
 	"String",
 	"Version",
+	"Strings",
+	"Bytes",
 }
 
 var kindStringsLower = []string{
@@ -34,6 +38,8 @@ var kindStringsLower = []string{
 	"int64",
 	"string",
 	"version",
+	"strings",
+	"bytes",
 }
 
 // String returns a string representation of k.
@@ -57,6 +63,10 @@ func (k *Kind) UnmarshalText(b []byte) error {
 		*k = KindString
 	case "version":
 		*k = KindVersion
+	case "strings":
+		*k = KindStrings
+	case "bytes":
+		*k = KindBytes
 	default:
 		return fmt.Errorf("unrecognized kind: %s", b)
 	}
@@ -1,9 +1,13 @@
 package lbvalue
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/leafbridge/leafbridge-deploy/datatype"
 )
@@ -40,6 +44,23 @@ func Version(v datatype.Version) Value {
 	return Value{data: v}
 }
 
+// Strings returns a [Value] representing the list of strings v.
+func Strings(v ...string) Value {
+	return Value{data: stringList(slices.Clone(v))}
+}
+
+// Bytes returns a [Value] representing the byte sequence v.
+func Bytes(v []byte) Value {
+	return Value{data: byteString(string(v))}
+}
+
+// stringList is the underlying data type of a list of strings.
+type stringList []string
+
+// byteString is the underlying data type of a byte sequence. It is held
+// as a string so that values remain immutable.
+type byteString string
+
 // Kind returns the kind of the value.
 func (v Value) Kind() Kind {
 	switch data := v.data.(type) {
@@ -49,6 +70,10 @@ func (v Value) Kind() Kind {
 		return KindString
 	case datatype.Version:
 		return KindVersion
+	case stringList:
+		return KindStrings
+	case byteString:
+		return KindBytes
 	default:
 		return KindUnknown
 	}
@@ -87,6 +112,10 @@ func (v Value) String() string {
 		return data
 	case datatype.Version:
 		return string(data)
+	case stringList:
+		return strings.Join(data, ", ")
+	case byteString:
+		return hex.EncodeToString([]byte(data))
 	}
 	return ""
 }
@@ -99,6 +128,22 @@ func (v Value) Version() datatype.Version {
 	return ""
 }
 
+// Strings returns the value as a list of strings.
+func (v Value) Strings() []string {
+	if value, ok := v.data.(stringList); ok {
+		return slices.Clone(value)
+	}
+	return nil
+}
+
+// Bytes returns the value as a byte sequence.
+func (v Value) Bytes() []byte {
+	if value, ok := v.data.(byteString); ok {
+		return []byte(value)
+	}
+	return nil
+}
+
 // UnmarshalJSON attempts to unmarshal the given JSON data into v.
 func (v *Value) UnmarshalJSON(b []byte) error {
 	if len(b) == 0 {
@@ -112,6 +157,12 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 			return err
 		}
 		*v = String(aux)
+	case symbol == '[':
+		var aux []string
+		if err := json.Unmarshal(b, &aux); err != nil {
+			return err
+		}
+		*v = Strings(aux...)
 	case symbol == '-', '0' <= symbol && symbol <= '9':
 		var aux int64
 		if err := json.Unmarshal(b, &aux); err != nil {
@@ -130,6 +181,16 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 				return err
 			}
 			*v = Version(aux.Version)
+		case keys.Contains("hex"):
+			var aux hexJSON
+			if err := json.Unmarshal(b, &aux); err != nil {
+				return err
+			}
+			data, err := hex.DecodeString(aux.Hex)
+			if err != nil {
+				return fmt.Errorf("the hex value is not valid: %w", err)
+			}
+			*v = Bytes(data)
 		default:
 			return errors.New("the value type could not be determined")
 		}
@@ -156,6 +217,10 @@ func (v Value) MarshalJSON() ([]byte, error) {
 		return json.Marshal(data)
 	case datatype.Version:
 		return json.Marshal(versionJSON{Version: data})
+	case stringList:
+		return json.Marshal([]string(data))
+	case byteString:
+		return json.Marshal(hexJSON{Hex: hex.EncodeToString([]byte(data))})
 	default:
 		return nil, errors.New("cannot marshal value of unknown kind")
 	}
@@ -171,3 +236,7 @@ func (set keySet) Contains(key string) bool {
 type versionJSON struct {
 	Version datatype.Version `json:"version"`
 }
+
+type hexJSON struct {
+	Hex string `json:"hex"`
+}
@@ -1,25 +1,404 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbsign"
 )
 
+// signaturePolicy determines how deployment file signatures are handled
+// when a deployment is loaded.
+type signaturePolicy struct {
+	// Required indicates that the deployment file must carry a valid
+	// signature from a trusted key.
+	Required bool
+
+	// TrustedKeys are the keys that are trusted to sign deployment files.
+	// If any keys are provided, any signature that is present must be valid.
+	TrustedKeys lbsign.KeySet
+}
+
 func loadDeployment(path string) (dep lbdeploy.Deployment, err error) {
+	return loadSignedDeployment(path, signaturePolicy{})
+}
+
+// loadSignedDeployment loads the deployment file at path and verifies its
+// signature according to policy. Any fragments and shared libraries
+// referenced by the deployment are loaded and merged into it, and are
+// subject to the same policy.
+//
+// Signed documents with embedded payloads are always unwrapped, but their
+// signatures are only verified when trusted keys are provided.
+func loadSignedDeployment(path string, policy signaturePolicy) (dep lbdeploy.Deployment, err error) {
+	data, err := readDeploymentFile(path)
+	if err != nil {
+		return dep, err
+	}
+
+	if policy.Required && len(policy.TrustedKeys) == 0 {
+		return dep, errors.New("a signature is required but no trusted keys were provided")
+	}
+
+	data, err = verifyDocument(path, data, policy)
+	if err != nil {
+		return dep, fmt.Errorf("%s: %w", path, err)
+	}
+
+	data, err = extendTemplate(data, path, policy, map[string]bool{path: true})
+	if err != nil {
+		return dep, err
+	}
+
+	if err := json.Unmarshal(data, &dep); err != nil {
+		return dep, err
+	}
+
+	if err := includeFragments(&dep, path, policy); err != nil {
+		return dep, err
+	}
+
+	if err := includeLibraries(&dep, path, policy); err != nil {
+		return dep, err
+	}
+
+	return dep, nil
+}
+
+// readDeploymentFile reads the raw content of the deployment file at path.
+func readDeploymentFile(path string) ([]byte, error) {
 	if path == "" {
-		return dep, errors.New("missing deployment configuraiton file path")
+		return nil, errors.New("missing deployment configuraiton file path")
 	}
 	if !strings.HasSuffix(path, "deploy.json") {
-		return dep, errors.New("the provided deployment file path must end in deploy.json")
+		return nil, errors.New("the provided deployment file path must end in deploy.json")
+	}
+	return os.ReadFile(path)
+}
+
+// verifyDocument verifies the signature of a document read from location,
+// which may be a local file path or a URL. It returns the document's
+// payload.
+func verifyDocument(location string, data []byte, policy signaturePolicy) ([]byte, error) {
+	if lbsign.IsEmbedded(data) {
+		if len(policy.TrustedKeys) == 0 {
+			return lbsign.EmbeddedPayload(data)
+		}
+		payload, _, err := lbsign.OpenEmbedded(data, policy.TrustedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("the signature could not be verified: %w", err)
+		}
+		return payload, nil
+	}
+
+	if len(policy.TrustedKeys) == 0 {
+		return data, nil
 	}
-	data, err := os.ReadFile(path)
+
+	sigLocation := location + lbsign.DetachedExtension
+	sig, err := readLocation(sigLocation)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if policy.Required {
+			return nil, fmt.Errorf("the file is not signed: neither an embedded signature nor a %s file was found", sigLocation)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if _, err := lbsign.VerifyDetached(data, sig, policy.TrustedKeys); err != nil {
+			return nil, fmt.Errorf("the signature could not be verified: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+// extendTemplate layers the deployment document in data over the base
+// template it extends, if any. Templates may themselves extend other
+// templates. The visited set holds the locations that have already been
+// loaded, so that cycles can be detected.
+//
+// Objects are merged recursively, with values in the deployment taking
+// precedence over values in the template. Other values, including arrays,
+// are replaced. The include and library lists are the exception: the
+// template's entries are kept, followed by the deployment's.
+func extendTemplate(data []byte, location string, policy signaturePolicy, visited map[string]bool) ([]byte, error) {
+	doc, err := decodeDocument(data)
 	if err != nil {
-		return dep, err
+		return nil, err
+	}
+
+	extends, _ := doc["extends"].(string)
+	if extends == "" {
+		return data, nil
+	}
+	delete(doc, "extends")
+
+	// Load and verify the template.
+	baseLocation, err := resolveLocation(location, extends)
+	if err != nil {
+		return nil, fmt.Errorf("extends \"%s\": %w", extends, err)
+	}
+	if visited[baseLocation] {
+		return nil, fmt.Errorf("extends \"%s\": the template extends itself", extends)
+	}
+	visited[baseLocation] = true
+
+	baseData, err := readLocation(baseLocation)
+	if err != nil {
+		return nil, fmt.Errorf("extends \"%s\": %w", extends, err)
+	}
+	baseData, err = verifyDocument(baseLocation, baseData, policy)
+	if err != nil {
+		return nil, fmt.Errorf("extends \"%s\": %w", extends, err)
+	}
+	baseData, err = extendTemplate(baseData, baseLocation, policy, visited)
+	if err != nil {
+		return nil, fmt.Errorf("extends \"%s\": %w", extends, err)
+	}
+	base, err := decodeDocument(baseData)
+	if err != nil {
+		return nil, fmt.Errorf("extends \"%s\": %w", extends, err)
+	}
+
+	// Resolve the template's relative include and library locations now,
+	// while its location is known.
+	includes, _ := base["include"].([]any)
+	for i, include := range includes {
+		if include, ok := include.(string); ok {
+			if includes[i], err = resolveLocation(baseLocation, include); err != nil {
+				return nil, fmt.Errorf("extends \"%s\": include \"%s\": %w", extends, include, err)
+			}
+		}
+	}
+	libraries, _ := base["libraries"].([]any)
+	for _, library := range libraries {
+		if library, ok := library.(map[string]any); ok {
+			if loc, ok := library["location"].(string); ok {
+				if library["location"], err = resolveLocation(baseLocation, loc); err != nil {
+					return nil, fmt.Errorf("extends \"%s\": library \"%s\": %w", extends, loc, err)
+				}
+			}
+		}
+	}
+
+	// Layer the deployment over the template.
+	for _, key := range []string{"include", "libraries"} {
+		baseList, _ := base[key].([]any)
+		docList, _ := doc[key].([]any)
+		if len(baseList) > 0 {
+			doc[key] = append(baseList, docList...)
+		}
+	}
+
+	return json.Marshal(mergeDocuments(base, doc))
+}
+
+// decodeDocument decodes a JSON document into a map. Numbers are preserved
+// as they appear in the document.
+func decodeDocument(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// mergeDocuments merges the values in over into base, recursively merging
+// objects that appear in both. It returns base.
+func mergeDocuments(base, over map[string]any) map[string]any {
+	if base == nil {
+		base = make(map[string]any)
+	}
+	for key, value := range over {
+		baseObject, baseIsObject := base[key].(map[string]any)
+		overObject, overIsObject := value.(map[string]any)
+		if baseIsObject && overIsObject {
+			base[key] = mergeDocuments(baseObject, overObject)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
+
+// includeFragments loads the fragments included by dep and merges them into
+// it. Relative include locations are resolved against the location of the
+// file that includes them. Each fragment is included only once.
+func includeFragments(dep *lbdeploy.Deployment, path string, policy signaturePolicy) error {
+	type include struct {
+		base     string
+		location string
+	}
+
+	var (
+		pending []include
+		visited = make(map[string]bool)
+		merged  lbdeploy.MergedSet
+	)
+	for _, location := range dep.Include {
+		pending = append(pending, include{base: path, location: location})
+	}
+
+	for len(pending) > 0 {
+		next := pending[0]
+		pending = pending[1:]
+
+		location, err := resolveLocation(next.base, next.location)
+		if err != nil {
+			return fmt.Errorf("include \"%s\": %w", next.location, err)
+		}
+		if visited[location] {
+			continue
+		}
+		visited[location] = true
+
+		data, err := readLocation(location)
+		if err != nil {
+			return fmt.Errorf("include \"%s\": %w", next.location, err)
+		}
+		data, err = verifyDocument(location, data, policy)
+		if err != nil {
+			return fmt.Errorf("include \"%s\": %w", next.location, err)
+		}
+
+		var fragment lbdeploy.Fragment
+		if err := json.Unmarshal(data, &fragment); err != nil {
+			return fmt.Errorf("include \"%s\": %w", next.location, err)
+		}
+		if err := dep.MergeFragment(fragment, &merged); err != nil {
+			return fmt.Errorf("include \"%s\": %w", next.location, err)
+		}
+
+		for _, child := range fragment.Include {
+			pending = append(pending, include{base: location, location: child})
+		}
+	}
+
+	return nil
+}
+
+// includeLibraries loads the shared libraries referenced by dep and merges
+// their definitions into it under their namespaces. Relative library
+// locations are resolved against the location of the deployment file.
+func includeLibraries(dep *lbdeploy.Deployment, path string, policy signaturePolicy) error {
+	var (
+		namespaces = make(map[string]bool)
+		merged     lbdeploy.MergedSet
+	)
+	for _, ref := range dep.Libraries {
+		if err := ref.Validate(); err != nil {
+			return err
+		}
+		if namespaces[ref.Namespace] {
+			return fmt.Errorf("the \"%s\" library namespace is used more than once", ref.Namespace)
+		}
+		namespaces[ref.Namespace] = true
+
+		location, err := resolveLocation(path, ref.Location)
+		if err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+		data, err := readLocation(location)
+		if err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+		data, err = verifyDocument(location, data, policy)
+		if err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+
+		var lib lbdeploy.Library
+		if err := json.Unmarshal(data, &lib); err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+		if err := ref.CheckVersion(lib); err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+		if err := dep.MergeFragment(lib.Namespaced(ref.Namespace), &merged); err != nil {
+			return fmt.Errorf("library \"%s\": %w", ref.Namespace, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveLocation resolves an include location relative to the location of
+// the file that includes it.
+func resolveLocation(base, location string) (string, error) {
+	if isURL(location) {
+		return location, nil
+	}
+	if isURL(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(filepath.ToSlash(location))
+		if err != nil {
+			return "", err
+		}
+		return baseURL.ResolveReference(ref).String(), nil
+	}
+	if filepath.IsAbs(location) {
+		return filepath.Clean(location), nil
+	}
+	return filepath.Join(filepath.Dir(base), location), nil
+}
+
+// isURL returns true if location is an HTTP or HTTPS URL.
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+// readLocation reads the content at location, which may be a local file
+// path or a URL. If the content does not exist, it returns an error that
+// wraps os.ErrNotExist.
+func readLocation(location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", location, os.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("%s: unexpected HTTP status: %s", location, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+}
+
+// loadTrustedKeys loads the public keys at the given paths.
+func loadTrustedKeys(paths []string) (lbsign.KeySet, error) {
+	var keys lbsign.KeySet
+	for _, path := range paths {
+		key, err := lbsign.LoadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load trusted key: %w", err)
+		}
+		keys = append(keys, key)
 	}
-	err = json.Unmarshal(data, &dep)
-	return
+	return keys, nil
 }
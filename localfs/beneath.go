@@ -0,0 +1,152 @@
+package localfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ErrReparsePoint is returned when a path that must be opened directly
+// passes through a reparse point, such as a symbolic link or a junction.
+var ErrReparsePoint = errors.New("the path contains a reparse point")
+
+// ErrHardLinked is returned when a file that must be written directly has
+// more than one hard link.
+var ErrHardLinked = errors.New("the file has more than one hard link")
+
+// CreateFileBeneath creates or truncates the file at the relative path rel
+// within dir, creating any directories along the way.
+//
+// It is meant for writing to directories that are controlled by another,
+// less privileged user, such as a user's profile. Each component of the
+// path is opened relative to the handle of its parent without following
+// reparse points, and the write is refused if dir or any component of rel
+// is a reparse point, or if the file has other hard links. This prevents
+// the user from redirecting the write to another location on the system.
+func CreateFileBeneath(dir, rel string) (*os.File, error) {
+	if !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("the path \"%s\" is not a local relative path", rel)
+	}
+	names := strings.Split(filepath.Clean(rel), string(filepath.Separator))
+
+	// Open the directory itself without following any reparse point.
+	dirName, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := windows.CreateFile(dirName,
+		windows.FILE_LIST_DIRECTORY|windows.FILE_TRAVERSE|windows.FILE_READ_ATTRIBUTES|windows.SYNCHRONIZE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT,
+		0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	if err := checkDirectHandle(parent, false); err != nil {
+		windows.CloseHandle(parent)
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+
+	// Open or create each directory beneath it, relative to its parent.
+	path := dir
+	for _, name := range names[:len(names)-1] {
+		path = filepath.Join(path, name)
+		child, err := openBeneath(parent, name,
+			windows.FILE_LIST_DIRECTORY|windows.FILE_TRAVERSE|windows.FILE_READ_ATTRIBUTES,
+			windows.FILE_DIRECTORY_FILE)
+		windows.CloseHandle(parent)
+		if err != nil {
+			return nil, &os.PathError{Op: "mkdir", Path: path, Err: err}
+		}
+		if err := checkDirectHandle(child, false); err != nil {
+			windows.CloseHandle(child)
+			return nil, &os.PathError{Op: "mkdir", Path: path, Err: err}
+		}
+		parent = child
+	}
+
+	// Open or create the file, then discard its previous content.
+	path = filepath.Join(path, names[len(names)-1])
+	file, err := openBeneath(parent, names[len(names)-1],
+		windows.FILE_GENERIC_WRITE|windows.FILE_READ_ATTRIBUTES,
+		windows.FILE_NON_DIRECTORY_FILE)
+	windows.CloseHandle(parent)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	if err := checkDirectHandle(file, true); err != nil {
+		windows.CloseHandle(file)
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	f := os.NewFile(uintptr(file), path)
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// openBeneath opens or creates the file or directory with the given name
+// within the parent directory, without following a reparse point.
+func openBeneath(parent windows.Handle, name string, access, options uint32) (windows.Handle, error) {
+	objectName, err := windows.NewNTUnicodeString(name)
+	if err != nil {
+		return 0, err
+	}
+	attrs := windows.OBJECT_ATTRIBUTES{
+		RootDirectory: parent,
+		ObjectName:    objectName,
+		Attributes:    windows.OBJ_CASE_INSENSITIVE,
+	}
+	attrs.Length = uint32(unsafe.Sizeof(attrs))
+
+	var (
+		handle windows.Handle
+		status windows.IO_STATUS_BLOCK
+	)
+	err = windows.NtCreateFile(&handle,
+		access|windows.SYNCHRONIZE,
+		&attrs,
+		&status,
+		nil,
+		windows.FILE_ATTRIBUTE_NORMAL,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		windows.FILE_OPEN_IF,
+		options|windows.FILE_OPEN_REPARSE_POINT|windows.FILE_SYNCHRONOUS_IO_NONALERT,
+		0,
+		0)
+	if err != nil {
+		if status, ok := err.(windows.NTStatus); ok {
+			return 0, status.Errno()
+		}
+		return 0, err
+	}
+
+	return handle, nil
+}
+
+// checkDirectHandle returns an error if the file or directory opened by
+// handle is a reparse point, or if it is a file with more than one hard
+// link.
+func checkDirectHandle(handle windows.Handle, file bool) error {
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &info); err != nil {
+		return err
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		return ErrReparsePoint
+	}
+	if file && info.NumberOfLinks > 1 {
+		return ErrHardLinked
+	}
+	return nil
+}
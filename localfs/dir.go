@@ -1,31 +1,51 @@
 package localfs
 
 import (
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/netshare"
 )
 
 // Dir is an open directory on the local file system.
 type Dir struct {
 	root *os.Root
 	path string
+	conn netshare.Connection
 }
 
 // OpenDir attempts to open the directory identified by the given file reference.
-func OpenDir(ref lbdeploy.DirRef) (Dir, error) {
-	// Retrieve the known folder path, which is our starting point.
-	knownFolderPath, err := ref.Root.Path()
+//
+// If the directory is rooted at a network share with credentials, a
+// connection to the share is established and held until the directory is
+// closed.
+func OpenDir(ref lbdeploy.DirRef) (dir Dir, err error) {
+	// Retrieve the known folder or network share path, which is our
+	// starting point.
+	rootPath, err := ref.RootPath()
 	if err != nil {
 		return Dir{}, err
 	}
 
+	// Connect to the network share if it requires credentials.
+	conn, err := connect(ref.Network)
+	if err != nil {
+		return Dir{}, err
+	}
+	defer func() {
+		if err != nil {
+			conn.Close()
+		}
+	}()
+
 	// Start to build up the path of the directory.
-	path := knownFolderPath
+	path := rootPath
 
-	// Open the known folder as our first root directory.
-	root, err := os.OpenRoot(knownFolderPath)
+	// Open the root path as our first root directory.
+	root, err := os.OpenRoot(rootPath)
 	if err != nil {
 		return Dir{}, err
 	}
@@ -58,6 +78,7 @@ func OpenDir(ref lbdeploy.DirRef) (Dir, error) {
 	return Dir{
 		root: root,
 		path: path,
+		conn: conn,
 	}, nil
 }
 
@@ -73,5 +94,58 @@ func (d Dir) System() *os.Root {
 
 // Close releases any resources or system handles held by the directory.
 func (d Dir) Close() error {
-	return d.root.Close()
+	err := d.root.Close()
+	d.conn.Close()
+	return err
+}
+
+// IsEmpty returns true if the directory does not contain any files or
+// subdirectories.
+func (d Dir) IsEmpty() (bool, error) {
+	f, err := d.root.Open(".")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(names) == 0, nil
+}
+
+// Contains returns true if a file or subdirectory within the directory
+// matches the given pattern. The pattern uses the syntax of [path.Match],
+// and may include forward-slash separated subdirectories.
+func (d Dir) Contains(pattern string) (bool, error) {
+	matches, err := fs.Glob(d.root.FS(), pattern)
+	if err != nil {
+		return false, err
+	}
+	return len(matches) > 0, nil
+}
+
+// Size returns the total size in bytes of the regular files within the
+// directory and all of its subdirectories.
+func (d Dir) Size() (int64, error) {
+	var total int64
+	err := fs.WalkDir(d.root.FS(), ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += fi.Size()
+		return nil
+	})
+	return total, err
 }
@@ -5,27 +5,44 @@ import (
 	"path/filepath"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/netshare"
 )
 
 // File is an open file on the local file system.
 type File struct {
 	file *os.File
 	path string
+	conn netshare.Connection
 }
 
 // OpenFile attempts to open the file identified by the given file reference.
-func OpenFile(ref lbdeploy.FileRef) (File, error) {
-	// Retrieve the known folder path, which is our starting point.
-	knownFolderPath, err := ref.Root.Path()
+//
+// If the file is rooted at a network share with credentials, a connection
+// to the share is established and held until the file is closed.
+func OpenFile(ref lbdeploy.FileRef) (f File, err error) {
+	// Retrieve the known folder or network share path, which is our
+	// starting point.
+	rootPath, err := ref.Dir().RootPath()
 	if err != nil {
 		return File{}, err
 	}
 
+	// Connect to the network share if it requires credentials.
+	conn, err := connect(ref.Network)
+	if err != nil {
+		return File{}, err
+	}
+	defer func() {
+		if err != nil {
+			conn.Close()
+		}
+	}()
+
 	// Start to build up the path of the file.
-	path := knownFolderPath
+	path := rootPath
 
-	// Open the known folder as our root directory.
-	root, err := os.OpenRoot(knownFolderPath)
+	// Open the root path as our root directory.
+	root, err := os.OpenRoot(rootPath)
 	if err != nil {
 		return File{}, err
 	}
@@ -77,6 +94,7 @@ func OpenFile(ref lbdeploy.FileRef) (File, error) {
 	return File{
 		file: file,
 		path: path,
+		conn: conn,
 	}, nil
 }
 
@@ -92,5 +110,7 @@ func (f File) System() *os.File {
 
 // Close releases any resources or system handles held by the file.
 func (f File) Close() error {
-	return f.file.Close()
+	err := f.file.Close()
+	f.conn.Close()
+	return err
 }
@@ -0,0 +1,119 @@
+package localfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// CreateJunction creates a directory junction at link that points to the
+// target directory. Both paths must be absolute local paths, and the
+// parent of link must already exist.
+//
+// If the junction cannot be created, the directory created for it is
+// removed.
+func CreateJunction(link, target string) error {
+	if !filepath.IsAbs(link) || !filepath.IsAbs(target) {
+		return errors.New("junction paths must be absolute")
+	}
+
+	// Prepare the reparse data before making any changes.
+	data, err := mountPointReparseData(target)
+	if err != nil {
+		return err
+	}
+
+	// Junctions are empty directories with a mount point reparse point.
+	if err := os.Mkdir(link, 0755); err != nil {
+		return err
+	}
+
+	if err := setReparsePoint(link, data); err != nil {
+		os.Remove(link)
+		return err
+	}
+
+	return nil
+}
+
+// JunctionTarget returns the target of the directory junction at link.
+func JunctionTarget(link string) (string, error) {
+	fi, err := os.Lstat(link)
+	if err != nil {
+		return "", err
+	}
+	if fi.Mode().Type() != os.ModeIrregular && fi.Mode()&os.ModeSymlink == 0 {
+		return "", errors.New("the path is not a directory junction")
+	}
+	return os.Readlink(link)
+}
+
+// setReparsePoint applies the given reparse data to the directory at path.
+func setReparsePoint(path string, data []byte) error {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	handle, err := windows.CreateFile(name,
+		windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS,
+		0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+
+	var returned uint32
+	return windows.DeviceIoControl(handle, windows.FSCTL_SET_REPARSE_POINT, &data[0], uint32(len(data)), nil, 0, &returned, nil)
+}
+
+// mountPointReparseData returns a REPARSE_DATA_BUFFER for a mount point
+// that refers to target.
+func mountPointReparseData(target string) ([]byte, error) {
+	target = filepath.Clean(target)
+
+	substitute := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+
+	// The path buffer holds the substitute name and the print name, each
+	// followed by a null terminator.
+	substituteLen := len(substitute) * 2
+	printLen := len(printName) * 2
+	pathBufferLen := substituteLen + 2 + printLen + 2
+
+	// The reparse data consists of the reparse tag, data length, reserved
+	// field and the mount point header, followed by the path buffer.
+	const headerLen = 8
+	const mountPointHeaderLen = 8
+	reparseDataLen := mountPointHeaderLen + pathBufferLen
+	if headerLen+reparseDataLen > windows.MAXIMUM_REPARSE_DATA_BUFFER_SIZE {
+		return nil, errors.New("the junction target path is too long")
+	}
+
+	data := make([]byte, headerLen+reparseDataLen)
+	binary.LittleEndian.PutUint32(data[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(data[4:], uint16(reparseDataLen))
+	binary.LittleEndian.PutUint16(data[8:], 0)                        // SubstituteNameOffset
+	binary.LittleEndian.PutUint16(data[10:], uint16(substituteLen))   // SubstituteNameLength
+	binary.LittleEndian.PutUint16(data[12:], uint16(substituteLen+2)) // PrintNameOffset
+	binary.LittleEndian.PutUint16(data[14:], uint16(printLen))        // PrintNameLength
+
+	buf := data[headerLen+mountPointHeaderLen:]
+	for i, c := range substitute {
+		binary.LittleEndian.PutUint16(buf[i*2:], c)
+	}
+	buf = buf[substituteLen+2:]
+	for i, c := range printName {
+		binary.LittleEndian.PutUint16(buf[i*2:], c)
+	}
+
+	return data, nil
+}
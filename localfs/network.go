@@ -0,0 +1,20 @@
+package localfs
+
+import (
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/netshare"
+)
+
+// connect establishes a connection to the network share that the given
+// root refers to, if it names a credential. The user name and password are
+// read from the Windows Credential Manager.
+//
+// If the root does not name a credential, or if it is not a network root,
+// no connection is made and the share is accessed with the identity of the
+// running process.
+func connect(root lbdeploy.NetworkRoot) (netshare.Connection, error) {
+	if root.IsZero() {
+		return netshare.Connection{}, nil
+	}
+	return netshare.Connect(root.Share(), root.Credential())
+}
@@ -0,0 +1,61 @@
+package localregistry
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
+)
+
+// ExportKey exports the registry key identified by ref, including all of
+// its values and subkeys, to a .reg file at the given path. The file can be
+// imported with reg.exe or the Registry Editor to restore the key.
+//
+// If the key does not exist, an error satisfying os.IsNotExist is returned
+// and no file is written.
+func ExportKey(ref lbdeploy.RegistryKeyRef, path string) error {
+	// Make sure the key exists before exporting it, so that a missing key
+	// can be reported as such.
+	key, err := OpenKey(ref)
+	if err != nil {
+		return err
+	}
+	keyPath := key.Path()
+	key.Close()
+
+	// Export the key from the same view of the registry that it is read
+	// from.
+	view, err := ref.View()
+	if err != nil {
+		return err
+	}
+	args := []string{"export", keyPath, path, "/y"}
+	switch view {
+	case lbdeploy.RegistryView32:
+		args = append(args, "/reg:32")
+	case lbdeploy.RegistryView64:
+		args = append(args, "/reg:64")
+	}
+
+	// Run reg.exe from the system directory instead of searching PATH, so
+	// that an elevated process can't be tricked into running another
+	// program.
+	systemDir, err := windows.GetSystemDirectory()
+	if err != nil {
+		return fmt.Errorf("failed to locate the system directory: %w", err)
+	}
+	execPath := filepath.Join(systemDir, "reg.exe")
+
+	output, err := exec.Command(execPath, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("failed to export the \"%s\" registry key: %w: %s", keyPath, err, msg)
+		}
+		return fmt.Errorf("failed to export the \"%s\" registry key: %w", keyPath, err)
+	}
+
+	return nil
+}
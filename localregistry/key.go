@@ -23,6 +23,25 @@ type Key struct {
 // OpenKey attempts to open the regisry key identified by the given registry
 // key reference.
 func OpenKey(ref lbdeploy.RegistryKeyRef) (Key, error) {
+	return openKey(ref, registry.QUERY_VALUE, false)
+}
+
+// OpenKeyForWriting attempts to open the registry key identified by the
+// given registry key reference with permission to change its values.
+func OpenKeyForWriting(ref lbdeploy.RegistryKeyRef) (Key, error) {
+	return openKey(ref, registry.QUERY_VALUE|registry.SET_VALUE, false)
+}
+
+// CreateKey opens the registry key identified by the given registry key
+// reference with permission to change its values. The key and any missing
+// keys in its lineage are created if they do not already exist.
+func CreateKey(ref lbdeploy.RegistryKeyRef) (Key, error) {
+	return openKey(ref, registry.QUERY_VALUE|registry.SET_VALUE, true)
+}
+
+// openKey opens the registry key identified by ref with the requested
+// access. If create is true, keys in the lineage are created as needed.
+func openKey(ref lbdeploy.RegistryKeyRef, baseAccess uint32, create bool) (Key, error) {
 	// Make sure the root is valid.
 	if ref.Root.IsZero() {
 		return Key{}, errors.New("unable to open registry key: an empty root was provided in the key reference")
@@ -34,9 +53,29 @@ func OpenKey(ref lbdeploy.RegistryKeyRef) (Key, error) {
 		return Key{}, err
 	}
 
+	// Determine which view of the registry to use.
+	view, err := ref.View()
+	if err != nil {
+		return Key{}, err
+	}
+	viewAccess, err := view.Access()
+	if err != nil {
+		return Key{}, err
+	}
+	access := baseAccess | viewAccess
+
+	// Select the function used to traverse down to subkeys.
+	openSubKey := registry.OpenKey
+	if create {
+		openSubKey = func(k registry.Key, path string, access uint32) (registry.Key, error) {
+			key, _, err := registry.CreateKey(k, path, access)
+			return key, err
+		}
+	}
+
 	// Open the root's path relative to a predefined key. If the root does
 	// not specify a path, this will return the predefined key.
-	key, err := registry.OpenKey(ref.Root.Key(), ref.Root.Path(), registry.QUERY_VALUE)
+	key, err := registry.OpenKey(ref.Root.Key(), ref.Root.Path(), access)
 	if err != nil {
 		return Key{}, err
 	}
@@ -52,13 +91,13 @@ func OpenKey(ref lbdeploy.RegistryKeyRef) (Key, error) {
 		// Traverse down to the next descendent.
 		switch {
 		case next.Name != "":
-			key, err = registry.OpenKey(parent, next.Name, registry.QUERY_VALUE)
+			key, err = openSubKey(parent, next.Name, access)
 			path = path + `\` + next.Name // Permit forward slashes
 		case next.Path != "":
 			var localized string
 			localized, err = filepath.Localize(next.Path)
 			if err == nil {
-				key, err = registry.OpenKey(parent, localized, registry.QUERY_VALUE)
+				key, err = openSubKey(parent, localized, access)
 				path = filepath.Join(path, localized)
 			}
 		default:
@@ -121,9 +160,9 @@ func (key Key) HasValue(name string) (bool, error) {
 }
 
 // GetValue retrieves a value from the registry key with the requested type.
-func (key Key) GetValue(name string, kind lbvalue.Kind) (lbvalue.Value, error) {
-	switch kind {
-	case lbvalue.KindBool:
+func (key Key) GetValue(name string, t lbdeploy.RegistryValueType) (lbvalue.Value, error) {
+	switch t {
+	case lbdeploy.RegistryBool:
 		valueAsString, _, err := key.key.GetStringValue(name)
 		if err != nil {
 			return lbvalue.Value{}, err
@@ -133,25 +172,64 @@ func (key Key) GetValue(name string, kind lbvalue.Kind) (lbvalue.Value, error) {
 			return lbvalue.Value{}, err
 		}
 		return lbvalue.Bool(value), nil
-	case lbvalue.KindInt64:
+	case lbdeploy.RegistryInt64:
 		value, _, err := key.key.GetIntegerValue(name)
 		if err != nil {
 			return lbvalue.Value{}, err
 		}
 		return lbvalue.Int64(int64(value)), nil
-	case lbvalue.KindString:
+	case lbdeploy.RegistryDWord, lbdeploy.RegistryQWord:
+		value, valtype, err := key.key.GetIntegerValue(name)
+		if err != nil {
+			return lbvalue.Value{}, err
+		}
+		if expected := integerType(t); valtype != expected {
+			return lbvalue.Value{}, fmt.Errorf("unable to retrieve \"%s\" registry value: it is not a %s value", name, t)
+		}
+		return lbvalue.Int64(int64(value)), nil
+	case lbdeploy.RegistryString:
 		value, _, err := key.key.GetStringValue(name)
 		if err != nil {
 			return lbvalue.Value{}, err
 		}
 		return lbvalue.String(value), nil
-	case lbvalue.KindVersion:
+	case lbdeploy.RegistryExpandString:
+		value, _, err := key.key.GetStringValue(name)
+		if err != nil {
+			return lbvalue.Value{}, err
+		}
+		expanded, err := registry.ExpandString(value)
+		if err != nil {
+			return lbvalue.Value{}, fmt.Errorf("unable to expand \"%s\" registry value: %w", name, err)
+		}
+		return lbvalue.String(expanded), nil
+	case lbdeploy.RegistryMultiString:
+		value, _, err := key.key.GetStringsValue(name)
+		if err != nil {
+			return lbvalue.Value{}, err
+		}
+		return lbvalue.Strings(value...), nil
+	case lbdeploy.RegistryBinary:
+		value, _, err := key.key.GetBinaryValue(name)
+		if err != nil {
+			return lbvalue.Value{}, err
+		}
+		return lbvalue.Bytes(value), nil
+	case lbdeploy.RegistryVersion:
 		value, _, err := key.key.GetStringValue(name)
 		if err != nil {
 			return lbvalue.Value{}, err
 		}
 		return lbvalue.Version(datatype.Version(value)), nil
 	default:
-		return lbvalue.Value{}, fmt.Errorf("unable to retrieve \"%s\" registry value: \"%s\" is not a regognized variable type", name, kind)
+		return lbvalue.Value{}, fmt.Errorf("unable to retrieve \"%s\" registry value: \"%s\" is not a regognized registry value type", name, t)
+	}
+}
+
+// integerType returns the registry type code for an integer value type.
+func integerType(t lbdeploy.RegistryValueType) uint32 {
+	if t == lbdeploy.RegistryQWord {
+		return registry.QWORD
 	}
+	return registry.DWORD
 }
@@ -0,0 +1,59 @@
+package localregistry
+
+import (
+	"context"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// notifyPollInterval is the longest that WaitForChange blocks at a time
+// before checking whether its context has been cancelled.
+const notifyPollInterval = time.Second
+
+// OpenKeyForNotification attempts to open the registry key identified by
+// the given registry key reference with permission to wait for changes to
+// it.
+func OpenKeyForNotification(ref lbdeploy.RegistryKeyRef) (Key, error) {
+	return openKey(ref, registry.QUERY_VALUE|registry.NOTIFY, false)
+}
+
+// WaitForChange waits until a value of the key is added, changed or
+// removed, or until a subkey is added or removed. It returns false if
+// timeout elapses first. The key must have been opened for notification.
+//
+// If ctx is cancelled while waiting, it returns the context's error.
+func (key Key) WaitForChange(ctx context.Context, timeout time.Duration) (changed bool, err error) {
+	event, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseHandle(event)
+
+	const filter = windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET
+	if err := windows.RegNotifyChangeKeyValue(windows.Handle(key.key), false, filter, event, true); err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, nil
+		}
+
+		result, err := windows.WaitForSingleObject(event, uint32(min(remaining, notifyPollInterval).Milliseconds()))
+		if err != nil {
+			return false, err
+		}
+		if result == windows.WAIT_OBJECT_0 {
+			return true, nil
+		}
+	}
+}
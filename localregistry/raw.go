@@ -0,0 +1,77 @@
+package localregistry
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modadvapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procRegSetValueExW = modadvapi32.NewProc("RegSetValueExW")
+)
+
+// RawValue holds the unconverted data of a registry value along with its
+// registry type code. It is used to restore a value to its exact original
+// state.
+type RawValue struct {
+	Type uint32
+	Data []byte
+}
+
+// GetRawValue retrieves the unconverted data and type of the named value.
+// It returns false if the value does not exist.
+func (key Key) GetRawValue(name string) (value RawValue, exists bool, err error) {
+	size, _, err := key.key.GetValue(name, nil)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return RawValue{}, false, nil
+		}
+		return RawValue{}, false, err
+	}
+
+	// The value could grow between calls, so keep trying until the buffer
+	// is large enough.
+	for {
+		buf := make([]byte, size)
+		n, valtype, err := key.key.GetValue(name, buf)
+		switch {
+		case err == windows.ERROR_MORE_DATA:
+			size = n
+		case err != nil:
+			if os.IsNotExist(err) {
+				return RawValue{}, false, nil
+			}
+			return RawValue{}, false, err
+		default:
+			return RawValue{Type: valtype, Data: buf[:n]}, true, nil
+		}
+	}
+}
+
+// SetRawValue writes the unconverted data and type of a value to the named
+// value of the key. The key must have been opened for writing.
+func (key Key) SetRawValue(name string, value RawValue) error {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	var data *byte
+	if len(value.Data) > 0 {
+		data = &value.Data[0]
+	}
+
+	r0, _, _ := procRegSetValueExW.Call(
+		uintptr(key.key),
+		uintptr(unsafe.Pointer(p)),
+		0,
+		uintptr(value.Type),
+		uintptr(unsafe.Pointer(data)),
+		uintptr(len(value.Data)))
+	if r0 != 0 {
+		return windows.Errno(r0)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package localregistry
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"golang.org/x/sys/windows/registry"
+)
+
+// SetValue writes v to the named value of key as the given registry type.
+//
+// If t is empty, the type is inferred from the kind of v. Integers are
+// written as 32-bit integers when they fit, and as 64-bit integers
+// otherwise. Strings and versions are written as strings, lists of strings
+// as multi-strings, and byte sequences as binary values.
+func SetValue(key registry.Key, name string, t lbdeploy.RegistryValueType, v lbvalue.Value) error {
+	if t == "" {
+		t = inferValueType(v)
+	}
+
+	if expected := t.Kind(); expected != v.Kind() {
+		return fmt.Errorf("a %s value cannot be written as a %s registry value", v.Kind(), t)
+	}
+
+	switch t {
+	case lbdeploy.RegistryString:
+		return key.SetStringValue(name, v.String())
+	case lbdeploy.RegistryExpandString:
+		return key.SetExpandStringValue(name, v.String())
+	case lbdeploy.RegistryMultiString:
+		return key.SetStringsValue(name, v.Strings())
+	case lbdeploy.RegistryBinary:
+		return key.SetBinaryValue(name, v.Bytes())
+	case lbdeploy.RegistryDWord:
+		n := v.Int64()
+		if n < 0 || n > math.MaxUint32 {
+			return fmt.Errorf("the value %d does not fit within a dword registry value", n)
+		}
+		return key.SetDWordValue(name, uint32(n))
+	case lbdeploy.RegistryQWord:
+		return key.SetQWordValue(name, uint64(v.Int64()))
+	case lbdeploy.RegistryInt64:
+		if n := v.Int64(); n >= 0 && n <= math.MaxUint32 {
+			return key.SetDWordValue(name, uint32(n))
+		}
+		return key.SetQWordValue(name, uint64(v.Int64()))
+	case lbdeploy.RegistryBool:
+		return key.SetStringValue(name, strconv.FormatBool(v.Bool()))
+	case lbdeploy.RegistryVersion:
+		return key.SetStringValue(name, string(v.Version()))
+	default:
+		return fmt.Errorf("\"%s\" is not a recognized registry value type", t)
+	}
+}
+
+// inferValueType returns the registry value type used to write v when a
+// type has not been specified.
+func inferValueType(v lbvalue.Value) lbdeploy.RegistryValueType {
+	switch v.Kind() {
+	case lbvalue.KindBool:
+		return lbdeploy.RegistryBool
+	case lbvalue.KindInt64:
+		return lbdeploy.RegistryInt64
+	case lbvalue.KindString:
+		return lbdeploy.RegistryString
+	case lbvalue.KindVersion:
+		return lbdeploy.RegistryVersion
+	case lbvalue.KindStrings:
+		return lbdeploy.RegistryMultiString
+	case lbvalue.KindBytes:
+		return lbdeploy.RegistryBinary
+	default:
+		return ""
+	}
+}
+
+// SetValue writes v to the named value of the key as the given registry
+// type. The key must have been opened for writing.
+func (key Key) SetValue(name string, t lbdeploy.RegistryValueType, v lbvalue.Value) error {
+	return SetValue(key.key, name, t, v)
+}
+
+// DeleteValue removes the named value from the key. The key must have been
+// opened for writing.
+func (key Key) DeleteValue(name string) error {
+	return key.key.DeleteValue(name)
+}
@@ -14,14 +14,23 @@ func main() {
 	defer stop()
 
 	var cli struct {
-		Deploy  DeployCmd  `kong:"cmd,help='Deploys a particular software package.'"`
-		Show    ShowCmd    `kong:"cmd,help='Shows information about a deployment.'"`
-		Version VersionCmd `kong:"cmd,help='Display leafbridge-deploy version information.'"`
+		Deploy   DeployCmd   `kong:"cmd,help='Deploys a particular software package.'"`
+		Export   ExportCmd   `kong:"cmd,help='Exports deployment artifacts for other management systems.'"`
+		Import   ImportCmd   `kong:"cmd,help='Imports a deployment from another deployment tool.'"`
+		New      NewCmd      `kong:"cmd,help='Generates a starter deployment file for a common pattern.'"`
+		Run      RunCmd      `kong:"cmd,help='Runs the agent, which enforces a set of deployments.'"`
+		Service  ServiceCmd  `kong:"cmd,help='Manages the Windows service that runs the agent.'"`
+		Show     ShowCmd     `kong:"cmd,help='Shows information about a deployment.'"`
+		Sign     SignCmd     `kong:"cmd,help='Signs a deployment file.'"`
+		Validate ValidateCmd `kong:"cmd,help='Validates a deployment file and checks it for likely mistakes.'"`
+		Verify   VerifyCmd   `kong:"cmd,help='Verifies staged package files for a deployment.'"`
+		Version  VersionCmd  `kong:"cmd,help='Display leafbridge-deploy version information.'"`
 	}
 
 	parser := kong.Must(&cli,
 		kong.Description("Deploys software to computers."),
 		kong.BindTo(ctx, (*context.Context)(nil)),
+		kong.Vars{"default_service_name": defaultServiceName},
 		kong.UsageOnError())
 
 	app, parseErr := parser.Parse(os.Args[1:])
@@ -0,0 +1,50 @@
+// Package msdelta applies binary deltas produced by the Windows delta
+// compression API, which is provided by msdelta.dll on all supported
+// versions of Windows.
+//
+// https://learn.microsoft.com/en-us/previous-versions/bb417345(v=msdn.10)
+package msdelta
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modmsdelta      = windows.NewLazySystemDLL("msdelta.dll")
+	procApplyDeltaW = modmsdelta.NewProc("ApplyDeltaW")
+)
+
+// deltaApplyFlagAllowPA19 permits deltas in the legacy PA19 format, which
+// older delta tools still produce.
+const deltaApplyFlagAllowPA19 = 0x1 // DELTA_APPLY_FLAG_ALLOW_PA19
+
+// Apply applies the delta file at deltaPath to the source file at
+// sourcePath, and writes the result to a new file at targetPath. If a file
+// already exists at targetPath it is replaced.
+func Apply(sourcePath, deltaPath, targetPath string) error {
+	if err := procApplyDeltaW.Find(); err != nil {
+		return fmt.Errorf("the delta compression API is not available: %w", err)
+	}
+
+	source, err := windows.UTF16PtrFromString(sourcePath)
+	if err != nil {
+		return err
+	}
+	delta, err := windows.UTF16PtrFromString(deltaPath)
+	if err != nil {
+		return err
+	}
+	target, err := windows.UTF16PtrFromString(targetPath)
+	if err != nil {
+		return err
+	}
+
+	r, _, callErr := procApplyDeltaW.Call(deltaApplyFlagAllowPA19, uintptr(unsafe.Pointer(source)), uintptr(unsafe.Pointer(delta)), uintptr(unsafe.Pointer(target)))
+	if r == 0 {
+		return fmt.Errorf("failed to apply the delta: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Package msiapi provides access to product information held by the
+// Windows Installer.
+package msiapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modmsi = windows.NewLazySystemDLL("msi.dll")
+
+	procMsiQueryProductState = modmsi.NewProc("MsiQueryProductStateW")
+	procMsiGetProductInfoEx  = modmsi.NewProc("MsiGetProductInfoExW")
+)
+
+// Windows Installer error codes that are handled specially.
+const (
+	errorMoreData        = syscall.Errno(234)
+	errorUnknownProduct  = syscall.Errno(1605)
+	errorUnknownProperty = syscall.Errno(1608)
+)
+
+// InstallState is the installation state of a product.
+type InstallState int32
+
+// Installation states returned by the Windows Installer.
+const (
+	StateInvalidArg InstallState = -2 // An invalid parameter was passed.
+	StateUnknown    InstallState = -1 // The product is neither advertised nor installed.
+	StateAdvertised InstallState = 1  // The product is advertised but not installed.
+	StateAbsent     InstallState = 2  // The product is installed for a different user.
+	StateDefault    InstallState = 5  // The product is installed for the current user.
+)
+
+// Installed returns true if the state indicates that the product is
+// installed.
+func (state InstallState) Installed() bool {
+	return state == StateDefault
+}
+
+// String returns a string representation of the installation state.
+func (state InstallState) String() string {
+	switch state {
+	case StateInvalidArg:
+		return "invalid-argument"
+	case StateUnknown:
+		return "unknown"
+	case StateAdvertised:
+		return "advertised"
+	case StateAbsent:
+		return "absent"
+	case StateDefault:
+		return "installed"
+	default:
+		return fmt.Sprintf("state-%d", int32(state))
+	}
+}
+
+// Context identifies an installation context for a product.
+type Context uint32
+
+// Installation contexts.
+const (
+	ContextUserManaged   Context = 1
+	ContextUserUnmanaged Context = 2
+	ContextMachine       Context = 4
+)
+
+// Product properties that can be retrieved with GetProductInfo.
+const (
+	PropertyProductState  = "State"
+	PropertyVersionString = "VersionString"
+	PropertyProductName   = "ProductName"
+	PropertyInstallDate   = "InstallDate"
+)
+
+// QueryProductState returns the installation state of the product with the
+// given product code for the current user.
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/msi/nf-msi-msiqueryproductstatew
+func QueryProductState(productCode string) (InstallState, error) {
+	code, err := syscall.UTF16PtrFromString(productCode)
+	if err != nil {
+		return StateInvalidArg, err
+	}
+
+	r0, _, _ := syscall.SyscallN(procMsiQueryProductState.Addr(), uintptr(unsafe.Pointer(code)))
+	return InstallState(int32(r0)), nil
+}
+
+// GetProductInfo returns the value of a property for the product with the
+// given product code, as installed in the given context. User contexts
+// refer to the current user.
+//
+// If the product is not installed in the context, or the property is not
+// available, it returns an error that satisfies errors.Is(err,
+// os.ErrNotExist).
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/msi/nf-msi-msigetproductinfoexw
+func GetProductInfo(productCode string, context Context, property string) (string, error) {
+	code, err := syscall.UTF16PtrFromString(productCode)
+	if err != nil {
+		return "", err
+	}
+	prop, err := syscall.UTF16PtrFromString(property)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, 64)
+	for {
+		size := uint32(len(buf))
+		r0, _, _ := syscall.SyscallN(procMsiGetProductInfoEx.Addr(),
+			uintptr(unsafe.Pointer(code)),
+			0, // The current user, or no user for the machine context.
+			uintptr(context),
+			uintptr(unsafe.Pointer(prop)),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(unsafe.Pointer(&size)))
+
+		switch errno := syscall.Errno(r0); errno {
+		case 0:
+			return syscall.UTF16ToString(buf[:size]), nil
+		case errorMoreData:
+			// The size excludes the terminating null character.
+			buf = make([]uint16, size+1)
+		case errorUnknownProduct, errorUnknownProperty:
+			return "", fmt.Errorf("msi: %s %s: %w", productCode, property, errors.Join(os.ErrNotExist, errno))
+		default:
+			return "", fmt.Errorf("msi: %s %s: %w", productCode, property, errno)
+		}
+	}
+}
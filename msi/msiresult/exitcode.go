@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
 )
 
 // ExitCode is an exit code produced by msiexec.
@@ -39,3 +41,16 @@ func (code ExitCode) Error() string {
 
 	return out
 }
+
+// SystemMessage returns the message that Windows provides for the exit
+// code, which msiexec shares with Windows error codes. An empty string is
+// returned if Windows does not have a message for it.
+func (code ExitCode) SystemMessage() string {
+	var buf [512]uint16
+	flags := uint32(windows.FORMAT_MESSAGE_FROM_SYSTEM | windows.FORMAT_MESSAGE_IGNORE_INSERTS)
+	n, err := windows.FormatMessage(flags, 0, uint32(code), 0, buf[:], nil)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return strings.TrimSpace(windows.UTF16ToString(buf[:n]))
+}
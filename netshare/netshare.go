@@ -0,0 +1,119 @@
+// Package netshare connects to network file shares, optionally with
+// credentials that are stored in the Windows Credential Manager.
+package netshare
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/leafbridge/leafbridge-deploy/wincred"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modmpr = windows.NewLazySystemDLL("mpr.dll")
+
+	procWNetAddConnection2W    = modmpr.NewProc("WNetAddConnection2W")
+	procWNetCancelConnection2W = modmpr.NewProc("WNetCancelConnection2W")
+)
+
+// resourceTypeDisk is the RESOURCETYPE_DISK network resource type.
+const resourceTypeDisk = 1
+
+// errSessionCredentialConflict is returned by WNetAddConnection2 when the
+// share is already connected with different credentials.
+const errSessionCredentialConflict = windows.Errno(1219) // ERROR_SESSION_CREDENTIAL_CONFLICT
+
+// netResource is the NETRESOURCEW structure.
+type netResource struct {
+	Scope       uint32
+	Type        uint32
+	DisplayType uint32
+	Usage       uint32
+	LocalName   *uint16
+	RemoteName  *uint16
+	Comment     *uint16
+	Provider    *uint16
+}
+
+// Connection is a connection to a network share. It must be closed when
+// it is no longer needed.
+type Connection struct {
+	share string
+	added bool
+}
+
+// Connect connects to the network share at share, which takes the form
+// \\server\share.
+//
+// If credentialName is not empty, the user name and password of the named
+// credential in the Windows Credential Manager are used. Otherwise the
+// identity of the calling process is used, and no connection needs to be
+// made in advance.
+func Connect(share, credentialName string) (Connection, error) {
+	if credentialName == "" {
+		return Connection{share: share}, nil
+	}
+
+	cred, err := wincred.Read(credentialName)
+	if err != nil {
+		return Connection{}, fmt.Errorf("failed to read the \"%s\" credential: %w", credentialName, err)
+	}
+	if cred.UserName == "" {
+		return Connection{}, fmt.Errorf("the \"%s\" credential does not include a user name", credentialName)
+	}
+
+	if err := procWNetAddConnection2W.Find(); err != nil {
+		return Connection{}, fmt.Errorf("network connections are not available: %w", err)
+	}
+
+	remote, err := windows.UTF16PtrFromString(share)
+	if err != nil {
+		return Connection{}, err
+	}
+	userPtr, err := windows.UTF16PtrFromString(cred.UserName)
+	if err != nil {
+		return Connection{}, err
+	}
+	passwordPtr, err := windows.UTF16PtrFromString(cred.Secret)
+	if err != nil {
+		return Connection{}, err
+	}
+
+	resource := netResource{
+		Type:       resourceTypeDisk,
+		RemoteName: remote,
+	}
+	r0, _, _ := procWNetAddConnection2W.Call(
+		uintptr(unsafe.Pointer(&resource)),
+		uintptr(unsafe.Pointer(passwordPtr)),
+		uintptr(unsafe.Pointer(userPtr)),
+		0)
+	switch errno := windows.Errno(r0); errno {
+	case 0:
+		return Connection{share: share, added: true}, nil
+	case errSessionCredentialConflict:
+		// The share is already connected, which is good enough to reach
+		// the file if the existing connection has access to it.
+		return Connection{share: share}, nil
+	default:
+		return Connection{}, fmt.Errorf("failed to connect to \"%s\": %w", share, errno)
+	}
+}
+
+// Close removes the connection to the share, if one was made.
+func (c Connection) Close() error {
+	if !c.added {
+		return nil
+	}
+
+	remote, err := windows.UTF16PtrFromString(c.share)
+	if err != nil {
+		return err
+	}
+	r0, _, _ := procWNetCancelConnection2W.Call(uintptr(unsafe.Pointer(remote)), 0, 0)
+	if r0 != 0 {
+		return windows.Errno(r0)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gentlemanautomaton/winapp/appcode"
+	"github.com/gentlemanautomaton/winapp/unpackaged/appscope"
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// NewCmd generates a starter deployment file for a common deployment
+// pattern.
+type NewCmd struct {
+	Template     string                `kong:"required,name='template',enum='msi,archive,uninstall',help='The deployment pattern to generate (msi, archive, uninstall).'"`
+	ID           lbdeploy.DeploymentID `kong:"required,name='id',help='The identifier of the new deployment.'"`
+	Name         string                `kong:"optional,name='name',help='A human-readable name for the deployment.'"`
+	App          lbdeploy.AppID        `kong:"optional,name='app',default='app',help='The identifier of the application managed by the deployment.'"`
+	AppName      string                `kong:"optional,name='app-name',help='The name of the application managed by the deployment.'"`
+	ProductCode  lbdeploy.ProductCode  `kong:"optional,name='product-code',help='The product code of the application. It is required by the msi and uninstall templates.'"`
+	Architecture appcode.Architecture  `kong:"optional,name='architecture',default='x64',enum='x64,x86',help='The architecture of the application (x64, x86).'"`
+	URL          string                `kong:"optional,name='url',help='A URL, UNC path or local path from which the package can be obtained.'"`
+	Executable   string                `kong:"optional,name='executable',default='setup.exe',help='The path of the setup executable within an archive package.'"`
+	Output       string                `kong:"required,name='output',help='Path of the deployment file to write. It must end in deploy.json.'"`
+	Overwrite    bool                  `kong:"optional,name='overwrite',help='Overwrite the output file if it already exists.'"`
+}
+
+// Run executes the LeafBridge new command.
+func (cmd NewCmd) Run(ctx context.Context) error {
+	if !strings.HasSuffix(cmd.Output, "deploy.json") {
+		return errors.New("the provided deployment file path must end in deploy.json")
+	}
+
+	// The msi and uninstall templates identify the application by its
+	// product code, so one must be provided.
+	if cmd.ProductCode == "" && (cmd.Template == "msi" || cmd.Template == "uninstall") {
+		return fmt.Errorf("the \"%s\" template requires a product code to be provided with --product-code", cmd.Template)
+	}
+
+	// Build the deployment from the requested template.
+	var dep lbdeploy.Deployment
+	switch cmd.Template {
+	case "msi":
+		dep = cmd.msiDeployment()
+	case "archive":
+		dep = cmd.archiveDeployment()
+	case "uninstall":
+		dep = cmd.uninstallDeployment()
+	default:
+		return fmt.Errorf("the \"%s\" template is not recognized", cmd.Template)
+	}
+
+	// Write the deployment file.
+	if err := writeDeployment(cmd.Output, dep, cmd.Overwrite); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote a new \"%s\" deployment to %s.\n", cmd.Template, cmd.Output)
+	if len(dep.Resources.Packages) > 0 {
+		fmt.Printf("Fill in the package size, %s hash and sources before using it.\n", filehash.SHA3_256)
+	}
+
+	return nil
+}
+
+// writeDeployment encodes dep as JSON and writes it to the given path. It
+// refuses to replace an existing file unless overwrite is true.
+func writeDeployment(path string, dep lbdeploy.Deployment, overwrite bool) error {
+	// Encode the deployment as JSON.
+	out, err := json.MarshalIndent(dep, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	// Write the deployment file, refusing to replace an existing file
+	// unless asked to.
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(out); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// name returns the name of the deployment, falling back to its ID.
+func (cmd NewCmd) name() string {
+	if cmd.Name != "" {
+		return cmd.Name
+	}
+	return string(cmd.ID)
+}
+
+// app returns the application definition for the deployment.
+func (cmd NewCmd) app() lbdeploy.Application {
+	name := cmd.AppName
+	if name == "" {
+		name = cmd.name()
+	}
+	return lbdeploy.Application{
+		Name:         name,
+		Architecture: cmd.Architecture,
+		Scope:        appscope.Machine,
+		ProductCode:  cmd.ProductCode,
+	}
+}
+
+// sources returns the package sources for the deployment.
+func (cmd NewCmd) sources() []lbdeploy.PackageSource {
+	url := cmd.URL
+	if url == "" {
+		url = "https://example.com/path/to/package"
+	}
+	if strings.HasPrefix(url, `\\`) || strings.HasPrefix(url, "smb://") {
+		return []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceSMB, URL: url}}
+	}
+	if strings.HasPrefix(url, "file:") || filepath.IsAbs(url) {
+		return []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceFile, URL: url}}
+	}
+	return []lbdeploy.PackageSource{{Type: lbdeploy.PackageSourceHTTP, URL: url}}
+}
+
+// attributes returns placeholder file attributes for a package.
+func (cmd NewCmd) attributes() lbdeploy.FileAttributes {
+	return lbdeploy.FileAttributes{
+		Hashes: filehash.Map{filehash.SHA3_256: nil},
+	}
+}
+
+// msiDeployment returns a deployment that installs and uninstalls a single
+// MSI package.
+func (cmd NewCmd) msiDeployment() lbdeploy.Deployment {
+	return lbdeploy.Deployment{
+		ID:   cmd.ID,
+		Name: cmd.name(),
+		Apps: lbdeploy.AppMap{cmd.App: cmd.app()},
+		Resources: lbdeploy.Resources{
+			Packages: lbdeploy.PackageMap{
+				"installer": {
+					Name:       string(cmd.ID),
+					Type:       "msi",
+					Sources:    cmd.sources(),
+					Attributes: cmd.attributes(),
+					Commands: lbdeploy.CommandMap{
+						"install": {
+							Installs: lbdeploy.AppList{cmd.App},
+							Type:     lbdeploy.CommandTypeMSIInstall,
+						},
+						"uninstall": {
+							Uninstalls: lbdeploy.AppList{cmd.App},
+							Type:       lbdeploy.CommandTypeMSIUninstallProductCode,
+						},
+					},
+				},
+			},
+		},
+		Flows: lbdeploy.FlowMap{
+			"install": {
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionInvokeCommand, Package: "installer", Command: "install"},
+				},
+			},
+			"uninstall": {
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionInvokeCommand, Package: "installer", Command: "uninstall"},
+				},
+			},
+		},
+	}
+}
+
+// archiveDeployment returns a deployment that extracts a zip archive and
+// runs a setup executable contained within it.
+func (cmd NewCmd) archiveDeployment() lbdeploy.Deployment {
+	return lbdeploy.Deployment{
+		ID:   cmd.ID,
+		Name: cmd.name(),
+		Apps: lbdeploy.AppMap{cmd.App: cmd.app()},
+		Resources: lbdeploy.Resources{
+			Packages: lbdeploy.PackageMap{
+				"archive": {
+					Name:       string(cmd.ID),
+					Type:       "archive",
+					Format:     "zip",
+					Sources:    cmd.sources(),
+					Attributes: cmd.attributes(),
+					Files: lbdeploy.PackageFileMap{
+						"setup": {Path: cmd.Executable},
+					},
+					Commands: lbdeploy.CommandMap{
+						"install": {
+							Installs:   lbdeploy.AppList{cmd.App},
+							Type:       lbdeploy.CommandTypeExe,
+							Executable: "setup",
+						},
+					},
+				},
+			},
+		},
+		Flows: lbdeploy.FlowMap{
+			"install": {
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionInvokeCommand, Package: "archive", Command: "install"},
+				},
+			},
+		},
+	}
+}
+
+// uninstallDeployment returns a deployment that uninstalls an application
+// by its product code.
+func (cmd NewCmd) uninstallDeployment() lbdeploy.Deployment {
+	return lbdeploy.Deployment{
+		ID:   cmd.ID,
+		Name: cmd.name(),
+		Apps: lbdeploy.AppMap{cmd.App: cmd.app()},
+		Commands: lbdeploy.CommandMap{
+			"uninstall": {
+				Uninstalls: lbdeploy.AppList{cmd.App},
+				Type:       lbdeploy.CommandTypeMSIUninstallProductCode,
+			},
+		},
+		Flows: lbdeploy.FlowMap{
+			"uninstall": {
+				Actions: []lbdeploy.Action{
+					{Type: lbdeploy.ActionInvokeCommand, Command: "uninstall"},
+				},
+			},
+		},
+	}
+}
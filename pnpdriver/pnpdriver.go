@@ -0,0 +1,121 @@
+// Package pnpdriver installs Plug and Play driver packages with the Windows
+// setup API.
+package pnpdriver
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modnewdev   = windows.NewLazySystemDLL("newdev.dll")
+	modsetupapi = windows.NewLazySystemDLL("setupapi.dll")
+
+	procDiInstallDriverW                = modnewdev.NewProc("DiInstallDriverW")
+	procSetupGetInfDriverStoreLocationW = modsetupapi.NewProc("SetupGetInfDriverStoreLocationW")
+)
+
+const diirflagForceINF = 0x00000002 // DIIRFLAG_FORCE_INF
+
+// ErrNotStaged is returned when a driver package is not present in the
+// driver store.
+var ErrNotStaged = errors.New("the driver package is not present in the driver store")
+
+// Result describes the outcome of a driver package installation.
+//
+// Applied is true if the driver was installed on at least one device. It is
+// false if the driver package was added to the driver store without being
+// installed on any device, which happens when no devices are present that
+// it supports, or when the devices already have drivers that outrank it.
+//
+// RebootRequired is true if the system must be restarted before the driver
+// takes effect.
+//
+// DriverStorePath is the path of the INF file within the driver store.
+type Result struct {
+	Applied         bool
+	RebootRequired  bool
+	DriverStorePath string
+}
+
+// Install adds the driver package described by the INF file at infPath to
+// the driver store, then installs it on the devices present that it
+// supports.
+//
+// The driver is only installed on devices for which it outranks the
+// driver already installed, unless force is true. Once installed, the
+// presence of the driver package in the driver store is verified.
+func Install(infPath string, force bool) (Result, error) {
+	if err := procDiInstallDriverW.Find(); err != nil {
+		return Result{}, err
+	}
+
+	path, err := windows.UTF16PtrFromString(infPath)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var flags uint32
+	if force {
+		flags |= diirflagForceINF
+	}
+
+	var result Result
+	var needReboot int32
+	r, _, e := procDiInstallDriverW.Call(0, uintptr(unsafe.Pointer(path)), uintptr(flags), uintptr(unsafe.Pointer(&needReboot)))
+	switch {
+	case r != 0:
+		result.Applied = true
+	case e == windows.ERROR_NO_MORE_ITEMS:
+		// The driver package was staged, but not installed on any device.
+	default:
+		return Result{}, fmt.Errorf("the driver could not be installed: %w", e)
+	}
+	result.RebootRequired = needReboot != 0
+
+	result.DriverStorePath, err = DriverStoreLocation(infPath)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DriverStoreLocation returns the path within the driver store of the INF
+// file that matches the one at infPath. If the driver package has not been
+// added to the driver store, it returns ErrNotStaged.
+func DriverStoreLocation(infPath string) (string, error) {
+	if err := procSetupGetInfDriverStoreLocationW.Find(); err != nil {
+		return "", err
+	}
+
+	path, err := windows.UTF16PtrFromString(infPath)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		var required uint32
+		r, _, e := procSetupGetInfDriverStoreLocationW.Call(
+			uintptr(unsafe.Pointer(path)),
+			0,
+			0,
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+			uintptr(unsafe.Pointer(&required)))
+		switch {
+		case r != 0:
+			return windows.UTF16ToString(buf), nil
+		case e == windows.ERROR_INSUFFICIENT_BUFFER && int(required) > len(buf):
+			buf = make([]uint16, required)
+		case e == windows.ERROR_NOT_FOUND || e == windows.ERROR_FILE_NOT_FOUND:
+			return "", ErrNotStaged
+		default:
+			return "", fmt.Errorf("the driver store location could not be determined: %w", e)
+		}
+	}
+}
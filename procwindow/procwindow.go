@@ -0,0 +1,88 @@
+// Package procwindow finds the top-level windows that belong to processes
+// and changes how they are shown.
+package procwindow
+
+import (
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moduser32           = windows.NewLazySystemDLL("user32.dll")
+	procShowWindowAsync = moduser32.NewProc("ShowWindowAsync")
+)
+
+// enumCallback is shared by every call to List, because the number of
+// callbacks that can be created is limited. Calls to List are serialized by
+// enumMutex, which guards enumCurrent.
+var (
+	enumMutex    sync.Mutex
+	enumCurrent  *enumState
+	enumCallback = syscall.NewCallback(func(hwnd windows.HWND, param uintptr) uintptr {
+		return enumCurrent.add(hwnd)
+	})
+)
+
+// enumState collects the windows found by enumCallback.
+type enumState struct {
+	match   func(pid uint32) bool
+	windows []Window
+}
+
+// add records the window if it is visible and belongs to a process that
+// matches. It returns 1 so that the enumeration continues.
+func (state *enumState) add(hwnd windows.HWND) uintptr {
+	if !windows.IsWindowVisible(hwnd) {
+		return 1
+	}
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return 1
+	}
+	if state.match(pid) {
+		state.windows = append(state.windows, Window{Handle: hwnd, ProcessID: pid})
+	}
+	return 1
+}
+
+// Command determines how a window is shown.
+type Command int32
+
+// Show commands.
+const (
+	Hide     Command = windows.SW_HIDE
+	Minimize Command = windows.SW_SHOWMINNOACTIVE // Minimize without activating
+)
+
+// Window is a top-level window that belongs to a process.
+type Window struct {
+	Handle    windows.HWND
+	ProcessID uint32
+}
+
+// Show changes how the window is shown. It does not wait for the window to
+// respond, so a window that has stopped responding can't block the caller.
+func (w Window) Show(cmd Command) error {
+	if err := procShowWindowAsync.Find(); err != nil {
+		return err
+	}
+	procShowWindowAsync.Call(uintptr(w.Handle), uintptr(cmd))
+	return nil
+}
+
+// List returns the visible top-level windows in the current desktop that
+// belong to processes accepted by match.
+func List(match func(pid uint32) bool) ([]Window, error) {
+	enumMutex.Lock()
+	defer enumMutex.Unlock()
+
+	enumCurrent = &enumState{match: match}
+	defer func() { enumCurrent = nil }()
+
+	if err := windows.EnumWindows(enumCallback, nil); err != nil {
+		return nil, err
+	}
+	return enumCurrent.windows, nil
+}
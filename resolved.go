@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
+)
+
+// resolvedConfig is a deployment as the engine would see it on the local
+// system, along with the local paths of its resources.
+type resolvedConfig struct {
+	Environment lbdeploy.EnvironmentID `json:"environment,omitempty"`
+	Languages   []string               `json:"languages,omitempty"`
+	Deployment  lbdeploy.Deployment    `json:"deployment"`
+	Resources   resolvedResources      `json:"resolved-resources,omitzero"`
+	Errors      []string               `json:"errors,omitempty"`
+}
+
+// resolvedResources holds the local paths of the resources within a
+// deployment.
+type resolvedResources struct {
+	Directories    map[lbdeploy.DirectoryResourceID]resolvedPath     `json:"directories,omitempty"`
+	Files          map[lbdeploy.FileResourceID]resolvedPath          `json:"files,omitempty"`
+	RegistryKeys   map[lbdeploy.RegistryKeyResourceID]resolvedPath   `json:"registry-keys,omitempty"`
+	RegistryValues map[lbdeploy.RegistryValueResourceID]resolvedPath `json:"registry-values,omitempty"`
+}
+
+// resolvedPath is the local path of a resource, or the reason that it
+// could not be resolved.
+type resolvedPath struct {
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// newResolvedPath returns a resolved path for path and err.
+func newResolvedPath(path string, err error) resolvedPath {
+	if err != nil {
+		return resolvedPath{Error: err.Error()}
+	}
+	return resolvedPath{Path: path}
+}
+
+// resolveConfig applies the environment overlay and the language variants
+// for the local system to dep, expands the fact placeholders in its
+// commands and resolves the local paths of its resources.
+//
+// Problems that would stop the engine from acting on part of the
+// deployment are recorded in the result instead of being returned, so
+// that the rest of it can still be examined.
+func resolveConfig(dep lbdeploy.Deployment, env lbdeploy.EnvironmentID) (resolvedConfig, error) {
+	dep, err := dep.ForEnvironment(env)
+	if err != nil {
+		return resolvedConfig{}, err
+	}
+
+	languages := sysfacts.Languages()
+	dep = dep.ForLanguages(languages)
+
+	result := resolvedConfig{
+		Environment: env,
+		Languages:   languages,
+	}
+
+	// Expand the fact placeholders within commands.
+	facts, _ := sysfacts.Snapshot()
+	expand := func(desc string, commands lbdeploy.CommandMap) lbdeploy.CommandMap {
+		if len(commands) == 0 {
+			return commands
+		}
+		expanded := make(lbdeploy.CommandMap, len(commands))
+		for id, command := range commands {
+			command.Args = slices.Clone(command.Args)
+			for i, arg := range command.Args {
+				value, err := sysfacts.Expand(arg, facts)
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s command \"%s\": argument %d: %s", desc, id, i, err))
+					continue
+				}
+				command.Args[i] = value
+			}
+			if len(command.Properties) > 0 {
+				command.Properties = maps.Clone(command.Properties)
+				for name, prop := range command.Properties {
+					value, err := sysfacts.Expand(prop, facts)
+					if err != nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("%s command \"%s\": property %s: %s", desc, id, name, err))
+						continue
+					}
+					command.Properties[name] = value
+				}
+			}
+			expanded[id] = command
+		}
+		return expanded
+	}
+	dep.Commands = expand("deployment", dep.Commands)
+	if len(dep.Resources.Packages) > 0 {
+		packages := make(lbdeploy.PackageMap, len(dep.Resources.Packages))
+		for id, pkg := range dep.Resources.Packages {
+			pkg.Commands = expand(fmt.Sprintf("package \"%s\"", id), pkg.Commands)
+			packages[id] = pkg
+		}
+		dep.Resources.Packages = packages
+	}
+	result.Deployment = dep
+
+	// Resolve the local paths of file system and registry resources.
+	resources := &result.Resources
+	for id := range dep.Resources.FileSystem.Directories {
+		if resources.Directories == nil {
+			resources.Directories = make(map[lbdeploy.DirectoryResourceID]resolvedPath)
+		}
+		ref, err := dep.Resources.FileSystem.ResolveDirectory(id)
+		if err != nil {
+			resources.Directories[id] = newResolvedPath("", err)
+			continue
+		}
+		resources.Directories[id] = newResolvedPath(ref.Path())
+	}
+	for id := range dep.Resources.FileSystem.Files {
+		if resources.Files == nil {
+			resources.Files = make(map[lbdeploy.FileResourceID]resolvedPath)
+		}
+		ref, err := dep.Resources.FileSystem.ResolveFile(id)
+		if err != nil {
+			resources.Files[id] = newResolvedPath("", err)
+			continue
+		}
+		resources.Files[id] = newResolvedPath(ref.Path())
+	}
+	for id := range dep.Resources.Registry.Keys {
+		if resources.RegistryKeys == nil {
+			resources.RegistryKeys = make(map[lbdeploy.RegistryKeyResourceID]resolvedPath)
+		}
+		ref, err := dep.Resources.Registry.ResolveKey(id)
+		if err != nil {
+			resources.RegistryKeys[id] = newResolvedPath("", err)
+			continue
+		}
+		resources.RegistryKeys[id] = newResolvedPath(ref.Path())
+	}
+	for id := range dep.Resources.Registry.Values {
+		if resources.RegistryValues == nil {
+			resources.RegistryValues = make(map[lbdeploy.RegistryValueResourceID]resolvedPath)
+		}
+		ref, err := dep.Resources.Registry.ResolveValue(id)
+		if err != nil {
+			resources.RegistryValues[id] = newResolvedPath("", err)
+			continue
+		}
+		path, err := ref.Key().Path()
+		if err == nil {
+			name := ref.Name
+			if name == "" {
+				name = "(Default)"
+			}
+			path = path + `\` + name
+		}
+		resources.RegistryValues[id] = newResolvedPath(path, err)
+	}
+
+	return result, nil
+}
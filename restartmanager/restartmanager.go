@@ -0,0 +1,214 @@
+// Package restartmanager identifies the processes that hold files open on
+// the local system by way of the Windows Restart Manager, and can ask those
+// processes to shut down.
+//
+// https://learn.microsoft.com/en-us/windows/win32/rstmgr/restart-manager-portal
+package restartmanager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modrstrtmgr             = windows.NewLazySystemDLL("rstrtmgr.dll")
+	procRmStartSession      = modrstrtmgr.NewProc("RmStartSession")
+	procRmEndSession        = modrstrtmgr.NewProc("RmEndSession")
+	procRmRegisterResources = modrstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList           = modrstrtmgr.NewProc("RmGetList")
+	procRmShutdown          = modrstrtmgr.NewProc("RmShutdown")
+)
+
+const (
+	sessionKeyLength = 32 // CCH_RM_SESSION_KEY
+	maxAppName       = 255
+	maxServiceName   = 63
+	forceShutdown    = 0x1 // RmForceShutdown
+	maxListAttempts  = 3
+)
+
+// AppType identifies the type of application that holds a resource.
+type AppType uint32
+
+// Application types reported by the Restart Manager.
+const (
+	UnknownApp  AppType = 0
+	MainWindow  AppType = 1
+	OtherWindow AppType = 2
+	Service     AppType = 3
+	Explorer    AppType = 4
+	Console     AppType = 5
+	Critical    AppType = 1000
+)
+
+// String returns a string representation of the application type.
+func (t AppType) String() string {
+	switch t {
+	case MainWindow:
+		return "main-window"
+	case OtherWindow:
+		return "other-window"
+	case Service:
+		return "service"
+	case Explorer:
+		return "explorer"
+	case Console:
+		return "console"
+	case Critical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Process is a process that holds a resource registered with a Restart
+// Manager session.
+type Process struct {
+	ID          uint32
+	Started     time.Time
+	AppName     string
+	ServiceName string
+	Type        AppType
+	SessionID   uint32
+	Restartable bool
+}
+
+// rmProcessInfo mirrors the RM_PROCESS_INFO structure.
+type rmProcessInfo struct {
+	ProcessID        uint32
+	ProcessStartTime windows.Filetime
+	AppName          [maxAppName + 1]uint16
+	ServiceShortName [maxServiceName + 1]uint16
+	ApplicationType  uint32
+	AppStatus        uint32
+	TSSessionID      uint32
+	Restartable      int32
+}
+
+// Session is a Restart Manager session.
+type Session struct {
+	handle uint32
+}
+
+// Start starts a new Restart Manager session. It is the caller's
+// responsibility to close the session when finished with it.
+func Start() (*Session, error) {
+	var (
+		handle uint32
+		key    [sessionKeyLength + 1]uint16
+	)
+	if r, _, _ := procRmStartSession.Call(uintptr(unsafe.Pointer(&handle)), 0, uintptr(unsafe.Pointer(&key[0]))); r != 0 {
+		return nil, fmt.Errorf("failed to start a restart manager session: %w", windows.Errno(r))
+	}
+	return &Session{handle: handle}, nil
+}
+
+// RegisterFiles registers the files at the given paths with the session.
+func (s *Session) RegisterFiles(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	names := make([]*uint16, 0, len(paths))
+	for _, path := range paths {
+		name, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	if r, _, _ := procRmRegisterResources.Call(uintptr(s.handle), uintptr(len(names)), uintptr(unsafe.Pointer(&names[0])), 0, 0, 0, 0); r != 0 {
+		return fmt.Errorf("failed to register files with the restart manager: %w", windows.Errno(r))
+	}
+	return nil
+}
+
+// List returns the processes that hold any of the resources registered
+// with the session.
+func (s *Session) List() ([]Process, error) {
+	var infos []rmProcessInfo
+	for attempt := 0; ; attempt++ {
+		var (
+			needed  uint32
+			count   = uint32(len(infos))
+			reasons uint32
+			ptr     uintptr
+		)
+		if count > 0 {
+			ptr = uintptr(unsafe.Pointer(&infos[0]))
+		}
+		r, _, _ := procRmGetList.Call(uintptr(s.handle), uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)), ptr, uintptr(unsafe.Pointer(&reasons)))
+		switch windows.Errno(r) {
+		case 0:
+			return convertProcessInfo(infos[:count]), nil
+		case windows.ERROR_MORE_DATA:
+			// The list changed size between calls, so try again with a
+			// buffer of the new size.
+			if attempt >= maxListAttempts {
+				return nil, errors.New("the list of processes held by the restart manager kept changing")
+			}
+			infos = make([]rmProcessInfo, needed)
+		default:
+			return nil, fmt.Errorf("failed to retrieve the list of processes from the restart manager: %w", windows.Errno(r))
+		}
+	}
+}
+
+// Shutdown asks the processes that hold the registered resources to shut
+// down. If force is true, processes that do not respond are terminated.
+func (s *Session) Shutdown(force bool) error {
+	var flags uintptr
+	if force {
+		flags = forceShutdown
+	}
+	if r, _, _ := procRmShutdown.Call(uintptr(s.handle), flags, 0); r != 0 {
+		return fmt.Errorf("the restart manager failed to shut down the processes: %w", windows.Errno(r))
+	}
+	return nil
+}
+
+// Close ends the session.
+func (s *Session) Close() error {
+	if r, _, _ := procRmEndSession.Call(uintptr(s.handle)); r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// Lockers returns the processes that hold any of the files at the given
+// paths.
+func Lockers(paths ...string) ([]Process, error) {
+	session, err := Start()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	if err := session.RegisterFiles(paths...); err != nil {
+		return nil, err
+	}
+
+	return session.List()
+}
+
+func convertProcessInfo(infos []rmProcessInfo) []Process {
+	if len(infos) == 0 {
+		return nil
+	}
+	procs := make([]Process, len(infos))
+	for i, info := range infos {
+		procs[i] = Process{
+			ID:          info.ProcessID,
+			Started:     time.Unix(0, info.ProcessStartTime.Nanoseconds()),
+			AppName:     windows.UTF16ToString(info.AppName[:]),
+			ServiceName: windows.UTF16ToString(info.ServiceShortName[:]),
+			Type:        AppType(info.ApplicationType),
+			SessionID:   info.TSSessionID,
+			Restartable: info.Restartable != 0,
+		}
+	}
+	return procs
+}
@@ -0,0 +1,37 @@
+package restartmanager
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// User returns the account that the process is running as, in the form
+// DOMAIN\user.
+func (p Process) User() (string, error) {
+	proc, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, p.ID)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(proc)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(proc, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+
+	account, domain, _, err := user.User.Sid.LookupAccount("")
+	if err != nil {
+		return user.User.Sid.String(), nil
+	}
+	if domain == "" {
+		return account, nil
+	}
+	return fmt.Sprintf("%s\\%s", domain, account), nil
+}
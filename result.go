@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbdeployevent"
+	"github.com/leafbridge/leafbridge-deploy/lbengine"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+)
+
+// Deployment result statuses.
+const (
+	resultSucceeded    = "succeeded"
+	resultFailed       = "failed"
+	resultCancelled    = "cancelled"
+	resultDeferred     = "deferred"
+	resultNotCompliant = "not-compliant"
+)
+
+// deploymentResult is a machine-readable summary of a deploy command. It is
+// written to the result file when the command finishes, so that
+// orchestration wrappers don't have to infer outcomes from exit codes.
+type deploymentResult struct {
+	Deployment       lbdeploy.DeploymentID  `json:"deployment,omitempty"`
+	Flows            []lbdeploy.FlowID      `json:"flows"`
+	Environment      lbdeploy.EnvironmentID `json:"environment,omitempty"`
+	DetectOnly       bool                   `json:"detect-only,omitempty"`
+	Status           string                 `json:"status"`
+	Error            string                 `json:"error,omitempty"`
+	FailedAction     *failedActionResult    `json:"failed-action,omitempty"`
+	RebootRequired   bool                   `json:"reboot-required"`
+	RebootReasons    []string               `json:"reboot-reasons,omitzero"`
+	PendingRestart   []string               `json:"pending-restart,omitzero"`
+	RestartInitiated bool                   `json:"restart-initiated,omitempty"`
+	Commands         []commandResult        `json:"commands,omitzero"`
+	Apps             appResultSummary       `json:"apps"`
+	Started          time.Time              `json:"started"`
+	Stopped          time.Time              `json:"stopped"`
+}
+
+// failedActionResult identifies the first action that failed.
+//
+// Configuration is true when the action failed because it refers to a
+// resource that could not be resolved, which is a problem with the
+// deployment rather than the computer it ran on.
+type failedActionResult struct {
+	Flow          lbdeploy.FlowID     `json:"flow"`
+	Index         int                 `json:"index"`
+	ID            lbdeploy.ActionID   `json:"id,omitempty"`
+	Type          lbdeploy.ActionType `json:"type"`
+	Error         string              `json:"error,omitempty"`
+	Configuration bool                `json:"configuration-error,omitempty"`
+}
+
+// commandResult describes a command that was invoked.
+type commandResult struct {
+	Flow     lbdeploy.FlowID    `json:"flow"`
+	Package  lbdeploy.PackageID `json:"package,omitempty"`
+	Command  lbdeploy.CommandID `json:"command"`
+	ExitCode lbdeploy.ExitCode  `json:"exit-code"`
+	Error    string             `json:"error,omitempty"`
+	Reboot   []string           `json:"reboot-reasons,omitzero"`
+}
+
+// appResultSummary describes the changes made to applications and their
+// state when the command finished.
+type appResultSummary struct {
+	Installed           lbdeploy.AppList                 `json:"installed,omitzero"`
+	Uninstalled         lbdeploy.AppList                 `json:"uninstalled,omitzero"`
+	StillNotInstalled   lbdeploy.AppList                 `json:"still-not-installed,omitzero"`
+	StillNotUninstalled lbdeploy.AppList                 `json:"still-not-uninstalled,omitzero"`
+	State               map[lbdeploy.AppID]appStateEntry `json:"state,omitzero"`
+}
+
+// appStateEntry describes the state of an application.
+type appStateEntry struct {
+	Installed bool             `json:"installed"`
+	Version   datatype.Version `json:"version,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// resultHandler is an event handler that collects information about a
+// deployment for its result.
+type resultHandler struct {
+	result *deploymentResult
+}
+
+// Name returns a name for the handler.
+func (h resultHandler) Name() string {
+	return "result-handler"
+}
+
+// Handle processes the given event record.
+func (h resultHandler) Handle(r lbevent.Record) error {
+	record, ok := r.(lbevent.RecordOf[lbevent.Interface])
+	if !ok {
+		return nil
+	}
+
+	switch e := record.Event.(type) {
+	case lbdeployevent.ActionStopped:
+		if e.Err != nil && h.result.FailedAction == nil {
+			h.result.FailedAction = &failedActionResult{
+				Flow:          e.Flow,
+				Index:         e.ActionIndex,
+				ID:            e.ActionID,
+				Type:          e.ActionType,
+				Error:         e.Err.Error(),
+				Configuration: lbdeploy.IsResolutionError(e.Err),
+			}
+		}
+	case lbdeployevent.CommandStopped:
+		entry := commandResult{
+			Flow:     e.Flow,
+			Package:  e.Package,
+			Command:  e.Command,
+			ExitCode: e.Result.ExitCode,
+		}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+		h.result.Commands = append(h.result.Commands, entry)
+
+		if e.Result.Info.Reboot {
+			h.result.RebootRequired = true
+		}
+
+		apps := &h.result.Apps
+		apps.Installed = append(apps.Installed, e.AppsAfter.Installed...)
+		apps.Uninstalled = append(apps.Uninstalled, e.AppsAfter.Uninstalled...)
+		apps.StillNotInstalled = append(apps.StillNotInstalled, e.AppsAfter.StillNotInstalled...)
+		apps.StillNotUninstalled = append(apps.StillNotUninstalled, e.AppsAfter.StillNotUninstalled...)
+	case lbdeployevent.RebootRequired:
+		// The reboot event follows the stop event of the command it
+		// applies to.
+		for i := len(h.result.Commands) - 1; i >= 0; i-- {
+			entry := &h.result.Commands[i]
+			if entry.Flow == e.Flow && entry.Package == e.Package && entry.Command == e.Command {
+				entry.Reboot = e.Reasons
+				break
+			}
+		}
+		h.result.RebootRequired = true
+	case lbdeployevent.DeploymentResult:
+		h.result.RebootRequired = h.result.RebootRequired || e.RebootRequired()
+		h.result.RebootReasons = e.RebootReasons
+		h.result.PendingRestart = e.PendingRestart
+		h.result.RestartInitiated = e.RestartInitiated
+	}
+
+	return nil
+}
+
+// finish records the outcome of the command and the final state of the
+// deployment's applications.
+func (result *deploymentResult) finish(ctx context.Context, dep lbdeploy.Deployment, err error) {
+	result.Stopped = time.Now()
+
+	switch {
+	case err == nil:
+		result.Status = resultSucceeded
+	case errors.Is(err, errNotCompliant):
+		result.Status = resultNotCompliant
+		result.Error = err.Error()
+	case errors.Is(err, lbengine.ErrDeferred):
+		result.Status = resultDeferred
+		result.Error = err.Error()
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+		result.Status = resultCancelled
+		result.Error = err.Error()
+	default:
+		result.Status = resultFailed
+		result.Error = err.Error()
+	}
+
+	if len(dep.Apps) == 0 {
+		return
+	}
+
+	ae := lbengine.NewAppEngine(dep)
+	result.Apps.State = make(map[lbdeploy.AppID]appStateEntry, len(dep.Apps))
+	for id := range dep.Apps {
+		var entry appStateEntry
+		installed, err := ae.IsInstalled(id)
+		if err != nil {
+			entry.Error = err.Error()
+		} else if installed {
+			entry.Installed = true
+			if version, err := ae.Version(id); err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.Version = version
+			}
+		}
+		result.Apps.State[id] = entry
+	}
+}
+
+// write writes the result to a file at path. The file is written to a
+// temporary location first, so that wrappers never see a partial result.
+func (result *deploymentResult) write(path string) error {
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(out); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
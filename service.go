@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// defaultServiceName is the default name of the LeafBridge agent service.
+const defaultServiceName = "LeafBridgeDeploy"
+
+// ServiceCmd manages the Windows service that runs the LeafBridge agent.
+type ServiceCmd struct {
+	Install   ServiceInstallCmd   `kong:"cmd,help='Installs the LeafBridge agent as a Windows service.'"`
+	Uninstall ServiceUninstallCmd `kong:"cmd,help='Stops and removes the LeafBridge agent service.'"`
+}
+
+// ServiceInstallCmd installs the LeafBridge agent as a Windows service.
+type ServiceInstallCmd struct {
+	AgentConfig string `kong:"required,name='agent-config',type='existingfile',help='Path to an agent configuration file listing the deployments to enforce.'"`
+	Name        string `kong:"optional,name='name',default='${default_service_name}',help='The name of the service.'"`
+	Start       bool   `kong:"optional,name='start',help='Start the service after it is installed.'"`
+}
+
+// Run executes the LeafBridge service install command.
+func (cmd ServiceInstallCmd) Run(ctx context.Context) error {
+	// Make sure the agent configuration is valid before installing.
+	config, err := filepath.Abs(cmd.AgentConfig)
+	if err != nil {
+		return err
+	}
+	if _, err := loadAgentConfig(config); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(cmd.Name, exe, mgr.Config{
+		DisplayName: "LeafBridge Deploy",
+		Description: "Periodically re-evaluates and enforces LeafBridge deployments.",
+		StartType:   mgr.StartAutomatic,
+	}, "run", "--agent-config", config, "--schedule")
+	if err != nil {
+		return fmt.Errorf("failed to create the \"%s\" service: %w", cmd.Name, err)
+	}
+	defer s.Close()
+
+	fmt.Printf("Installed the \"%s\" service.\n", cmd.Name)
+
+	if cmd.Start {
+		if err := s.Start(); err != nil {
+			return fmt.Errorf("failed to start the \"%s\" service: %w", cmd.Name, err)
+		}
+		fmt.Printf("Started the \"%s\" service.\n", cmd.Name)
+	}
+
+	return nil
+}
+
+// ServiceUninstallCmd stops and removes the LeafBridge agent service.
+type ServiceUninstallCmd struct {
+	Name string `kong:"optional,name='name',default='${default_service_name}',help='The name of the service.'"`
+}
+
+// Run executes the LeafBridge service uninstall command.
+func (cmd ServiceUninstallCmd) Run(ctx context.Context) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(cmd.Name)
+	if err != nil {
+		return fmt.Errorf("failed to open the \"%s\" service: %w", cmd.Name, err)
+	}
+	defer s.Close()
+
+	// Stop the service if it is running.
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if _, err := s.Control(svc.Stop); err != nil {
+			return fmt.Errorf("failed to stop the \"%s\" service: %w", cmd.Name, err)
+		}
+		deadline := time.Now().Add(time.Minute)
+		for status.State != svc.Stopped && time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			if status, err = s.Query(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to remove the \"%s\" service: %w", cmd.Name, err)
+	}
+
+	fmt.Printf("Removed the \"%s\" service.\n", cmd.Name)
+
+	return nil
+}
+
+// agentService is a Windows service handler that runs the agent.
+type agentService struct {
+	ctx context.Context
+	run func(context.Context) error
+}
+
+// Execute runs the agent until the service is asked to stop.
+func (s agentService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (svcSpecificEC bool, exitCode uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(ctx)
+	}()
+
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+	changes <- svc.Status{State: svc.Running, Accepts: accepted}
+
+	for {
+		select {
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				changes <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		case err := <-done:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return false, 1
+			}
+			return false, 0
+		}
+	}
+}
+
+// runAgentService runs the given function as a Windows service. It returns
+// when the service has stopped.
+func runAgentService(ctx context.Context, run func(context.Context) error) error {
+	return svc.Run(defaultServiceName, agentService{ctx: ctx, run: run})
+}
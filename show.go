@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"maps"
 	"os"
 	"slices"
 	"strings"
+	"time"
 
-	"github.com/gentlemanautomaton/winobj/winmutex"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
 	"github.com/leafbridge/leafbridge-deploy/lbengine"
-	"github.com/leafbridge/leafbridge-deploy/localfs"
-	"github.com/leafbridge/leafbridge-deploy/localregistry"
+	"github.com/leafbridge/leafbridge-deploy/lbevent"
+	"github.com/leafbridge/leafbridge-deploy/lbgraph"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
 )
 
 // ShowCmd shows information that is relevant to a LeafBridge deployment.
@@ -21,11 +24,18 @@ type ShowCmd struct {
 	Apps       ShowAppsCmd       `kong:"cmd,help='Shows the installation status of applications for a deployment.'"`
 	Conditions ShowConditionsCmd `kong:"cmd,help='Shows the current conditions for a deployment.'"`
 	Resources  ShowResourcesCmd  `kong:"cmd,help='Shows the relevant resources for a deployment.'"`
+	Flows      ShowFlowsCmd      `kong:"cmd,help='Shows the flows of a deployment and their actions.'"`
+	Packages   ShowPackagesCmd   `kong:"cmd,help='Shows the packages for a deployment and the health of their sources.'"`
+	Facts      ShowFactsCmd      `kong:"cmd,help='Shows the facts gathered about the local computer.'"`
+	Graph      ShowGraphCmd      `kong:"cmd,help='Shows a graph of the references within a deployment in the DOT language.'"`
+	Events     ShowEventsCmd     `kong:"cmd,help='Shows events recorded in an event journal or exported from the Windows event log.'"`
 }
 
 // ShowConfigCmd shows the configuration of a LeafBridge deployment.
 type ShowConfigCmd struct {
-	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	ConfigFile  string                 `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Resolved    bool                   `kong:"optional,name='resolved',help='Show the deployment as the engine would act on it on this computer, with overlays and variants applied, facts expanded and resource paths resolved.'"`
+	Environment lbdeploy.EnvironmentID `kong:"optional,name='environment',help='The environment overlay to apply when showing the resolved deployment, such as pilot or broad.'"`
 }
 
 // Run executes the LeafBridge show config command.
@@ -36,8 +46,18 @@ func (cmd ShowConfigCmd) Run(ctx context.Context) error {
 		return err
 	}
 
-	// Print the loaded configuration.
-	out, err := json.MarshalIndent(dep, "", "  ")
+	// Print the loaded configuration, or the configuration as it resolves
+	// on this computer.
+	var config any = dep
+	if cmd.Resolved {
+		resolved, err := resolveConfig(dep, cmd.Environment)
+		if err != nil {
+			return err
+		}
+		config = resolved
+	}
+
+	out, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -47,6 +67,141 @@ func (cmd ShowConfigCmd) Run(ctx context.Context) error {
 	return nil
 }
 
+// ShowGraphCmd shows a graph of the references between the flows, actions,
+// commands, conditions and resources of a LeafBridge deployment.
+type ShowGraphCmd struct {
+	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Output     string `kong:"optional,name='output',short='o',help='Path of a file to write the graph to. The graph is written to standard output if omitted.'"`
+}
+
+// Run executes the LeafBridge show graph command.
+func (cmd ShowGraphCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	graph := lbgraph.Build(dep)
+
+	name := string(dep.ID)
+	if name == "" {
+		name = dep.Name
+	}
+
+	if cmd.Output == "" {
+		return graph.WriteDOT(os.Stdout, name)
+	}
+
+	f, err := os.Create(cmd.Output)
+	if err != nil {
+		return err
+	}
+	if err := graph.WriteDOT(f, name); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Summarize the definitions that nothing refers to.
+	orphans := graph.Orphans()
+	fmt.Printf("Wrote a graph of %d definitions to %s.\n", len(graph.Nodes), cmd.Output)
+	if len(orphans) > 0 {
+		fmt.Printf("The following definitions are not referred to:\n")
+		for _, node := range orphans {
+			fmt.Printf("  %s: %s\n", node.Kind, node.Label)
+		}
+	}
+
+	return nil
+}
+
+// ShowEventsCmd shows events that were previously recorded, for review
+// after an incident.
+type ShowEventsCmd struct {
+	Journal       string                `kong:"required,name='journal',type='existingfile',xor='source',help='Path of an event journal written by the deploy command.'"`
+	WindowsExport string                `kong:"required,name='windows-export',type='existingfile',xor='source',help='Path of Windows event log records exported in XML form, such as by wevtutil qe Application /f:xml.'"`
+	Deployment    lbdeploy.DeploymentID `kong:"optional,name='deployment',help='Only show events for the given deployment.'"`
+	Flow          lbdeploy.FlowID       `kong:"optional,name='flow',help='Only show events for the given flow.'"`
+	Since         time.Time             `kong:"optional,name='since',help='Only show events recorded at or after the given RFC 3339 time.'"`
+	Until         time.Time             `kong:"optional,name='until',help='Only show events recorded before the given RFC 3339 time.'"`
+	Verbose       bool                  `kong:"optional,name='verbose',short='v',help='Show debug events and the details of each event.'"`
+}
+
+// Run executes the LeafBridge show events command.
+func (cmd ShowEventsCmd) Run(ctx context.Context) error {
+	// Read the events.
+	path, read := cmd.Journal, lbevent.ReadJournal
+	if cmd.WindowsExport != "" {
+		path, read = cmd.WindowsExport, lbevent.ReadWindowsExport
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Print the events that match the filters.
+	min := slog.LevelInfo
+	if cmd.Verbose {
+		min = slog.LevelDebug
+	}
+	for _, entry := range entries {
+		if entry.Level < min || !cmd.matches(entry) {
+			continue
+		}
+		fmt.Printf("%s: %-6s %s\n", entry.Time.Local().Format(time.DateTime), entry.Level.String()+":", entry.Message)
+		if cmd.Verbose && entry.Details != "" {
+			for line := range strings.SplitSeq(entry.Details, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if entry passes the command's filters.
+func (cmd ShowEventsCmd) matches(entry lbevent.JournalEntry) bool {
+	if !cmd.Since.IsZero() && entry.Time.Before(cmd.Since) {
+		return false
+	}
+	if !cmd.Until.IsZero() && !entry.Time.Before(cmd.Until) {
+		return false
+	}
+
+	// Records exported from the Windows event log don't carry attributes,
+	// so fall back to the deployment and flow that lead their messages.
+	if cmd.Deployment != "" || cmd.Flow != "" {
+		deployment, hasDeployment := entry.Attr("deployment")
+		flow, hasFlow := entry.Attr("flow")
+		if entry.Attrs == nil {
+			fields := strings.SplitN(entry.Message, ": ", 3)
+			if len(fields) > 1 {
+				deployment, hasDeployment = fields[0], true
+			}
+			if len(fields) > 2 {
+				flow, hasFlow = fields[1], true
+			}
+		}
+		if cmd.Deployment != "" && (!hasDeployment || deployment != string(cmd.Deployment)) {
+			return false
+		}
+		if cmd.Flow != "" && (!hasFlow || flow != string(cmd.Flow)) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // ShowAppsCmd shows the current status of applications for a LeafBridge
 // deployment.
 type ShowAppsCmd struct {
@@ -156,6 +311,7 @@ func (cmd ShowAppsCmd) showAll() bool {
 // LeafBridge deployment.
 type ShowConditionsCmd struct {
 	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Format     string `kong:"optional,name='format',enum='text,json',default='text',help='The output format (text, json).'"`
 }
 
 // Run executes the LeafBridge show conditions command.
@@ -172,348 +328,239 @@ func (cmd ShowConditionsCmd) Run(ctx context.Context) error {
 		os.Exit(1)
 	}
 
-	fmt.Printf("---- %s (%s): Conditions ----\n", dep.Name, cmd.ConfigFile)
+	// Evaluate each condition.
+	report := evaluateConditions(dep)
 
-	// Prepare a condition engine.
-	ce := lbengine.NewConditionEngine(dep)
+	if cmd.Format == showFormatJSON {
+		return printJSON(report)
+	}
 
-	// Sort the condition IDs for a deterministic order.
-	ids := slices.Collect(maps.Keys(dep.Conditions))
-	slices.Sort(ids)
+	fmt.Printf("---- %s (%s): Conditions ----\n", dep.Name, cmd.ConfigFile)
 
 	// Print the status of each condition.
-	for _, id := range ids {
-		result, err := ce.Evaluate(id)
-		if err != nil {
-			fmt.Printf("    %s: %s\n", id, err)
+	for _, condition := range report.Conditions {
+		if condition.Result == nil {
+			fmt.Printf("    %s: %s\n", condition.ID, condition.Error)
 		} else {
-			fmt.Printf("    %s: %t\n", id, result)
+			fmt.Printf("    %s: %t\n", condition.ID, *condition.Result)
 		}
 	}
 
 	return nil
 }
 
-// ShowResourcesCmd shows the current condition of relevant resources for
-// a LeafBridge deployment.
-type ShowResourcesCmd struct {
-	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
-}
+// ShowFactsCmd shows the facts gathered about the local computer, which
+// can be used by conditions and command arguments.
+type ShowFactsCmd struct{}
 
-// Run executes the LeafBridge show resources command.
-func (cmd ShowResourcesCmd) Run(ctx context.Context) error {
-	// Read the deployment file.
-	dep, err := loadDeployment(cmd.ConfigFile)
-	if err != nil {
-		return err
-	}
+// Run executes the LeafBridge show facts command.
+func (cmd ShowFactsCmd) Run(ctx context.Context) error {
+	facts, err := sysfacts.Snapshot()
 
-	// Validate the dpeloyment.
-	if err := dep.Validate(); err != nil {
-		fmt.Printf("The deployment contains invalid configuration: %s\n", err)
-		os.Exit(1)
-	}
+	fmt.Printf("---- Facts ----\n")
 
-	fmt.Printf("---- %s (%s): Resources ----\n", dep.Name, cmd.ConfigFile)
-
-	// Print process resources.
-	if processes := dep.Resources.Processes; len(processes) > 0 {
-		// Sort the process resource IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(processes))
-		slices.Sort(ids)
-
-		// Print information about each process.
-		fmt.Printf("  Processes:\n")
-		for _, id := range ids {
-			process := processes[id]
-			func() {
-				// Print the resource ID and description.
-				fmt.Printf("    %s:\n", id)
-				fmt.Printf("      Description: %s\n", process.Description)
-
-				// Look for running processes that match the criteria.
-				total, err := lbengine.NumberOfRunningProcesses(process.Match)
-				if err != nil {
-					fmt.Printf("      Running:     (%v)\n", process.Description)
-					return
-				}
+	// Print each fact that was gathered.
+	for _, name := range facts.Names() {
+		fmt.Printf("    %s: %s\n", name, facts[name])
+	}
 
-				// Print the number of running processes.
-				switch total {
-				case 0:
-					fmt.Printf("      Running:     No\n")
-				case 1:
-					fmt.Printf("      Running:     Yes (%d process)\n", total)
-				default:
-					fmt.Printf("      Running:     Yes (%d processes)\n", total)
-				}
-			}()
+	// Print any facts that could not be gathered.
+	if err != nil {
+		fmt.Printf("  Problems:\n")
+		for line := range strings.SplitSeq(err.Error(), "\n") {
+			fmt.Printf("    %s\n", line)
 		}
 	}
 
-	// Print mutex resources.
-	if mutexes := dep.Resources.Mutexes; len(mutexes) > 0 {
-		// Sort the mutex IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(mutexes))
-		slices.Sort(ids)
-
-		// Print information about each mutex.
-		fmt.Printf("  Mutexes:\n")
-		for _, id := range ids {
-			mutex := mutexes[id]
-			func() {
-				fmt.Printf("    %s:\n", id)
-
-				// Print the object name of the mutex.
-				name, err := mutex.ObjectName()
-				if err != nil {
-					fmt.Printf("      Name:        (%v)\n", err)
-					return
-				}
-				fmt.Printf("      Name:        %s\n", name)
+	return nil
+}
 
-				// Print the status of the mutex.
-				exists, err := winmutex.Exists(name)
-				if err != nil {
-					fmt.Printf("      Status:      (%v)\n", err)
-					return
-				}
+// ShowFlowsCmd shows the flows of a LeafBridge deployment, along with their
+// descriptions and the actions that they invoke.
+type ShowFlowsCmd struct {
+	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+}
 
-				if exists {
-					fmt.Printf("      Status:      Present\n")
-				} else {
-					fmt.Printf("      Status:      Missing\n")
-				}
-			}()
-		}
+// Run executes the LeafBridge show flows command.
+func (cmd ShowFlowsCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
 	}
 
-	// Print registry key resources.
-	if keys := dep.Resources.Registry.Keys; len(keys) > 0 {
-		// Sort the registry key IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(keys))
-		slices.Sort(ids)
-
-		// Print information about each registry key.
-		fmt.Printf("  Registry Keys:\n")
-		for _, id := range ids {
-			func() {
-				fmt.Printf("    %s:\n", id)
-
-				// Resolve the registry key reference.
-				ref, err := dep.Resources.Registry.ResolveKey(id)
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
+	fmt.Printf("---- %s (%s): Flows ----\n", dep.Name, cmd.ConfigFile)
 
-				// Generate a registry key path.
-				path, err := ref.Path()
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
+	// Sort the flow IDs for a deterministic order.
+	ids := slices.Collect(maps.Keys(dep.Flows))
+	slices.Sort(ids)
 
-				// Open the registry key.
-				key, err := localregistry.OpenKey(ref)
-				if err != nil {
-					fmt.Printf("      Path:        %s\n", path)
-					if os.IsNotExist(err) {
-						fmt.Printf("      Status:      Missing\n")
-					} else {
-						fmt.Printf("      Status:      (%v)\n", err)
-					}
-					return
-				}
-				defer key.Close()
+	// Print information about each flow.
+	for _, id := range ids {
+		flow := dep.Flows[id]
+		if flow.Description != "" {
+			fmt.Printf("    %s: %s\n", id, flow.Description)
+		} else {
+			fmt.Printf("    %s:\n", id)
+		}
+		if len(flow.DependsOn) > 0 {
+			fmt.Printf("      Depends On:  %s\n", joinIDs(flow.DependsOn))
+		}
 
-				// Print the path and status.
-				fmt.Printf("      Path:        %s\n", key.Path())
-				fmt.Printf("      Status:      Present\n")
-			}()
+		// Print each action with the number that it is known by in events
+		// and action references.
+		number := 0
+		for _, group := range []struct {
+			Name    string
+			Actions []lbdeploy.Action
+		}{
+			{"Before", flow.Before},
+			{"Actions", flow.Actions},
+			{"After", flow.After},
+		} {
+			if len(group.Actions) == 0 {
+				continue
+			}
+			fmt.Printf("      %s:\n", group.Name)
+			for _, action := range group.Actions {
+				number++
+				fmt.Printf("        %d: %s\n", number, actionSummary(action))
+			}
 		}
 	}
 
-	// Print registry value resources.
-	if values := dep.Resources.Registry.Values; len(values) > 0 {
-		// Sort the registry value IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(values))
-		slices.Sort(ids)
-
-		// Print information about each registry value.
-		fmt.Printf("  Registry Values:\n")
-		for _, id := range ids {
-			func() {
-				fmt.Printf("    %s:\n", id)
-
-				// Resolve the registry value reference.
-				ref, err := dep.Resources.Registry.ResolveValue(id)
-				if err != nil {
-					fmt.Printf("      Key:         (%v)\n", err)
-					fmt.Printf("      Name:        %s\n", ref.Name)
-					return
-				}
-
-				// Generate a registry key path.
-				path, err := ref.Key().Path()
-				if err != nil {
-					fmt.Printf("      Key:         (%v)\n", err)
-					fmt.Printf("      Name:        %s\n", ref.Name)
-					return
-				}
-
-				// Attempt to open the parent key.
-				key, err := localregistry.OpenKey(ref.Key())
-				if err != nil {
-					fmt.Printf("      Key:         %s\n", path)
-					fmt.Printf("      Name:        %s\n", ref.Name)
-					if os.IsNotExist(err) {
-						fmt.Printf("      Status:      Missing\n")
-					} else {
-						fmt.Printf("      Status:      (%v)\n", err)
-					}
-					return
-				}
-				defer key.Close()
-
-				// Print the key path and value name
-				fmt.Printf("      Key:         %s\n", key.Path())
-				fmt.Printf("      Name:        %s\n", ref.Name)
+	return nil
+}
 
-				// Determine whether the registry value exists.
-				exists, err := key.HasValue(ref.Name)
-				if err != nil {
-					fmt.Printf("      Status:      (%v)\n", err)
-				}
+// actionSummary returns a single line that describes an action.
+func actionSummary(action lbdeploy.Action) string {
+	summary := string(action.Type)
+	if action.ID != "" {
+		summary += fmt.Sprintf(" [%s]", action.ID)
+	}
+	if action.Description != "" {
+		summary += ": " + action.Description
+	}
+	if len(action.DependsOn) > 0 {
+		summary += fmt.Sprintf(" (depends on %s)", joinIDs(action.DependsOn))
+	}
+	return summary
+}
 
-				if !exists {
-					fmt.Printf("      Status:      Missing\n")
-					return
-				}
+// joinIDs returns the given IDs as a comma-separated list.
+func joinIDs[T ~string](ids []T) string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = string(id)
+	}
+	return strings.Join(out, ", ")
+}
 
-				value, err := key.GetValue(ref.Name, ref.Type)
-				if err != nil {
-					fmt.Printf("      Value:       (%v)\n", err)
-					return
-				}
-				fmt.Printf("      Value:       %s\n", value)
+// ShowPackagesCmd shows the packages of a LeafBridge deployment, along with
+// the download statistics recorded for each of their sources.
+type ShowPackagesCmd struct {
+	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+}
 
-				// TODO: Report statistics.
-				//fmt.Printf("      Modified: %s\n", fi.ModTime())
-				//fmt.Printf("      Size:     %d bytes(s)\n", fi.Size())
-			}()
-		}
+// Run executes the LeafBridge show packages command.
+func (cmd ShowPackagesCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
 	}
 
-	// Print directory resources.
-	if dirs := dep.Resources.FileSystem.Directories; len(dirs) > 0 {
-		// Sort the directory IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(dirs))
-		slices.Sort(ids)
+	// Validate the dpeloyment.
+	if err := dep.Validate(); err != nil {
+		fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+		os.Exit(1)
+	}
 
-		// Print information about each file.
-		fmt.Printf("  Directories:\n")
-		for _, id := range ids {
-			func() {
-				fmt.Printf("    %s:\n", id)
+	fmt.Printf("---- %s (%s): Packages ----\n", dep.Name, cmd.ConfigFile)
 
-				// Resolve the directory reference.
-				ref, err := dep.Resources.FileSystem.ResolveDirectory(id)
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
+	// Read the statistics recorded for package sources.
+	stats, statsErr := lbengine.SourceStats(dep.ID)
 
-				// Generate a file path.
-				path, err := ref.Path()
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
+	// Sort the package IDs for a deterministic order.
+	ids := slices.Collect(maps.Keys(dep.Resources.Packages))
+	slices.Sort(ids)
 
-				// Open the parent directory.
-				dir, err := localfs.OpenDir(ref)
-				if err != nil {
-					fmt.Printf("      Path:        %s\n", path)
-					if os.IsNotExist(err) {
-						fmt.Printf("      Status:      Missing\n")
-					} else {
-						fmt.Printf("      Status:      (%v)\n", err)
-					}
-					return
-				}
-				defer dir.Close()
+	// Print information about each package.
+	for _, id := range ids {
+		pkg := dep.Resources.Packages[id]
+		fmt.Printf("    %s:\n", id)
+		fmt.Printf("      Name:        %s\n", pkg.Name)
+		fmt.Printf("      Size:        %d bytes\n", pkg.Attributes.Size)
+		if len(pkg.Sources) == 0 {
+			continue
+		}
 
-				// Print the path and status.
-				fmt.Printf("      Path:        %s\n", dir.Path())
-				fmt.Printf("      Status:      Present\n")
-			}()
+		// Print the health of each source.
+		fmt.Printf("      Sources:\n")
+		for _, source := range pkg.Sources {
+			fmt.Printf("        %s:\n", source.URL)
+			if statsErr != nil {
+				fmt.Printf("          Statistics:  (%v)\n", statsErr)
+				continue
+			}
+			s, found := stats[source.URL]
+			if !found {
+				fmt.Printf("          Statistics:  None recorded\n")
+				continue
+			}
+			fmt.Printf("          Successes:   %d\n", s.Successes)
+			fmt.Printf("          Failures:    %d\n", s.Failures)
+			if s.Rejections > 0 {
+				fmt.Printf("          Rejections:  %d\n", s.Rejections)
+			}
+			if s.ConsecutiveFailures > 0 {
+				fmt.Printf("          Failing:     %d consecutive\n", s.ConsecutiveFailures)
+			}
+			if throughput := s.Throughput(); throughput > 0 {
+				fmt.Printf("          Throughput:  %.2f MB/s\n", throughput/1000000)
+			}
+			if !s.LastSuccess.IsZero() {
+				fmt.Printf("          Succeeded:   %s\n", s.LastSuccess.Local().Format(time.DateTime))
+			}
+			if !s.LastFailure.IsZero() {
+				fmt.Printf("          Failed:      %s (%s)\n", s.LastFailure.Local().Format(time.DateTime), s.LastError)
+			}
 		}
 	}
 
-	// Print file resources.
-	if files := dep.Resources.FileSystem.Files; len(files) > 0 {
-		// Sort the file IDs for a deterministic order.
-		ids := slices.Collect(maps.Keys(files))
-		slices.Sort(ids)
+	return nil
+}
 
-		// Print information about each file.
-		fmt.Printf("  Files:\n")
-		for _, id := range ids {
-			func() {
-				fmt.Printf("    %s:\n", id)
+// ShowResourcesCmd shows the current condition of relevant resources for
+// a LeafBridge deployment.
+type ShowResourcesCmd struct {
+	ConfigFile string `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Format     string `kong:"optional,name='format',enum='text,json',default='text',help='The output format (text, json).'"`
+}
 
-				// Resolve the file reference.
-				ref, err := dep.Resources.FileSystem.ResolveFile(id)
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
+// Run executes the LeafBridge show resources command.
+func (cmd ShowResourcesCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
+	}
 
-				// Generate a file path.
-				path, err := ref.Path()
-				if err != nil {
-					fmt.Printf("      Path:        (%v)\n", err)
-					return
-				}
-				fmt.Printf("      Path:        %s\n", path)
-
-				// Attempt to open the parent directory.
-				dir, err := localfs.OpenDir(ref.Dir())
-				if err != nil {
-					if os.IsNotExist(err) {
-						fmt.Printf("      Status:      Missing\n")
-					} else {
-						fmt.Printf("      Status:      (%v)\n", err)
-					}
-					return
-				}
-				defer dir.Close()
-
-				// Stat the file path.
-				fi, err := dir.System().Stat(ref.FilePath)
-				if err != nil {
-					if os.IsNotExist(err) {
-						fmt.Printf("      Status:      Missing\n")
-					} else {
-						fmt.Printf("      Status:      (%v)\n", err)
-					}
-					return
-				}
+	// Validate the dpeloyment.
+	if err := dep.Validate(); err != nil {
+		fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+		os.Exit(1)
+	}
 
-				// Make sure it's a regular file.
-				if !fi.Mode().IsRegular() {
-					fmt.Printf("      Status:      Not A File\n")
-					return
-				}
+	// Determine the status of each resource.
+	report := inspectResources(dep)
 
-				// Report statistics.
-				fmt.Printf("      Status:      Present\n")
-				fmt.Printf("      Modified:    %s\n", fi.ModTime())
-				fmt.Printf("      Size:        %d bytes(s)\n", fi.Size())
-			}()
-		}
+	if cmd.Format == showFormatJSON {
+		return printJSON(report)
 	}
 
+	fmt.Printf("---- %s (%s): Resources ----\n", dep.Name, cmd.ConfigFile)
+	printResources(report)
+
 	return nil
 }
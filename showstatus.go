@@ -0,0 +1,539 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gentlemanautomaton/winobj/winmutex"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbengine"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"github.com/leafbridge/leafbridge-deploy/localfs"
+	"github.com/leafbridge/leafbridge-deploy/localregistry"
+)
+
+// Output formats supported by show commands.
+const (
+	showFormatText = "text"
+	showFormatJSON = "json"
+)
+
+// printJSON prints v as indented JSON.
+func printJSON(v any) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// errString returns the message of err, or an empty string if err is nil.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// conditionReport holds the outcome of each condition in a deployment.
+type conditionReport struct {
+	Deployment lbdeploy.DeploymentID `json:"deployment"`
+	Name       string                `json:"name,omitempty"`
+	Conditions []conditionStatus     `json:"conditions"`
+}
+
+// conditionStatus is the outcome of a condition. Result is nil if the
+// condition could not be evaluated.
+type conditionStatus struct {
+	ID     lbdeploy.ConditionID `json:"id"`
+	Result *bool                `json:"result,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// evaluateConditions evaluates each of the conditions in dep.
+func evaluateConditions(dep lbdeploy.Deployment) conditionReport {
+	report := conditionReport{
+		Deployment: dep.ID,
+		Name:       dep.Name,
+		Conditions: []conditionStatus{},
+	}
+
+	// Prepare a condition engine.
+	ce := lbengine.NewConditionEngine(dep)
+
+	// Evaluate each condition in a deterministic order.
+	for _, id := range slices.Sorted(maps.Keys(dep.Conditions)) {
+		status := conditionStatus{ID: id}
+		if result, err := ce.Evaluate(id); err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Result = &result
+		}
+		report.Conditions = append(report.Conditions, status)
+	}
+
+	return report
+}
+
+// Resource statuses.
+const (
+	resourcePresent  = "present"
+	resourceMissing  = "missing"
+	resourceNotAFile = "not-a-file"
+)
+
+// resourceReport holds the status of each resource in a deployment.
+type resourceReport struct {
+	Deployment     lbdeploy.DeploymentID `json:"deployment"`
+	Name           string                `json:"name,omitempty"`
+	Processes      []processStatus       `json:"processes,omitempty"`
+	Mutexes        []mutexStatus         `json:"mutexes,omitempty"`
+	Semaphores     []semaphoreStatus     `json:"semaphores,omitempty"`
+	RegistryKeys   []registryKeyStatus   `json:"registry-keys,omitempty"`
+	RegistryValues []registryValueStatus `json:"registry-values,omitempty"`
+	Directories    []directoryStatus     `json:"directories,omitempty"`
+	Files          []fileStatus          `json:"files,omitempty"`
+}
+
+// processStatus reports the number of running processes that match a
+// process resource. Running is nil if they could not be counted.
+type processStatus struct {
+	ID          lbdeploy.ProcessResourceID `json:"id"`
+	Description string                     `json:"description,omitempty"`
+	Running     *int                       `json:"running,omitempty"`
+	Error       string                     `json:"error,omitempty"`
+}
+
+// mutexStatus reports whether a mutex exists.
+type mutexStatus struct {
+	ID     lbdeploy.MutexID `json:"id"`
+	Name   string           `json:"name,omitempty"`
+	Status string           `json:"status,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// semaphoreStatus describes a semaphore.
+type semaphoreStatus struct {
+	ID    lbdeploy.SemaphoreID `json:"id"`
+	Name  string               `json:"name,omitempty"`
+	Count int                  `json:"count"`
+	Error string               `json:"error,omitempty"`
+}
+
+// registryKeyStatus reports whether a registry key exists.
+type registryKeyStatus struct {
+	ID     lbdeploy.RegistryKeyResourceID `json:"id"`
+	Path   string                         `json:"path,omitempty"`
+	Status string                         `json:"status,omitempty"`
+	Error  string                         `json:"error,omitempty"`
+}
+
+// registryValueStatus reports whether a registry value exists, and its
+// value if it does.
+type registryValueStatus struct {
+	ID     lbdeploy.RegistryValueResourceID `json:"id"`
+	Key    string                           `json:"key,omitempty"`
+	Name   string                           `json:"name"`
+	Status string                           `json:"status,omitempty"`
+	Value  lbvalue.Value                    `json:"value,omitzero"`
+	Error  string                           `json:"error,omitempty"`
+}
+
+// directoryStatus reports whether a directory exists.
+type directoryStatus struct {
+	ID     lbdeploy.DirectoryResourceID `json:"id"`
+	Path   string                       `json:"path,omitempty"`
+	Status string                       `json:"status,omitempty"`
+	Error  string                       `json:"error,omitempty"`
+}
+
+// fileStatus reports whether a file exists, and its statistics if it does.
+type fileStatus struct {
+	ID       lbdeploy.FileResourceID `json:"id"`
+	Path     string                  `json:"path,omitempty"`
+	Status   string                  `json:"status,omitempty"`
+	Modified time.Time               `json:"modified,omitzero"`
+	Size     int64                   `json:"size,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// inspectResources determines the status of each of the resources in dep.
+func inspectResources(dep lbdeploy.Deployment) resourceReport {
+	report := resourceReport{
+		Deployment: dep.ID,
+		Name:       dep.Name,
+	}
+
+	// Inspect process resources.
+	processes := dep.Resources.Processes
+	for _, id := range slices.Sorted(maps.Keys(processes)) {
+		process := processes[id]
+		status := processStatus{ID: id, Description: process.Description}
+
+		// Look for running processes that match the criteria.
+		if total, err := lbengine.NumberOfRunningProcesses(process.Match); err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Running = &total
+		}
+		report.Processes = append(report.Processes, status)
+	}
+
+	// Inspect mutex resources.
+	mutexes := dep.Resources.Mutexes
+	for _, id := range slices.Sorted(maps.Keys(mutexes)) {
+		status := mutexStatus{ID: id}
+		func() {
+			// Determine the object name of the mutex.
+			name, err := mutexes[id].ObjectName()
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+			status.Name = name
+
+			// Determine whether the mutex exists.
+			exists, err := winmutex.Exists(name)
+			switch {
+			case err != nil:
+				status.Error = err.Error()
+			case exists:
+				status.Status = resourcePresent
+			default:
+				status.Status = resourceMissing
+			}
+		}()
+		report.Mutexes = append(report.Mutexes, status)
+	}
+
+	// Inspect semaphore resources.
+	semaphores := dep.Resources.Semaphores
+	for _, id := range slices.Sorted(maps.Keys(semaphores)) {
+		semaphore := semaphores[id]
+		status := semaphoreStatus{ID: id, Count: semaphore.Count}
+		name, err := semaphore.ObjectName()
+		status.Name, status.Error = name, errString(err)
+		report.Semaphores = append(report.Semaphores, status)
+	}
+
+	// Inspect registry key resources.
+	for _, id := range slices.Sorted(maps.Keys(dep.Resources.Registry.Keys)) {
+		status := registryKeyStatus{ID: id}
+		func() {
+			// Resolve the registry key reference.
+			ref, err := dep.Resources.Registry.ResolveKey(id)
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Generate a registry key path.
+			status.Path, err = ref.Path()
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Open the registry key.
+			key, err := localregistry.OpenKey(ref)
+			if err != nil {
+				if os.IsNotExist(err) {
+					status.Status = resourceMissing
+				} else {
+					status.Error = err.Error()
+				}
+				return
+			}
+			defer key.Close()
+
+			status.Path = key.Path()
+			status.Status = resourcePresent
+		}()
+		report.RegistryKeys = append(report.RegistryKeys, status)
+	}
+
+	// Inspect registry value resources.
+	for _, id := range slices.Sorted(maps.Keys(dep.Resources.Registry.Values)) {
+		status := registryValueStatus{ID: id}
+		func() {
+			// Resolve the registry value reference.
+			ref, err := dep.Resources.Registry.ResolveValue(id)
+			status.Name = ref.Name
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Generate a registry key path.
+			status.Key, err = ref.Key().Path()
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Attempt to open the parent key.
+			key, err := localregistry.OpenKey(ref.Key())
+			if err != nil {
+				if os.IsNotExist(err) {
+					status.Status = resourceMissing
+				} else {
+					status.Error = err.Error()
+				}
+				return
+			}
+			defer key.Close()
+			status.Key = key.Path()
+
+			// Determine whether the registry value exists.
+			exists, err := key.HasValue(ref.Name)
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+			if !exists {
+				status.Status = resourceMissing
+				return
+			}
+			status.Status = resourcePresent
+
+			// Read the value.
+			status.Value, err = key.GetValue(ref.Name, ref.Type)
+			status.Error = errString(err)
+		}()
+		report.RegistryValues = append(report.RegistryValues, status)
+	}
+
+	// Inspect directory resources.
+	for _, id := range slices.Sorted(maps.Keys(dep.Resources.FileSystem.Directories)) {
+		status := directoryStatus{ID: id}
+		func() {
+			// Resolve the directory reference.
+			ref, err := dep.Resources.FileSystem.ResolveDirectory(id)
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Generate a file path.
+			status.Path, err = ref.Path()
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Open the directory.
+			dir, err := localfs.OpenDir(ref)
+			if err != nil {
+				if os.IsNotExist(err) {
+					status.Status = resourceMissing
+				} else {
+					status.Error = err.Error()
+				}
+				return
+			}
+			defer dir.Close()
+
+			status.Path = dir.Path()
+			status.Status = resourcePresent
+		}()
+		report.Directories = append(report.Directories, status)
+	}
+
+	// Inspect file resources.
+	for _, id := range slices.Sorted(maps.Keys(dep.Resources.FileSystem.Files)) {
+		status := fileStatus{ID: id}
+		func() {
+			// Resolve the file reference.
+			ref, err := dep.Resources.FileSystem.ResolveFile(id)
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Generate a file path.
+			status.Path, err = ref.Path()
+			if err != nil {
+				status.Error = err.Error()
+				return
+			}
+
+			// Attempt to open the parent directory.
+			dir, err := localfs.OpenDir(ref.Dir())
+			if err != nil {
+				if os.IsNotExist(err) {
+					status.Status = resourceMissing
+				} else {
+					status.Error = err.Error()
+				}
+				return
+			}
+			defer dir.Close()
+
+			// Stat the file path.
+			fi, err := dir.System().Stat(ref.FilePath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					status.Status = resourceMissing
+				} else {
+					status.Error = err.Error()
+				}
+				return
+			}
+
+			// Make sure it's a regular file.
+			if !fi.Mode().IsRegular() {
+				status.Status = resourceNotAFile
+				return
+			}
+
+			status.Status = resourcePresent
+			status.Modified = fi.ModTime()
+			status.Size = fi.Size()
+		}()
+		report.Files = append(report.Files, status)
+	}
+
+	return report
+}
+
+// resourceStatusText returns the text form of a resource status, or of the
+// error that prevented it from being determined.
+func resourceStatusText(status, err string) string {
+	switch {
+	case err != "":
+		return "(" + err + ")"
+	case status == resourcePresent:
+		return "Present"
+	case status == resourceMissing:
+		return "Missing"
+	case status == resourceNotAFile:
+		return "Not A File"
+	default:
+		return status
+	}
+}
+
+// printResources prints the resource report in text form.
+func printResources(report resourceReport) {
+	// Print process resources.
+	if len(report.Processes) > 0 {
+		fmt.Printf("  Processes:\n")
+		for _, process := range report.Processes {
+			fmt.Printf("    %s:\n", process.ID)
+			fmt.Printf("      Description: %s\n", process.Description)
+			switch {
+			case process.Running == nil:
+				fmt.Printf("      Running:     (%s)\n", process.Error)
+			case *process.Running == 0:
+				fmt.Printf("      Running:     No\n")
+			case *process.Running == 1:
+				fmt.Printf("      Running:     Yes (%d process)\n", *process.Running)
+			default:
+				fmt.Printf("      Running:     Yes (%d processes)\n", *process.Running)
+			}
+		}
+	}
+
+	// Print mutex resources.
+	if len(report.Mutexes) > 0 {
+		fmt.Printf("  Mutexes:\n")
+		for _, mutex := range report.Mutexes {
+			fmt.Printf("    %s:\n", mutex.ID)
+			if mutex.Name == "" {
+				fmt.Printf("      Name:        (%s)\n", mutex.Error)
+				continue
+			}
+			fmt.Printf("      Name:        %s\n", mutex.Name)
+			fmt.Printf("      Status:      %s\n", resourceStatusText(mutex.Status, mutex.Error))
+		}
+	}
+
+	// Print semaphore resources.
+	if len(report.Semaphores) > 0 {
+		fmt.Printf("  Semaphores:\n")
+		for _, semaphore := range report.Semaphores {
+			fmt.Printf("    %s:\n", semaphore.ID)
+			if semaphore.Error != "" {
+				fmt.Printf("      Name:        (%s)\n", semaphore.Error)
+				continue
+			}
+			fmt.Printf("      Name:        %s\n", semaphore.Name)
+			fmt.Printf("      Count:       %d\n", semaphore.Count)
+		}
+	}
+
+	// Print registry key resources.
+	if len(report.RegistryKeys) > 0 {
+		fmt.Printf("  Registry Keys:\n")
+		for _, key := range report.RegistryKeys {
+			fmt.Printf("    %s:\n", key.ID)
+			if key.Path == "" {
+				fmt.Printf("      Path:        (%s)\n", key.Error)
+				continue
+			}
+			fmt.Printf("      Path:        %s\n", key.Path)
+			fmt.Printf("      Status:      %s\n", resourceStatusText(key.Status, key.Error))
+		}
+	}
+
+	// Print registry value resources.
+	if len(report.RegistryValues) > 0 {
+		fmt.Printf("  Registry Values:\n")
+		for _, value := range report.RegistryValues {
+			fmt.Printf("    %s:\n", value.ID)
+			if value.Key == "" {
+				fmt.Printf("      Key:         (%s)\n", value.Error)
+				fmt.Printf("      Name:        %s\n", value.Name)
+				continue
+			}
+			fmt.Printf("      Key:         %s\n", value.Key)
+			fmt.Printf("      Name:        %s\n", value.Name)
+			if value.Status != resourcePresent {
+				fmt.Printf("      Status:      %s\n", resourceStatusText(value.Status, value.Error))
+				continue
+			}
+			if value.Error != "" {
+				fmt.Printf("      Value:       (%s)\n", value.Error)
+				continue
+			}
+			fmt.Printf("      Value:       %s\n", value.Value)
+		}
+	}
+
+	// Print directory resources.
+	if len(report.Directories) > 0 {
+		fmt.Printf("  Directories:\n")
+		for _, dir := range report.Directories {
+			fmt.Printf("    %s:\n", dir.ID)
+			if dir.Path == "" {
+				fmt.Printf("      Path:        (%s)\n", dir.Error)
+				continue
+			}
+			fmt.Printf("      Path:        %s\n", dir.Path)
+			fmt.Printf("      Status:      %s\n", resourceStatusText(dir.Status, dir.Error))
+		}
+	}
+
+	// Print file resources.
+	if len(report.Files) > 0 {
+		fmt.Printf("  Files:\n")
+		for _, file := range report.Files {
+			fmt.Printf("    %s:\n", file.ID)
+			if file.Path == "" {
+				fmt.Printf("      Path:        (%s)\n", file.Error)
+				continue
+			}
+			fmt.Printf("      Path:        %s\n", file.Path)
+			fmt.Printf("      Status:      %s\n", resourceStatusText(file.Status, file.Error))
+			if file.Status == resourcePresent {
+				fmt.Printf("      Modified:    %s\n", file.Modified)
+				fmt.Printf("      Size:        %d bytes(s)\n", file.Size)
+			}
+		}
+	}
+}
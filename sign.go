@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbsign"
+)
+
+// SignCmd signs a LeafBridge deployment file.
+type SignCmd struct {
+	ConfigFile string `kong:"required,name='config-file',help='Path to the deployment or fragment file to sign.'"`
+	Key        string `kong:"required,name='key',type='existingfile',help='Path to a PEM-encoded Ed25519 private key, such as one generated with openssl genpkey -algorithm ed25519.'"`
+	Embedded   bool   `kong:"optional,name='embedded',help='Replace the deployment file with a signed document that embeds it, instead of writing a detached signature file.'"`
+	Fragment   bool   `kong:"optional,name='fragment',help='Sign a fragment that is included by deployments, instead of a deployment file.'"`
+}
+
+// Run executes the LeafBridge sign command.
+func (cmd SignCmd) Run(ctx context.Context) error {
+	// Read the file.
+	var data []byte
+	var err error
+	if cmd.Fragment {
+		data, err = os.ReadFile(cmd.ConfigFile)
+	} else {
+		data, err = readDeploymentFile(cmd.ConfigFile)
+	}
+	if err != nil {
+		return err
+	}
+	if lbsign.IsEmbedded(data) {
+		return fmt.Errorf("%s is already signed", cmd.ConfigFile)
+	}
+
+	// Make sure it contains a valid fragment or deployment before signing
+	// it.
+	if cmd.Fragment {
+		var fragment lbdeploy.Fragment
+		if err := json.Unmarshal(data, &fragment); err != nil {
+			return fmt.Errorf("%s does not contain a valid fragment: %w", cmd.ConfigFile, err)
+		}
+	} else {
+		dep, err := loadDeployment(cmd.ConfigFile)
+		if err != nil {
+			return err
+		}
+		if err := dep.Validate(); err != nil {
+			fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Read the private key.
+	key, err := lbsign.LoadPrivateKey(cmd.Key)
+	if err != nil {
+		return err
+	}
+
+	keyID := lbsign.KeyID(key.Public().(ed25519.PublicKey))
+
+	// Sign the deployment file.
+	if cmd.Embedded {
+		out, err := lbsign.SignEmbedded(data, key)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cmd.ConfigFile, out, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Signed %s with an embedded signature (key %s).\n", cmd.ConfigFile, keyID)
+		return nil
+	}
+
+	out, err := lbsign.SignDetached(data, key)
+	if err != nil {
+		return err
+	}
+	sigPath := cmd.ConfigFile + lbsign.DetachedExtension
+	if err := os.WriteFile(sigPath, out, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote a detached signature for %s to %s (key %s).\n", cmd.ConfigFile, sigPath, keyID)
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package stagingfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// FileBackupDir is the name of the directory within a deployment's staging
+// directory that holds backups of files that were replaced.
+const FileBackupDir = "file-backups"
+
+// FileBackupPath returns the path of a new backup file for the given file
+// resource. The backup directory is created if it does not already exist.
+//
+// The file name includes the time of the backup, so that earlier backups
+// of the same file are not replaced.
+func (r DeploymentDir) FileBackupPath(file lbdeploy.FileResourceID, when time.Time) (string, error) {
+	if err := r.dir.Mkdir(FileBackupDir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	name := fmt.Sprintf("%s.%s", when.UTC().Format("20060102T150405.000Z"), safeFileName(string(file)))
+	return filepath.Join(r.path, FileBackupDir, name), nil
+}
@@ -0,0 +1,95 @@
+package stagingfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// Checkpoint records the progress of a flow within a deployment, so that an
+// interrupted flow can be resumed.
+type Checkpoint struct {
+	Flow lbdeploy.FlowID `json:"flow"`
+
+	// Fingerprint identifies the flow definition that the checkpoint was
+	// recorded for. A checkpoint should not be used if the flow has changed.
+	Fingerprint string `json:"fingerprint"`
+
+	// Completed is the number of actions at the start of the flow that have
+	// completed successfully.
+	Completed int `json:"completed"`
+
+	// Updated is the time that the checkpoint was recorded.
+	Updated time.Time `json:"updated"`
+}
+
+// checkpointFileName returns the name of the checkpoint file for a flow.
+func checkpointFileName(flow lbdeploy.FlowID) string {
+	return string(flow) + ".checkpoint.json"
+}
+
+// ReadCheckpoint reads the checkpoint for the given flow.
+//
+// If a checkpoint has not been recorded for the flow, an error satisfying
+// os.IsNotExist is returned.
+func (r DeploymentDir) ReadCheckpoint(flow lbdeploy.FlowID) (Checkpoint, error) {
+	f, err := r.dir.Open(checkpointFileName(flow))
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	defer f.Close()
+
+	var checkpoint Checkpoint
+	if err := json.NewDecoder(f).Decode(&checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("the checkpoint for the \"%s\" flow is invalid: %w", flow, err)
+	}
+	if checkpoint.Flow != flow {
+		return Checkpoint{}, fmt.Errorf("the checkpoint for the \"%s\" flow belongs to the \"%s\" flow", flow, checkpoint.Flow)
+	}
+
+	return checkpoint, nil
+}
+
+// WriteCheckpoint records the given checkpoint, replacing any checkpoint
+// that was previously recorded for the same flow.
+func (r DeploymentDir) WriteCheckpoint(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that an interruption doesn't
+	// leave a partial checkpoint behind.
+	name := checkpointFileName(checkpoint.Flow)
+	temp := name + ".tmp"
+	f, err := r.dir.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(filepath.Join(r.path, temp), filepath.Join(r.path, name))
+	}
+	if err != nil {
+		r.dir.Remove(temp)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveCheckpoint removes the checkpoint for the given flow, if one
+// exists.
+func (r DeploymentDir) RemoveCheckpoint(flow lbdeploy.FlowID) error {
+	if err := r.dir.Remove(checkpointFileName(flow)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -110,3 +110,51 @@ func openOrCreateRootInRoot(parent *os.Root, name string, perm os.FileMode) (*os
 	// Attempt to open the directory a second time.
 	return parent.OpenRoot(name)
 }
+
+// OpenExistingDeployment opens the staging directory for a deployment in
+// LeafBridge. Unlike OpenDeployment, it does not create the directory if it
+// is missing.
+//
+// If the directory does not exist, an error satisfying os.IsNotExist is
+// returned.
+//
+// It is the caller's responsibility to close the directory when finished
+// with it.
+func OpenExistingDeployment(id lbdeploy.DeploymentID) (DeploymentDir, error) {
+	// Look up the system's ProgramData directory path.
+	programDataPath, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
+	if err != nil {
+		return DeploymentDir{}, err
+	}
+
+	// Open the ProgramData/LeafBridge/Deploy/{DeploymentID} directory.
+	path := filepath.Join(programDataPath, RootDir, StagingDir, string(id))
+	dir, err := os.OpenRoot(path)
+	if err != nil {
+		return DeploymentDir{}, err
+	}
+
+	return DeploymentDir{
+		deployment: id,
+		path:       path,
+		dir:        dir,
+	}, nil
+}
+
+// OpenExistingPackage opens the staging directory for the given package
+// content. Unlike OpenPackage, it does not create the directory if it is
+// missing.
+//
+// It is the caller's responsibility to close the directory when finished
+// with it.
+func (r DeploymentDir) OpenExistingPackage(content lbdeploy.PackageContent) (PackageDir, error) {
+	dir, err := r.dir.OpenRoot(content.String())
+	if err != nil {
+		return PackageDir{}, err
+	}
+	return PackageDir{
+		content: content,
+		path:    filepath.Join(r.path, content.String()),
+		dir:     dir,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package stagingfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/filehash"
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// HashState records the progress of hashing a partially downloaded package
+// file, so that an interrupted download can be resumed without reading the
+// file's existing content again.
+//
+// Size is the number of bytes at the start of the file that have been
+// absorbed into the hashes. Hashes holds the marshaled internal state of
+// each hash, not its sum.
+type HashState struct {
+	Size    int64                    `json:"size"`
+	Hashes  map[filehash.Type][]byte `json:"hashes"`
+	Updated time.Time                `json:"updated"`
+}
+
+// hashStatePath returns the path of the hash state file for the package
+// file.
+func (f PackageFile) hashStatePath() string {
+	return f.Path + ".hash-state.json"
+}
+
+// ReadHashState reads the hash state that was recorded for the package
+// file.
+//
+// If a hash state has not been recorded, an error satisfying os.IsNotExist
+// is returned.
+func (f PackageFile) ReadHashState() (HashState, error) {
+	data, err := os.ReadFile(f.hashStatePath())
+	if err != nil {
+		return HashState{}, err
+	}
+
+	var state HashState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return HashState{}, fmt.Errorf("the hash state for the \"%s\" package file is invalid: %w", f.Name, err)
+	}
+
+	return state, nil
+}
+
+// WriteHashState records the given hash state for the package file,
+// replacing any hash state that was previously recorded.
+func (f PackageFile) WriteHashState(state HashState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that an interruption doesn't
+	// leave a partial hash state behind.
+	path := f.hashStatePath()
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveHashState removes the hash state that was recorded for the package
+// file, if there is one.
+func (f PackageFile) RemoveHashState() error {
+	if err := os.Remove(f.hashStatePath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// verifiedPath returns the path of the file that records the verified
+// attributes of the package file.
+func (f PackageFile) verifiedPath() string {
+	return f.Path + verifiedSuffix
+}
+
+// WriteVerified records that the package file has been verified and found
+// to match the given attributes. The record allows the package file to be
+// shared with other systems by its content hash.
+func (f PackageFile) WriteVerified(attrs lbdeploy.FileAttributes) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+
+	path := f.verifiedPath()
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveVerified removes the record of the package file's verification, if
+// there is one. It must be called before the package file is modified.
+func (f PackageFile) RemoveVerified() error {
+	if err := os.Remove(f.verifiedPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
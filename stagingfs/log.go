@@ -0,0 +1,110 @@
+package stagingfs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// LogDir is the name of the directory within a deployment's staging
+// directory that holds command logs. Logs for package commands are kept in
+// a subdirectory for each package.
+const LogDir = "logs"
+
+// logTimeFormat is the format of the time at the start of log file names.
+const logTimeFormat = "20060102T150405.000Z"
+
+// CommandLogPath returns the path of a new log file for the given command.
+// The log directory is created if it does not already exist. If pkg is
+// empty, the command is not affiliated with a package.
+//
+// The file name includes the time of the command, so that earlier logs
+// for the same command are not replaced.
+func (r DeploymentDir) CommandLogPath(pkg lbdeploy.PackageID, command lbdeploy.CommandID, when time.Time) (string, error) {
+	dir, err := r.logDir(pkg, true)
+	if err != nil {
+		return "", err
+	}
+	name := when.UTC().Format(logTimeFormat) + commandLogSuffix(command)
+	return filepath.Join(r.path, dir, name), nil
+}
+
+// PruneCommandLogs removes all but the most recent keep logs for the
+// given command. It returns the paths of the logs that were removed.
+func (r DeploymentDir) PruneCommandLogs(pkg lbdeploy.PackageID, command lbdeploy.CommandID, keep int) (removed []string, err error) {
+	dir, err := r.logDir(pkg, false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(r.dir.FS(), filepath.ToSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	// Log file names begin with their creation time, so sorting them by
+	// name puts them in chronological order.
+	suffix := commandLogSuffix(command)
+	var logs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && len(name) == len(logTimeFormat)+len(suffix) && strings.HasSuffix(name, suffix) {
+			logs = append(logs, name)
+		}
+	}
+	slices.Sort(logs)
+
+	if len(logs) <= keep {
+		return nil, nil
+	}
+
+	for _, name := range logs[:len(logs)-keep] {
+		if err := r.dir.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, fmt.Errorf("failed to remove command log \"%s\": %w", name, err)
+		}
+		removed = append(removed, filepath.Join(r.path, dir, name))
+	}
+
+	return removed, nil
+}
+
+// logDir returns the path of the log directory for pkg, relative to the
+// deployment's staging directory. If create is true, the directory is
+// created if it does not already exist.
+func (r DeploymentDir) logDir(pkg lbdeploy.PackageID, create bool) (string, error) {
+	dir := LogDir
+	if pkg != "" {
+		dir = filepath.Join(LogDir, safeFileName(string(pkg)))
+	}
+
+	if !create {
+		if _, err := r.dir.Stat(dir); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	if err := r.dir.Mkdir(LogDir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	if pkg != "" {
+		if err := r.dir.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// commandLogSuffix returns the suffix of the log file names for command.
+func commandLogSuffix(command lbdeploy.CommandID) string {
+	return fmt.Sprintf(".%s.log", safeFileName(string(command)))
+}
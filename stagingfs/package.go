@@ -65,6 +65,31 @@ func (d PackageDir) OpenFile(pkg lbdeploy.Package) (PackageFile, error) {
 	}, nil
 }
 
+// OpenExistingFile opens the staging file for the given package in read-only
+// mode. Unlike OpenFile, it does not create the file if it is missing.
+//
+// It is the caller's responsibility to close the file when finished with it.
+func (d PackageDir) OpenExistingFile(pkg lbdeploy.Package) (PackageFile, error) {
+	// Localize the file path, which ensures that it conforms to the
+	// local file system path separators and is in fact a relative path.
+	localized, err := filepath.Localize(pkg.FileName())
+	if err != nil {
+		return PackageFile{}, fmt.Errorf("localization of the package file name failed: %w", err)
+	}
+
+	f, err := d.dir.Open(localized)
+	if err != nil {
+		return PackageFile{}, err
+	}
+	return PackageFile{
+		Name:   localized,
+		Type:   pkg.Type,
+		Format: pkg.Format,
+		Path:   filepath.Join(d.path, localized),
+		File:   f,
+	}, nil
+}
+
 // Close releases any file handles or resources held by the package
 // staging directory.
 func (d PackageDir) Close() error {
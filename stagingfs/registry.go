@@ -0,0 +1,42 @@
+package stagingfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// RegistryBackupDir is the name of the directory within a deployment's
+// staging directory that holds backups of registry keys.
+const RegistryBackupDir = "registry-backups"
+
+// RegistryBackupPath returns the path of a new backup file for the key
+// that holds the given registry value. The backup directory is created if
+// it does not already exist.
+//
+// The file name includes the time of the backup, so that earlier backups
+// of the same key are not replaced.
+func (r DeploymentDir) RegistryBackupPath(value lbdeploy.RegistryValueResourceID, when time.Time) (string, error) {
+	if err := r.dir.Mkdir(RegistryBackupDir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	name := fmt.Sprintf("%s.%s.reg", when.UTC().Format("20060102T150405.000Z"), safeFileName(string(value)))
+	return filepath.Join(r.path, RegistryBackupDir, name), nil
+}
+
+// safeFileName replaces characters in s that are not safe to use in a file
+// name with underscores.
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
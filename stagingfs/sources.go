@@ -0,0 +1,100 @@
+package stagingfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+)
+
+// sourceJournalFileName is the name of the file within a deployment's
+// staging directory that holds its source journal.
+const sourceJournalFileName = "sources.json"
+
+// SourceJournal records the download history of the package sources used
+// by a deployment, so that the health of each source can be tracked
+// across invocations.
+//
+// Sources are mapped by their URLs.
+type SourceJournal struct {
+	Sources map[string]SourceStats `json:"sources,omitempty"`
+}
+
+// SourceStats holds the download statistics for a package source.
+//
+// Failures counts downloads that could not be completed. Rejections counts
+// downloads that were completed but failed verification.
+// ConsecutiveFailures counts both, and is reset by a successful download.
+type SourceStats struct {
+	Successes           int               `json:"successes,omitempty"`
+	Failures            int               `json:"failures,omitempty"`
+	Rejections          int               `json:"rejections,omitempty"`
+	ConsecutiveFailures int               `json:"consecutive-failures,omitempty"`
+	Bytes               int64             `json:"bytes,omitempty"`
+	Duration            datatype.Duration `json:"duration,omitempty"`
+	LastSuccess         time.Time         `json:"last-success,omitzero"`
+	LastFailure         time.Time         `json:"last-failure,omitzero"`
+	LastError           string            `json:"last-error,omitempty"`
+}
+
+// Throughput returns the average number of bytes per second that have been
+// downloaded from the source. It returns zero if nothing has been
+// downloaded.
+func (stats SourceStats) Throughput() float64 {
+	if stats.Bytes <= 0 || stats.Duration <= 0 {
+		return 0
+	}
+	return float64(stats.Bytes) / time.Duration(stats.Duration).Seconds()
+}
+
+// ReadSourceJournal reads the source journal for the deployment.
+//
+// If a source journal has not been recorded, an error satisfying
+// os.IsNotExist is returned.
+func (r DeploymentDir) ReadSourceJournal() (SourceJournal, error) {
+	f, err := r.dir.Open(sourceJournalFileName)
+	if err != nil {
+		return SourceJournal{}, err
+	}
+	defer f.Close()
+
+	var journal SourceJournal
+	if err := json.NewDecoder(f).Decode(&journal); err != nil {
+		return SourceJournal{}, fmt.Errorf("the source journal for the \"%s\" deployment is invalid: %w", r.deployment, err)
+	}
+
+	return journal, nil
+}
+
+// WriteSourceJournal records the given source journal, replacing any
+// source journal that was previously recorded.
+func (r DeploymentDir) WriteSourceJournal(journal SourceJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that an interruption doesn't
+	// leave a partial journal behind.
+	temp := sourceJournalFileName + ".tmp"
+	f, err := r.dir.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(filepath.Join(r.path, temp), filepath.Join(r.path, sourceJournalFileName))
+	}
+	if err != nil {
+		r.dir.Remove(temp)
+		return err
+	}
+
+	return nil
+}
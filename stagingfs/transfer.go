@@ -0,0 +1,90 @@
+package stagingfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TransferJob records a background transfer job that is downloading a
+// package file, so that the transfer can be picked up again after the
+// process or the system restarts.
+//
+// ID identifies the job and URL is the address it downloads from. If
+// Completed is true, the job has finished and the transferred file is
+// waiting at the package file's transfer path.
+type TransferJob struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Completed bool      `json:"completed,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// transferJobPath returns the path of the file that records the transfer
+// job for the package file.
+func (f PackageFile) transferJobPath() string {
+	return f.Path + ".transfer-job.json"
+}
+
+// TransferPath returns the path that a background transfer job writes the
+// package file to. The file is copied into the package file once the
+// transfer has finished.
+func (f PackageFile) TransferPath() string {
+	return f.Path + ".transfer"
+}
+
+// ReadTransferJob reads the transfer job that was recorded for the package
+// file.
+//
+// If a transfer job has not been recorded, an error satisfying
+// os.IsNotExist is returned.
+func (f PackageFile) ReadTransferJob() (TransferJob, error) {
+	data, err := os.ReadFile(f.transferJobPath())
+	if err != nil {
+		return TransferJob{}, err
+	}
+
+	var job TransferJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return TransferJob{}, fmt.Errorf("the transfer job for the \"%s\" package file is invalid: %w", f.Name, err)
+	}
+
+	return job, nil
+}
+
+// WriteTransferJob records the given transfer job for the package file,
+// replacing any transfer job that was previously recorded.
+func (f PackageFile) WriteTransferJob(job TransferJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	path := f.transferJobPath()
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveTransferJob removes the transfer job that was recorded for the
+// package file, along with any file it transferred.
+func (f PackageFile) RemoveTransferJob() error {
+	err1 := os.Remove(f.transferJobPath())
+	if errors.Is(err1, os.ErrNotExist) {
+		err1 = nil
+	}
+	err2 := os.Remove(f.TransferPath())
+	if errors.Is(err2, os.ErrNotExist) {
+		err2 = nil
+	}
+	return errors.Join(err1, err2)
+}
@@ -0,0 +1,61 @@
+package stagingfs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"golang.org/x/sys/windows"
+)
+
+// verifiedSuffix is the suffix of the files that record the verified
+// attributes of package files.
+const verifiedSuffix = ".verified.json"
+
+// VerifiedPackage is a staged package file that has been verified.
+type VerifiedPackage struct {
+	Path       string
+	Attributes lbdeploy.FileAttributes
+}
+
+// VerifiedPackages returns the package files that have been verified
+// within the staging directories of all deployments.
+//
+// Package files whose verification records are missing or unreadable, or
+// whose size no longer matches the record, are not included.
+func VerifiedPackages() ([]VerifiedPackage, error) {
+	programDataPath, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verification records are kept alongside their package files, in the
+	// form {Deploy}/{DeploymentID}/{PackageContent}/{FileName}.verified.json.
+	pattern := filepath.Join(programDataPath, RootDir, StagingDir, "*", "pkg-*", "*"+verifiedSuffix)
+	records, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []VerifiedPackage
+	for _, record := range records {
+		data, err := os.ReadFile(record)
+		if err != nil {
+			continue
+		}
+		var attrs lbdeploy.FileAttributes
+		if err := json.Unmarshal(data, &attrs); err != nil {
+			continue
+		}
+		path := strings.TrimSuffix(record, verifiedSuffix)
+		fi, err := os.Stat(path)
+		if err != nil || fi.Size() != attrs.Size {
+			continue
+		}
+		packages = append(packages, VerifiedPackage{Path: path, Attributes: attrs})
+	}
+
+	return packages, nil
+}
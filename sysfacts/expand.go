@@ -0,0 +1,60 @@
+package sysfacts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholders that refer to facts take the form "{fact:name}", such as
+// "{fact:system.model}".
+const (
+	placeholderPrefix = "{fact:"
+	placeholderSuffix = "}"
+)
+
+// Placeholder returns the placeholder that refers to the named fact.
+func Placeholder(name Name) string {
+	return placeholderPrefix + string(name) + placeholderSuffix
+}
+
+// References returns the names of the facts that are referred to by
+// placeholders in s, in the order that they appear.
+func References(s string) []Name {
+	var names []Name
+	for {
+		start := strings.Index(s, placeholderPrefix)
+		if start < 0 {
+			return names
+		}
+		s = s[start+len(placeholderPrefix):]
+		end := strings.Index(s, placeholderSuffix)
+		if end < 0 {
+			return names
+		}
+		names = append(names, Name(s[:end]))
+		s = s[end+len(placeholderSuffix):]
+	}
+}
+
+// Expand replaces each fact placeholder in s with the value of the fact.
+// It returns an error if s refers to a fact that is not recognized or that
+// could not be determined.
+func Expand(s string, facts Facts) (string, error) {
+	names := References(s)
+	if len(names) == 0 {
+		return s, nil
+	}
+
+	for _, name := range names {
+		if err := name.Validate(); err != nil {
+			return "", err
+		}
+		value, ok := facts.Get(name)
+		if !ok {
+			return "", fmt.Errorf("the \"%s\" fact could not be determined on this computer", name)
+		}
+		s = strings.ReplaceAll(s, Placeholder(name), value.String())
+	}
+
+	return s, nil
+}
@@ -0,0 +1,141 @@
+// Package sysfacts gathers facts about the local computer, such as its
+// manufacturer, model, operating system, languages, disks, TPM and graphics
+// adapters.
+//
+// Facts are gathered from the same sources that back the Windows
+// Management Instrumentation inventory classes, including the SMBIOS data
+// published in the registry, the operating system version information and
+// the TPM base services. They are gathered once per invocation and cached.
+//
+// WMI itself is not queried. A query requires COM to be initialized on a
+// dedicated thread and depends on the WMI service, which is slow to start
+// and is often the component that is broken on the computers a deployment
+// is meant to repair. Reading the underlying sources directly keeps fact
+// gathering fast and independent of that service. When the SMBIOS values in
+// the registry are empty, they are read from the raw SMBIOS table, which is
+// what Win32_ComputerSystem and Win32_BIOS report.
+package sysfacts
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+)
+
+// Name is the name of a fact, such as "system.model".
+type Name string
+
+// Known facts.
+const (
+	ComputerName Name = "computer.name"
+
+	SystemManufacturer Name = "system.manufacturer"
+	SystemModel        Name = "system.model"
+	SystemFamily       Name = "system.family"
+	BIOSVendor         Name = "bios.vendor"
+	BIOSVersion        Name = "bios.version"
+
+	OSName           Name = "os.name"
+	OSEdition        Name = "os.edition"
+	OSDisplayVersion Name = "os.display-version"
+	OSVersion        Name = "os.version"
+	OSBuild          Name = "os.build"
+	OSArchitecture   Name = "os.architecture"
+	OSLanguage       Name = "os.language"
+	OSLanguages      Name = "os.languages"
+
+	MemoryTotal Name = "memory.total"
+
+	DiskFixed      Name = "disk.fixed"
+	DiskSystemSize Name = "disk.system.size"
+	DiskSystemFree Name = "disk.system.free"
+
+	TPMPresent Name = "tpm.present"
+	TPMVersion Name = "tpm.version"
+
+	GPUNames Name = "gpu.names"
+)
+
+// Names returns the names of all known facts, in sorted order.
+func Names() []Name {
+	names := []Name{
+		ComputerName,
+		SystemManufacturer,
+		SystemModel,
+		SystemFamily,
+		BIOSVendor,
+		BIOSVersion,
+		OSName,
+		OSEdition,
+		OSDisplayVersion,
+		OSVersion,
+		OSBuild,
+		OSArchitecture,
+		OSLanguage,
+		OSLanguages,
+		MemoryTotal,
+		DiskFixed,
+		DiskSystemSize,
+		DiskSystemFree,
+		TPMPresent,
+		TPMVersion,
+		GPUNames,
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Validate returns a non-nil error if the fact name is not recognized.
+func (name Name) Validate() error {
+	if name == "" {
+		return errors.New("a fact name was not provided")
+	}
+	if !slices.Contains(Names(), name) {
+		return fmt.Errorf("the \"%s\" fact is not recognized", name)
+	}
+	return nil
+}
+
+// Facts holds a snapshot of facts about the local computer, mapped by
+// name. Facts that could not be determined are absent.
+type Facts map[Name]lbvalue.Value
+
+// Get returns the value of the named fact. It returns false if the fact
+// could not be determined.
+func (facts Facts) Get(name Name) (value lbvalue.Value, ok bool) {
+	value, ok = facts[name]
+	return
+}
+
+// Names returns the names of the facts that are present, in sorted order.
+func (facts Facts) Names() []Name {
+	return slices.Sorted(maps.Keys(facts))
+}
+
+// snapshot holds the cached result of Gather.
+var snapshot = sync.OnceValues(Gather)
+
+// Snapshot returns the facts about the local computer. The facts are
+// gathered the first time Snapshot is called, and the same snapshot is
+// returned by each call that follows.
+//
+// If some facts could not be gathered, the facts that were gathered are
+// returned along with a non-nil error.
+func Snapshot() (Facts, error) {
+	facts, err := snapshot()
+	return maps.Clone(facts), err
+}
+
+// Languages returns the system's preferred UI languages, in order of
+// preference. It returns nil if they could not be determined.
+func Languages() []string {
+	facts, _ := Snapshot()
+	if value, ok := facts.Get(OSLanguages); ok {
+		return value.Strings()
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package sysfacts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/leafbridge/leafbridge-deploy/datatype"
+	"github.com/leafbridge/leafbridge-deploy/lbvalue"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var (
+	modkernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procGlobalMemoryStatusEx = modkernel32.NewProc("GlobalMemoryStatusEx")
+
+	modtbs                = windows.NewLazySystemDLL("tbs.dll")
+	procTbsiGetDeviceInfo = modtbs.NewProc("Tbsi_GetDeviceInfo")
+)
+
+// TPM base services values.
+const (
+	tbsErrTPMNotFound = 0x8028400F
+	tpmVersion12      = 1
+	tpmVersion20      = 2
+)
+
+// Registry keys that hold facts.
+const (
+	biosKey        = `HARDWARE\DESCRIPTION\System\BIOS`
+	currentVersion = `SOFTWARE\Microsoft\Windows NT\CurrentVersion`
+	environmentKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+	displayClass   = `SYSTEM\CurrentControlSet\Control\Class\{4d36e968-e325-11ce-bfc1-08002be10318}`
+)
+
+// Gather collects facts about the local computer.
+//
+// Most callers should use Snapshot instead, which gathers the facts once
+// and caches them.
+//
+// If some facts could not be gathered, the facts that were gathered are
+// returned along with a non-nil error.
+func Gather() (Facts, error) {
+	facts := make(Facts)
+
+	gatherers := []struct {
+		name   string
+		gather func(Facts) error
+	}{
+		{"computer", gatherComputer},
+		{"system", gatherSystem},
+		{"operating system", gatherOS},
+		{"language", gatherLanguages},
+		{"memory", gatherMemory},
+		{"disk", gatherDisks},
+		{"tpm", gatherTPM},
+		{"graphics adapter", gatherGPUs},
+	}
+
+	var errs []error
+	for _, g := range gatherers {
+		if err := g.gather(facts); err != nil {
+			errs = append(errs, fmt.Errorf("%s facts: %w", g.name, err))
+		}
+	}
+
+	return facts, errors.Join(errs...)
+}
+
+func gatherComputer(facts Facts) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	facts[ComputerName] = lbvalue.String(hostname)
+	return nil
+}
+
+// gatherSystem records the manufacturer, model and firmware of the
+// computer. The values that Windows copies from SMBIOS into the registry are
+// read first, and any that are missing are read from the SMBIOS table
+// itself.
+func gatherSystem(facts Facts) error {
+	key, keyErr := registry.OpenKey(registry.LOCAL_MACHINE, biosKey, registry.QUERY_VALUE)
+	if keyErr == nil {
+		readString(key, "SystemManufacturer", SystemManufacturer, facts)
+		readString(key, "SystemProductName", SystemModel, facts)
+		readString(key, "SystemFamily", SystemFamily, facts)
+		readString(key, "BIOSVendor", BIOSVendor, facts)
+		readString(key, "BIOSVersion", BIOSVersion, facts)
+		key.Close()
+	}
+
+	var missing []smbiosString
+	for _, f := range smbiosFacts {
+		if _, ok := facts[f.Name]; !ok {
+			missing = append(missing, f.String)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	table, err := readSMBIOS()
+	if err != nil {
+		return errors.Join(keyErr, err)
+	}
+	found := smbiosStrings(table, missing)
+	for _, f := range smbiosFacts {
+		if s, ok := found[f.String]; ok {
+			facts[f.Name] = lbvalue.String(s)
+		}
+	}
+
+	return nil
+}
+
+func gatherOS(facts Facts) error {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, currentVersion, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	readString(key, "ProductName", OSName, facts)
+	readString(key, "EditionID", OSEdition, facts)
+	readString(key, "DisplayVersion", OSDisplayVersion, facts)
+
+	// The version reported by RtlGetVersion isn't subject to application
+	// compatibility shims. The update build revision is only available
+	// from the registry.
+	info := windows.RtlGetVersion()
+	version := fmt.Sprintf("%d.%d.%d", info.MajorVersion, info.MinorVersion, info.BuildNumber)
+	if ubr, _, err := key.GetIntegerValue("UBR"); err == nil {
+		version = fmt.Sprintf("%s.%d", version, ubr)
+	}
+	facts[OSVersion] = lbvalue.Version(datatype.Version(version))
+	facts[OSBuild] = lbvalue.Int64(int64(info.BuildNumber))
+
+	env, err := registry.OpenKey(registry.LOCAL_MACHINE, environmentKey, registry.QUERY_VALUE)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	if arch, _, err := env.GetStringValue("PROCESSOR_ARCHITECTURE"); err == nil && arch != "" {
+		facts[OSArchitecture] = lbvalue.String(strings.ToLower(arch))
+	}
+
+	return nil
+}
+
+// gatherLanguages records the system's preferred UI languages, such as
+// "en-US", in order of preference.
+func gatherLanguages(facts Facts) error {
+	languages, err := windows.GetSystemPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil {
+		return err
+	}
+	if len(languages) == 0 {
+		return nil
+	}
+	facts[OSLanguage] = lbvalue.String(languages[0])
+	facts[OSLanguages] = lbvalue.Strings(languages...)
+	return nil
+}
+
+// memoryStatusEx is the MEMORYSTATUSEX structure.
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+func gatherMemory(facts Facts) error {
+	if err := procGlobalMemoryStatusEx.Find(); err != nil {
+		return err
+	}
+
+	status := memoryStatusEx{Length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+	r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if r == 0 {
+		return err
+	}
+
+	facts[MemoryTotal] = lbvalue.Int64(int64(status.TotalPhys))
+	return nil
+}
+
+func gatherDisks(facts Facts) error {
+	// Collect the root paths of fixed drives, such as "C:\".
+	buf := make([]uint16, 256)
+	n, err := windows.GetLogicalDriveStrings(uint32(len(buf)), &buf[0])
+	if err != nil {
+		return err
+	}
+	if int(n) > len(buf) {
+		buf = make([]uint16, n)
+		if n, err = windows.GetLogicalDriveStrings(uint32(len(buf)), &buf[0]); err != nil {
+			return err
+		}
+	}
+
+	var fixed []string
+	for _, root := range strings.Split(windows.UTF16ToString(buf[:n]), "\x00") {
+		if root == "" {
+			continue
+		}
+		rootPtr, err := windows.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		if windows.GetDriveType(rootPtr) == windows.DRIVE_FIXED {
+			fixed = append(fixed, root)
+		}
+	}
+	facts[DiskFixed] = lbvalue.Strings(fixed...)
+
+	// Determine the size of the volume that holds Windows.
+	windowsDir, err := windows.GetSystemWindowsDirectory()
+	if err != nil {
+		return err
+	}
+	systemRoot, err := windows.UTF16PtrFromString(filepath.VolumeName(windowsDir) + `\`)
+	if err != nil {
+		return err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(systemRoot, &available, &total, &free); err != nil {
+		return err
+	}
+	facts[DiskSystemSize] = lbvalue.Int64(int64(total))
+	facts[DiskSystemFree] = lbvalue.Int64(int64(free))
+
+	return nil
+}
+
+// tpmDeviceInfo is the TPM_DEVICE_INFO structure.
+type tpmDeviceInfo struct {
+	StructVersion    uint32
+	TPMVersion       uint32
+	TPMInterfaceType uint32
+	TPMImpRevision   uint32
+}
+
+func gatherTPM(facts Facts) error {
+	if err := procTbsiGetDeviceInfo.Find(); err != nil {
+		return err
+	}
+
+	var info tpmDeviceInfo
+	r, _, _ := procTbsiGetDeviceInfo.Call(uintptr(unsafe.Sizeof(info)), uintptr(unsafe.Pointer(&info)))
+	switch r {
+	case 0:
+	case tbsErrTPMNotFound:
+		facts[TPMPresent] = lbvalue.Bool(false)
+		return nil
+	default:
+		return fmt.Errorf("the TPM device information could not be retrieved (0x%08X)", r)
+	}
+
+	facts[TPMPresent] = lbvalue.Bool(true)
+	switch info.TPMVersion {
+	case tpmVersion12:
+		facts[TPMVersion] = lbvalue.Version("1.2")
+	case tpmVersion20:
+		facts[TPMVersion] = lbvalue.Version("2.0")
+	}
+
+	return nil
+}
+
+func gatherGPUs(facts Facts) error {
+	class, err := registry.OpenKey(registry.LOCAL_MACHINE, displayClass, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return err
+	}
+	defer class.Close()
+
+	subkeys, err := class.ReadSubKeyNames(-1)
+	if err != nil {
+		return err
+	}
+
+	// Each adapter is recorded in a numbered subkey, such as "0000".
+	var names []string
+	for _, subkey := range subkeys {
+		if len(subkey) != 4 {
+			continue
+		}
+		func() {
+			adapter, err := registry.OpenKey(class, subkey, registry.QUERY_VALUE)
+			if err != nil {
+				return
+			}
+			defer adapter.Close()
+			if name, _, err := adapter.GetStringValue("DriverDesc"); err == nil && name != "" {
+				names = append(names, name)
+			}
+		}()
+	}
+	facts[GPUNames] = lbvalue.Strings(names...)
+
+	return nil
+}
+
+// readString reads a string value from key and records it as the named
+// fact. Missing and empty values are not recorded.
+func readString(key registry.Key, value string, name Name, facts Facts) {
+	if s, _, err := key.GetStringValue(value); err == nil {
+		if s = strings.TrimSpace(s); s != "" {
+			facts[name] = lbvalue.String(s)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package sysfacts
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+var procGetSystemFirmwareTable = modkernel32.NewProc("GetSystemFirmwareTable")
+
+// firmwareRSMB is the provider signature for the raw SMBIOS table, which is
+// 'RSMB' as a big-endian integer.
+const firmwareRSMB = 'R'<<24 | 'S'<<16 | 'M'<<8 | 'B'
+
+// SMBIOS structure types.
+const (
+	smbiosBIOSInformation   = 0
+	smbiosSystemInformation = 1
+	smbiosEndOfTable        = 127
+)
+
+// smbiosString identifies a string within an SMBIOS structure by the
+// structure's type and the offset of the string's index within it.
+type smbiosString struct {
+	Type   byte
+	Offset int
+}
+
+// smbiosFacts maps facts to the SMBIOS strings that hold them. These are
+// the same strings that Win32_ComputerSystem and Win32_BIOS report.
+var smbiosFacts = []struct {
+	Name   Name
+	String smbiosString
+}{
+	{SystemManufacturer, smbiosString{smbiosSystemInformation, 0x04}},
+	{SystemModel, smbiosString{smbiosSystemInformation, 0x05}},
+	{SystemFamily, smbiosString{smbiosSystemInformation, 0x1A}},
+	{BIOSVendor, smbiosString{smbiosBIOSInformation, 0x04}},
+	{BIOSVersion, smbiosString{smbiosBIOSInformation, 0x05}},
+}
+
+// readSMBIOS returns the raw SMBIOS structure table of the local computer.
+func readSMBIOS() ([]byte, error) {
+	if err := procGetSystemFirmwareTable.Find(); err != nil {
+		return nil, err
+	}
+
+	size, _, err := procGetSystemFirmwareTable.Call(firmwareRSMB, 0, 0, 0)
+	if size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	n, _, err := procGetSystemFirmwareTable.Call(firmwareRSMB, 0, uintptr(unsafe.Pointer(&buf[0])), size)
+	if n == 0 {
+		return nil, err
+	}
+	if n > size {
+		return nil, errors.New("the SMBIOS table grew while it was being read")
+	}
+	buf = buf[:n]
+
+	// The table is preceded by the RawSMBIOSData header, which ends with
+	// the length of the table.
+	const headerSize = 8
+	if len(buf) < headerSize {
+		return nil, errors.New("the SMBIOS data is truncated")
+	}
+	length := int(binary.LittleEndian.Uint32(buf[4:8]))
+	if length > len(buf)-headerSize {
+		return nil, errors.New("the SMBIOS table is truncated")
+	}
+
+	return buf[headerSize : headerSize+length], nil
+}
+
+// smbiosStrings returns the value of each requested string within table.
+// Strings that are missing or empty are omitted.
+func smbiosStrings(table []byte, wanted []smbiosString) map[smbiosString]string {
+	found := make(map[smbiosString]string)
+	for len(table) >= 4 {
+		// Each structure starts with a formatted area of the given length,
+		// followed by a set of strings that ends with a double null.
+		typ, length := table[0], int(table[1])
+		if length < 4 || length > len(table) {
+			break
+		}
+		formatted := table[:length]
+		end := strings.Index(string(table[length:]), "\x00\x00")
+		if end < 0 {
+			break
+		}
+		strs := strings.Split(string(table[length:length+end]), "\x00")
+
+		for _, w := range wanted {
+			if w.Type != typ || w.Offset >= len(formatted) {
+				continue
+			}
+			index := int(formatted[w.Offset])
+			if index == 0 || index > len(strs) {
+				continue
+			}
+			if s := strings.TrimSpace(strs[index-1]); s != "" {
+				found[w] = s
+			}
+		}
+
+		if typ == smbiosEndOfTable {
+			break
+		}
+		table = table[length+end+2:]
+	}
+	return found
+}
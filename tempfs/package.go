@@ -41,25 +41,11 @@ type ExtractionDir struct {
 //
 // TODO: Make the options variadic.
 func OpenExtractionDirForPackage(pkg lbdeploy.PackageContent, opts Options) (ExtractionDir, error) {
-	// Unfortunately, this returns a path instead of an open directory handle.
-	dirPath, err := os.MkdirTemp("", "leafbridge-"+pkg.String())
+	dirPath, err := mkdirTemp("leafbridge-" + pkg.String())
 	if err != nil {
 		return ExtractionDir{}, err
 	}
 
-	// Sanity check the directory path to make sure it conforms to our
-	// expectations. If it doesn't, then return an error.
-	//
-	// Note that We might call os.RemoveAll() on the path later, and we really
-	// don't want to make that call on an unintended path, especially when
-	// operating with SYSTEM privileges.
-	{
-		dirPath := strings.ToLower(dirPath) // Case-insensitive search
-		if !strings.Contains(dirPath, "leafbridge") || !strings.Contains(dirPath, "temp") {
-			return ExtractionDir{}, fmt.Errorf("the os.MkdirTemp call failed to create a directory with the expected format: %s", dirPath)
-		}
-	}
-
 	// Open the root of the newly created temp directory.
 	dir, err := os.OpenRoot(dirPath)
 	if err != nil {
@@ -210,3 +196,31 @@ func (d ExtractionDir) Close() error {
 
 	return errors.Join(err1, err2)
 }
+
+// mkdirTemp creates a new temporary directory via os.MkdirTemp and returns
+// its path.
+//
+// It returns an error if the path of the new directory doesn't conform to
+// our expectations.
+func mkdirTemp(pattern string) (string, error) {
+	// Unfortunately, this returns a path instead of an open directory handle.
+	dirPath, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// Sanity check the directory path to make sure it conforms to our
+	// expectations. If it doesn't, then return an error.
+	//
+	// Note that We might call os.RemoveAll() on the path later, and we really
+	// don't want to make that call on an unintended path, especially when
+	// operating with SYSTEM privileges.
+	{
+		dirPath := strings.ToLower(dirPath) // Case-insensitive search
+		if !strings.Contains(dirPath, "leafbridge") || !strings.Contains(dirPath, "temp") {
+			return "", fmt.Errorf("the os.MkdirTemp call failed to create a directory with the expected format: %s", dirPath)
+		}
+	}
+
+	return dirPath, nil
+}
@@ -0,0 +1,39 @@
+package tempfs
+
+import (
+	"os"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+)
+
+// WorkingDir is a temporary working directory for a command in LeafBridge.
+//
+// It is a temporary directory created via os.MkdirTemp. Its name will have
+// "leafbridge-" as a prefix. It is empty when created, and it is deleted
+// along with its contents when closed.
+type WorkingDir struct {
+	path string
+}
+
+// OpenWorkingDirForCommand creates an empty temporary directory to be used
+// as the working directory of a command.
+//
+// It is the caller's responsibility to close the returned directory when
+// finished with it.
+func OpenWorkingDirForCommand(command lbdeploy.CommandID) (WorkingDir, error) {
+	dirPath, err := mkdirTemp("leafbridge-" + string(command) + "-")
+	if err != nil {
+		return WorkingDir{}, err
+	}
+	return WorkingDir{path: dirPath}, nil
+}
+
+// Path returns the path to the working directory.
+func (d WorkingDir) Path() string {
+	return d.path
+}
+
+// Close deletes the directory and all of its contents.
+func (d WorkingDir) Close() error {
+	return os.RemoveAll(d.path)
+}
@@ -0,0 +1,100 @@
+package userprofile
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var (
+	modadvapi32      = windows.NewLazySystemDLL("advapi32.dll")
+	procRegLoadKey   = modadvapi32.NewProc("RegLoadKeyW")
+	procRegUnLoadKey = modadvapi32.NewProc("RegUnLoadKeyW")
+)
+
+// loadKey loads the registry hive stored in file as a subkey of key.
+func loadKey(key registry.Key, subkey, file string) error {
+	subkeyPtr, err := windows.UTF16PtrFromString(subkey)
+	if err != nil {
+		return err
+	}
+	filePtr, err := windows.UTF16PtrFromString(file)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegLoadKey.Call(uintptr(key), uintptr(unsafe.Pointer(subkeyPtr)), uintptr(unsafe.Pointer(filePtr)))
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// unloadKey unloads a registry hive that was loaded as a subkey of key.
+func unloadKey(key registry.Key, subkey string) error {
+	subkeyPtr, err := windows.UTF16PtrFromString(subkey)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegUnLoadKey.Call(uintptr(key), uintptr(unsafe.Pointer(subkeyPtr)))
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+var (
+	hivePrivilegesOnce sync.Once
+	hivePrivilegesErr  error
+)
+
+// enableHivePrivileges enables the backup and restore privileges that are
+// needed to load and unload registry hives. The privileges are enabled for
+// the lifetime of the process.
+func enableHivePrivileges() error {
+	hivePrivilegesOnce.Do(func() {
+		hivePrivilegesErr = enablePrivileges("SeBackupPrivilege", "SeRestorePrivilege")
+	})
+	return hivePrivilegesErr
+}
+
+// enablePrivileges enables the named privileges in the process token.
+func enablePrivileges(names ...string) error {
+	// Stay on one thread so that the last error can be inspected after
+	// each adjustment.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+		return err
+	}
+	defer token.Close()
+
+	for _, name := range names {
+		namePtr, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			return err
+		}
+
+		privileges := windows.Tokenprivileges{PrivilegeCount: 1}
+		if err := windows.LookupPrivilegeValue(nil, namePtr, &privileges.Privileges[0].Luid); err != nil {
+			return err
+		}
+		privileges.Privileges[0].Attributes = windows.SE_PRIVILEGE_ENABLED
+
+		if err := windows.AdjustTokenPrivileges(token, false, &privileges, 0, nil, nil); err != nil {
+			return err
+		}
+
+		// AdjustTokenPrivileges succeeds without enabling privileges that
+		// the token doesn't hold.
+		if errno := windows.GetLastError(); errno == windows.ERROR_NOT_ALL_ASSIGNED {
+			return errno
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// Package userprofile enumerates the user profiles on the local system and
+// provides access to their registry hives.
+package userprofile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const profileListPath = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList`
+
+// Profile is a user profile on the local system.
+type Profile struct {
+	// SID is the security identifier of the user that owns the profile. It
+	// is empty for the default profile.
+	SID string
+
+	// Path is the absolute path of the profile's directory.
+	Path string
+}
+
+// IsDefault returns true if p is the default profile, which new user
+// profiles are created from.
+func (p Profile) IsDefault() bool {
+	return p.SID == ""
+}
+
+// String returns a string representation of the profile.
+func (p Profile) String() string {
+	if p.IsDefault() {
+		return "default"
+	}
+	return p.SID
+}
+
+// List returns the profiles of the local user accounts and domain users
+// that have signed in to the system, followed by the default profile.
+//
+// Profiles of well-known service accounts are not included.
+func List() ([]Profile, error) {
+	list, err := registry.OpenKey(registry.LOCAL_MACHINE, profileListPath, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, err
+	}
+	defer list.Close()
+
+	sids, err := list.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	for _, sid := range sids {
+		// Only include the profiles of real users.
+		if !strings.HasPrefix(sid, "S-1-5-21-") {
+			continue
+		}
+
+		path, err := profilePath(list, sid)
+		if err != nil {
+			return nil, fmt.Errorf("the profile of %s could not be read: %w", sid, err)
+		}
+
+		// Skip profiles that have been deleted without cleaning up the
+		// registry, and profiles whose directories are reparse points,
+		// which their users could have pointed anywhere.
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if reparse, err := isReparsePoint(path); err != nil || reparse {
+			continue
+		}
+
+		profiles = append(profiles, Profile{SID: sid, Path: path})
+	}
+
+	// Add the default profile.
+	defaultPath, _, err := list.GetStringValue("Default")
+	if err != nil {
+		return nil, fmt.Errorf("the default profile could not be located: %w", err)
+	}
+	defaultPath, err = registry.ExpandString(defaultPath)
+	if err != nil {
+		return nil, err
+	}
+	profiles = append(profiles, Profile{Path: defaultPath})
+
+	return profiles, nil
+}
+
+// profilePath returns the path of the profile directory for sid.
+func profilePath(list registry.Key, sid string) (string, error) {
+	key, err := registry.OpenKey(list, sid, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	path, _, err := key.GetStringValue("ProfileImagePath")
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", errors.New("the profile does not have a path")
+	}
+	return registry.ExpandString(path)
+}
+
+// OpenHive opens the registry hive of the profile, which is the content of
+// HKEY_CURRENT_USER when the user is signed in.
+//
+// If the user is signed in, their hive is already loaded and is opened
+// directly. Otherwise the hive is loaded from the profile's NTUSER.DAT file
+// beneath HKEY_USERS, and it is unloaded when the hive is closed. Loading a
+// hive requires elevation.
+func (p Profile) OpenHive() (*Hive, error) {
+	// Look for a hive that is already loaded.
+	if !p.IsDefault() {
+		key, err := registry.OpenKey(registry.USERS, p.SID, registry.ALL_ACCESS)
+		if err == nil {
+			return &Hive{key: key}, nil
+		}
+		if !errors.Is(err, registry.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	// Load the hive from the profile.
+	if err := enableHivePrivileges(); err != nil {
+		return nil, fmt.Errorf("the privileges needed to load registry hives could not be enabled: %w", err)
+	}
+
+	name := "LeafBridge_" + p.String()
+	if err := loadKey(registry.USERS, name, filepath.Join(p.Path, "NTUSER.DAT")); err != nil {
+		return nil, err
+	}
+
+	key, err := registry.OpenKey(registry.USERS, name, registry.ALL_ACCESS)
+	if err != nil {
+		unloadKey(registry.USERS, name)
+		return nil, err
+	}
+
+	return &Hive{key: key, loaded: name}, nil
+}
+
+// Hive is an open user registry hive.
+type Hive struct {
+	key    registry.Key
+	loaded string // The name under HKEY_USERS, if we loaded it
+}
+
+// Key returns the root key of the hive.
+func (h *Hive) Key() registry.Key {
+	return h.key
+}
+
+// Loaded returns true if the hive was loaded from the profile's file, which
+// means the user is not signed in.
+func (h *Hive) Loaded() bool {
+	return h.loaded != ""
+}
+
+// Close closes the hive. If the hive was loaded by [Profile.OpenHive], it
+// is unloaded, which writes its changes back to the profile.
+func (h *Hive) Close() error {
+	err := h.key.Close()
+	if h.loaded != "" {
+		if unloadErr := unloadKey(registry.USERS, h.loaded); unloadErr != nil {
+			return unloadErr
+		}
+	}
+	return err
+}
+
+// isReparsePoint returns true if the directory at path is a reparse point,
+// such as a junction or a symbolic link.
+func isReparsePoint(path string) (bool, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+	attrs, err := windows.GetFileAttributes(name)
+	if err != nil {
+		return false, err
+	}
+	return attrs&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0, nil
+}
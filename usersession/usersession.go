@@ -0,0 +1,133 @@
+// Package usersession communicates with the user signed in to the console
+// session of the local system. It works from services running in session
+// zero, where windows cannot be shown directly.
+package usersession
+
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ErrNoUser is returned when no user is signed in to the console session.
+var ErrNoUser = errors.New("no user is signed in to the console session")
+
+// Response is a user's response to a prompt.
+type Response int
+
+// Prompt responses.
+const (
+	ResponseNone     Response = iota // The prompt timed out without a response
+	ResponseAccepted                 // The user pressed OK
+	ResponseDeclined                 // The user pressed Cancel or closed the prompt
+)
+
+// String returns a string representation of the response.
+func (r Response) String() string {
+	switch r {
+	case ResponseAccepted:
+		return "accepted"
+	case ResponseDeclined:
+		return "declined"
+	default:
+		return "no response"
+	}
+}
+
+var (
+	modwtsapi32                    = windows.NewLazySystemDLL("wtsapi32.dll")
+	procWTSSendMessage             = modwtsapi32.NewProc("WTSSendMessageW")
+	procWTSQuerySessionInformation = modwtsapi32.NewProc("WTSQuerySessionInformationW")
+)
+
+const (
+	wtsCurrentServerHandle = 0
+	wtsUserName            = 5
+
+	mbOKCancel       = 0x00000001
+	mbIconWarning    = 0x00000030
+	mbSetForeground  = 0x00010000
+	mbTopMost        = 0x00040000
+	idOK             = 1
+	idTimeout        = 32000
+	noConsoleSession = 0xFFFFFFFF
+)
+
+// Prompt shows a message with OK and Cancel buttons to the user signed in
+// to the console session, and waits up to timeout for the user to respond.
+//
+// If no user is signed in, it returns [ErrNoUser].
+func Prompt(title, message string, timeout time.Duration) (Response, error) {
+	session := windows.WTSGetActiveConsoleSessionId()
+	if session == noConsoleSession {
+		return ResponseNone, ErrNoUser
+	}
+
+	user, err := sessionUser(session)
+	if err != nil {
+		return ResponseNone, err
+	}
+	if user == "" {
+		return ResponseNone, ErrNoUser
+	}
+
+	titleUTF16, err := windows.UTF16FromString(title)
+	if err != nil {
+		return ResponseNone, err
+	}
+	messageUTF16, err := windows.UTF16FromString(message)
+	if err != nil {
+		return ResponseNone, err
+	}
+
+	// The lengths are given in bytes, without the terminating null.
+	var response uint32
+	r, _, e := procWTSSendMessage.Call(
+		wtsCurrentServerHandle,
+		uintptr(session),
+		uintptr(unsafe.Pointer(&titleUTF16[0])),
+		uintptr((len(titleUTF16)-1)*2),
+		uintptr(unsafe.Pointer(&messageUTF16[0])),
+		uintptr((len(messageUTF16)-1)*2),
+		mbOKCancel|mbIconWarning|mbSetForeground|mbTopMost,
+		uintptr(timeout/time.Second),
+		uintptr(unsafe.Pointer(&response)),
+		1, // Wait for a response
+	)
+	if r == 0 {
+		return ResponseNone, e
+	}
+
+	switch response {
+	case idOK:
+		return ResponseAccepted, nil
+	case idTimeout:
+		return ResponseNone, nil
+	default:
+		return ResponseDeclined, nil
+	}
+}
+
+// sessionUser returns the name of the user signed in to the session. It
+// returns an empty string if no user is signed in.
+func sessionUser(session uint32) (string, error) {
+	var (
+		buffer *uint16
+		size   uint32
+	)
+	r, _, e := procWTSQuerySessionInformation.Call(
+		wtsCurrentServerHandle,
+		uintptr(session),
+		wtsUserName,
+		uintptr(unsafe.Pointer(&buffer)),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if r == 0 {
+		return "", e
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(buffer)))
+
+	return windows.UTF16PtrToString(buffer), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lblint"
+)
+
+// ValidateCmd validates a LeafBridge deployment file and examines it for
+// likely mistakes.
+type ValidateCmd struct {
+	ConfigFile  string                 `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Environment lbdeploy.EnvironmentID `kong:"optional,name='environment',help='The environment overlay to apply to the deployment, such as pilot or broad.'"`
+	Errors      []lblint.Rule          `kong:"optional,name='error',help='Lint rules whose findings are reported as errors.'"`
+	Warnings    []lblint.Rule          `kong:"optional,name='warning',help='Lint rules whose findings are reported as warnings.'"`
+	Ignore      []lblint.Rule          `kong:"optional,name='ignore',help='Lint rules that are not applied.'"`
+	Strict      bool                   `kong:"optional,name='strict',help='Report the findings of every lint rule as errors.'"`
+}
+
+// Run executes the LeafBridge validate command.
+func (cmd ValidateCmd) Run(ctx context.Context) error {
+	// Prepare the lint configuration.
+	config, err := cmd.lintConfig()
+	if err != nil {
+		return err
+	}
+
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	// Apply the environment overlay.
+	dep, err = dep.ForEnvironment(cmd.Environment)
+	if err != nil {
+		return err
+	}
+
+	// Validate the deployment.
+	if err := dep.Validate(); err != nil {
+		if lbdeploy.IsResolutionError(err) {
+			fmt.Printf("The deployment contains resources that cannot be resolved: %s\n", err)
+		} else {
+			fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+		}
+		os.Exit(1)
+	}
+
+	// Look for likely mistakes.
+	findings := lblint.Lint(dep, config)
+	for _, finding := range findings {
+		fmt.Println(finding)
+	}
+
+	if n := findings.Errors(); n > 0 {
+		fmt.Printf("The deployment is valid, but linting found %d errors and %d warnings.\n", n, len(findings)-n)
+		os.Exit(1)
+	}
+
+	if len(findings) > 0 {
+		fmt.Printf("The deployment is valid, but linting found %d warnings.\n", len(findings))
+	} else {
+		fmt.Println("The deployment is valid.")
+	}
+
+	return nil
+}
+
+// lintConfig returns the lint configuration requested by the command.
+func (cmd ValidateCmd) lintConfig() (lblint.Config, error) {
+	config := lblint.Config{Severities: make(map[lblint.Rule]lblint.Severity)}
+
+	if cmd.Strict {
+		for _, rule := range lblint.Rules() {
+			config.Severities[rule] = lblint.SeverityError
+		}
+	}
+
+	for _, set := range []struct {
+		rules    []lblint.Rule
+		severity lblint.Severity
+	}{
+		{cmd.Warnings, lblint.SeverityWarning},
+		{cmd.Errors, lblint.SeverityError},
+		{cmd.Ignore, lblint.SeverityIgnore},
+	} {
+		for _, rule := range set.rules {
+			if err := rule.Validate(); err != nil {
+				return lblint.Config{}, err
+			}
+			config.Severities[rule] = set.severity
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/leafbridge/leafbridge-deploy/lbdeploy"
+	"github.com/leafbridge/leafbridge-deploy/lbengine"
+	"github.com/leafbridge/leafbridge-deploy/stagingfs"
+	"github.com/leafbridge/leafbridge-deploy/sysfacts"
+)
+
+// VerifyCmd verifies the staged package files of a LeafBridge deployment
+// against the file attributes declared in its configuration.
+type VerifyCmd struct {
+	ConfigFile  string                 `kong:"required,name='config-file',help='Path to a deployment file describing the deployment.'"`
+	Environment lbdeploy.EnvironmentID `kong:"optional,name='environment',help='The environment overlay to apply to the deployment, such as pilot or broad.'"`
+}
+
+// Run executes the LeafBridge verify command.
+func (cmd VerifyCmd) Run(ctx context.Context) error {
+	// Read the deployment file.
+	dep, err := loadDeployment(cmd.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	// Apply the environment overlay.
+	dep, err = dep.ForEnvironment(cmd.Environment)
+	if err != nil {
+		return err
+	}
+
+	// Select the package variants that suit the system's UI languages.
+	dep = dep.ForLanguages(sysfacts.Languages())
+
+	// Validate the deployment.
+	if err := dep.Validate(); err != nil {
+		fmt.Printf("The deployment contains invalid configuration: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("---- %s (%s): Staged Packages ----\n", dep.Name, cmd.ConfigFile)
+
+	// Sort the package IDs for a deterministic order.
+	ids := slices.Collect(maps.Keys(dep.Resources.Packages))
+	slices.Sort(ids)
+
+	// Verify each package.
+	var failed []string
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fmt.Printf("    %s:\n", id)
+		if err := verifyStagedPackage(dep.ID, id, dep.Resources.Packages[id]); err != nil {
+			fmt.Printf("      Status:      Failed (%v)\n", err)
+			failed = append(failed, string(id))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d staged packages failed verification: %s", len(failed), len(ids), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// verifyStagedPackage verifies the staged file for a single package and
+// prints its details. It returns a non-nil error if the package file is
+// missing or does not match its declared attributes.
+func verifyStagedPackage(deployment lbdeploy.DeploymentID, id lbdeploy.PackageID, pkg lbdeploy.Package) error {
+	// Open the deployment's staging directory without creating it.
+	deployDir, err := stagingfs.OpenExistingDeployment(deployment)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("the package has not been staged")
+		}
+		return err
+	}
+	defer deployDir.Close()
+
+	// Open the package's staging directory without creating it.
+	packageDir, err := deployDir.OpenExistingPackage(lbdeploy.PackageContent{
+		ID:          id,
+		PrimaryHash: pkg.Attributes.Hashes.Primary(),
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("the package has not been staged")
+		}
+		return err
+	}
+	defer packageDir.Close()
+
+	// Open the package file.
+	file, err := packageDir.OpenExistingFile(pkg)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("the package has not been staged")
+		}
+		return err
+	}
+	defer file.Close()
+
+	fmt.Printf("      Path:        %s\n", file.Path)
+
+	// Prepare a verifier for the package.
+	verifier, err := lbengine.NewFileVerifier(pkg.Attributes.Hashes.Types()...)
+	if err != nil {
+		return err
+	}
+	if len(verifier.HashTypes()) == 0 {
+		return fmt.Errorf("the package does not provide any file hashes for verification")
+	}
+
+	// Re-hash the file content.
+	if _, err := verifier.ReadFrom(file); err != nil {
+		return fmt.Errorf("failed to read the package file: %w", err)
+	}
+	actual := verifier.State()
+
+	fmt.Printf("      Size:        %d bytes\n", actual.Size)
+
+	// Compare the file attributes against the deployment's expectations.
+	if !lbdeploy.EqualFileAttributes(pkg.Attributes, actual) {
+		if actual.Size != pkg.Attributes.Size {
+			return fmt.Errorf("expected %d byte(s) but found %d", pkg.Attributes.Size, actual.Size)
+		}
+		return fmt.Errorf("the file hashes do not match")
+	}
+
+	fmt.Printf("      Status:      Passed (%s)\n", strings.Join(actual.Features(), ", "))
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package wincred reads credentials from the Windows Credential Manager.
+//
+// Credentials are referred to by name, so that deployment files never
+// need to hold secrets. They can be stored for the account that runs
+// deployments with cmdkey or the Credential Manager control panel.
+package wincred
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modadvapi32 = windows.NewLazySystemDLL("advapi32.dll")
+
+	procCredReadW = modadvapi32.NewProc("CredReadW")
+	procCredFree  = modadvapi32.NewProc("CredFree")
+)
+
+// Credential types used by the Windows API.
+const (
+	credTypeGeneric        = 1 // CRED_TYPE_GENERIC
+	credTypeDomainPassword = 2 // CRED_TYPE_DOMAIN_PASSWORD
+)
+
+// credential is the CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// Credential is a credential stored in the Windows Credential Manager.
+type Credential struct {
+	UserName string
+	Secret   string
+}
+
+// Read returns the named credential from the Windows Credential Manager
+// of the calling account. Windows credentials are preferred to generic
+// credentials with the same name.
+func Read(name string) (Credential, error) {
+	if err := procCredReadW.Find(); err != nil {
+		return Credential{}, fmt.Errorf("the credential manager is not available: %w", err)
+	}
+
+	target, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var lastErr error
+	for _, credType := range []uintptr{credTypeDomainPassword, credTypeGeneric} {
+		var cred *credential
+		r0, _, e1 := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credType, 0, uintptr(unsafe.Pointer(&cred)))
+		if r0 == 0 {
+			lastErr = e1
+			continue
+		}
+
+		var result Credential
+		result.UserName = windows.UTF16PtrToString(cred.UserName)
+		if cred.CredentialBlobSize > 0 && cred.CredentialBlob != nil {
+			blob := unsafe.Slice((*uint16)(unsafe.Pointer(cred.CredentialBlob)), cred.CredentialBlobSize/2)
+			result.Secret = windows.UTF16ToString(blob)
+		}
+		procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+		return result, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("the credential was not found")
+	}
+	return Credential{}, lastErr
+}
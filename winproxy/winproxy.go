@@ -0,0 +1,105 @@
+// Package winproxy reads the proxy configuration of the local system.
+//
+// The configuration is the WinHTTP default proxy, which can be managed with
+// "netsh winhttp set proxy" or imported from the Internet Options of the
+// current user with "netsh winhttp import proxy source=ie". Unlike the
+// per-user settings, it applies to services running as the local system
+// account.
+package winproxy
+
+import (
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwinhttp  = windows.NewLazySystemDLL("winhttp.dll")
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procWinHttpGetDefaultProxyConfiguration = modwinhttp.NewProc("WinHttpGetDefaultProxyConfiguration")
+	procGlobalFree                          = modkernel32.NewProc("GlobalFree")
+)
+
+// Access types used by the Windows API.
+const (
+	accessTypeNamedProxy = 3 // WINHTTP_ACCESS_TYPE_NAMED_PROXY
+)
+
+// proxyInfo is the WINHTTP_PROXY_INFO structure.
+type proxyInfo struct {
+	AccessType  uint32
+	Proxy       *uint16
+	ProxyBypass *uint16
+}
+
+// Config is a proxy configuration.
+//
+// Proxy holds one or more proxy servers in the form used by WinHTTP, such
+// as "proxy:8080" or "http=proxy:8080;https=proxy:8443". It is empty if
+// connections are made directly.
+//
+// Bypass holds the hosts that are reached directly, such as
+// "*.example.com" or "<local>".
+type Config struct {
+	Proxy  string
+	Bypass []string
+}
+
+// ProxyFor returns the proxy server that requests with the given URL scheme
+// are sent through. It returns an empty string if they are sent directly.
+func (c Config) ProxyFor(scheme string) string {
+	var fallback string
+	for _, entry := range splitList(c.Proxy) {
+		name, server, found := strings.Cut(entry, "=")
+		switch {
+		case !found:
+			if fallback == "" {
+				fallback = entry
+			}
+		case strings.EqualFold(name, scheme):
+			return server
+		}
+	}
+	return fallback
+}
+
+// Default returns the WinHTTP default proxy configuration of the local
+// system.
+func Default() (Config, error) {
+	if err := procWinHttpGetDefaultProxyConfiguration.Find(); err != nil {
+		return Config{}, err
+	}
+
+	var info proxyInfo
+	r0, _, e1 := procWinHttpGetDefaultProxyConfiguration.Call(uintptr(unsafe.Pointer(&info)))
+	if r0 == 0 {
+		return Config{}, e1
+	}
+
+	// The strings are allocated by the system and must be released.
+	proxy := windows.UTF16PtrToString(info.Proxy)
+	bypass := windows.UTF16PtrToString(info.ProxyBypass)
+	for _, s := range []*uint16{info.Proxy, info.ProxyBypass} {
+		if s != nil {
+			procGlobalFree.Call(uintptr(unsafe.Pointer(s)))
+		}
+	}
+
+	if info.AccessType != accessTypeNamedProxy {
+		return Config{}, nil
+	}
+
+	return Config{
+		Proxy:  proxy,
+		Bypass: splitList(bypass),
+	}, nil
+}
+
+// splitList splits a list of entries separated by semicolons or whitespace.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t'
+	})
+}